@@ -0,0 +1,287 @@
+package driver
+
+import (
+	"context"
+	"database/sql/driver"
+	"fmt"
+	"time"
+
+	sqle "github.com/src-d/go-mysql-server"
+	"github.com/src-d/go-mysql-server/sql"
+	"github.com/src-d/go-mysql-server/sql/expression"
+	"github.com/src-d/go-mysql-server/sql/parse"
+	"github.com/src-d/go-mysql-server/sql/plan"
+	"gopkg.in/src-d/go-errors.v1"
+)
+
+// ErrNamedParameter is returned when a query is given named parameters,
+// which are not supported.
+var ErrNamedParameter = errors.NewKind("named parameters are not supported: %s")
+
+// ErrUnsupportedArgument is returned when a query is given a parameter of a
+// type that can't be used in a query.
+var ErrUnsupportedArgument = errors.NewKind("unsupported argument of type %T")
+
+// Conn is a connection to an engine. Each connection has its own session, so
+// session variables and transactions are kept between the queries of the
+// same connection.
+type Conn struct {
+	engine  *sqle.Engine
+	session sql.Session
+}
+
+func newConn(e *sqle.Engine, id uint32) *Conn {
+	return &Conn{
+		engine:  e,
+		session: sql.NewSession("", "", "", id),
+	}
+}
+
+var (
+	_ driver.Conn               = (*Conn)(nil)
+	_ driver.ConnPrepareContext = (*Conn)(nil)
+	_ driver.ConnBeginTx        = (*Conn)(nil)
+	_ driver.ExecerContext      = (*Conn)(nil)
+	_ driver.QueryerContext     = (*Conn)(nil)
+)
+
+// Prepare implements the driver.Conn interface.
+func (c *Conn) Prepare(query string) (driver.Stmt, error) {
+	return c.PrepareContext(context.Background(), query)
+}
+
+// PrepareContext implements the driver.ConnPrepareContext interface.
+func (c *Conn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	node, err := parse.Parse(c.newContext(ctx, query), query)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Stmt{conn: c, query: query, numInput: len(plan.BindVars(node))}, nil
+}
+
+// Close implements the driver.Conn interface. Any transaction in progress is
+// rolled back.
+func (c *Conn) Close() error {
+	if tx := c.session.Transaction(); tx != nil {
+		return tx.Rollback(c.newContext(context.Background(), ""))
+	}
+	return nil
+}
+
+// Begin implements the driver.Conn interface.
+func (c *Conn) Begin() (driver.Tx, error) {
+	return c.BeginTx(context.Background(), driver.TxOptions{})
+}
+
+// BeginTx implements the driver.ConnBeginTx interface. Transaction options
+// are ignored.
+func (c *Conn) BeginTx(ctx context.Context, _ driver.TxOptions) (driver.Tx, error) {
+	if _, err := c.exec(ctx, "START TRANSACTION", nil); err != nil {
+		return nil, err
+	}
+	return &Tx{conn: c}, nil
+}
+
+// ExecContext implements the driver.ExecerContext interface.
+func (c *Conn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	return c.exec(ctx, query, args)
+}
+
+// QueryContext implements the driver.QueryerContext interface.
+func (c *Conn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	return c.query(ctx, query, args)
+}
+
+func (c *Conn) newContext(ctx context.Context, query string) *sql.Context {
+	return sql.NewContext(
+		ctx,
+		sql.WithSession(c.session),
+		sql.WithPid(c.engine.Catalog.NextPid()),
+		sql.WithQuery(query),
+		sql.WithMemoryManager(c.engine.Catalog.MemoryManager),
+	)
+}
+
+func (c *Conn) query(ctx context.Context, query string, args []driver.NamedValue) (*Rows, error) {
+	bindings, err := argsToBindings(args)
+	if err != nil {
+		return nil, err
+	}
+
+	schema, iter, err := c.engine.QueryWithBindings(c.newContext(ctx, query), query, bindings)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Rows{schema: schema, iter: iter}, nil
+}
+
+func (c *Conn) exec(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	rows, err := c.query(ctx, query, args)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := sql.RowIterToRows(rows.iter)
+	if err != nil {
+		_ = rows.iter.Close()
+		return nil, err
+	}
+
+	return &Result{affected: affectedRows(rows.schema, result)}, nil
+}
+
+var (
+	okResultSchema     = new(plan.InsertInto).Schema()
+	updateResultSchema = new(plan.Update).Schema()
+)
+
+// affectedRows returns the number of rows changed by a statement given its
+// result, which is zero for statements that don't change rows.
+func affectedRows(schema sql.Schema, rows []sql.Row) int64 {
+	if len(rows) != 1 {
+		return 0
+	}
+
+	var idx int
+	switch {
+	case schema.Equals(okResultSchema):
+		idx = 0
+	case schema.Equals(updateResultSchema):
+		idx = 1
+	default:
+		return 0
+	}
+
+	n, ok := rows[0][idx].(int64)
+	if !ok {
+		return 0
+	}
+	return n
+}
+
+func argsToBindings(args []driver.NamedValue) (map[string]sql.Expression, error) {
+	if len(args) == 0 {
+		return nil, nil
+	}
+
+	bindings := make(map[string]sql.Expression, len(args))
+	for _, arg := range args {
+		if arg.Name != "" {
+			return nil, ErrNamedParameter.New(arg.Name)
+		}
+
+		expr, err := valueToExpression(arg.Value)
+		if err != nil {
+			return nil, err
+		}
+
+		// Placeholders are named v1, v2 and so on, like the ordinal of the
+		// arguments.
+		bindings[fmt.Sprintf("v%d", arg.Ordinal)] = expr
+	}
+
+	return bindings, nil
+}
+
+func valueToExpression(v driver.Value) (sql.Expression, error) {
+	switch v := v.(type) {
+	case nil:
+		return expression.NewLiteral(nil, sql.Null), nil
+	case int64:
+		return expression.NewLiteral(v, sql.Int64), nil
+	case float64:
+		return expression.NewLiteral(v, sql.Float64), nil
+	case bool:
+		return expression.NewLiteral(v, sql.Boolean), nil
+	case []byte:
+		return expression.NewLiteral(v, sql.Blob), nil
+	case string:
+		return expression.NewLiteral(v, sql.Text), nil
+	case time.Time:
+		return expression.NewLiteral(v, sql.Datetime), nil
+	default:
+		return nil, ErrUnsupportedArgument.New(v)
+	}
+}
+
+// Result is the result of a statement executed with Exec.
+type Result struct {
+	affected int64
+}
+
+var _ driver.Result = (*Result)(nil)
+
+// LastInsertId implements the driver.Result interface. It's always zero,
+// because engines don't have auto increment columns.
+func (*Result) LastInsertId() (int64, error) { return 0, nil }
+
+// RowsAffected implements the driver.Result interface.
+func (r *Result) RowsAffected() (int64, error) { return r.affected, nil }
+
+// Tx is a transaction in a connection.
+type Tx struct {
+	conn *Conn
+}
+
+var _ driver.Tx = (*Tx)(nil)
+
+// Commit implements the driver.Tx interface.
+func (t *Tx) Commit() error {
+	_, err := t.conn.exec(context.Background(), "COMMIT", nil)
+	return err
+}
+
+// Rollback implements the driver.Tx interface.
+func (t *Tx) Rollback() error {
+	_, err := t.conn.exec(context.Background(), "ROLLBACK", nil)
+	return err
+}
+
+// Stmt is a prepared statement of a connection.
+type Stmt struct {
+	conn     *Conn
+	query    string
+	numInput int
+}
+
+var (
+	_ driver.Stmt             = (*Stmt)(nil)
+	_ driver.StmtExecContext  = (*Stmt)(nil)
+	_ driver.StmtQueryContext = (*Stmt)(nil)
+)
+
+// Close implements the driver.Stmt interface.
+func (*Stmt) Close() error { return nil }
+
+// NumInput implements the driver.Stmt interface.
+func (s *Stmt) NumInput() int { return s.numInput }
+
+// Exec implements the driver.Stmt interface.
+func (s *Stmt) Exec(args []driver.Value) (driver.Result, error) {
+	return s.ExecContext(context.Background(), namedValues(args))
+}
+
+// Query implements the driver.Stmt interface.
+func (s *Stmt) Query(args []driver.Value) (driver.Rows, error) {
+	return s.QueryContext(context.Background(), namedValues(args))
+}
+
+// ExecContext implements the driver.StmtExecContext interface.
+func (s *Stmt) ExecContext(ctx context.Context, args []driver.NamedValue) (driver.Result, error) {
+	return s.conn.exec(ctx, s.query, args)
+}
+
+// QueryContext implements the driver.StmtQueryContext interface.
+func (s *Stmt) QueryContext(ctx context.Context, args []driver.NamedValue) (driver.Rows, error) {
+	return s.conn.query(ctx, s.query, args)
+}
+
+func namedValues(args []driver.Value) []driver.NamedValue {
+	result := make([]driver.NamedValue, len(args))
+	for i, v := range args {
+		result[i] = driver.NamedValue{Ordinal: i + 1, Value: v}
+	}
+	return result
+}
@@ -0,0 +1,107 @@
+// Package driver provides a database/sql driver to run queries in an
+// in-process go-mysql-server engine.
+//
+// Engines are registered with a name, which is used as the data source name
+// to open them:
+//
+//	driver.Register("mydb", engine)
+//	db, err := sql.Open("sqle", "mydb")
+//
+// Alternatively, a connector for an engine can be used without registering
+// it:
+//
+//	db := sql.OpenDB(driver.NewConnector(engine))
+package driver
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"sync"
+
+	sqle "github.com/src-d/go-mysql-server"
+	"gopkg.in/src-d/go-errors.v1"
+)
+
+// DriverName is the name of the driver registered in database/sql.
+const DriverName = "sqle"
+
+// ErrEngineNotFound is returned when there is no engine registered with the
+// name given as data source name.
+var ErrEngineNotFound = errors.NewKind("there is no engine registered with name %q")
+
+var defaultDriver = &Driver{engines: make(map[string]*sqle.Engine)}
+
+func init() {
+	sql.Register(DriverName, defaultDriver)
+}
+
+// Register makes the given engine available to database/sql with the given
+// name as data source name. An engine registered with the same name is
+// replaced.
+func Register(name string, e *sqle.Engine) {
+	defaultDriver.mu.Lock()
+	defaultDriver.engines[name] = e
+	defaultDriver.mu.Unlock()
+}
+
+// Unregister removes the engine registered with the given name. Connections
+// already open are not affected.
+func Unregister(name string) {
+	defaultDriver.mu.Lock()
+	delete(defaultDriver.engines, name)
+	defaultDriver.mu.Unlock()
+}
+
+// Driver is a database/sql driver whose data source names are the names of
+// the registered engines.
+type Driver struct {
+	mu      sync.RWMutex
+	engines map[string]*sqle.Engine
+}
+
+var _ driver.DriverContext = (*Driver)(nil)
+
+// Open implements the driver.Driver interface.
+func (d *Driver) Open(name string) (driver.Conn, error) {
+	c, err := d.OpenConnector(name)
+	if err != nil {
+		return nil, err
+	}
+	return c.Connect(context.Background())
+}
+
+// OpenConnector implements the driver.DriverContext interface.
+func (d *Driver) OpenConnector(name string) (driver.Connector, error) {
+	d.mu.RLock()
+	e, ok := d.engines[name]
+	d.mu.RUnlock()
+	if !ok {
+		return nil, ErrEngineNotFound.New(name)
+	}
+
+	return &Connector{engine: e, driver: d}, nil
+}
+
+// Connector opens connections to an engine.
+type Connector struct {
+	engine *sqle.Engine
+	driver driver.Driver
+}
+
+// NewConnector creates a connector for the given engine, which can be used
+// with sql.OpenDB.
+func NewConnector(e *sqle.Engine) *Connector {
+	return &Connector{engine: e, driver: defaultDriver}
+}
+
+// Connect implements the driver.Connector interface. Each connection has its
+// own session in the engine.
+func (c *Connector) Connect(context.Context) (driver.Conn, error) {
+	return newConn(c.engine, c.engine.Catalog.NextConnectionID()), nil
+}
+
+// Driver implements the driver.Connector interface.
+func (c *Connector) Driver() driver.Driver {
+	return c.driver
+}
@@ -0,0 +1,153 @@
+package driver
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	sqle "github.com/src-d/go-mysql-server"
+	"github.com/src-d/go-mysql-server/memory"
+	gmssql "github.com/src-d/go-mysql-server/sql"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestDB(t *testing.T) (*sql.DB, func()) {
+	t.Helper()
+	require := require.New(t)
+
+	table := memory.NewTable("mytable", gmssql.Schema{
+		{Name: "i", Type: gmssql.Int64, Source: "mytable"},
+		{Name: "s", Type: gmssql.Text, Source: "mytable", Nullable: true},
+	})
+
+	ctx := gmssql.NewEmptyContext()
+	require.NoError(table.Insert(ctx, gmssql.NewRow(int64(1), "first row")))
+	require.NoError(table.Insert(ctx, gmssql.NewRow(int64(2), "second row")))
+	require.NoError(table.Insert(ctx, gmssql.NewRow(int64(3), "third row")))
+
+	db := memory.NewDatabase("mydb")
+	db.AddTable("mytable", table)
+
+	e := sqle.NewDefault()
+	e.AddDatabase(db)
+
+	Register(t.Name(), e)
+	sqlDB, err := sql.Open(DriverName, t.Name())
+	require.NoError(err)
+
+	return sqlDB, func() {
+		require.NoError(sqlDB.Close())
+		Unregister(t.Name())
+	}
+}
+
+func TestQuery(t *testing.T) {
+	require := require.New(t)
+	db, cleanup := newTestDB(t)
+	defer cleanup()
+
+	rows, err := db.Query("SELECT i, s FROM mytable WHERE i > ? ORDER BY i", 1)
+	require.NoError(err)
+
+	types, err := rows.ColumnTypes()
+	require.NoError(err)
+	require.Equal("BIGINT", types[0].DatabaseTypeName())
+	require.Equal("TEXT", types[1].DatabaseTypeName())
+	nullable, ok := types[1].Nullable()
+	require.True(ok)
+	require.True(nullable)
+
+	var result []string
+	for rows.Next() {
+		var i int64
+		var s string
+		require.NoError(rows.Scan(&i, &s))
+		result = append(result, s)
+	}
+	require.NoError(rows.Err())
+	require.NoError(rows.Close())
+	require.Equal([]string{"second row", "third row"}, result)
+}
+
+func TestExec(t *testing.T) {
+	require := require.New(t)
+	db, cleanup := newTestDB(t)
+	defer cleanup()
+
+	result, err := db.Exec("INSERT INTO mytable (i, s) VALUES (?, ?), (?, ?)", 4, "fourth row", 5, nil)
+	require.NoError(err)
+	affected, err := result.RowsAffected()
+	require.NoError(err)
+	require.Equal(int64(2), affected)
+
+	result, err = db.Exec("UPDATE mytable SET s = ? WHERE i > ?", "updated", 3)
+	require.NoError(err)
+	affected, err = result.RowsAffected()
+	require.NoError(err)
+	require.Equal(int64(2), affected)
+
+	result, err = db.Exec("DELETE FROM mytable WHERE i < ?", 3)
+	require.NoError(err)
+	affected, err = result.RowsAffected()
+	require.NoError(err)
+	require.Equal(int64(2), affected)
+
+	var count int64
+	require.NoError(db.QueryRow("SELECT COUNT(*) FROM mytable WHERE s = 'updated'").Scan(&count))
+	require.Equal(int64(2), count)
+}
+
+func TestPreparedStatement(t *testing.T) {
+	require := require.New(t)
+	db, cleanup := newTestDB(t)
+	defer cleanup()
+
+	stmt, err := db.Prepare("SELECT s FROM mytable WHERE i = ?")
+	require.NoError(err)
+	defer stmt.Close()
+
+	for i, expected := range []string{"first row", "second row", "third row"} {
+		var s string
+		require.NoError(stmt.QueryRow(i + 1).Scan(&s))
+		require.Equal(expected, s)
+	}
+
+	_, err = stmt.Query()
+	require.Error(err)
+}
+
+func TestTransaction(t *testing.T) {
+	require := require.New(t)
+	db, cleanup := newTestDB(t)
+	defer cleanup()
+
+	tx, err := db.Begin()
+	require.NoError(err)
+	_, err = tx.Exec("INSERT INTO mytable (i, s) VALUES (4, 'fourth row')")
+	require.NoError(err)
+
+	var count int64
+	require.NoError(tx.QueryRow("SELECT COUNT(*) FROM mytable").Scan(&count))
+	require.Equal(int64(4), count)
+	require.NoError(tx.Rollback())
+
+	require.NoError(db.QueryRow("SELECT COUNT(*) FROM mytable").Scan(&count))
+	require.Equal(int64(3), count)
+}
+
+func TestContextCancellation(t *testing.T) {
+	require := require.New(t)
+	db, cleanup := newTestDB(t)
+	defer cleanup()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := db.QueryContext(ctx, "SELECT * FROM mytable")
+	require.Error(err)
+}
+
+func TestUnknownEngine(t *testing.T) {
+	_, err := sql.Open(DriverName, "unknown")
+	require.True(t, ErrEngineNotFound.Is(err))
+}
@@ -0,0 +1,110 @@
+package driver
+
+import (
+	"database/sql/driver"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/src-d/go-mysql-server/sql"
+)
+
+// Rows is an iterator over the rows returned by a query.
+type Rows struct {
+	schema sql.Schema
+	iter   sql.RowIter
+}
+
+var (
+	_ driver.Rows                           = (*Rows)(nil)
+	_ driver.RowsColumnTypeDatabaseTypeName = (*Rows)(nil)
+	_ driver.RowsColumnTypeNullable         = (*Rows)(nil)
+)
+
+// Columns implements the driver.Rows interface.
+func (r *Rows) Columns() []string {
+	names := make([]string, len(r.schema))
+	for i, col := range r.schema {
+		names[i] = col.Name
+	}
+	return names
+}
+
+// Close implements the driver.Rows interface.
+func (r *Rows) Close() error {
+	return r.iter.Close()
+}
+
+// Next implements the driver.Rows interface.
+func (r *Rows) Next(dest []driver.Value) error {
+	row, err := r.iter.Next()
+	if err != nil {
+		return err
+	}
+
+	for i, v := range row {
+		dest[i], err = toDriverValue(r.schema[i].Type, v)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ColumnTypeDatabaseTypeName implements the
+// driver.RowsColumnTypeDatabaseTypeName interface.
+func (r *Rows) ColumnTypeDatabaseTypeName(index int) string {
+	return sql.MySQLTypeName(r.schema[index].Type)
+}
+
+// ColumnTypeNullable implements the driver.RowsColumnTypeNullable interface.
+func (r *Rows) ColumnTypeNullable(index int) (nullable, ok bool) {
+	return r.schema[index].Nullable, true
+}
+
+// toDriverValue converts a value of the given type to one of the types
+// database/sql drivers must return.
+func toDriverValue(typ sql.Type, v interface{}) (driver.Value, error) {
+	switch v := v.(type) {
+	case nil, int64, float64, bool, []byte, string, time.Time:
+		return v, nil
+	case int:
+		return int64(v), nil
+	case int8:
+		return int64(v), nil
+	case int16:
+		return int64(v), nil
+	case int32:
+		return int64(v), nil
+	case uint:
+		return uint64ToDriverValue(uint64(v)), nil
+	case uint8:
+		return int64(v), nil
+	case uint16:
+		return int64(v), nil
+	case uint32:
+		return int64(v), nil
+	case uint64:
+		return uint64ToDriverValue(v), nil
+	case float32:
+		return float64(v), nil
+	}
+
+	// The rest of the values, such as JSON documents, are returned as they
+	// would be sent by a MySQL server.
+	value, err := typ.SQL(v)
+	if err != nil {
+		return nil, err
+	}
+	return value.ToBytes(), nil
+}
+
+// uint64ToDriverValue returns the value as an int64 if it fits, and as its
+// text representation otherwise.
+func uint64ToDriverValue(v uint64) driver.Value {
+	if v > math.MaxInt64 {
+		return strconv.FormatUint(v, 10)
+	}
+	return int64(v)
+}
@@ -1,8 +1,9 @@
 package sqle
 
 import (
-	"errors"
 	"github.com/src-d/go-mysql-server/sql/expression/function/udf"
+	"io"
+	"sync/atomic"
 	"time"
 
 	"github.com/go-kit/kit/metrics/discard"
@@ -22,14 +23,35 @@ type Config struct {
 	VersionPostfix string
 	// Auth used for authentication and authorization.
 	Auth auth.Auth
+	// SandboxScripts runs all the scripts as untrusted, including the
+	// macros of the queries run with SQuery and the UDFs registered with
+	// RegisterUDF. The scripts of functions created with CREATE FUNCTION and
+	// tables created with ENGINE=SCRIPT are always untrusted, as they run in
+	// the sessions of any user.
+	SandboxScripts bool
 }
 
 // Engine is a SQL engine.
 type Engine struct {
-	Catalog       *sql.Catalog
-	Analyzer      *analyzer.Analyzer
-	Auth          auth.Auth
+	// generation is increased every time the plans of the queries are
+	// invalidated. It's the first field so it's aligned for atomic
+	// operations.
+	generation uint64
+
+	Catalog  *sql.Catalog
+	Analyzer *analyzer.Analyzer
+	Auth     auth.Auth
+	// NumCustomUdfs is the number of UDFs registered with RegisterUDF.
 	NumCustomUdfs int
+	// Scripts caches the scripts of the macros used in queries run with
+	// SQuery, so repeated queries reuse them.
+	Scripts *udf.ScriptCache
+	// PlanCache caches the plans of the SELECT queries, so queries that
+	// only differ in their literals are analyzed once.
+	PlanCache *PlanCache
+
+	// sandboxScripts is true if all the scripts run as untrusted.
+	sandboxScripts bool
 }
 
 var (
@@ -41,6 +63,12 @@ var (
 
 	// QueryHistogram describes a queries latency.
 	QueryHistogram = discard.NewHistogram()
+
+	// PlanCacheHitCounter describes a metric that accumulates number of queries run with a cached plan monotonically.
+	PlanCacheHitCounter = discard.NewCounter()
+
+	// PlanCacheMissCounter describes a metric that accumulates number of queries whose plan was not cached monotonically.
+	PlanCacheMissCounter = discard.NewCounter()
 )
 
 func observeQuery(ctx *sql.Context, query string) func(err error) {
@@ -64,8 +92,10 @@ func observeQuery(ctx *sql.Context, query string) func(err error) {
 // the default settings use `NewDefault`.
 func New(c *sql.Catalog, a *analyzer.Analyzer, cfg *Config) *Engine {
 	var versionPostfix string
+	var sandboxScripts bool
 	if cfg != nil {
 		versionPostfix = cfg.VersionPostfix
+		sandboxScripts = cfg.SandboxScripts
 	}
 
 	c.MustRegister(
@@ -78,6 +108,7 @@ func New(c *sql.Catalog, a *analyzer.Analyzer, cfg *Config) *Engine {
 			Fn:   function.NewDatabase(c),
 		})
 	c.MustRegister(function.Defaults...)
+	c.MustRegister(udf.TableFunctions...)
 
 	// use auth.None if auth is not specified
 	var au auth.Auth
@@ -87,7 +118,15 @@ func New(c *sql.Catalog, a *analyzer.Analyzer, cfg *Config) *Engine {
 		au = cfg.Auth
 	}
 
-	return &Engine{Catalog: c, Analyzer: a, Auth: au}
+	return &Engine{
+		Catalog:   c,
+		Analyzer:  a,
+		Auth:      au,
+		Scripts:   udf.NewScriptCache(udf.DefaultScriptCacheSize),
+		PlanCache: NewPlanCache(DefaultPlanCacheSize),
+
+		sandboxScripts: sandboxScripts,
+	}
 }
 
 // NewDefault creates a new default Engine.
@@ -100,60 +139,126 @@ func NewDefault() *Engine {
 
 func (e *Engine) RegisterUDF(scriptUDF udf.ScriptUDF) error {
 	e.NumCustomUdfs++
-	return e.Catalog.FunctionRegistry.Register(scriptUDF.AsFunction())
+	if e.sandboxScripts {
+		scriptUDF.Sandboxed = true
+	}
+	e.invalidatePlans()
+	return e.Catalog.Register(scriptUDF.AsFunction())
 }
 
-// SQuery executes a Script based query
+// SQuery executes a Script based query. The UDFs of the macros in the query
+// are only available to the query itself, and they are discarded once its
+// iterator is closed.
 func (e *Engine) SQuery(
 	ctx *sql.Context,
 	query string,
 	scriptLang string,
 ) (sql.Schema, sql.RowIter, error) {
-	processedQuery, customFunctions := udf.MacroProcessor(query, e.NumCustomUdfs, scriptLang)
-	if customFunctions != nil {
-		// now fill up the UDFs...
-		transposeCount := 0
-		for i := 0; i < len(customFunctions); i++ {
-			if customFunctions[i].UdfType.Transpose {
-				transposeCount++;
-			}
-		}
-		if transposeCount > 1 {
-			return nil, nil, errors.New("Cannot have more than one pivot agg udf.")
+	processedQuery, customFunctions, err := e.Scripts.MacroProcessor(query, 0, scriptLang)
+	if err != nil {
+		return nil, nil, err
+	}
+	if customFunctions == nil {
+		return e.Query(ctx, processedQuery)
+	}
+
+	functions := make(sql.FunctionRegistry, len(customFunctions))
+	for i := 0; i < len(customFunctions); i++ {
+		if e.sandboxScripts {
+			customFunctions[i].Sandboxed = true
 		}
-		for i := 0; i < len(customFunctions); i++ {
-			_ = e.RegisterUDF(customFunctions[i])
+		if err := functions.Register(customFunctions[i].AsFunction()); err != nil {
+			return nil, nil, err
 		}
 	}
-	return e.Query(ctx, processedQuery)
+
+	schema, iter, err := e.Query(ctx.WithFunctions(functions), processedQuery)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return schema, &scriptQueryIter{iter, functions}, nil
+}
+
+// scriptQueryIter is the iterator of a query run with SQuery, which discards
+// the UDFs of the query when it's closed.
+type scriptQueryIter struct {
+	sql.RowIter
+	functions sql.FunctionRegistry
+}
+
+func (i *scriptQueryIter) Close() error {
+	for name := range i.functions {
+		i.functions.Unregister(name)
+	}
+	return i.RowIter.Close()
 }
 
 // Query executes a query.
 func (e *Engine) Query(
 	ctx *sql.Context,
 	query string,
+) (sql.Schema, sql.RowIter, error) {
+	return e.QueryWithBindings(ctx, query, nil)
+}
+
+// QueryWithBindings executes a query replacing its placeholders with the
+// expressions bound to their names. Placeholders are named v1, v2 and so on
+// in the order they appear in the query.
+func (e *Engine) QueryWithBindings(
+	ctx *sql.Context,
+	query string,
+	bindings map[string]sql.Expression,
 ) (sql.Schema, sql.RowIter, error) {
 	var (
-		parsed, analyzed sql.Node
-		iter             sql.RowIter
-		err              error
+		parsed sql.Node
+		err    error
 	)
 
 	finish := observeQuery(ctx, query)
 	defer finish(err)
 
-	parsed, err = parse.Parse(ctx, query)
-	if err != nil {
-		return nil, nil, err
+	normalized := e.normalize(ctx, query)
+	if normalized == nil {
+		parsed, err = e.parse(ctx, query, bindings)
+		if err != nil {
+			return nil, nil, err
+		}
 	}
 
+	return e.run(ctx, query, parsed, func(ctx *sql.Context) (sql.Node, error) {
+		if normalized != nil {
+			return e.analyzeNormalized(ctx, query, normalized, bindings)
+		}
+		return e.Analyzer.Analyze(ctx, parsed)
+	})
+}
+
+// run executes the given parsed statement, whose node is analyzed with the
+// given function once the statement is allowed and its process is added to
+// the catalog.
+func (e *Engine) run(
+	ctx *sql.Context,
+	query string,
+	parsed sql.Node,
+	analyze func(*sql.Context) (sql.Node, error),
+) (sql.Schema, sql.RowIter, error) {
+	var (
+		analyzed sql.Node
+		iter     sql.RowIter
+		err      error
+	)
+
 	var perm = auth.ReadPerm
 	var typ = sql.QueryProcess
 	switch parsed.(type) {
 	case *plan.CreateIndex:
 		typ = sql.CreateIndexProcess
 		perm = auth.ReadPerm | auth.WritePerm
-	case *plan.InsertInto, *plan.DeleteFrom, *plan.Update, *plan.DropIndex, *plan.UnlockTables, *plan.LockTables:
+	case *plan.InsertInto, *plan.DeleteFrom, *plan.Update, *plan.DropIndex, *plan.UnlockTables, *plan.LockTables,
+		*plan.CreateFunction, *plan.DropFunction,
+		*plan.AddColumn, *plan.DropColumn, *plan.ModifyColumn, *plan.RenameColumn, *plan.RenameTable,
+		*plan.CreateView, *plan.DropView, *plan.AnalyzeTable:
 		perm = auth.ReadPerm | auth.WritePerm
 	}
 
@@ -173,19 +278,263 @@ func (e *Engine) Query(
 		return nil, nil, err
 	}
 
-	analyzed, err = e.Analyzer.Analyze(ctx, parsed)
+	analyzed, err = analyze(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	tx, err := e.beginStatement(ctx, parsed)
 	if err != nil {
 		return nil, nil, err
 	}
 
 	iter, err = analyzed.RowIter(ctx)
+	if changesCatalog(parsed) {
+		e.invalidatePlans()
+	}
+
 	if err != nil {
+		if tx != nil {
+			_ = tx.Rollback(ctx)
+		}
 		return nil, nil, err
 	}
 
+	if tx != nil {
+		iter = &transactionIter{RowIter: iter, ctx: ctx, tx: tx}
+	}
+
 	return analyzed.Schema(), iter, nil
 }
 
+// normalize returns the normalized query if its plan can be taken from the
+// plan cache, or nil if it can't.
+func (e *Engine) normalize(ctx *sql.Context, query string) *parse.NormalizedQuery {
+	// The functions of the context may replace the ones of the catalog, so
+	// the plans with them are only valid for the context.
+	if e.PlanCache == nil || ctx.Functions() != nil {
+		return nil
+	}
+
+	return parse.Normalize(ctx, query)
+}
+
+// parse parses the query replacing its placeholders with the given
+// bindings.
+func (e *Engine) parse(
+	ctx *sql.Context,
+	query string,
+	bindings map[string]sql.Expression,
+) (sql.Node, error) {
+	parsed, err := parse.Parse(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(bindings) > 0 {
+		return plan.ApplyBindings(parsed, bindings)
+	}
+
+	return parsed, nil
+}
+
+// analyzeNormalized returns the analyzed node of the given normalized query,
+// whose plan is taken from the plan cache and bound to the literals of the
+// query and the given bindings. If the plan is not in the cache, the query
+// is analyzed as usual and its plan is added to the cache, or marked as not
+// cacheable if it can't be reused.
+func (e *Engine) analyzeNormalized(
+	ctx *sql.Context,
+	query string,
+	normalized *parse.NormalizedQuery,
+	bindings map[string]sql.Expression,
+) (sql.Node, error) {
+	var all = make(map[string]sql.Expression, len(bindings)+len(normalized.Bindings))
+	for name, expr := range bindings {
+		all[name] = expr
+	}
+	for name, expr := range normalized.Bindings {
+		all[name] = expr
+	}
+
+	cached, ok, add := e.PlanCache.lookup(e.Catalog, normalized)
+	if ok && cached != nil {
+		// Binding the placeholders copies all the nodes and expressions
+		// of the plan, so executions don't share them.
+		node, err := plan.ApplyBindings(cached, all)
+		if err != nil {
+			return nil, err
+		}
+		return e.Analyzer.AnalyzeExecution(ctx, node)
+	}
+
+	parsed, err := e.parse(ctx, query, bindings)
+	if err != nil {
+		return nil, err
+	}
+
+	analyzed, err := e.Analyzer.AnalyzePlan(ctx, parsed)
+	if err != nil {
+		return nil, err
+	}
+
+	if !ok {
+		add(e.reusablePlan(ctx, normalized, all, analyzed))
+	}
+
+	return e.Analyzer.AnalyzeExecution(ctx, analyzed)
+}
+
+// reusablePlan returns the plan of the normalized query, with placeholders
+// instead of its literals, or nil if it can't be reused for other literals.
+// Literals can be pushed down to the tables or used to choose indexes, but
+// placeholders can't, so the plan is only reused if it's the same as the
+// given plan of the query once the placeholders are bound to its literals.
+func (e *Engine) reusablePlan(
+	ctx *sql.Context,
+	normalized *parse.NormalizedQuery,
+	bindings map[string]sql.Expression,
+	analyzed sql.Node,
+) sql.Node {
+	parsed, err := normalized.Parse(ctx)
+	if err != nil {
+		return nil
+	}
+
+	node, err := e.Analyzer.AnalyzePlan(ctx, parsed)
+	if err != nil || !isCacheable(node) {
+		return nil
+	}
+
+	bound, err := plan.ApplyBindings(node, bindings)
+	if err != nil || bound.String() != analyzed.String() {
+		return nil
+	}
+
+	return node
+}
+
+// invalidatePlans discards the plans of the plan cache and the prepared
+// queries, which are analyzed again the next time they are executed.
+func (e *Engine) invalidatePlans() {
+	atomic.AddUint64(&e.generation, 1)
+	e.PlanCache.Invalidate()
+}
+
+// changesCatalog returns whether the given statement changes the tables,
+// views, functions or statistics the plans of the queries depend on.
+func changesCatalog(n sql.Node) bool {
+	switch n.(type) {
+	case *plan.CreateTable, *plan.DropTable, *plan.RenameTable,
+		*plan.AddColumn, *plan.DropColumn, *plan.ModifyColumn, *plan.RenameColumn,
+		*plan.CreateView, *plan.DropView, *plan.CreateIndex, *plan.DropIndex,
+		*plan.CreateFunction, *plan.DropFunction, *plan.AnalyzeTable:
+		return true
+	default:
+		return false
+	}
+}
+
+// beginStatement starts a transaction for the given statement if there is no
+// transaction in progress in the session. If autocommit is enabled, the
+// transaction is only for this statement and it's returned, so it can be
+// finished once the statement is done. Such transactions are only started
+// for the statements that change rows, in the databases of their tables.
+// Otherwise, the transaction lasts until it's explicitly committed or rolled
+// back, and no transaction is returned.
+func (e *Engine) beginStatement(ctx *sql.Context, parsed sql.Node) (*sql.Transaction, error) {
+	switch parsed.(type) {
+	case *plan.StartTransaction, *plan.Commit, *plan.Rollback,
+		*plan.CreateSavepoint, *plan.RollbackSavepoint, *plan.ReleaseSavepoint:
+		return nil, nil
+	}
+
+	if tx := ctx.Transaction(); tx != nil {
+		if !tx.Implicit {
+			return nil, nil
+		}
+
+		// The previous statement was not finished, so its changes are
+		// discarded.
+		if err := tx.Rollback(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	if !sql.IsAutocommit(ctx.Session) {
+		_, err := sql.BeginTransaction(ctx, e.Catalog.AllDatabases(), false)
+		return nil, err
+	}
+
+	dbs := e.changedDatabases(ctx, parsed)
+	if len(dbs) == 0 {
+		return nil, nil
+	}
+
+	return sql.BeginTransaction(ctx, dbs, true)
+}
+
+// changedDatabases returns the databases of the tables used by the given
+// statement if it changes rows.
+func (e *Engine) changedDatabases(ctx *sql.Context, parsed sql.Node) sql.Databases {
+	switch parsed.(type) {
+	case *plan.InsertInto, *plan.DeleteFrom, *plan.Update:
+	default:
+		return nil
+	}
+
+	var dbs sql.Databases
+	seen := make(map[string]bool)
+	plan.Inspect(parsed, func(n sql.Node) bool {
+		t, ok := n.(*plan.UnresolvedTable)
+		if !ok {
+			return true
+		}
+
+		name := t.Database
+		if name == "" {
+			name = e.Catalog.CurrentDatabase()
+		}
+
+		db, err := e.Catalog.Database(name)
+		if err == nil && !seen[db.Name()] {
+			seen[db.Name()] = true
+			dbs = append(dbs, db)
+		}
+		return true
+	})
+
+	return dbs
+}
+
+// transactionIter finishes the transaction of a single statement when the
+// rows of the statement are closed. The transaction is committed unless
+// there was any error.
+type transactionIter struct {
+	sql.RowIter
+	ctx *sql.Context
+	tx  *sql.Transaction
+	err error
+}
+
+func (i *transactionIter) Next() (sql.Row, error) {
+	row, err := i.RowIter.Next()
+	if err != nil && err != io.EOF {
+		i.err = err
+	}
+	return row, err
+}
+
+func (i *transactionIter) Close() error {
+	err := i.RowIter.Close()
+	if err != nil || i.err != nil {
+		_ = i.tx.Rollback(i.ctx)
+		return err
+	}
+
+	return i.tx.Commit(i.ctx)
+}
+
 // Async returns true if the query is async. If there are any errors with the
 // query it returns false
 func (e *Engine) Async(ctx *sql.Context, query string) bool {
@@ -201,6 +550,48 @@ func (e *Engine) Async(ctx *sql.Context, query string) bool {
 // AddDatabase adds the given database to the catalog.
 func (e *Engine) AddDatabase(db sql.Database) {
 	e.Catalog.AddDatabase(db)
+	e.invalidatePlans()
+}
+
+// SaveFunctions writes all the functions created with CREATE FUNCTION to the
+// given writer, so they can be created again with LoadFunctions.
+func (e *Engine) SaveFunctions(w io.Writer) error {
+	return e.Catalog.SaveStoredFunctions(w)
+}
+
+// LoadFunctions creates again the functions written with SaveFunctions.
+func (e *Engine) LoadFunctions(ctx *sql.Context, r io.Reader) error {
+	saved, err := sql.ReadSavedFunctions(r)
+	if err != nil {
+		return err
+	}
+
+	for _, fn := range saved {
+		parsed, err := parse.Parse(ctx, fn.Definition)
+		if err != nil {
+			return err
+		}
+
+		create, ok := parsed.(*plan.CreateFunction)
+		if !ok {
+			return parse.ErrUnsupportedSyntax.New(fn.Definition)
+		}
+
+		create.Catalog = e.Catalog
+		create.Function.Created = fn.Created
+
+		iter, err := create.RowIter(ctx)
+		if err != nil {
+			return err
+		}
+
+		if _, err := sql.RowIterToRows(iter); err != nil {
+			return err
+		}
+	}
+
+	e.invalidatePlans()
+	return nil
 }
 
 // Init performs all the initialization requirements for the engine to work.
@@ -1,9 +1,13 @@
 package sqle
 
 import (
+	"context"
 	"fmt"
 	"github.com/src-d/go-mysql-server/memory"
 	"github.com/src-d/go-mysql-server/sql"
+	"github.com/src-d/go-mysql-server/sql/analyzer"
+	"github.com/src-d/go-mysql-server/sql/expression/function/udf"
+	"github.com/src-d/go-mysql-server/sql/plan"
 	"github.com/stretchr/testify/require"
 	"testing"
 	"time"
@@ -130,3 +134,329 @@ func TestGeneric_Agg_Collector_Transpose(t *testing.T) {
 	assertions.Equal(nil, e)
 	assertions.Equal(3, len(rows))
 }
+
+func TestSQuery_ScopedUDFs(t *testing.T) {
+	require := require.New(t)
+	engine := NewDefault()
+	engine.AddDatabase(createTestDatabase())
+	ctx := sql.NewEmptyContext()
+	numFunctions := len(engine.Catalog.FunctionRegistry)
+
+	query := "SELECT <? @{mytable.phone_numbers}.length ?> FROM mytable"
+	for i := 0; i < 3; i++ {
+		_, iter, err := engine.SQuery(ctx, query, defaultDialect)
+		require.NoError(err)
+
+		functions := iter.(*scriptQueryIter).functions
+		require.Len(functions, 1)
+
+		rows, err := sql.RowIterToRows(iter)
+		require.NoError(err)
+		require.Len(rows, 4)
+		require.Len(functions, 0)
+	}
+
+	// The same script is used by all the queries.
+	require.Equal(1, engine.Scripts.Len())
+	require.Len(engine.Catalog.FunctionRegistry, numFunctions)
+	require.Equal(0, engine.NumCustomUdfs)
+
+	_, _, err := engine.Query(ctx, "SELECT _auto_1_udf_(mytable.phone_numbers) FROM mytable")
+	require.True(sql.ErrFunctionNotFound.Is(err))
+}
+
+func TestSQuery_InterruptedUDF(t *testing.T) {
+	require := require.New(t)
+	engine := NewDefault()
+	engine.AddDatabase(createTestDatabase())
+
+	query := "SELECT <?AGG@ 0 # while (true) {} ?> FROM mytable"
+
+	ctx := sql.NewEmptyContext()
+	ctx.Set(udf.TimeoutKey, sql.Int64, int64(50))
+	_, iter, err := engine.SQuery(ctx, query, defaultDialect)
+	require.NoError(err)
+	_, err = sql.RowIterToRows(iter)
+	require.True(udf.ErrScriptTimeout.Is(err))
+	require.NoError(iter.Close())
+
+	cancelCtx, cancel := context.WithCancel(context.Background())
+	ctx = sql.NewContext(cancelCtx)
+	_, iter, err = engine.SQuery(ctx, query, defaultDialect)
+	require.NoError(err)
+
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		cancel()
+	}()
+	_, err = sql.RowIterToRows(iter)
+	require.Equal(context.Canceled, err)
+}
+
+func TestSQuery_ReturnType(t *testing.T) {
+	require := require.New(t)
+	engine := NewDefault()
+	engine.AddDatabase(createTestDatabase())
+	ctx := sql.NewEmptyContext()
+
+	query := "SELECT <?:INT64 @{mytable.phone_numbers}.length ?> AS n FROM mytable ORDER BY n DESC"
+	schema, iter, err := engine.SQuery(ctx, query, defaultDialect)
+	require.NoError(err)
+	require.Equal(sql.Int64, schema[0].Type)
+	rows, err := sql.RowIterToRows(iter)
+	require.NoError(err)
+	require.Equal([]sql.Row{{int64(2)}, {int64(1)}, {int64(0)}, {int64(0)}}, rows)
+
+	query = "SELECT SUM(<?:INT64 @{mytable.phone_numbers}.length ?>) FROM mytable"
+	_, iter, err = engine.SQuery(ctx, query, defaultDialect)
+	require.NoError(err)
+	rows, err = sql.RowIterToRows(iter)
+	require.NoError(err)
+	require.Equal([]sql.Row{{float64(3)}}, rows)
+
+	query = "SELECT <?:INT64 L__@ @{mytable.phone_numbers}.length ?> FROM mytable"
+	schema, iter, err = engine.SQuery(ctx, query, defaultDialect)
+	require.NoError(err)
+	require.Equal(sql.JSON, schema[0].Type)
+	rows, err = sql.RowIterToRows(iter)
+	require.NoError(err)
+	require.Len(rows, 1)
+	require.ElementsMatch([]interface{}{int64(2), int64(1), int64(0), int64(0)}, rows[0][0])
+
+	query = "SELECT <?:INT64 @{mytable.name} ?> FROM mytable"
+	_, iter, err = engine.SQuery(ctx, query, defaultDialect)
+	require.NoError(err)
+	_, err = sql.RowIterToRows(iter)
+	require.True(udf.ErrInvalidReturnValue.Is(err))
+	require.NoError(iter.Close())
+
+	_, _, err = engine.SQuery(ctx, "SELECT <?:FOO 1 ?> FROM mytable", defaultDialect)
+	require.True(udf.ErrUnknownReturnType.Is(err))
+}
+
+func TestSQuery_SandboxScripts(t *testing.T) {
+	require := require.New(t)
+
+	contextType := func(engine *Engine, query string) interface{} {
+		_, iter, err := engine.SQuery(sql.NewEmptyContext(), query, defaultDialect)
+		require.NoError(err)
+		rows, err := sql.RowIterToRows(iter)
+		require.NoError(err)
+		require.NotEmpty(rows)
+		return rows[0][0]
+	}
+
+	engine := NewDefault()
+	engine.AddDatabase(createTestDatabase())
+	require.NoError(engine.RegisterUDF(udf.ScriptUDF{
+		Id:     "context_type",
+		Script: udf.GetScriptInstance(defaultDialect, "typeof $CONTEXT"),
+	}))
+
+	require.Equal("object", contextType(engine, "SELECT <? typeof $CONTEXT ?> FROM mytable"))
+	require.Equal("object", contextType(engine, "SELECT context_type() FROM mytable"))
+
+	// Stored functions are always sandboxed.
+	_, iter, err := engine.Query(
+		sql.NewEmptyContext(),
+		"CREATE FUNCTION stored_context_type() RETURNS TEXT LANGUAGE js AS 'typeof $CONTEXT'",
+	)
+	require.NoError(err)
+	_, err = sql.RowIterToRows(iter)
+	require.NoError(err)
+	require.Equal("undefined", contextType(engine, "SELECT stored_context_type() FROM mytable"))
+
+	catalog := sql.NewCatalog()
+	engine = New(catalog, analyzer.NewDefault(catalog), &Config{SandboxScripts: true})
+	engine.AddDatabase(createTestDatabase())
+	require.NoError(engine.RegisterUDF(udf.ScriptUDF{
+		Id:     "context_type",
+		Script: udf.GetScriptInstance(defaultDialect, "typeof $CONTEXT"),
+	}))
+
+	require.Equal("undefined", contextType(engine, "SELECT <? typeof $CONTEXT ?> FROM mytable"))
+	require.Equal("undefined", contextType(engine, "SELECT context_type() FROM mytable"))
+}
+
+func TestTableFunctions(t *testing.T) {
+	require := require.New(t)
+	engine := NewDefault()
+	engine.AddDatabase(createTestDatabase())
+	ctx := sql.NewEmptyContext()
+
+	query := "SELECT * FROM js_table('[{n: $ARGS[0], s: \"a\"}, {n: $ARGS[0] + 1, s: \"b\"}]', 1) AS t ORDER BY n DESC"
+	schema, iter, err := engine.Query(ctx, query)
+	require.NoError(err)
+	require.Equal(sql.Schema{
+		{Name: "n", Type: sql.Int64, Source: "t", Nullable: true},
+		{Name: "s", Type: sql.Text, Source: "t", Nullable: true},
+	}, schema)
+	rows, err := sql.RowIterToRows(iter)
+	require.NoError(err)
+	require.Equal([]sql.Row{{int64(2), "b"}, {int64(1), "a"}}, rows)
+
+	query = "SELECT t.phone, m.name FROM mytable m JOIN js_table('[[\"John Doe\", 1], [\"Evil Bob\", 2.5]]') AS t(name TEXT, phone FLOAT) ON m.name = t.name ORDER BY t.phone"
+	_, iter, err = engine.Query(ctx, query)
+	require.NoError(err)
+	rows, err = sql.RowIterToRows(iter)
+	require.NoError(err)
+	require.Equal([]sql.Row{{float32(1), "John Doe"}, {float32(1), "John Doe"}, {float32(2.5), "Evil Bob"}}, rows)
+
+	query = "SELECT COUNT(*) FROM <? [1, 2, 3].map(function(x) { return {x: x} }) ?> AS t WHERE t.x > 1"
+	_, iter, err = engine.SQuery(ctx, query, defaultDialect)
+	require.NoError(err)
+	rows, err = sql.RowIterToRows(iter)
+	require.NoError(err)
+	require.Equal([]sql.Row{{int64(2)}}, rows)
+
+	_, _, err = engine.Query(ctx, "SELECT * FROM js_table(CONCAT('[', ']'))")
+	require.True(udf.ErrInvalidScriptArgument.Is(err))
+
+	_, _, err = engine.Query(ctx, "SELECT * FROM js_table('1')")
+	require.True(plan.ErrInvalidTableFunctionResult.Is(err))
+}
+
+func TestSQuery_MacroLexer(t *testing.T) {
+	require := require.New(t)
+	engine := NewDefault()
+	engine.AddDatabase(createTestDatabase())
+	ctx := sql.NewEmptyContext()
+
+	query := `SELECT <? @{mytable.name} + "?>" ?> FROM mytable WHERE name <> '<?xml version="1.0"?>' AND name LIKE 'J%'`
+	_, iter, err := engine.SQuery(ctx, query, defaultDialect)
+	require.NoError(err)
+	rows, err := sql.RowIterToRows(iter)
+	require.NoError(err)
+	require.Equal([]sql.Row{{"John Doe?>"}, {"John Doe?>"}, {"Jane Doe?>"}}, rows)
+
+	_, _, err = engine.SQuery(ctx, "SELECT <?XYZ@ @{mytable.name} ?> FROM mytable", defaultDialect)
+	require.True(udf.ErrUnknownAggregator.Is(err))
+}
+
+func TestSQuery_MultipleTransposed(t *testing.T) {
+	require := require.New(t)
+	engine := NewDefault()
+	engine.AddDatabase(createTestDatabase())
+	ctx := sql.NewEmptyContext()
+
+	query := "SELECT COUNT(*), <?L_T@ @{mytable.name} ?>, <?LFT@ @{mytable.phone_numbers} ?> FROM mytable"
+	_, iter, err := engine.SQuery(ctx, query, defaultDialect)
+	require.NoError(err)
+	rows, err := sql.RowIterToRows(iter)
+	require.NoError(err)
+	require.Equal([]sql.Row{
+		{int64(4), "John Doe", "555-555-555"},
+		{int64(4), "John Doe", "555-666-555"},
+		{int64(4), "Jane Doe", "666-666-666"},
+		{int64(4), "Evil Bob", nil},
+	}, rows)
+
+	ctx.Set(udf.TransposeKey, sql.Text, udf.TransposeCross)
+	_, iter, err = engine.SQuery(ctx, query, defaultDialect)
+	require.NoError(err)
+	rows, err = sql.RowIterToRows(iter)
+	require.NoError(err)
+	require.Len(rows, 12)
+	require.Equal(sql.Row{int64(4), "Jane Doe", "666-666-666"}, rows[8])
+
+	ctx.Set(udf.TransposeKey, sql.Text, "foo")
+	_, iter, err = engine.SQuery(ctx, query, defaultDialect)
+	require.NoError(err)
+	_, err = sql.RowIterToRows(iter)
+	require.True(udf.ErrInvalidTransposeMode.Is(err))
+	require.NoError(iter.Close())
+}
+
+func TestPivot(t *testing.T) {
+	require := require.New(t)
+	engine := NewDefault()
+	engine.AddDatabase(createTestDatabase())
+	ctx := sql.NewEmptyContext()
+
+	sales := `js_table('[{r: "n", m: "jan", a: 1}, {r: "n", m: "feb", a: 2}, {r: "s", m: "jan", a: 3}, {r: "n", m: "jan", a: 4}]') AS s`
+
+	query := "SELECT * FROM " + sales + " PIVOT (SUM(a) FOR m IN ('jan', 'feb' AS february)) AS p ORDER BY r"
+	schema, iter, err := engine.Query(ctx, query)
+	require.NoError(err)
+	require.Equal([]string{"r", "jan", "february"}, []string{schema[0].Name, schema[1].Name, schema[2].Name})
+	rows, err := sql.RowIterToRows(iter)
+	require.NoError(err)
+	require.Equal([]sql.Row{{"n", float64(5), float64(2)}, {"s", float64(3), nil}}, rows)
+
+	query = "SELECT p.r, p.jan_total, p.feb_n FROM " + sales + " PIVOT (SUM(a) AS total, COUNT(*) AS n FOR m IN ('jan', 'feb')) p ORDER BY r"
+	_, iter, err = engine.Query(ctx, query)
+	require.NoError(err)
+	rows, err = sql.RowIterToRows(iter)
+	require.NoError(err)
+	require.Equal([]sql.Row{{"n", float64(5), int64(1)}, {"s", float64(3), int64(0)}}, rows)
+
+	query = "SELECT * FROM " + sales + " UNPIVOT (v FOR col IN (m, r AS 'region')) WHERE a < 3 ORDER BY a, col"
+	_, iter, err = engine.Query(ctx, query)
+	require.NoError(err)
+	rows, err = sql.RowIterToRows(iter)
+	require.NoError(err)
+	require.Equal([]sql.Row{
+		{int64(1), "m", "jan"},
+		{int64(1), "region", "n"},
+		{int64(2), "m", "feb"},
+		{int64(2), "region", "n"},
+	}, rows)
+
+	query = "SELECT u.c, SUM(u.v) FROM (SELECT * FROM " + sales + " PIVOT (SUM(a) FOR m IN ('jan', 'feb'))) AS t UNPIVOT (v FOR c IN (jan, feb)) AS u GROUP BY u.c ORDER BY u.c"
+	_, iter, err = engine.Query(ctx, query)
+	require.NoError(err)
+	rows, err = sql.RowIterToRows(iter)
+	require.NoError(err)
+	require.Equal([]sql.Row{{"feb", float64(2)}, {"jan", float64(8)}}, rows)
+
+	query = "SELECT * FROM js_table('[{k: 1, x: 1, y: null}]') AS s UNPIVOT %s (v FOR c IN (x, y)) AS u"
+	_, iter, err = engine.Query(ctx, fmt.Sprintf(query, "EXCLUDE NULLS"))
+	require.NoError(err)
+	rows, err = sql.RowIterToRows(iter)
+	require.NoError(err)
+	require.Equal([]sql.Row{{int64(1), "x", "1"}}, rows)
+
+	_, iter, err = engine.Query(ctx, fmt.Sprintf(query, "INCLUDE NULLS"))
+	require.NoError(err)
+	rows, err = sql.RowIterToRows(iter)
+	require.NoError(err)
+	require.Equal([]sql.Row{{int64(1), "x", "1"}, {int64(1), "y", nil}}, rows)
+}
+
+func TestScriptTables(t *testing.T) {
+	require := require.New(t)
+	engine := NewDefault()
+	db := createTestDatabase()
+	engine.AddDatabase(db)
+	ctx := sql.NewEmptyContext()
+
+	query := `CREATE TABLE nums (n BIGINT, f TEXT) ENGINE=SCRIPT SCRIPT='
+		$PARTITION == null
+			? {partitions: [1, 2]}
+			: [{n: $PARTITION, f: $FILTERS.join(" AND ")}, {n: $PARTITION * 10, f: $COLUMNS.join(",")}]
+	'`
+	_, iter, err := engine.Query(ctx, query)
+	require.NoError(err)
+	_, err = sql.RowIterToRows(iter)
+	require.NoError(err)
+
+	_, iter, err = engine.Query(ctx, "SELECT n, f FROM nums WHERE n > 1 ORDER BY n")
+	require.NoError(err)
+	rows, err := sql.RowIterToRows(iter)
+	require.NoError(err)
+	require.Equal([]sql.Row{
+		{int64(2), "nums.n > 1"},
+		{int64(10), "n,f"},
+		{int64(20), "n,f"},
+	}, rows)
+
+	db.AddTable("names", udf.NewScriptTable("names", sql.Schema{
+		{Name: "name", Type: sql.Text, Source: "names"},
+	}, udf.GetScriptInstance("expr", `["John Doe", "Evil Bob"]`)))
+
+	_, iter, err = engine.Query(ctx, "SELECT COUNT(*) FROM mytable JOIN names ON mytable.name = names.name")
+	require.NoError(err)
+	rows, err = sql.RowIterToRows(iter)
+	require.NoError(err)
+	require.Equal([]sql.Row{{int64(3)}}, rows)
+}
@@ -1,6 +1,7 @@
 package sqle_test
 
 import (
+	"bytes"
 	"context"
 	"io"
 	"math"
@@ -16,11 +17,13 @@ import (
 	"github.com/src-d/go-mysql-server/memory"
 	"github.com/src-d/go-mysql-server/sql"
 	"github.com/src-d/go-mysql-server/sql/analyzer"
+	"github.com/src-d/go-mysql-server/sql/expression"
 	"github.com/src-d/go-mysql-server/sql/parse"
 	"github.com/src-d/go-mysql-server/sql/plan"
 	"github.com/src-d/go-mysql-server/test"
 
 	"github.com/stretchr/testify/require"
+	"gopkg.in/src-d/go-errors.v1"
 )
 
 var queries = []struct {
@@ -767,6 +770,8 @@ var queries = []struct {
 			{"transaction_isolation", "READ UNCOMMITTED"},
 			{"version", ""},
 			{"version_comment", ""},
+			{"cte_max_recursion_depth", int64(1000)},
+			{"autocommit", int64(1)},
 		},
 	},
 	{
@@ -1380,6 +1385,22 @@ var queries = []struct {
 			{int64(2), int64(3), "first"},
 		},
 	},
+	{
+		"SELECT i, i2, s2 FROM mytable LEFT JOIN othertable ON i = i2 AND s2 <> 'first'",
+		[]sql.Row{
+			{int64(1), int64(1), "third"},
+			{int64(2), int64(2), "second"},
+			{int64(3), nil, nil},
+		},
+	},
+	{
+		"SELECT i, i2, s2 FROM mytable RIGHT JOIN othertable ON i = i2 AND s2 <> 'first'",
+		[]sql.Row{
+			{int64(1), int64(1), "third"},
+			{int64(2), int64(2), "second"},
+			{nil, int64(3), "first"},
+		},
+	},
 	{
 		"SELECT i, i2, s2 FROM mytable LEFT OUTER JOIN othertable ON i = i2 - 1",
 		[]sql.Row{
@@ -1586,6 +1607,141 @@ var queries = []struct {
 			{int64(4)},
 		},
 	},
+	{
+		`SELECT i FROM mytable UNION ALL SELECT i2 FROM othertable`,
+		[]sql.Row{
+			{int64(1)}, {int64(2)}, {int64(3)},
+			{int64(3)}, {int64(2)}, {int64(1)},
+		},
+	},
+	{
+		`SELECT i FROM mytable UNION SELECT i2 FROM othertable`,
+		[]sql.Row{{int64(1)}, {int64(2)}, {int64(3)}},
+	},
+	{
+		`SELECT i FROM mytable UNION DISTINCT SELECT i + 1 FROM mytable ORDER BY i DESC`,
+		[]sql.Row{{int64(4)}, {int64(3)}, {int64(2)}, {int64(1)}},
+	},
+	{
+		`SELECT i, s FROM mytable WHERE i = 1 UNION ALL SELECT i2, s2 FROM othertable ORDER BY i LIMIT 2`,
+		[]sql.Row{{int64(1), "first row"}, {int64(1), "third"}},
+	},
+	{
+		`(SELECT i FROM mytable ORDER BY i DESC LIMIT 1) UNION ALL (SELECT i2 FROM othertable ORDER BY i2 LIMIT 1)`,
+		[]sql.Row{{int64(3)}, {int64(1)}},
+	},
+	{
+		`WITH t AS (SELECT i, s FROM mytable WHERE i > 1) SELECT s FROM t ORDER BY i`,
+		[]sql.Row{{"second row"}, {"third row"}},
+	},
+	{
+		`WITH a AS (SELECT i FROM mytable), b (n) AS (SELECT i + 1 FROM a)
+		SELECT a.i, b.n FROM a INNER JOIN b ON a.i = b.n ORDER BY a.i`,
+		[]sql.Row{{int64(2), int64(2)}, {int64(3), int64(3)}},
+	},
+	{
+		`WITH t AS (SELECT i2 FROM othertable) SELECT i FROM mytable WHERE i > (SELECT MIN(i2) FROM t) ORDER BY i`,
+		[]sql.Row{{int64(2)}, {int64(3)}},
+	},
+	{
+		`WITH RECURSIVE cnt (n) AS (SELECT 1 UNION ALL SELECT n + 1 FROM cnt WHERE n < 5) SELECT n FROM cnt ORDER BY n`,
+		[]sql.Row{{int8(1)}, {int8(2)}, {int8(3)}, {int8(4)}, {int8(5)}},
+	},
+	{
+		`WITH RECURSIVE t (n) AS (
+			SELECT i FROM mytable WHERE i = 1
+			UNION
+			SELECT i2 FROM othertable INNER JOIN t ON i2 = n + 1
+		)
+		SELECT n FROM t ORDER BY n`,
+		[]sql.Row{{int64(1)}, {int64(2)}, {int64(3)}},
+	},
+	{
+		`SELECT t, n, ROW_NUMBER() OVER (PARTITION BY n ORDER BY t) AS rn
+		FROM bigtable WHERE n < 3 ORDER BY n, t`,
+		[]sql.Row{
+			{"a", int64(1), int64(1)},
+			{"g", int64(1), int64(2)},
+			{"k", int64(1), int64(3)},
+			{"h", int64(2), int64(1)},
+			{"l", int64(2), int64(2)},
+			{"s", int64(2), int64(3)},
+		},
+	},
+	{
+		`SELECT t, RANK() OVER (ORDER BY n) AS r, DENSE_RANK() OVER (ORDER BY n) AS dr
+		FROM bigtable WHERE n < 4 ORDER BY t`,
+		[]sql.Row{
+			{"a", int64(1), int64(1)},
+			{"f", int64(7), int64(3)},
+			{"g", int64(1), int64(1)},
+			{"h", int64(4), int64(2)},
+			{"j", int64(7), int64(3)},
+			{"k", int64(1), int64(1)},
+			{"l", int64(4), int64(2)},
+			{"s", int64(4), int64(2)},
+		},
+	},
+	{
+		`SELECT t, NTILE(4) OVER (ORDER BY t) FROM bigtable WHERE n < 3 ORDER BY t`,
+		[]sql.Row{
+			{"a", int64(1)},
+			{"g", int64(1)},
+			{"h", int64(2)},
+			{"k", int64(2)},
+			{"l", int64(3)},
+			{"s", int64(4)},
+		},
+	},
+	{
+		`SELECT i, LAG(i) OVER (ORDER BY i) AS prev, LEAD(i, 1, 0) OVER (ORDER BY i) AS nxt
+		FROM mytable ORDER BY i`,
+		[]sql.Row{
+			{int64(1), nil, int64(2)},
+			{int64(2), int64(1), int64(3)},
+			{int64(3), int64(2), int64(0)},
+		},
+	},
+	{
+		`SELECT i,
+			FIRST_VALUE(s) OVER (ORDER BY i ROWS BETWEEN 1 PRECEDING AND 1 FOLLOWING) AS f,
+			LAST_VALUE(s) OVER (ORDER BY i ROWS BETWEEN 1 PRECEDING AND 1 FOLLOWING) AS l
+		FROM mytable ORDER BY i`,
+		[]sql.Row{
+			{int64(1), "first row", "second row"},
+			{int64(2), "first row", "third row"},
+			{int64(3), "second row", "third row"},
+		},
+	},
+	{
+		`SELECT i,
+			SUM(i) OVER (ORDER BY i) AS running,
+			SUM(i) OVER () AS total,
+			COUNT(*) OVER (ORDER BY i ROWS BETWEEN CURRENT ROW AND UNBOUNDED FOLLOWING) AS remaining
+		FROM mytable ORDER BY i`,
+		[]sql.Row{
+			{int64(1), float64(1), float64(6), int64(3)},
+			{int64(2), float64(3), float64(6), int64(2)},
+			{int64(3), float64(6), float64(6), int64(1)},
+		},
+	},
+	{
+		`SELECT n, COUNT(*) OVER (ORDER BY n RANGE BETWEEN 1 PRECEDING AND 1 FOLLOWING) AS c,
+			MAX(t) OVER (ORDER BY n DESC RANGE 2 PRECEDING) AS m
+		FROM bigtable WHERE n > 3 ORDER BY n`,
+		[]sql.Row{
+			{int64(4), int64(2), "ñ"},
+			{int64(5), int64(3), "z"},
+			{int64(6), int64(3), "x"},
+			{int64(7), int64(3), "v"},
+			{int64(8), int64(3), "v"},
+			{int64(9), int64(2), "b"},
+		},
+	},
+	{
+		`SELECT i, ROW_NUMBER() OVER (ORDER BY i DESC) + 10 AS x FROM mytable ORDER BY i`,
+		[]sql.Row{{int64(1), int64(13)}, {int64(2), int64(12)}, {int64(3), int64(11)}},
+	},
 }
 
 func TestQueries(t *testing.T) {
@@ -1631,6 +1787,14 @@ func TestSessionSelectLimit(t *testing.T) {
 			"SELECT i FROM (SELECT i FROM mytable) t ORDER BY i LIMIT 2",
 			[]sql.Row{{int64(1)}},
 		},
+		{
+			"SELECT i FROM mytable UNION ALL SELECT i2 FROM othertable ORDER BY i",
+			[]sql.Row{{int64(1)}},
+		},
+		{
+			"SELECT i FROM mytable UNION ALL SELECT i2 FROM othertable ORDER BY i LIMIT 3",
+			[]sql.Row{{int64(1)}, {int64(1)}, {int64(2)}},
+		},
 	}
 	e := newEngine(t)
 	t.Run("sql_select_limit", func(t *testing.T) {
@@ -1640,6 +1804,36 @@ func TestSessionSelectLimit(t *testing.T) {
 	})
 }
 
+func TestRecursiveCteDepth(t *testing.T) {
+	require := require.New(t)
+	e := newEngine(t)
+
+	const query = `WITH RECURSIVE cnt (n) AS (SELECT 1 UNION ALL SELECT n + 1 FROM cnt WHERE n < 10) SELECT n FROM cnt`
+
+	ctx := newCtx()
+	ctx.Session.Set(plan.CteMaxRecursionDepthKey, sql.Int64, int64(3))
+	_, iter, err := e.Query(ctx, query)
+	require.NoError(err)
+	_, err = sql.RowIterToRows(iter)
+	require.Error(err)
+	require.True(plan.ErrCteMaxRecursionDepth.Is(err))
+
+	ctx = newCtx()
+	ctx.Session.Set(plan.CteMaxRecursionDepthKey, sql.Int64, int64(9))
+	_, iter, err = e.Query(ctx, query)
+	require.NoError(err)
+	rows, err := sql.RowIterToRows(iter)
+	require.NoError(err)
+	require.Len(rows, 10)
+
+	_, iter, err = e.Query(newCtx(), `WITH RECURSIVE t (n) AS (SELECT 1 UNION ALL SELECT n FROM t) SELECT n FROM t`)
+	require.NoError(err)
+	_, err = sql.RowIterToRows(iter)
+	require.True(plan.ErrCteMaxRecursionDepth.Is(err))
+
+	testQuery(t, e, `WITH RECURSIVE t (n) AS (SELECT 1 UNION SELECT n FROM t) SELECT n FROM t`, []sql.Row{{int8(1)}})
+}
+
 func TestSessionDefaults(t *testing.T) {
 	ctx := newCtx()
 	ctx.Session.Set("auto_increment_increment", sql.Int64, 0)
@@ -1841,6 +2035,246 @@ func TestDescribe(t *testing.T) {
 	})
 }
 
+func TestAggregationPushdown(t *testing.T) {
+	e := newEngine(t)
+	ep := newEngineWithParallelism(t, 2)
+
+	// The memory tables compute the aggregations that are supported, and the
+	// other ones are computed partially in each partition.
+	testQuery(t, ep, `DESCRIBE FORMAT=TREE SELECT COUNT(*), MAX(i) FROM mytable`, []sql.Row{
+		{"GroupBy"},
+		{" ├─ Aggregate(MERGE(COUNT(*)), MERGE(MAX(mytable.i)))"},
+		{" ├─ Grouping()"},
+		{" └─ Exchange(parallelism=2)"},
+		{"     └─ Table(mytable): Projected Aggregated "},
+		{"         ├─ Column(COUNT(*), INT64, nullable=true)"},
+		{"         └─ Column(MAX(mytable.i), INT64, nullable=true)"},
+	})
+
+	testQuery(t, ep, `DESCRIBE FORMAT=TREE SELECT s, AVG(i) FROM mytable WHERE i > 1 GROUP BY s`, []sql.Row{
+		{"GroupBy"},
+		{" ├─ Aggregate(mytable.s, MERGE(AVG(mytable.i)))"},
+		{" ├─ Grouping(mytable.s)"},
+		{" └─ Exchange(parallelism=2)"},
+		{"     └─ PartialGroupBy"},
+		{"         ├─ Aggregations(AVG(mytable.i))"},
+		{"         ├─ Grouping(mytable.s)"},
+		{"         └─ Table(mytable): Projected Filtered "},
+		{"             ├─ Column(s, TEXT, nullable=false)"},
+		{"             └─ Column(i, INT64, nullable=false)"},
+	})
+
+	queries := []struct {
+		query    string
+		expected []sql.Row
+	}{
+		{
+			`SELECT COUNT(*), MAX(i), MIN(s) FROM mytable`,
+			[]sql.Row{{int64(3), int64(3), "first row"}},
+		},
+		{
+			`SELECT i % 2 AS x, SUM(i), COUNT(i) FROM mytable GROUP BY x`,
+			[]sql.Row{{int64(1), float64(4), int64(2)}, {int64(0), float64(2), int64(1)}},
+		},
+		{
+			`SELECT s, AVG(i) FROM mytable WHERE i > 1 GROUP BY s`,
+			[]sql.Row{{"second row", float64(2)}, {"third row", float64(3)}},
+		},
+		{
+			`SELECT COUNT(DISTINCT i % 2) FROM mytable`,
+			[]sql.Row{{int64(2)}},
+		},
+		{
+			`SELECT COUNT(*) FROM mytable WHERE i > 10`,
+			[]sql.Row{{int64(0)}},
+		},
+	}
+
+	for _, tt := range queries {
+		testQuery(t, e, tt.query, tt.expected)
+		testQuery(t, ep, tt.query, tt.expected)
+	}
+}
+
+func TestTopN(t *testing.T) {
+	e := newEngine(t)
+	ep := newEngineWithParallelism(t, 2)
+
+	testQuery(t, e, `DESCRIBE FORMAT=TREE SELECT i FROM mytable ORDER BY i DESC LIMIT 2 OFFSET 1`, []sql.Row{
+		{"TopN(mytable.i DESC; limit=2, offset=1)"},
+		{" └─ Table(mytable): Projected "},
+		{"     └─ Column(i, INT64, nullable=false)"},
+	})
+
+	// The top rows of each partition are computed in parallel.
+	testQuery(t, ep, `DESCRIBE FORMAT=TREE SELECT i FROM mytable ORDER BY i DESC LIMIT 2 OFFSET 1`, []sql.Row{
+		{"TopN(mytable.i DESC; limit=2, offset=1)"},
+		{" └─ Exchange(parallelism=2)"},
+		{"     └─ TopN(mytable.i DESC; limit=3)"},
+		{"         └─ Table(mytable): Projected "},
+		{"             └─ Column(i, INT64, nullable=false)"},
+	})
+
+	// Without sorting, the tables are told how many rows are needed.
+	testQuery(t, ep, `DESCRIBE FORMAT=TREE SELECT i FROM mytable LIMIT 2`, []sql.Row{
+		{"Limit(2)"},
+		{" └─ Exchange(parallelism=2)"},
+		{"     └─ Table(mytable): Projected Limited(2) "},
+		{"         └─ Column(i, INT64, nullable=false)"},
+	})
+
+	queries := []struct {
+		query    string
+		expected []sql.Row
+	}{
+		{
+			`SELECT i FROM mytable ORDER BY i DESC LIMIT 2`,
+			[]sql.Row{{int64(3)}, {int64(2)}},
+		},
+		{
+			`SELECT s, i FROM mytable ORDER BY i LIMIT 2 OFFSET 1`,
+			[]sql.Row{{"second row", int64(2)}, {"third row", int64(3)}},
+		},
+		{
+			`SELECT i FROM mytable ORDER BY i LIMIT 10 OFFSET 3`,
+			nil,
+		},
+		{
+			`SELECT i * 2 AS x FROM mytable ORDER BY x DESC LIMIT 1`,
+			[]sql.Row{{int64(6)}},
+		},
+		{
+			`SELECT COUNT(*) FROM (SELECT i FROM mytable LIMIT 2) t`,
+			[]sql.Row{{int64(2)}},
+		},
+	}
+
+	for _, tt := range queries {
+		testQuery(t, e, tt.query, tt.expected)
+		testQuery(t, ep, tt.query, tt.expected)
+	}
+}
+
+func TestSubqueries(t *testing.T) {
+	e := newEngine(t)
+	ep := newEngineWithParallelism(t, 2)
+
+	testQuery(t, e, `DESCRIBE FORMAT=TREE SELECT i FROM mytable WHERE i NOT IN (SELECT i FROM niltable)`, []sql.Row{
+		{"HashJoin(NullAwareAntiJoin, mytable.i = subquery1.i, build right)"},
+		{" ├─ Table(mytable): Projected "},
+		{" │   └─ Column(i, INT64, nullable=false)"},
+		{" └─ SubqueryAlias(subquery1)"},
+		{"     └─ Table(niltable): Projected "},
+		{"         └─ Column(i, INT64, nullable=true)"},
+	})
+
+	// The conditions using the outer query become the condition of the join.
+	testQuery(t, e, `DESCRIBE FORMAT=TREE SELECT i FROM mytable mt WHERE NOT EXISTS (SELECT * FROM othertable WHERE i2 = mt.i + 1)`, []sql.Row{
+		{"HashJoin(AntiJoin, subquery1.i2 = mytable.i + 1, build right)"},
+		{" ├─ TableAlias(mt)"},
+		{" │   └─ Table(mytable): Projected "},
+		{" │       └─ Column(i, INT64, nullable=false)"},
+		{" └─ SubqueryAlias(subquery1)"},
+		{"     └─ Project(othertable.i2)"},
+		{"         └─ Table(othertable): Projected "},
+		{"             ├─ Column(s2, TEXT, nullable=false)"},
+		{"             └─ Column(i2, INT64, nullable=false)"},
+	})
+
+	queries := []struct {
+		query    string
+		expected []sql.Row
+	}{
+		{
+			`SELECT i FROM mytable WHERE i IN (SELECT i2 FROM othertable WHERE s2 <> 'first')`,
+			[]sql.Row{{int64(1)}, {int64(2)}},
+		},
+		{
+			`SELECT i FROM mytable WHERE i NOT IN (SELECT i2 FROM othertable WHERE s2 = 'first')`,
+			[]sql.Row{{int64(1)}, {int64(2)}},
+		},
+		{
+			`SELECT i FROM mytable WHERE i NOT IN (SELECT i FROM niltable)`,
+			nil,
+		},
+		{
+			`SELECT i FROM niltable WHERE i NOT IN (SELECT i FROM mytable)`,
+			[]sql.Row{{int64(4)}},
+		},
+		{
+			`SELECT i FROM mytable WHERE EXISTS (SELECT * FROM othertable WHERE i2 = i)`,
+			[]sql.Row{{int64(1)}, {int64(2)}, {int64(3)}},
+		},
+		{
+			`SELECT i FROM mytable WHERE EXISTS (SELECT * FROM othertable WHERE i2 = i AND s2 = 'first')`,
+			[]sql.Row{{int64(3)}},
+		},
+		{
+			`SELECT i FROM mytable mt WHERE NOT EXISTS (SELECT * FROM othertable WHERE i2 = mt.i + 1)`,
+			[]sql.Row{{int64(3)}},
+		},
+		{
+			`SELECT i FROM niltable WHERE NOT EXISTS (SELECT * FROM mytable WHERE mytable.i = niltable.i)`,
+			[]sql.Row{{nil}, {int64(4)}, {nil}},
+		},
+		{
+			`SELECT i FROM mytable WHERE EXISTS (SELECT * FROM othertable WHERE s2 = 'fourth')`,
+			nil,
+		},
+		{
+			`SELECT i FROM mytable WHERE i IN (SELECT i2 FROM othertable WHERE s2 <> CONCAT(mytable.s, ''))`,
+			[]sql.Row{{int64(1)}, {int64(2)}, {int64(3)}},
+		},
+		{
+			`SELECT i FROM mytable WHERE i IN (SELECT i2 + 1 FROM othertable WHERE i2 < mytable.i)`,
+			[]sql.Row{{int64(2)}, {int64(3)}},
+		},
+		{
+			`SELECT i FROM mytable WHERE i NOT IN (SELECT i2 FROM othertable WHERE i2 < mytable.i)`,
+			[]sql.Row{{int64(1)}, {int64(2)}, {int64(3)}},
+		},
+		{
+			`SELECT i, (SELECT s2 FROM othertable WHERE i2 = i) AS s2 FROM mytable ORDER BY i`,
+			[]sql.Row{{int64(1), "third"}, {int64(2), "second"}, {int64(3), "first"}},
+		},
+		{
+			`SELECT i FROM mytable WHERE EXISTS (SELECT * FROM othertable WHERE i2 = i AND EXISTS (SELECT * FROM mytable m WHERE m.i = othertable.i2 AND m.i > 1))`,
+			[]sql.Row{{int64(2)}, {int64(3)}},
+		},
+	}
+
+	for _, tt := range queries {
+		testQuery(t, e, tt.query, tt.expected)
+		testQuery(t, ep, tt.query, tt.expected)
+	}
+}
+
+func TestAliasedSelfJoinFilters(t *testing.T) {
+	e := newEngine(t)
+	for _, q := range []string{
+		`CREATE TABLE a (x INTEGER)`,
+		`CREATE TABLE b (y INTEGER)`,
+		`INSERT INTO a (x) VALUES (1), (2), (3)`,
+		`INSERT INTO b (y) VALUES (2), (3), (4)`,
+	} {
+		_, iter, err := e.Query(newCtx(), q)
+		require.NoError(t, err)
+		_, err = sql.RowIterToRows(iter)
+		require.NoError(t, err)
+	}
+
+	// The columns of a table alias are resolved as the columns of its
+	// table, so the filters using them must keep their indexes.
+	testQuery(t, e, `SELECT a.x, b.y, c.y FROM a, b, b c WHERE a.x = c.y`, []sql.Row{
+		{int32(2), int32(2), int32(2)},
+		{int32(2), int32(3), int32(2)},
+		{int32(2), int32(4), int32(2)},
+		{int32(3), int32(2), int32(3)},
+		{int32(3), int32(3), int32(3)},
+		{int32(3), int32(4), int32(3)},
+	})
+}
+
 func TestOrderByColumns(t *testing.T) {
 	require := require.New(t)
 	e := newEngine(t)
@@ -2517,6 +2951,106 @@ func TestCreateTable(t *testing.T) {
 	require.Equal(s, testTable.Schema())
 }
 
+func TestTransactions(t *testing.T) {
+	require := require.New(t)
+	e := newEngine(t)
+
+	session := sql.NewSession("address", "client", "user", 2)
+	newSessionCtx := func() *sql.Context {
+		return sql.NewContext(
+			context.Background(),
+			sql.WithPid(atomic.AddUint64(&pid, 1)),
+			sql.WithSession(session),
+		)
+	}
+
+	query := func(q string, expected []sql.Row) {
+		testQueryWithContext(newSessionCtx(), t, e, q, expected)
+	}
+
+	committed := func(expected ...int64) {
+		var rows = make([]sql.Row, len(expected))
+		for i, v := range expected {
+			rows[i] = sql.Row{v}
+		}
+		testQuery(t, e, "SELECT i FROM mytable ORDER BY i", rows)
+	}
+
+	query("BEGIN", []sql.Row(nil))
+	query("INSERT INTO mytable (i, s) VALUES (4, 'fourth row')", []sql.Row{{int64(1)}})
+	query("SELECT i FROM mytable ORDER BY i", []sql.Row{{int64(1)}, {int64(2)}, {int64(3)}, {int64(4)}})
+	committed(1, 2, 3)
+	query("ROLLBACK", []sql.Row(nil))
+	query("SELECT i FROM mytable ORDER BY i", []sql.Row{{int64(1)}, {int64(2)}, {int64(3)}})
+
+	query("START TRANSACTION", []sql.Row(nil))
+	query("INSERT INTO mytable (i, s) VALUES (4, 'fourth row')", []sql.Row{{int64(1)}})
+	query("DELETE FROM mytable WHERE i = 1", []sql.Row{{int64(1)}})
+	committed(1, 2, 3)
+	query("COMMIT", []sql.Row(nil))
+	committed(2, 3, 4)
+
+	query("BEGIN", []sql.Row(nil))
+	query("INSERT INTO mytable (i, s) VALUES (5, 'fifth row')", []sql.Row{{int64(1)}})
+	query("SAVEPOINT a", []sql.Row(nil))
+	query("INSERT INTO mytable (i, s) VALUES (6, 'sixth row')", []sql.Row{{int64(1)}})
+	query("SAVEPOINT b", []sql.Row(nil))
+	query("DELETE FROM mytable WHERE i = 2", []sql.Row{{int64(1)}})
+	query("SELECT i FROM mytable ORDER BY i", []sql.Row{{int64(3)}, {int64(4)}, {int64(5)}, {int64(6)}})
+	query("ROLLBACK TO SAVEPOINT b", []sql.Row(nil))
+	query("SELECT i FROM mytable ORDER BY i", []sql.Row{{int64(2)}, {int64(3)}, {int64(4)}, {int64(5)}, {int64(6)}})
+	query("ROLLBACK TO a", []sql.Row(nil))
+	query("SELECT i FROM mytable ORDER BY i", []sql.Row{{int64(2)}, {int64(3)}, {int64(4)}, {int64(5)}})
+
+	_, _, err := e.Query(newSessionCtx(), "ROLLBACK TO SAVEPOINT b")
+	require.Error(err)
+	require.True(sql.ErrSavepointNotFound.Is(err))
+
+	query("RELEASE SAVEPOINT a", []sql.Row(nil))
+	_, _, err = e.Query(newSessionCtx(), "ROLLBACK TO SAVEPOINT a")
+	require.Error(err)
+	require.True(sql.ErrSavepointNotFound.Is(err))
+
+	query("COMMIT", []sql.Row(nil))
+	committed(2, 3, 4, 5)
+
+	// Tables created in a transaction are part of it.
+	query("BEGIN", []sql.Row(nil))
+	query("CREATE TABLE t1 (a INTEGER)", []sql.Row(nil))
+	query("INSERT INTO t1 (a) VALUES (1)", []sql.Row{{int64(1)}})
+	testQuery(t, e, "SELECT * FROM t1", []sql.Row(nil))
+	query("ROLLBACK", []sql.Row(nil))
+	query("SELECT * FROM t1", []sql.Row(nil))
+
+	query("SET autocommit = 0", []sql.Row(nil))
+	query("INSERT INTO mytable (i, s) VALUES (6, 'sixth row')", []sql.Row{{int64(1)}})
+	committed(2, 3, 4, 5)
+	query("COMMIT", []sql.Row(nil))
+	committed(2, 3, 4, 5, 6)
+
+	query("INSERT INTO mytable (i, s) VALUES (7, 'seventh row')", []sql.Row{{int64(1)}})
+	committed(2, 3, 4, 5, 6)
+	query("SET autocommit = ON", []sql.Row(nil))
+	committed(2, 3, 4, 5, 6, 7)
+
+	// A failed statement doesn't leave any of its changes with autocommit.
+	_, _, err = e.Query(
+		newSessionCtx(),
+		"INSERT INTO mytable (i, s) VALUES (8, 'eighth row'), (9, NULL)",
+	)
+	require.Error(err)
+	require.Nil(session.Transaction())
+	committed(2, 3, 4, 5, 6, 7)
+
+	// Statements that don't change rows don't start transactions with
+	// autocommit.
+	_, iter, err := e.Query(newSessionCtx(), "SELECT i FROM mytable")
+	require.NoError(err)
+	require.Nil(session.Transaction())
+	_, err = sql.RowIterToRows(iter)
+	require.NoError(err)
+}
+
 func TestDropTable(t *testing.T) {
 	require := require.New(t)
 
@@ -2554,6 +3088,344 @@ func TestDropTable(t *testing.T) {
 	require.Error(err)
 }
 
+func TestAlterTable(t *testing.T) {
+	require := require.New(t)
+	e := newEngine(t)
+
+	testQuery(t, e,
+		"ALTER TABLE mytable ADD COLUMN n INT NOT NULL DEFAULT 10 AFTER i",
+		[]sql.Row(nil),
+	)
+
+	testQuery(t, e,
+		"SELECT * FROM mytable ORDER BY i",
+		[]sql.Row{
+			{int64(1), int32(10), "first row"},
+			{int64(2), int32(10), "second row"},
+			{int64(3), int32(10), "third row"},
+		},
+	)
+
+	testQuery(t, e,
+		"INSERT INTO mytable (i, s) VALUES (4, 'fourth row')",
+		[]sql.Row{{int64(1)}},
+	)
+
+	testQuery(t, e,
+		"ALTER TABLE mytable CHANGE n m BIGINT FIRST",
+		[]sql.Row(nil),
+	)
+
+	testQuery(t, e,
+		"ALTER TABLE mytable RENAME COLUMN s TO t",
+		[]sql.Row(nil),
+	)
+
+	testQuery(t, e,
+		"SELECT m, i, t FROM mytable WHERE i > 2 ORDER BY i",
+		[]sql.Row{
+			{int64(10), int64(3), "third row"},
+			{int64(10), int64(4), "fourth row"},
+		},
+	)
+
+	testQuery(t, e,
+		"ALTER TABLE mytable DROP m",
+		[]sql.Row(nil),
+	)
+
+	testQuery(t, e,
+		"RENAME TABLE mytable TO tmp, othertable TO mytable, tmp TO othertable",
+		[]sql.Row(nil),
+	)
+
+	testQuery(t, e,
+		"ALTER TABLE othertable RENAME TO newtable",
+		[]sql.Row(nil),
+	)
+
+	testQuery(t, e,
+		"SELECT * FROM newtable ORDER BY i",
+		[]sql.Row{
+			{int64(1), "first row"},
+			{int64(2), "second row"},
+			{int64(3), "third row"},
+			{int64(4), "fourth row"},
+		},
+	)
+
+	testQuery(t, e,
+		"SELECT s2, i2 FROM mytable ORDER BY i2",
+		[]sql.Row{
+			{"third", int64(1)},
+			{"second", int64(2)},
+			{"first", int64(3)},
+		},
+	)
+
+	for _, q := range []string{
+		"ALTER TABLE newtable DROP COLUMN z",
+		"ALTER TABLE newtable ADD COLUMN i INT",
+		"ALTER TABLE newtable ADD COLUMN n INT NOT NULL",
+		"ALTER TABLE newtable MODIFY t TEXT NOT NULL AFTER z",
+		"ALTER TABLE missing ADD COLUMN n INT",
+		"RENAME TABLE newtable TO mytable",
+	} {
+		_, _, err := e.Query(newCtx(), q)
+		require.Error(err, q)
+	}
+}
+
+func TestQueryWithBindings(t *testing.T) {
+	require := require.New(t)
+	e := newEngine(t)
+
+	stmt, err := e.PrepareQuery(newCtx(), "SELECT s FROM mytable WHERE i > ? ORDER BY i")
+	require.NoError(err)
+	require.Equal([]string{"v1"}, plan.BindVars(stmt.Node))
+	require.Equal(map[string]sql.Type{"v1": sql.Int64}, plan.BindVarTypes(stmt.Node))
+
+	_, iter, err := e.QueryWithBindings(
+		newCtx(),
+		"SELECT s FROM mytable WHERE i > ? ORDER BY i",
+		map[string]sql.Expression{"v1": expression.NewLiteral(int64(1), sql.Int64)},
+	)
+	require.NoError(err)
+	rows, err := sql.RowIterToRows(iter)
+	require.NoError(err)
+	require.Equal([]sql.Row{{"second row"}, {"third row"}}, rows)
+
+	_, iter, err = e.QueryWithBindings(
+		newCtx(),
+		"INSERT INTO mytable (i, s) VALUES (?, ?)",
+		map[string]sql.Expression{
+			"v1": expression.NewLiteral(int64(4), sql.Int64),
+			"v2": expression.NewLiteral("fourth row", sql.Text),
+		},
+	)
+	require.NoError(err)
+	_, err = sql.RowIterToRows(iter)
+	require.NoError(err)
+
+	testQuery(t, e, "SELECT s FROM mytable WHERE i = 4", []sql.Row{{"fourth row"}})
+
+	_, iter, err = e.Query(newCtx(), "SELECT s FROM mytable WHERE i = ?")
+	require.NoError(err)
+	_, err = sql.RowIterToRows(iter)
+	require.True(expression.ErrUnboundVariable.Is(err))
+}
+
+func TestQueryPrepared(t *testing.T) {
+	require := require.New(t)
+	e := newEngine(t)
+
+	stmt, err := e.PrepareQuery(newCtx(), "SELECT s FROM mytable WHERE i > ? ORDER BY i")
+	require.NoError(err)
+	node := stmt.Node
+
+	queryPrepared := func(v int64) []sql.Row {
+		_, iter, err := e.QueryPrepared(newCtx(), stmt, map[string]sql.Expression{
+			"v1": expression.NewLiteral(v, sql.Int64),
+		})
+		require.NoError(err)
+		rows, err := sql.RowIterToRows(iter)
+		require.NoError(err)
+		return rows
+	}
+
+	require.Equal([]sql.Row{{"second row"}, {"third row"}}, queryPrepared(1))
+	require.Equal([]sql.Row{{"third row"}}, queryPrepared(2))
+	require.True(node == stmt.Node, "plan was analyzed again")
+
+	testQuery(t, e, "ALTER TABLE mytable ADD COLUMN n INT", []sql.Row(nil))
+	require.Equal([]sql.Row{{"third row"}}, queryPrepared(2))
+	require.False(node == stmt.Node, "plan was not analyzed again")
+
+	_, iter, err := e.QueryPrepared(newCtx(), stmt, nil)
+	require.NoError(err)
+	_, err = sql.RowIterToRows(iter)
+	require.True(expression.ErrUnboundVariable.Is(err))
+}
+
+func TestViews(t *testing.T) {
+	require := require.New(t)
+	e := newEngine(t)
+
+	testQuery(t, e,
+		"CREATE VIEW myview AS SELECT i, s FROM mytable WHERE i > 1",
+		[]sql.Row(nil),
+	)
+
+	testQuery(t, e,
+		"CREATE VIEW myview2 (a, b) AS SELECT i, s FROM myview WHERE i < 3",
+		[]sql.Row(nil),
+	)
+
+	testQuery(t, e,
+		"SELECT * FROM myview ORDER BY i",
+		[]sql.Row{{int64(2), "second row"}, {int64(3), "third row"}},
+	)
+
+	testQuery(t, e,
+		"SELECT b FROM myview2 WHERE a = 2",
+		[]sql.Row{{"second row"}},
+	)
+
+	testQuery(t, e,
+		"SELECT v.i, s2 FROM myview v INNER JOIN othertable ON v.i = i2 ORDER BY v.i",
+		[]sql.Row{{int64(2), "second"}, {int64(3), "first"}},
+	)
+
+	testQuery(t, e,
+		"SELECT i FROM mytable WHERE i NOT IN (SELECT i FROM myview)",
+		[]sql.Row{{int64(1)}},
+	)
+
+	testQuery(t, e,
+		"SHOW FULL TABLES LIKE 'my%'",
+		[]sql.Row{{"mytable", "BASE TABLE"}, {"myview", "VIEW"}, {"myview2", "VIEW"}},
+	)
+
+	testQuery(t, e,
+		"SHOW CREATE VIEW myview2",
+		[]sql.Row{{
+			"myview2",
+			"CREATE VIEW `myview2` (`a`, `b`) AS SELECT i, s FROM myview WHERE i < 3",
+			"utf8mb4",
+			"utf8_bin",
+		}},
+	)
+
+	testQuery(t, e,
+		"SELECT table_name, view_definition FROM information_schema.views",
+		[]sql.Row{
+			{"myview", "SELECT i, s FROM mytable WHERE i > 1"},
+			{"myview2", "SELECT i, s FROM myview WHERE i < 3"},
+		},
+	)
+
+	testQuery(t, e,
+		"SELECT table_name FROM information_schema.tables WHERE table_type = 'VIEW'",
+		[]sql.Row{{"myview"}, {"myview2"}},
+	)
+
+	_, _, err := e.Query(newCtx(), "CREATE VIEW myview AS SELECT 1")
+	require.Error(err)
+	require.True(sql.ErrViewAlreadyExists.Is(err))
+
+	_, _, err = e.Query(newCtx(), "CREATE VIEW mytable AS SELECT 1")
+	require.Error(err)
+	require.True(sql.ErrTableAlreadyExists.Is(err))
+
+	_, _, err = e.Query(newCtx(), "CREATE OR REPLACE VIEW myview AS SELECT * FROM myview2")
+	require.Error(err)
+	require.True(analyzer.ErrViewRecursion.Is(err))
+
+	_, _, err = e.Query(newCtx(), "CREATE VIEW myview3 AS SELECT * FROM not_exist")
+	require.Error(err)
+	require.True(sql.ErrTableNotFound.Is(err))
+
+	testQuery(t, e,
+		"CREATE OR REPLACE VIEW myview AS SELECT i, s FROM mytable WHERE i = 1",
+		[]sql.Row(nil),
+	)
+
+	testQuery(t, e,
+		"SELECT * FROM myview",
+		[]sql.Row{{int64(1), "first row"}},
+	)
+
+	// The tables of a view are always the ones of its database.
+	testQuery(t, e, "USE foo", []sql.Row(nil))
+	testQuery(t, e,
+		"SELECT * FROM mydb.myview",
+		[]sql.Row{{int64(1), "first row"}},
+	)
+	testQuery(t, e, "USE mydb", []sql.Row(nil))
+
+	_, _, err = e.Query(newCtx(), "DROP VIEW myview, not_exist")
+	require.Error(err)
+	require.True(sql.ErrViewNotFound.Is(err))
+
+	testQuery(t, e, "DROP VIEW IF EXISTS myview, not_exist", []sql.Row(nil))
+
+	testQuery(t, e,
+		"SHOW TABLES LIKE 'my%'",
+		[]sql.Row{{"mytable"}, {"myview2"}},
+	)
+
+	_, _, err = e.Query(newCtx(), "SELECT * FROM myview")
+	require.Error(err)
+	require.True(sql.ErrTableNotFound.Is(err))
+}
+
+func TestStoredFunctions(t *testing.T) {
+	require := require.New(t)
+
+	e := newEngine(t)
+
+	testQuery(t, e,
+		"CREATE FUNCTION shout(str, times INT) RETURNS TEXT LANGUAGE js AS 'str + Array(times + 1).join(\"!\")'",
+		[]sql.Row(nil),
+	)
+
+	testQuery(t, e,
+		"CREATE FUNCTION half(n) RETURNS DOUBLE LANGUAGE expr AS 'n / 2.0'",
+		[]sql.Row(nil),
+	)
+
+	testQuery(t, e,
+		"SELECT shout(s, i), half(i) FROM mytable ORDER BY i",
+		[]sql.Row{
+			{"first row!", 0.5},
+			{"second row!!", float64(1)},
+			{"third row!!!", 1.5},
+		},
+	)
+
+	_, iter, err := e.Query(newCtx(), "SHOW FUNCTION STATUS")
+	require.NoError(err)
+	rows, err := sql.RowIterToRows(iter)
+	require.NoError(err)
+	require.Len(rows, 2)
+	require.Equal("half", rows[0][1])
+	require.Equal("shout", rows[1][1])
+
+	_, _, err = e.Query(newCtx(), "CREATE FUNCTION half(n) RETURNS INT LANGUAGE js AS 'n'")
+	require.Error(err)
+	require.True(sql.ErrStoredFunctionAlreadyExists.Is(err))
+
+	_, _, err = e.Query(newCtx(), "CREATE FUNCTION substring(n) RETURNS INT LANGUAGE js AS 'n'")
+	require.Error(err)
+	require.True(sql.ErrFunctionAlreadyRegistered.Is(err))
+
+	_, _, err = e.Query(newCtx(), "SELECT half(1, 2)")
+	require.Error(err)
+	require.True(sql.ErrInvalidArgumentNumber.Is(err))
+
+	var buf bytes.Buffer
+	require.NoError(e.SaveFunctions(&buf))
+
+	_, _, err = e.Query(newCtx(), "DROP FUNCTION half, not_exist")
+	require.Error(err)
+	require.True(sql.ErrStoredFunctionNotFound.Is(err))
+
+	testQuery(t, e, "DROP FUNCTION IF EXISTS half, shout, not_exist", []sql.Row(nil))
+
+	_, _, err = e.Query(newCtx(), "SELECT half(1)")
+	require.Error(err)
+	require.True(sql.ErrFunctionNotFound.Is(err))
+
+	// Functions can be created again in another engine.
+	e = newEngine(t)
+	require.NoError(e.LoadFunctions(newCtx(), &buf))
+
+	testQuery(t, e,
+		"SELECT shout('hey', 2), half(3)",
+		[]sql.Row{{"hey!!", 1.5}},
+	)
+}
+
 func TestNaturalJoin(t *testing.T) {
 	require := require.New(t)
 
@@ -3101,8 +3973,7 @@ func TestTracing(t *testing.T) {
 
 	spans := tracer.Spans
 	var expectedSpans = []string{
-		"plan.Limit",
-		"plan.Sort",
+		"plan.TopN",
 		"plan.Distinct",
 		"plan.Project",
 		"plan.ResolvedTable",
@@ -3155,6 +4026,24 @@ func TestReadOnly(t *testing.T) {
 	_, _, err = e.Query(newCtx(), `INSERT INTO mytable (i, s) VALUES(42, 'yolo')`)
 	require.Error(err)
 	require.True(auth.ErrNotAuthorized.Is(err))
+
+	writeQueries := []string{
+		`ALTER TABLE mytable ADD COLUMN n INT`,
+		`ALTER TABLE mytable DROP COLUMN s`,
+		`ALTER TABLE mytable MODIFY s TEXT NOT NULL`,
+		`ALTER TABLE mytable RENAME COLUMN s TO t`,
+		`ALTER TABLE mytable RENAME TO foo`,
+		`RENAME TABLE mytable TO foo`,
+		`CREATE VIEW myview AS SELECT i FROM mytable`,
+		`DROP VIEW IF EXISTS myview`,
+		`ANALYZE TABLE mytable`,
+	}
+
+	for _, q := range writeQueries {
+		_, _, err = e.Query(newCtx(), q)
+		require.Error(err, q)
+		require.True(auth.ErrNotAuthorized.Is(err), q)
+	}
 }
 
 func TestSessionVariables(t *testing.T) {
@@ -3211,6 +4100,43 @@ func TestNestedAliases(t *testing.T) {
 	require.True(analyzer.ErrMisusedAlias.Is(err))
 }
 
+func TestWindowFunctionsErrors(t *testing.T) {
+	testCases := []struct {
+		query string
+		err   *errors.Kind
+	}{
+		{
+			`SELECT i FROM mytable WHERE ROW_NUMBER() OVER (ORDER BY i) > 1`,
+			analyzer.ErrWindowInvalidUse,
+		},
+		{
+			`SELECT ROW_NUMBER() FROM mytable`,
+			analyzer.ErrWindowFunctionWithoutOver,
+		},
+		{
+			`SELECT i, SUM(i) OVER () FROM mytable GROUP BY i`,
+			parse.ErrUnsupportedFeature,
+		},
+		{
+			`SELECT SUM(i) OVER (ROWS BETWEEN 1 FOLLOWING AND CURRENT ROW) FROM mytable`,
+			plan.ErrInvalidWindowFrame,
+		},
+	}
+
+	e := newEngine(t)
+	for _, tt := range testCases {
+		t.Run(tt.query, func(t *testing.T) {
+			require := require.New(t)
+			_, iter, err := e.Query(newCtx(), tt.query)
+			if err == nil {
+				_, err = sql.RowIterToRows(iter)
+			}
+			require.Error(err)
+			require.True(tt.err.Is(err), "unexpected error: %s", err)
+		})
+	}
+}
+
 func TestUse(t *testing.T) {
 	require := require.New(t)
 	e := newEngine(t)
@@ -3264,6 +4190,60 @@ func TestLocks(t *testing.T) {
 	require.Equal(1, t2.unlocks)
 }
 
+func TestAnalyzeTable(t *testing.T) {
+	e := newEngine(t)
+
+	join := `SELECT mytable.s, othertable.s2, bigtable.t
+		FROM bigtable
+		INNER JOIN mytable ON bigtable.n = mytable.i
+		INNER JOIN othertable ON mytable.i = othertable.i2
+		WHERE bigtable.n < 3`
+	expected := []sql.Row{
+		{"first row", "third", "a"},
+		{"first row", "third", "g"},
+		{"first row", "third", "k"},
+		{"second row", "second", "s"},
+		{"second row", "second", "h"},
+		{"second row", "second", "l"},
+	}
+	testQuery(t, e, join, expected)
+
+	testQuery(t, e, "ANALYZE TABLE bigtable, mytable, othertable, foo.other_table", []sql.Row{
+		{"mydb.bigtable", "analyze", "status", "OK"},
+		{"mydb.mytable", "analyze", "status", "OK"},
+		{"mydb.othertable", "analyze", "status", "OK"},
+		{"foo.other_table", "analyze", "status", "OK"},
+	})
+
+	// The joins are reordered using the statistics, but the result is the
+	// same.
+	testQuery(t, e, join, expected)
+
+	testQuery(
+		t, e,
+		`SELECT table_name, column_name FROM information_schema.column_statistics
+		WHERE schema_name = 'mydb' ORDER BY table_name, column_name`,
+		[]sql.Row{
+			{"bigtable", "n"},
+			{"bigtable", "t"},
+			{"mytable", "i"},
+			{"mytable", "s"},
+			{"othertable", "i2"},
+			{"othertable", "s2"},
+		},
+	)
+
+	testQuery(
+		t, e,
+		`SELECT table_name, table_rows FROM information_schema.tables
+		WHERE table_schema = 'mydb' AND table_name IN ('bigtable', 'floattable')`,
+		[]sql.Row{
+			{"bigtable", uint64(14)},
+			{"floattable", nil},
+		},
+	)
+}
+
 func TestDescribeNoPruneColumns(t *testing.T) {
 	require := require.New(t)
 	ctx := newCtx()
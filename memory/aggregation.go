@@ -0,0 +1,138 @@
+package memory
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/src-d/go-mysql-server/sql"
+	"github.com/src-d/go-mysql-server/sql/expression/function/aggregation"
+)
+
+var _ sql.AggregatableTable = (*Table)(nil)
+
+// HandledAggregations implements the sql.AggregatableTable interface. Like a
+// storage backend that computes them natively, the table can only compute
+// COUNT, SUM, MIN and MAX of its own columns.
+func (t *Table) HandledAggregations(
+	grouping []sql.Expression,
+	aggregations []sql.Aggregation,
+) []sql.Aggregation {
+	if t.aggregated() {
+		return nil
+	}
+
+	for _, e := range grouping {
+		if !t.hasOnlyOwnFields(e) {
+			return nil
+		}
+	}
+
+	var handled []sql.Aggregation
+	for _, a := range aggregations {
+		switch a.(type) {
+		case *aggregation.Count, *aggregation.Sum, *aggregation.Min, *aggregation.Max:
+			if t.hasOnlyOwnFields(a) {
+				handled = append(handled, a)
+			}
+		}
+	}
+
+	return handled
+}
+
+// WithAggregation implements the sql.AggregatableTable interface.
+func (t *Table) WithAggregation(grouping []sql.Expression, aggregations []sql.Aggregation) sql.Table {
+	if len(grouping) == 0 && len(aggregations) == 0 {
+		return t
+	}
+
+	nt := *t
+	nt.grouping = grouping
+	nt.aggregations = aggregations
+	nt.schema = sql.PartialAggregationSchema(t.name, grouping, aggregations)
+	return &nt
+}
+
+// Grouping implements the sql.AggregatableTable interface.
+func (t *Table) Grouping() []sql.Expression {
+	return t.grouping
+}
+
+// Aggregations implements the sql.AggregatableTable interface.
+func (t *Table) Aggregations() []sql.Aggregation {
+	return t.aggregations
+}
+
+func (t *Table) aggregated() bool {
+	return len(t.grouping) > 0 || len(t.aggregations) > 0
+}
+
+// aggregateRows returns the rows of the groups of the rows of the iterator,
+// which have the values of the grouping expressions followed by the buffers
+// of the aggregations.
+func aggregateRows(
+	ctx *sql.Context,
+	iter sql.RowIter,
+	grouping []sql.Expression,
+	aggregations []sql.Aggregation,
+) (sql.RowIter, error) {
+	type group struct {
+		values  sql.Row
+		buffers []sql.Row
+	}
+
+	var groups []*group
+	var byKey = make(map[string]*group)
+	for {
+		row, err := iter.Next()
+		if err == io.EOF {
+			break
+		}
+
+		if err != nil {
+			_ = iter.Close()
+			return nil, err
+		}
+
+		var values = make(sql.Row, len(grouping))
+		for i, e := range grouping {
+			values[i], err = e.Eval(ctx, row)
+			if err != nil {
+				_ = iter.Close()
+				return nil, err
+			}
+		}
+
+		key := fmt.Sprintf("%#v", values)
+		g, ok := byKey[key]
+		if !ok {
+			g = &group{values: values, buffers: make([]sql.Row, len(aggregations))}
+			for i, a := range aggregations {
+				g.buffers[i] = a.NewBuffer()
+			}
+			byKey[key] = g
+			groups = append(groups, g)
+		}
+
+		for i, a := range aggregations {
+			if err := a.Update(ctx, g.buffers[i], row); err != nil {
+				_ = iter.Close()
+				return nil, err
+			}
+		}
+	}
+
+	if err := iter.Close(); err != nil {
+		return nil, err
+	}
+
+	var rows = make([]sql.Row, len(groups))
+	for i, g := range groups {
+		rows[i] = append(sql.Row{}, g.values...)
+		for _, b := range g.buffers {
+			rows[i] = append(rows[i], b...)
+		}
+	}
+
+	return sql.RowsToRowIter(rows...), nil
+}
@@ -0,0 +1,65 @@
+package memory
+
+import (
+	"testing"
+
+	"github.com/src-d/go-mysql-server/sql"
+	"github.com/src-d/go-mysql-server/sql/expression"
+	"github.com/src-d/go-mysql-server/sql/expression/function/aggregation"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAggregated(t *testing.T) {
+	require := require.New(t)
+	ctx := sql.NewEmptyContext()
+
+	table := NewPartitionedTable("foo", sql.Schema{
+		{Name: "a", Type: sql.Text, Source: "foo"},
+		{Name: "b", Type: sql.Int64, Source: "foo", Nullable: true},
+	}, 2)
+
+	for _, row := range []sql.Row{
+		sql.NewRow("x", int64(1)),
+		sql.NewRow("y", int64(2)),
+		sql.NewRow("x", int64(3)),
+		sql.NewRow("x", nil),
+		sql.NewRow("y", int64(5)),
+	} {
+		require.NoError(table.Insert(ctx, row))
+	}
+
+	a := expression.NewGetFieldWithTable(0, sql.Text, "foo", "a", false)
+	b := expression.NewGetFieldWithTable(1, sql.Int64, "foo", "b", true)
+	count := aggregation.NewCount(b)
+	sum := aggregation.NewSum(b)
+	avg := aggregation.NewAvg(b)
+
+	grouping := []sql.Expression{a}
+	require.Equal(
+		[]sql.Aggregation{count, sum},
+		table.HandledAggregations(grouping, []sql.Aggregation{count, avg, sum}),
+	)
+
+	other := expression.NewGetFieldWithTable(0, sql.Text, "bar", "a", false)
+	require.Empty(table.HandledAggregations([]sql.Expression{other}, []sql.Aggregation{count}))
+
+	aggregated := table.WithAggregation(grouping, []sql.Aggregation{count, sum}).(*Table)
+	require.Equal(grouping, aggregated.Grouping())
+	require.Equal([]sql.Aggregation{count, sum}, aggregated.Aggregations())
+	require.Equal(sql.Schema{
+		{Name: "a", Type: sql.Text, Source: "foo"},
+		{Name: "COUNT(foo.b)", Type: sql.Int64, Source: "foo", Nullable: true},
+		{Name: "SUM(foo.b)", Type: sql.Float64, Source: "foo", Nullable: true},
+	}, aggregated.Schema())
+	require.Empty(aggregated.HandledAggregations(grouping, []sql.Aggregation{count}))
+
+	// The rows of each partition are aggregated separately.
+	require.ElementsMatch([]sql.Row{
+		{"x", int64(2), float64(4)},
+		{"y", int64(1), float64(2)},
+		{"x", int64(0), nil},
+		{"y", int64(1), float64(5)},
+	}, testFlatRows(t, aggregated))
+
+	require.Equal(table, table.WithAggregation(nil, nil))
+}
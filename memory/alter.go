@@ -0,0 +1,310 @@
+package memory
+
+import (
+	"strings"
+
+	"github.com/src-d/go-mysql-server/sql"
+)
+
+var _ sql.TableAlterer = (*Table)(nil)
+
+// AddColumn implements the sql.TableAlterer interface.
+func (t *Table) AddColumn(ctx *sql.Context, column *sql.Column, order *sql.ColumnOrder) error {
+	if t.columnIndex(column.Name) >= 0 {
+		return sql.ErrColumnAlreadyExists.New(t.name, column.Name)
+	}
+
+	value, err := columnDefault(column)
+	if err != nil {
+		return err
+	}
+
+	idx, err := columnPosition(t.name, t.schema, order, len(t.schema))
+	if err != nil {
+		return err
+	}
+
+	col := *column
+	col.Source = t.name
+	schema := insertColumn(t.schema, idx, &col)
+
+	err = t.rewriteRows(func(row sql.Row) (sql.Row, error) {
+		if value == nil && !col.Nullable {
+			return nil, sql.ErrInvalidNullValue.New(col.Name)
+		}
+		return insertValue(row, idx, value), nil
+	})
+	if err != nil {
+		return err
+	}
+
+	t.schema = schema
+	return nil
+}
+
+// DropColumn implements the sql.TableAlterer interface.
+func (t *Table) DropColumn(ctx *sql.Context, name string) error {
+	idx := t.columnIndex(name)
+	if idx < 0 {
+		return sql.ErrTableColumnNotFound.New(t.name, name)
+	}
+
+	if len(t.schema) == 1 {
+		return sql.ErrDropAllColumns.New(t.name)
+	}
+
+	err := t.rewriteRows(func(row sql.Row) (sql.Row, error) {
+		return removeValue(row, idx), nil
+	})
+	if err != nil {
+		return err
+	}
+
+	t.schema = removeColumn(t.schema, idx)
+	return nil
+}
+
+// ModifyColumn implements the sql.TableAlterer interface.
+func (t *Table) ModifyColumn(
+	ctx *sql.Context,
+	name string,
+	column *sql.Column,
+	order *sql.ColumnOrder,
+) error {
+	idx := t.columnIndex(name)
+	if idx < 0 {
+		return sql.ErrTableColumnNotFound.New(t.name, name)
+	}
+
+	if i := t.columnIndex(column.Name); i >= 0 && i != idx {
+		return sql.ErrColumnAlreadyExists.New(t.name, column.Name)
+	}
+
+	if _, err := columnDefault(column); err != nil {
+		return err
+	}
+
+	// The position of the column is relative to the rest of the columns.
+	schema := removeColumn(t.schema, idx)
+	newIdx, err := columnPosition(t.name, schema, order, idx)
+	if err != nil {
+		return err
+	}
+
+	col := *column
+	col.Source = t.name
+	schema = insertColumn(schema, newIdx, &col)
+
+	err = t.rewriteRows(func(row sql.Row) (sql.Row, error) {
+		value := row[idx]
+		if value == nil {
+			if !col.Nullable {
+				return nil, sql.ErrInvalidNullValue.New(col.Name)
+			}
+		} else {
+			var err error
+			value, err = col.Type.Convert(value)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		return insertValue(removeValue(row, idx), newIdx, value), nil
+	})
+	if err != nil {
+		return err
+	}
+
+	t.schema = schema
+	return nil
+}
+
+// RenameColumn implements the sql.TableAlterer interface.
+func (t *Table) RenameColumn(ctx *sql.Context, oldName, newName string) error {
+	idx := t.columnIndex(oldName)
+	if idx < 0 {
+		return sql.ErrTableColumnNotFound.New(t.name, oldName)
+	}
+
+	if i := t.columnIndex(newName); i >= 0 && i != idx {
+		return sql.ErrColumnAlreadyExists.New(t.name, newName)
+	}
+
+	col := *t.schema[idx]
+	col.Name = newName
+	schema := make(sql.Schema, len(t.schema))
+	copy(schema, t.schema)
+	schema[idx] = &col
+
+	t.schema = schema
+	return nil
+}
+
+// rename changes the name of the table and the source of its columns.
+func (t *Table) rename(name string) {
+	schema := make(sql.Schema, len(t.schema))
+	for i, c := range t.schema {
+		col := *c
+		col.Source = name
+		schema[i] = &col
+	}
+
+	t.name = name
+	t.schema = schema
+}
+
+func (t *Table) columnIndex(name string) int {
+	for i, col := range t.schema {
+		if strings.EqualFold(col.Name, name) {
+			return i
+		}
+	}
+	return -1
+}
+
+// rewriteRows replaces all the rows of the table, including the ones of the
+// transactions in progress, with the result of the given function. If the
+// function fails for any row, no row is replaced.
+func (t *Table) rewriteRows(fn func(sql.Row) (sql.Row, error)) error {
+	t.txs.mu.Lock()
+	defer t.txs.mu.Unlock()
+
+	rewriteSlice := func(rows []sql.Row) ([]sql.Row, error) {
+		newRows := make([]sql.Row, len(rows))
+		for i, row := range rows {
+			var err error
+			if newRows[i], err = fn(row); err != nil {
+				return nil, err
+			}
+		}
+		return newRows, nil
+	}
+
+	rewrite := func(partitions map[string][]sql.Row) (map[string][]sql.Row, error) {
+		result := make(map[string][]sql.Row, len(partitions))
+		for key, rows := range partitions {
+			var err error
+			if result[key], err = rewriteSlice(rows); err != nil {
+				return nil, err
+			}
+		}
+		return result, nil
+	}
+
+	partitions, err := rewrite(t.partitions)
+	if err != nil {
+		return err
+	}
+
+	var txs = make(map[*sql.Transaction]*transaction, len(t.txs.txs))
+	for handle, tx := range t.txs.txs {
+		r := &transaction{
+			base:       make(map[string][]sql.Row, len(tx.base)),
+			owned:      make(map[string]bool),
+			changes:    make(map[string][]rowChange, len(tx.changes)),
+			savepoints: make(map[string]*savepoint, len(tx.savepoints)),
+		}
+
+		// The committed partitions that did not change since the
+		// transaction started are still its base, so they can be replaced
+		// on commit.
+		for key, rows := range tx.base {
+			if sameRows(rows, t.partitions[key]) {
+				r.base[key] = partitions[key]
+			} else if r.base[key], err = rewriteSlice(rows); err != nil {
+				return err
+			}
+		}
+
+		if r.partitions, err = rewrite(tx.partitions); err != nil {
+			return err
+		}
+
+		for key, changes := range tx.changes {
+			newChanges := make([]rowChange, len(changes))
+			for i, c := range changes {
+				if c.old != nil {
+					if newChanges[i].old, err = fn(c.old); err != nil {
+						return err
+					}
+				}
+				if c.new != nil {
+					if newChanges[i].new, err = fn(c.new); err != nil {
+						return err
+					}
+				}
+			}
+			r.changes[key] = newChanges
+		}
+
+		for name, sp := range tx.savepoints {
+			newSp := &savepoint{changes: sp.changes}
+			if newSp.partitions, err = rewrite(sp.partitions); err != nil {
+				return err
+			}
+			r.savepoints[name] = newSp
+		}
+		txs[handle] = r
+	}
+
+	// The partitions are replaced in the existing map because it's shared
+	// with all the copies of the table.
+	for key, rows := range partitions {
+		t.partitions[key] = rows
+	}
+	t.txs.txs = txs
+
+	return nil
+}
+
+func columnDefault(column *sql.Column) (interface{}, error) {
+	if column.Default == nil {
+		return nil, nil
+	}
+	return column.Type.Convert(column.Default)
+}
+
+// columnPosition returns the index a column must have in the given schema
+// according to the given order, or def if there is no order.
+func columnPosition(table string, schema sql.Schema, order *sql.ColumnOrder, def int) (int, error) {
+	switch {
+	case order == nil:
+		return def, nil
+	case order.First:
+		return 0, nil
+	}
+
+	for i, col := range schema {
+		if strings.EqualFold(col.Name, order.AfterColumn) {
+			return i + 1, nil
+		}
+	}
+
+	return -1, sql.ErrTableColumnNotFound.New(table, order.AfterColumn)
+}
+
+func insertColumn(schema sql.Schema, idx int, column *sql.Column) sql.Schema {
+	result := make(sql.Schema, 0, len(schema)+1)
+	result = append(result, schema[:idx]...)
+	result = append(result, column)
+	return append(result, schema[idx:]...)
+}
+
+func removeColumn(schema sql.Schema, idx int) sql.Schema {
+	result := make(sql.Schema, 0, len(schema)-1)
+	result = append(result, schema[:idx]...)
+	return append(result, schema[idx+1:]...)
+}
+
+func insertValue(row sql.Row, idx int, value interface{}) sql.Row {
+	result := make(sql.Row, 0, len(row)+1)
+	result = append(result, row[:idx]...)
+	result = append(result, value)
+	return append(result, row[idx:]...)
+}
+
+func removeValue(row sql.Row, idx int) sql.Row {
+	result := make(sql.Row, 0, len(row)-1)
+	result = append(result, row[:idx]...)
+	return append(result, row[idx+1:]...)
+}
@@ -0,0 +1,160 @@
+package memory
+
+import (
+	"context"
+	"testing"
+
+	"github.com/src-d/go-mysql-server/sql"
+	"github.com/stretchr/testify/require"
+)
+
+func newAlterTestTable(t *testing.T) *Table {
+	t.Helper()
+	table := NewPartitionedTable("t", sql.Schema{
+		{Name: "a", Type: sql.Int64, Source: "t"},
+		{Name: "b", Type: sql.Text, Source: "t", Nullable: true},
+	}, 2)
+
+	ctx := sql.NewEmptyContext()
+	require.NoError(t, table.Insert(ctx, sql.NewRow(int64(1), "one")))
+	require.NoError(t, table.Insert(ctx, sql.NewRow(int64(2), nil)))
+	return table
+}
+
+func TestAddColumn(t *testing.T) {
+	require := require.New(t)
+	ctx := sql.NewEmptyContext()
+
+	table := newAlterTestTable(t)
+	err := table.AddColumn(ctx, &sql.Column{Name: "c", Type: sql.Int32, Default: int64(7)}, nil)
+	require.NoError(err)
+	require.Equal([]string{"a", "b", "c"}, columnNames(table.Schema()))
+	require.Equal("t", table.Schema()[2].Source)
+	require.ElementsMatch([]sql.Row{
+		{int64(1), "one", int32(7)},
+		{int64(2), nil, int32(7)},
+	}, testFlatRows(t, table))
+
+	err = table.AddColumn(ctx, &sql.Column{Name: "d", Type: sql.Text, Nullable: true}, &sql.ColumnOrder{First: true})
+	require.NoError(err)
+	require.Equal([]string{"d", "a", "b", "c"}, columnNames(table.Schema()))
+
+	err = table.AddColumn(ctx, &sql.Column{Name: "e", Type: sql.Text, Nullable: true}, &sql.ColumnOrder{AfterColumn: "A"})
+	require.NoError(err)
+	require.Equal([]string{"d", "a", "e", "b", "c"}, columnNames(table.Schema()))
+	require.ElementsMatch([]sql.Row{
+		{nil, int64(1), nil, "one", int32(7)},
+		{nil, int64(2), nil, nil, int32(7)},
+	}, testFlatRows(t, table))
+
+	err = table.AddColumn(ctx, &sql.Column{Name: "B", Type: sql.Text, Nullable: true}, nil)
+	require.True(sql.ErrColumnAlreadyExists.Is(err))
+
+	err = table.AddColumn(ctx, &sql.Column{Name: "f", Type: sql.Text}, nil)
+	require.True(sql.ErrInvalidNullValue.Is(err))
+
+	err = table.AddColumn(ctx, &sql.Column{Name: "f", Type: sql.Text, Nullable: true}, &sql.ColumnOrder{AfterColumn: "z"})
+	require.True(sql.ErrTableColumnNotFound.Is(err))
+	require.Len(table.Schema(), 5)
+}
+
+func TestDropColumn(t *testing.T) {
+	require := require.New(t)
+	ctx := sql.NewEmptyContext()
+
+	table := newAlterTestTable(t)
+	require.True(sql.ErrTableColumnNotFound.Is(table.DropColumn(ctx, "z")))
+
+	require.NoError(table.DropColumn(ctx, "a"))
+	require.Equal([]string{"b"}, columnNames(table.Schema()))
+	require.ElementsMatch([]sql.Row{{"one"}, {nil}}, testFlatRows(t, table))
+
+	require.True(sql.ErrDropAllColumns.Is(table.DropColumn(ctx, "b")))
+}
+
+func TestModifyColumn(t *testing.T) {
+	require := require.New(t)
+	ctx := sql.NewEmptyContext()
+
+	table := newAlterTestTable(t)
+	err := table.ModifyColumn(ctx, "a", &sql.Column{Name: "x", Type: sql.Text}, &sql.ColumnOrder{AfterColumn: "b"})
+	require.NoError(err)
+	require.Equal([]string{"b", "x"}, columnNames(table.Schema()))
+	require.ElementsMatch([]sql.Row{{"one", "1"}, {nil, "2"}}, testFlatRows(t, table))
+
+	err = table.ModifyColumn(ctx, "b", &sql.Column{Name: "b", Type: sql.Text}, nil)
+	require.True(sql.ErrInvalidNullValue.Is(err))
+	require.ElementsMatch([]sql.Row{{"one", "1"}, {nil, "2"}}, testFlatRows(t, table))
+
+	err = table.ModifyColumn(ctx, "b", &sql.Column{Name: "x", Type: sql.Text}, nil)
+	require.True(sql.ErrColumnAlreadyExists.Is(err))
+
+	err = table.ModifyColumn(ctx, "z", &sql.Column{Name: "z", Type: sql.Text}, nil)
+	require.True(sql.ErrTableColumnNotFound.Is(err))
+}
+
+func TestRenameColumn(t *testing.T) {
+	require := require.New(t)
+	ctx := sql.NewEmptyContext()
+
+	table := newAlterTestTable(t)
+	require.NoError(table.RenameColumn(ctx, "a", "c"))
+	require.Equal([]string{"c", "b"}, columnNames(table.Schema()))
+	require.True(sql.ErrColumnAlreadyExists.Is(table.RenameColumn(ctx, "c", "b")))
+	require.True(sql.ErrTableColumnNotFound.Is(table.RenameColumn(ctx, "a", "d")))
+}
+
+func TestAlterTableInTransaction(t *testing.T) {
+	require := require.New(t)
+
+	db := NewDatabase("db")
+	table := newAlterTestTable(t)
+	db.AddTable("t", table)
+
+	ctx := sql.NewContext(context.Background(), sql.WithSession(sql.NewSession("", "", "", 1)))
+	tx, err := sql.BeginTransaction(ctx, sql.Databases{db}, false)
+	require.NoError(err)
+	require.NoError(table.Insert(ctx, sql.NewRow(int64(3), "three")))
+	require.NoError(tx.CreateSavepoint(ctx, "sp"))
+
+	require.NoError(table.DropColumn(sql.NewEmptyContext(), "b"))
+	require.ElementsMatch([]sql.Row{{int64(1)}, {int64(2)}}, testFlatRows(t, table))
+	require.ElementsMatch([]sql.Row{{int64(1)}, {int64(2)}, {int64(3)}}, testSessionRows(t, ctx, table))
+
+	require.NoError(table.Insert(ctx, sql.NewRow(int64(4))))
+	require.NoError(tx.RollbackToSavepoint(ctx, "sp"))
+	require.ElementsMatch([]sql.Row{{int64(1)}, {int64(2)}, {int64(3)}}, testSessionRows(t, ctx, table))
+
+	require.NoError(tx.Commit(ctx))
+	require.ElementsMatch([]sql.Row{{int64(1)}, {int64(2)}, {int64(3)}}, testFlatRows(t, table))
+}
+
+func TestRenameTable(t *testing.T) {
+	require := require.New(t)
+	ctx := sql.NewEmptyContext()
+
+	db := NewDatabase("db")
+	db.AddTable("t", newAlterTestTable(t))
+	db.AddTable("u", NewTable("u", sql.Schema{{Name: "a", Type: sql.Int64, Source: "u"}}))
+
+	require.True(sql.ErrTableAlreadyExists.Is(db.RenameTable(ctx, "t", "u")))
+	require.True(sql.ErrTableNotFound.Is(db.RenameTable(ctx, "z", "y")))
+
+	require.NoError(db.RenameTable(ctx, "t", "v"))
+	_, ok := db.Tables()["t"]
+	require.False(ok)
+
+	table, ok := db.Tables()["v"]
+	require.True(ok)
+	require.Equal("v", table.Name())
+	require.Equal("v", table.Schema()[0].Source)
+	require.Len(testFlatRows(t, table), 2)
+}
+
+func columnNames(schema sql.Schema) []string {
+	var names = make([]string, len(schema))
+	for i, col := range schema {
+		names[i] = col.Name
+	}
+	return names
+}
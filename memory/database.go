@@ -40,7 +40,17 @@ func (d *Database) CreateTable(ctx *sql.Context, name string, schema sql.Schema)
 		return sql.ErrTableAlreadyExists.New(name)
 	}
 
-	d.tables[name] = NewTable(name, schema)
+	table := NewTable(name, schema)
+	if tx := ctx.Transaction(); tx != nil {
+		// The new table is part of the transaction in progress, so its rows
+		// are only visible to the session until it's committed.
+		table.beginTransaction(tx)
+		for _, sp := range tx.Savepoints() {
+			table.createSavepoint(tx, sp)
+		}
+	}
+
+	d.tables[name] = table
 	return nil
 }
 
@@ -55,3 +65,22 @@ func (d *Database) DropTable(ctx *sql.Context, name string) error {
 	return nil
 }
 
+// RenameTable renames the table with the given name.
+func (d *Database) RenameTable(ctx *sql.Context, oldName, newName string) error {
+	t, ok := d.tables[oldName]
+	if !ok {
+		return sql.ErrTableNotFound.New(oldName)
+	}
+
+	if _, ok := d.tables[newName]; ok {
+		return sql.ErrTableAlreadyExists.New(newName)
+	}
+
+	if mt, ok := t.(*Table); ok {
+		mt.rename(newName)
+	}
+
+	delete(d.tables, oldName)
+	d.tables[newName] = t
+	return nil
+}
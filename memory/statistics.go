@@ -0,0 +1,38 @@
+package memory
+
+import (
+	"sync"
+
+	"github.com/src-d/go-mysql-server/sql"
+)
+
+var _ sql.AnalyzableTable = (*Table)(nil)
+
+// statistics are the statistics of a table computed by Analyze, which are
+// shared by all the copies of the table made to filter or project it.
+type statistics struct {
+	mu    sync.RWMutex
+	table *sql.TableStatistics
+}
+
+// Analyze implements the sql.AnalyzableTable interface. The statistics are
+// computed with the rows seen by the session of the context, and they are
+// not updated when rows are modified until the table is analyzed again.
+func (t *Table) Analyze(ctx *sql.Context) error {
+	stats, err := sql.ComputeStatistics(ctx, t, sql.DefaultHistogramBuckets)
+	if err != nil {
+		return err
+	}
+
+	t.stats.mu.Lock()
+	t.stats.table = stats
+	t.stats.mu.Unlock()
+	return nil
+}
+
+// Statistics implements the sql.StatisticsTable interface.
+func (t *Table) Statistics(ctx *sql.Context) (*sql.TableStatistics, error) {
+	t.stats.mu.RLock()
+	defer t.stats.mu.RUnlock()
+	return t.stats.table, nil
+}
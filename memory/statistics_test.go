@@ -0,0 +1,51 @@
+package memory
+
+import (
+	"testing"
+
+	"github.com/src-d/go-mysql-server/sql"
+	"github.com/src-d/go-mysql-server/sql/expression"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTableStatistics(t *testing.T) {
+	require := require.New(t)
+	ctx := sql.NewEmptyContext()
+
+	table := NewPartitionedTable("t", sql.Schema{
+		{Name: "i", Type: sql.Int64, Source: "t"},
+	}, 2)
+	for i := 0; i < 5; i++ {
+		require.NoError(table.Insert(ctx, sql.NewRow(int64(i%3))))
+	}
+
+	stats, err := table.Statistics(ctx)
+	require.NoError(err)
+	require.Nil(stats)
+
+	filtered := table.WithFilters([]sql.Expression{
+		expression.NewEquals(
+			expression.NewGetFieldWithTable(0, sql.Int64, "t", "i", false),
+			expression.NewLiteral(int64(1), sql.Int64),
+		),
+	})
+
+	require.NoError(table.Analyze(ctx))
+	stats, err = table.Statistics(ctx)
+	require.NoError(err)
+	require.Equal(uint64(5), stats.RowCount)
+	require.Equal(uint64(3), stats.Column("i").DistinctCount)
+
+	// Statistics are shared with the copies of the table, and they are not
+	// updated until the table is analyzed again.
+	require.NoError(table.Insert(ctx, sql.NewRow(int64(3))))
+	filteredStats, err := filtered.(sql.StatisticsTable).Statistics(ctx)
+	require.NoError(err)
+	require.Equal(stats, filteredStats)
+
+	require.NoError(table.Analyze(ctx))
+	stats, err = table.Statistics(ctx)
+	require.NoError(err)
+	require.Equal(uint64(6), stats.RowCount)
+	require.Equal(int64(3), stats.Column("i").Max)
+}
@@ -18,13 +18,18 @@ type Table struct {
 	schema     sql.Schema
 	partitions map[string][]sql.Row
 	keys       [][]byte
+	txs        *transactions
+	stats      *statistics
 
 	insert int
 
-	filters    []sql.Expression
-	projection []string
-	columns    []int
-	lookup     sql.IndexLookup
+	filters      []sql.Expression
+	projection   []string
+	columns      []int
+	lookup       sql.IndexLookup
+	grouping     []sql.Expression
+	aggregations []sql.Aggregation
+	limit        int64
 }
 
 var _ sql.Table = (*Table)(nil)
@@ -32,6 +37,7 @@ var _ sql.Inserter = (*Table)(nil)
 var _ sql.FilteredTable = (*Table)(nil)
 var _ sql.ProjectedTable = (*Table)(nil)
 var _ sql.IndexableTable = (*Table)(nil)
+var _ sql.LimitedTable = (*Table)(nil)
 
 // NewTable creates a new Table with the given name and schema.
 func NewTable(name string, schema sql.Schema) *Table {
@@ -58,6 +64,8 @@ func NewPartitionedTable(name string, schema sql.Schema, numPartitions int) *Tab
 		schema:     schema,
 		partitions: partitions,
 		keys:       keys,
+		txs:        newTransactions(),
+		stats:      new(statistics),
 	}
 }
 
@@ -75,7 +83,7 @@ func (t *Table) Schema() sql.Schema {
 func (t *Table) Partitions(ctx *sql.Context) (sql.PartitionIter, error) {
 	var keys [][]byte
 	for _, k := range t.keys {
-		if rows, ok := t.partitions[string(k)]; ok && len(rows) > 0 {
+		if rows, ok := t.partition(ctx, string(k)); ok && len(rows) > 0 {
 			keys = append(keys, k)
 		}
 	}
@@ -89,7 +97,7 @@ func (t *Table) PartitionCount(ctx *sql.Context) (int64, error) {
 
 // PartitionRows implements the sql.PartitionRows interface.
 func (t *Table) PartitionRows(ctx *sql.Context, partition sql.Partition) (sql.RowIter, error) {
-	rows, ok := t.partitions[string(partition.Key())]
+	rows, ok := t.partition(ctx, string(partition.Key()))
 	if !ok {
 		return nil, fmt.Errorf(
 			"partition not found: %q", partition.Key(),
@@ -105,12 +113,22 @@ func (t *Table) PartitionRows(ctx *sql.Context, partition sql.Partition) (sql.Ro
 		}
 	}
 
-	return &tableIter{
+	iter := &tableIter{
 		rows:        rows,
 		columns:     t.columns,
 		filters:     t.filters,
 		indexValues: values,
-	}, nil
+	}
+
+	if t.aggregated() {
+		return aggregateRows(ctx, iter, t.grouping, t.aggregations)
+	}
+
+	if t.limit > 0 {
+		return &limitedIter{iter, t.limit}, nil
+	}
+
+	return iter, nil
 }
 
 type partition struct {
@@ -253,8 +271,10 @@ func (t *Table) Insert(ctx *sql.Context, row sql.Row) error {
 		t.insert = 0
 	}
 
-	t.partitions[key] = append(t.partitions[key], row)
-	return nil
+	return t.modify(ctx, func(s *partitionSet) error {
+		s.insert(key, row)
+		return nil
+	})
 }
 
 // Delete the given row from the table.
@@ -263,31 +283,33 @@ func (t *Table) Delete(ctx *sql.Context, row sql.Row) error {
 		return err
 	}
 
-	matches := false
-	for partitionIndex, partition := range t.partitions {
-		for partitionRowIndex, partitionRow := range partition {
-			matches = true
-			for rIndex, val := range row {
-				if val != partitionRow[rIndex] {
-					matches = false
+	return t.modify(ctx, func(s *partitionSet) error {
+		matches := false
+		for partitionIndex, partition := range s.rows {
+			for partitionRowIndex, partitionRow := range partition {
+				matches = true
+				for rIndex, val := range row {
+					if val != partitionRow[rIndex] {
+						matches = false
+						break
+					}
+				}
+				if matches {
+					s.delete(partitionIndex, partitionRowIndex)
 					break
 				}
 			}
 			if matches {
-				t.partitions[partitionIndex] = append(partition[:partitionRowIndex], partition[partitionRowIndex+1:]...)
 				break
 			}
 		}
-		if matches {
-			break
-		}
-	}
 
-	if !matches {
-		return sql.ErrDeleteRowNotFound
-	}
+		if !matches {
+			return sql.ErrDeleteRowNotFound
+		}
 
-	return nil
+		return nil
+	})
 }
 
 func (t *Table) Update(ctx *sql.Context, oldRow sql.Row, newRow sql.Row) error {
@@ -298,27 +320,29 @@ func (t *Table) Update(ctx *sql.Context, oldRow sql.Row, newRow sql.Row) error {
 		return err
 	}
 
-	matches := false
-	for partitionIndex, partition := range t.partitions {
-		for partitionRowIndex, partitionRow := range partition {
-			matches = true
-			for rIndex, val := range oldRow {
-				if val != partitionRow[rIndex] {
-					matches = false
+	return t.modify(ctx, func(s *partitionSet) error {
+		matches := false
+		for partitionIndex, partition := range s.rows {
+			for partitionRowIndex, partitionRow := range partition {
+				matches = true
+				for rIndex, val := range oldRow {
+					if val != partitionRow[rIndex] {
+						matches = false
+						break
+					}
+				}
+				if matches {
+					s.update(partitionIndex, partitionRowIndex, newRow)
 					break
 				}
 			}
 			if matches {
-				t.partitions[partitionIndex][partitionRowIndex] = newRow
 				break
 			}
 		}
-		if matches {
-			break
-		}
-	}
 
-	return nil
+		return nil
+	})
 }
 
 func checkRow(schema sql.Schema, row sql.Row) error {
@@ -350,7 +374,15 @@ func (t *Table) String() string {
 	}
 
 	if t.lookup != nil {
-		kind += "Indexed"
+		kind += "Indexed "
+	}
+
+	if t.aggregated() {
+		kind += "Aggregated "
+	}
+
+	if t.limit > 0 {
+		kind += fmt.Sprintf("Limited(%d) ", t.limit)
 	}
 
 	if kind != "" {
@@ -375,18 +407,7 @@ func (t *Table) String() string {
 func (t *Table) HandledFilters(filters []sql.Expression) []sql.Expression {
 	var handled []sql.Expression
 	for _, f := range filters {
-		var hasOtherFields bool
-		expression.Inspect(f, func(e sql.Expression) bool {
-			if e, ok := e.(*expression.GetField); ok {
-				if e.Table() != t.name || !t.schema.Contains(e.Name(), t.name) {
-					hasOtherFields = true
-					return false
-				}
-			}
-			return true
-		})
-
-		if !hasOtherFields {
+		if t.hasOnlyOwnFields(f) {
 			handled = append(handled, f)
 		}
 	}
@@ -394,6 +415,22 @@ func (t *Table) HandledFilters(filters []sql.Expression) []sql.Expression {
 	return handled
 }
 
+// hasOnlyOwnFields returns whether all the fields of the expression are
+// columns of the table.
+func (t *Table) hasOnlyOwnFields(e sql.Expression) bool {
+	var hasOtherFields bool
+	expression.Inspect(e, func(e sql.Expression) bool {
+		if e, ok := e.(*expression.GetField); ok {
+			if e.Table() != t.name || !t.schema.Contains(e.Name(), t.name) {
+				hasOtherFields = true
+				return false
+			}
+		}
+		return true
+	})
+	return !hasOtherFields
+}
+
 // WithFilters implements the sql.FilteredTable interface.
 func (t *Table) WithFilters(filters []sql.Expression) sql.Table {
 	if len(filters) == 0 {
@@ -446,6 +483,33 @@ func (t *Table) newColumnIndexesAndSchema(colNames []string) ([]int, sql.Schema,
 	return columns, schema, nil
 }
 
+// WithLimit implements the sql.LimitedTable interface.
+func (t *Table) WithLimit(limit int64) sql.Table {
+	nt := *t
+	nt.limit = limit
+	return &nt
+}
+
+// Limit implements the sql.LimitedTable interface.
+func (t *Table) Limit() int64 {
+	return t.limit
+}
+
+// limitedIter returns up to a number of rows of an iterator.
+type limitedIter struct {
+	sql.RowIter
+	left int64
+}
+
+func (i *limitedIter) Next() (sql.Row, error) {
+	if i.left <= 0 {
+		return nil, io.EOF
+	}
+
+	i.left--
+	return i.RowIter.Next()
+}
+
 // WithIndexLookup implements the sql.IndexableTable interface.
 func (t *Table) WithIndexLookup(lookup sql.IndexLookup) sql.Table {
 	if lookup == nil {
@@ -318,6 +318,28 @@ func TestIndexed(t *testing.T) {
 	}
 }
 
+func TestLimited(t *testing.T) {
+	require := require.New(t)
+
+	table := NewPartitionedTable("foo", sql.Schema{
+		{Name: "a", Type: sql.Int64, Source: "foo"},
+	}, 2)
+	for i := 0; i < 7; i++ {
+		require.NoError(table.Insert(sql.NewEmptyContext(), sql.NewRow(int64(i))))
+	}
+
+	limited := table.WithLimit(2).(*Table)
+	require.Equal(int64(2), limited.Limit())
+	require.Equal(int64(0), table.Limit())
+
+	// The limit is applied to each partition.
+	require.ElementsMatch([]sql.Row{
+		{int64(0)}, {int64(2)}, {int64(1)}, {int64(3)},
+	}, testFlatRows(t, limited))
+
+	require.Len(testFlatRows(t, table.WithLimit(10)), 7)
+}
+
 func testFlatRows(t *testing.T, table sql.Table) []sql.Row {
 	var require = require.New(t)
 
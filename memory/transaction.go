@@ -0,0 +1,377 @@
+package memory
+
+import (
+	"strings"
+	"sync"
+
+	"github.com/src-d/go-mysql-server/sql"
+)
+
+// transactions keeps the partitions of a table as seen by each transaction
+// in progress. Partitions are copied on write, so a transaction only pays for
+// the partitions it modifies.
+//
+// Transactions also record the rows they change in each partition. On
+// commit, the partitions that no other transaction changed in the meantime
+// are replaced with the ones of the transaction, and the changes of the rest
+// are applied on top of the committed rows.
+type transactions struct {
+	mu  sync.RWMutex
+	txs map[*sql.Transaction]*transaction
+}
+
+func newTransactions() *transactions {
+	return &transactions{txs: make(map[*sql.Transaction]*transaction)}
+}
+
+type transaction struct {
+	// base are the committed partitions when the transaction started.
+	base       map[string][]sql.Row
+	partitions map[string][]sql.Row
+	// owned are the partitions copied by the transaction, which can be
+	// modified in place because nobody else has them.
+	owned map[string]bool
+	// changes are the changes made by the transaction to each partition, in
+	// the order they were made. Only the partitions with changes are written
+	// on commit.
+	changes    map[string][]rowChange
+	savepoints map[string]*savepoint
+}
+
+type savepoint struct {
+	partitions map[string][]sql.Row
+	// changes is the number of changes of each partition when the savepoint
+	// was created.
+	changes map[string]int
+}
+
+// rowChange is a change to a row of a partition, which is an insert if there
+// is no old row, a delete if there is no new row, or an update otherwise.
+type rowChange struct {
+	old, new sql.Row
+}
+
+// apply applies the change to the given rows, which can be modified in place.
+// Deletes and updates of rows that are not there any more, because another
+// transaction deleted or updated them first, are ignored.
+func (c rowChange) apply(rows []sql.Row) []sql.Row {
+	if c.old == nil {
+		return append(rows, c.new)
+	}
+
+	for i, row := range rows {
+		if !equalRows(row, c.old) {
+			continue
+		}
+
+		if c.new == nil {
+			return append(rows[:i], rows[i+1:]...)
+		}
+		rows[i] = c.new
+		return rows
+	}
+	return rows
+}
+
+func copyPartitions(partitions map[string][]sql.Row) map[string][]sql.Row {
+	result := make(map[string][]sql.Row, len(partitions))
+	for k, rows := range partitions {
+		result[k] = rows
+	}
+	return result
+}
+
+// partitionSet is the set of partitions of a table that can be modified by a
+// session, which are either the committed ones or the ones of its
+// transaction.
+type partitionSet struct {
+	rows map[string][]sql.Row
+	tx   *transaction
+}
+
+// insert appends the row to the partition with the given key.
+func (s *partitionSet) insert(key string, row sql.Row) {
+	if s.tx == nil {
+		s.rows[key] = append(s.rows[key], row)
+		return
+	}
+
+	s.set(key, append(s.writable(key), row), rowChange{new: row})
+}
+
+// delete removes the row at the given index of the partition with the given
+// key.
+func (s *partitionSet) delete(key string, i int) {
+	rows := s.writable(key)
+	old := rows[i]
+	s.set(key, append(rows[:i], rows[i+1:]...), rowChange{old: old})
+}
+
+// update replaces the row at the given index of the partition with the given
+// key.
+func (s *partitionSet) update(key string, i int, row sql.Row) {
+	rows := s.writable(key)
+	old := rows[i]
+	rows[i] = row
+	s.set(key, rows, rowChange{old: old, new: row})
+}
+
+// writable returns the rows of the partition with the given key, which can
+// be modified in place and must be stored again with set.
+func (s *partitionSet) writable(key string) []sql.Row {
+	rows := s.rows[key]
+	if s.tx != nil && s.tx.owned[key] {
+		return rows
+	}
+
+	// Committed partitions may be shared with the transactions in progress,
+	// so they are never modified in place.
+	result := make([]sql.Row, len(rows), len(rows)+1)
+	copy(result, rows)
+	if s.tx != nil {
+		s.tx.owned[key] = true
+	}
+	return result
+}
+
+// set replaces the rows of the partition with the given key, which were
+// modified with the given change.
+func (s *partitionSet) set(key string, rows []sql.Row, change rowChange) {
+	s.rows[key] = rows
+	if s.tx != nil {
+		s.tx.changes[key] = append(s.tx.changes[key], change)
+	}
+}
+
+// transaction returns the transaction in progress in the session of the
+// context in the table, if any. The caller must hold the lock of the table
+// transactions.
+func (t *Table) transaction(ctx *sql.Context) *transaction {
+	if ctx == nil || ctx.Session == nil || ctx.Transaction() == nil {
+		return nil
+	}
+	return t.txs.txs[ctx.Transaction()]
+}
+
+// partition returns the rows of the partition with the given key as seen by
+// the session of the context.
+func (t *Table) partition(ctx *sql.Context, key string) ([]sql.Row, bool) {
+	t.txs.mu.RLock()
+	defer t.txs.mu.RUnlock()
+
+	partitions := t.partitions
+	if tx := t.transaction(ctx); tx != nil {
+		partitions = tx.partitions
+	}
+
+	rows, ok := partitions[key]
+	return rows, ok
+}
+
+// modify calls fn with the partitions the session of the context can modify.
+func (t *Table) modify(ctx *sql.Context, fn func(*partitionSet) error) error {
+	t.txs.mu.Lock()
+	defer t.txs.mu.Unlock()
+
+	s := &partitionSet{rows: t.partitions}
+	if tx := t.transaction(ctx); tx != nil {
+		s.rows = tx.partitions
+		s.tx = tx
+	}
+
+	return fn(s)
+}
+
+// beginTransaction starts the given transaction in the table, unless it was
+// already started.
+func (t *Table) beginTransaction(tx *sql.Transaction) {
+	t.txs.mu.Lock()
+	defer t.txs.mu.Unlock()
+
+	if _, ok := t.txs.txs[tx]; ok {
+		return
+	}
+
+	t.txs.txs[tx] = &transaction{
+		base:       copyPartitions(t.partitions),
+		partitions: copyPartitions(t.partitions),
+		owned:      make(map[string]bool),
+		changes:    make(map[string][]rowChange),
+		savepoints: make(map[string]*savepoint),
+	}
+}
+
+func (t *Table) commitTransaction(tx *sql.Transaction) {
+	t.txs.mu.Lock()
+	defer t.txs.mu.Unlock()
+
+	ttx, ok := t.txs.txs[tx]
+	if !ok {
+		return
+	}
+	delete(t.txs.txs, tx)
+
+	// The partitions are replaced in the existing map because it's shared
+	// with all the copies of the table.
+	for key, changes := range ttx.changes {
+		committed := t.partitions[key]
+		if sameRows(committed, ttx.base[key]) {
+			t.partitions[key] = ttx.partitions[key]
+			continue
+		}
+
+		// The partition was changed after the transaction started, so its
+		// changes are made again on the rows committed since then.
+		rows := make([]sql.Row, len(committed), len(committed)+len(changes))
+		copy(rows, committed)
+		for _, c := range changes {
+			rows = c.apply(rows)
+		}
+		t.partitions[key] = rows
+	}
+}
+
+func (t *Table) rollbackTransaction(tx *sql.Transaction) {
+	t.txs.mu.Lock()
+	delete(t.txs.txs, tx)
+	t.txs.mu.Unlock()
+}
+
+func (t *Table) createSavepoint(tx *sql.Transaction, name string) {
+	t.txs.mu.Lock()
+	defer t.txs.mu.Unlock()
+
+	ttx, ok := t.txs.txs[tx]
+	if !ok {
+		return
+	}
+
+	sp := &savepoint{
+		partitions: copyPartitions(ttx.partitions),
+		changes:    make(map[string]int, len(ttx.changes)),
+	}
+	for key, changes := range ttx.changes {
+		sp.changes[key] = len(changes)
+	}
+
+	ttx.savepoints[strings.ToLower(name)] = sp
+	// The saved partitions must not be modified from now on.
+	ttx.owned = make(map[string]bool)
+}
+
+func (t *Table) rollbackToSavepoint(tx *sql.Transaction, name string) error {
+	t.txs.mu.Lock()
+	defer t.txs.mu.Unlock()
+
+	ttx, ok := t.txs.txs[tx]
+	if !ok {
+		return nil
+	}
+
+	sp, ok := ttx.savepoints[strings.ToLower(name)]
+	if !ok {
+		return sql.ErrSavepointNotFound.New(name)
+	}
+
+	for key, changes := range ttx.changes {
+		if n := sp.changes[key]; n > 0 {
+			ttx.changes[key] = changes[:n]
+		} else {
+			delete(ttx.changes, key)
+		}
+	}
+	ttx.partitions = copyPartitions(sp.partitions)
+	ttx.owned = make(map[string]bool)
+	return nil
+}
+
+func (t *Table) releaseSavepoint(tx *sql.Transaction, name string) {
+	t.txs.mu.Lock()
+	defer t.txs.mu.Unlock()
+
+	if ttx, ok := t.txs.txs[tx]; ok {
+		delete(ttx.savepoints, strings.ToLower(name))
+	}
+}
+
+// sameRows returns whether both slices are the same slice of rows.
+func sameRows(a, b []sql.Row) bool {
+	return len(a) == len(b) && (len(a) == 0 || &a[0] == &b[0])
+}
+
+// equalRows returns whether both rows have the same values.
+func equalRows(a, b sql.Row) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+var _ sql.TransactionalDatabase = (*Database)(nil)
+
+// BeginTransaction implements the sql.TransactionalDatabase interface.
+func (d *Database) BeginTransaction(ctx *sql.Context) error {
+	for _, t := range d.memoryTables() {
+		t.beginTransaction(ctx.Transaction())
+	}
+	return nil
+}
+
+// CommitTransaction implements the sql.TransactionalDatabase interface.
+func (d *Database) CommitTransaction(ctx *sql.Context) error {
+	for _, t := range d.memoryTables() {
+		t.commitTransaction(ctx.Transaction())
+	}
+	return nil
+}
+
+// RollbackTransaction implements the sql.TransactionalDatabase interface.
+func (d *Database) RollbackTransaction(ctx *sql.Context) error {
+	for _, t := range d.memoryTables() {
+		t.rollbackTransaction(ctx.Transaction())
+	}
+	return nil
+}
+
+// CreateSavepoint implements the sql.TransactionalDatabase interface.
+func (d *Database) CreateSavepoint(ctx *sql.Context, name string) error {
+	for _, t := range d.memoryTables() {
+		t.createSavepoint(ctx.Transaction(), name)
+	}
+	return nil
+}
+
+// RollbackToSavepoint implements the sql.TransactionalDatabase interface.
+func (d *Database) RollbackToSavepoint(ctx *sql.Context, name string) error {
+	for _, t := range d.memoryTables() {
+		if err := t.rollbackToSavepoint(ctx.Transaction(), name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ReleaseSavepoint implements the sql.TransactionalDatabase interface.
+func (d *Database) ReleaseSavepoint(ctx *sql.Context, name string) error {
+	for _, t := range d.memoryTables() {
+		t.releaseSavepoint(ctx.Transaction(), name)
+	}
+	return nil
+}
+
+// memoryTables returns the tables of the database that support transactions.
+func (d *Database) memoryTables() []*Table {
+	var tables []*Table
+	for _, t := range d.tables {
+		if t, ok := t.(*Table); ok {
+			tables = append(tables, t)
+		}
+	}
+	return tables
+}
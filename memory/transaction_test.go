@@ -0,0 +1,151 @@
+package memory
+
+import (
+	"context"
+	"io"
+	"testing"
+
+	"github.com/src-d/go-mysql-server/sql"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTransactions(t *testing.T) {
+	require := require.New(t)
+
+	db := NewDatabase("db")
+	table := NewPartitionedTable("t", sql.Schema{
+		{Name: "a", Type: sql.Int64, Source: "t"},
+	}, 2)
+	db.AddTable("t", table)
+
+	newCtx := func(id uint32) *sql.Context {
+		return sql.NewContext(
+			context.Background(),
+			sql.WithSession(sql.NewSession("", "", "", id)),
+		)
+	}
+
+	rows := func(ctx *sql.Context) []sql.Row {
+		return testSessionRows(t, ctx, table)
+	}
+
+	ctx1, ctx2 := newCtx(1), newCtx(2)
+	require.NoError(table.Insert(ctx1, sql.NewRow(int64(1))))
+	require.NoError(table.Insert(ctx1, sql.NewRow(int64(2))))
+
+	tx1, err := sql.BeginTransaction(ctx1, sql.Databases{db}, false)
+	require.NoError(err)
+	tx2, err := sql.BeginTransaction(ctx2, sql.Databases{db}, false)
+	require.NoError(err)
+
+	require.NoError(table.Insert(ctx1, sql.NewRow(int64(3))))
+	require.NoError(table.Delete(ctx1, sql.NewRow(int64(1))))
+	require.NoError(table.Update(ctx2, sql.NewRow(int64(2)), sql.NewRow(int64(4))))
+
+	require.ElementsMatch([]sql.Row{{int64(2)}, {int64(3)}}, rows(ctx1))
+	require.ElementsMatch([]sql.Row{{int64(1)}, {int64(4)}}, rows(ctx2))
+	require.ElementsMatch([]sql.Row{{int64(1)}, {int64(2)}}, rows(sql.NewEmptyContext()))
+
+	require.NoError(tx2.Rollback(ctx2))
+	require.ElementsMatch([]sql.Row{{int64(1)}, {int64(2)}}, rows(ctx2))
+
+	require.NoError(tx1.CreateSavepoint(ctx1, "sp"))
+	require.NoError(table.Insert(ctx1, sql.NewRow(int64(5))))
+	require.NoError(table.Delete(ctx1, sql.NewRow(int64(2))))
+	require.ElementsMatch([]sql.Row{{int64(3)}, {int64(5)}}, rows(ctx1))
+
+	require.NoError(tx1.RollbackToSavepoint(ctx1, "SP"))
+	require.ElementsMatch([]sql.Row{{int64(2)}, {int64(3)}}, rows(ctx1))
+
+	require.NoError(tx1.Commit(ctx1))
+	require.ElementsMatch([]sql.Row{{int64(2)}, {int64(3)}}, rows(ctx2))
+	require.ElementsMatch([]sql.Row{{int64(2)}, {int64(3)}}, rows(sql.NewEmptyContext()))
+}
+
+func TestTransactionsSameSession(t *testing.T) {
+	require := require.New(t)
+
+	db := NewDatabase("db")
+	table := NewTable("t", sql.Schema{
+		{Name: "a", Type: sql.Int64, Source: "t"},
+	})
+	db.AddTable("t", table)
+
+	// Both contexts have sessions with the same ID.
+	ctx1, ctx2 := sql.NewEmptyContext(), sql.NewEmptyContext()
+
+	tx1, err := sql.BeginTransaction(ctx1, sql.Databases{db}, false)
+	require.NoError(err)
+	require.NoError(table.Insert(ctx1, sql.NewRow(int64(1))))
+
+	tx2, err := sql.BeginTransaction(ctx2, sql.Databases{db}, true)
+	require.NoError(err)
+	require.Empty(testSessionRows(t, ctx2, table))
+	require.NoError(tx2.Commit(ctx2))
+
+	require.NoError(tx1.Commit(ctx1))
+	require.Equal([]sql.Row{{int64(1)}}, testSessionRows(t, ctx2, table))
+}
+
+func TestTransactionsConcurrentCommits(t *testing.T) {
+	require := require.New(t)
+
+	db := NewDatabase("db")
+	table := NewTable("t", sql.Schema{
+		{Name: "a", Type: sql.Int64, Source: "t"},
+	})
+	db.AddTable("t", table)
+
+	ctx := sql.NewEmptyContext()
+	require.NoError(table.Insert(ctx, sql.NewRow(int64(1))))
+	require.NoError(table.Insert(ctx, sql.NewRow(int64(2))))
+
+	ctx1, ctx2 := sql.NewEmptyContext(), sql.NewEmptyContext()
+	tx1, err := sql.BeginTransaction(ctx1, sql.Databases{db}, false)
+	require.NoError(err)
+	tx2, err := sql.BeginTransaction(ctx2, sql.Databases{db}, false)
+	require.NoError(err)
+
+	require.NoError(table.Insert(ctx1, sql.NewRow(int64(3))))
+	require.NoError(table.Delete(ctx1, sql.NewRow(int64(1))))
+
+	require.NoError(table.Insert(ctx2, sql.NewRow(int64(4))))
+	require.NoError(table.Update(ctx2, sql.NewRow(int64(2)), sql.NewRow(int64(5))))
+	// The row is deleted by the first transaction before this one commits.
+	require.NoError(table.Update(ctx2, sql.NewRow(int64(1)), sql.NewRow(int64(6))))
+
+	require.NoError(tx1.Commit(ctx1))
+	require.ElementsMatch([]sql.Row{{int64(2)}, {int64(3)}}, testSessionRows(t, ctx, table))
+
+	require.NoError(tx2.Commit(ctx2))
+	require.ElementsMatch(
+		[]sql.Row{{int64(3)}, {int64(4)}, {int64(5)}},
+		testSessionRows(t, ctx, table),
+	)
+}
+
+func testSessionRows(t *testing.T, ctx *sql.Context, table sql.Table) []sql.Row {
+	var require = require.New(t)
+
+	pIter, err := table.Partitions(ctx)
+	require.NoError(err)
+
+	var result []sql.Row
+	for {
+		p, err := pIter.Next()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(err)
+
+		iter, err := table.PartitionRows(ctx, p)
+		require.NoError(err)
+
+		rows, err := sql.RowIterToRows(iter)
+		require.NoError(err)
+
+		result = append(result, rows...)
+	}
+
+	return result
+}
@@ -0,0 +1,176 @@
+package sqle
+
+import (
+	"sort"
+	"strings"
+	"sync/atomic"
+
+	lru "github.com/hashicorp/golang-lru"
+	"github.com/src-d/go-mysql-server/sql"
+	"github.com/src-d/go-mysql-server/sql/expression"
+	"github.com/src-d/go-mysql-server/sql/expression/function/udf"
+	"github.com/src-d/go-mysql-server/sql/parse"
+	"github.com/src-d/go-mysql-server/sql/plan"
+)
+
+// DefaultPlanCacheSize is the number of plans kept by the plan cache of an
+// engine.
+const DefaultPlanCacheSize = 512
+
+// PlanCache keeps the most recently used plans of SELECT queries by their
+// normalized query, so the queries that only differ in the literals of their
+// conditions are parsed and analyzed only once. The plans have placeholders
+// instead of the literals, which are bound to the literals of each query
+// before running it.
+//
+// Plans are discarded when the cache is invalidated, which happens on every
+// DDL statement, and when the indexes of the catalog change. It is safe to
+// use concurrently, and a nil cache does not cache anything.
+type PlanCache struct {
+	// generation is increased every time the cache is invalidated. The
+	// counters are the first fields so they are aligned for atomic
+	// operations.
+	generation uint64
+	hits       uint64
+	misses     uint64
+	cache      *lru.Cache
+}
+
+// planCacheKey identifies the plan of a normalized query. Literals of
+// different types may lead to different plans, so their types are part of
+// the key too.
+type planCacheKey struct {
+	db    string
+	query string
+	types string
+}
+
+// planCacheEntry is a plan in the cache along with the generations of the
+// cache and the indexes when it was analyzed. A nil node means the plan of
+// the query can't be cached.
+type planCacheEntry struct {
+	generation      uint64
+	indexGeneration uint64
+	node            sql.Node
+}
+
+// NewPlanCache creates a new cache keeping at most size plans.
+func NewPlanCache(size int) *PlanCache {
+	cache, _ := lru.New(size)
+	return &PlanCache{cache: cache}
+}
+
+// Invalidate discards all the plans in the cache.
+func (c *PlanCache) Invalidate() {
+	if c == nil {
+		return
+	}
+
+	atomic.AddUint64(&c.generation, 1)
+	c.cache.Purge()
+}
+
+// Len returns the number of plans in the cache.
+func (c *PlanCache) Len() int {
+	if c == nil {
+		return 0
+	}
+	return c.cache.Len()
+}
+
+// Hits returns the number of queries run with a plan from the cache.
+func (c *PlanCache) Hits() uint64 {
+	if c == nil {
+		return 0
+	}
+	return atomic.LoadUint64(&c.hits)
+}
+
+// Misses returns the number of queries that could use a plan from the
+// cache, but it was not there.
+func (c *PlanCache) Misses() uint64 {
+	if c == nil {
+		return 0
+	}
+	return atomic.LoadUint64(&c.misses)
+}
+
+// lookup returns the plan of the given normalized query in the cache and
+// whether it was there. A nil plan in the cache means the plan of the query
+// can't be cached. It also returns a function to add the plan of the query
+// to the cache, which is discarded if the cache is invalidated before that.
+func (c *PlanCache) lookup(
+	catalog *sql.Catalog,
+	q *parse.NormalizedQuery,
+) (sql.Node, bool, func(sql.Node)) {
+	key := newPlanCacheKey(catalog.CurrentDatabase(), q)
+	generation := atomic.LoadUint64(&c.generation)
+	indexGeneration := catalog.IndexGeneration()
+
+	if v, ok := c.cache.Get(key); ok {
+		entry := v.(planCacheEntry)
+		if entry.generation == generation && entry.indexGeneration == indexGeneration {
+			if entry.node != nil {
+				atomic.AddUint64(&c.hits, 1)
+				PlanCacheHitCounter.Add(1)
+			}
+			return entry.node, true, nil
+		}
+	}
+
+	atomic.AddUint64(&c.misses, 1)
+	PlanCacheMissCounter.Add(1)
+
+	return nil, false, func(node sql.Node) {
+		if atomic.LoadUint64(&c.generation) != generation {
+			return
+		}
+		c.cache.Add(key, planCacheEntry{generation, indexGeneration, node})
+	}
+}
+
+func newPlanCacheKey(db string, q *parse.NormalizedQuery) planCacheKey {
+	var names = make([]string, 0, len(q.Bindings))
+	for name := range q.Bindings {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var types = make([]string, len(names))
+	for i, name := range names {
+		types[i] = name + " " + q.Bindings[name].Type().String()
+	}
+
+	return planCacheKey{db, q.Query, strings.Join(types, ", ")}
+}
+
+// isCacheable returns whether the given plan can be run many times. Plans
+// with the values of session variables or with script UDFs, which count
+// their calls in each query, can't. Every execution runs a copy of the plan
+// made when its placeholders are bound, but the copy only has new nodes and
+// expressions, so the plans with nodes that have other nodes that are not
+// their children, such as recursive common table expressions, can't either.
+func isCacheable(node sql.Node) bool {
+	var cacheable = true
+	plan.Inspect(node, func(n sql.Node) bool {
+		if _, ok := n.(*plan.RecursiveCte); ok {
+			cacheable = false
+		}
+		return cacheable
+	})
+
+	if !cacheable {
+		return false
+	}
+
+	plan.InspectExpressions(node, func(e sql.Expression) bool {
+		switch e := e.(type) {
+		case *expression.GetSessionField, *udf.Scriptable:
+			cacheable = false
+		case *expression.Subquery:
+			cacheable = isCacheable(e.Query)
+		}
+		return cacheable
+	})
+	return cacheable
+}
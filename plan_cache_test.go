@@ -0,0 +1,141 @@
+package sqle_test
+
+import (
+	"testing"
+
+	"github.com/src-d/go-mysql-server/sql"
+	"github.com/src-d/go-mysql-server/sql/expression/function/udf"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPlanCache(t *testing.T) {
+	require := require.New(t)
+	e := newEngine(t)
+
+	query := func(q string) []sql.Row {
+		_, iter, err := e.Query(newCtx(), q)
+		require.NoError(err)
+		rows, err := sql.RowIterToRows(iter)
+		require.NoError(err)
+		return rows
+	}
+
+	require.Equal(
+		[]sql.Row{{int64(2), int64(3)}, {int64(3), int64(2)}, {int64(3), int64(3)}},
+		query(`SELECT i, i2 FROM mytable INNER JOIN othertable ON i + i2 > 4 ORDER BY i, i2`),
+	)
+	require.Equal(uint64(0), e.PlanCache.Hits())
+	require.Equal(uint64(1), e.PlanCache.Misses())
+	require.Equal(1, e.PlanCache.Len())
+
+	require.Equal(
+		[]sql.Row{{int64(3), int64(3)}},
+		query(`SELECT i, i2 FROM mytable INNER JOIN othertable ON i + i2 > 5 ORDER BY i, i2`),
+	)
+	require.Equal(uint64(1), e.PlanCache.Hits())
+	require.Equal(uint64(1), e.PlanCache.Misses())
+
+	// Literals of other types may need another plan.
+	require.Equal(
+		[]sql.Row{{int64(3), int64(3)}},
+		query(`SELECT i, i2 FROM mytable INNER JOIN othertable ON i + i2 > 5.5 ORDER BY i, i2`),
+	)
+	require.Equal(uint64(1), e.PlanCache.Hits())
+	require.Equal(uint64(2), e.PlanCache.Misses())
+	require.Equal(2, e.PlanCache.Len())
+
+	query(`CREATE TABLE foo (a INT)`)
+	require.Equal(0, e.PlanCache.Len())
+
+	require.Equal(
+		[]sql.Row{{int64(3), int64(3)}},
+		query(`SELECT i, i2 FROM mytable INNER JOIN othertable ON i + i2 > 5 ORDER BY i, i2`),
+	)
+	require.Equal(uint64(1), e.PlanCache.Hits())
+	require.Equal(uint64(3), e.PlanCache.Misses())
+
+	require.NoError(e.RegisterUDF(udf.ScriptUDF{
+		Id:     "_auto_1_udf_",
+		Script: udf.GetScriptInstance("js", "$ARGS[0]"),
+	}))
+	require.Equal(0, e.PlanCache.Len())
+}
+
+func TestPlanCacheNotReusable(t *testing.T) {
+	testCases := []struct {
+		name     string
+		queries  []string
+		expected [][]sql.Row
+	}{
+		{
+			"filters pushed down to the table",
+			[]string{
+				`SELECT i FROM mytable WHERE i > 1 ORDER BY i`,
+				`SELECT i FROM mytable WHERE i > 2 ORDER BY i`,
+			},
+			[][]sql.Row{
+				{{int64(2)}, {int64(3)}},
+				{{int64(3)}},
+			},
+		},
+		{
+			"recursive common table expressions",
+			[]string{
+				`CREATE VIEW v AS WITH RECURSIVE t (n) AS (SELECT 1 UNION ALL SELECT n + 1 FROM t WHERE n < 3) SELECT n FROM t`,
+				`SELECT n FROM v WHERE n > 1 ORDER BY n`,
+				`SELECT n FROM v WHERE n > 2 ORDER BY n`,
+			},
+			[][]sql.Row{
+				nil,
+				{{int8(2)}, {int8(3)}},
+				{{int8(3)}},
+			},
+		},
+		{
+			"session variables",
+			[]string{
+				`SELECT i FROM mytable WHERE i = 1 AND @@autocommit`,
+				`SELECT i FROM mytable WHERE i = 2 AND @@autocommit`,
+			},
+			[][]sql.Row{
+				{{int64(1)}},
+				{{int64(2)}},
+			},
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			require := require.New(t)
+			e := newEngine(t)
+
+			for i, q := range tt.queries {
+				_, iter, err := e.Query(newCtx(), q)
+				require.NoError(err)
+				rows, err := sql.RowIterToRows(iter)
+				require.NoError(err)
+				require.Equal(tt.expected[i], rows)
+			}
+
+			require.Equal(uint64(0), e.PlanCache.Hits())
+			require.Equal(uint64(1), e.PlanCache.Misses())
+		})
+	}
+}
+
+func TestPlanCacheDisabled(t *testing.T) {
+	require := require.New(t)
+	e := newEngine(t)
+	e.PlanCache = nil
+
+	for i := 0; i < 2; i++ {
+		_, iter, err := e.Query(newCtx(), `SELECT i FROM mytable WHERE i + 1 > 3`)
+		require.NoError(err)
+		rows, err := sql.RowIterToRows(iter)
+		require.NoError(err)
+		require.Equal([]sql.Row{{int64(3)}}, rows)
+	}
+
+	require.Equal(uint64(0), e.PlanCache.Hits())
+	require.Equal(0, e.PlanCache.Len())
+}
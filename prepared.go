@@ -0,0 +1,135 @@
+package sqle
+
+import (
+	"sync"
+	"sync/atomic"
+
+	"github.com/src-d/go-mysql-server/sql"
+	"github.com/src-d/go-mysql-server/sql/parse"
+	"github.com/src-d/go-mysql-server/sql/plan"
+)
+
+// PreparedQuery is a query that may contain ? placeholders, which is parsed
+// and analyzed once so it can be executed many times with QueryPrepared,
+// binding different values to its placeholders on each execution.
+//
+// The plan of the query is analyzed again when it's executed after the
+// tables, functions or indexes of the catalog change, or in a different
+// current database. Queries whose plans can't be reused, such as the ones
+// with session variables or script UDFs, are analyzed on every execution.
+// It is safe to use concurrently.
+type PreparedQuery struct {
+	// Query is the text of the query.
+	Query string
+	// Node is the analyzed plan of the query, with its placeholders unbound.
+	Node sql.Node
+
+	parsed sql.Node
+
+	mu              sync.Mutex
+	db              string
+	generation      uint64
+	indexGeneration uint64
+	reusable        bool
+}
+
+// PrepareQuery parses and analyzes a query that may contain ? placeholders
+// without executing it. The node of the resulting query can be used to
+// describe the statement, and the query can be executed with QueryPrepared.
+func (e *Engine) PrepareQuery(ctx *sql.Context, query string) (*PreparedQuery, error) {
+	parsed, err := parse.Parse(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	q := &PreparedQuery{Query: query, parsed: parsed}
+	if err := e.analyzePrepared(ctx, q); err != nil {
+		return nil, err
+	}
+
+	return q, nil
+}
+
+// Async returns true if the query is async.
+func (q *PreparedQuery) Async() bool {
+	asyncNode, ok := q.parsed.(sql.AsyncNode)
+	return ok && asyncNode.IsAsync()
+}
+
+// QueryPrepared executes a prepared query replacing its placeholders with
+// the expressions bound to their names, which are v1, v2 and so on in the
+// order they appear in the query.
+func (e *Engine) QueryPrepared(
+	ctx *sql.Context,
+	q *PreparedQuery,
+	bindings map[string]sql.Expression,
+) (sql.Schema, sql.RowIter, error) {
+	var err error
+
+	finish := observeQuery(ctx, q.Query)
+	defer finish(err)
+
+	return e.run(ctx, q.Query, q.parsed, func(ctx *sql.Context) (sql.Node, error) {
+		node, err := e.preparedPlan(ctx, q)
+		if err != nil {
+			return nil, err
+		}
+
+		if node == nil {
+			parsed, err := e.parse(ctx, q.Query, bindings)
+			if err != nil {
+				return nil, err
+			}
+			return e.Analyzer.Analyze(ctx, parsed)
+		}
+
+		// Binding the placeholders copies all the nodes and expressions of
+		// the plan, so executions don't share them.
+		bound, err := plan.ApplyBindings(node, bindings)
+		if err != nil {
+			return nil, err
+		}
+		return e.Analyzer.AnalyzeExecution(ctx, bound)
+	})
+}
+
+// preparedPlan returns the plan of the prepared query, analyzing it again
+// if it's no longer valid, or nil if the plan can't be reused.
+func (e *Engine) preparedPlan(ctx *sql.Context, q *PreparedQuery) (sql.Node, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.db != e.Catalog.CurrentDatabase() ||
+		q.generation != atomic.LoadUint64(&e.generation) ||
+		q.indexGeneration != e.Catalog.IndexGeneration() {
+		if err := e.analyzePrepared(ctx, q); err != nil {
+			return nil, err
+		}
+	}
+
+	if !q.reusable {
+		return nil, nil
+	}
+	return q.Node, nil
+}
+
+// analyzePrepared analyzes the plan of the prepared query, which can be
+// reused if the query is not affected by the functions of the context and
+// its plan can be run many times.
+func (e *Engine) analyzePrepared(ctx *sql.Context, q *PreparedQuery) error {
+	db := e.Catalog.CurrentDatabase()
+	generation := atomic.LoadUint64(&e.generation)
+	indexGeneration := e.Catalog.IndexGeneration()
+
+	node, err := e.Analyzer.AnalyzePlan(ctx, q.parsed)
+	if err != nil {
+		return err
+	}
+
+	q.Node = node
+	q.db = db
+	q.generation = generation
+	q.indexGeneration = indexGeneration
+	q.reusable = ctx.Functions() == nil && isCacheable(node)
+	return nil
+}
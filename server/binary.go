@@ -0,0 +1,541 @@
+package server
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"gopkg.in/src-d/go-errors.v1"
+
+	"vitess.io/vitess/go/mysql"
+	"vitess.io/vitess/go/sqltypes"
+	"vitess.io/vitess/go/vt/proto/query"
+)
+
+// ErrMalformedPacket is returned when a packet sent by the client can't be
+// decoded.
+var ErrMalformedPacket = errors.NewKind("malformed packet of command %d")
+
+// ErrInvalidTemporalValue is returned when a temporal value of a row can't
+// be encoded in the binary protocol.
+var ErrInvalidTemporalValue = errors.NewKind("invalid %s value: %q")
+
+// unsignedParamFlag is the flag of the unsigned types of the parameters of
+// prepared statements.
+const unsignedParamFlag = 0x80
+
+// packetReader reads the values of a packet in the binary protocol.
+type packetReader struct {
+	cmd  byte
+	data []byte
+	pos  int
+}
+
+func (r *packetReader) next(n int) ([]byte, error) {
+	if n < 0 || r.pos+n > len(r.data) {
+		return nil, ErrMalformedPacket.New(r.cmd)
+	}
+
+	b := r.data[r.pos : r.pos+n]
+	r.pos += n
+	return b, nil
+}
+
+func (r *packetReader) byte() (byte, error) {
+	b, err := r.next(1)
+	if err != nil {
+		return 0, err
+	}
+	return b[0], nil
+}
+
+func (r *packetReader) uint16() (uint16, error) {
+	b, err := r.next(2)
+	if err != nil {
+		return 0, err
+	}
+	return binary.LittleEndian.Uint16(b), nil
+}
+
+func (r *packetReader) uint32() (uint32, error) {
+	b, err := r.next(4)
+	if err != nil {
+		return 0, err
+	}
+	return binary.LittleEndian.Uint32(b), nil
+}
+
+func (r *packetReader) uint64() (uint64, error) {
+	b, err := r.next(8)
+	if err != nil {
+		return 0, err
+	}
+	return binary.LittleEndian.Uint64(b), nil
+}
+
+func (r *packetReader) lenEncInt() (uint64, error) {
+	b, err := r.byte()
+	if err != nil {
+		return 0, err
+	}
+
+	var n int
+	switch b {
+	case 0xfc:
+		n = 2
+	case 0xfd:
+		n = 3
+	case 0xfe:
+		n = 8
+	default:
+		return uint64(b), nil
+	}
+
+	data, err := r.next(n)
+	if err != nil {
+		return 0, err
+	}
+
+	var v uint64
+	for i := n - 1; i >= 0; i-- {
+		v = v<<8 | uint64(data[i])
+	}
+	return v, nil
+}
+
+func (r *packetReader) lenEncString() ([]byte, error) {
+	n, err := r.lenEncInt()
+	if err != nil {
+		return nil, err
+	}
+	if n > uint64(len(r.data)) {
+		return nil, ErrMalformedPacket.New(r.cmd)
+	}
+	return r.next(int(n))
+}
+
+// readParam reads the value of a parameter of the given type in the binary
+// protocol.
+func (r *packetReader) readParam(typ, flags byte) (sqltypes.Value, error) {
+	unsigned := flags&unsignedParamFlag != 0
+
+	switch typ {
+	case mysql.TypeNull:
+		return sqltypes.NULL, nil
+	case mysql.TypeTiny:
+		b, err := r.byte()
+		if err != nil {
+			return sqltypes.NULL, err
+		}
+		if unsigned {
+			return sqltypes.NewUint64(uint64(b)), nil
+		}
+		return sqltypes.NewInt64(int64(int8(b))), nil
+	case mysql.TypeShort, mysql.TypeYear:
+		v, err := r.uint16()
+		if err != nil {
+			return sqltypes.NULL, err
+		}
+		if unsigned {
+			return sqltypes.NewUint64(uint64(v)), nil
+		}
+		return sqltypes.NewInt64(int64(int16(v))), nil
+	case mysql.TypeLong, mysql.TypeInt24:
+		v, err := r.uint32()
+		if err != nil {
+			return sqltypes.NULL, err
+		}
+		if unsigned {
+			return sqltypes.NewUint64(uint64(v)), nil
+		}
+		return sqltypes.NewInt64(int64(int32(v))), nil
+	case mysql.TypeLongLong:
+		v, err := r.uint64()
+		if err != nil {
+			return sqltypes.NULL, err
+		}
+		if unsigned {
+			return sqltypes.NewUint64(v), nil
+		}
+		return sqltypes.NewInt64(int64(v)), nil
+	case mysql.TypeFloat:
+		v, err := r.uint32()
+		if err != nil {
+			return sqltypes.NULL, err
+		}
+		return sqltypes.NewFloat64(float64(math.Float32frombits(v))), nil
+	case mysql.TypeDouble:
+		v, err := r.uint64()
+		if err != nil {
+			return sqltypes.NULL, err
+		}
+		return sqltypes.NewFloat64(math.Float64frombits(v)), nil
+	case mysql.TypeDate, mysql.TypeDateTime, mysql.TypeTimestamp:
+		return r.readDatetime(typ)
+	case mysql.TypeTime:
+		return r.readTime()
+	default:
+		b, err := r.lenEncString()
+		if err != nil {
+			return sqltypes.NULL, err
+		}
+		return stringParam(typ, b), nil
+	}
+}
+
+// stringParam returns the value of a parameter of the given type sent as a
+// string.
+func stringParam(typ byte, b []byte) sqltypes.Value {
+	switch typ {
+	case mysql.TypeDecimal, mysql.TypeNewDecimal:
+		return sqltypes.MakeTrusted(sqltypes.Decimal, b)
+	case mysql.TypeTinyBlob, mysql.TypeMediumBlob, mysql.TypeLongBlob, mysql.TypeBlob:
+		return sqltypes.MakeTrusted(sqltypes.Blob, b)
+	default:
+		return sqltypes.NewVarChar(string(b))
+	}
+}
+
+func (r *packetReader) readDatetime(typ byte) (sqltypes.Value, error) {
+	n, err := r.byte()
+	if err != nil {
+		return sqltypes.NULL, err
+	}
+
+	if n != 0 && n != 4 && n != 7 && n != 11 {
+		return sqltypes.NULL, ErrMalformedPacket.New(r.cmd)
+	}
+
+	b, err := r.next(int(n))
+	if err != nil {
+		return sqltypes.NULL, err
+	}
+
+	var year, month, day, hour, minute, second, micros int
+	if n >= 4 {
+		year = int(binary.LittleEndian.Uint16(b))
+		month, day = int(b[2]), int(b[3])
+	}
+	if n >= 7 {
+		hour, minute, second = int(b[4]), int(b[5]), int(b[6])
+	}
+	if n == 11 {
+		micros = int(binary.LittleEndian.Uint32(b[7:]))
+	}
+
+	if typ == mysql.TypeDate {
+		s := fmt.Sprintf("%04d-%02d-%02d", year, month, day)
+		return sqltypes.MakeTrusted(sqltypes.Date, []byte(s)), nil
+	}
+
+	s := fmt.Sprintf("%04d-%02d-%02d %02d:%02d:%02d", year, month, day, hour, minute, second)
+	if micros > 0 {
+		s += fmt.Sprintf(".%06d", micros)
+	}
+	return sqltypes.MakeTrusted(sqltypes.Datetime, []byte(s)), nil
+}
+
+func (r *packetReader) readTime() (sqltypes.Value, error) {
+	n, err := r.byte()
+	if err != nil {
+		return sqltypes.NULL, err
+	}
+
+	if n != 0 && n != 8 && n != 12 {
+		return sqltypes.NULL, ErrMalformedPacket.New(r.cmd)
+	}
+
+	b, err := r.next(int(n))
+	if err != nil {
+		return sqltypes.NULL, err
+	}
+
+	if n == 0 {
+		return sqltypes.MakeTrusted(sqltypes.Time, []byte("00:00:00")), nil
+	}
+
+	var sign string
+	if b[0] == 1 {
+		sign = "-"
+	}
+
+	hours := int(binary.LittleEndian.Uint32(b[1:]))*24 + int(b[5])
+	s := fmt.Sprintf("%s%02d:%02d:%02d", sign, hours, b[6], b[7])
+	if n == 12 {
+		if micros := binary.LittleEndian.Uint32(b[8:]); micros > 0 {
+			s += fmt.Sprintf(".%06d", micros)
+		}
+	}
+	return sqltypes.MakeTrusted(sqltypes.Time, []byte(s)), nil
+}
+
+// packetBuilder builds the payload of a packet in the binary protocol.
+type packetBuilder struct {
+	data []byte
+}
+
+func (b *packetBuilder) byte(v byte) {
+	b.data = append(b.data, v)
+}
+
+func (b *packetBuilder) uint16(v uint16) {
+	b.data = append(b.data, byte(v), byte(v>>8))
+}
+
+func (b *packetBuilder) uint32(v uint32) {
+	b.data = append(b.data, byte(v), byte(v>>8), byte(v>>16), byte(v>>24))
+}
+
+func (b *packetBuilder) uint64(v uint64) {
+	b.uint32(uint32(v))
+	b.uint32(uint32(v >> 32))
+}
+
+func (b *packetBuilder) lenEncInt(v uint64) {
+	switch {
+	case v < 251:
+		b.byte(byte(v))
+	case v < 1<<16:
+		b.byte(0xfc)
+		b.uint16(uint16(v))
+	case v < 1<<24:
+		b.byte(0xfd)
+		b.data = append(b.data, byte(v), byte(v>>8), byte(v>>16))
+	default:
+		b.byte(0xfe)
+		b.uint64(v)
+	}
+}
+
+func (b *packetBuilder) lenEncString(s []byte) {
+	b.lenEncInt(uint64(len(s)))
+	b.data = append(b.data, s...)
+}
+
+// columnDefinition builds the definition of a column of a result set or a
+// parameter of a prepared statement.
+func columnDefinition(f *query.Field) []byte {
+	typ, flags := sqltypes.TypeToMySQL(f.Type)
+
+	var b packetBuilder
+	b.lenEncString([]byte("def"))
+	b.lenEncString([]byte(f.Database))
+	b.lenEncString([]byte(f.Table))
+	b.lenEncString([]byte(f.OrgTable))
+	b.lenEncString([]byte(f.Name))
+	b.lenEncString([]byte(f.OrgName))
+	b.byte(0x0c)
+	b.uint16(uint16(f.Charset))
+	b.uint32(f.ColumnLength)
+	b.byte(byte(typ))
+	b.uint16(uint16(flags) | uint16(f.Flags))
+	b.byte(byte(f.Decimals))
+	b.uint16(0)
+	return b.data
+}
+
+// binaryRow builds a row of a result set in the binary protocol, whose
+// values are encoded as the types of the given fields.
+func binaryRow(fields []*query.Field, row []sqltypes.Value) ([]byte, error) {
+	// The null bitmap of the rows of a result set starts at the third bit.
+	nulls := make([]byte, (len(fields)+7+2)/8)
+
+	var b packetBuilder
+	b.byte(0x00)
+	b.data = append(b.data, nulls...)
+
+	for i, f := range fields {
+		v := row[i]
+		if v.IsNull() {
+			pos := i + 2
+			b.data[1+pos/8] |= 1 << uint(pos%8)
+			continue
+		}
+
+		if err := appendBinaryValue(&b, f.Type, v); err != nil {
+			return nil, err
+		}
+	}
+
+	return b.data, nil
+}
+
+func appendBinaryValue(b *packetBuilder, typ query.Type, v sqltypes.Value) error {
+	switch typ {
+	case sqltypes.Int8, sqltypes.Uint8, sqltypes.Int16, sqltypes.Uint16,
+		sqltypes.Year, sqltypes.Int24, sqltypes.Uint24, sqltypes.Int32,
+		sqltypes.Uint32, sqltypes.Int64, sqltypes.Uint64:
+		var n uint64
+		if sqltypes.IsUnsigned(typ) {
+			u, err := strconv.ParseUint(v.ToString(), 10, 64)
+			if err != nil {
+				return err
+			}
+			n = u
+		} else {
+			i, err := strconv.ParseInt(v.ToString(), 10, 64)
+			if err != nil {
+				return err
+			}
+			n = uint64(i)
+		}
+
+		switch typ {
+		case sqltypes.Int8, sqltypes.Uint8:
+			b.byte(byte(n))
+		case sqltypes.Int16, sqltypes.Uint16, sqltypes.Year:
+			b.uint16(uint16(n))
+		case sqltypes.Int64, sqltypes.Uint64:
+			b.uint64(n)
+		default:
+			b.uint32(uint32(n))
+		}
+	case sqltypes.Float32:
+		f, err := strconv.ParseFloat(v.ToString(), 32)
+		if err != nil {
+			return err
+		}
+		b.uint32(math.Float32bits(float32(f)))
+	case sqltypes.Float64:
+		f, err := strconv.ParseFloat(v.ToString(), 64)
+		if err != nil {
+			return err
+		}
+		b.uint64(math.Float64bits(f))
+	case sqltypes.Date, sqltypes.Datetime, sqltypes.Timestamp:
+		return appendDatetime(b, typ, v.ToString())
+	case sqltypes.Time:
+		return appendTime(b, v.ToString())
+	default:
+		b.lenEncString(v.ToBytes())
+	}
+
+	return nil
+}
+
+// appendDatetime appends a date, datetime or timestamp written as
+// YYYY-MM-DD[ HH:MM:SS[.ffffff]] in the binary protocol.
+func appendDatetime(b *packetBuilder, typ query.Type, s string) error {
+	var date, clock = s, ""
+	if i := strings.IndexAny(s, " T"); i >= 0 {
+		date, clock = s[:i], s[i+1:]
+	}
+
+	ymd, ok := splitInts(date, "-", 3)
+	if !ok {
+		return ErrInvalidTemporalValue.New(strings.ToLower(typ.String()), s)
+	}
+
+	var hms = []int{0, 0, 0}
+	var micros int
+	if clock != "" {
+		hms, micros, ok = splitClock(clock)
+		if !ok {
+			return ErrInvalidTemporalValue.New(strings.ToLower(typ.String()), s)
+		}
+	}
+
+	switch {
+	case micros != 0:
+		b.byte(11)
+	case hms[0] != 0 || hms[1] != 0 || hms[2] != 0:
+		b.byte(7)
+	case ymd[0] != 0 || ymd[1] != 0 || ymd[2] != 0:
+		b.byte(4)
+	default:
+		b.byte(0)
+		return nil
+	}
+
+	n := b.data[len(b.data)-1]
+	b.uint16(uint16(ymd[0]))
+	b.byte(byte(ymd[1]))
+	b.byte(byte(ymd[2]))
+	if n >= 7 {
+		b.byte(byte(hms[0]))
+		b.byte(byte(hms[1]))
+		b.byte(byte(hms[2]))
+	}
+	if n == 11 {
+		b.uint32(uint32(micros))
+	}
+
+	return nil
+}
+
+// appendTime appends a time written as [-]HH:MM:SS[.ffffff] in the binary
+// protocol.
+func appendTime(b *packetBuilder, s string) error {
+	var negative byte
+	clock := s
+	if strings.HasPrefix(clock, "-") {
+		negative, clock = 1, clock[1:]
+	}
+
+	hms, micros, ok := splitClock(clock)
+	if !ok {
+		return ErrInvalidTemporalValue.New("time", s)
+	}
+
+	switch {
+	case micros != 0:
+		b.byte(12)
+	case hms[0] != 0 || hms[1] != 0 || hms[2] != 0:
+		b.byte(8)
+	default:
+		b.byte(0)
+		return nil
+	}
+
+	b.byte(negative)
+	b.uint32(uint32(hms[0] / 24))
+	b.byte(byte(hms[0] % 24))
+	b.byte(byte(hms[1]))
+	b.byte(byte(hms[2]))
+	if micros != 0 {
+		b.uint32(uint32(micros))
+	}
+
+	return nil
+}
+
+// splitClock splits a time written as HH:MM:SS[.ffffff] in its hours,
+// minutes and seconds, and its microseconds.
+func splitClock(s string) ([]int, int, bool) {
+	var micros int
+	if i := strings.IndexByte(s, '.'); i >= 0 {
+		frac := s[i+1:]
+		if len(frac) > 6 {
+			frac = frac[:6]
+		}
+		frac += strings.Repeat("0", 6-len(frac))
+
+		n, err := strconv.Atoi(frac)
+		if err != nil {
+			return nil, 0, false
+		}
+		s, micros = s[:i], n
+	}
+
+	hms, ok := splitInts(s, ":", 3)
+	return hms, micros, ok
+}
+
+func splitInts(s, sep string, n int) ([]int, bool) {
+	parts := strings.Split(s, sep)
+	if len(parts) != n {
+		return nil, false
+	}
+
+	ints := make([]int, n)
+	for i, p := range parts {
+		v, err := strconv.Atoi(p)
+		if err != nil || v < 0 {
+			return nil, false
+		}
+		ints[i] = v
+	}
+	return ints, true
+}
@@ -0,0 +1,81 @@
+package server
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"vitess.io/vitess/go/mysql"
+	"vitess.io/vitess/go/sqltypes"
+	"vitess.io/vitess/go/vt/proto/query"
+)
+
+func TestBinaryTemporalValues(t *testing.T) {
+	testCases := []struct {
+		typ      query.Type
+		mysqlTyp byte
+		value    string
+		size     byte
+	}{
+		{sqltypes.Date, mysql.TypeDate, "2019-01-02", 4},
+		{sqltypes.Date, mysql.TypeDate, "0000-00-00", 0},
+		{sqltypes.Datetime, mysql.TypeDateTime, "2019-01-02 03:04:05", 7},
+		{sqltypes.Datetime, mysql.TypeDateTime, "2019-01-02 00:00:00", 4},
+		{sqltypes.Timestamp, mysql.TypeTimestamp, "2019-01-02 03:04:05.000006", 11},
+		{sqltypes.Time, mysql.TypeTime, "-27:04:05", 8},
+		{sqltypes.Time, mysql.TypeTime, "03:04:05.123456", 12},
+		{sqltypes.Time, mysql.TypeTime, "00:00:00", 0},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.value, func(t *testing.T) {
+			require := require.New(t)
+
+			var b packetBuilder
+			v := sqltypes.MakeTrusted(tt.typ, []byte(tt.value))
+			require.NoError(appendBinaryValue(&b, tt.typ, v))
+			require.Equal(tt.size, b.data[0])
+
+			r := &packetReader{data: b.data}
+			decoded, err := r.readParam(tt.mysqlTyp, 0)
+			require.NoError(err)
+			require.Equal(len(b.data), r.pos)
+
+			expected := tt.value
+			if tt.size == 4 && tt.typ != sqltypes.Date {
+				expected = tt.value[:10] + " 00:00:00"
+			}
+			require.Equal(expected, decoded.ToString())
+		})
+	}
+
+	var b packetBuilder
+	err := appendBinaryValue(&b, sqltypes.Datetime, sqltypes.NewVarChar("yesterday"))
+	require.True(t, ErrInvalidTemporalValue.Is(err))
+}
+
+func TestBinaryRow(t *testing.T) {
+	require := require.New(t)
+
+	fields := []*query.Field{
+		{Name: "a", Type: sqltypes.Int8},
+		{Name: "b", Type: sqltypes.Uint32},
+		{Name: "c", Type: sqltypes.Text},
+		{Name: "d", Type: sqltypes.Int64},
+	}
+
+	data, err := binaryRow(fields, []sqltypes.Value{
+		sqltypes.NewInt8(-1),
+		sqltypes.NewUint32(4294967295),
+		sqltypes.NULL,
+		sqltypes.NewInt64(258),
+	})
+	require.NoError(err)
+	require.Equal([]byte{
+		0x00,
+		// The third column is null, which is the fifth bit of the bitmap.
+		0x10,
+		0xff,
+		0xff, 0xff, 0xff, 0xff,
+		0x02, 0x01, 0, 0, 0, 0, 0, 0,
+	}, data)
+}
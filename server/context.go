@@ -32,33 +32,33 @@ type SessionManager struct {
 	mu       *sync.Mutex
 	builder  SessionBuilder
 	sessions map[uint32]sql.Session
-	pid      uint64
+	// processes is the process list of the engine, which gives the pids
+	// of the queries, so they don't collide with the ones of other
+	// sessions of the engine.
+	processes *sql.ProcessList
 }
 
 // NewSessionManager creates a SessionManager with the given SessionBuilder.
+// The pids of the queries are taken from the given process list, which must
+// be the one of the engine.
 func NewSessionManager(
 	builder SessionBuilder,
 	tracer opentracing.Tracer,
 	memory *sql.MemoryManager,
+	processes *sql.ProcessList,
 	addr string,
 ) *SessionManager {
 	return &SessionManager{
-		addr:     addr,
-		tracer:   tracer,
-		memory:   memory,
-		mu:       new(sync.Mutex),
-		builder:  builder,
-		sessions: make(map[uint32]sql.Session),
+		addr:      addr,
+		tracer:    tracer,
+		memory:    memory,
+		mu:        new(sync.Mutex),
+		builder:   builder,
+		sessions:  make(map[uint32]sql.Session),
+		processes: processes,
 	}
 }
 
-func (s *SessionManager) nextPid() uint64 {
-	s.mu.Lock()
-	defer s.mu.Unlock()
-	s.pid++
-	return s.pid
-}
-
 // NewSession creates a Session for the given connection and saves it to
 // session pool.
 func (s *SessionManager) NewSession(conn *mysql.Conn) {
@@ -95,7 +95,7 @@ func (s *SessionManager) NewContextWithQuery(
 		context.Background(),
 		sql.WithSession(sess),
 		sql.WithTracer(s.tracer),
-		sql.WithPid(s.nextPid()),
+		sql.WithPid(s.processes.NextPid()),
 		sql.WithQuery(query),
 		sql.WithMemoryManager(s.memory),
 		sql.WithRootSpan(s.tracer.StartSpan("query")),
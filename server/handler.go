@@ -49,6 +49,7 @@ type Handler struct {
 	c           map[uint32]conntainer
 	readTimeout time.Duration
 	lc          []*net.Conn
+	stmts       *preparedStatements
 }
 
 // NewHandler creates a new Handler given a SQLe engine.
@@ -58,6 +59,7 @@ func NewHandler(e *sqle.Engine, sm *SessionManager, rt time.Duration) *Handler {
 		sm:          sm,
 		c:           make(map[uint32]conntainer),
 		readTimeout: rt,
+		stmts:       newPreparedStatements(),
 	}
 }
 
@@ -67,8 +69,12 @@ func (h *Handler) AddNetConnection(c *net.Conn) {
 	h.lc = append(h.lc, c)
 }
 
-// NewConnection reports that a new connection has been established.
+// NewConnection reports that a new connection has been established. Its ID
+// is replaced with one taken from the engine, so it doesn't collide with the
+// IDs of the sessions of the engine that are not connections of the server.
 func (h *Handler) NewConnection(c *mysql.Conn) {
+	c.ConnectionID = h.e.Catalog.NextConnectionID()
+
 	h.mu.Lock()
 	if _, ok := h.c[c.ConnectionID]; !ok {
 		// Retrieve the latest net.Conn stored by Listener.Accept(), if called, and remove it
@@ -90,12 +96,23 @@ func (h *Handler) NewConnection(c *mysql.Conn) {
 
 // ConnectionClosed reports that a connection has been closed.
 func (h *Handler) ConnectionClosed(c *mysql.Conn) {
+	// Any transaction in progress is rolled back, so its changes don't stay
+	// in the tables.
+	if sess := h.sm.session(c); sess != nil && sess.Transaction() != nil {
+		ctx := sql.NewContext(context.Background(), sql.WithSession(sess))
+		if err := sess.Transaction().Rollback(ctx); err != nil {
+			logrus.Errorf("unable to rollback transaction on session close: %s", err)
+		}
+	}
+
 	h.sm.CloseConn(c)
 
 	h.mu.Lock()
 	delete(h.c, c.ConnectionID)
 	h.mu.Unlock()
 
+	h.stmts.closeConn(c.ConnectionID)
+
 	// If connection was closed, kill only its associated queries.
 	h.e.Catalog.ProcessList.KillOnlyQueries(c.ConnectionID)
 
@@ -111,10 +128,29 @@ func (h *Handler) ComQuery(
 	c *mysql.Conn,
 	query string,
 	callback func(*sqltypes.Result) error,
+) error {
+	return h.doQuery(c, query, nil, nil, callback)
+}
+
+// doQuery executes the given query, or the given prepared query if it's not
+// nil, with the given bindings for its placeholders.
+func (h *Handler) doQuery(
+	c *mysql.Conn,
+	query string,
+	prepared *sqle.PreparedQuery,
+	bindings map[string]sql.Expression,
+	callback func(*sqltypes.Result) error,
 ) (err error) {
 	ctx := h.sm.NewContextWithQuery(c, query)
 
-	if !h.e.Async(ctx, query) {
+	var async bool
+	if prepared != nil {
+		async = prepared.Async()
+	} else {
+		async = h.e.Async(ctx, query)
+	}
+
+	if !async {
 		newCtx, cancel := context.WithCancel(ctx)
 		ctx = ctx.WithContext(newCtx)
 
@@ -130,8 +166,15 @@ func (h *Handler) ComQuery(
 		return callback(&sqltypes.Result{})
 	}
 
+	var schema sql.Schema
+	var rows sql.RowIter
+
 	start := time.Now()
-	schema, rows, err := h.e.Query(ctx, query)
+	if prepared != nil {
+		schema, rows, err = h.e.QueryPrepared(ctx, prepared, bindings)
+	} else {
+		schema, rows, err = h.e.QueryWithBindings(ctx, query, bindings)
+	}
 	defer func() {
 		if q, ok := h.e.Auth.(*auth.Audit); ok {
 			q.Query(ctx, time.Since(start), err)
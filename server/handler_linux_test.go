@@ -30,6 +30,7 @@ func TestBrokenConnection(t *testing.T) {
 			testSessionBuilder,
 			opentracing.NoopTracer{},
 			sql.NewMemoryManager(nil),
+			e.Catalog.ProcessList,
 			"foo",
 		),
 		0,
@@ -1,12 +1,14 @@
 package server
 
 import (
+	gosql "database/sql"
 	"fmt"
 	"net"
 	"testing"
 	"time"
 
 	sqle "github.com/src-d/go-mysql-server"
+	"github.com/src-d/go-mysql-server/driver"
 	"github.com/src-d/go-mysql-server/sql"
 
 	"vitess.io/vitess/go/mysql"
@@ -27,6 +29,7 @@ func TestHandlerOutput(t *testing.T) {
 			testSessionBuilder,
 			opentracing.NoopTracer{},
 			sql.NewMemoryManager(nil),
+			e.Catalog.ProcessList,
 			"foo",
 		),
 		0,
@@ -136,6 +139,7 @@ func TestHandlerKill(t *testing.T) {
 			},
 			opentracing.NoopTracer{},
 			sql.NewMemoryManager(nil),
+			e.Catalog.ProcessList,
 			"foo",
 		),
 		0,
@@ -193,6 +197,43 @@ func assertNoConnProcesses(t *testing.T, e *sqle.Engine, conn uint32) {
 	}
 }
 
+func TestHandlerAndDriverIDs(t *testing.T) {
+	require := require.New(t)
+	e := setupMemDB(require)
+
+	handler := NewHandler(
+		e,
+		NewSessionManager(
+			testSessionBuilder,
+			opentracing.NoopTracer{},
+			sql.NewMemoryManager(nil),
+			e.Catalog.ProcessList,
+			"foo",
+		),
+		0,
+	)
+
+	db := gosql.OpenDB(driver.NewConnector(e))
+	defer db.Close()
+
+	// The query of the driver stays in the process list until its rows are
+	// closed.
+	rows, err := db.Query("SELECT c1 FROM test")
+	require.NoError(err)
+	defer rows.Close()
+
+	conn := newConn(1)
+	handler.NewConnection(conn)
+	err = handler.ComQuery(conn, "SELECT c1 FROM test LIMIT 1", func(res *sqltypes.Result) error {
+		return nil
+	})
+	require.NoError(err)
+
+	procs := e.Catalog.Processes()
+	require.Len(procs, 1)
+	require.NotEqual(procs[0].Connection, conn.ConnectionID)
+}
+
 func TestSchemaToFields(t *testing.T) {
 	require := require.New(t)
 
@@ -222,6 +263,7 @@ func TestHandlerTimeout(t *testing.T) {
 		e, NewSessionManager(testSessionBuilder,
 			opentracing.NoopTracer{},
 			sql.NewMemoryManager(nil),
+			e.Catalog.ProcessList,
 			"foo"),
 		1*time.Second)
 
@@ -229,6 +271,7 @@ func TestHandlerTimeout(t *testing.T) {
 		e2, NewSessionManager(testSessionBuilder,
 			opentracing.NoopTracer{},
 			sql.NewMemoryManager(nil),
+			e2.Catalog.ProcessList,
 			"foo"),
 		0)
 	require.Equal(1*time.Second, timeOutHandler.readTimeout)
@@ -277,6 +320,7 @@ func TestOkClosedConnection(t *testing.T) {
 			testSessionBuilder,
 			opentracing.NoopTracer{},
 			sql.NewMemoryManager(nil),
+			e.Catalog.ProcessList,
 			"foo",
 		),
 		0,
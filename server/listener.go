@@ -25,5 +25,5 @@ func (l *Listener) Accept() (net.Conn, error) {
 	}
 
 	l.h.AddNetConnection(&conn)
-	return conn, err
+	return newStmtConn(conn, l.h), nil
 }
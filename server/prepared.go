@@ -0,0 +1,214 @@
+package server
+
+import (
+	"strconv"
+	"sync"
+
+	sqle "github.com/src-d/go-mysql-server"
+	"github.com/src-d/go-mysql-server/sql"
+	"github.com/src-d/go-mysql-server/sql/expression"
+	"github.com/src-d/go-mysql-server/sql/plan"
+	"gopkg.in/src-d/go-errors.v1"
+
+	"vitess.io/vitess/go/mysql"
+	"vitess.io/vitess/go/sqltypes"
+	"vitess.io/vitess/go/vt/proto/query"
+)
+
+// ErrUnknownPreparedStatement is returned when a prepared statement does not
+// exist in the connection.
+var ErrUnknownPreparedStatement = errors.NewKind("unknown prepared statement handler (%d) given to %s")
+
+// ErrMissingParameter is returned when a prepared statement is executed
+// without a value for one of its parameters.
+var ErrMissingParameter = errors.NewKind("missing value for parameter %d of prepared statement %d")
+
+// ErrUnsupportedParameter is returned when the value of a parameter of a
+// prepared statement has a type that can't be used in a query.
+var ErrUnsupportedParameter = errors.NewKind("unsupported value for parameter %s of type %s")
+
+// PreparedStatement is a statement prepared in a connection, which can be
+// executed several times with different values for its parameters.
+type PreparedStatement struct {
+	// ID identifies the statement in its connection.
+	ID uint32
+	// Query is the text of the statement, with ? placeholders for its
+	// parameters.
+	Query string
+	// Params describes the parameters of the statement, in order.
+	Params []*query.Field
+	// Fields describes the columns of the rows returned by the statement.
+	Fields []*query.Field
+
+	prepared *sqle.PreparedQuery
+	bindVars []string
+
+	// paramTypes are the types of the parameters sent by the client in the
+	// last execution, which are reused if the next one doesn't send them.
+	paramTypes []byte
+	// longData are the values of the parameters sent in chunks before
+	// executing the statement.
+	longData map[int][]byte
+}
+
+// preparedStatements keeps the statements prepared in each connection.
+type preparedStatements struct {
+	mu     sync.Mutex
+	lastID uint32
+	conns  map[uint32]map[uint32]*PreparedStatement
+}
+
+func newPreparedStatements() *preparedStatements {
+	return &preparedStatements{
+		conns: make(map[uint32]map[uint32]*PreparedStatement),
+	}
+}
+
+func (p *preparedStatements) add(connID uint32, stmt *PreparedStatement) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.lastID++
+	stmt.ID = p.lastID
+
+	stmts, ok := p.conns[connID]
+	if !ok {
+		stmts = make(map[uint32]*PreparedStatement)
+		p.conns[connID] = stmts
+	}
+	stmts[stmt.ID] = stmt
+}
+
+func (p *preparedStatements) get(connID, id uint32) (*PreparedStatement, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	stmt, ok := p.conns[connID][id]
+	return stmt, ok
+}
+
+func (p *preparedStatements) remove(connID, id uint32) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	delete(p.conns[connID], id)
+}
+
+func (p *preparedStatements) closeConn(connID uint32) {
+	p.mu.Lock()
+	delete(p.conns, connID)
+	p.mu.Unlock()
+}
+
+// ComPrepare prepares the given query, which may contain ? placeholders for
+// its parameters, in the connection. The statement is analyzed once, and
+// its plan is reused by every execution.
+func (h *Handler) ComPrepare(c *mysql.Conn, q string) (*PreparedStatement, error) {
+	ctx := h.sm.NewContextWithQuery(c, q)
+	prepared, err := h.e.PrepareQuery(ctx, q)
+	if err != nil {
+		return nil, err
+	}
+
+	node := prepared.Node
+
+	bindVars := plan.BindVars(node)
+	types := plan.BindVarTypes(node)
+	params := make([]*query.Field, len(bindVars))
+	for i, name := range bindVars {
+		typ, ok := types[name]
+		if !ok {
+			typ = sql.Text
+		}
+
+		params[i] = &query.Field{
+			Name:    "?",
+			Type:    typ.Type(),
+			Charset: mysql.CharacterSetUtf8,
+		}
+	}
+
+	stmt := &PreparedStatement{
+		Query:    q,
+		Params:   params,
+		Fields:   schemaToFields(node.Schema()),
+		prepared: prepared,
+		bindVars: bindVars,
+	}
+	h.stmts.add(c.ConnectionID, stmt)
+
+	return stmt, nil
+}
+
+// ComStmtExecute executes the prepared statement with the given id in the
+// connection. The values of the parameters are bound by name, which are v1,
+// v2 and so on in the order the parameters appear in the statement.
+func (h *Handler) ComStmtExecute(
+	c *mysql.Conn,
+	id uint32,
+	bindVars map[string]*query.BindVariable,
+	callback func(*sqltypes.Result) error,
+) error {
+	stmt, ok := h.stmts.get(c.ConnectionID, id)
+	if !ok {
+		return ErrUnknownPreparedStatement.New(id, "mysqld_stmt_execute")
+	}
+
+	bindings := make(map[string]sql.Expression, len(stmt.bindVars))
+	for i, name := range stmt.bindVars {
+		bv, ok := bindVars[name]
+		if !ok {
+			return ErrMissingParameter.New(i+1, id)
+		}
+
+		expr, err := bindVarToExpression(name, bv)
+		if err != nil {
+			return err
+		}
+		bindings[name] = expr
+	}
+
+	return h.doQuery(c, stmt.Query, stmt.prepared, bindings, callback)
+}
+
+// ComStmtClose removes the prepared statement with the given id from the
+// connection.
+func (h *Handler) ComStmtClose(c *mysql.Conn, id uint32) {
+	h.stmts.remove(c.ConnectionID, id)
+}
+
+func bindVarToExpression(name string, bv *query.BindVariable) (sql.Expression, error) {
+	v, err := sqltypes.BindVariableToValue(bv)
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case v.IsNull():
+		return expression.NewLiteral(nil, sql.Null), nil
+	case v.IsSigned():
+		n, err := sqltypes.ToInt64(v)
+		if err != nil {
+			return nil, err
+		}
+		return expression.NewLiteral(n, sql.Int64), nil
+	case v.IsUnsigned():
+		n, err := sqltypes.ToUint64(v)
+		if err != nil {
+			return nil, err
+		}
+		return expression.NewLiteral(n, sql.Uint64), nil
+	case v.IsFloat() || v.Type() == sqltypes.Decimal:
+		n, err := strconv.ParseFloat(v.ToString(), 64)
+		if err != nil {
+			return nil, err
+		}
+		return expression.NewLiteral(n, sql.Float64), nil
+	case v.Type() == sqltypes.Blob:
+		return expression.NewLiteral(v.ToBytes(), sql.Blob), nil
+	case v.IsQuoted():
+		return expression.NewLiteral(v.ToString(), sql.Text), nil
+	default:
+		return nil, ErrUnsupportedParameter.New(name, v.Type())
+	}
+}
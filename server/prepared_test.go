@@ -0,0 +1,166 @@
+package server
+
+import (
+	gosql "database/sql"
+	"testing"
+	"time"
+
+	_ "github.com/go-sql-driver/mysql"
+	"github.com/opentracing/opentracing-go"
+	"github.com/src-d/go-mysql-server/auth"
+	"github.com/src-d/go-mysql-server/sql"
+	"github.com/stretchr/testify/require"
+	"vitess.io/vitess/go/mysql"
+	"vitess.io/vitess/go/sqltypes"
+	"vitess.io/vitess/go/vt/proto/query"
+)
+
+func TestPreparedStatements(t *testing.T) {
+	require := require.New(t)
+
+	e := setupMemDB(require)
+	conn := &mysql.Conn{ConnectionID: 1}
+	handler := NewHandler(
+		e,
+		NewSessionManager(
+			testSessionBuilder,
+			opentracing.NoopTracer{},
+			sql.NewMemoryManager(nil),
+			e.Catalog.ProcessList,
+			"foo",
+		),
+		0,
+	)
+	handler.NewConnection(conn)
+
+	stmt, err := handler.ComPrepare(conn, "SELECT c1 FROM test WHERE c1 > ? AND c1 <= ?")
+	require.NoError(err)
+	require.Equal([]*query.Field{
+		{Name: "?", Type: query.Type_INT32, Charset: mysql.CharacterSetUtf8},
+		{Name: "?", Type: query.Type_INT32, Charset: mysql.CharacterSetUtf8},
+	}, stmt.Params)
+	require.Equal([]*query.Field{
+		{Name: "c1", Type: query.Type_INT32, Charset: mysql.CharacterSetUtf8},
+	}, stmt.Fields)
+
+	var rows [][]sqltypes.Value
+	callback := func(r *sqltypes.Result) error {
+		rows = append(rows, r.Rows...)
+		return nil
+	}
+
+	err = handler.ComStmtExecute(conn, stmt.ID, map[string]*query.BindVariable{
+		"v1": sqltypes.Int64BindVariable(5),
+		"v2": sqltypes.Int64BindVariable(8),
+	}, callback)
+	require.NoError(err)
+	require.Equal([][]sqltypes.Value{
+		{sqltypes.NewInt32(6)},
+		{sqltypes.NewInt32(7)},
+		{sqltypes.NewInt32(8)},
+	}, rows)
+
+	rows = nil
+	err = handler.ComStmtExecute(conn, stmt.ID, map[string]*query.BindVariable{
+		"v1": sqltypes.StringBindVariable("1000"),
+		"v2": sqltypes.Int64BindVariable(1007),
+	}, callback)
+	require.NoError(err)
+	require.Len(rows, 7)
+
+	err = handler.ComStmtExecute(conn, stmt.ID, map[string]*query.BindVariable{
+		"v1": sqltypes.Int64BindVariable(5),
+	}, callback)
+	require.True(ErrMissingParameter.Is(err))
+
+	handler.ComStmtClose(conn, stmt.ID)
+	err = handler.ComStmtExecute(conn, stmt.ID, nil, callback)
+	require.True(ErrUnknownPreparedStatement.Is(err))
+
+	stmt, err = handler.ComPrepare(conn, "INSERT INTO test (c1) VALUES (?)")
+	require.NoError(err)
+	require.Equal([]*query.Field{
+		{Name: "?", Type: query.Type_INT32, Charset: mysql.CharacterSetUtf8},
+	}, stmt.Params)
+
+	err = handler.ComStmtExecute(conn, stmt.ID, map[string]*query.BindVariable{
+		"v1": sqltypes.Int64BindVariable(2000),
+	}, callback)
+	require.NoError(err)
+
+	handler.ConnectionClosed(conn)
+	_, ok := handler.stmts.get(conn.ConnectionID, stmt.ID)
+	require.False(ok)
+}
+
+func TestPreparedStatementsProtocol(t *testing.T) {
+	require := require.New(t)
+
+	port, err := getFreePort()
+	require.NoError(err)
+
+	s, err := NewDefaultServer(Config{
+		Protocol: "tcp",
+		Address:  "localhost:" + port,
+		Auth:     new(auth.None),
+	}, setupMemDB(require))
+	require.NoError(err)
+	go s.Start()
+	defer s.Close()
+
+	db, err := gosql.Open("mysql", "root:@tcp(127.0.0.1:"+port+")/test")
+	require.NoError(err)
+	defer db.Close()
+
+	stmt, err := db.Prepare("SELECT c1 FROM test WHERE c1 > ? AND c1 <= ?")
+	require.NoError(err)
+
+	queryInts := func(args ...interface{}) []int32 {
+		rows, err := stmt.Query(args...)
+		require.NoError(err)
+		defer rows.Close()
+
+		var result []int32
+		for rows.Next() {
+			var n int32
+			require.NoError(rows.Scan(&n))
+			result = append(result, n)
+		}
+		require.NoError(rows.Err())
+		return result
+	}
+
+	require.Equal([]int32{6, 7, 8}, queryInts(5, 8))
+	require.Equal([]int32{1008, 1009}, queryInts(int64(1007), "1009"))
+	require.Nil(queryInts(nil, 8))
+	require.NoError(stmt.Close())
+
+	var (
+		text    string
+		null    gosql.NullString
+		float   float64
+		date    string
+		created string
+	)
+	err = db.QueryRow(
+		"SELECT ?, ?, ?, ?, ? FROM test WHERE c1 = ?",
+		"foo", nil, 1.5, "2019-01-02", time.Date(2019, 1, 2, 3, 4, 5, 0, time.UTC), 1,
+	).Scan(&text, &null, &float, &date, &created)
+	require.NoError(err)
+	require.Equal("foo", text)
+	require.False(null.Valid)
+	require.Equal(1.5, float)
+	require.Equal("2019-01-02", date)
+	require.Equal("2019-01-02 03:04:05", created)
+
+	var n int
+	require.NoError(db.QueryRow("INSERT INTO test (c1) VALUES (?)", 2000).Scan(&n))
+	require.Equal(1, n)
+
+	require.NoError(db.QueryRow("SELECT COUNT(*) FROM test WHERE c1 >= ?", 2000).Scan(&n))
+	require.Equal(1, n)
+
+	_, err = db.Query("SELECT c1 FROM missing WHERE c1 = ?", 1)
+	require.Error(err)
+	require.Contains(err.Error(), "table not found: missing")
+}
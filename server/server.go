@@ -59,6 +59,7 @@ func NewServer(cfg Config, e *sqle.Engine, sb SessionBuilder) (*Server, error) {
 		NewSessionManager(
 			sb, tracer,
 			e.Catalog.MemoryManager,
+			e.Catalog.ProcessList,
 			cfg.Address),
 		cfg.ConnReadTimeout)
 	a := cfg.Auth.Mysql()
@@ -0,0 +1,496 @@
+package server
+
+import (
+	"bufio"
+	"io"
+	"net"
+
+	"github.com/sirupsen/logrus"
+	"vitess.io/vitess/go/mysql"
+	"vitess.io/vitess/go/sqltypes"
+	"vitess.io/vitess/go/vt/proto/query"
+)
+
+// Commands of the prepared statements in the binary protocol.
+const (
+	comStmtPrepare      = 0x16
+	comStmtExecute      = 0x17
+	comStmtSendLongData = 0x18
+	comStmtClose        = 0x19
+	comStmtReset        = 0x1a
+)
+
+// maxPacketSize is the maximum size of the payload of a packet. Larger
+// payloads are split in several packets.
+const maxPacketSize = 1<<24 - 1
+
+// stmtConn is the connection of a client that handles the commands of the
+// prepared statements, which the vitess server doesn't dispatch to the
+// handler. The rest of the packets are read by the server as usual.
+//
+// Packets are read one command at a time, and the commands of the prepared
+// statements are handled when the server reads the next command, so they
+// are handled in order with the rest. Once the client asks for SSL, the
+// packets are encrypted and they are all read by the server.
+type stmtConn struct {
+	net.Conn
+	h *Handler
+	r *bufio.Reader
+	w *bufio.Writer
+
+	conn        *mysql.Conn
+	handshaken  bool
+	passthrough bool
+	pending     []byte
+}
+
+func newStmtConn(conn net.Conn, h *Handler) *stmtConn {
+	return &stmtConn{
+		Conn: conn,
+		h:    h,
+		r:    bufio.NewReader(conn),
+		w:    bufio.NewWriter(conn),
+	}
+}
+
+// Read reads the packets of the next command that is not a command of the
+// prepared statements, handling the ones that are.
+func (c *stmtConn) Read(p []byte) (int, error) {
+	for len(c.pending) == 0 {
+		if c.passthrough {
+			return c.r.Read(p)
+		}
+
+		raw, payload, seq, err := c.readCommand()
+		if err != nil {
+			return 0, err
+		}
+
+		if !c.handshaken {
+			// The first packet of the client is the response to the
+			// handshake, or the request to switch to SSL.
+			c.handshaken = true
+			c.passthrough = isSSLRequest(payload)
+		} else if seq == 0 && isStmtCommand(payload) {
+			if conn := c.mysqlConn(); conn != nil {
+				if err := c.h.handleStmtCommand(conn, payload, c.packets(seq+1)); err != nil {
+					return 0, err
+				}
+				continue
+			}
+		}
+
+		c.pending = raw
+	}
+
+	n := copy(p, c.pending)
+	c.pending = c.pending[n:]
+	return n, nil
+}
+
+// readCommand reads all the packets of the next command. It returns the
+// packets as they were read, the payload of the command and the sequence
+// number of its last packet.
+func (c *stmtConn) readCommand() (raw, payload []byte, seq byte, err error) {
+	for {
+		var header [4]byte
+		if _, err := io.ReadFull(c.r, header[:]); err != nil {
+			return nil, nil, 0, err
+		}
+
+		size := int(header[0]) | int(header[1])<<8 | int(header[2])<<16
+		data := make([]byte, size)
+		if _, err := io.ReadFull(c.r, data); err != nil {
+			return nil, nil, 0, err
+		}
+
+		raw = append(append(raw, header[:]...), data...)
+		payload = append(payload, data...)
+		seq = header[3]
+
+		if size < maxPacketSize {
+			return raw, payload, seq, nil
+		}
+	}
+}
+
+// mysqlConn returns the connection of the vitess server that reads from
+// this connection, or nil if the handler doesn't know it.
+func (c *stmtConn) mysqlConn() *mysql.Conn {
+	if c.conn == nil {
+		c.conn = c.h.connByAddr(c.Conn.RemoteAddr())
+	}
+	return c.conn
+}
+
+func (c *stmtConn) packets(seq byte) *packetWriter {
+	return &packetWriter{w: c.w, seq: seq}
+}
+
+func isSSLRequest(payload []byte) bool {
+	// The SSL request only has the capabilities of the client, its maximum
+	// packet size, its character set and 23 bytes of padding.
+	if len(payload) != 32 {
+		return false
+	}
+
+	flags := uint32(payload[0]) | uint32(payload[1])<<8 |
+		uint32(payload[2])<<16 | uint32(payload[3])<<24
+	return flags&mysql.CapabilityClientSSL != 0
+}
+
+func isStmtCommand(payload []byte) bool {
+	return len(payload) > 0 &&
+		payload[0] >= comStmtPrepare &&
+		payload[0] <= comStmtReset
+}
+
+// packetWriter writes the packets of the response to a command.
+type packetWriter struct {
+	w   *bufio.Writer
+	seq byte
+}
+
+// write writes the given payload, split in as many packets as needed.
+func (w *packetWriter) write(payload []byte) error {
+	for {
+		size := len(payload)
+		if size > maxPacketSize {
+			size = maxPacketSize
+		}
+
+		header := [4]byte{byte(size), byte(size >> 8), byte(size >> 16), w.seq}
+		w.seq++
+		if _, err := w.w.Write(header[:]); err != nil {
+			return err
+		}
+		if _, err := w.w.Write(payload[:size]); err != nil {
+			return err
+		}
+
+		payload = payload[size:]
+		// A payload of the maximum size is followed by an empty packet.
+		if size < maxPacketSize {
+			return nil
+		}
+	}
+}
+
+func (w *packetWriter) flush() error {
+	return w.w.Flush()
+}
+
+// connByAddr returns the connection of the client with the given remote
+// address, or nil if there is none. Addresses are compared by identity,
+// so they are only the same for the same connection.
+func (h *Handler) connByAddr(addr net.Addr) *mysql.Conn {
+	if addr == nil {
+		return nil
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for _, c := range h.c {
+		if c.MysqlConn.RemoteAddr() == addr {
+			return c.MysqlConn
+		}
+	}
+	return nil
+}
+
+// handleStmtCommand handles a command of the prepared statements in the
+// connection and writes its response. Errors of the commands are sent to
+// the client, so only the errors writing the response are returned.
+func (h *Handler) handleStmtCommand(c *mysql.Conn, payload []byte, w *packetWriter) error {
+	r := &packetReader{cmd: payload[0], data: payload, pos: 1}
+
+	var err error
+	switch payload[0] {
+	case comStmtPrepare:
+		err = h.handlePrepare(c, string(payload[1:]), w)
+	case comStmtExecute:
+		err = h.handleExecute(c, r, w)
+	case comStmtSendLongData:
+		// The client doesn't expect any response, even if it fails.
+		if err := h.handleSendLongData(c, r); err != nil {
+			logrus.Errorf("unable to store data of prepared statement: %s", err)
+		}
+		return nil
+	case comStmtClose:
+		// The client doesn't expect any response.
+		if id, err := r.uint32(); err == nil {
+			h.ComStmtClose(c, id)
+		}
+		return nil
+	case comStmtReset:
+		err = h.handleReset(c, r, w)
+	}
+
+	if err != nil {
+		if err := writeError(w, err); err != nil {
+			return err
+		}
+	}
+
+	return w.flush()
+}
+
+func (h *Handler) handlePrepare(c *mysql.Conn, q string, w *packetWriter) error {
+	stmt, err := h.ComPrepare(c, q)
+	if err != nil {
+		return err
+	}
+
+	var b packetBuilder
+	b.byte(mysql.OKPacket)
+	b.uint32(stmt.ID)
+	b.uint16(uint16(len(stmt.Fields)))
+	b.uint16(uint16(len(stmt.Params)))
+	b.byte(0)
+	b.uint16(h.WarningCount(c))
+	if err := w.write(b.data); err != nil {
+		return err
+	}
+
+	for _, fields := range [][]*query.Field{stmt.Params, stmt.Fields} {
+		if len(fields) == 0 {
+			continue
+		}
+
+		if err := writeFields(c, w, fields, false); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (h *Handler) handleExecute(c *mysql.Conn, r *packetReader, w *packetWriter) error {
+	id, err := r.uint32()
+	if err != nil {
+		return err
+	}
+
+	stmt, ok := h.stmts.get(c.ConnectionID, id)
+	if !ok {
+		return ErrUnknownPreparedStatement.New(id, "mysqld_stmt_execute")
+	}
+
+	bindVars, err := readParams(r, stmt)
+	if err != nil {
+		return err
+	}
+
+	var fields []*query.Field
+	var affected, insertID uint64
+	err = h.ComStmtExecute(c, id, bindVars, func(res *sqltypes.Result) error {
+		if len(res.Fields) == 0 {
+			affected += res.RowsAffected
+			insertID = res.InsertID
+			return nil
+		}
+
+		if fields == nil {
+			fields = res.Fields
+			if err := writeFields(c, w, fields, true); err != nil {
+				return err
+			}
+		}
+
+		for _, row := range res.Rows {
+			data, err := binaryRow(fields, row)
+			if err != nil {
+				return err
+			}
+
+			if err := w.write(data); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if fields == nil {
+		return writeOK(c, w, mysql.OKPacket, affected, insertID, h.WarningCount(c))
+	}
+
+	return writeEOF(c, w, h.WarningCount(c))
+}
+
+// readParams reads the values of the parameters of the statement sent in
+// the packet to execute it, which are bound by name to their placeholders.
+func readParams(r *packetReader, stmt *PreparedStatement) (map[string]*query.BindVariable, error) {
+	defer func() { stmt.longData = nil }()
+
+	// Flags and iteration count, which is always one.
+	if _, err := r.next(5); err != nil {
+		return nil, err
+	}
+
+	n := len(stmt.bindVars)
+	if n == 0 {
+		return nil, nil
+	}
+
+	nulls, err := r.next((n + 7) / 8)
+	if err != nil {
+		return nil, err
+	}
+
+	bound, err := r.byte()
+	if err != nil {
+		return nil, err
+	}
+
+	if bound == 1 {
+		types, err := r.next(2 * n)
+		if err != nil {
+			return nil, err
+		}
+		stmt.paramTypes = append([]byte(nil), types...)
+	}
+
+	if len(stmt.paramTypes) != 2*n {
+		return nil, ErrMalformedPacket.New(r.cmd)
+	}
+
+	bindVars := make(map[string]*query.BindVariable, n)
+	for i, name := range stmt.bindVars {
+		typ, flags := stmt.paramTypes[2*i], stmt.paramTypes[2*i+1]
+
+		var v sqltypes.Value
+		if nulls[i/8]&(1<<uint(i%8)) != 0 {
+			v = sqltypes.NULL
+		} else if data, ok := stmt.longData[i]; ok {
+			v = stringParam(typ, data)
+		} else if v, err = r.readParam(typ, flags); err != nil {
+			return nil, err
+		}
+
+		bindVars[name] = sqltypes.ValueBindVariable(v)
+	}
+
+	return bindVars, nil
+}
+
+func (h *Handler) handleSendLongData(c *mysql.Conn, r *packetReader) error {
+	id, err := r.uint32()
+	if err != nil {
+		return err
+	}
+
+	param, err := r.uint16()
+	if err != nil {
+		return err
+	}
+
+	stmt, ok := h.stmts.get(c.ConnectionID, id)
+	if !ok {
+		return ErrUnknownPreparedStatement.New(id, "mysqld_stmt_send_long_data")
+	}
+
+	if stmt.longData == nil {
+		stmt.longData = make(map[int][]byte)
+	}
+
+	i := int(param)
+	stmt.longData[i] = append(stmt.longData[i], r.data[r.pos:]...)
+	return nil
+}
+
+func (h *Handler) handleReset(c *mysql.Conn, r *packetReader, w *packetWriter) error {
+	id, err := r.uint32()
+	if err != nil {
+		return err
+	}
+
+	stmt, ok := h.stmts.get(c.ConnectionID, id)
+	if !ok {
+		return ErrUnknownPreparedStatement.New(id, "mysqld_stmt_reset")
+	}
+
+	stmt.longData = nil
+	return writeOK(c, w, mysql.OKPacket, 0, 0, h.WarningCount(c))
+}
+
+// writeFields writes the definitions of the given fields, preceded by
+// their count if withCount is true, and followed by an EOF packet unless
+// the client doesn't expect it.
+func writeFields(c *mysql.Conn, w *packetWriter, fields []*query.Field, withCount bool) error {
+	if withCount {
+		var b packetBuilder
+		b.lenEncInt(uint64(len(fields)))
+		if err := w.write(b.data); err != nil {
+			return err
+		}
+	}
+
+	for _, f := range fields {
+		if err := w.write(columnDefinition(f)); err != nil {
+			return err
+		}
+	}
+
+	if c.Capabilities&mysql.CapabilityClientDeprecateEOF != 0 {
+		return nil
+	}
+
+	return writeEOFPacket(c, w, 0)
+}
+
+// writeEOF writes the end of a result set, which is an OK packet with the
+// header of an EOF packet if the client doesn't expect EOF packets.
+func writeEOF(c *mysql.Conn, w *packetWriter, warnings uint16) error {
+	if c.Capabilities&mysql.CapabilityClientDeprecateEOF != 0 {
+		return writeOK(c, w, mysql.EOFPacket, 0, 0, warnings)
+	}
+	return writeEOFPacket(c, w, warnings)
+}
+
+func writeEOFPacket(c *mysql.Conn, w *packetWriter, warnings uint16) error {
+	var b packetBuilder
+	b.byte(mysql.EOFPacket)
+	b.uint16(warnings)
+	b.uint16(c.StatusFlags)
+	return w.write(b.data)
+}
+
+func writeOK(
+	c *mysql.Conn,
+	w *packetWriter,
+	header byte,
+	affected, insertID uint64,
+	warnings uint16,
+) error {
+	var b packetBuilder
+	b.byte(header)
+	b.lenEncInt(affected)
+	b.lenEncInt(insertID)
+	b.uint16(c.StatusFlags)
+	b.uint16(warnings)
+	return w.write(b.data)
+}
+
+func writeError(w *packetWriter, err error) error {
+	serr, ok := mysql.NewSQLErrorFromError(err).(*mysql.SQLError)
+	if !ok {
+		serr = mysql.NewSQLError(mysql.ERUnknownError, mysql.SSUnknownSQLState, "%v", err)
+	}
+
+	state := serr.State
+	if len(state) != 5 {
+		state = mysql.SSUnknownSQLState
+	}
+
+	var b packetBuilder
+	b.byte(mysql.ErrPacket)
+	b.uint16(uint16(serr.Num))
+	b.byte('#')
+	b.data = append(b.data, state...)
+	b.data = append(b.data, serr.Message...)
+	return w.write(b.data)
+}
@@ -142,6 +142,8 @@ type Analyzer struct {
 	Batches []*Batch
 	// Catalog of databases and registered functions.
 	Catalog *sql.Catalog
+	// scope of the outer queries when a subquery expression is analyzed.
+	scope *scope
 }
 
 // NewDefault creates a default Analyzer instance with all default Rules and configuration.
@@ -160,6 +162,30 @@ func (a *Analyzer) Log(msg string, args ...interface{}) {
 
 // Analyze the node and all its children.
 func (a *Analyzer) Analyze(ctx *sql.Context, n sql.Node) (sql.Node, error) {
+	return a.analyze(ctx, n, a.Batches)
+}
+
+// AnalyzePlan analyzes the node with all the batches but the last one,
+// whose rules depend on each execution of the query, such as the tracking
+// of its process. The resulting plan can be executed many times applying
+// the rest of the rules to it with AnalyzeExecution.
+func (a *Analyzer) AnalyzePlan(ctx *sql.Context, n sql.Node) (sql.Node, error) {
+	if len(a.Batches) == 0 {
+		return n, nil
+	}
+	return a.analyze(ctx, n, a.Batches[:len(a.Batches)-1])
+}
+
+// AnalyzeExecution applies the rules of the last batch to a node analyzed
+// with AnalyzePlan, so it can be executed.
+func (a *Analyzer) AnalyzeExecution(ctx *sql.Context, n sql.Node) (sql.Node, error) {
+	if len(a.Batches) == 0 {
+		return n, nil
+	}
+	return a.analyze(ctx, n, a.Batches[len(a.Batches)-1:])
+}
+
+func (a *Analyzer) analyze(ctx *sql.Context, n sql.Node, batches []*Batch) (sql.Node, error) {
 	span, ctx := ctx.Span("analyze", opentracing.Tags{
 		"plan": n.String(),
 	})
@@ -167,7 +193,7 @@ func (a *Analyzer) Analyze(ctx *sql.Context, n sql.Node) (sql.Node, error) {
 	prev := n
 	var err error
 	a.Log("starting analysis of node of type: %T", n)
-	for _, batch := range a.Batches {
+	for _, batch := range batches {
 		prev, err = batch.Eval(ctx, a, prev)
 		if ErrMaxAnalysisIters.Is(err) {
 			a.Log(err.Error())
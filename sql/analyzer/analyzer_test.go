@@ -178,7 +178,9 @@ func TestAnalyzer_Analyze(t *testing.T) {
 	analyzed, err = a.Analyze(sql.NewEmptyContext(), notAnalyzed)
 	expected = plan.NewLimit(
 		int64(1),
-		plan.NewResolvedTable(table.WithProjection([]string{"i"})),
+		plan.NewResolvedTable(
+			table.WithProjection([]string{"i"}).(*memory.Table).WithLimit(1),
+		),
 	)
 	require.NoError(err)
 	require.Equal(expected, analyzed)
@@ -326,8 +328,10 @@ func TestMixInnerAndNaturalJoins(t *testing.T) {
 			expression.NewGetFieldWithTable(5, sql.Text, "mytable2", "t2", false),
 			expression.NewGetFieldWithTable(6, sql.Text, "mytable3", "t3", false),
 		},
-		plan.NewInnerJoin(
-			plan.NewInnerJoin(
+		plan.NewHashJoin(
+			plan.JoinTypeInner,
+			plan.NewHashJoin(
+				plan.JoinTypeInner,
 				plan.NewResolvedTable(table.WithProjection([]string{"i", "f", "t"})),
 				plan.NewResolvedTable(table2.WithProjection([]string{"f2", "i2", "t2"})),
 				expression.NewEquals(
@@ -44,10 +44,42 @@ func assignCatalog(ctx *sql.Context, a *Analyzer, n sql.Node) (sql.Node, error)
 			nc.Database = a.Catalog.CurrentDatabase()
 			nc.ProcessList = a.Catalog.ProcessList
 			return &nc, nil
+		case *plan.AnalyzeTable:
+			nc := *node
+			nc.CurrentDatabase = a.Catalog.CurrentDatabase()
+			return &nc, nil
 		case *plan.ShowTableStatus:
 			nc := *node
 			nc.Catalog = a.Catalog
 			return &nc, nil
+		case *plan.ShowTables:
+			nc := *node
+			nc.Catalog = a.Catalog
+			return &nc, nil
+		case *plan.CreateView:
+			nc := *node
+			nc.Catalog = a.Catalog
+			return &nc, nil
+		case *plan.DropView:
+			nc := *node
+			nc.Catalog = a.Catalog
+			return &nc, nil
+		case *plan.ShowCreateView:
+			nc := *node
+			nc.Catalog = a.Catalog
+			return &nc, nil
+		case *plan.CreateFunction:
+			nc := *node
+			nc.Catalog = a.Catalog
+			return &nc, nil
+		case *plan.DropFunction:
+			nc := *node
+			nc.Catalog = a.Catalog
+			return &nc, nil
+		case *plan.ShowFunctionStatus:
+			nc := *node
+			nc.Catalog = a.Catalog
+			return &nc, nil
 		case *plan.Use:
 			nc := *node
 			nc.Catalog = a.Catalog
@@ -60,6 +92,10 @@ func assignCatalog(ctx *sql.Context, a *Analyzer, n sql.Node) (sql.Node, error)
 			nc := *node
 			nc.Catalog = a.Catalog
 			return &nc, nil
+		case *plan.StartTransaction:
+			nc := *node
+			nc.Catalog = a.Catalog
+			return &nc, nil
 		default:
 			return n, nil
 		}
@@ -751,7 +751,8 @@ func extractColumnExpr(e sql.Expression) (string, *columnExpr) {
 func containsColumns(e sql.Expression) bool {
 	var result bool
 	expression.Inspect(e, func(e sql.Expression) bool {
-		if _, ok := e.(*expression.GetField); ok {
+		switch e.(type) {
+		case *expression.GetField, *expression.OuterField:
 			result = true
 		}
 		return true
@@ -772,7 +773,19 @@ func containsSubquery(e sql.Expression) bool {
 }
 
 func isEvaluable(e sql.Expression) bool {
-	return !containsColumns(e) && !containsSubquery(e)
+	return !containsColumns(e) && !containsSubquery(e) && !containsBindVars(e)
+}
+
+func containsBindVars(e sql.Expression) bool {
+	var result bool
+	expression.Inspect(e, func(e sql.Expression) bool {
+		if _, ok := e.(*expression.BindVar); ok {
+			result = true
+			return false
+		}
+		return true
+	})
+	return result
 }
 
 func canMergeIndexes(a, b sql.IndexLookup) bool {
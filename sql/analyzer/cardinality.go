@@ -0,0 +1,299 @@
+package analyzer
+
+import (
+	"math"
+
+	"github.com/src-d/go-mysql-server/sql"
+	"github.com/src-d/go-mysql-server/sql/expression"
+	"github.com/src-d/go-mysql-server/sql/plan"
+)
+
+const (
+	// defaultSelectivity is the estimated fraction of the rows that satisfy
+	// a condition when there are no statistics to estimate it.
+	defaultSelectivity = 1.0 / 3
+	// defaultEqualitySelectivity is the estimated fraction of the rows that
+	// satisfy an equality when the number of distinct values is unknown.
+	defaultEqualitySelectivity = 0.1
+)
+
+// cardinality estimates the number of rows returned by nodes using the
+// statistics of the tables they read.
+type cardinality struct {
+	ctx *sql.Context
+}
+
+// rows returns the estimated number of rows returned by the node, or false
+// if it can't be estimated because some table has no statistics.
+func (c *cardinality) rows(n sql.Node) (float64, bool) {
+	switch n := n.(type) {
+	case *plan.ResolvedTable:
+		stats := c.tableStatistics(n.Table)
+		if stats == nil {
+			return 0, false
+		}
+
+		rows := float64(stats.RowCount)
+		if filters := tableFilters(n.Table); len(filters) > 0 {
+			rows *= c.selectivity(n, expression.JoinAnd(filters...))
+		}
+		return rows, true
+	case *plan.Filter:
+		rows, ok := c.rows(n.Child)
+		return rows * c.selectivity(n.Child, n.Expression), ok
+	case *plan.Limit:
+		rows, ok := c.rows(n.Child)
+		return math.Min(rows, float64(n.Limit)), ok
+	case *plan.CrossJoin:
+		return c.joinRows(n, plan.JoinTypeInner, n.Left, n.Right, nil)
+	case *plan.InnerJoin:
+		return c.joinRows(n, plan.JoinTypeInner, n.Left, n.Right, n.Cond)
+	case *plan.LeftJoin:
+		return c.joinRows(n, plan.JoinTypeLeft, n.Left, n.Right, n.Cond)
+	case *plan.RightJoin:
+		return c.joinRows(n, plan.JoinTypeRight, n.Left, n.Right, n.Cond)
+	case *plan.SemiJoin:
+		return c.joinRows(n, plan.JoinTypeSemi, n.Left, n.Right, n.Cond)
+	case *plan.AntiJoin:
+		return c.joinRows(n, n.Type(), n.Left, n.Right, n.Cond)
+	case *plan.HashJoin:
+		return c.joinRows(n, n.Type, n.Left, n.Right, n.Cond)
+	}
+
+	// Nodes such as projections, sorts or aliases return as many rows as
+	// their child at most.
+	if children := n.Children(); len(children) == 1 {
+		return c.rows(children[0])
+	}
+
+	return 0, false
+}
+
+func (c *cardinality) joinRows(
+	n sql.Node,
+	typ plan.JoinType,
+	left, right sql.Node,
+	cond sql.Expression,
+) (float64, bool) {
+	l, ok := c.rows(left)
+	if !ok {
+		return 0, false
+	}
+
+	r, ok := c.rows(right)
+	if !ok {
+		return 0, false
+	}
+
+	rows := l * r
+	if cond != nil {
+		rows *= c.selectivity(n, cond)
+	}
+
+	// Outer joins return all the rows of the preserved side at least.
+	switch typ {
+	case plan.JoinTypeLeft:
+		rows = math.Max(rows, l)
+	case plan.JoinTypeRight:
+		rows = math.Max(rows, r)
+	// Semi and anti joins return each row of the left side once at most.
+	case plan.JoinTypeSemi:
+		rows = math.Min(rows, l)
+	case plan.JoinTypeAnti, plan.JoinTypeNullAwareAnti:
+		rows = l - math.Min(rows, l)
+	}
+
+	return rows, true
+}
+
+// selectivity returns the estimated fraction of the rows of the node that
+// satisfy the condition.
+func (c *cardinality) selectivity(n sql.Node, cond sql.Expression) float64 {
+	switch e := cond.(type) {
+	case *expression.And:
+		return c.selectivity(n, e.Left) * c.selectivity(n, e.Right)
+	case *expression.Or:
+		l, r := c.selectivity(n, e.Left), c.selectivity(n, e.Right)
+		return l + r - l*r
+	case *expression.Not:
+		return 1 - c.selectivity(n, e.Child)
+	case *expression.IsNull:
+		if stats := c.fieldStatistics(n, e.Child); stats != nil {
+			return stats.NullFraction
+		}
+	case *expression.Equals:
+		return c.equalitySelectivity(n, e.Left(), e.Right())
+	case *expression.In:
+		if tuple, ok := e.Right().(expression.Tuple); ok {
+			sel := c.equalitySelectivity(n, e.Left(), nil)
+			return math.Min(1, sel*float64(len(tuple)))
+		}
+	case *expression.LessThan:
+		return c.rangeSelectivity(n, e.Left(), e.Right(), true)
+	case *expression.LessThanOrEqual:
+		return c.rangeSelectivity(n, e.Left(), e.Right(), true)
+	case *expression.GreaterThan:
+		return c.rangeSelectivity(n, e.Left(), e.Right(), false)
+	case *expression.GreaterThanOrEqual:
+		return c.rangeSelectivity(n, e.Left(), e.Right(), false)
+	}
+
+	return defaultSelectivity
+}
+
+// equalitySelectivity returns the estimated fraction of the rows where the
+// given expressions are equal, assuming values are uniformly distributed.
+// The right one may be nil if it's not a column.
+func (c *cardinality) equalitySelectivity(n sql.Node, left, right sql.Expression) float64 {
+	var distinct uint64
+	var nulls float64
+	for _, e := range []sql.Expression{left, right} {
+		if stats := c.fieldStatistics(n, e); stats != nil {
+			if stats.DistinctCount > distinct {
+				distinct = stats.DistinctCount
+			}
+			nulls = math.Max(nulls, stats.NullFraction)
+		}
+	}
+
+	if distinct == 0 {
+		return defaultEqualitySelectivity
+	}
+
+	return (1 - nulls) / float64(distinct)
+}
+
+// rangeSelectivity returns the estimated fraction of the rows where the
+// left expression is less than the right one, or greater if less is false,
+// using the histogram of the column compared with a literal.
+func (c *cardinality) rangeSelectivity(n sql.Node, left, right sql.Expression, less bool) float64 {
+	if _, ok := left.(*expression.Literal); ok {
+		left, right, less = right, left, !less
+	}
+
+	lit, ok := right.(*expression.Literal)
+	if !ok {
+		return defaultSelectivity
+	}
+
+	stats := c.fieldStatistics(n, left)
+	if stats == nil || len(stats.Histogram) == 0 {
+		return defaultSelectivity
+	}
+
+	below, ok := fractionBelow(stats, lit.Value())
+	if !ok {
+		return defaultSelectivity
+	}
+
+	if less {
+		return below
+	}
+	return math.Max(0, 1-stats.NullFraction-below)
+}
+
+// fractionBelow returns the estimated fraction of the rows whose value is
+// less than the given one according to the histogram of the column. Values
+// are assumed to be in the middle of the bucket they fall in.
+func fractionBelow(stats *sql.ColumnStatistics, value interface{}) (float64, bool) {
+	v, err := stats.Type.Convert(value)
+	if err != nil || v == nil {
+		return 0, false
+	}
+
+	var prev float64
+	for _, b := range stats.Histogram {
+		cmp, err := stats.Type.Compare(v, b.LowerBound)
+		if err != nil {
+			return 0, false
+		}
+
+		if cmp <= 0 {
+			return prev, true
+		}
+
+		cmp, err = stats.Type.Compare(v, b.UpperBound)
+		if err != nil {
+			return 0, false
+		}
+
+		if cmp <= 0 {
+			return prev + (b.CumulativeFrequency-prev)/2, true
+		}
+
+		prev = b.CumulativeFrequency
+	}
+
+	return prev, true
+}
+
+// fieldStatistics returns the statistics of the column of the given field
+// in the table of the node it comes from, or nil if it's not a field or there
+// are no statistics.
+func (c *cardinality) fieldStatistics(n sql.Node, e sql.Expression) *sql.ColumnStatistics {
+	field, ok := e.(*expression.GetField)
+	if !ok {
+		return nil
+	}
+
+	var stats *sql.ColumnStatistics
+	plan.Inspect(n, func(n sql.Node) bool {
+		if stats != nil {
+			return false
+		}
+
+		switch n := n.(type) {
+		case *plan.TableAlias:
+			t, ok := n.Child.(*plan.ResolvedTable)
+			if ok && n.Name() == field.Table() {
+				stats = c.tableStatistics(t.Table).Column(field.Name())
+			}
+			return false
+		case *plan.ResolvedTable:
+			if n.Name() == field.Table() {
+				stats = c.tableStatistics(n.Table).Column(field.Name())
+			}
+			return false
+		case *plan.SubqueryAlias:
+			// The tables of subqueries are not in scope.
+			return false
+		}
+		return true
+	})
+
+	return stats
+}
+
+// tableStatistics returns the statistics of the table or the one it wraps,
+// or nil if it has none.
+func (c *cardinality) tableStatistics(t sql.Table) *sql.TableStatistics {
+	for {
+		switch tt := t.(type) {
+		case sql.StatisticsTable:
+			stats, err := tt.Statistics(c.ctx)
+			if err != nil {
+				return nil
+			}
+			return stats
+		case sql.TableWrapper:
+			t = tt.Underlying()
+		default:
+			return nil
+		}
+	}
+}
+
+// tableFilters returns the filters pushed down to the table or the one it
+// wraps.
+func tableFilters(t sql.Table) []sql.Expression {
+	for {
+		switch tt := t.(type) {
+		case sql.FilteredTable:
+			return tt.Filters()
+		case sql.TableWrapper:
+			t = tt.Underlying()
+		default:
+			return nil
+		}
+	}
+}
@@ -20,19 +20,27 @@ func exprToTableFilters(expr sql.Expression) filters {
 	for _, expr := range splitExpression(expr) {
 		var seenTables = make(map[string]struct{})
 		var lastTable string
+		var hasBindVars, hasOuterFields bool
 		expression.Inspect(expr, func(e sql.Expression) bool {
-			f, ok := e.(*expression.GetField)
-			if ok {
-				if _, ok := seenTables[f.Table()]; !ok {
-					seenTables[f.Table()] = struct{}{}
-					lastTable = f.Table()
+			switch e := e.(type) {
+			case *expression.GetField:
+				if _, ok := seenTables[e.Table()]; !ok {
+					seenTables[e.Table()] = struct{}{}
+					lastTable = e.Table()
 				}
+			case *expression.BindVar:
+				hasBindVars = true
+			case *expression.OuterField:
+				hasOuterFields = true
 			}
 
 			return true
 		})
 
-		if len(seenTables) == 1 {
+		// Filters with placeholders of a prepared statement are kept in the
+		// plan so they can be bound later, and so are the ones that use
+		// values of an outer query, which tables do not have.
+		if len(seenTables) == 1 && !hasBindVars && !hasOuterFields {
 			filtersByTable[lastTable] = append(filtersByTable[lastTable], expr)
 		}
 	}
@@ -28,6 +28,57 @@ func eraseProjection(ctx *sql.Context, a *Analyzer, node sql.Node) (sql.Node, er
 	})
 }
 
+// useTopN replaces a sort followed by a limit, and optionally an offset, with
+// a TopN node, so that only the rows that may be returned are kept in memory.
+// Projections between the limit and the sort are moved on top of the TopN.
+func useTopN(ctx *sql.Context, a *Analyzer, node sql.Node) (sql.Node, error) {
+	span, _ := ctx.Span("use_top_n")
+	defer span.Finish()
+
+	if !node.Resolved() {
+		return node, nil
+	}
+
+	a.Log("use top n, node of type: %T", node)
+
+	return plan.TransformUp(node, func(node sql.Node) (sql.Node, error) {
+		limit, ok := node.(*plan.Limit)
+		if !ok {
+			return node, nil
+		}
+
+		var offset int64
+		var projects []*plan.Project
+		child := limit.Child
+	Loop:
+		for {
+			switch n := child.(type) {
+			case *plan.Offset:
+				offset += n.Offset
+				child = n.Child
+			case *plan.Project:
+				projects = append(projects, n)
+				child = n.Child
+			default:
+				break Loop
+			}
+		}
+
+		sort, ok := child.(*plan.Sort)
+		if !ok {
+			return node, nil
+		}
+
+		a.Log("sort and limit replaced with top n")
+		var result sql.Node = plan.NewTopN(sort.SortFields, limit.Limit, offset, sort.Child)
+		for i := len(projects) - 1; i >= 0; i-- {
+			result = plan.NewProject(projects[i].Projections, result)
+		}
+
+		return result, nil
+	})
+}
+
 func optimizeDistinct(ctx *sql.Context, a *Analyzer, node sql.Node) (sql.Node, error) {
 	span, _ := ctx.Span("optimize_distinct")
 	defer span.Finish()
@@ -261,6 +312,58 @@ func moveJoinConditionsToFilter(ctx *sql.Context, a *Analyzer, n sql.Node) (sql.
 	})
 }
 
+// useHashJoins replaces the joins whose condition contains equalities between
+// both sides with hash joins. If the number of rows of both sides can be
+// estimated, the hash table is built with the smaller one.
+func useHashJoins(ctx *sql.Context, a *Analyzer, n sql.Node) (sql.Node, error) {
+	span, _ := ctx.Span("use_hash_joins")
+	defer span.Finish()
+
+	if !n.Resolved() {
+		return n, nil
+	}
+
+	card := &cardinality{ctx}
+
+	return plan.TransformUp(n, func(n sql.Node) (sql.Node, error) {
+		var typ plan.JoinType
+		var left, right sql.Node
+		var cond sql.Expression
+		switch j := n.(type) {
+		case *plan.InnerJoin:
+			typ, left, right, cond = plan.JoinTypeInner, j.Left, j.Right, j.Cond
+		case *plan.LeftJoin:
+			typ, left, right, cond = plan.JoinTypeLeft, j.Left, j.Right, j.Cond
+		case *plan.RightJoin:
+			typ, left, right, cond = plan.JoinTypeRight, j.Left, j.Right, j.Cond
+		case *plan.SemiJoin:
+			typ, left, right, cond = plan.JoinTypeSemi, j.Left, j.Right, j.Cond
+		case *plan.AntiJoin:
+			typ, left, right, cond = j.Type(), j.Left, j.Right, j.Cond
+		default:
+			return n, nil
+		}
+
+		if !plan.CanHashJoin(left, cond) {
+			return n, nil
+		}
+
+		join := plan.NewHashJoin(typ, left, right, cond)
+		if l, ok := card.rows(left); ok {
+			if r, ok := card.rows(right); ok {
+				side := plan.BuildRight
+				if l < r {
+					side = plan.BuildLeft
+				}
+				join = join.WithBuildSide(side)
+			}
+		}
+
+		a.Log("using hash join for %s with condition %s, building with the %s side", typ, cond, join.Build)
+		return join, nil
+	})
+}
+
 func removeUnnecessaryConverts(ctx *sql.Context, a *Analyzer, n sql.Node) (sql.Node, error) {
 	span, _ := ctx.Span("remove_unnecessary_converts")
 	defer span.Finish()
@@ -324,6 +324,129 @@ func TestMoveJoinConditionsToFilter(t *testing.T) {
 	require.Equal(result, expected)
 }
 
+func TestUseHashJoins(t *testing.T) {
+	t1 := memory.NewTable("t1", sql.Schema{
+		{Name: "a", Source: "t1", Type: sql.Int64},
+		{Name: "b", Source: "t1", Type: sql.Int64},
+	})
+
+	t2 := memory.NewTable("t2", sql.Schema{
+		{Name: "c", Source: "t2", Type: sql.Int64},
+		{Name: "d", Source: "t2", Type: sql.Int64},
+	})
+
+	rule := getRule("use_hash_joins")
+	require := require.New(t)
+
+	equiCond := and(
+		eq(col(0, "t1", "a"), col(2, "t2", "c")),
+		expression.NewGreaterThan(col(1, "t1", "b"), col(3, "t2", "d")),
+	)
+	nonEquiCond := expression.NewGreaterThan(col(0, "t1", "a"), col(2, "t2", "c"))
+
+	node := plan.NewProject(
+		[]sql.Expression{col(0, "t1", "a")},
+		plan.NewLeftJoin(
+			plan.NewInnerJoin(
+				plan.NewResolvedTable(t1),
+				plan.NewResolvedTable(t2),
+				equiCond,
+			),
+			plan.NewResolvedTable(t2),
+			nonEquiCond,
+		),
+	)
+
+	result, err := rule.Apply(sql.NewEmptyContext(), NewDefault(nil), node)
+	require.NoError(err)
+
+	expected := plan.NewProject(
+		[]sql.Expression{col(0, "t1", "a")},
+		plan.NewLeftJoin(
+			plan.NewHashJoin(
+				plan.JoinTypeInner,
+				plan.NewResolvedTable(t1),
+				plan.NewResolvedTable(t2),
+				equiCond,
+			),
+			plan.NewResolvedTable(t2),
+			nonEquiCond,
+		),
+	)
+
+	require.Equal(expected, result)
+
+	// With statistics, the hash table is built with the smaller side.
+	ctx := sql.NewEmptyContext()
+	require.NoError(t1.Insert(ctx, sql.NewRow(int64(1), int64(2))))
+	require.NoError(t1.Analyze(ctx))
+	require.NoError(t2.Analyze(ctx))
+
+	join := plan.NewInnerJoin(plan.NewResolvedTable(t1), plan.NewResolvedTable(t2), equiCond)
+	result, err = rule.Apply(ctx, NewDefault(nil), join)
+	require.NoError(err)
+	require.Equal(
+		plan.NewHashJoin(
+			plan.JoinTypeInner,
+			plan.NewResolvedTable(t1),
+			plan.NewResolvedTable(t2),
+			equiCond,
+		).WithBuildSide(plan.BuildRight),
+		result,
+	)
+}
+
+func TestUseTopN(t *testing.T) {
+	require := require.New(t)
+	rule := getRule("use_top_n")
+
+	table := plan.NewResolvedTable(memory.NewTable("mytable", sql.Schema{
+		{Name: "i", Source: "mytable", Type: sql.Int64},
+	}))
+	fields := []plan.SortField{{Column: col(0, "mytable", "i"), Order: plan.Descending}}
+	projections := []sql.Expression{col(0, "mytable", "i")}
+
+	testCases := []struct {
+		name     string
+		node     sql.Node
+		expected sql.Node
+	}{
+		{
+			"limit",
+			plan.NewLimit(10, plan.NewProject(projections, plan.NewSort(fields, table))),
+			plan.NewProject(projections, plan.NewTopN(fields, 10, 0, table)),
+		},
+		{
+			"limit and offset",
+			plan.NewLimit(10, plan.NewOffset(5, plan.NewSort(fields, plan.NewProject(projections, table)))),
+			plan.NewTopN(fields, 10, 5, plan.NewProject(projections, table)),
+		},
+		{
+			"no sort",
+			plan.NewLimit(10, plan.NewProject(projections, table)),
+			plan.NewLimit(10, plan.NewProject(projections, table)),
+		},
+		{
+			"no limit",
+			plan.NewOffset(5, plan.NewSort(fields, table)),
+			plan.NewOffset(5, plan.NewSort(fields, table)),
+		},
+		{
+			"filter between",
+			plan.NewLimit(10, plan.NewFilter(expression.NewLiteral(true, sql.Boolean), plan.NewSort(fields, table))),
+			plan.NewLimit(10, plan.NewFilter(expression.NewLiteral(true, sql.Boolean), plan.NewSort(fields, table))),
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := rule.Apply(sql.NewEmptyContext(), NewDefault(nil), tt.node)
+			require.NoError(err)
+			require.Equal(tt.expected, result)
+		})
+	}
+}
+
 func TestEvalFilter(t *testing.T) {
 	inner := memory.NewTable("foo", nil)
 	rule := getRule("eval_filter")
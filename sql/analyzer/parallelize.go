@@ -15,9 +15,10 @@ var (
 )
 
 func shouldParallelize(node sql.Node) bool {
-	// Do not try to parallelize index operations.
+	// Do not try to parallelize index operations or the reads of tables
+	// being analyzed.
 	switch node.(type) {
-	case *plan.CreateIndex, *plan.DropIndex, *plan.Describe:
+	case *plan.CreateIndex, *plan.DropIndex, *plan.Describe, *plan.AnalyzeTable:
 		return false
 	default:
 		return true
@@ -47,7 +48,38 @@ func parallelize(ctx *sql.Context, a *Analyzer, node sql.Node) (sql.Node, error)
 		return nil, err
 	}
 
-	return plan.TransformUp(node, removeRedundantExchanges)
+	node, err = plan.TransformUp(node, removeRedundantExchanges)
+	if err != nil {
+		return nil, err
+	}
+
+	return plan.TransformUp(node, partitionTopN)
+}
+
+// partitionTopN computes the top rows of each partition of an exchange in
+// parallel, so that the final top rows are selected only among them.
+func partitionTopN(node sql.Node) (sql.Node, error) {
+	topN, ok := node.(*plan.TopN)
+	if !ok {
+		return node, nil
+	}
+
+	exchange, ok := topN.Child.(*plan.Exchange)
+	if !ok {
+		return node, nil
+	}
+
+	child, err := exchange.WithChildren(plan.NewTopN(
+		topN.SortFields,
+		topN.Limit+topN.Offset,
+		0,
+		exchange.Child,
+	))
+	if err != nil {
+		return nil, err
+	}
+
+	return topN.WithChildren(child)
 }
 
 // removeRedundantExchanges removes all the exchanges except for the topmost
@@ -93,6 +125,7 @@ func isParallelizable(node sql.Node) bool {
 		case *plan.Filter,
 			*plan.Project,
 			*plan.TableAlias,
+			*plan.PartialGroupBy,
 			*plan.Exchange:
 		case sql.Table:
 			lastWasTable = true
@@ -55,6 +55,63 @@ func TestParallelize(t *testing.T) {
 	require.Equal(expected, result)
 }
 
+func TestParallelizeTopN(t *testing.T) {
+	require := require.New(t)
+	table := memory.NewTable("t", nil)
+	rule := getRuleFrom(OnceAfterAll, "parallelize")
+	fields := []plan.SortField{{Column: expression.NewLiteral(1, sql.Int64)}}
+
+	node := plan.NewTopN(fields, 10, 5, plan.NewResolvedTable(table))
+	expected := plan.NewTopN(
+		fields, 10, 5,
+		plan.NewExchange(
+			2,
+			plan.NewTopN(fields, 15, 0, plan.NewResolvedTable(table)),
+		),
+	)
+
+	result, err := rule.Apply(sql.NewEmptyContext(), &Analyzer{Parallelism: 2}, node)
+	require.NoError(err)
+	require.Equal(expected, result)
+}
+
+func TestParallelizeUnion(t *testing.T) {
+	require := require.New(t)
+	table := memory.NewTable("t", nil)
+	rule := getRuleFrom(OnceAfterAll, "parallelize")
+	node := plan.NewLimit(
+		5,
+		plan.NewDistinctUnion(
+			plan.NewFilter(
+				expression.NewLiteral(1, sql.Int64),
+				plan.NewResolvedTable(table),
+			),
+			plan.NewResolvedTable(table),
+		),
+	)
+
+	expected := plan.NewLimit(
+		5,
+		plan.NewDistinctUnion(
+			plan.NewExchange(
+				2,
+				plan.NewFilter(
+					expression.NewLiteral(1, sql.Int64),
+					plan.NewResolvedTable(table),
+				),
+			),
+			plan.NewExchange(
+				2,
+				plan.NewResolvedTable(table),
+			),
+		),
+	)
+
+	result, err := rule.Apply(sql.NewEmptyContext(), &Analyzer{Parallelism: 2}, node)
+	require.NoError(err)
+	require.Equal(expected, result)
+}
+
 func TestParallelizeCreateIndex(t *testing.T) {
 	require := require.New(t)
 	table := memory.NewTable("t", nil)
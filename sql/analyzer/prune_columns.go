@@ -29,12 +29,7 @@ func pruneColumns(ctx *sql.Context, a *Analyzer, n sql.Node) (sql.Node, error) {
 
 	// All the columns required for the output of the query must be mark as
 	// used, otherwise the schema would change.
-	for _, col := range n.Schema() {
-		if _, ok := columns[col.Source]; !ok {
-			columns[col.Source] = make(map[string]struct{})
-		}
-		columns[col.Source][col.Name] = struct{}{}
-	}
+	addUsedSchemaColumns(columns, n.Schema())
 
 	findUsedColumns(columns, n)
 
@@ -114,6 +109,12 @@ func findUsedColumns(columns usedColumns, n sql.Node) {
 			return true
 		case *plan.SubqueryAlias:
 			return false
+		case *plan.Union:
+			// Columns of an union are matched by position, so none of the
+			// columns returned by its branches can be pruned.
+			addUsedSchemaColumns(columns, n.Left.Schema())
+			addUsedSchemaColumns(columns, n.Right.Schema())
+			return true
 		}
 
 		exp, ok := n.(sql.Expressioner)
@@ -238,6 +239,15 @@ func addUsedColumns(columns usedColumns, exprs []sql.Expression) {
 	}
 }
 
+func addUsedSchemaColumns(columns usedColumns, schema sql.Schema) {
+	for _, col := range schema {
+		if _, ok := columns[col.Source]; !ok {
+			columns[col.Source] = make(map[string]struct{})
+		}
+		columns[col.Source][col.Name] = struct{}{}
+	}
+}
+
 func pruneProject(n *plan.Project, columns usedColumns) sql.Node {
 	var remaining []sql.Expression
 	for _, e := range n.Projections {
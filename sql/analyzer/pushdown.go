@@ -6,6 +6,8 @@ import (
 
 	"github.com/src-d/go-mysql-server/sql"
 	"github.com/src-d/go-mysql-server/sql/expression"
+	"github.com/src-d/go-mysql-server/sql/expression/function/aggregation"
+	"github.com/src-d/go-mysql-server/sql/expression/function/udf"
 	"github.com/src-d/go-mysql-server/sql/plan"
 )
 
@@ -139,6 +141,10 @@ func transformPushdown(
 		switch node := node.(type) {
 		case *plan.Filter:
 			return pushdownFilter(a, node, handledFilters)
+		case *plan.GroupBy:
+			return pushdownGroupBy(a, node)
+		case *plan.Limit:
+			return pushdownLimit(a, node)
 		case *plan.ResolvedTable:
 			return pushdownTable(
 				a,
@@ -229,6 +235,22 @@ func transformExpressioners(node sql.Node) (sql.Node, error) {
 		}
 
 		n = plan.NewLeftJoin(j.Left, j.Right, cond)
+	case *plan.SemiJoin:
+		// The condition uses the columns of both sides even though only
+		// the ones of the left side are returned.
+		cond, err := fixFieldIndexes(append(j.Left.Schema(), j.Right.Schema()...), j.Cond)
+		if err != nil {
+			return nil, err
+		}
+
+		n = plan.NewSemiJoin(j.Left, j.Right, cond)
+	case *plan.AntiJoin:
+		cond, err := fixFieldIndexes(append(j.Left.Schema(), j.Right.Schema()...), j.Cond)
+		if err != nil {
+			return nil, err
+		}
+
+		n = plan.NewAntiJoin(j.Left, j.Right, cond, j.NullAware)
 	}
 
 	return n, nil
@@ -288,7 +310,7 @@ func pushdownFilter(
 ) (sql.Node, error) {
 	if len(handledFilters) == 0 {
 		a.Log("no handled filters, leaving filter untouched")
-		return node, nil
+		return fixFilterIndexes(node)
 	}
 
 	unhandled := getUnhandledFilters(
@@ -307,7 +329,217 @@ func pushdownFilter(
 		len(unhandled),
 	)
 
-	return plan.NewFilter(expression.JoinAnd(unhandled...), node.Child), nil
+	return fixFilterIndexes(plan.NewFilter(expression.JoinAnd(unhandled...), node.Child))
+}
+
+// fixFilterIndexes fixes the indexes of the fields of a filter, since the
+// schema of its child may have changed with the pushdown of projections.
+// Filters over joins are left as they are, because the columns of table
+// aliases are resolved as the columns of their tables, so finding them
+// again by name could pick the columns of the other side of a self join.
+func fixFilterIndexes(n *plan.Filter) (sql.Node, error) {
+	switch n.Child.(type) {
+	case *plan.InnerJoin, *plan.CrossJoin, *plan.LeftJoin, *plan.RightJoin:
+		return n, nil
+	default:
+		return transformExpressioners(n)
+	}
+}
+
+// pushdownGroupBy pushes down the aggregations of a group by to the table it
+// reads if the table can compute all of them. Otherwise, if the table can
+// compute aggregations, the rows are aggregated partially, which is done for
+// each partition separately once the query is parallelized, and the partial
+// results are merged.
+func pushdownGroupBy(a *Analyzer, node *plan.GroupBy) (sql.Node, error) {
+	n, err := transformExpressioners(node)
+	if err != nil {
+		return nil, err
+	}
+
+	gb := n.(*plan.GroupBy)
+	table, ok := findAggregatableTable(gb.Child)
+	if !ok {
+		return gb, nil
+	}
+
+	aggregations, ok := groupByAggregations(gb)
+	if !ok || (len(aggregations) == 0 && len(gb.Grouping) == 0) {
+		return gb, nil
+	}
+
+	if rt, ok := gb.Child.(*plan.ResolvedTable); ok {
+		handled := table.HandledAggregations(gb.Grouping, aggregations)
+		if len(handled) == len(aggregations) {
+			a.Log("table %q transformed with pushdown of aggregations", rt.Name())
+			child := plan.NewResolvedTable(table.WithAggregation(gb.Grouping, aggregations))
+			return mergeGroupBy(gb, child), nil
+		}
+	}
+
+	for _, agg := range aggregations {
+		if !isMergeable(agg) {
+			return gb, nil
+		}
+	}
+
+	a.Log("aggregations of table %q computed partially and merged", table.Name())
+	child := plan.NewPartialGroupBy(gb.Grouping, aggregations, gb.Child)
+	return mergeGroupBy(gb, child), nil
+}
+
+// pushdownLimit gives the limit, plus any offset, as a hint to the table it
+// reads if the rows of the table are returned as they are read.
+func pushdownLimit(a *Analyzer, node *plan.Limit) (sql.Node, error) {
+	if node.Limit <= 0 {
+		return node, nil
+	}
+
+	child, ok, err := withTableLimit(a, node.Child, node.Limit)
+	if err != nil || !ok {
+		return node, err
+	}
+
+	return node.WithChildren(child)
+}
+
+func withTableLimit(a *Analyzer, n sql.Node, limit int64) (sql.Node, bool, error) {
+	switch n := n.(type) {
+	case *plan.Offset:
+		limit += n.Offset
+	case *plan.Project, *plan.TableAlias:
+	case *plan.ResolvedTable:
+		table, ok := n.Table.(sql.LimitedTable)
+		if !ok {
+			return n, false, nil
+		}
+
+		a.Log("table %q transformed with pushdown of limit %d", n.Name(), limit)
+		return plan.NewResolvedTable(table.WithLimit(limit)), true, nil
+	default:
+		return n, false, nil
+	}
+
+	child, ok, err := withTableLimit(a, n.Children()[0], limit)
+	if err != nil || !ok {
+		return n, false, err
+	}
+
+	nc, err := n.WithChildren(child)
+	return nc, err == nil, err
+}
+
+// findAggregatableTable returns the table read by the node if it can compute
+// aggregations and the rows are only filtered.
+func findAggregatableTable(n sql.Node) (sql.AggregatableTable, bool) {
+	for {
+		switch node := n.(type) {
+		case *plan.Filter:
+			n = node.Child
+		case *plan.ResolvedTable:
+			t, ok := node.Table.(sql.AggregatableTable)
+			return t, ok
+		default:
+			return nil, false
+		}
+	}
+}
+
+// groupByAggregations returns the aggregations of a group by, or false if
+// any of its other expressions is not one of the grouping expressions.
+func groupByAggregations(gb *plan.GroupBy) ([]sql.Aggregation, bool) {
+	var aggregations []sql.Aggregation
+	for _, e := range gb.Aggregate {
+		if alias, ok := e.(*expression.Alias); ok {
+			e = alias.Child
+		}
+
+		if agg, ok := e.(sql.Aggregation); ok {
+			aggregations = append(aggregations, agg)
+		} else if groupingIndex(gb.Grouping, e) < 0 {
+			return nil, false
+		}
+	}
+	return aggregations, true
+}
+
+func groupingIndex(grouping []sql.Expression, e sql.Expression) int {
+	for i, g := range grouping {
+		if g.String() == e.String() {
+			return i
+		}
+	}
+	return -1
+}
+
+// isMergeable returns whether the partial results of the aggregation can be
+// merged in any order. Script aggregators can be merged if they are lists or
+// sets, or if they have a combiner.
+func isMergeable(agg sql.Aggregation) bool {
+	switch agg := agg.(type) {
+	case *aggregation.Count,
+		*aggregation.CountDistinct,
+		*aggregation.Sum,
+		*aggregation.Min,
+		*aggregation.Max,
+		*aggregation.Avg:
+		return true
+	case *udf.Scriptable:
+		switch agg.Meta.UdfType.AggregatorType {
+		case udf.ListAggregator, udf.SetAggregator:
+			return true
+		case udf.GenericAggregator:
+			return agg.Meta.Combiner != nil
+		default:
+			return false
+		}
+	default:
+		return false
+	}
+}
+
+// mergeGroupBy returns a group by that merges the partial aggregations in the
+// rows of the given node, which start with the values of the grouping
+// expressions of the given group by, followed by the buffers of its
+// aggregations.
+func mergeGroupBy(gb *plan.GroupBy, child sql.Node) sql.Node {
+	var grouping = make([]sql.Expression, len(gb.Grouping))
+	for i, e := range gb.Grouping {
+		var table string
+		if t, ok := e.(sql.Tableable); ok {
+			table = t.Table()
+		}
+
+		name := e.String()
+		if n, ok := e.(sql.Nameable); ok {
+			name = n.Name()
+		}
+
+		grouping[i] = expression.NewGetFieldWithTable(i, e.Type(), table, name, e.IsNullable())
+	}
+
+	var aggregate = make([]sql.Expression, len(gb.Aggregate))
+	offset := len(gb.Grouping)
+	for i, e := range gb.Aggregate {
+		alias, isAlias := e.(*expression.Alias)
+		if isAlias {
+			e = alias.Child
+		}
+
+		if agg, ok := e.(sql.Aggregation); ok {
+			e = aggregation.NewMerge(agg, offset)
+			offset += len(agg.NewBuffer())
+		} else {
+			e = grouping[groupingIndex(gb.Grouping, e)]
+		}
+
+		if isAlias {
+			e = expression.NewAlias(e, alias.Name())
+		}
+		aggregate[i] = e
+	}
+
+	return plan.NewGroupBy(aggregate, grouping, child)
 }
 
 type releaser struct {
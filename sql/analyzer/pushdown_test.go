@@ -6,6 +6,7 @@ import (
 	"github.com/src-d/go-mysql-server/memory"
 	"github.com/src-d/go-mysql-server/sql"
 	"github.com/src-d/go-mysql-server/sql/expression"
+	"github.com/src-d/go-mysql-server/sql/expression/function/udf"
 	"github.com/src-d/go-mysql-server/sql/plan"
 	"github.com/stretchr/testify/require"
 )
@@ -224,3 +225,104 @@ func TestPushdownIndexable(t *testing.T) {
 
 	require.Equal(expected, result)
 }
+
+func TestPushdownLimit(t *testing.T) {
+	require := require.New(t)
+	f := getRule("pushdown")
+
+	table := memory.NewTable("mytable", sql.Schema{
+		{Name: "i", Type: sql.Int32, Source: "mytable"},
+		{Name: "f", Type: sql.Float64, Source: "mytable"},
+	})
+
+	db := memory.NewDatabase("mydb")
+	db.AddTable("mytable", table)
+
+	catalog := sql.NewCatalog()
+	catalog.AddDatabase(db)
+	a := NewDefault(catalog)
+
+	i := expression.NewGetFieldWithTable(0, sql.Int32, "mytable", "i", false)
+
+	node := plan.NewLimit(5, plan.NewOffset(2, plan.NewProject(
+		[]sql.Expression{i},
+		plan.NewResolvedTable(table),
+	)))
+	expected := plan.NewLimit(5, plan.NewOffset(2, plan.NewProject(
+		[]sql.Expression{i},
+		plan.NewResolvedTable(
+			table.WithProjection([]string{"i"}).(*memory.Table).WithLimit(7),
+		),
+	)))
+
+	result, err := f.Apply(sql.NewEmptyContext(), a, node)
+	require.NoError(err)
+	require.Equal(expected, result)
+
+	// The rows need to be sorted before the limit is applied.
+	node = plan.NewLimit(5, plan.NewSort(
+		[]plan.SortField{{Column: i, Order: plan.Ascending}},
+		plan.NewResolvedTable(table),
+	))
+	expected = plan.NewLimit(5, plan.NewSort(
+		[]plan.SortField{{Column: i, Order: plan.Ascending}},
+		plan.NewResolvedTable(table.WithProjection([]string{"i"})),
+	))
+
+	result, err = f.Apply(sql.NewEmptyContext(), a, node)
+	require.NoError(err)
+	require.Equal(expected, result)
+}
+
+func TestPushdownScriptAggregations(t *testing.T) {
+	f := getRule("pushdown")
+
+	table := memory.NewPartitionedTable("mytable", sql.Schema{
+		{Name: "i", Type: sql.Int64, Source: "mytable"},
+	}, 2)
+
+	db := memory.NewDatabase("mydb")
+	db.AddTable("mytable", table)
+
+	catalog := sql.NewCatalog()
+	catalog.AddDatabase(db)
+	a := NewDefault(catalog)
+
+	testCases := []struct {
+		name    string
+		macro   string
+		partial bool
+	}{
+		{"list", "<?L__@ @{mytable.i} ?>", true},
+		{"set", "<?S__@ @{mytable.i} ?>", true},
+		{"generic with combiner", "<?AGG@ 0 # $_ + @{mytable.i} # $_ + $PARTIAL ?>", true},
+		{"generic without combiner", "<?AGG@ 0 # $_ + @{mytable.i} ?>", false},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			require := require.New(t)
+
+			var scripts *udf.ScriptCache
+			_, udfs, err := scripts.MacroProcessor("SELECT "+tt.macro+" FROM mytable", 0, "js")
+			require.NoError(err)
+			require.Len(udfs, 1)
+
+			agg, err := udfs[0].Fn(expression.NewGetFieldWithTable(0, sql.Int64, "mytable", "i", false))
+			require.NoError(err)
+
+			node := plan.NewGroupBy([]sql.Expression{agg}, nil, plan.NewResolvedTable(table))
+			result, err := f.Apply(sql.NewEmptyContext(), a, node)
+			require.NoError(err)
+
+			var partial bool
+			plan.Inspect(result, func(n sql.Node) bool {
+				if _, ok := n.(*plan.PartialGroupBy); ok {
+					partial = true
+				}
+				return true
+			})
+			require.Equal(tt.partial, partial)
+		})
+	}
+}
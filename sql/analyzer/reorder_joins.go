@@ -0,0 +1,351 @@
+package analyzer
+
+import (
+	"math"
+
+	"github.com/src-d/go-mysql-server/sql"
+	"github.com/src-d/go-mysql-server/sql/expression"
+	"github.com/src-d/go-mysql-server/sql/plan"
+)
+
+// reorderJoins reorders the tables of trees of inner and cross joins so that
+// the estimated number of rows of the intermediate results is as small as
+// possible. Tables are joined one by one, starting with the smallest one and
+// then choosing the one whose join with the previous ones is estimated to
+// return the fewest rows, preferring the ones that don't need a cross join.
+//
+// Joins are only reordered when the number of rows of all the tables can be
+// estimated, which requires their statistics. The columns of the reordered
+// joins are projected in their original order.
+func reorderJoins(ctx *sql.Context, a *Analyzer, n sql.Node) (sql.Node, error) {
+	span, _ := ctx.Span("reorder_joins")
+	defer span.Finish()
+
+	if !n.Resolved() {
+		return n, nil
+	}
+
+	r := &joinReorderer{a: a, card: &cardinality{ctx}}
+	return r.transform(n)
+}
+
+type joinReorderer struct {
+	a    *Analyzer
+	card *cardinality
+}
+
+// joinTable is a table of a tree of joins, which is any node that is not an
+// inner or cross join.
+type joinTable struct {
+	node sql.Node
+	rows float64
+	// offset is the index of the first column of the table in the schema of
+	// the tree.
+	offset int
+}
+
+// joinCondition is a conjunct of the conditions of a tree of joins, with
+// its fields indexed using the schema of the whole tree.
+type joinCondition struct {
+	expr   sql.Expression
+	tables map[int]bool
+}
+
+// transform reorders the topmost trees of joins of the node.
+func (r *joinReorderer) transform(n sql.Node) (sql.Node, error) {
+	switch n.(type) {
+	case *plan.InnerJoin, *plan.CrossJoin:
+		return r.reorder(n)
+	default:
+		return r.transformChildren(n)
+	}
+}
+
+func (r *joinReorderer) transformChildren(n sql.Node) (sql.Node, error) {
+	if o, ok := n.(sql.OpaqueNode); ok && o.Opaque() {
+		return n, nil
+	}
+
+	children := n.Children()
+	if len(children) == 0 {
+		return n, nil
+	}
+
+	newChildren := make([]sql.Node, len(children))
+	for i, c := range children {
+		var err error
+		newChildren[i], err = r.transform(c)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return n.WithChildren(newChildren...)
+}
+
+func (r *joinReorderer) reorder(n sql.Node) (sql.Node, error) {
+	var tables []*joinTable
+	var conds []sql.Expression
+	if err := flattenJoins(n, 0, &tables, &conds); err != nil {
+		return nil, err
+	}
+
+	for _, t := range tables {
+		rows, ok := r.card.rows(t.node)
+		if !ok {
+			return r.transformChildren(n)
+		}
+		t.rows = rows
+	}
+
+	conditions, ok := joinConditions(tables, conds, len(n.Schema()))
+	if !ok {
+		return r.transformChildren(n)
+	}
+
+	for _, t := range tables {
+		node, err := r.transform(t.node)
+		if err != nil {
+			return nil, err
+		}
+		t.node = node
+	}
+
+	order := r.joinOrder(n, tables, conditions)
+
+	// The tables are joined in the same order as they were, so the tree is
+	// left as it is.
+	inOrder := true
+	for i, idx := range order {
+		inOrder = inOrder && i == idx
+	}
+
+	if inOrder {
+		var nodes = make([]sql.Node, len(tables))
+		for i, t := range tables {
+			nodes[i] = t.node
+		}
+		return replaceJoinTables(n, &nodes)
+	}
+
+	r.a.Log("reordering joins of %d tables", len(tables))
+	return buildJoins(n.Schema(), tables, conditions, order), nil
+}
+
+// flattenJoins collects the tables of a tree of inner and cross joins, along
+// with the conjuncts of the join conditions, which are indexed using the
+// schema of the whole tree.
+func flattenJoins(n sql.Node, offset int, tables *[]*joinTable, conds *[]sql.Expression) error {
+	var left, right sql.Node
+	switch n := n.(type) {
+	case *plan.InnerJoin:
+		left, right = n.Left, n.Right
+		cond, err := expression.TransformUp(n.Cond, func(e sql.Expression) (sql.Expression, error) {
+			if gf, ok := e.(*expression.GetField); ok {
+				return gf.WithIndex(gf.Index() + offset), nil
+			}
+			return e, nil
+		})
+		if err != nil {
+			return err
+		}
+		*conds = append(*conds, splitExpression(cond)...)
+	case *plan.CrossJoin:
+		left, right = n.Left, n.Right
+	default:
+		*tables = append(*tables, &joinTable{node: n, offset: offset})
+		return nil
+	}
+
+	if err := flattenJoins(left, offset, tables, conds); err != nil {
+		return err
+	}
+	return flattenJoins(right, offset+len(left.Schema()), tables, conds)
+}
+
+// joinConditions returns the conditions of a tree of joins along with the
+// tables they use, or false if some of them use columns that are not in the
+// tree.
+func joinConditions(tables []*joinTable, conds []sql.Expression, size int) ([]*joinCondition, bool) {
+	var result = make([]*joinCondition, len(conds))
+	for i, e := range conds {
+		c := &joinCondition{expr: e, tables: make(map[int]bool)}
+		ok := true
+		expression.Inspect(e, func(e sql.Expression) bool {
+			gf, isField := e.(*expression.GetField)
+			if !isField {
+				return true
+			}
+
+			if gf.Index() >= size {
+				ok = false
+				return false
+			}
+
+			c.tables[tableOfColumn(tables, gf.Index())] = true
+			return true
+		})
+
+		if !ok {
+			return nil, false
+		}
+		result[i] = c
+	}
+
+	return result, true
+}
+
+func tableOfColumn(tables []*joinTable, idx int) int {
+	for i := len(tables) - 1; i > 0; i-- {
+		if idx >= tables[i].offset {
+			return i
+		}
+	}
+	return 0
+}
+
+// joinOrder returns the order in which the tables are joined.
+func (r *joinReorderer) joinOrder(n sql.Node, tables []*joinTable, conds []*joinCondition) []int {
+	joined := make(map[int]bool)
+	var order []int
+	var rows float64
+
+	for len(order) < len(tables) {
+		best, bestRows, bestConnected := -1, math.Inf(1), false
+		for i, t := range tables {
+			if joined[i] {
+				continue
+			}
+
+			estimate := t.rows
+			var connected bool
+			if len(order) > 0 {
+				estimate *= rows
+				for _, c := range conds {
+					if c.tables[i] && usesOnly(c, joined, i) {
+						connected = true
+						estimate *= r.card.selectivity(n, c.expr)
+					}
+				}
+			}
+
+			if best < 0 ||
+				(connected && !bestConnected) ||
+				(connected == bestConnected && estimate < bestRows) {
+				best, bestRows, bestConnected = i, estimate, connected
+			}
+		}
+
+		joined[best] = true
+		order = append(order, best)
+		rows = bestRows
+	}
+
+	return order
+}
+
+// usesOnly reports whether the condition only uses the joined tables and the
+// given one.
+func usesOnly(c *joinCondition, joined map[int]bool, table int) bool {
+	for t := range c.tables {
+		if t != table && !joined[t] {
+			return false
+		}
+	}
+	return true
+}
+
+// buildJoins builds a left-deep tree joining the tables in the given order,
+// with each condition in the first join that has all the tables it uses.
+// The result is projected to have the columns of the given schema, which is
+// the one of the original tree.
+func buildJoins(
+	schema sql.Schema,
+	tables []*joinTable,
+	conds []*joinCondition,
+	order []int,
+) sql.Node {
+	// mapping has the index of each column of the original tree in the new
+	// one.
+	mapping := make([]int, len(schema))
+	var offset int
+	for _, idx := range order {
+		t := tables[idx]
+		for i := range t.node.Schema() {
+			mapping[t.offset+i] = offset + i
+		}
+		offset += len(t.node.Schema())
+	}
+
+	used := make([]bool, len(conds))
+	joined := make(map[int]bool)
+	var node sql.Node
+	for _, idx := range order {
+		joined[idx] = true
+		if node == nil {
+			node = tables[idx].node
+			continue
+		}
+
+		var exprs []sql.Expression
+		for i, c := range conds {
+			if !used[i] && usesOnly(c, joined, idx) {
+				used[i] = true
+				exprs = append(exprs, remapFields(c.expr, mapping))
+			}
+		}
+
+		if len(exprs) == 0 {
+			node = plan.NewCrossJoin(node, tables[idx].node)
+		} else {
+			node = plan.NewInnerJoin(node, tables[idx].node, expression.JoinAnd(exprs...))
+		}
+	}
+
+	var projection = make([]sql.Expression, len(schema))
+	for i, col := range schema {
+		projection[i] = expression.NewGetFieldWithTable(
+			mapping[i],
+			col.Type,
+			col.Source,
+			col.Name,
+			col.Nullable,
+		)
+	}
+
+	return plan.NewProject(projection, node)
+}
+
+func remapFields(e sql.Expression, mapping []int) sql.Expression {
+	e, _ = expression.TransformUp(e, func(e sql.Expression) (sql.Expression, error) {
+		if gf, ok := e.(*expression.GetField); ok {
+			return gf.WithIndex(mapping[gf.Index()]), nil
+		}
+		return e, nil
+	})
+	return e
+}
+
+// replaceJoinTables replaces the tables of a tree of joins with the given
+// ones, in the same order.
+func replaceJoinTables(n sql.Node, tables *[]sql.Node) (sql.Node, error) {
+	switch n := n.(type) {
+	case *plan.InnerJoin, *plan.CrossJoin:
+		children := n.Children()
+		left, err := replaceJoinTables(children[0], tables)
+		if err != nil {
+			return nil, err
+		}
+
+		right, err := replaceJoinTables(children[1], tables)
+		if err != nil {
+			return nil, err
+		}
+
+		return n.WithChildren(left, right)
+	default:
+		t := (*tables)[0]
+		*tables = (*tables)[1:]
+		return t, nil
+	}
+}
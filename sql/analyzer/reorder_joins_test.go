@@ -0,0 +1,83 @@
+package analyzer
+
+import (
+	"testing"
+
+	"github.com/src-d/go-mysql-server/memory"
+	"github.com/src-d/go-mysql-server/sql"
+	"github.com/src-d/go-mysql-server/sql/plan"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReorderJoins(t *testing.T) {
+	require := require.New(t)
+	ctx := sql.NewEmptyContext()
+
+	newTable := func(name string, rows int, cols ...string) *memory.Table {
+		var schema sql.Schema
+		for _, c := range cols {
+			schema = append(schema, &sql.Column{Name: c, Source: name, Type: sql.Int64})
+		}
+
+		table := memory.NewTable(name, schema)
+		for i := 0; i < rows; i++ {
+			require.NoError(table.Insert(ctx, sql.NewRow(int64(i), int64(i))))
+		}
+		return table
+	}
+
+	t1 := newTable("t1", 6, "a", "b")
+	t2 := newTable("t2", 2, "c", "d")
+	t3 := newTable("t3", 4, "e", "f")
+
+	node := plan.NewInnerJoin(
+		plan.NewInnerJoin(
+			plan.NewResolvedTable(t1),
+			plan.NewResolvedTable(t2),
+			eq(col(0, "t1", "a"), col(2, "t2", "c")),
+		),
+		plan.NewResolvedTable(t3),
+		eq(col(0, "t1", "a"), col(4, "t3", "e")),
+	)
+
+	rule := getRule("reorder_joins")
+
+	// Without statistics, the joins are left as they are.
+	result, err := rule.Apply(ctx, NewDefault(nil), node)
+	require.NoError(err)
+	require.Equal(node, result)
+
+	for _, table := range []*memory.Table{t1, t2, t3} {
+		require.NoError(table.Analyze(ctx))
+	}
+
+	result, err = rule.Apply(ctx, NewDefault(nil), node)
+	require.NoError(err)
+
+	expected := plan.NewProject(
+		[]sql.Expression{
+			col(2, "t1", "a"),
+			col(3, "t1", "b"),
+			col(0, "t2", "c"),
+			col(1, "t2", "d"),
+			col(4, "t3", "e"),
+			col(5, "t3", "f"),
+		},
+		plan.NewInnerJoin(
+			plan.NewInnerJoin(
+				plan.NewResolvedTable(t2),
+				plan.NewResolvedTable(t1),
+				eq(col(2, "t1", "a"), col(0, "t2", "c")),
+			),
+			plan.NewResolvedTable(t3),
+			eq(col(2, "t1", "a"), col(4, "t3", "e")),
+		),
+	)
+	require.Equal(expected, result)
+
+	expectedRows, err := sql.NodeToRows(ctx, node)
+	require.NoError(err)
+	rows, err := sql.NodeToRows(ctx, result)
+	require.NoError(err)
+	require.ElementsMatch(expectedRows, rows)
+}
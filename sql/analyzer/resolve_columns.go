@@ -136,6 +136,15 @@ func qualifyColumns(ctx *sql.Context, a *Analyzer, n sql.Node) (sql.Node, error)
 		tables := getNodeAvailableTables(n)
 
 		return plan.TransformExpressions(n, func(e sql.Expression) (sql.Expression, error) {
+			// Columns of tables of an outer query are resolved later using
+			// the scope of the analyzer.
+			if col, ok := e.(column); ok && col.Table() != "" {
+				table := strings.ToLower(col.Table())
+				if _, ok := tables[table]; !ok && a != nil && a.scope.hasTable(table) {
+					return col, nil
+				}
+			}
+
 			return qualifyExpression(e, columns, tables)
 		})
 	})
@@ -240,7 +249,7 @@ func getColumnsInNodes(nodes []sql.Node, columns map[string][]string) {
 
 	for _, node := range nodes {
 		switch n := node.(type) {
-		case *plan.ResolvedTable, *plan.SubqueryAlias:
+		case *plan.ResolvedTable, *plan.SubqueryAlias, *plan.TableFunction:
 			for _, col := range n.Schema() {
 				indexCol(col.Source, col.Name)
 			}
@@ -263,7 +272,7 @@ func getNodeAvailableTables(n sql.Node) map[string]string {
 func getNodesAvailableTables(tables map[string]string, nodes ...sql.Node) {
 	for _, n := range nodes {
 		switch n := n.(type) {
-		case *plan.SubqueryAlias, *plan.ResolvedTable:
+		case *plan.SubqueryAlias, *plan.ResolvedTable, *plan.TableFunction:
 			name := strings.ToLower(n.(sql.Nameable).Name())
 			tables[name] = name
 		case *plan.TableAlias:
@@ -325,7 +334,7 @@ func resolveColumns(ctx *sql.Context, a *Analyzer, n sql.Node) (sql.Node, error)
 				return resolveGlobalOrSessionColumn(ctx, uc)
 			}
 
-			return resolveColumnExpression(ctx, uc, columns)
+			return resolveColumnExpression(ctx, a, uc, columns)
 		})
 	})
 }
@@ -360,6 +369,7 @@ func resolveGlobalOrSessionColumn(ctx *sql.Context, col column) (sql.Expression,
 
 func resolveColumnExpression(
 	ctx *sql.Context,
+	a *Analyzer,
 	e column,
 	columns map[tableCol]indexedCol,
 ) (sql.Expression, error) {
@@ -373,6 +383,19 @@ func resolveColumnExpression(
 			// time to resolve other parts so this can be resolved.
 			return &deferredColumn{uc}, nil
 		default:
+			// The column may be a reference to the outer query if this is
+			// a subquery.
+			if a != nil {
+				outer, ok, err := a.scope.resolve(table, name)
+				if err != nil {
+					return nil, err
+				}
+
+				if ok {
+					return outer, nil
+				}
+			}
+
 			if table != "" {
 				return nil, ErrColumnTableNotFound.New(e.Table(), e.Name())
 			}
@@ -0,0 +1,275 @@
+package analyzer
+
+import (
+	"strings"
+
+	"github.com/src-d/go-mysql-server/sql"
+	"github.com/src-d/go-mysql-server/sql/expression"
+	"github.com/src-d/go-mysql-server/sql/plan"
+	errors "gopkg.in/src-d/go-errors.v1"
+)
+
+var (
+	// ErrInvalidRecursiveCte is returned when a recursive common table
+	// expression is not an union of a non recursive and a recursive part.
+	ErrInvalidRecursiveCte = errors.NewKind(
+		"recursive common table expression %q must be an UNION of a non recursive and a recursive part",
+	)
+	// ErrCteColumnCount is returned when the number of column names given to
+	// a common table expression does not match the number of columns of its
+	// definition.
+	ErrCteColumnCount = errors.NewKind(
+		"common table expression %q has %d columns, but %d column names were given",
+	)
+)
+
+// resolveCommonTableExpressions replaces all the references to the common
+// table expressions defined in With nodes with subqueries, so they can be
+// resolved later as any other subquery. Each reference gets its own copy of
+// the definition, which allows a common table expression to be used
+// several times in the same query.
+func resolveCommonTableExpressions(ctx *sql.Context, a *Analyzer, n sql.Node) (sql.Node, error) {
+	span, _ := ctx.Span("resolve_ctes")
+	defer span.Finish()
+
+	return plan.TransformUp(n, func(n sql.Node) (sql.Node, error) {
+		with, ok := n.(*plan.With)
+		if !ok {
+			return n, nil
+		}
+
+		a.Log("resolving %d common table expressions", len(with.CTEs))
+		return resolveWith(with)
+	})
+}
+
+type cteScope map[string]*plan.SubqueryAlias
+
+// without returns a copy of the scope without the given names.
+func (s cteScope) without(ctes []*plan.CommonTableExpression) cteScope {
+	var scope = make(cteScope, len(s))
+	for name, cte := range s {
+		scope[name] = cte
+	}
+
+	for _, cte := range ctes {
+		delete(scope, strings.ToLower(cte.Name))
+	}
+
+	return scope
+}
+
+func resolveWith(with *plan.With) (sql.Node, error) {
+	var scope = make(cteScope)
+	for _, cte := range with.CTEs {
+		name := strings.ToLower(cte.Name)
+		definition, err := replaceCteReferences(cte.Definition, scope)
+		if err != nil {
+			return nil, err
+		}
+
+		if with.Recursive && referencesTable(definition, name) {
+			definition, err = newRecursiveCte(cte, definition)
+			if err != nil {
+				return nil, err
+			}
+
+			scope[name] = plan.NewSubqueryAlias(cte.Name, definition)
+			continue
+		}
+
+		scope[name] = plan.NewSubqueryAlias(cte.Name, definition).WithColumns(cte.Columns)
+	}
+
+	return replaceCteReferences(with.Child, scope)
+}
+
+func newRecursiveCte(cte *plan.CommonTableExpression, definition sql.Node) (sql.Node, error) {
+	name := strings.ToLower(cte.Name)
+	union, ok := definition.(*plan.Union)
+	if !ok || referencesTable(union.Left, name) {
+		return nil, ErrInvalidRecursiveCte.New(cte.Name)
+	}
+
+	return plan.NewRecursiveCte(
+		cte.Name,
+		cte.Columns,
+		union.Distinct,
+		union.Left,
+		union.Right,
+	), nil
+}
+
+// referencesTable reports whether the given node has any reference to a table
+// with the given name in the current database.
+func referencesTable(n sql.Node, name string) bool {
+	var found bool
+	plan.Inspect(n, func(n sql.Node) bool {
+		if t, ok := n.(*plan.UnresolvedTable); ok {
+			if t.Database == "" && strings.ToLower(t.Name()) == name {
+				found = true
+			}
+		}
+		return !found
+	})
+	return found
+}
+
+// replaceCteReferences replaces all the tables referencing a common table
+// expression in the scope with its subquery, including the ones inside
+// subqueries.
+func replaceCteReferences(n sql.Node, scope cteScope) (sql.Node, error) {
+	if len(scope) == 0 {
+		return n, nil
+	}
+
+	switch n := n.(type) {
+	case *plan.UnresolvedTable:
+		if cte, ok := scope[strings.ToLower(n.Name())]; ok && n.Database == "" {
+			return plan.NewSubqueryAlias(cte.Name(), cte.Child).WithColumns(cte.Columns), nil
+		}
+		return n, nil
+	case *plan.TableAlias:
+		if t, ok := n.Child.(*plan.UnresolvedTable); ok && t.Database == "" {
+			if cte, ok := scope[strings.ToLower(t.Name())]; ok {
+				return plan.NewSubqueryAlias(n.Name(), cte.Child).WithColumns(cte.Columns), nil
+			}
+		}
+	case *plan.SubqueryAlias:
+		child, err := replaceCteReferences(n.Child, scope)
+		if err != nil {
+			return nil, err
+		}
+
+		return plan.NewSubqueryAlias(n.Name(), child).WithColumns(n.Columns), nil
+	case *plan.With:
+		// Expressions defined in a nested WITH clause hide the ones with the
+		// same name in the outer scope.
+		inner := scope.without(n.CTEs)
+		var ctes = make([]*plan.CommonTableExpression, len(n.CTEs))
+		for i, cte := range n.CTEs {
+			definition, err := replaceCteReferences(cte.Definition, inner)
+			if err != nil {
+				return nil, err
+			}
+
+			ctes[i] = plan.NewCommonTableExpression(cte.Name, cte.Columns, definition)
+		}
+
+		child, err := replaceCteReferences(n.Child, inner)
+		if err != nil {
+			return nil, err
+		}
+
+		return plan.NewWith(child, ctes, n.Recursive), nil
+	}
+
+	children := n.Children()
+	if len(children) > 0 {
+		var newChildren = make([]sql.Node, len(children))
+		for i, c := range children {
+			var err error
+			newChildren[i], err = replaceCteReferences(c, scope)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		var err error
+		n, err = n.WithChildren(newChildren...)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return plan.TransformExpressions(n, func(e sql.Expression) (sql.Expression, error) {
+		s, ok := e.(*expression.Subquery)
+		if !ok {
+			return e, nil
+		}
+
+		q, err := replaceCteReferences(s.Query, scope)
+		if err != nil {
+			return nil, err
+		}
+
+		return s.WithQuery(q), nil
+	})
+}
+
+// resolveRecursiveCte resolves the anchor of the recursive common table
+// expression and then the recursive part, reading the rows of the previous
+// iteration from a recursive table with the schema of the anchor.
+func resolveRecursiveCte(ctx *sql.Context, a *Analyzer, n *plan.RecursiveCte) (sql.Node, error) {
+	a.Log("resolving recursive common table expression %q", n.Name())
+
+	anchor, err := a.Analyze(ctx, n.Left)
+	if err != nil {
+		return nil, err
+	}
+	anchor = stripQueryProcess(anchor)
+
+	if len(n.Columns) > 0 {
+		anchor, err = renameColumns(n.Name(), anchor, n.Columns)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	table := plan.NewRecursiveTable(n.Name(), anchor.Schema())
+	name := strings.ToLower(n.Name())
+	recursive, err := plan.TransformUp(n.Right, func(n sql.Node) (sql.Node, error) {
+		if t, ok := n.(*plan.UnresolvedTable); ok {
+			if t.Database == "" && strings.ToLower(t.Name()) == name {
+				return plan.NewResolvedTable(table), nil
+			}
+		}
+		return n, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	recursive, err = a.Analyze(ctx, recursive)
+	if err != nil {
+		return nil, err
+	}
+	recursive = stripQueryProcess(recursive)
+
+	if l, r := len(anchor.Schema()), len(recursive.Schema()); l != r {
+		return nil, ErrUnionColumnCount.New(l, r)
+	}
+
+	node, err := n.WithChildren(anchor, recursive)
+	if err != nil {
+		return nil, err
+	}
+
+	return node.(*plan.RecursiveCte).WithTable(table), nil
+}
+
+// renameColumns returns a projection of the given resolved node with its
+// columns renamed.
+func renameColumns(name string, n sql.Node, columns []string) (sql.Node, error) {
+	schema := n.Schema()
+	if len(schema) != len(columns) {
+		return nil, ErrCteColumnCount.New(name, len(schema), len(columns))
+	}
+
+	var projections = make([]sql.Expression, len(schema))
+	for i, col := range schema {
+		projections[i] = expression.NewAlias(
+			expression.NewGetFieldWithTable(i, col.Type, col.Source, col.Name, col.Nullable),
+			columns[i],
+		)
+	}
+
+	return plan.NewProject(projections, n), nil
+}
+
+func stripQueryProcess(n sql.Node) sql.Node {
+	if qp, ok := n.(*plan.QueryProcess); ok {
+		return qp.Child
+	}
+	return n
+}
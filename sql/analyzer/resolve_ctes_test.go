@@ -0,0 +1,122 @@
+package analyzer
+
+import (
+	"testing"
+
+	"github.com/src-d/go-mysql-server/sql"
+	"github.com/src-d/go-mysql-server/sql/expression"
+	"github.com/src-d/go-mysql-server/sql/plan"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResolveCommonTableExpressions(t *testing.T) {
+	definition := plan.NewProject(
+		[]sql.Expression{expression.NewUnresolvedColumn("a")},
+		plan.NewUnresolvedTable("foo", ""),
+	)
+
+	recursiveDefinition := plan.NewUnion(
+		definition,
+		plan.NewProject(
+			[]sql.Expression{expression.NewUnresolvedColumn("a")},
+			plan.NewUnresolvedTable("t", ""),
+		),
+	)
+
+	testCases := []struct {
+		name     string
+		node     sql.Node
+		expected sql.Node
+		err      bool
+	}{
+		{
+			"references are replaced with subqueries",
+			plan.NewWith(
+				plan.NewCrossJoin(
+					plan.NewUnresolvedTable("t", ""),
+					plan.NewTableAlias("t2", plan.NewUnresolvedTable("T", "")),
+				),
+				[]*plan.CommonTableExpression{
+					plan.NewCommonTableExpression("t", []string{"b"}, definition),
+				},
+				false,
+			),
+			plan.NewCrossJoin(
+				plan.NewSubqueryAlias("t", definition).WithColumns([]string{"b"}),
+				plan.NewSubqueryAlias("t2", definition).WithColumns([]string{"b"}),
+			),
+			false,
+		},
+		{
+			"tables in other databases are not replaced",
+			plan.NewWith(
+				plan.NewUnresolvedTable("t", "mydb"),
+				[]*plan.CommonTableExpression{
+					plan.NewCommonTableExpression("t", nil, definition),
+				},
+				false,
+			),
+			plan.NewUnresolvedTable("t", "mydb"),
+			false,
+		},
+		{
+			"later expressions can use the previous ones",
+			plan.NewWith(
+				plan.NewUnresolvedTable("u", ""),
+				[]*plan.CommonTableExpression{
+					plan.NewCommonTableExpression("t", nil, definition),
+					plan.NewCommonTableExpression("u", nil, plan.NewUnresolvedTable("t", "")),
+				},
+				false,
+			),
+			plan.NewSubqueryAlias("u", plan.NewSubqueryAlias("t", definition)),
+			false,
+		},
+		{
+			"recursive expression",
+			plan.NewWith(
+				plan.NewUnresolvedTable("t", ""),
+				[]*plan.CommonTableExpression{
+					plan.NewCommonTableExpression("t", []string{"b"}, recursiveDefinition),
+				},
+				true,
+			),
+			plan.NewSubqueryAlias("t", plan.NewRecursiveCte(
+				"t",
+				[]string{"b"},
+				false,
+				recursiveDefinition.Left,
+				recursiveDefinition.Right,
+			)),
+			false,
+		},
+		{
+			"recursive expression without union",
+			plan.NewWith(
+				plan.NewUnresolvedTable("t", ""),
+				[]*plan.CommonTableExpression{
+					plan.NewCommonTableExpression("t", nil, plan.NewUnresolvedTable("t", "")),
+				},
+				true,
+			),
+			nil,
+			true,
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			require := require.New(t)
+
+			result, err := resolveCommonTableExpressions(sql.NewEmptyContext(), NewDefault(nil), tt.node)
+			if tt.err {
+				require.Error(err)
+				require.True(ErrInvalidRecursiveCte.Is(err))
+				return
+			}
+
+			require.NoError(err)
+			require.Equal(tt.expected, result)
+		})
+	}
+}
@@ -29,9 +29,15 @@ func resolveFunctions(ctx *sql.Context, a *Analyzer, n sql.Node) (sql.Node, erro
 			}
 
 			n := uf.Name()
-			f, err := a.Catalog.Function(n)
-			if err != nil {
-				return nil, err
+			// Functions of the query itself take precedence over the ones
+			// in the catalog.
+			f, ok := ctx.Functions()[n]
+			if !ok {
+				var err error
+				f, err = a.Catalog.Function(n)
+				if err != nil {
+					return nil, err
+				}
 			}
 
 			rf, err := f.Call(uf.Arguments...)
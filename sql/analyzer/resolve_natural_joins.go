@@ -19,7 +19,7 @@ func resolveNaturalJoins(ctx *sql.Context, a *Analyzer, n sql.Node) (sql.Node, e
 		switch n := node.(type) {
 		case *plan.TableAlias:
 			alias := n.Name()
-			table := n.Child.(*plan.ResolvedTable).Name()
+			table := n.Child.(sql.Nameable).Name()
 			tableAliases[strings.ToLower(alias)] = table
 			return n, nil
 		case *plan.NaturalJoin:
@@ -0,0 +1,239 @@
+package analyzer
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/src-d/go-mysql-server/sql"
+	"github.com/src-d/go-mysql-server/sql/expression"
+	"github.com/src-d/go-mysql-server/sql/expression/function"
+	"github.com/src-d/go-mysql-server/sql/plan"
+	errors "gopkg.in/src-d/go-errors.v1"
+)
+
+// ErrInvalidPivotAggregate is returned when an expression that is not an
+// aggregation is used in a PIVOT.
+var ErrInvalidPivotAggregate = errors.NewKind("%s is not an aggregation and can't be used in PIVOT")
+
+// unpivotIndexColumn is the name of the column with the index of the
+// unpivoted column each row is generated for.
+const unpivotIndexColumn = "__unpivot_idx"
+
+// resolvePivots replaces the PIVOT and UNPIVOT nodes whose child is resolved
+// with the GroupBy and Generate nodes that compute them.
+func resolvePivots(ctx *sql.Context, a *Analyzer, n sql.Node) (sql.Node, error) {
+	span, _ := ctx.Span("resolve_pivots")
+	defer span.Finish()
+
+	a.Log("resolve pivots, node of type: %T", n)
+	return plan.TransformUp(n, func(n sql.Node) (sql.Node, error) {
+		switch n := n.(type) {
+		case *plan.Pivot:
+			if !n.Child.Resolved() {
+				return n, nil
+			}
+
+			a.Log("resolving pivot on %s", n.Column)
+			return pivotToGroupBy(n)
+		case *plan.Unpivot:
+			if !n.Child.Resolved() {
+				return n, nil
+			}
+
+			a.Log("resolving unpivot into %s and %s", n.NameColumn, n.ValueColumn)
+			return unpivotToGenerate(n)
+		default:
+			return n, nil
+		}
+	})
+}
+
+// pivotToGroupBy turns a pivot into a GroupBy, grouping by the columns of
+// its child not used in the aggregations or the pivoted column. For each
+// value and aggregation, the result has the aggregation of the rows with
+// that value, as in `SUM(CASE WHEN month = 'jan' THEN amount END) AS jan`.
+func pivotToGroupBy(p *plan.Pivot) (sql.Node, error) {
+	used := make(map[string]bool)
+	for _, e := range append([]sql.Expression{p.Column}, p.Aggregates...) {
+		expression.Inspect(e, func(e sql.Expression) bool {
+			if col, ok := e.(*expression.UnresolvedColumn); ok {
+				used[strings.ToLower(col.Name())] = true
+			}
+			return true
+		})
+	}
+
+	var grouping []sql.Expression
+	for i, col := range p.Child.Schema() {
+		if !used[strings.ToLower(col.Name)] {
+			grouping = append(grouping, expression.NewGetFieldWithTable(i, col.Type, col.Source, col.Name, col.Nullable))
+		}
+	}
+
+	aggregate := append([]sql.Expression{}, grouping...)
+	for _, v := range p.Values {
+		value, name := v, ""
+		if alias, ok := v.(*expression.Alias); ok {
+			value, name = alias.Child, alias.Name()
+		} else if lit, ok := v.(*expression.Literal); ok {
+			name = fmt.Sprint(lit.Value())
+		} else {
+			name = v.String()
+		}
+
+		for _, agg := range p.Aggregates {
+			agg, aggName := agg, ""
+			if alias, ok := agg.(*expression.Alias); ok {
+				agg, aggName = alias.Child, alias.Name()
+			}
+
+			f, ok := agg.(*expression.UnresolvedFunction)
+			if !ok || !f.IsAggregate {
+				return nil, ErrInvalidPivotAggregate.New(agg)
+			}
+
+			if aggName == "" {
+				aggName = f.Name()
+			}
+
+			args := make([]sql.Expression, len(f.Arguments))
+			for i, arg := range f.Arguments {
+				if _, ok := arg.(*expression.Star); ok {
+					arg = expression.NewLiteral(int64(1), sql.Int64)
+				}
+
+				args[i] = expression.NewCase(
+					nil,
+					[]expression.CaseBranch{{Cond: expression.NewEquals(p.Column, value), Value: arg}},
+					nil,
+				)
+			}
+
+			column := name
+			if len(p.Aggregates) > 1 {
+				column = name + "_" + aggName
+			}
+
+			aggregate = append(aggregate, expression.NewAlias(
+				expression.NewUnresolvedFunction(f.Name(), true, args...),
+				column,
+			))
+		}
+	}
+
+	return plan.NewGroupBy(aggregate, grouping, p.Child), nil
+}
+
+// unpivotToGenerate turns an unpivot into a projection of the rows of its
+// child with an index for each of the unpivoted columns, which is exploded
+// and projected into the label and the value of the column with that index.
+// Rows with a null value are then filtered out unless nulls are included.
+func unpivotToGenerate(u *plan.Unpivot) (sql.Node, error) {
+	schema := u.Child.Schema()
+
+	unpivoted := make(map[int]bool)
+	columns := make([]int, len(u.Columns))
+	for i, col := range u.Columns {
+		idx, err := unpivotColumnIndex(schema, col)
+		if err != nil {
+			return nil, err
+		}
+
+		unpivoted[idx] = true
+		columns[i] = idx
+	}
+
+	indexes := make([]interface{}, len(columns))
+	for i := range indexes {
+		indexes[i] = int64(i)
+	}
+
+	var inner, outer []sql.Expression
+	for i, col := range schema {
+		field := expression.NewGetFieldWithTable(i, col.Type, col.Source, col.Name, col.Nullable)
+		inner = append(inner, field)
+		if !unpivoted[i] {
+			outer = append(outer, field)
+		}
+	}
+
+	inner = append(inner, expression.NewAlias(
+		function.NewExplode(expression.NewLiteral(indexes, sql.Array(sql.Int64))),
+		unpivotIndexColumn,
+	))
+
+	// All the values must be of the same type, so they are converted to
+	// text if they are not.
+	sameType := true
+	for _, idx := range columns {
+		sameType = sameType && schema[idx].Type == schema[columns[0]].Type
+	}
+
+	index := expression.NewGetField(len(schema), sql.Int64, unpivotIndexColumn, false)
+	var names, values []expression.CaseBranch
+	for i, idx := range columns {
+		cond := expression.NewLiteral(int64(i), sql.Int64)
+		col := schema[idx]
+
+		var value sql.Expression = expression.NewGetFieldWithTable(idx, col.Type, col.Source, col.Name, col.Nullable)
+		if !sameType {
+			value = expression.NewConvert(value, expression.ConvertToChar)
+		}
+
+		names = append(names, expression.CaseBranch{
+			Cond:  cond,
+			Value: expression.NewLiteral(u.Labels[i], sql.Text),
+		})
+		values = append(values, expression.CaseBranch{Cond: cond, Value: value})
+	}
+
+	outer = append(
+		outer,
+		expression.NewAlias(expression.NewCase(index, names, nil), u.NameColumn),
+		expression.NewAlias(expression.NewCase(index, values, nil), u.ValueColumn),
+	)
+
+	var node sql.Node = plan.NewProject(outer, plan.NewProject(inner, u.Child))
+	if !u.IncludeNulls {
+		value := outer[len(outer)-1]
+		node = plan.NewFilter(
+			expression.NewNot(expression.NewIsNull(
+				expression.NewGetField(len(outer)-1, value.Type(), u.ValueColumn, true),
+			)),
+			node,
+		)
+	}
+
+	return node, nil
+}
+
+// unpivotColumnIndex returns the index in the schema of the given column to
+// unpivot.
+func unpivotColumnIndex(schema sql.Schema, e sql.Expression) (int, error) {
+	col, ok := e.(*expression.UnresolvedColumn)
+	if !ok {
+		return -1, ErrColumnNotFound.New(e)
+	}
+
+	idx := -1
+	for i, c := range schema {
+		if !strings.EqualFold(c.Name, col.Name()) ||
+			(col.Table() != "" && !strings.EqualFold(c.Source, col.Table())) {
+			continue
+		}
+
+		if idx >= 0 {
+			return -1, ErrAmbiguousColumnName.New(col.Name(), []string{schema[idx].Source, c.Source})
+		}
+		idx = i
+	}
+
+	if idx < 0 {
+		if col.Table() != "" {
+			return -1, ErrColumnTableNotFound.New(col.Table(), col.Name())
+		}
+		return -1, ErrColumnNotFound.New(col.Name())
+	}
+
+	return idx, nil
+}
@@ -0,0 +1,162 @@
+package analyzer
+
+import (
+	"testing"
+
+	"github.com/src-d/go-mysql-server/memory"
+	"github.com/src-d/go-mysql-server/sql"
+	"github.com/src-d/go-mysql-server/sql/expression"
+	"github.com/src-d/go-mysql-server/sql/expression/function"
+	"github.com/src-d/go-mysql-server/sql/plan"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResolvePivot(t *testing.T) {
+	require := require.New(t)
+	a := NewDefault(sql.NewCatalog())
+	ctx := sql.NewEmptyContext()
+
+	table := plan.NewResolvedTable(memory.NewTable("sales", sql.Schema{
+		{Name: "region", Type: sql.Text, Source: "sales"},
+		{Name: "month", Type: sql.Text, Source: "sales"},
+		{Name: "amount", Type: sql.Int64, Source: "sales"},
+	}))
+
+	month := expression.NewUnresolvedColumn("month")
+	jan := expression.NewLiteral("jan", sql.Text)
+	feb := expression.NewLiteral("feb", sql.Text)
+	node := plan.NewPivot(
+		[]sql.Expression{
+			expression.NewUnresolvedFunction("sum", true, expression.NewUnresolvedColumn("amount")),
+			expression.NewAlias(expression.NewUnresolvedFunction("count", true, expression.NewStar()), "n"),
+		},
+		month,
+		[]sql.Expression{jan, expression.NewAlias(feb, "february")},
+		table,
+	)
+
+	when := func(value, arg sql.Expression) sql.Expression {
+		return expression.NewCase(
+			nil,
+			[]expression.CaseBranch{{Cond: expression.NewEquals(month, value), Value: arg}},
+			nil,
+		)
+	}
+
+	region := expression.NewGetFieldWithTable(0, sql.Text, "sales", "region", false)
+	amount := expression.NewUnresolvedColumn("amount")
+	one := expression.NewLiteral(int64(1), sql.Int64)
+	expected := plan.NewGroupBy(
+		[]sql.Expression{
+			region,
+			expression.NewAlias(expression.NewUnresolvedFunction("sum", true, when(jan, amount)), "jan_sum"),
+			expression.NewAlias(expression.NewUnresolvedFunction("count", true, when(jan, one)), "jan_n"),
+			expression.NewAlias(expression.NewUnresolvedFunction("sum", true, when(feb, amount)), "february_sum"),
+			expression.NewAlias(expression.NewUnresolvedFunction("count", true, when(feb, one)), "february_n"),
+		},
+		[]sql.Expression{region},
+		table,
+	)
+
+	result, err := resolvePivots(ctx, a, node)
+	require.NoError(err)
+	require.Equal(expected, result)
+
+	// Pivots on unresolved tables are resolved later.
+	unresolved := plan.NewPivot(node.Aggregates, month, node.Values, plan.NewUnresolvedTable("sales", ""))
+	result, err = resolvePivots(ctx, a, unresolved)
+	require.NoError(err)
+	require.Equal(unresolved, result)
+
+	_, err = resolvePivots(ctx, a, plan.NewPivot(
+		[]sql.Expression{expression.NewUnresolvedColumn("amount")},
+		month,
+		[]sql.Expression{jan},
+		table,
+	))
+	require.Error(err)
+	require.True(ErrInvalidPivotAggregate.Is(err))
+}
+
+func TestResolveUnpivot(t *testing.T) {
+	require := require.New(t)
+	a := NewDefault(sql.NewCatalog())
+	ctx := sql.NewEmptyContext()
+
+	table := plan.NewResolvedTable(memory.NewTable("sales", sql.Schema{
+		{Name: "region", Type: sql.Text, Source: "sales"},
+		{Name: "jan", Type: sql.Int64, Source: "sales", Nullable: true},
+		{Name: "feb", Type: sql.Int64, Source: "sales", Nullable: true},
+	}))
+
+	node := plan.NewUnpivot(
+		"amount", "month",
+		[]sql.Expression{
+			expression.NewUnresolvedColumn("jan"),
+			expression.NewUnresolvedQualifiedColumn("sales", "feb"),
+		},
+		[]string{"jan", "february"},
+		false,
+		table,
+	)
+
+	region := expression.NewGetFieldWithTable(0, sql.Text, "sales", "region", false)
+	janField := expression.NewGetFieldWithTable(1, sql.Int64, "sales", "jan", true)
+	febField := expression.NewGetFieldWithTable(2, sql.Int64, "sales", "feb", true)
+	index := expression.NewGetField(3, sql.Int64, unpivotIndexColumn, false)
+	zero := expression.NewLiteral(int64(0), sql.Int64)
+	one := expression.NewLiteral(int64(1), sql.Int64)
+
+	month := expression.NewAlias(expression.NewCase(index, []expression.CaseBranch{
+		{Cond: zero, Value: expression.NewLiteral("jan", sql.Text)},
+		{Cond: one, Value: expression.NewLiteral("february", sql.Text)},
+	}, nil), "month")
+	amount := expression.NewAlias(expression.NewCase(index, []expression.CaseBranch{
+		{Cond: zero, Value: janField},
+		{Cond: one, Value: febField},
+	}, nil), "amount")
+
+	expected := plan.NewFilter(
+		expression.NewNot(expression.NewIsNull(
+			expression.NewGetField(2, sql.Int64, "amount", true),
+		)),
+		plan.NewProject(
+			[]sql.Expression{region, month, amount},
+			plan.NewProject(
+				[]sql.Expression{
+					region,
+					janField,
+					febField,
+					expression.NewAlias(
+						function.NewExplode(expression.NewLiteral(
+							[]interface{}{int64(0), int64(1)},
+							sql.Array(sql.Int64),
+						)),
+						unpivotIndexColumn,
+					),
+				},
+				table,
+			),
+		),
+	)
+
+	result, err := resolvePivots(ctx, a, node)
+	require.NoError(err)
+	require.Equal(expected, result)
+
+	// Nulls are not filtered out when they are included.
+	node.IncludeNulls = true
+	result, err = resolvePivots(ctx, a, node)
+	require.NoError(err)
+	require.Equal(expected.Child, result)
+
+	_, err = resolvePivots(ctx, a, plan.NewUnpivot(
+		"amount", "month",
+		[]sql.Expression{expression.NewUnresolvedColumn("mar")},
+		[]string{"mar"},
+		false,
+		table,
+	))
+	require.Error(err)
+	require.True(ErrColumnNotFound.Is(err))
+}
@@ -1,6 +1,9 @@
 package analyzer
 
 import (
+	"reflect"
+	"strings"
+
 	"github.com/src-d/go-mysql-server/sql"
 	"github.com/src-d/go-mysql-server/sql/expression"
 	"github.com/src-d/go-mysql-server/sql/plan"
@@ -15,35 +18,190 @@ func resolveSubqueries(ctx *sql.Context, a *Analyzer, n sql.Node) (sql.Node, err
 		switch n := n.(type) {
 		case *plan.SubqueryAlias:
 			a.Log("found subquery %q with child of type %T", n.Name(), n.Child)
-			child, err := a.Analyze(ctx, n.Child)
+			// Subqueries in the FROM clause cannot reference the columns of
+			// the outer query.
+			child, err := a.withScope(nil).Analyze(ctx, n.Child)
 			if err != nil {
 				return nil, err
 			}
 
+			if len(n.Columns) > 0 {
+				qp, ok := child.(*plan.QueryProcess)
+				if ok {
+					child = qp.Child
+				}
+
+				child, err = renameColumns(n.Name(), child, n.Columns)
+				if err != nil {
+					return nil, err
+				}
+
+				if ok {
+					child, err = qp.WithChildren(child)
+					if err != nil {
+						return nil, err
+					}
+				}
+			}
+
 			return plan.NewSubqueryAlias(n.Name(), child), nil
+		case *plan.RecursiveCte:
+			if n.Resolved() {
+				return n, nil
+			}
+
+			return resolveRecursiveCte(ctx, a, n)
 		default:
 			return n, nil
 		}
 	})
-	if err != nil {
-		return nil, err
+	return n, err
+}
+
+// resolveSubqueryExprs analyzes the subqueries used as expressions once the
+// children of the node using them are resolved, so they can reference the
+// columns of the outer query.
+func resolveSubqueryExprs(ctx *sql.Context, a *Analyzer, n sql.Node) (sql.Node, error) {
+	span, ctx := ctx.Span("resolve_subquery_exprs")
+	defer span.Finish()
+
+	a.Log("resolving subquery expressions")
+	return plan.TransformUp(n, func(n sql.Node) (sql.Node, error) {
+		if _, ok := n.(sql.Expressioner); !ok || n.Resolved() {
+			return n, nil
+		}
+
+		var schema sql.Schema
+		for _, c := range n.Children() {
+			if !c.Resolved() {
+				return n, nil
+			}
+			schema = append(schema, c.Schema()...)
+		}
+
+		return plan.TransformExpressions(n, func(e sql.Expression) (sql.Expression, error) {
+			s, ok := e.(*expression.Subquery)
+			if !ok || s.Resolved() {
+				return e, nil
+			}
+
+			sa := a.withScope(newScope(a.scope, schema, getNodeAvailableTables(n)))
+			q, err := sa.Analyze(ctx, s.Query)
+			if err != nil {
+				return nil, err
+			}
+
+			if qp, ok := q.(*plan.QueryProcess); ok {
+				q = qp.Child
+			}
+
+			return s.WithQuery(q).WithOuter(sa.scope.outer), nil
+		})
+	})
+}
+
+// scope contains the columns of an outer query that can be referenced by a
+// subquery being analyzed. The values of the ones that are used are computed
+// by the outer expressions of the subquery, and referenced inside it with
+// an OuterField.
+type scope struct {
+	parent *scope
+	schema sql.Schema
+	// tables available in the scope by name or alias.
+	tables map[string]string
+	outer  []sql.Expression
+}
+
+func newScope(parent *scope, schema sql.Schema, tables map[string]string) *scope {
+	return &scope{parent: parent, schema: schema, tables: tables}
+}
+
+// hasTable returns whether there is any column of the given table in the
+// scope or any of its parents.
+func (s *scope) hasTable(table string) bool {
+	for ; s != nil; s = s.parent {
+		if _, ok := s.tables[table]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// resolve returns the expression that references the given column in the
+// scope, if it's found in the scope or any of its parents.
+func (s *scope) resolve(table, name string) (sql.Expression, bool, error) {
+	if s == nil {
+		return nil, false, nil
 	}
 
-	return plan.TransformExpressionsUp(n, func(e sql.Expression) (sql.Expression, error) {
-		s, ok := e.(*expression.Subquery)
-		if !ok || s.Resolved() {
-			return e, nil
+	source := table
+	if t, ok := s.tables[table]; ok {
+		source = t
+	}
+
+	var found = -1
+	for i, col := range s.schema {
+		if strings.ToLower(col.Name) != name {
+			continue
 		}
 
-		q, err := a.Analyze(ctx, s.Query)
-		if err != nil {
-			return nil, err
+		if source != "" && strings.ToLower(col.Source) != source {
+			continue
 		}
 
-		if qp, ok := q.(*plan.QueryProcess); ok {
-			q = qp.Child
+		if found >= 0 && s.schema[found].Source != col.Source {
+			return nil, false, ErrAmbiguousColumnName.New(
+				name,
+				strings.Join([]string{s.schema[found].Source, col.Source}, ", "),
+			)
 		}
 
-		return s.WithQuery(q), nil
-	})
+		if found < 0 {
+			found = i
+		}
+	}
+
+	var binding sql.Expression
+	if found >= 0 {
+		col := s.schema[found]
+		binding = expression.NewGetFieldWithTable(found, col.Type, col.Source, col.Name, col.Nullable)
+	} else {
+		var ok bool
+		var err error
+		// The column may belong to a query outside of the outer one, in
+		// which case the outer query needs to receive its value too.
+		binding, ok, err = s.parent.resolve(table, name)
+		if err != nil || !ok {
+			return nil, ok, err
+		}
+	}
+
+	idx := -1
+	for i, e := range s.outer {
+		if reflect.DeepEqual(e, binding) {
+			idx = i
+			break
+		}
+	}
+
+	if idx < 0 {
+		idx = len(s.outer)
+		s.outer = append(s.outer, binding)
+	}
+
+	return expression.NewOuterField(
+		idx,
+		binding.Type(),
+		binding.(sql.Tableable).Table(),
+		binding.(sql.Nameable).Name(),
+		binding.IsNullable(),
+	), true, nil
+}
+
+// withScope returns a copy of the analyzer that resolves the columns that
+// are not found in the node being analyzed using the given scope.
+func (a *Analyzer) withScope(s *scope) *Analyzer {
+	na := *a
+	na.scope = s
+	return &na
 }
@@ -0,0 +1,24 @@
+package analyzer
+
+import (
+	"github.com/src-d/go-mysql-server/sql"
+	"github.com/src-d/go-mysql-server/sql/plan"
+)
+
+// resolveTableFunctions infers the schema of the functions used as tables
+// without a declared schema, once their function has been resolved.
+func resolveTableFunctions(ctx *sql.Context, a *Analyzer, n sql.Node) (sql.Node, error) {
+	span, ctx := ctx.Span("resolve_table_functions")
+	defer span.Finish()
+
+	a.Log("resolve table functions, node of type: %T", n)
+	return plan.TransformUp(n, func(n sql.Node) (sql.Node, error) {
+		t, ok := n.(*plan.TableFunction)
+		if !ok || t.Resolved() || !t.Function.Resolved() {
+			return n, nil
+		}
+
+		a.Log("inferring schema of table function %q", t.Name())
+		return t.WithInferredSchema(ctx)
+	})
+}
@@ -0,0 +1,50 @@
+package analyzer
+
+import (
+	"testing"
+
+	"github.com/src-d/go-mysql-server/sql"
+	"github.com/src-d/go-mysql-server/sql/expression"
+	"github.com/src-d/go-mysql-server/sql/plan"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResolveTableFunctions(t *testing.T) {
+	require := require.New(t)
+	a := NewDefault(sql.NewCatalog())
+	ctx := sql.NewEmptyContext()
+
+	value := []interface{}{int64(1), int64(2)}
+	fn := expression.NewLiteral(value, sql.JSON)
+
+	node := plan.NewProject(
+		[]sql.Expression{expression.NewStar()},
+		plan.NewTableFunction("t", fn, nil),
+	)
+	expected := plan.NewProject(
+		[]sql.Expression{expression.NewStar()},
+		plan.NewTableFunction("t", fn, sql.Schema{{Name: "value", Type: sql.Int64}}),
+	)
+
+	result, err := resolveTableFunctions(ctx, a, node)
+	require.NoError(err)
+	require.Equal(expected, result)
+
+	// Unresolved functions are resolved first.
+	node = plan.NewProject(
+		[]sql.Expression{expression.NewStar()},
+		plan.NewTableFunction("t", expression.NewUnresolvedFunction("f", false), nil),
+	)
+	result, err = resolveTableFunctions(ctx, a, node)
+	require.NoError(err)
+	require.Equal(node, result)
+
+	// Declared schemas are kept.
+	node = plan.NewProject(
+		[]sql.Expression{expression.NewStar()},
+		plan.NewTableFunction("t", fn, sql.Schema{{Name: "a", Type: sql.Text}}),
+	)
+	result, err = resolveTableFunctions(ctx, a, node)
+	require.NoError(err)
+	require.Equal(node, result)
+}
@@ -23,6 +23,14 @@ func resolveTables(ctx *sql.Context, a *Analyzer, n sql.Node) (sql.Node, error)
 	a.Log("resolve table, node of type: %T", n)
 	return plan.TransformUp(n, func(n sql.Node) (sql.Node, error) {
 		a.Log("transforming node of type: %T", n)
+		// Views are resolved as subqueries, so an alias of a view is the
+		// name of the subquery instead.
+		if ta, ok := n.(*plan.TableAlias); ok {
+			if sq, ok := ta.Child.(*plan.SubqueryAlias); ok {
+				return plan.NewSubqueryAlias(ta.Name(), sq.Child).WithColumns(sq.Columns), nil
+			}
+		}
+
 		if n.Resolved() {
 			return n, nil
 		}
@@ -40,7 +48,15 @@ func resolveTables(ctx *sql.Context, a *Analyzer, n sql.Node) (sql.Node, error)
 
 		rt, err := a.Catalog.Table(db, name)
 		if err != nil {
-			if sql.ErrTableNotFound.Is(err) && name == dualTableName {
+			if !sql.ErrTableNotFound.Is(err) {
+				return nil, err
+			}
+
+			if view, verr := a.Catalog.View(db, name); verr == nil {
+				return resolveView(ctx, a, view)
+			}
+
+			if name == dualTableName {
 				rt = dualTable
 				name = dualTableName
 			} else {
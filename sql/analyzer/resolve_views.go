@@ -0,0 +1,204 @@
+package analyzer
+
+import (
+	"strings"
+
+	"github.com/src-d/go-mysql-server/sql"
+	"github.com/src-d/go-mysql-server/sql/expression"
+	"github.com/src-d/go-mysql-server/sql/plan"
+	errors "gopkg.in/src-d/go-errors.v1"
+)
+
+// ErrViewRecursion is returned when the definition of a view references the
+// view itself, either directly or through other views.
+var ErrViewRecursion = errors.NewKind("view `%s`.`%s` contains view recursion")
+
+// resolveCreateView qualifies all the tables in the definition of the views
+// being created with the database of the view, so the tables of the view do
+// not depend on the current database when it's used, and makes sure the view
+// does not reference itself.
+func resolveCreateView(ctx *sql.Context, a *Analyzer, n sql.Node) (sql.Node, error) {
+	span, _ := ctx.Span("resolve_create_view")
+	defer span.Finish()
+
+	cv, ok := n.(*plan.CreateView)
+	if !ok || cv.Resolved() {
+		return n, nil
+	}
+
+	db := cv.Database().Name()
+	if db == "" {
+		db = a.Catalog.CurrentDatabase()
+	}
+
+	definition, err := qualifyTables(cv.Definition, db, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := checkViewRecursion(a, db, cv.Name(), definition); err != nil {
+		return nil, err
+	}
+
+	a.Log("qualified tables of view %q with database %q", cv.Name(), db)
+	return cv.WithDefinition(definition), nil
+}
+
+// resolveView resolves the definition of the given view and returns it as a
+// subquery with the name of the view.
+func resolveView(ctx *sql.Context, a *Analyzer, view *sql.View) (sql.Node, error) {
+	a.Log("resolving view %q", view.Name())
+
+	child, err := a.Analyze(ctx, view.Definition)
+	if err != nil {
+		return nil, err
+	}
+	child = stripQueryProcess(child)
+
+	if len(view.Columns) > 0 {
+		child, err = renameColumns(view.Name(), child, view.Columns)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return plan.NewSubqueryAlias(view.Name(), child), nil
+}
+
+// checkViewRecursion returns an error if the given definition of a view
+// references the view, including the references in the views it uses.
+func checkViewRecursion(a *Analyzer, db, name string, definition sql.Node) error {
+	for _, t := range unresolvedTables(definition) {
+		// Tables without a database reference common table expressions.
+		if t.Database == "" {
+			continue
+		}
+
+		if strings.EqualFold(t.Database, db) && strings.EqualFold(t.Name(), name) {
+			return ErrViewRecursion.New(db, name)
+		}
+
+		view, err := a.Catalog.View(t.Database, t.Name())
+		if err != nil {
+			continue
+		}
+
+		if err := checkViewRecursion(a, db, name, view.Definition); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// unresolvedTables returns all the unresolved tables of the node, including
+// the ones inside subqueries and common table expressions.
+func unresolvedTables(n sql.Node) []*plan.UnresolvedTable {
+	var tables []*plan.UnresolvedTable
+	plan.Inspect(n, func(n sql.Node) bool {
+		switch n := n.(type) {
+		case *plan.UnresolvedTable:
+			tables = append(tables, n)
+		case *plan.With:
+			for _, cte := range n.CTEs {
+				tables = append(tables, unresolvedTables(cte.Definition)...)
+			}
+		}
+
+		if exp, ok := n.(sql.Expressioner); ok {
+			for _, e := range exp.Expressions() {
+				expression.Inspect(e, func(e sql.Expression) bool {
+					if s, ok := e.(*expression.Subquery); ok {
+						tables = append(tables, unresolvedTables(s.Query)...)
+					}
+					return true
+				})
+			}
+		}
+
+		return true
+	})
+	return tables
+}
+
+// qualifyTables sets the given database in all the tables of the node that
+// do not have one, including the ones inside subqueries, except for the
+// tables referencing the common table expressions in scope.
+func qualifyTables(n sql.Node, db string, ctes map[string]bool) (sql.Node, error) {
+	switch n := n.(type) {
+	case *plan.UnresolvedTable:
+		if n.Database != "" || ctes[strings.ToLower(n.Name())] {
+			return n, nil
+		}
+		return plan.NewUnresolvedTable(n.Name(), db), nil
+	case *plan.SubqueryAlias:
+		child, err := qualifyTables(n.Child, db, ctes)
+		if err != nil {
+			return nil, err
+		}
+
+		return plan.NewSubqueryAlias(n.Name(), child).WithColumns(n.Columns), nil
+	case *plan.With:
+		var scope = make(map[string]bool, len(ctes)+len(n.CTEs))
+		for name := range ctes {
+			scope[name] = true
+		}
+
+		// Each expression can reference the previous ones, and itself if
+		// the clause is recursive.
+		var defs = make([]*plan.CommonTableExpression, len(n.CTEs))
+		for i, cte := range n.CTEs {
+			name := strings.ToLower(cte.Name)
+			if n.Recursive {
+				scope[name] = true
+			}
+
+			definition, err := qualifyTables(cte.Definition, db, scope)
+			if err != nil {
+				return nil, err
+			}
+
+			defs[i] = plan.NewCommonTableExpression(cte.Name, cte.Columns, definition)
+			scope[name] = true
+		}
+
+		child, err := qualifyTables(n.Child, db, scope)
+		if err != nil {
+			return nil, err
+		}
+
+		return plan.NewWith(child, defs, n.Recursive), nil
+	}
+
+	children := n.Children()
+	if len(children) > 0 {
+		var newChildren = make([]sql.Node, len(children))
+		for i, c := range children {
+			var err error
+			newChildren[i], err = qualifyTables(c, db, ctes)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		var err error
+		n, err = n.WithChildren(newChildren...)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return plan.TransformExpressions(n, func(e sql.Expression) (sql.Expression, error) {
+		s, ok := e.(*expression.Subquery)
+		if !ok {
+			return e, nil
+		}
+
+		q, err := qualifyTables(s.Query, db, ctes)
+		if err != nil {
+			return nil, err
+		}
+
+		return s.WithQuery(q), nil
+	})
+}
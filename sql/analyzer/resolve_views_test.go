@@ -0,0 +1,83 @@
+package analyzer
+
+import (
+	"testing"
+
+	"github.com/src-d/go-mysql-server/memory"
+	"github.com/src-d/go-mysql-server/sql"
+	"github.com/src-d/go-mysql-server/sql/expression"
+	"github.com/src-d/go-mysql-server/sql/plan"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResolveCreateView(t *testing.T) {
+	require := require.New(t)
+
+	catalog := sql.NewCatalog()
+	catalog.AddDatabase(memory.NewDatabase("mydb"))
+	a := NewDefault(catalog)
+
+	definition := plan.NewWith(
+		plan.NewCrossJoin(
+			plan.NewUnresolvedTable("cte", ""),
+			plan.NewFilter(
+				expression.NewIn(
+					expression.NewUnresolvedColumn("a"),
+					expression.NewSubquery(plan.NewUnresolvedTable("bar", "")),
+				),
+				plan.NewUnresolvedTable("baz", "otherdb"),
+			),
+		),
+		[]*plan.CommonTableExpression{
+			plan.NewCommonTableExpression("cte", nil, plan.NewUnresolvedTable("foo", "")),
+		},
+		false,
+	)
+
+	expected := plan.NewWith(
+		plan.NewCrossJoin(
+			plan.NewUnresolvedTable("cte", ""),
+			plan.NewFilter(
+				expression.NewIn(
+					expression.NewUnresolvedColumn("a"),
+					expression.NewSubquery(plan.NewUnresolvedTable("bar", "mydb")),
+				),
+				plan.NewUnresolvedTable("baz", "otherdb"),
+			),
+		),
+		[]*plan.CommonTableExpression{
+			plan.NewCommonTableExpression("cte", nil, plan.NewUnresolvedTable("foo", "mydb")),
+		},
+		false,
+	)
+
+	node := plan.NewCreateView(sql.UnresolvedDatabase(""), "v", nil, definition, "", false)
+	result, err := resolveCreateView(sql.NewEmptyContext(), a, node)
+	require.NoError(err)
+	require.Equal(expected, result.(*plan.CreateView).Definition)
+}
+
+func TestResolveCreateViewRecursion(t *testing.T) {
+	require := require.New(t)
+
+	catalog := sql.NewCatalog()
+	catalog.AddDatabase(memory.NewDatabase("mydb"))
+	require.NoError(catalog.RegisterView(
+		"mydb",
+		sql.NewView("v1", nil, plan.NewUnresolvedTable("v2", "mydb"), ""),
+	))
+	a := NewDefault(catalog)
+
+	node := plan.NewCreateView(
+		sql.UnresolvedDatabase("mydb"),
+		"v2",
+		nil,
+		plan.NewUnresolvedTable("v1", ""),
+		"",
+		true,
+	)
+
+	_, err := resolveCreateView(sql.NewEmptyContext(), a, node)
+	require.Error(err)
+	require.True(ErrViewRecursion.Is(err))
+}
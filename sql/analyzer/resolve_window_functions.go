@@ -0,0 +1,37 @@
+package analyzer
+
+import (
+	"github.com/src-d/go-mysql-server/sql"
+	"github.com/src-d/go-mysql-server/sql/expression"
+	"github.com/src-d/go-mysql-server/sql/plan"
+)
+
+// resolveWindows replaces the projections that contain window expressions
+// with Window nodes, which are able to compute them.
+func resolveWindows(ctx *sql.Context, a *Analyzer, n sql.Node) (sql.Node, error) {
+	span, _ := ctx.Span("resolve_windows")
+	defer span.Finish()
+
+	return plan.TransformUp(n, func(n sql.Node) (sql.Node, error) {
+		p, ok := n.(*plan.Project)
+		if !ok || !p.Resolved() || !hasWindowExpressions(p.Projections) {
+			return n, nil
+		}
+
+		a.Log("replacing projection with window expressions with a window node")
+		return plan.NewWindow(p.Projections, p.Child), nil
+	})
+}
+
+func hasWindowExpressions(exprs []sql.Expression) bool {
+	var found bool
+	for _, e := range exprs {
+		expression.Inspect(e, func(e sql.Expression) bool {
+			if _, ok := e.(*plan.WindowExpression); ok {
+				found = true
+			}
+			return !found
+		})
+	}
+	return found
+}
@@ -0,0 +1,45 @@
+package analyzer
+
+import (
+	"testing"
+
+	"github.com/src-d/go-mysql-server/sql"
+	"github.com/src-d/go-mysql-server/sql/expression"
+	"github.com/src-d/go-mysql-server/sql/expression/function/aggregation"
+	"github.com/src-d/go-mysql-server/sql/plan"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResolveWindows(t *testing.T) {
+	require := require.New(t)
+
+	a := expression.NewGetField(0, sql.Int64, "a", false)
+	window := expression.NewAlias(
+		plan.NewWindowExpression(
+			aggregation.RowNumber{},
+			nil,
+			[]plan.SortField{{Column: a, Order: plan.Ascending}},
+			nil,
+		),
+		"rn",
+	)
+
+	child := plan.NewResolvedTable(dualTable)
+	var node sql.Node = plan.NewSort(
+		[]plan.SortField{{Column: a, Order: plan.Ascending}},
+		plan.NewProject([]sql.Expression{a, window}, child),
+	)
+
+	result, err := resolveWindows(sql.NewEmptyContext(), NewDefault(nil), node)
+	require.NoError(err)
+	require.Equal(plan.NewSort(
+		[]plan.SortField{{Column: a, Order: plan.Ascending}},
+		plan.NewWindow([]sql.Expression{a, window}, child),
+	), result)
+
+	// Projections without window expressions are left untouched.
+	node = plan.NewProject([]sql.Expression{a}, child)
+	result, err = resolveWindows(sql.NewEmptyContext(), NewDefault(nil), node)
+	require.NoError(err)
+	require.Equal(node, result)
+}
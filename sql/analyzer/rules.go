@@ -6,15 +6,18 @@ import (
 
 // DefaultRules to apply when analyzing nodes.
 var DefaultRules = []Rule{
+	{"resolve_pivots", resolvePivots},
 	{"resolve_natural_joins", resolveNaturalJoins},
 	{"resolve_orderby_literals", resolveOrderByLiterals},
 	{"resolve_orderby", resolveOrderBy},
 	{"resolve_grouping_columns", resolveGroupingColumns},
 	{"qualify_columns", qualifyColumns},
 	{"resolve_columns", resolveColumns},
+	{"resolve_subquery_exprs", resolveSubqueryExprs},
 	{"resolve_database", resolveDatabase},
 	{"resolve_star", resolveStar},
 	{"resolve_functions", resolveFunctions},
+	{"resolve_table_functions", resolveTableFunctions},
 	{"resolve_having", resolveHaving},
 	{"reorder_aggregations", reorderAggregations},
 	{"reorder_projection", reorderProjection},
@@ -26,6 +29,8 @@ var DefaultRules = []Rule{
 // OnceBeforeDefault contains the rules to be applied just once before the
 // DefaultRules.
 var OnceBeforeDefault = []Rule{
+	{"resolve_ctes", resolveCommonTableExpressions},
+	{"resolve_create_view", resolveCreateView},
 	{"resolve_subqueries", resolveSubqueries},
 	{"resolve_tables", resolveTables},
 	{"check_aliases", checkAliases},
@@ -34,12 +39,17 @@ var OnceBeforeDefault = []Rule{
 // OnceAfterDefault contains the rules to be applied just once after the
 // DefaultRules.
 var OnceAfterDefault = []Rule{
+	{"resolve_windows", resolveWindows},
 	{"resolve_generators", resolveGenerators},
 	{"remove_unnecessary_converts", removeUnnecessaryConverts},
 	{"assign_catalog", assignCatalog},
+	{"use_semi_joins", useSemiJoins},
 	{"prune_columns", pruneColumns},
 	{"convert_dates", convertDates},
 	{"pushdown", pushdown},
+	{"reorder_joins", reorderJoins},
+	{"use_hash_joins", useHashJoins},
+	{"use_top_n", useTopN},
 	{"erase_projection", eraseProjection},
 }
 
@@ -0,0 +1,368 @@
+package analyzer
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/src-d/go-mysql-server/sql"
+	"github.com/src-d/go-mysql-server/sql/expression"
+	"github.com/src-d/go-mysql-server/sql/plan"
+)
+
+// useSemiJoins rewrites the IN, NOT IN, EXISTS and NOT EXISTS predicates with
+// subqueries of filters into semi and anti joins with the subqueries, so they
+// are not executed again for each row and can be computed with hash joins.
+// Correlated subqueries are decorrelated moving the conditions that use the
+// outer query to the condition of the join, which is only possible when they
+// are in a filter right under the projection of the subquery.
+// Uncorrelated EXISTS and correlated NOT IN are kept as they are.
+func useSemiJoins(ctx *sql.Context, a *Analyzer, n sql.Node) (sql.Node, error) {
+	span, _ := ctx.Span("use_semi_joins")
+	defer span.Finish()
+
+	if !n.Resolved() {
+		return n, nil
+	}
+
+	a.Log("use semi joins, node of type: %T", n)
+
+	names := newSubqueryNames(n)
+	return plan.TransformUp(n, func(n sql.Node) (sql.Node, error) {
+		filter, ok := n.(*plan.Filter)
+		if !ok {
+			return n, nil
+		}
+
+		var joins []func(sql.Node) sql.Node
+		var remaining []sql.Expression
+		for _, e := range splitExpression(filter.Expression) {
+			join, err := semiJoinFor(e, filter.Child.Schema(), names)
+			if err != nil {
+				return nil, err
+			}
+
+			if join == nil {
+				remaining = append(remaining, e)
+				continue
+			}
+
+			a.Log("rewriting %s into a join", e)
+			joins = append(joins, join)
+		}
+
+		if len(joins) == 0 {
+			return n, nil
+		}
+
+		// Semi and anti joins return the rows of the left side, so the rest
+		// of the conditions can be evaluated before them.
+		var node = filter.Child
+		if len(remaining) > 0 {
+			node = plan.NewFilter(expression.JoinAnd(remaining...), node)
+		}
+
+		for _, join := range joins {
+			node = join(node)
+		}
+
+		return node, nil
+	})
+}
+
+// semiJoinFor returns a function that joins the given node with the subquery
+// of the given predicate, or nil if the predicate can't be rewritten.
+func semiJoinFor(
+	e sql.Expression,
+	schema sql.Schema,
+	names *subqueryNames,
+) (func(sql.Node) sql.Node, error) {
+	var s *expression.Subquery
+	var left sql.Expression
+	var anti, exists bool
+	switch e := e.(type) {
+	case *expression.In:
+		s, _ = e.Right().(*expression.Subquery)
+		left = e.Left()
+	case *expression.NotIn:
+		s, _ = e.Right().(*expression.Subquery)
+		left, anti = e.Left(), true
+	case *expression.Exists:
+		s, exists = e.Subquery(), true
+	case *expression.Not:
+		if ex, ok := e.Child.(*expression.Exists); ok {
+			s, anti, exists = ex.Subquery(), true, true
+		}
+	}
+
+	if s == nil {
+		return nil, nil
+	}
+
+	if !exists && (sql.NumColumns(left.Type()) != 1 || len(s.Query.Schema()) != 1) {
+		return nil, nil
+	}
+
+	if !s.Correlated() {
+		// Uncorrelated EXISTS only needs to be checked once.
+		if exists {
+			return nil, nil
+		}
+
+		name := names.next()
+		col := s.Query.Schema()[0]
+		right := plan.NewSubqueryAlias(name, s.Query)
+		cond := expression.NewEquals(
+			left,
+			expression.NewGetFieldWithTable(len(schema), col.Type, name, col.Name, col.Nullable),
+		)
+
+		return func(n sql.Node) sql.Node {
+			if anti {
+				return plan.NewAntiJoin(n, right, cond, true)
+			}
+			return plan.NewSemiJoin(n, right, cond)
+		}, nil
+	}
+
+	// The result of NOT IN depends on the null values returned by the
+	// subquery for each row, which are only known by running it.
+	if anti && !exists {
+		return nil, nil
+	}
+
+	right, cond, err := decorrelate(s, left, schema, names)
+	if err != nil || right == nil {
+		return nil, err
+	}
+
+	return func(n sql.Node) sql.Node {
+		if anti {
+			return plan.NewAntiJoin(n, right, cond, false)
+		}
+		return plan.NewSemiJoin(n, right, cond)
+	}, nil
+}
+
+// decorrelate returns the node with the rows of the correlated subquery that
+// the outer rows are joined with, along with the condition of the join. The
+// conditions of the subquery that use the outer row become part of the join
+// condition, as well as the equality with the left value of an IN, if any.
+func decorrelate(
+	s *expression.Subquery,
+	left sql.Expression,
+	schema sql.Schema,
+	names *subqueryNames,
+) (sql.Node, sql.Expression, error) {
+	var parallelism int
+	var project *plan.Project
+	node := s.Query
+Loop:
+	for {
+		switch n := node.(type) {
+		case *plan.Exchange:
+			parallelism = n.Parallelism
+			node = n.Child
+		case *plan.Distinct:
+			node = n.Child
+		case *plan.OrderedDistinct:
+			node = n.Child
+		case *plan.Sort:
+			node = n.Child
+		case *plan.Project:
+			if project != nil {
+				return nil, nil, nil
+			}
+			project = n
+			node = n.Child
+		default:
+			break Loop
+		}
+	}
+
+	filter, ok := node.(*plan.Filter)
+	if !ok || containsOuterFields(filter.Child) {
+		return nil, nil, nil
+	}
+
+	// The outer expressions may not have the indexes of the schema of the
+	// node with the subquery yet.
+	outer, err := fixFieldIndexesOnExpressions(schema, s.Outer...)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var local, correlated []sql.Expression
+	for _, e := range splitExpression(filter.Expression) {
+		if containsOuterField(e) {
+			correlated = append(correlated, e)
+		} else {
+			local = append(local, e)
+		}
+	}
+
+	var value sql.Expression
+	if left != nil {
+		if project != nil {
+			value = project.Projections[0]
+			if alias, ok := value.(*expression.Alias); ok {
+				value = alias.Child
+			}
+		} else {
+			col := filter.Schema()[0]
+			value = expression.NewGetFieldWithTable(0, col.Type, col.Source, col.Name, col.Nullable)
+		}
+
+		if containsOuterField(value) {
+			return nil, nil, nil
+		}
+	}
+
+	name := names.next()
+	columns := &neededColumns{table: name, offset: len(schema)}
+	replace := func(e sql.Expression) (sql.Expression, error) {
+		switch e := e.(type) {
+		case *expression.OuterField:
+			return outer[e.Index()], nil
+		case *expression.GetField:
+			return columns.add(e), nil
+		default:
+			return e, nil
+		}
+	}
+
+	var conds []sql.Expression
+	for _, e := range correlated {
+		e, err := expression.TransformUp(e, replace)
+		if err != nil {
+			return nil, nil, err
+		}
+		conds = append(conds, e)
+	}
+
+	// The left side of the IN already uses the fields of the outer query,
+	// so only the value of the subquery is transformed.
+	if value != nil {
+		value, err = expression.TransformUp(value, replace)
+		if err != nil {
+			return nil, nil, err
+		}
+		conds = append([]sql.Expression{expression.NewEquals(left, value)}, conds...)
+	}
+
+	if len(columns.exprs) == 0 {
+		return nil, nil, nil
+	}
+
+	var right sql.Node = filter.Child
+	if len(local) > 0 {
+		right = plan.NewFilter(expression.JoinAnd(local...), right)
+	}
+
+	right = plan.NewProject(columns.exprs, right)
+	if parallelism > 1 {
+		right = plan.NewExchange(parallelism, right)
+	}
+
+	return plan.NewSubqueryAlias(name, right), expression.JoinAnd(conds...), nil
+}
+
+// neededColumns are the columns of a subquery used in the condition of a
+// join after decorrelating it, which the subquery needs to return.
+type neededColumns struct {
+	table   string
+	offset  int
+	exprs   []sql.Expression
+	indexes map[tableCol]int
+	names   map[string]bool
+}
+
+// add returns the field that references the given column of the subquery in
+// the rows of the join.
+func (c *neededColumns) add(gf *expression.GetField) sql.Expression {
+	if c.indexes == nil {
+		c.indexes = make(map[tableCol]int)
+		c.names = make(map[string]bool)
+	}
+
+	key := tableCol{gf.Table(), gf.Name()}
+	idx, ok := c.indexes[key]
+	if !ok {
+		idx = len(c.exprs)
+		c.indexes[key] = idx
+
+		// Columns of different tables may have the same name, but the
+		// ones of the subquery need to be unique.
+		var e sql.Expression = gf
+		name := gf.Name()
+		for i := 1; c.names[strings.ToLower(name)]; i++ {
+			name = fmt.Sprintf("%s_%02d", gf.Name(), i)
+		}
+
+		if name != gf.Name() {
+			e = expression.NewAlias(gf, name)
+		}
+
+		c.names[strings.ToLower(name)] = true
+		c.exprs = append(c.exprs, e)
+	}
+
+	name := gf.Name()
+	if alias, ok := c.exprs[idx].(*expression.Alias); ok {
+		name = alias.Name()
+	}
+
+	return expression.NewGetFieldWithTable(c.offset+idx, gf.Type(), c.table, name, gf.IsNullable())
+}
+
+func containsOuterField(e sql.Expression) bool {
+	var result bool
+	expression.Inspect(e, func(e sql.Expression) bool {
+		if _, ok := e.(*expression.OuterField); ok {
+			result = true
+			return false
+		}
+		return true
+	})
+	return result
+}
+
+func containsOuterFields(n sql.Node) bool {
+	var result bool
+	plan.InspectExpressions(n, func(e sql.Expression) bool {
+		if _, ok := e.(*expression.OuterField); ok {
+			result = true
+			return false
+		}
+		return true
+	})
+	return result
+}
+
+// subqueryNames generates names for the subqueries turned into joins that
+// are not used by any other table of the query.
+type subqueryNames struct {
+	used map[string]bool
+	n    int
+}
+
+func newSubqueryNames(n sql.Node) *subqueryNames {
+	used := make(map[string]bool)
+	plan.Inspect(n, func(n sql.Node) bool {
+		if t, ok := n.(sql.Nameable); ok {
+			used[strings.ToLower(t.Name())] = true
+		}
+		return true
+	})
+	return &subqueryNames{used: used}
+}
+
+func (n *subqueryNames) next() string {
+	for {
+		n.n++
+		name := fmt.Sprintf("subquery%d", n.n)
+		if !n.used[name] {
+			n.used[name] = true
+			return name
+		}
+	}
+}
@@ -0,0 +1,132 @@
+package analyzer
+
+import (
+	"testing"
+
+	"github.com/src-d/go-mysql-server/memory"
+	"github.com/src-d/go-mysql-server/sql"
+	"github.com/src-d/go-mysql-server/sql/expression"
+	"github.com/src-d/go-mysql-server/sql/plan"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUseSemiJoins(t *testing.T) {
+	rule := getRule("use_semi_joins")
+
+	t1 := plan.NewResolvedTable(memory.NewTable("t1", sql.Schema{
+		{Name: "a", Source: "t1", Type: sql.Int64},
+		{Name: "b", Source: "t1", Type: sql.Int64},
+	}))
+	t2 := plan.NewResolvedTable(memory.NewTable("t2", sql.Schema{
+		{Name: "c", Source: "t2", Type: sql.Int64},
+		{Name: "d", Source: "t2", Type: sql.Int64},
+	}))
+
+	uncorrelated := expression.NewSubquery(plan.NewProject([]sql.Expression{col(0, "t2", "c")}, t2))
+	correlated := expression.NewSubquery(plan.NewProject(
+		[]sql.Expression{col(0, "t2", "c")},
+		plan.NewFilter(
+			and(
+				eq(col(1, "t2", "d"), expression.NewOuterField(0, sql.Int64, "t1", "b", false)),
+				eq(col(0, "t2", "c"), lit(1)),
+			),
+			t2,
+		),
+	)).WithOuter([]sql.Expression{col(1, "t1", "b")})
+
+	decorrelated := plan.NewSubqueryAlias(
+		"subquery1",
+		plan.NewProject(
+			[]sql.Expression{col(1, "t2", "d")},
+			plan.NewFilter(eq(col(0, "t2", "c"), lit(1)), t2),
+		),
+	)
+
+	testCases := []struct {
+		name     string
+		node     sql.Node
+		expected sql.Node
+	}{
+		{
+			"in",
+			plan.NewFilter(expression.NewIn(col(0, "t1", "a"), uncorrelated), t1),
+			plan.NewSemiJoin(
+				t1,
+				plan.NewSubqueryAlias("subquery1", uncorrelated.Query),
+				eq(col(0, "t1", "a"), col(2, "subquery1", "c")),
+			),
+		},
+		{
+			"not in",
+			plan.NewFilter(
+				and(
+					expression.NewNotIn(col(0, "t1", "a"), uncorrelated),
+					eq(col(1, "t1", "b"), lit(2)),
+				),
+				t1,
+			),
+			plan.NewAntiJoin(
+				plan.NewFilter(eq(col(1, "t1", "b"), lit(2)), t1),
+				plan.NewSubqueryAlias("subquery1", uncorrelated.Query),
+				eq(col(0, "t1", "a"), col(2, "subquery1", "c")),
+				true,
+			),
+		},
+		{
+			"correlated exists",
+			plan.NewFilter(expression.NewExists(correlated), t1),
+			plan.NewSemiJoin(
+				t1,
+				decorrelated,
+				eq(col(2, "subquery1", "d"), col(1, "t1", "b")),
+			),
+		},
+		{
+			"correlated not exists",
+			plan.NewFilter(expression.NewNot(expression.NewExists(correlated)), t1),
+			plan.NewAntiJoin(
+				t1,
+				decorrelated,
+				eq(col(2, "subquery1", "d"), col(1, "t1", "b")),
+				false,
+			),
+		},
+		{
+			"correlated in",
+			plan.NewFilter(expression.NewIn(col(0, "t1", "a"), correlated), t1),
+			plan.NewSemiJoin(
+				t1,
+				plan.NewSubqueryAlias(
+					"subquery1",
+					plan.NewProject(
+						[]sql.Expression{col(1, "t2", "d"), col(0, "t2", "c")},
+						plan.NewFilter(eq(col(0, "t2", "c"), lit(1)), t2),
+					),
+				),
+				and(
+					eq(col(0, "t1", "a"), col(3, "subquery1", "c")),
+					eq(col(2, "subquery1", "d"), col(1, "t1", "b")),
+				),
+			),
+		},
+		{
+			"correlated not in",
+			plan.NewFilter(expression.NewNotIn(col(0, "t1", "a"), correlated), t1),
+			plan.NewFilter(expression.NewNotIn(col(0, "t1", "a"), correlated), t1),
+		},
+		{
+			"uncorrelated exists",
+			plan.NewFilter(expression.NewExists(uncorrelated), t1),
+			plan.NewFilter(expression.NewExists(uncorrelated), t1),
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			require := require.New(t)
+			result, err := rule.Apply(sql.NewEmptyContext(), NewDefault(nil), tt.node)
+			require.NoError(err)
+			require.Equal(tt.expected, result)
+		})
+	}
+}
@@ -21,6 +21,8 @@ const (
 	validateIntervalUsageRule   = "validate_interval_usage"
 	validateExplodeUsageRule    = "validate_explode_usage"
 	validateSubqueryColumnsRule = "validate_subquery_columns"
+	validateUnionSchemasRule    = "validate_union_schemas"
+	validateWindowUsageRule     = "validate_window_usage"
 )
 
 var (
@@ -64,6 +66,30 @@ var (
 	ErrSubqueryColumns = errors.NewKind(
 		"subquery expressions can only return a single column",
 	)
+
+	// ErrUnionColumnCount is returned when the branches of an union return
+	// a different number of columns.
+	ErrUnionColumnCount = errors.NewKind(
+		"the used SELECT statements have a different number of columns: %d and %d",
+	)
+
+	// ErrUnionColumnType is returned when a column of the branches of an
+	// union has incompatible types.
+	ErrUnionColumnType = errors.NewKind(
+		"column %d of UNION has incompatible types %s and %s",
+	)
+
+	// ErrWindowInvalidUse is returned when a window expression is used
+	// outside the selected expressions of a query.
+	ErrWindowInvalidUse = errors.NewKind(
+		"window functions can only be used in the selected expressions of a query",
+	)
+
+	// ErrWindowFunctionWithoutOver is returned when a window function is
+	// used without an OVER clause.
+	ErrWindowFunctionWithoutOver = errors.NewKind(
+		"window function %s requires an OVER clause",
+	)
 )
 
 // DefaultValidationRules to apply while analyzing nodes.
@@ -78,6 +104,8 @@ var DefaultValidationRules = []Rule{
 	{validateIntervalUsageRule, validateIntervalUsage},
 	{validateExplodeUsageRule, validateExplodeUsage},
 	{validateSubqueryColumnsRule, validateSubqueryColumns},
+	{validateUnionSchemasRule, validateUnionSchemas},
+	{validateWindowUsageRule, validateWindowUsage},
 }
 
 func validateIsResolved(ctx *sql.Context, a *Analyzer, n sql.Node) (sql.Node, error) {
@@ -333,6 +361,11 @@ func validateExplodeUsage(ctx *sql.Context, a *Analyzer, n sql.Node) (sql.Node,
 func validateSubqueryColumns(ctx *sql.Context, a *Analyzer, n sql.Node) (sql.Node, error) {
 	valid := true
 	plan.InspectExpressions(n, func(e sql.Expression) bool {
+		// Subqueries of EXISTS can return any number of columns.
+		if _, ok := e.(*expression.Exists); ok {
+			return false
+		}
+
 		s, ok := e.(*expression.Subquery)
 		if ok && len(s.Query.Schema()) != 1 {
 			valid = false
@@ -349,6 +382,57 @@ func validateSubqueryColumns(ctx *sql.Context, a *Analyzer, n sql.Node) (sql.Nod
 	return n, nil
 }
 
+func validateUnionSchemas(ctx *sql.Context, a *Analyzer, n sql.Node) (sql.Node, error) {
+	span, _ := ctx.Span("validate_union_schemas")
+	defer span.Finish()
+
+	var err error
+	plan.Inspect(n, func(node sql.Node) bool {
+		u, ok := node.(*plan.Union)
+		if !ok {
+			return true
+		}
+
+		left, right := u.Left.Schema(), u.Right.Schema()
+		if len(left) != len(right) {
+			err = ErrUnionColumnCount.New(len(left), len(right))
+			return false
+		}
+
+		for i := range left {
+			if !unionCompatibleTypes(left[i].Type, right[i].Type) {
+				err = ErrUnionColumnType.New(i+1, left[i].Type, right[i].Type)
+				return false
+			}
+		}
+
+		return true
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return n, nil
+}
+
+// unionCompatibleTypes reports whether values of both types can be returned
+// in the same column of an union.
+func unionCompatibleTypes(a, b sql.Type) bool {
+	switch {
+	case a == b, a == sql.Null, b == sql.Null:
+		return true
+	case sql.IsNumber(a) && sql.IsNumber(b):
+		return true
+	case sql.IsText(a) && sql.IsText(b):
+		return true
+	case sql.IsTime(a) && sql.IsTime(b):
+		return true
+	default:
+		return false
+	}
+}
+
 func stringContains(strs []string, target string) bool {
 	for _, s := range strs {
 		if s == target {
@@ -357,3 +441,80 @@ func stringContains(strs []string, target string) bool {
 	}
 	return false
 }
+
+func validateWindowUsage(ctx *sql.Context, a *Analyzer, n sql.Node) (sql.Node, error) {
+	span, _ := ctx.Span(validateWindowUsageRule)
+	defer span.Finish()
+
+	var err error
+	plan.Inspect(n, func(node sql.Node) bool {
+		if err != nil {
+			return false
+		}
+
+		var exprs []sql.Expression
+		switch node := node.(type) {
+		case sql.Expressioner:
+			exprs = node.Expressions()
+		case *plan.ResolvedTable:
+			// Filters may have already been pushed down to the table.
+			if t, ok := node.Table.(sql.FilteredTable); ok {
+				exprs = t.Filters()
+			}
+		}
+
+		// All the valid window expressions are in Window nodes, because the
+		// projections containing them have already been replaced.
+		_, inWindow := node.(*plan.Window)
+		for _, e := range exprs {
+			if err = checkWindowExpressions(e, inWindow); err != nil {
+				return false
+			}
+		}
+
+		return true
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return n, nil
+}
+
+func checkWindowExpressions(e sql.Expression, allowed bool) error {
+	switch e := e.(type) {
+	case *plan.WindowExpression:
+		if !allowed {
+			return ErrWindowInvalidUse.New()
+		}
+
+		switch e.Function.(type) {
+		case sql.WindowFunction, sql.Aggregation:
+		default:
+			return plan.ErrNotWindowFunction.New(e.Function)
+		}
+
+		// Windows cannot be nested, and the function of this window is
+		// already known to be valid.
+		var children = append([]sql.Expression{}, e.Function.Children()...)
+		children = append(children, e.Children()[1:]...)
+		for _, c := range children {
+			if err := checkWindowExpressions(c, false); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	case sql.WindowFunction:
+		return ErrWindowFunctionWithoutOver.New(e)
+	}
+
+	for _, c := range e.Children() {
+		if err := checkWindowExpressions(c, allowed); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -11,6 +11,7 @@ import (
 	"github.com/src-d/go-mysql-server/sql/plan"
 
 	"github.com/stretchr/testify/require"
+	"gopkg.in/src-d/go-errors.v1"
 )
 
 func TestValidateResolved(t *testing.T) {
@@ -674,6 +675,94 @@ func TestValidateExplodeUsage(t *testing.T) {
 	}
 }
 
+func TestValidateWindowUsage(t *testing.T) {
+	a := expression.NewGetField(0, sql.Int64, "a", false)
+	orderByA := []plan.SortField{{Column: a, Order: plan.Ascending}}
+
+	testCases := []struct {
+		name string
+		node sql.Node
+		err  *errors.Kind
+	}{
+		{
+			"valid",
+			plan.NewWindow(
+				[]sql.Expression{
+					a,
+					expression.NewAlias(
+						plan.NewWindowExpression(aggregation.RowNumber{}, nil, orderByA, nil),
+						"rn",
+					),
+					plan.NewWindowExpression(aggregation.NewSum(a), nil, nil, nil),
+				},
+				plan.NewUnresolvedTable("dual", ""),
+			),
+			nil,
+		},
+		{
+			"where",
+			plan.NewFilter(
+				expression.NewGreaterThan(
+					plan.NewWindowExpression(aggregation.RowNumber{}, nil, orderByA, nil),
+					expression.NewLiteral(int64(1), sql.Int64),
+				),
+				plan.NewUnresolvedTable("dual", ""),
+			),
+			ErrWindowInvalidUse,
+		},
+		{
+			"nested",
+			plan.NewWindow(
+				[]sql.Expression{
+					plan.NewWindowExpression(
+						aggregation.NewSum(
+							plan.NewWindowExpression(aggregation.RowNumber{}, nil, orderByA, nil),
+						),
+						nil, nil, nil,
+					),
+				},
+				plan.NewUnresolvedTable("dual", ""),
+			),
+			ErrWindowInvalidUse,
+		},
+		{
+			"not a window function",
+			plan.NewWindow(
+				[]sql.Expression{
+					plan.NewWindowExpression(
+						expression.NewArithmetic(a, a, "+"),
+						nil, nil, nil,
+					),
+				},
+				plan.NewUnresolvedTable("dual", ""),
+			),
+			plan.ErrNotWindowFunction,
+		},
+		{
+			"window function without over",
+			plan.NewProject(
+				[]sql.Expression{aggregation.RowNumber{}},
+				plan.NewUnresolvedTable("dual", ""),
+			),
+			ErrWindowFunctionWithoutOver,
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			require := require.New(t)
+
+			_, err := validateWindowUsage(sql.NewEmptyContext(), nil, tt.node)
+			if tt.err == nil {
+				require.NoError(err)
+			} else {
+				require.Error(err)
+				require.True(tt.err.Is(err), "unexpected error: %s", err)
+			}
+		})
+	}
+}
+
 func TestValidateSubqueryColumns(t *testing.T) {
 	require := require.New(t)
 	ctx := sql.NewEmptyContext()
@@ -705,6 +794,45 @@ func TestValidateSubqueryColumns(t *testing.T) {
 	require.NoError(err)
 }
 
+func TestValidateUnionSchemas(t *testing.T) {
+	require := require.New(t)
+	ctx := sql.NewEmptyContext()
+
+	node := plan.NewUnion(
+		plan.NewProject([]sql.Expression{lit(1), lit(2)}, dummyNode{true}),
+		plan.NewProject([]sql.Expression{lit(1)}, dummyNode{true}),
+	)
+
+	_, err := validateUnionSchemas(ctx, nil, node)
+	require.Error(err)
+	require.True(ErrUnionColumnCount.Is(err))
+
+	node = plan.NewDistinctUnion(
+		plan.NewProject([]sql.Expression{lit(1)}, dummyNode{true}),
+		plan.NewProject([]sql.Expression{
+			expression.NewLiteral("foo", sql.Text),
+		}, dummyNode{true}),
+	)
+
+	_, err = validateUnionSchemas(ctx, nil, plan.NewLimit(1, node))
+	require.Error(err)
+	require.True(ErrUnionColumnType.Is(err))
+
+	node = plan.NewUnion(
+		plan.NewProject([]sql.Expression{
+			lit(1),
+			expression.NewLiteral("foo", sql.Text),
+		}, dummyNode{true}),
+		plan.NewProject([]sql.Expression{
+			expression.NewLiteral(int64(2), sql.Int64),
+			expression.NewLiteral(nil, sql.Null),
+		}, dummyNode{true}),
+	)
+
+	_, err = validateUnionSchemas(ctx, nil, node)
+	require.NoError(err)
+}
+
 type dummyNode struct{ resolved bool }
 
 func (n dummyNode) String() string                           { return "dummynode" }
@@ -17,9 +17,14 @@ var ErrDatabaseNotFound = errors.NewKind("database not found: %s")
 type Catalog struct {
 	FunctionRegistry
 	*IndexRegistry
+	*ViewRegistry
+	*StoredFunctionRegistry
 	*ProcessList
 	*MemoryManager
 
+	// fmu guards the functions of the FunctionRegistry, which can be
+	// registered and unregistered while queries are analyzed.
+	fmu             sync.RWMutex
 	mu              sync.RWMutex
 	currentDatabase string
 	dbs             Databases
@@ -35,11 +40,13 @@ type (
 // NewCatalog returns a new empty Catalog.
 func NewCatalog() *Catalog {
 	return &Catalog{
-		FunctionRegistry: NewFunctionRegistry(),
-		IndexRegistry:    NewIndexRegistry(),
-		MemoryManager:    NewMemoryManager(ProcessMemory),
-		ProcessList:      NewProcessList(),
-		locks:            make(sessionLocks),
+		FunctionRegistry:       NewFunctionRegistry(),
+		IndexRegistry:          NewIndexRegistry(),
+		ViewRegistry:           NewViewRegistry(),
+		StoredFunctionRegistry: NewStoredFunctionRegistry(),
+		MemoryManager:          NewMemoryManager(ProcessMemory),
+		ProcessList:            NewProcessList(),
+		locks:                  make(sessionLocks),
 	}
 }
 
@@ -92,6 +99,38 @@ func (c *Catalog) Table(db, table string) (Table, error) {
 	return c.dbs.Table(db, table)
 }
 
+// Register registers the given functions in the function registry of the
+// catalog. It fails if there is already a function with the same name.
+func (c *Catalog) Register(fn ...Function) error {
+	c.fmu.Lock()
+	defer c.fmu.Unlock()
+	return c.FunctionRegistry.Register(fn...)
+}
+
+// MustRegister registers the given functions in the function registry of
+// the catalog and panics if any of them can't be registered.
+func (c *Catalog) MustRegister(fn ...Function) {
+	if err := c.Register(fn...); err != nil {
+		panic(err)
+	}
+}
+
+// Unregister removes the function with the given name from the function
+// registry of the catalog, if any.
+func (c *Catalog) Unregister(name string) {
+	c.fmu.Lock()
+	c.FunctionRegistry.Unregister(name)
+	c.fmu.Unlock()
+}
+
+// Function returns the function with the given name from the function
+// registry of the catalog.
+func (c *Catalog) Function(name string) (Function, error) {
+	c.fmu.RLock()
+	defer c.fmu.RUnlock()
+	return c.FunctionRegistry.Function(name)
+}
+
 // Databases is a collection of Database.
 type Databases []Database
 
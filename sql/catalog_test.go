@@ -1,6 +1,8 @@
 package sql_test
 
 import (
+	"fmt"
+	"sync"
 	"testing"
 
 	"github.com/src-d/go-mysql-server/memory"
@@ -130,3 +132,38 @@ func (l *lockableTable) Unlock(ctx *sql.Context, id uint32) error {
 	l.unlocks++
 	return nil
 }
+
+func TestCatalogFunctionsConcurrently(t *testing.T) {
+	require := require.New(t)
+
+	c := sql.NewCatalog()
+	c.MustRegister(sql.Function0{Name: "foo"})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(2)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				name := fmt.Sprintf("fn_%d_%d", i, j)
+				if err := c.Register(sql.Function0{Name: name}); err != nil {
+					panic(err)
+				}
+				c.Unregister(name)
+			}
+		}(i)
+
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				if _, err := c.Function("foo"); err != nil {
+					panic(err)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	_, err := c.Function("fn_0_0")
+	require.True(sql.ErrFunctionNotFound.Is(err))
+}
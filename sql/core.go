@@ -32,6 +32,22 @@ var (
 
 	// ErrDeleteRowNotFound
 	ErrDeleteRowNotFound = errors.NewKind("row was not found when attempting to delete").New()
+
+	// ErrTableColumnNotFound is returned when a column is not part of the
+	// schema of a table.
+	ErrTableColumnNotFound = errors.NewKind("table %s does not have column %s")
+
+	// ErrColumnAlreadyExists is returned when a column is added to a table
+	// that already has a column with the same name.
+	ErrColumnAlreadyExists = errors.NewKind("table %s already has column %s")
+
+	// ErrDropAllColumns is returned when the last column of a table is
+	// dropped.
+	ErrDropAllColumns = errors.NewKind("can't drop all columns of table %s, use DROP TABLE instead")
+
+	// ErrInvalidNullValue is returned when a column that can't be NULL would
+	// have NULL values after changing the schema of a table.
+	ErrInvalidNullValue = errors.NewKind("invalid use of NULL value in column %s")
 )
 
 // Nameable is something that has a name.
@@ -95,6 +111,44 @@ type Aggregation interface {
 	Merge(ctx *Context, buffer, partial Row) error
 }
 
+// TransposedAggregation is an aggregation whose result is a list that is
+// returned as one row for each of its elements, instead of a single row.
+type TransposedAggregation interface {
+	Aggregation
+	// Transposed returns whether the result of the aggregation is
+	// transposed.
+	Transposed() bool
+}
+
+// WindowFunction implements a function that is computed for every row of a
+// window partition, such as ROW_NUMBER or LAG. Like in an aggregation, a
+// buffer is created for each partition (NewBuffer) and the rows of the
+// partition are fed to the buffer in order (UpdateWindow).
+// Note that Eval must be called with the buffer in order to get the result
+// for the last row the buffer was updated with.
+type WindowFunction interface {
+	Expression
+	// NewBuffer creates a new buffer for a partition and returns it as a Row.
+	NewBuffer() Row
+	// UpdateWindow updates the given buffer with the row at the given index
+	// of the partition.
+	UpdateWindow(ctx *Context, buffer Row, partition *WindowPartition, idx int) error
+}
+
+// WindowPartition is a partition of the rows of a window, sorted by the
+// ORDER BY clause of the window.
+type WindowPartition struct {
+	// Rows of the partition.
+	Rows []Row
+	// Peers reports for each row whether it has the same values in the
+	// ORDER BY clause as the previous one.
+	Peers []bool
+	// FrameStart is the index of the first row of the frame of each row.
+	FrameStart []int
+	// FrameEnd is the index after the last row of the frame of each row.
+	FrameEnd []int
+}
+
 // Node is a node in the execution plan tree.
 type Node interface {
 	Resolvable
@@ -196,6 +250,80 @@ type ProjectedTable interface {
 	Projection() []string
 }
 
+// AggregatableTable is a table that can group its rows and compute
+// aggregations of the groups, so that it returns rows that are already
+// aggregated instead of all of them.
+type AggregatableTable interface {
+	Table
+	// HandledAggregations returns the aggregations the table can compute
+	// grouping its rows by the given expressions, which are none if it can't
+	// group them by those expressions.
+	HandledAggregations(grouping []Expression, aggregations []Aggregation) []Aggregation
+	// WithAggregation returns a table whose rows have the values of the
+	// grouping expressions followed by the elements of the buffers of the
+	// aggregations, with the schema returned by PartialAggregationSchema.
+	// The buffers are partial results of the aggregations, which are merged
+	// with the ones of the rows of the same group, so a group can be in more
+	// than one row, such as once in each partition.
+	WithAggregation(grouping []Expression, aggregations []Aggregation) Table
+	Grouping() []Expression
+	Aggregations() []Aggregation
+}
+
+// LimitedTable is a table that can be given the maximum number of rows that
+// are needed from each partition, so that it doesn't read more than that.
+// The limit is only a hint, so the table can still return more rows.
+type LimitedTable interface {
+	Table
+	WithLimit(limit int64) Table
+	// Limit returns the maximum number of rows that are needed from each
+	// partition, or zero if there is no limit.
+	Limit() int64
+}
+
+// PartialAggregationSchema returns the schema of rows with the values of the
+// given grouping expressions followed by the elements of the buffers of the
+// given aggregations, whose columns come from the given source.
+func PartialAggregationSchema(
+	source string,
+	grouping []Expression,
+	aggregations []Aggregation,
+) Schema {
+	var schema Schema
+	for _, e := range grouping {
+		name := e.String()
+		if n, ok := e.(Nameable); ok {
+			name = n.Name()
+		}
+
+		schema = append(schema, &Column{
+			Name:     name,
+			Type:     e.Type(),
+			Nullable: e.IsNullable(),
+			Source:   source,
+		})
+	}
+
+	for _, a := range aggregations {
+		size := len(a.NewBuffer())
+		for i := 0; i < size; i++ {
+			name := a.String()
+			if size > 1 {
+				name = fmt.Sprintf("%s[%d]", name, i)
+			}
+
+			schema = append(schema, &Column{
+				Name:     name,
+				Type:     a.Type(),
+				Nullable: true,
+				Source:   source,
+			})
+		}
+	}
+
+	return schema
+}
+
 // IndexableTable represents a table that supports being indexed and
 // receiving indexes to be able to speed up its execution.
 type IndexableTable interface {
@@ -241,11 +369,50 @@ type TableCreator interface {
 	CreateTable(ctx *Context, name string, schema Schema) error
 }
 
+// TableAdder should be implemented by databases that can hold tables created
+// outside of them, such as tables whose rows are produced by a script.
+type TableAdder interface {
+	AddTable(name string, table Table)
+}
+
 // TableDropper should be implemented by databases that can drop tables.
 type TableDropper interface {
 	DropTable(ctx *Context, name string) error
 }
 
+// TableRenamer should be implemented by databases that can rename tables.
+type TableRenamer interface {
+	RenameTable(ctx *Context, oldName, newName string) error
+}
+
+// ColumnOrder is the position of a column being added or modified in the
+// schema of a table. A nil ColumnOrder keeps the position of a modified
+// column, or places a new column at the end.
+type ColumnOrder struct {
+	// First is true if the column must be the first one of the table.
+	First bool
+	// AfterColumn is the name of the column after which the column must be
+	// placed.
+	AfterColumn string
+}
+
+// TableAlterer should be implemented by tables whose schema can be changed.
+// Existing rows must be rewritten to match the new schema.
+type TableAlterer interface {
+	Table
+	// AddColumn adds a new column to the table. Existing rows have the
+	// default value of the column.
+	AddColumn(ctx *Context, column *Column, order *ColumnOrder) error
+	// DropColumn removes the column with the given name from the table.
+	DropColumn(ctx *Context, name string) error
+	// ModifyColumn replaces the column with the given name with the given
+	// definition, which may have a different name. Existing values are
+	// converted to the type of the new definition.
+	ModifyColumn(ctx *Context, name string, column *Column, order *ColumnOrder) error
+	// RenameColumn changes the name of a column.
+	RenameColumn(ctx *Context, oldName, newName string) error
+}
+
 // Lockable should be implemented by tables that can be locked and unlocked.
 type Lockable interface {
 	Nameable
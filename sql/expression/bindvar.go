@@ -0,0 +1,50 @@
+package expression
+
+import (
+	"github.com/src-d/go-mysql-server/sql"
+	"gopkg.in/src-d/go-errors.v1"
+)
+
+// ErrUnboundVariable is returned when a placeholder of a prepared statement
+// is evaluated without being replaced by a value first.
+var ErrUnboundVariable = errors.NewKind("unbound variable %s")
+
+// BindVar is a placeholder of a prepared statement, written as ? in a query,
+// which must be replaced by a value before the statement is executed.
+type BindVar struct {
+	Name string
+}
+
+// NewBindVar creates a new BindVar expression with the given name.
+func NewBindVar(name string) *BindVar {
+	return &BindVar{Name: name}
+}
+
+// Resolved implements the sql.Expression interface. Placeholders are always
+// resolved, so statements can be analyzed before their values are known.
+func (*BindVar) Resolved() bool { return true }
+
+// IsNullable implements the sql.Expression interface.
+func (*BindVar) IsNullable() bool { return true }
+
+// Type implements the sql.Expression interface.
+func (*BindVar) Type() sql.Type { return sql.Null }
+
+// Children implements the sql.Expression interface.
+func (*BindVar) Children() []sql.Expression { return nil }
+
+// Eval implements the sql.Expression interface. It always fails, because
+// placeholders have no value.
+func (b *BindVar) Eval(*sql.Context, sql.Row) (interface{}, error) {
+	return nil, ErrUnboundVariable.New(b.Name)
+}
+
+func (*BindVar) String() string { return "?" }
+
+// WithChildren implements the Expression interface.
+func (b *BindVar) WithChildren(children ...sql.Expression) (sql.Expression, error) {
+	if len(children) != 0 {
+		return nil, sql.ErrInvalidChildrenNumber.New(b, len(children), 0)
+	}
+	return b, nil
+}
@@ -502,12 +502,20 @@ func (in *In) Eval(ctx *sql.Context, row sql.Row) (interface{}, error) {
 		}
 
 		typ := right.Type()
-		values, err := right.EvalMultiple(ctx)
+		values, err := right.EvalMultiple(ctx, row)
 		if err != nil {
 			return nil, err
 		}
 
+		var hasNulls bool
 		for _, val := range values {
+			// A NULL value may or may not be equal to the left value, so
+			// the result is unknown if there is no other value equal to it.
+			if val == nil {
+				hasNulls = true
+				continue
+			}
+
 			val, err = typ.Convert(val)
 			if err != nil {
 				return nil, err
@@ -523,6 +531,10 @@ func (in *In) Eval(ctx *sql.Context, row sql.Row) (interface{}, error) {
 			}
 		}
 
+		if hasNulls {
+			return nil, nil
+		}
+
 		return false, nil
 	default:
 		return nil, ErrUnsupportedInOperand.New(right)
@@ -610,12 +622,20 @@ func (in *NotIn) Eval(ctx *sql.Context, row sql.Row) (interface{}, error) {
 		}
 
 		typ := right.Type()
-		values, err := right.EvalMultiple(ctx)
+		values, err := right.EvalMultiple(ctx, row)
 		if err != nil {
 			return nil, err
 		}
 
+		var hasNulls bool
 		for _, val := range values {
+			// A NULL value may or may not be equal to the left value, so
+			// the result is unknown if there is no other value equal to it.
+			if val == nil {
+				hasNulls = true
+				continue
+			}
+
 			val, err = typ.Convert(val)
 			if err != nil {
 				return nil, err
@@ -631,6 +651,10 @@ func (in *NotIn) Eval(ctx *sql.Context, row sql.Row) (interface{}, error) {
 			}
 		}
 
+		if hasNulls {
+			return nil, nil
+		}
+
 		return true, nil
 	default:
 		return nil, ErrUnsupportedInOperand.New(right)
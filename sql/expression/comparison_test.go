@@ -376,6 +376,16 @@ func TestInSubquery(t *testing.T) {
 			false,
 			nil,
 		},
+		{
+			"right has nulls",
+			expression.NewGetField(0, sql.Text, "foo", false),
+			project(
+				expression.NewLiteral(nil, sql.Null),
+			),
+			sql.NewRow("four"),
+			nil,
+			nil,
+		},
 	}
 
 	for _, tt := range testCases {
@@ -547,6 +557,16 @@ func TestNotInSubquery(t *testing.T) {
 			true,
 			nil,
 		},
+		{
+			"right has nulls",
+			expression.NewGetField(0, sql.Text, "foo", false),
+			project(
+				expression.NewLiteral(nil, sql.Null),
+			),
+			sql.NewRow("four"),
+			nil,
+			nil,
+		},
 	}
 
 	for _, tt := range testCases {
@@ -0,0 +1,60 @@
+package expression
+
+import (
+	"fmt"
+
+	"github.com/src-d/go-mysql-server/sql"
+	errors "gopkg.in/src-d/go-errors.v1"
+)
+
+// ErrInvalidExistsChild is returned when the child of an EXISTS expression
+// is not a subquery.
+var ErrInvalidExistsChild = errors.NewKind("EXISTS expects a subquery, but got %T")
+
+// Exists is an expression that checks if a subquery returns any row.
+type Exists struct {
+	UnaryExpression
+}
+
+// NewExists creates a new Exists expression.
+func NewExists(subquery *Subquery) *Exists {
+	return &Exists{UnaryExpression{subquery}}
+}
+
+// Subquery returns the subquery of the expression.
+func (e *Exists) Subquery() *Subquery {
+	return e.Child.(*Subquery)
+}
+
+// Type implements the Expression interface.
+func (e *Exists) Type() sql.Type {
+	return sql.Boolean
+}
+
+// IsNullable implements the Expression interface.
+func (e *Exists) IsNullable() bool {
+	return false
+}
+
+// Eval implements the Expression interface.
+func (e *Exists) Eval(ctx *sql.Context, row sql.Row) (interface{}, error) {
+	return e.Subquery().HasRows(ctx, row)
+}
+
+func (e *Exists) String() string {
+	return fmt.Sprintf("EXISTS %s", e.Child)
+}
+
+// WithChildren implements the Expression interface.
+func (e *Exists) WithChildren(children ...sql.Expression) (sql.Expression, error) {
+	if len(children) != 1 {
+		return nil, sql.ErrInvalidChildrenNumber.New(e, len(children), 1)
+	}
+
+	subquery, ok := children[0].(*Subquery)
+	if !ok {
+		return nil, ErrInvalidExistsChild.New(children[0])
+	}
+
+	return NewExists(subquery), nil
+}
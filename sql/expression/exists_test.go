@@ -0,0 +1,85 @@
+package expression_test
+
+import (
+	"testing"
+
+	"github.com/src-d/go-mysql-server/memory"
+	"github.com/src-d/go-mysql-server/sql"
+	"github.com/src-d/go-mysql-server/sql/expression"
+	"github.com/src-d/go-mysql-server/sql/plan"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExists(t *testing.T) {
+	require := require.New(t)
+	ctx := sql.NewEmptyContext()
+
+	table := memory.NewTable("foo", sql.Schema{
+		{Name: "i", Source: "foo", Type: sql.Int64},
+	})
+	require.NoError(table.Insert(ctx, sql.NewRow(int64(1))))
+	require.NoError(table.Insert(ctx, sql.NewRow(int64(2))))
+
+	// SELECT i FROM foo WHERE i = <outer value>
+	subquery := expression.NewSubquery(plan.NewFilter(
+		expression.NewEquals(
+			expression.NewGetFieldWithTable(0, sql.Int64, "foo", "i", false),
+			expression.NewOuterField(0, sql.Int64, "bar", "i", false),
+		),
+		plan.NewResolvedTable(table),
+	)).WithOuter([]sql.Expression{
+		expression.NewGetFieldWithTable(1, sql.Int64, "bar", "i", false),
+	})
+
+	e := expression.NewExists(subquery)
+	require.Equal(sql.Boolean, e.Type())
+	require.False(e.IsNullable())
+
+	v, err := e.Eval(ctx, sql.NewRow("a", int64(2)))
+	require.NoError(err)
+	require.Equal(true, v)
+
+	v, err = e.Eval(ctx, sql.NewRow("a", int64(3)))
+	require.NoError(err)
+	require.Equal(false, v)
+
+	_, err = e.WithChildren(expression.NewLiteral(1, sql.Int64))
+	require.True(expression.ErrInvalidExistsChild.Is(err))
+}
+
+func TestCorrelatedSubquery(t *testing.T) {
+	require := require.New(t)
+	ctx := sql.NewEmptyContext()
+
+	table := memory.NewTable("foo", sql.Schema{
+		{Name: "i", Source: "foo", Type: sql.Int64},
+	})
+	for i := int64(1); i <= 3; i++ {
+		require.NoError(table.Insert(ctx, sql.NewRow(i)))
+	}
+
+	// SELECT i FROM foo WHERE i > <outer value>
+	subquery := expression.NewSubquery(plan.NewFilter(
+		expression.NewGreaterThan(
+			expression.NewGetFieldWithTable(0, sql.Int64, "foo", "i", false),
+			expression.NewOuterField(0, sql.Int64, "bar", "i", false),
+		),
+		plan.NewResolvedTable(table),
+	)).WithOuter([]sql.Expression{
+		expression.NewGetFieldWithTable(0, sql.Int64, "bar", "i", false),
+	})
+	require.True(subquery.Correlated())
+
+	values, err := subquery.EvalMultiple(ctx, sql.NewRow(int64(1)))
+	require.NoError(err)
+	require.Equal([]interface{}{int64(2), int64(3)}, values)
+
+	// The result is not cached for correlated subqueries.
+	values, err = subquery.EvalMultiple(ctx, sql.NewRow(int64(2)))
+	require.NoError(err)
+	require.Equal([]interface{}{int64(3)}, values)
+
+	value, err := subquery.Eval(ctx, sql.NewRow(int64(3)))
+	require.NoError(err)
+	require.Nil(value)
+}
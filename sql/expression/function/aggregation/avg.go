@@ -108,7 +108,7 @@ func (a *Avg) Merge(ctx *sql.Context, buffer, partial sql.Row) error {
 
 	psum := partial[0].(float64)
 	prows := partial[1].(int64)
-	pnulls := buffer[2].(bool)
+	pnulls := partial[2].(bool)
 
 	buffer[0] = bsum + psum
 	buffer[1] = brows + prows
@@ -79,7 +79,12 @@ func (c *Count) Update(ctx *sql.Context, buffer, row sql.Row) error {
 
 // Merge implements the Aggregation interface.
 func (c *Count) Merge(ctx *sql.Context, buffer, partial sql.Row) error {
-	buffer[0] = buffer[0].(int64) + partial[0].(int64)
+	count, err := sql.Int64.Convert(partial[0])
+	if err != nil {
+		return err
+	}
+
+	buffer[0] = buffer[0].(int64) + count.(int64)
 	return nil
 }
 
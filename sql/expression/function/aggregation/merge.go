@@ -0,0 +1,98 @@
+package aggregation
+
+import (
+	"fmt"
+
+	"github.com/src-d/go-mysql-server/sql"
+)
+
+// Merge is an aggregation that merges partial results of another one, which
+// are in the rows it's updated with, instead of aggregating the rows. This is
+// used with the rows of tables and nodes that return partial aggregations.
+type Merge struct {
+	Aggregation sql.Aggregation
+	// Index is the position in the rows of the first element of the buffer
+	// of the partial result.
+	Index int
+	size  int
+}
+
+var _ sql.Aggregation = (*Merge)(nil)
+var _ sql.TransposedAggregation = (*Merge)(nil)
+
+// NewMerge returns a new Merge aggregation of the partial results of the
+// given aggregation, whose buffers start at the given position of the rows.
+func NewMerge(agg sql.Aggregation, index int) *Merge {
+	return &Merge{
+		Aggregation: agg,
+		Index:       index,
+		size:        len(agg.NewBuffer()),
+	}
+}
+
+// Name returns the name of the merged aggregation, so that the result has
+// the same name as the one computed with all the rows.
+func (m *Merge) Name() string {
+	return m.Aggregation.String()
+}
+
+// Transposed implements the TransposedAggregation interface. The result is
+// transposed if the one of the merged aggregation is.
+func (m *Merge) Transposed() bool {
+	t, ok := m.Aggregation.(sql.TransposedAggregation)
+	return ok && t.Transposed()
+}
+
+// Resolved implements the Resolvable interface.
+func (m *Merge) Resolved() bool {
+	return m.Aggregation.Resolved()
+}
+
+// Type implements the Expression interface.
+func (m *Merge) Type() sql.Type {
+	return m.Aggregation.Type()
+}
+
+// IsNullable implements the Expression interface.
+func (m *Merge) IsNullable() bool {
+	return m.Aggregation.IsNullable()
+}
+
+// Children implements the Expression interface. The expressions of the
+// merged aggregation are not children, since they are not evaluated with the
+// rows.
+func (m *Merge) Children() []sql.Expression {
+	return nil
+}
+
+// WithChildren implements the Expression interface.
+func (m *Merge) WithChildren(children ...sql.Expression) (sql.Expression, error) {
+	if len(children) != 0 {
+		return nil, sql.ErrInvalidChildrenNumber.New(m, len(children), 0)
+	}
+	return m, nil
+}
+
+func (m *Merge) String() string {
+	return fmt.Sprintf("MERGE(%s)", m.Aggregation)
+}
+
+// NewBuffer implements the Aggregation interface.
+func (m *Merge) NewBuffer() sql.Row {
+	return m.Aggregation.NewBuffer()
+}
+
+// Update implements the Aggregation interface.
+func (m *Merge) Update(ctx *sql.Context, buffer, row sql.Row) error {
+	return m.Aggregation.Merge(ctx, buffer, row[m.Index:m.Index+m.size])
+}
+
+// Merge implements the Aggregation interface.
+func (m *Merge) Merge(ctx *sql.Context, buffer, partial sql.Row) error {
+	return m.Aggregation.Merge(ctx, buffer, partial)
+}
+
+// Eval implements the Aggregation interface.
+func (m *Merge) Eval(ctx *sql.Context, buffer sql.Row) (interface{}, error) {
+	return m.Aggregation.Eval(ctx, buffer)
+}
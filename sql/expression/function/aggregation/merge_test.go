@@ -0,0 +1,61 @@
+package aggregation
+
+import (
+	"testing"
+
+	"github.com/src-d/go-mysql-server/sql"
+	"github.com/src-d/go-mysql-server/sql/expression"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMerge(t *testing.T) {
+	field := expression.NewGetField(1, sql.Int64, "field", true)
+	rows := []sql.Row{
+		{"a", int64(7)},
+		{"a", int64(2)},
+		{"b", nil},
+		{"b", int64(5)},
+		{"c", int64(3)},
+	}
+
+	testCases := []struct {
+		agg      sql.Aggregation
+		expected interface{}
+	}{
+		{NewCount(field), int64(4)},
+		{NewCount(expression.NewStar()), int64(5)},
+		{NewCountDistinct(field), int64(4)},
+		{NewSum(field), float64(17)},
+		{NewMin(field), int64(2)},
+		{NewMax(field), int64(7)},
+		{NewAvg(field), nil},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.agg.String(), func(t *testing.T) {
+			require := require.New(t)
+			ctx := sql.NewEmptyContext()
+
+			// The partial results are in rows with something else before,
+			// and the ones of some groups are empty.
+			merge := NewMerge(tt.agg, 1)
+			buffer := merge.NewBuffer()
+			for _, group := range [][]sql.Row{rows[:2], rows[2:4], nil, rows[4:]} {
+				partial := tt.agg.NewBuffer()
+				for _, row := range group {
+					require.NoError(tt.agg.Update(ctx, partial, row))
+				}
+
+				require.NoError(merge.Update(ctx, buffer, append(sql.Row{"foo"}, partial...)))
+			}
+
+			require.Equal(tt.expected, eval(t, merge, buffer))
+			require.Equal(tt.expected, aggregate(t, tt.agg, rows...))
+		})
+	}
+
+	merge := NewMerge(NewSum(expression.NewGetField(0, sql.Int64, "field", false)), 0)
+	require.Equal(t, "MERGE(SUM(field))", merge.String())
+	require.Equal(t, "SUM(field)", merge.Name())
+	require.Nil(t, merge.Children())
+}
@@ -58,6 +58,15 @@ func (m *Min) Update(ctx *sql.Context, buffer, row sql.Row) error {
 		return err
 	}
 
+	return m.update(buffer, v)
+}
+
+// Merge implements the Aggregation interface.
+func (m *Min) Merge(ctx *sql.Context, buffer, partial sql.Row) error {
+	return m.update(buffer, partial[0])
+}
+
+func (m *Min) update(buffer sql.Row, v interface{}) error {
 	if reflect.TypeOf(v) == nil {
 		return nil
 	}
@@ -77,11 +86,6 @@ func (m *Min) Update(ctx *sql.Context, buffer, row sql.Row) error {
 	return nil
 }
 
-// Merge implements the Aggregation interface.
-func (m *Min) Merge(ctx *sql.Context, buffer, partial sql.Row) error {
-	return m.Update(ctx, buffer, partial)
-}
-
 // Eval implements the Aggregation interface
 func (m *Min) Eval(ctx *sql.Context, buffer sql.Row) (interface{}, error) {
 	min := buffer[0]
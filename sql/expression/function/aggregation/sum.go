@@ -47,8 +47,19 @@ func (m *Sum) Update(ctx *sql.Context, buffer, row sql.Row) error {
 		return err
 	}
 
+	m.add(buffer, v)
+	return nil
+}
+
+// Merge implements the Aggregation interface.
+func (m *Sum) Merge(ctx *sql.Context, buffer, partial sql.Row) error {
+	m.add(buffer, partial[0])
+	return nil
+}
+
+func (m *Sum) add(buffer sql.Row, v interface{}) {
 	if v == nil {
-		return nil
+		return
 	}
 
 	val, err := sql.Float64.Convert(v)
@@ -61,13 +72,6 @@ func (m *Sum) Update(ctx *sql.Context, buffer, row sql.Row) error {
 	}
 
 	buffer[0] = buffer[0].(float64) + val.(float64)
-
-	return nil
-}
-
-// Merge implements the Aggregation interface.
-func (m *Sum) Merge(ctx *sql.Context, buffer, partial sql.Row) error {
-	return m.Update(ctx, buffer, partial)
 }
 
 // Eval implements the Aggregation interface.
@@ -0,0 +1,523 @@
+package aggregation
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/src-d/go-mysql-server/sql"
+	"github.com/src-d/go-mysql-server/sql/expression"
+	errors "gopkg.in/src-d/go-errors.v1"
+)
+
+// ErrInvalidWindowArgument is returned when an argument of a window function
+// has an invalid value.
+var ErrInvalidWindowArgument = errors.NewKind("invalid argument for %s: %v")
+
+// ErrWindowFunctionNoBuffer is returned when a window function is evaluated
+// outside of a window, so there is no buffer to read the result from.
+var ErrWindowFunctionNoBuffer = errors.NewKind("window functions can only be evaluated over a window")
+
+// RowNumber window function returns the number of the current row in its
+// partition, starting at 1.
+// It implements the WindowFunction interface.
+type RowNumber struct{}
+
+// NewRowNumber returns a new RowNumber node.
+func NewRowNumber() sql.Expression {
+	return RowNumber{}
+}
+
+// Type returns the resultant type of the window function.
+func (RowNumber) Type() sql.Type { return sql.Int64 }
+
+// IsNullable implements the Expression interface.
+func (RowNumber) IsNullable() bool { return false }
+
+// Resolved implements the Expression interface.
+func (RowNumber) Resolved() bool { return true }
+
+// Children implements the Expression interface.
+func (RowNumber) Children() []sql.Expression { return nil }
+
+func (RowNumber) String() string { return "ROW_NUMBER()" }
+
+// WithChildren implements the Expression interface.
+func (r RowNumber) WithChildren(children ...sql.Expression) (sql.Expression, error) {
+	if len(children) != 0 {
+		return nil, sql.ErrInvalidChildrenNumber.New(r, len(children), 0)
+	}
+	return r, nil
+}
+
+// NewBuffer implements the WindowFunction interface.
+func (RowNumber) NewBuffer() sql.Row {
+	return sql.NewRow(int64(0))
+}
+
+// UpdateWindow implements the WindowFunction interface.
+func (RowNumber) UpdateWindow(ctx *sql.Context, buffer sql.Row, p *sql.WindowPartition, idx int) error {
+	buffer[0] = int64(idx + 1)
+	return nil
+}
+
+// Eval implements the WindowFunction interface.
+func (RowNumber) Eval(ctx *sql.Context, buffer sql.Row) (interface{}, error) {
+	return windowResult(buffer)
+}
+
+// Rank window function returns the rank of the current row in its partition,
+// with gaps. Peer rows have the same rank.
+// It implements the WindowFunction interface.
+type Rank struct{}
+
+// NewRank returns a new Rank node.
+func NewRank() sql.Expression {
+	return Rank{}
+}
+
+// Type returns the resultant type of the window function.
+func (Rank) Type() sql.Type { return sql.Int64 }
+
+// IsNullable implements the Expression interface.
+func (Rank) IsNullable() bool { return false }
+
+// Resolved implements the Expression interface.
+func (Rank) Resolved() bool { return true }
+
+// Children implements the Expression interface.
+func (Rank) Children() []sql.Expression { return nil }
+
+func (Rank) String() string { return "RANK()" }
+
+// WithChildren implements the Expression interface.
+func (r Rank) WithChildren(children ...sql.Expression) (sql.Expression, error) {
+	if len(children) != 0 {
+		return nil, sql.ErrInvalidChildrenNumber.New(r, len(children), 0)
+	}
+	return r, nil
+}
+
+// NewBuffer implements the WindowFunction interface.
+func (Rank) NewBuffer() sql.Row {
+	return sql.NewRow(int64(0))
+}
+
+// UpdateWindow implements the WindowFunction interface.
+func (Rank) UpdateWindow(ctx *sql.Context, buffer sql.Row, p *sql.WindowPartition, idx int) error {
+	if idx == 0 || !p.Peers[idx] {
+		buffer[0] = int64(idx + 1)
+	}
+	return nil
+}
+
+// Eval implements the WindowFunction interface.
+func (Rank) Eval(ctx *sql.Context, buffer sql.Row) (interface{}, error) {
+	return windowResult(buffer)
+}
+
+// DenseRank window function returns the rank of the current row in its
+// partition, without gaps. Peer rows have the same rank.
+// It implements the WindowFunction interface.
+type DenseRank struct{}
+
+// NewDenseRank returns a new DenseRank node.
+func NewDenseRank() sql.Expression {
+	return DenseRank{}
+}
+
+// Type returns the resultant type of the window function.
+func (DenseRank) Type() sql.Type { return sql.Int64 }
+
+// IsNullable implements the Expression interface.
+func (DenseRank) IsNullable() bool { return false }
+
+// Resolved implements the Expression interface.
+func (DenseRank) Resolved() bool { return true }
+
+// Children implements the Expression interface.
+func (DenseRank) Children() []sql.Expression { return nil }
+
+func (DenseRank) String() string { return "DENSE_RANK()" }
+
+// WithChildren implements the Expression interface.
+func (r DenseRank) WithChildren(children ...sql.Expression) (sql.Expression, error) {
+	if len(children) != 0 {
+		return nil, sql.ErrInvalidChildrenNumber.New(r, len(children), 0)
+	}
+	return r, nil
+}
+
+// NewBuffer implements the WindowFunction interface.
+func (DenseRank) NewBuffer() sql.Row {
+	return sql.NewRow(int64(0))
+}
+
+// UpdateWindow implements the WindowFunction interface.
+func (DenseRank) UpdateWindow(ctx *sql.Context, buffer sql.Row, p *sql.WindowPartition, idx int) error {
+	if idx == 0 || !p.Peers[idx] {
+		buffer[0] = buffer[0].(int64) + 1
+	}
+	return nil
+}
+
+// Eval implements the WindowFunction interface.
+func (DenseRank) Eval(ctx *sql.Context, buffer sql.Row) (interface{}, error) {
+	return windowResult(buffer)
+}
+
+// Ntile window function divides the rows of the partition in the given
+// number of buckets and returns the number of the bucket of the current row,
+// starting at 1.
+// It implements the WindowFunction interface.
+type Ntile struct {
+	expression.UnaryExpression
+}
+
+// NewNtile returns a new Ntile node.
+func NewNtile(buckets sql.Expression) sql.Expression {
+	return &Ntile{expression.UnaryExpression{Child: buckets}}
+}
+
+// Type returns the resultant type of the window function.
+func (n *Ntile) Type() sql.Type { return sql.Int64 }
+
+// IsNullable implements the Expression interface.
+func (n *Ntile) IsNullable() bool { return false }
+
+func (n *Ntile) String() string {
+	return fmt.Sprintf("NTILE(%s)", n.Child)
+}
+
+// WithChildren implements the Expression interface.
+func (n *Ntile) WithChildren(children ...sql.Expression) (sql.Expression, error) {
+	if len(children) != 1 {
+		return nil, sql.ErrInvalidChildrenNumber.New(n, len(children), 1)
+	}
+	return NewNtile(children[0]), nil
+}
+
+// NewBuffer implements the WindowFunction interface.
+func (n *Ntile) NewBuffer() sql.Row {
+	return sql.NewRow(nil)
+}
+
+// UpdateWindow implements the WindowFunction interface.
+func (n *Ntile) UpdateWindow(ctx *sql.Context, buffer sql.Row, p *sql.WindowPartition, idx int) error {
+	v, err := n.Child.Eval(ctx, p.Rows[idx])
+	if err != nil {
+		return err
+	}
+
+	buckets, err := sql.Int64.Convert(v)
+	if err != nil || v == nil || buckets.(int64) <= 0 {
+		return ErrInvalidWindowArgument.New("NTILE", v)
+	}
+
+	// The first size % buckets buckets have one row more than the rest.
+	size := int64(len(p.Rows))
+	perBucket := size / buckets.(int64)
+	bigBuckets := size % buckets.(int64)
+
+	i := int64(idx)
+	if i < bigBuckets*(perBucket+1) {
+		buffer[0] = i/(perBucket+1) + 1
+	} else {
+		buffer[0] = bigBuckets + (i-bigBuckets*(perBucket+1))/perBucket + 1
+	}
+
+	return nil
+}
+
+// Eval implements the WindowFunction interface.
+func (n *Ntile) Eval(ctx *sql.Context, buffer sql.Row) (interface{}, error) {
+	return windowResult(buffer)
+}
+
+// Lag window function returns the value of the expression for the row that
+// is the given number of rows before the current one in its partition, or
+// the default value if there is no such row.
+// It implements the WindowFunction interface.
+type Lag struct {
+	offsetFunction
+}
+
+// NewLag returns a new Lag node. It accepts an expression and, optionally,
+// an offset, which is 1 by default, and a default value.
+func NewLag(args ...sql.Expression) (sql.Expression, error) {
+	f, err := newOffsetFunction("LAG", args...)
+	if err != nil {
+		return nil, err
+	}
+	return &Lag{f}, nil
+}
+
+func (l *Lag) String() string {
+	return l.offsetFunction.string("LAG")
+}
+
+// WithChildren implements the Expression interface.
+func (l *Lag) WithChildren(children ...sql.Expression) (sql.Expression, error) {
+	return NewLag(children...)
+}
+
+// UpdateWindow implements the WindowFunction interface.
+func (l *Lag) UpdateWindow(ctx *sql.Context, buffer sql.Row, p *sql.WindowPartition, idx int) error {
+	return l.update(ctx, buffer, p, idx, -1)
+}
+
+// Lead window function returns the value of the expression for the row that
+// is the given number of rows after the current one in its partition, or
+// the default value if there is no such row.
+// It implements the WindowFunction interface.
+type Lead struct {
+	offsetFunction
+}
+
+// NewLead returns a new Lead node. It accepts an expression and, optionally,
+// an offset, which is 1 by default, and a default value.
+func NewLead(args ...sql.Expression) (sql.Expression, error) {
+	f, err := newOffsetFunction("LEAD", args...)
+	if err != nil {
+		return nil, err
+	}
+	return &Lead{f}, nil
+}
+
+func (l *Lead) String() string {
+	return l.offsetFunction.string("LEAD")
+}
+
+// WithChildren implements the Expression interface.
+func (l *Lead) WithChildren(children ...sql.Expression) (sql.Expression, error) {
+	return NewLead(children...)
+}
+
+// UpdateWindow implements the WindowFunction interface.
+func (l *Lead) UpdateWindow(ctx *sql.Context, buffer sql.Row, p *sql.WindowPartition, idx int) error {
+	return l.update(ctx, buffer, p, idx, 1)
+}
+
+// offsetFunction contains the common logic of LAG and LEAD.
+type offsetFunction struct {
+	Child   sql.Expression
+	Offset  sql.Expression
+	Default sql.Expression
+}
+
+func newOffsetFunction(name string, args ...sql.Expression) (offsetFunction, error) {
+	if len(args) < 1 || len(args) > 3 {
+		return offsetFunction{}, sql.ErrInvalidArgumentNumber.New(name, "1, 2 or 3", len(args))
+	}
+
+	f := offsetFunction{Child: args[0]}
+	if len(args) > 1 {
+		f.Offset = args[1]
+	}
+
+	if len(args) > 2 {
+		f.Default = args[2]
+	}
+
+	return f, nil
+}
+
+// Type returns the resultant type of the window function.
+func (f *offsetFunction) Type() sql.Type { return f.Child.Type() }
+
+// IsNullable implements the Expression interface.
+func (f *offsetFunction) IsNullable() bool { return true }
+
+// Children implements the Expression interface.
+func (f *offsetFunction) Children() []sql.Expression {
+	var children = []sql.Expression{f.Child}
+	if f.Offset != nil {
+		children = append(children, f.Offset)
+	}
+
+	if f.Default != nil {
+		children = append(children, f.Default)
+	}
+
+	return children
+}
+
+// Resolved implements the Expression interface.
+func (f *offsetFunction) Resolved() bool {
+	for _, c := range f.Children() {
+		if !c.Resolved() {
+			return false
+		}
+	}
+	return true
+}
+
+func (f *offsetFunction) string(name string) string {
+	var args []string
+	for _, c := range f.Children() {
+		args = append(args, c.String())
+	}
+	return fmt.Sprintf("%s(%s)", name, strings.Join(args, ", "))
+}
+
+// NewBuffer implements the WindowFunction interface.
+func (f *offsetFunction) NewBuffer() sql.Row {
+	return sql.NewRow(nil)
+}
+
+func (f *offsetFunction) update(
+	ctx *sql.Context,
+	buffer sql.Row,
+	p *sql.WindowPartition,
+	idx int,
+	direction int,
+) error {
+	var offset int64 = 1
+	if f.Offset != nil {
+		v, err := f.Offset.Eval(ctx, p.Rows[idx])
+		if err != nil {
+			return err
+		}
+
+		o, err := sql.Int64.Convert(v)
+		if err != nil || v == nil || o.(int64) < 0 {
+			return ErrInvalidWindowArgument.New("offset", v)
+		}
+		offset = o.(int64)
+	}
+
+	var err error
+	target := int64(idx) + int64(direction)*offset
+	switch {
+	case target >= 0 && target < int64(len(p.Rows)):
+		buffer[0], err = f.Child.Eval(ctx, p.Rows[target])
+	case f.Default != nil:
+		var v interface{}
+		v, err = f.Default.Eval(ctx, p.Rows[idx])
+		buffer[0] = nil
+		if err == nil && v != nil {
+			buffer[0], err = f.Child.Type().Convert(v)
+		}
+	default:
+		buffer[0] = nil
+	}
+
+	return err
+}
+
+// Eval implements the WindowFunction interface.
+func (f *offsetFunction) Eval(ctx *sql.Context, buffer sql.Row) (interface{}, error) {
+	return windowResult(buffer)
+}
+
+// FirstValue window function returns the value of the expression for the
+// first row of the frame of the current row.
+// It implements the WindowFunction interface.
+type FirstValue struct {
+	expression.UnaryExpression
+}
+
+// NewFirstValue returns a new FirstValue node.
+func NewFirstValue(e sql.Expression) sql.Expression {
+	return &FirstValue{expression.UnaryExpression{Child: e}}
+}
+
+// Type returns the resultant type of the window function.
+func (f *FirstValue) Type() sql.Type { return f.Child.Type() }
+
+// IsNullable implements the Expression interface.
+func (f *FirstValue) IsNullable() bool { return true }
+
+func (f *FirstValue) String() string {
+	return fmt.Sprintf("FIRST_VALUE(%s)", f.Child)
+}
+
+// WithChildren implements the Expression interface.
+func (f *FirstValue) WithChildren(children ...sql.Expression) (sql.Expression, error) {
+	if len(children) != 1 {
+		return nil, sql.ErrInvalidChildrenNumber.New(f, len(children), 1)
+	}
+	return NewFirstValue(children[0]), nil
+}
+
+// NewBuffer implements the WindowFunction interface.
+func (f *FirstValue) NewBuffer() sql.Row {
+	return sql.NewRow(nil)
+}
+
+// UpdateWindow implements the WindowFunction interface.
+func (f *FirstValue) UpdateWindow(ctx *sql.Context, buffer sql.Row, p *sql.WindowPartition, idx int) error {
+	start, end := p.FrameStart[idx], p.FrameEnd[idx]
+	if start >= end {
+		buffer[0] = nil
+		return nil
+	}
+
+	var err error
+	buffer[0], err = f.Child.Eval(ctx, p.Rows[start])
+	return err
+}
+
+// Eval implements the WindowFunction interface.
+func (f *FirstValue) Eval(ctx *sql.Context, buffer sql.Row) (interface{}, error) {
+	return windowResult(buffer)
+}
+
+// LastValue window function returns the value of the expression for the
+// last row of the frame of the current row.
+// It implements the WindowFunction interface.
+type LastValue struct {
+	expression.UnaryExpression
+}
+
+// NewLastValue returns a new LastValue node.
+func NewLastValue(e sql.Expression) sql.Expression {
+	return &LastValue{expression.UnaryExpression{Child: e}}
+}
+
+// Type returns the resultant type of the window function.
+func (l *LastValue) Type() sql.Type { return l.Child.Type() }
+
+// IsNullable implements the Expression interface.
+func (l *LastValue) IsNullable() bool { return true }
+
+func (l *LastValue) String() string {
+	return fmt.Sprintf("LAST_VALUE(%s)", l.Child)
+}
+
+// WithChildren implements the Expression interface.
+func (l *LastValue) WithChildren(children ...sql.Expression) (sql.Expression, error) {
+	if len(children) != 1 {
+		return nil, sql.ErrInvalidChildrenNumber.New(l, len(children), 1)
+	}
+	return NewLastValue(children[0]), nil
+}
+
+// NewBuffer implements the WindowFunction interface.
+func (l *LastValue) NewBuffer() sql.Row {
+	return sql.NewRow(nil)
+}
+
+// UpdateWindow implements the WindowFunction interface.
+func (l *LastValue) UpdateWindow(ctx *sql.Context, buffer sql.Row, p *sql.WindowPartition, idx int) error {
+	start, end := p.FrameStart[idx], p.FrameEnd[idx]
+	if start >= end {
+		buffer[0] = nil
+		return nil
+	}
+
+	var err error
+	buffer[0], err = l.Child.Eval(ctx, p.Rows[end-1])
+	return err
+}
+
+// Eval implements the WindowFunction interface.
+func (l *LastValue) Eval(ctx *sql.Context, buffer sql.Row) (interface{}, error) {
+	return windowResult(buffer)
+}
+
+func windowResult(buffer sql.Row) (interface{}, error) {
+	if len(buffer) == 0 {
+		return nil, ErrWindowFunctionNoBuffer.New()
+	}
+	return buffer[0], nil
+}
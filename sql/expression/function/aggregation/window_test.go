@@ -0,0 +1,188 @@
+package aggregation
+
+import (
+	"testing"
+
+	"github.com/src-d/go-mysql-server/sql"
+	"github.com/src-d/go-mysql-server/sql/expression"
+	"github.com/stretchr/testify/require"
+)
+
+// window evaluates the window function for every row of a partition whose
+// frames span the whole partition and whose rows are peers when they have the
+// same value in the first column.
+func window(t *testing.T, fn sql.WindowFunction, rows ...sql.Row) []interface{} {
+	t.Helper()
+
+	p := &sql.WindowPartition{
+		Rows:       rows,
+		Peers:      make([]bool, len(rows)),
+		FrameStart: make([]int, len(rows)),
+		FrameEnd:   make([]int, len(rows)),
+	}
+
+	for i := range rows {
+		p.Peers[i] = i > 0 && rows[i][0] == rows[i-1][0]
+		p.FrameEnd[i] = len(rows)
+	}
+
+	return windowPartition(t, fn, p)
+}
+
+func windowPartition(t *testing.T, fn sql.WindowFunction, p *sql.WindowPartition) []interface{} {
+	t.Helper()
+
+	ctx := sql.NewEmptyContext()
+	buf := fn.NewBuffer()
+	var result []interface{}
+	for i := range p.Rows {
+		require.NoError(t, fn.UpdateWindow(ctx, buf, p, i))
+		v, err := fn.Eval(ctx, buf)
+		require.NoError(t, err)
+		result = append(result, v)
+	}
+
+	return result
+}
+
+func TestRanking(t *testing.T) {
+	rows := []sql.Row{{int64(1)}, {int64(1)}, {int64(2)}, {int64(3)}, {int64(3)}, {int64(4)}}
+
+	testCases := []struct {
+		name     string
+		fn       sql.WindowFunction
+		expected []interface{}
+	}{
+		{
+			"row_number",
+			RowNumber{},
+			[]interface{}{int64(1), int64(2), int64(3), int64(4), int64(5), int64(6)},
+		},
+		{
+			"rank",
+			Rank{},
+			[]interface{}{int64(1), int64(1), int64(3), int64(4), int64(4), int64(6)},
+		},
+		{
+			"dense_rank",
+			DenseRank{},
+			[]interface{}{int64(1), int64(1), int64(2), int64(3), int64(3), int64(4)},
+		},
+		{
+			"ntile",
+			NewNtile(expression.NewLiteral(int64(4), sql.Int64)).(sql.WindowFunction),
+			[]interface{}{int64(1), int64(1), int64(2), int64(2), int64(3), int64(4)},
+		},
+		{
+			"ntile with more buckets than rows",
+			NewNtile(expression.NewLiteral(int64(10), sql.Int64)).(sql.WindowFunction),
+			[]interface{}{int64(1), int64(2), int64(3), int64(4), int64(5), int64(6)},
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.expected, window(t, tt.fn, rows...))
+		})
+	}
+}
+
+func TestNtileInvalidBuckets(t *testing.T) {
+	require := require.New(t)
+
+	fn := NewNtile(expression.NewLiteral(int64(0), sql.Int64)).(sql.WindowFunction)
+	p := &sql.WindowPartition{
+		Rows:       []sql.Row{{int64(1)}},
+		Peers:      []bool{false},
+		FrameStart: []int{0},
+		FrameEnd:   []int{1},
+	}
+
+	err := fn.UpdateWindow(sql.NewEmptyContext(), fn.NewBuffer(), p, 0)
+	require.Error(err)
+	require.True(ErrInvalidWindowArgument.Is(err))
+}
+
+func TestLagLead(t *testing.T) {
+	rows := []sql.Row{{int64(1)}, {int64(2)}, {int64(3)}, {nil}}
+	child := expression.NewGetField(0, sql.Int64, "a", true)
+
+	testCases := []struct {
+		name     string
+		new      func(...sql.Expression) (sql.Expression, error)
+		args     []sql.Expression
+		expected []interface{}
+	}{
+		{
+			"lag",
+			NewLag,
+			[]sql.Expression{child},
+			[]interface{}{nil, int64(1), int64(2), int64(3)},
+		},
+		{
+			"lag with offset and default",
+			NewLag,
+			[]sql.Expression{
+				child,
+				expression.NewLiteral(int8(2), sql.Int8),
+				expression.NewLiteral(int8(0), sql.Int8),
+			},
+			[]interface{}{int64(0), int64(0), int64(1), int64(2)},
+		},
+		{
+			"lead",
+			NewLead,
+			[]sql.Expression{child},
+			[]interface{}{int64(2), int64(3), nil, nil},
+		},
+		{
+			"lead with offset 0",
+			NewLead,
+			[]sql.Expression{child, expression.NewLiteral(int8(0), sql.Int8)},
+			[]interface{}{int64(1), int64(2), int64(3), nil},
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			fn, err := tt.new(tt.args...)
+			require.NoError(t, err)
+			require.Equal(t, tt.expected, window(t, fn.(sql.WindowFunction), rows...))
+		})
+	}
+
+	_, err := NewLag()
+	require.Error(t, err)
+
+	_, err = NewLead(child, child, child, child)
+	require.Error(t, err)
+}
+
+func TestFirstLastValue(t *testing.T) {
+	require := require.New(t)
+
+	// Frames of ROWS BETWEEN 1 PRECEDING AND CURRENT ROW, with an empty frame
+	// for the last row.
+	p := &sql.WindowPartition{
+		Rows:       []sql.Row{{"a"}, {"b"}, {"c"}, {"d"}},
+		Peers:      make([]bool, 4),
+		FrameStart: []int{0, 0, 1, 3},
+		FrameEnd:   []int{1, 2, 3, 3},
+	}
+
+	child := expression.NewGetField(0, sql.Text, "s", true)
+
+	first := NewFirstValue(child).(sql.WindowFunction)
+	require.Equal([]interface{}{"a", "a", "b", nil}, windowPartition(t, first, p))
+
+	last := NewLastValue(child).(sql.WindowFunction)
+	require.Equal([]interface{}{"a", "b", "c", nil}, windowPartition(t, last, p))
+}
+
+func TestWindowFunctionWithoutBuffer(t *testing.T) {
+	require := require.New(t)
+
+	_, err := RowNumber{}.Eval(sql.NewEmptyContext(), nil)
+	require.Error(err)
+	require.True(ErrWindowFunctionNoBuffer.Is(err))
+}
@@ -37,6 +37,14 @@ var Defaults = []sql.Function{
 		Name: "last",
 		Fn:   func(e sql.Expression) sql.Expression { return aggregation.NewLast(e) },
 	},
+	sql.Function0{Name: "row_number", Fn: aggregation.NewRowNumber},
+	sql.Function0{Name: "rank", Fn: aggregation.NewRank},
+	sql.Function0{Name: "dense_rank", Fn: aggregation.NewDenseRank},
+	sql.Function1{Name: "ntile", Fn: aggregation.NewNtile},
+	sql.FunctionN{Name: "lag", Fn: aggregation.NewLag},
+	sql.FunctionN{Name: "lead", Fn: aggregation.NewLead},
+	sql.Function1{Name: "first_value", Fn: aggregation.NewFirstValue},
+	sql.Function1{Name: "last_value", Fn: aggregation.NewLastValue},
 	sql.Function1{Name: "is_binary", Fn: NewIsBinary},
 	sql.FunctionN{Name: "substring", Fn: NewSubstring},
 	sql.Function3{Name: "substring_index", Fn: NewSubstringIndex},
@@ -0,0 +1,427 @@
+package udf
+
+import (
+	"fmt"
+	"math"
+	"sync"
+
+	"github.com/robertkrimen/otto"
+	"github.com/robertkrimen/otto/ast"
+	"github.com/robertkrimen/otto/parser"
+	"github.com/robertkrimen/otto/token"
+)
+
+// builtinObject is a builtin object of a runtime along with the values of
+// its properties before running anything in the runtime.
+type builtinObject struct {
+	object *otto.Object
+	// properties is the number of own properties of the object, and
+	// enumerable the number of them that are enumerable, which are the
+	// ones added by assigning them.
+	properties int64
+	enumerable int
+	// names are the properties that can be changed, which are the ones
+	// that are either writable or configurable.
+	names  []string
+	values []otto.Value
+}
+
+// modified returns whether any property of the object was added, removed
+// or has a different value, given the function that lists the names of its
+// properties. Only the enumerable properties are counted unless all is
+// true, as listing all of them takes much longer.
+func (o builtinObject) modified(names otto.Value, all bool) bool {
+	if all {
+		properties, err := countProperties(names, o.object)
+		if err != nil || properties != o.properties {
+			return true
+		}
+	} else if len(o.object.Keys()) != o.enumerable {
+		return true
+	}
+	return o.changed()
+}
+
+// countProperties returns the number of own properties of the object, given
+// the function that lists their names.
+func countProperties(names otto.Value, o *otto.Object) (int64, error) {
+	v, err := names.Call(otto.NullValue(), o)
+	if err != nil {
+		return 0, err
+	}
+
+	length, err := v.Object().Get("length")
+	if err != nil {
+		return 0, err
+	}
+	return length.ToInteger()
+}
+
+// changed returns whether any property of the object has a different value.
+func (o builtinObject) changed() bool {
+	for i, name := range o.names {
+		v, err := o.object.Get(name)
+		if err != nil || !sameValue(v, o.values[i]) {
+			return true
+		}
+	}
+	return false
+}
+
+// sameValue returns whether the value is the same as the builtin one, as in
+// the === operator of JS, but NaN is the same as itself. Builtin values can
+// always be compared, as they are never strings with non-ASCII characters.
+func sameValue(v, builtin otto.Value) bool {
+	if v == builtin {
+		return true
+	}
+
+	if v.IsNumber() && builtin.IsNumber() {
+		x, _ := v.ToFloat()
+		y, _ := builtin.ToFloat()
+		return x == y || (math.IsNaN(x) && math.IsNaN(y))
+	}
+	return false
+}
+
+// builtinPath is how a builtin object is reached from the global object,
+// along with the properties of the object that can be changed, which are
+// the ones that are either writable or configurable, and the number of all
+// its properties and of the enumerable ones.
+type builtinPath struct {
+	// global is the name of the builtin global that holds the object, or
+	// the object it's reached from. It's empty for the global object.
+	global string
+	// step is either empty, prototype for the prototype property of the
+	// global, or inherited for the prototype it inherits from.
+	step       string
+	names      []string
+	properties int64
+	enumerable int64
+}
+
+// builtinPathsScript returns how to reach the global object and the objects
+// reachable from the builtin globals that scripts could modify, which are
+// the objects the globals hold, their prototypes and the prototypes they
+// inherit from.
+const builtinPathsScript = `(function (global) {
+	var paths = [], objects = [], add = function (o, name, step) {
+		if (o !== null && (typeof o === "object" || typeof o === "function") &&
+			objects.indexOf(o) < 0) {
+			var names = Object.getOwnPropertyNames(o);
+			objects.push(o);
+			paths.push([name, step, names.filter(function (name) {
+				var p = Object.getOwnPropertyDescriptor(o, name);
+				return p.writable || p.configurable;
+			}), names.length, Object.keys(o).length]);
+		}
+	};
+
+	add(global, "", "");
+	var names = Object.getOwnPropertyNames(global);
+	for (var i = 0; i < names.length; i++) {
+		var o = global[names[i]];
+		add(o, names[i], "");
+		if (o !== null && (typeof o === "object" || typeof o === "function")) {
+			add(o.prototype, names[i], "prototype");
+			add(Object.getPrototypeOf(o), names[i], "inherited");
+		}
+	}
+	return paths;
+})(this)`
+
+// builtinPaths are the paths of the builtin objects of the runtimes, and
+// of the sandboxed ones. All the runtimes are copies of the same one, so
+// their builtin objects are found once, which takes much longer than
+// creating a runtime.
+var builtinPaths [2]struct {
+	once  sync.Once
+	paths []builtinPath
+	err   error
+}
+
+// getBuiltinPaths returns the paths of the builtin objects of runtimes
+// sandboxed or not.
+func getBuiltinPaths(sandboxed bool) ([]builtinPath, error) {
+	b := &builtinPaths[0]
+	if sandboxed {
+		b = &builtinPaths[1]
+	}
+
+	b.once.Do(func() {
+		rt := otto.New()
+		if sandboxed {
+			sandbox(rt)
+		}
+
+		var v otto.Value
+		if v, b.err = rt.Run(builtinPathsScript); b.err != nil {
+			return
+		}
+
+		var exported interface{}
+		if exported, b.err = v.Export(); b.err != nil {
+			return
+		}
+
+		// arrays of arrays are exported as such
+		var paths [][]interface{}
+		switch exported := exported.(type) {
+		case [][]interface{}:
+			paths = exported
+		case []interface{}:
+			for _, p := range exported {
+				fields, _ := p.([]interface{})
+				paths = append(paths, fields)
+			}
+		}
+
+		b.paths = make([]builtinPath, len(paths))
+		for i, fields := range paths {
+			if len(fields) != 5 {
+				b.err = fmt.Errorf("invalid builtin path: %v", fields)
+				return
+			}
+
+			path := &b.paths[i]
+			path.global, _ = fields[0].(string)
+			path.step, _ = fields[1].(string)
+			path.names = toStrings(fields[2])
+			if path.properties, b.err = toInteger(fields[3]); b.err != nil {
+				return
+			}
+			if path.enumerable, b.err = toInteger(fields[4]); b.err != nil {
+				return
+			}
+		}
+	})
+	return b.paths, b.err
+}
+
+// snapshot records the builtin objects of the runtime along with the values
+// of their properties. Prototypes of objects can't be replaced in ES5, so
+// only their properties are recorded.
+func (r *jsRuntime) snapshot(sandboxed bool) error {
+	paths, err := getBuiltinPaths(sandboxed)
+	if err != nil {
+		return err
+	}
+
+	getPrototypeOf, err := r.Run("Object.getPrototypeOf")
+	if err != nil {
+		return err
+	}
+
+	r.builtinObjects = make([]builtinObject, len(paths))
+	for i, path := range paths {
+		v := r.global.Value()
+		if path.global != "" {
+			if v, err = r.global.Get(path.global); err != nil {
+				return err
+			}
+		}
+
+		switch path.step {
+		case "prototype":
+			v, err = v.Object().Get("prototype")
+		case "inherited":
+			v, err = getPrototypeOf.Call(otto.NullValue(), v)
+		}
+		if err != nil {
+			return err
+		}
+		if !v.IsObject() {
+			return fmt.Errorf("builtin object not found: %s %s", path.global, path.step)
+		}
+
+		o := &r.builtinObjects[i]
+		o.object = v.Object()
+		o.properties = path.properties
+		o.enumerable = int(path.enumerable)
+		o.names = path.names
+		o.values = make([]otto.Value, len(o.names))
+		for j, name := range o.names {
+			if o.values[j], err = o.object.Get(name); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func toInteger(exported interface{}) (int64, error) {
+	v, err := otto.ToValue(exported)
+	if err != nil {
+		return 0, err
+	}
+	return v.ToInteger()
+}
+
+// builtinsCheck is how the builtins of a runtime are checked to find out
+// whether an evaluation modified them.
+type builtinsCheck int
+
+const (
+	// skipBuiltins doesn't check them, for evaluations that can't modify
+	// any builtin.
+	skipBuiltins builtinsCheck = iota
+	// checkBuiltins checks the values of the builtins and the properties
+	// of the objects reachable from them, and whether properties were
+	// added to those objects by assigning them.
+	checkBuiltins
+	// checkAllBuiltins also checks whether properties were added to those
+	// objects by defining them, which takes much longer.
+	checkAllBuiltins
+)
+
+// builtinsModified returns whether any builtin global was replaced or
+// removed, or any object reachable from them was modified, as far as the
+// check can tell. Globals added by scripts are not builtins, and clean
+// removes them.
+func (r *jsRuntime) builtinsModified(check builtinsCheck) bool {
+	if check == skipBuiltins {
+		return false
+	}
+
+	if r.builtinObjects[0].changed() {
+		return true
+	}
+
+	for _, o := range r.builtinObjects[1:] {
+		if o.modified(r.names, check == checkAllBuiltins) {
+			return true
+		}
+	}
+	return false
+}
+
+// isBuiltinGlobal returns whether the name is the name of a builtin global
+// that can be changed.
+func isBuiltinGlobal(name string) bool {
+	paths, err := getBuiltinPaths(false)
+	if err != nil {
+		return true
+	}
+
+	for _, global := range paths[0].names {
+		if name == global {
+			return true
+		}
+	}
+	return false
+}
+
+// mutators are the names of the builtin functions that modify the objects
+// they are given or are called on, and whether they define properties.
+var mutators = map[string]builtinsCheck{
+	"defineProperty":    checkAllBuiltins,
+	"defineProperties":  checkAllBuiltins,
+	"freeze":            checkBuiltins,
+	"seal":              checkBuiltins,
+	"preventExtensions": checkBuiltins,
+	"push":              checkBuiltins,
+	"pop":               checkBuiltins,
+	"shift":             checkBuiltins,
+	"unshift":           checkBuiltins,
+	"splice":            checkBuiltins,
+	"sort":              checkBuiltins,
+	"reverse":           checkBuiltins,
+	"__proto__":         checkBuiltins,
+}
+
+// builtinsCheckOf returns how to check the builtins after evaluating the
+// given script, depending on what it may modify. Checking them takes longer
+// than running most scripts, so they are only checked if the script may
+// modify them, and properties defined with defineProperty, which take even
+// longer to check, only if the script may define them. It errs on the side
+// of caution: scripts that assign or delete properties or builtin globals,
+// use the builtin functions that modify objects or may reach them through
+// computed properties, or run code that is not known beforehand, may modify
+// builtins even if they actually don't.
+func builtinsCheckOf(body string) (check builtinsCheck) {
+	program, err := parser.ParseFile(nil, "", body, 0)
+	if err != nil {
+		return checkAllBuiltins
+	}
+
+	defer func() {
+		// nodes the walker doesn't know about may do anything
+		if recover() != nil {
+			check = checkAllBuiltins
+		}
+	}()
+
+	v := new(builtinsVisitor)
+	ast.Walk(v, program)
+	return v.check
+}
+
+// builtinsVisitor looks for anything that may modify builtins in a script.
+type builtinsVisitor struct {
+	check builtinsCheck
+}
+
+func (v *builtinsVisitor) Enter(n ast.Node) ast.Visitor {
+	switch n := n.(type) {
+	case *ast.AssignExpression:
+		v.modifies(modifiable(n.Left))
+	case *ast.UnaryExpression:
+		switch n.Operator {
+		case token.DELETE, token.INCREMENT, token.DECREMENT:
+			v.modifies(modifiable(n.Operand))
+		}
+	case *ast.ForInStatement:
+		if e, ok := n.Into.(*ast.VariableExpression); ok {
+			v.modifies(isBuiltinGlobal(e.Name))
+		} else {
+			v.modifies(modifiable(n.Into))
+		}
+	case *ast.VariableExpression:
+		v.modifies(isBuiltinGlobal(n.Name))
+	case *ast.FunctionLiteral:
+		v.modifies(n.Name != nil && isBuiltinGlobal(n.Name.Name))
+	case *ast.DotExpression:
+		v.requires(mutators[n.Identifier.Name])
+	case *ast.BracketExpression:
+		switch member := n.Member.(type) {
+		case *ast.NumberLiteral:
+		case *ast.StringLiteral:
+			v.requires(mutators[member.Value])
+		default:
+			v.requires(checkAllBuiltins)
+		}
+	case *ast.Identifier:
+		if n != nil && (n.Name == "eval" || n.Name == "Function") {
+			v.requires(checkAllBuiltins)
+		}
+	case *ast.WithStatement:
+		v.requires(checkAllBuiltins)
+	}
+
+	if v.check == checkAllBuiltins {
+		return nil
+	}
+	return v
+}
+
+func (v *builtinsVisitor) Exit(ast.Node) {}
+
+func (v *builtinsVisitor) modifies(modifies bool) {
+	if modifies {
+		v.requires(checkBuiltins)
+	}
+}
+
+func (v *builtinsVisitor) requires(check builtinsCheck) {
+	if check > v.check {
+		v.check = check
+	}
+}
+
+// modifiable returns whether modifying the given expression may modify a
+// builtin, which is the case for all of them but the identifiers of
+// variables that are not builtin globals.
+func modifiable(e ast.Expression) bool {
+	id, ok := e.(*ast.Identifier)
+	return !ok || isBuiltinGlobal(id.Name)
+}
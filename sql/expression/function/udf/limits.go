@@ -0,0 +1,132 @@
+package udf
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/src-d/go-mysql-server/sql"
+	errors "gopkg.in/src-d/go-errors.v1"
+)
+
+const (
+	// TimeoutKey is the session variable with the maximum number of
+	// milliseconds a single call to a script UDF can take. Zero means there
+	// is no limit.
+	TimeoutKey = "script_udf_timeout"
+	// MaxCallsKey is the session variable with the maximum number of times
+	// each script UDF can be called in a query. Zero means there is no
+	// limit.
+	MaxCallsKey = "script_udf_max_calls"
+	// TransposeKey is the session variable with the way the results of
+	// several transposed aggregations in a query are combined into rows:
+	// TransposeZip, the default, or TransposeCross.
+	TransposeKey = "script_udf_transpose"
+)
+
+const (
+	// TransposeZip combines the results of transposed aggregations
+	// element by element, so there are as many rows as elements in the
+	// longest result.
+	TransposeZip = "zip"
+	// TransposeCross combines every element of the result of each
+	// transposed aggregation with every element of the others.
+	TransposeCross = "cross"
+)
+
+var (
+	// ErrScriptTimeout is returned when a call to a script UDF takes longer
+	// than allowed.
+	ErrScriptTimeout = errors.NewKind(
+		"script UDF %s was aborted after %s, try increasing @@" + TimeoutKey + " to a larger value",
+	)
+
+	// ErrScriptMaxCalls is returned when a script UDF is called more times
+	// than allowed in a query.
+	ErrScriptMaxCalls = errors.NewKind(
+		"script UDF %s was aborted after %d calls, try increasing @@" + MaxCallsKey + " to a larger value",
+	)
+
+	// ErrInvalidTransposeMode is returned when the way transposed
+	// aggregations are combined is not a valid one.
+	ErrInvalidTransposeMode = errors.NewKind(
+		"invalid value for @@" + TransposeKey + ": %v, expected " + TransposeZip + " or " + TransposeCross,
+	)
+)
+
+// errTimeout is returned by script instances when the evaluation takes
+// longer than the timeout. Scriptable expressions turn it into
+// ErrScriptTimeout, as only they know the UDF being evaluated.
+var errTimeout = fmt.Errorf("script evaluation timed out")
+
+// EvalOptions configure the evaluation of a script.
+type EvalOptions struct {
+	// Timeout is the maximum time the evaluation can take. Zero means there
+	// is no limit.
+	Timeout time.Duration
+	// Sandboxed is true if the script is untrusted and the globals it could
+	// use to reach outside of the script, or to run code that is not in the
+	// script, must be disabled. Sandboxed scripts have no memory limit, so
+	// they should also be run with a timeout.
+	Sandboxed bool
+}
+
+// limits are the limits of the script UDFs configured in a session. Whether
+// scripts are sandboxed is not up to the session, but to whoever created
+// them, so it's set by the scripts themselves.
+type limits struct {
+	EvalOptions
+	maxCalls int64
+}
+
+func sessionLimits(ctx *sql.Context) (limits, error) {
+	var l limits
+
+	timeout, err := sessionInt64(ctx, TimeoutKey)
+	if err != nil {
+		return l, err
+	}
+	l.Timeout = time.Duration(timeout) * time.Millisecond
+
+	l.maxCalls, err = sessionInt64(ctx, MaxCallsKey)
+	if err != nil {
+		return l, err
+	}
+
+	return l, nil
+}
+
+func sessionInt64(ctx *sql.Context, key string) (int64, error) {
+	_, v := ctx.Session.Get(key)
+	if v == nil {
+		return 0, nil
+	}
+
+	n, err := sql.Int64.Convert(v)
+	if err != nil {
+		return 0, err
+	}
+
+	return n.(int64), nil
+}
+
+// TransposeMode returns the way the results of transposed aggregations are
+// combined in the session, either TransposeZip or TransposeCross.
+func TransposeMode(ctx *sql.Context) (string, error) {
+	_, v := ctx.Session.Get(TransposeKey)
+	if v == nil {
+		return TransposeZip, nil
+	}
+
+	mode, err := sql.Text.Convert(v)
+	if err != nil {
+		return "", err
+	}
+
+	switch m := strings.ToLower(mode.(string)); m {
+	case TransposeZip, TransposeCross:
+		return m, nil
+	default:
+		return "", ErrInvalidTransposeMode.New(v)
+	}
+}
@@ -0,0 +1,218 @@
+package udf
+
+import (
+	"strings"
+	"unicode/utf8"
+
+	errors "gopkg.in/src-d/go-errors.v1"
+)
+
+var (
+	// ErrInvalidMacro is returned when a macro in a query can't be read,
+	// such as when it's not closed.
+	ErrInvalidMacro = errors.NewKind("invalid macro at line %d, column %d: %s")
+
+	// ErrUnknownAggregator is returned when a macro starts with an
+	// aggregator prefix that does not exist.
+	ErrUnknownAggregator = errors.NewKind(
+		"unknown aggregator %s@ in macro at line %d, column %d, expected one of L__, LF_, L_T, LFT, S__, SF_, S_T, SFT, AGG or AGT",
+	)
+)
+
+const (
+	macroStart = "<?"
+	macroEnd   = "?>"
+)
+
+// macro is a macro found in a query.
+type macro struct {
+	// start and end are the offsets of the macro in the query, including
+	// its delimiters.
+	start, end int
+	// body is the script between the delimiters.
+	body string
+}
+
+// findMacros returns all the macros in the query. Macros are only looked
+// for outside of SQL strings, quoted identifiers and comments, and they end
+// at the first ?> outside of the strings and comments of the script.
+func findMacros(query string) ([]macro, error) {
+	var macros []macro
+	for i := 0; i < len(query); {
+		switch {
+		case strings.HasPrefix(query[i:], macroStart):
+			end, err := macroBodyEnd(query, i+len(macroStart))
+			if err != nil {
+				return nil, err
+			}
+			macros = append(macros, macro{
+				start: i,
+				end:   end + len(macroEnd),
+				body:  query[i+len(macroStart) : end],
+			})
+			i = end + len(macroEnd)
+		default:
+			i = skipSQLToken(query, i)
+		}
+	}
+	return macros, nil
+}
+
+// skipSQLToken returns the position after the SQL string, quoted identifier
+// or comment starting at the given position, or the next position if there
+// is none. Unterminated strings and comments are left to the SQL parser.
+func skipSQLToken(query string, i int) int {
+	switch c := query[i]; {
+	case c == '\'' || c == '"' || c == '`':
+		quote := c
+		for i++; i < len(query); i++ {
+			switch {
+			case query[i] == '\\' && quote != '`':
+				i++
+			case query[i] == quote:
+				if i+1 < len(query) && query[i+1] == quote {
+					i++
+					continue
+				}
+				return i + 1
+			}
+		}
+		return len(query)
+	case strings.HasPrefix(query[i:], "-- "):
+		if end := strings.IndexByte(query[i:], '\n'); end >= 0 {
+			return i + end + 1
+		}
+		return len(query)
+	case strings.HasPrefix(query[i:], "/*"):
+		if end := strings.Index(query[i+2:], "*/"); end >= 0 {
+			return i + 2 + end + 2
+		}
+		return len(query)
+	default:
+		return i + 1
+	}
+}
+
+// macroBodyEnd returns the position of the ?> closing the macro whose body
+// starts at the given position.
+func macroBodyEnd(query string, start int) (int, error) {
+	for i := start; i < len(query); {
+		if strings.HasPrefix(query[i:], macroEnd) {
+			return i, nil
+		}
+
+		next, err := skipScriptToken(query, i)
+		if err != nil {
+			return 0, err
+		}
+		i = next
+	}
+
+	line, col := position(query, start-len(macroStart))
+	return 0, ErrInvalidMacro.New(line, col, "missing "+macroEnd)
+}
+
+// skipScriptToken returns the position after the script string or comment
+// starting at the given position, or the next position if there is none.
+// Line comments end at the end of the line or the end of the macro.
+func skipScriptToken(script string, i int) (int, error) {
+	switch c := script[i]; {
+	case c == '\'' || c == '"' || c == '`':
+		for j := i + 1; j < len(script); j++ {
+			switch script[j] {
+			case '\\':
+				j++
+			case c:
+				return j + 1, nil
+			}
+		}
+		line, col := position(script, i)
+		return 0, ErrInvalidMacro.New(line, col, "unterminated string")
+	case strings.HasPrefix(script[i:], "//"):
+		for j := i + 2; j < len(script); j++ {
+			if script[j] == '\n' || strings.HasPrefix(script[j:], macroEnd) {
+				return j, nil
+			}
+		}
+		return len(script), nil
+	case strings.HasPrefix(script[i:], "/*"):
+		if end := strings.Index(script[i+2:], "*/"); end >= 0 {
+			return i + 2 + end + 2, nil
+		}
+		line, col := position(script, i)
+		return 0, ErrInvalidMacro.New(line, col, "unterminated comment")
+	default:
+		return i + 1, nil
+	}
+}
+
+// indexScript returns the position of the first c in the script outside of
+// its strings and comments, or -1 if there is none.
+func indexScript(script string, c byte) int {
+	for i := 0; i < len(script); {
+		if script[i] == c {
+			return i
+		}
+
+		next, err := skipScriptToken(script, i)
+		if err != nil {
+			return -1
+		}
+		i = next
+	}
+	return -1
+}
+
+// replaceParams replaces the parameters of the script, such as
+// @{mytable.x}, with their names, and returns the script and the names in
+// order of appearance. Parameters in strings and comments are left as they
+// are.
+func replaceParams(script string) (string, []string) {
+	var (
+		buf     strings.Builder
+		names   []string
+		seen    = make(map[string]bool)
+		written int
+	)
+
+	for i := 0; i < len(script); {
+		if !strings.HasPrefix(script[i:], "@{") {
+			next, err := skipScriptToken(script, i)
+			if err != nil {
+				break
+			}
+			i = next
+			continue
+		}
+
+		end := strings.IndexByte(script[i:], '}')
+		name := ""
+		if end > 0 {
+			name = script[i+2 : i+end]
+		}
+		if name == "" || strings.ContainsAny(name, "@{()^") {
+			i += 2
+			continue
+		}
+
+		buf.WriteString(script[written:i])
+		buf.WriteString(name)
+		if !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+
+		written = i + end + 1
+		i = written
+	}
+
+	buf.WriteString(script[written:])
+	return buf.String(), names
+}
+
+// position returns the line and the column of the given offset, both
+// starting at 1.
+func position(s string, offset int) (line, column int) {
+	lineStart := strings.LastIndexByte(s[:offset], '\n') + 1
+	return strings.Count(s[:offset], "\n") + 1, utf8.RuneCountInString(s[lineStart:offset]) + 1
+}
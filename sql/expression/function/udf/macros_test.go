@@ -0,0 +1,124 @@
+package udf
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	errors "gopkg.in/src-d/go-errors.v1"
+)
+
+func TestFindAllUDFStrings(t *testing.T) {
+	testCases := []struct {
+		query    string
+		expected [][]string
+	}{
+		{
+			"SELECT <? @{t.x} + 1 ?> FROM t",
+			[][]string{{"<? @{t.x} + 1 ?>", " @{t.x} + 1 "}},
+		},
+		{
+			"SELECT <? 1 ?>, <? 2 ?> FROM t",
+			[][]string{{"<? 1 ?>", " 1 "}, {"<? 2 ?>", " 2 "}},
+		},
+		{
+			"SELECT * FROM t WHERE doc = '<?xml version=\"1.0\"?>' AND b = \"<? ?>\" AND `<?` = 1",
+			[][]string{},
+		},
+		{
+			"SELECT 'it''s <? no ?>', 'it\\'s <? no ?>' FROM t",
+			[][]string{},
+		},
+		{
+			"SELECT 1 /* <? no ?> */ -- <? no ?>\nFROM t",
+			[][]string{},
+		},
+		{
+			`SELECT <? "?>" + '?>' + "\"?>" ?> FROM t`,
+			[][]string{{`<? "?>" + '?>' + "\"?>" ?>`, ` "?>" + '?>' + "\"?>" `}},
+		},
+		{
+			"SELECT <? 1 // a comment ?> FROM t",
+			[][]string{{"<? 1 // a comment ?>", " 1 // a comment "}},
+		},
+		{
+			"SELECT <? 1 /* ?> */ ?> FROM t",
+			[][]string{{"<? 1 /* ?> */ ?>", " 1 /* ?> */ "}},
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.query, func(t *testing.T) {
+			require := require.New(t)
+			macros, err := FindAllUDFStrings(tt.query)
+			require.NoError(err)
+			require.Equal(tt.expected, macros)
+		})
+	}
+}
+
+func TestFindAllUDFStringsErrors(t *testing.T) {
+	testCases := []struct {
+		query string
+		err   string
+	}{
+		{"SELECT <? 1 FROM t", "invalid macro at line 1, column 8: missing ?>"},
+		{"SELECT 1,\n  <? 'a ?> FROM t", "invalid macro at line 2, column 6: unterminated string"},
+		{"SELECT <? /* ?> FROM t", "invalid macro at line 1, column 11: unterminated comment"},
+		{"SELECT 'ñ', <? 1 FROM t", "invalid macro at line 1, column 13: missing ?>"},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.query, func(t *testing.T) {
+			require := require.New(t)
+			_, err := FindAllUDFStrings(tt.query)
+			require.True(ErrInvalidMacro.Is(err))
+			require.EqualError(err, tt.err)
+		})
+	}
+}
+
+func TestMacroProcessor_Lexer(t *testing.T) {
+	require := require.New(t)
+
+	var c *ScriptCache
+	q, udfs, err := c.MacroProcessor(
+		"SELECT <? @{t.x} + '@{t.y}' + @{t.z} + @{t.x} ?> FROM t WHERE a = '<? 1 ?>'", 0, "js",
+	)
+	require.NoError(err)
+	require.Equal("SELECT _auto_1_udf_(t.x,t.z) FROM t WHERE a = '<? 1 ?>'", q)
+	require.Equal(" t.x + '@{t.y}' + t.z + t.x ", udfs[0].Script.Body())
+
+	_, udfs, err = c.MacroProcessor(`SELECT <?AGG@ "#" # $_ + "#" # $_ + $PARTIAL ?> FROM t`, 0, "js")
+	require.NoError(err)
+	require.Equal(`@ "#" #`, udfs[0].initial)
+	require.Equal(` $_ + "#" `, udfs[0].Script.Body())
+	require.Equal(` $_ + $PARTIAL `, udfs[0].Combiner.Body())
+
+	testCases := []struct {
+		query string
+		kind  *errors.Kind
+		err   string
+	}{
+		{
+			"SELECT 1,\n <?XYZ@ @{t.x} ?> FROM t",
+			ErrUnknownAggregator,
+			"unknown aggregator XYZ@ in macro at line 2, column 2, expected one of L__, LF_, L_T, LFT, S__, SF_, S_T, SFT, AGG or AGT",
+		},
+		{
+			"SELECT <?:INT64 LT_@ @{t.x} ?> FROM t",
+			ErrUnknownAggregator,
+			"unknown aggregator LT_@ in macro at line 1, column 8, expected one of L__, LF_, L_T, LFT, S__, SF_, S_T, SFT, AGG or AGT",
+		},
+		{
+			"SELECT <?AGG@ 0 ?> FROM t",
+			ErrInvalidMacro,
+			"invalid macro at line 1, column 8: missing # after the initial value of the aggregator",
+		},
+	}
+
+	for _, tt := range testCases {
+		_, _, err := c.MacroProcessor(tt.query, 0, "js")
+		require.True(tt.kind.Is(err), tt.query)
+		require.EqualError(err, tt.err)
+	}
+}
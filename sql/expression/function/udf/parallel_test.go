@@ -0,0 +1,169 @@
+package udf_test
+
+import (
+	"fmt"
+	"runtime"
+	"testing"
+	"time"
+
+	sqle "github.com/src-d/go-mysql-server"
+	"github.com/src-d/go-mysql-server/memory"
+	"github.com/src-d/go-mysql-server/sql"
+	"github.com/src-d/go-mysql-server/sql/analyzer"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	benchPartitions = 8
+	benchRows       = 2000
+)
+
+func newParallelEngine(t testing.TB, parallelism, partitions int) *sqle.Engine {
+	table := memory.NewPartitionedTable("t", sql.Schema{
+		{Name: "n", Type: sql.Int64, Source: "t"},
+	}, partitions)
+
+	ctx := sql.NewEmptyContext()
+	for i := 0; i < benchRows; i++ {
+		require.NoError(t, table.Insert(ctx, sql.NewRow(int64(i))))
+	}
+
+	db := memory.NewDatabase("db")
+	db.AddTable("t", table)
+
+	catalog := sql.NewCatalog()
+	e := sqle.New(catalog, analyzer.NewBuilder(catalog).WithParallelism(parallelism).Build(), nil)
+	e.AddDatabase(db)
+	return e
+}
+
+const parallelQuery = `SELECT <? var s = 0; for (var i = 0; i < 100; i++) { s += @{t.n} * i; } s ?> FROM t`
+
+func TestParallelScripts(t *testing.T) {
+	require := require.New(t)
+
+	var expected []sql.Row
+	for _, parallelism := range []int{1, 4} {
+		e := newParallelEngine(t, parallelism, benchPartitions)
+		_, iter, err := e.SQuery(sql.NewEmptyContext(), parallelQuery, "js")
+		require.NoError(err)
+		rows, err := sql.RowIterToRows(iter)
+		require.NoError(err)
+		require.Len(rows, benchRows)
+
+		if expected == nil {
+			expected = rows
+		} else {
+			require.ElementsMatch(expected, rows)
+		}
+	}
+}
+
+func TestParallelScriptAggregates(t *testing.T) {
+	require := require.New(t)
+
+	query := `SELECT t.n % 3 AS g,
+		<?:INT64 AGG@ 0 # $_ + @{t.n} # $_ + $PARTIAL ?> AS total,
+		<?:INT64 AGG@ 0 # $_ + 1 # $_ + $PARTIAL ?> AS count,
+		<?S__@ @{t.n} % 2 ?> AS parities
+	FROM t GROUP BY t.n % 3 ORDER BY g`
+
+	var expected []sql.Row
+	for g := int64(0); g < 3; g++ {
+		var total, count int64
+		for n := int64(0); n < benchRows; n++ {
+			if n%3 == g {
+				total += n
+				count++
+			}
+		}
+		expected = append(expected, sql.NewRow(g, total, count))
+	}
+
+	for _, parallelism := range []int{1, 4, 16} {
+		e := newParallelEngine(t, parallelism, 32)
+
+		// The partial results of each partition are merged with the
+		// combiners.
+		_, iter, err := e.SQuery(sql.NewEmptyContext(), "DESCRIBE FORMAT=TREE "+query, "js")
+		require.NoError(err)
+		plan, err := sql.RowIterToRows(iter)
+		require.NoError(err)
+		require.Contains(fmt.Sprint(plan), "PartialGroupBy")
+
+		_, iter, err = e.SQuery(sql.NewEmptyContext(), query, "js")
+		require.NoError(err)
+		rows, err := sql.RowIterToRows(iter)
+		require.NoError(err)
+		require.Len(rows, len(expected))
+
+		for i, row := range rows {
+			require.Equal(expected[i], row[:3])
+			require.ElementsMatch([]interface{}{float64(0), float64(1)}, row[3])
+		}
+	}
+}
+
+// BenchmarkParallelScripts runs a query with a JS macro on a partitioned
+// table with different parallelism levels, and reports the speedup of each
+// level over running the query without parallelism. As long as there are
+// enough CPUs, the time per query decreases linearly with the parallelism,
+// up to the number of partitions of the table, so the benchmark fails if
+// the speedup is less than half of that.
+func BenchmarkParallelScripts(b *testing.B) {
+	levels := []int{1, 2, 4, 8}
+	perQuery := make(map[int]time.Duration, len(levels))
+
+	for _, parallelism := range levels {
+		parallelism := parallelism
+		b.Run(fmt.Sprintf("parallelism=%d", parallelism), func(b *testing.B) {
+			require := require.New(b)
+			e := newParallelEngine(b, parallelism, benchPartitions)
+
+			b.ResetTimer()
+			start := time.Now()
+			for i := 0; i < b.N; i++ {
+				_, iter, err := e.SQuery(sql.NewEmptyContext(), parallelQuery, "js")
+				require.NoError(err)
+				rows, err := sql.RowIterToRows(iter)
+				require.NoError(err)
+				require.Len(rows, benchRows)
+			}
+			b.StopTimer()
+
+			perQuery[parallelism] = time.Since(start) / time.Duration(b.N)
+			if sequential, ok := perQuery[1]; ok {
+				b.ReportMetric(float64(sequential)/float64(perQuery[parallelism]), "speedup")
+			}
+		})
+	}
+
+	sequential, ok := perQuery[1]
+	if !ok {
+		return
+	}
+
+	for _, parallelism := range levels {
+		elapsed, ok := perQuery[parallelism]
+		if !ok {
+			continue
+		}
+
+		expected := minInt(parallelism, runtime.GOMAXPROCS(0), benchPartitions)
+		speedup := float64(sequential) / float64(elapsed)
+		b.Logf("parallelism=%d: %s per query, %.2fx speedup, %dx expected", parallelism, elapsed, speedup, expected)
+		if speedup < float64(expected)/2 {
+			b.Errorf("parallelism=%d: %.2fx speedup is less than half of the expected %dx", parallelism, speedup, expected)
+		}
+	}
+}
+
+func minInt(values ...int) int {
+	min := values[0]
+	for _, v := range values[1:] {
+		if v < min {
+			min = v
+		}
+	}
+	return min
+}
@@ -0,0 +1,58 @@
+package udf
+
+import (
+	"strings"
+
+	"github.com/src-d/go-mysql-server/sql"
+	errors "gopkg.in/src-d/go-errors.v1"
+)
+
+var (
+	// ErrUnknownReturnType is returned when the return type of a script UDF
+	// is not one of the supported types.
+	ErrUnknownReturnType = errors.NewKind("unknown return type for script UDF: %s")
+
+	// ErrInvalidReturnValue is returned when a result of a script UDF can't
+	// be converted to its return type.
+	ErrInvalidReturnValue = errors.NewKind("script UDF %s returned %#v, which is not a valid %s: %s")
+)
+
+// returnTypes are the types script UDFs can return, by name.
+var returnTypes = map[string]sql.Type{
+	"INT8":      sql.Int8,
+	"TINYINT":   sql.Int8,
+	"INT16":     sql.Int16,
+	"SMALLINT":  sql.Int16,
+	"INT32":     sql.Int32,
+	"INT":       sql.Int32,
+	"INTEGER":   sql.Int32,
+	"INT64":     sql.Int64,
+	"BIGINT":    sql.Int64,
+	"UINT8":     sql.Uint8,
+	"UINT16":    sql.Uint16,
+	"UINT32":    sql.Uint32,
+	"UINT64":    sql.Uint64,
+	"FLOAT32":   sql.Float32,
+	"FLOAT":     sql.Float32,
+	"FLOAT64":   sql.Float64,
+	"DOUBLE":    sql.Float64,
+	"BOOLEAN":   sql.Boolean,
+	"BOOL":      sql.Boolean,
+	"TEXT":      sql.Text,
+	"VARCHAR":   sql.Text,
+	"BLOB":      sql.Blob,
+	"DATE":      sql.Date,
+	"DATETIME":  sql.Datetime,
+	"TIMESTAMP": sql.Timestamp,
+	"JSON":      sql.JSON,
+}
+
+// ReturnType returns the type with the given name that script UDFs can
+// return. Names are case insensitive.
+func ReturnType(name string) (sql.Type, error) {
+	typ, ok := returnTypes[strings.ToUpper(name)]
+	if !ok {
+		return nil, ErrUnknownReturnType.New(name)
+	}
+	return typ, nil
+}
@@ -0,0 +1,65 @@
+package udf
+
+import (
+	lru "github.com/hashicorp/golang-lru"
+	"github.com/src-d/go-mysql-server/sql"
+)
+
+// DefaultScriptCacheSize is the number of scripts kept by the script cache
+// of an engine.
+const DefaultScriptCacheSize = 512
+
+// ScriptCache keeps the most recently used script instances by the hash of
+// their language and body, so queries with the same macros reuse the
+// scripts already compiled instead of compiling them again. It is safe to
+// use concurrently, and a nil cache does not cache anything.
+type ScriptCache struct {
+	cache *lru.Cache
+}
+
+type scriptCacheKey struct {
+	lang string
+	body string
+}
+
+// NewScriptCache creates a new cache keeping at most size scripts.
+func NewScriptCache(size int) *ScriptCache {
+	cache, _ := lru.New(size)
+	return &ScriptCache{cache}
+}
+
+// ScriptInstance returns the cached instance of the script with the given
+// language and body, creating it if it's not cached yet.
+func (c *ScriptCache) ScriptInstance(lang, body string) ScriptInstance {
+	if c == nil {
+		return GetScriptInstance(lang, body)
+	}
+
+	key := sql.CacheKey(scriptCacheKey{lang, body})
+	if v, ok := c.cache.Get(key); ok {
+		// Make sure it's not a hash collision.
+		if si := v.(ScriptInstance); si.Body() == body {
+			return si
+		}
+	}
+
+	si := GetScriptInstance(lang, body)
+	c.cache.Add(key, si)
+	return si
+}
+
+// Len returns the number of scripts in the cache.
+func (c *ScriptCache) Len() int {
+	if c == nil {
+		return 0
+	}
+	return c.cache.Len()
+}
+
+// MacroProcessor is like the MacroProcessor function, but the scripts of
+// the macros are taken from the cache, and invalid macros are reported.
+func (c *ScriptCache) MacroProcessor(query string, funcNumStart int, langDialect string) (string, []ScriptUDF, error) {
+	return processMacros(query, funcNumStart, func(body string) ScriptInstance {
+		return c.ScriptInstance(langDialect, body)
+	})
+}
@@ -0,0 +1,44 @@
+package udf
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestScriptCache(t *testing.T) {
+	require := require.New(t)
+
+	c := NewScriptCache(2)
+	si := c.ScriptInstance("js", "x + 1")
+	require.True(si == c.ScriptInstance("js", "x + 1"))
+	require.False(si == c.ScriptInstance("js", "x + 2"))
+	require.False(si == c.ScriptInstance("expr", "x + 1"))
+	require.Equal(2, c.Len())
+
+	v, err := c.ScriptInstance("expr", "x + 1").ScriptEval(map[string]interface{}{"x": 1})
+	require.NoError(err)
+	require.Equal(2, v)
+
+	var nilCache *ScriptCache
+	require.False(nilCache.ScriptInstance("js", "1") == nilCache.ScriptInstance("js", "1"))
+	require.Equal(0, nilCache.Len())
+}
+
+func TestScriptCache_MacroProcessor(t *testing.T) {
+	require := require.New(t)
+
+	c := NewScriptCache(DefaultScriptCacheSize)
+	s := "SELECT <? @{mytable.x} + 1 ?>, <? @{mytable.y} + 1 ?> FROM mytable"
+	q1, udfs1, err := c.MacroProcessor(s, 0, "js")
+	require.NoError(err)
+	q2, udfs2, err := c.MacroProcessor(s, 0, "js")
+	require.NoError(err)
+	require.Equal(q1, q2)
+	require.Len(udfs1, 2)
+	require.Len(udfs2, 2)
+	require.True(udfs1[0].Script == udfs2[0].Script)
+	require.True(udfs1[1].Script == udfs2[1].Script)
+	require.False(udfs1[0].Script == udfs1[1].Script)
+	require.Equal(2, c.Len())
+}
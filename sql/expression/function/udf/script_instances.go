@@ -1,6 +1,11 @@
 package udf
 
 import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
 	"github.com/antonmedv/expr"
 	"github.com/antonmedv/expr/vm"
 	"github.com/robertkrimen/otto"
@@ -14,11 +19,19 @@ type ScriptInstance interface {
 		interface{},
 		error,
 	)
+	// ScriptEvalContext is like ScriptEval, but the evaluation is stopped
+	// when the context is cancelled or it exceeds the limits in the options.
+	ScriptEvalContext(ctx context.Context, opts EvalOptions, scriptEnvironment map[string]interface{}) (
+		interface{},
+		error,
+	)
 }
 
 type ExprScriptInstance struct {
 	body    string
+	once    sync.Once
 	program *vm.Program
+	err     error
 }
 
 func (exprInstance *ExprScriptInstance) EvalFromString(expressionString string) (interface{}, error) {
@@ -26,13 +39,23 @@ func (exprInstance *ExprScriptInstance) EvalFromString(expressionString string)
 }
 
 func (exprInstance *ExprScriptInstance) ScriptEval(scriptEnvironment map[string]interface{}) (interface{}, error) {
-	if exprInstance.program == nil {
-		p, e := expr.Compile(exprInstance.body)
-		if e != nil {
-			return nil, e
-		}
-		exprInstance.program = p
+	return exprInstance.ScriptEvalContext(context.Background(), EvalOptions{}, scriptEnvironment)
+}
+
+// ScriptEvalContext implements the ScriptInstance interface. Expressions
+// have no loops nor access to anything but their environment, so they are
+// always run to completion once started.
+func (exprInstance *ExprScriptInstance) ScriptEvalContext(ctx context.Context, opts EvalOptions, scriptEnvironment map[string]interface{}) (interface{}, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	exprInstance.once.Do(func() {
+		exprInstance.program, exprInstance.err = expr.Compile(exprInstance.body)
+	})
+	if exprInstance.err != nil {
+		return nil, exprInstance.err
 	}
+	// every run has its own vm, so the program can be run by many at once
 	return expr.Run(exprInstance.program, scriptEnvironment)
 }
 
@@ -40,14 +63,364 @@ func (exprInstance *ExprScriptInstance) Dialect() string { return "expr" }
 
 func (exprInstance *ExprScriptInstance) Body() string { return exprInstance.body }
 
+// JSScriptInstance runs scripts in otto runtimes. A runtime can't be used by
+// many at once, and expressions are evaluated concurrently when partitions
+// are iterated in parallel, so every evaluation takes a runtime from a pool
+// of copies of the same base runtime. The body is compiled only once, and
+// the compiled script is run in all the runtimes. Runtimes are cleaned
+// before going back to the pool, so evaluations, which may be of different
+// sessions, don't see the environment and globals of the previous ones, and
+// discarded if a script modified the builtins they all share.
 type JSScriptInstance struct {
 	body     string
+	mu       sync.Mutex
 	runtTime *otto.Otto
-	script   *otto.Script
+	runtimes sync.Pool
+	// sandboxed are the runtimes of untrusted scripts.
+	sandboxed sync.Pool
+	once      sync.Once
+	script    *otto.Script
+	err       error
+	// builtinsCheck is how builtins are checked after every evaluation,
+	// depending on what the script may modify.
+	builtinsCheck builtinsCheck
+}
+
+func newJSScriptInstance(body string) *JSScriptInstance {
+	return &JSScriptInstance{runtTime: otto.New(), body: body}
+}
+
+// jsRuntime is a runtime of the pool of a JSScriptInstance, along with what
+// is needed to clean its global object once an evaluation is done, so the
+// next one can't see the globals left by it. Scripts may replace any global,
+// so the function that lists the names of the globals is kept here.
+type jsRuntime struct {
+	*otto.Otto
+	global *otto.Object
+	names  otto.Value
+	// builtin are the names of the globals of the runtime before running
+	// anything in it.
+	builtin map[string]bool
+	// builtinObjects are the objects reachable from the builtins before
+	// running anything in the runtime, to find out whether a script modified
+	// them.
+	builtinObjects []builtinObject
+}
+
+func newJSRuntime(rt *otto.Otto, sandboxed bool) (*jsRuntime, error) {
+	global, err := rt.Run("this")
+	if err != nil {
+		return nil, err
+	}
+
+	names, err := rt.Run("Object.getOwnPropertyNames")
+	if err != nil {
+		return nil, err
+	}
+
+	r := &jsRuntime{Otto: rt, global: global.Object(), names: names}
+	globals, err := r.globals()
+	if err != nil {
+		return nil, err
+	}
+
+	r.builtin = make(map[string]bool, len(globals))
+	for _, name := range globals {
+		r.builtin[name] = true
+	}
+
+	if err := r.snapshot(sandboxed); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// globals returns the names of all the globals of the runtime.
+func (r *jsRuntime) globals() ([]string, error) {
+	v, err := r.names.Call(otto.NullValue(), r.global)
+	if err != nil {
+		return nil, err
+	}
+
+	exported, err := v.Export()
+	if err != nil {
+		return nil, err
+	}
+	return toStrings(exported), nil
+}
+
+// toStrings returns the strings of an exported array of strings.
+func toStrings(exported interface{}) []string {
+	switch names := exported.(type) {
+	case []string:
+		return names
+	case []interface{}:
+		var result = make([]string, len(names))
+		for i, name := range names {
+			result[i], _ = name.(string)
+		}
+		return result
+	default:
+		return nil
+	}
+}
+
+// clean removes the values of all the globals that were not in the runtime
+// before running anything in it, such as the environment of the last
+// evaluation and the globals created by scripts. It returns false if any of
+// them can't be removed, or if the given check finds that a builtin or an
+// object reachable from them was modified, in which case the runtime must
+// not be used again.
+func (r *jsRuntime) clean(check builtinsCheck) bool {
+	if r.builtinsModified(check) {
+		return false
+	}
+
+	globals, err := r.globals()
+	if err != nil {
+		return false
+	}
+
+	for _, name := range globals {
+		if r.builtin[name] {
+			continue
+		}
+
+		if err := r.global.Set(name, otto.UndefinedValue()); err != nil {
+			return false
+		}
+
+		if v, err := r.global.Get(name); err != nil || !v.IsUndefined() {
+			return false
+		}
+	}
+	return true
+}
+
+// runtime returns a runtime that is not being used by anyone else. It must
+// be given back with release once it's not needed anymore.
+func (jsScriptInstance *JSScriptInstance) runtime(sandboxed bool) (*jsRuntime, error) {
+	pool := &jsScriptInstance.runtimes
+	if sandboxed {
+		pool = &jsScriptInstance.sandboxed
+	}
+	if rt, ok := pool.Get().(*jsRuntime); ok {
+		return rt, nil
+	}
+
+	// the base runtime is never run, but copying it is not safe either
+	jsScriptInstance.mu.Lock()
+	rt := jsScriptInstance.runtTime.Copy()
+	jsScriptInstance.mu.Unlock()
+	rt.Interrupt = make(chan func(), 1)
+	if sandboxed {
+		sandbox(rt)
+	}
+	return newJSRuntime(rt, sandboxed)
+}
+
+// release gives back a runtime to the pool once the globals left by the
+// evaluation are removed. Runtimes that can't be cleaned are discarded, as
+// well as the ones whose builtins the given check finds were modified.
+func (jsScriptInstance *JSScriptInstance) release(rt *jsRuntime, sandboxed bool, check builtinsCheck) {
+	if !rt.clean(check) {
+		return
+	}
+
+	if sandboxed {
+		jsScriptInstance.sandboxed.Put(rt)
+	} else {
+		jsScriptInstance.runtimes.Put(rt)
+	}
+}
+
+// untrustedGlobals are the globals untrusted scripts can't use, because they
+// allow to run code that is not known beforehand or to reach outside of the
+// runtime.
+var untrustedGlobals = []string{"eval", "Function", "console"}
+
+// sandboxScript hides the Function constructor from the constructor
+// property all the functions inherit, as in [].constructor.constructor, so
+// it can't be reached once the Function global is gone. The property can't
+// be changed back.
+const sandboxScript = `Object.defineProperty(Function.prototype, "constructor", {
+	value: undefined, writable: false, enumerable: false, configurable: false
+});`
+
+// sandbox disables everything untrusted scripts can't use in the given
+// runtime.
+func sandbox(rt *otto.Otto) {
+	if _, err := rt.Run(sandboxScript); err != nil {
+		// the script is always valid, and runtimes are never left
+		// unprotected
+		panic(err)
+	}
+	for _, name := range untrustedGlobals {
+		_ = rt.Set(name, otto.UndefinedValue())
+	}
+}
+
+// interrupt is what runtimes panic with when their evaluation is stopped.
+type interrupt struct{ err error }
+
+// evaluation is an evaluation in a runtime that can be interrupted. It's
+// interrupted at most once, either by its timeout or by the watcher of its
+// context, and never once it's finished.
+type evaluation struct {
+	rt *otto.Otto
+	// state is evaluationRunning until the evaluation is either finished or
+	// interrupted.
+	state int32
+}
+
+const (
+	evaluationRunning int32 = iota
+	evaluationFinished
+	evaluationInterrupted
+)
+
+// interrupt stops the evaluation with the given error if it's still
+// running.
+func (e *evaluation) interrupt(err error) {
+	if atomic.CompareAndSwapInt32(&e.state, evaluationRunning, evaluationInterrupted) {
+		e.rt.Interrupt <- func() { panic(interrupt{err}) }
+	}
+}
+
+// finish marks the evaluation as finished, so it's not interrupted anymore.
+// It returns false if it was interrupted before, in which case the
+// interruption is already in the runtime or about to be.
+func (e *evaluation) finish() bool {
+	return atomic.CompareAndSwapInt32(&e.state, evaluationRunning, evaluationFinished)
+}
+
+// contextWatcher interrupts the evaluations running with a context once
+// it's done. There is a single watcher for each context at a time, so the
+// goroutine waiting for the context is started once per query, not once per
+// evaluation. The watcher is removed once its context is done, which for
+// the contexts of queries happens when they finish.
+type contextWatcher struct {
+	mu          sync.Mutex
+	err         error
+	evaluations map[*evaluation]struct{}
+}
+
+var contextWatchers = struct {
+	sync.Mutex
+	byDone map[<-chan struct{}]*contextWatcher
+}{byDone: make(map[<-chan struct{}]*contextWatcher)}
+
+// watch adds the evaluation to the watcher of the given context, starting
+// one if there is none. It fails if the context is already done.
+func watch(ctx context.Context, e *evaluation) (*contextWatcher, error) {
+	done := ctx.Done()
+
+	contextWatchers.Lock()
+	w, ok := contextWatchers.byDone[done]
+	if !ok {
+		w = &contextWatcher{evaluations: make(map[*evaluation]struct{})}
+		contextWatchers.byDone[done] = w
+		go func() {
+			<-done
+			w.mu.Lock()
+			w.err = ctx.Err()
+			for e := range w.evaluations {
+				e.interrupt(w.err)
+			}
+			w.mu.Unlock()
+
+			contextWatchers.Lock()
+			delete(contextWatchers.byDone, done)
+			contextWatchers.Unlock()
+		}()
+	}
+	contextWatchers.Unlock()
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.err != nil {
+		return nil, w.err
+	}
+	w.evaluations[e] = struct{}{}
+	return w, nil
+}
+
+func (w *contextWatcher) unwatch(e *evaluation) {
+	w.mu.Lock()
+	delete(w.evaluations, e)
+	w.mu.Unlock()
+}
+
+// runInterruptible runs the given function, which evaluates something in
+// the given runtime, until it's done, the context is cancelled or the
+// timeout expires. Runtimes that have been interrupted must not be used
+// again.
+func runInterruptible(
+	ctx context.Context,
+	timeout time.Duration,
+	rt *otto.Otto,
+	run func() (otto.Value, error),
+) (value otto.Value, interrupted bool, err error) {
+	if ctx.Done() == nil && timeout <= 0 {
+		value, err = run()
+		return value, false, err
+	}
+
+	if err := ctx.Err(); err != nil {
+		return otto.Value{}, false, err
+	}
+
+	e := &evaluation{rt: rt}
+	if ctx.Done() != nil {
+		w, err := watch(ctx, e)
+		if err != nil {
+			return otto.Value{}, false, err
+		}
+		defer w.unwatch(e)
+	}
+
+	if timeout > 0 {
+		timer := time.AfterFunc(timeout, func() { e.interrupt(errTimeout) })
+		defer timer.Stop()
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			i, ok := r.(interrupt)
+			if !ok {
+				panic(r)
+			}
+			err, interrupted = i.err, true
+			return
+		}
+		// the evaluation may have finished right before being interrupted,
+		// so the interruption is taken out of the runtime
+		if !e.finish() {
+			<-rt.Interrupt
+		}
+	}()
+
+	value, err = run()
+	return value, false, err
+}
+
+func (jsScriptInstance *JSScriptInstance) compile() (*otto.Script, error) {
+	jsScriptInstance.once.Do(func() {
+		jsScriptInstance.mu.Lock()
+		defer jsScriptInstance.mu.Unlock()
+		jsScriptInstance.script, jsScriptInstance.err = jsScriptInstance.runtTime.Compile("_js_", jsScriptInstance.body)
+		jsScriptInstance.builtinsCheck = builtinsCheckOf(jsScriptInstance.body)
+	})
+	return jsScriptInstance.script, jsScriptInstance.err
 }
 
 func (jsScriptInstance *JSScriptInstance) EvalFromString(expressionString string) (interface{}, error) {
-	value, e := jsScriptInstance.runtTime.Run(expressionString)
+	rt, e := jsScriptInstance.runtime(false)
+	if e != nil {
+		return nil, e
+	}
+	defer jsScriptInstance.release(rt, false, checkAllBuiltins)
+	value, e := rt.Run(expressionString)
 	if e != nil {
 		return nil, e
 	}
@@ -56,18 +429,36 @@ func (jsScriptInstance *JSScriptInstance) EvalFromString(expressionString string
 }
 
 func (jsScriptInstance *JSScriptInstance) ScriptEval(scriptEnvironment map[string]interface{}) (interface{}, error) {
-	if jsScriptInstance.script == nil {
-		s, e := jsScriptInstance.runtTime.Compile("_js_", jsScriptInstance.body)
-		if e != nil {
-			return nil, e
-		}
-		jsScriptInstance.script = s
+	return jsScriptInstance.ScriptEvalContext(context.Background(), EvalOptions{}, scriptEnvironment)
+}
+
+// ScriptEvalContext implements the ScriptInstance interface. Scripts are
+// interrupted as soon as the context is cancelled or the timeout expires,
+// even if they never finish by themselves.
+func (jsScriptInstance *JSScriptInstance) ScriptEvalContext(ctx context.Context, opts EvalOptions, scriptEnvironment map[string]interface{}) (interface{}, error) {
+	script, e := jsScriptInstance.compile()
+	if e != nil {
+		return nil, e
+	}
+	rt, e := jsScriptInstance.runtime(opts.Sandboxed)
+	if e != nil {
+		return nil, e
 	}
 	// setup the params ???
+	check := jsScriptInstance.builtinsCheck
 	for name := range scriptEnvironment {
-		jsScriptInstance.runtTime.Set(name, scriptEnvironment[name])
+		rt.Set(name, scriptEnvironment[name])
+		if rt.builtin[name] && check < checkBuiltins {
+			check = checkBuiltins
+		}
+	}
+	value, interrupted, e := runInterruptible(ctx, opts.Timeout, rt.Otto, func() (otto.Value, error) {
+		return rt.Run(script)
+	})
+	if !interrupted {
+		// interrupted runtimes may be left in the middle of anything
+		jsScriptInstance.release(rt, opts.Sandboxed, check)
 	}
-	value, e := jsScriptInstance.runtTime.Run(jsScriptInstance.script)
 	if e != nil {
 		return nil, e
 	}
@@ -136,6 +527,6 @@ func GetScriptInstance(langString string, bodyString string) ScriptInstance {
 	//	ctx, _ := v8go.NewContext(nil)
 	//	return &V8EcmaScript6{ctx: ctx, body: bodyString}
 	default:
-		return &JSScriptInstance{runtTime: otto.New(), body: bodyString}
+		return newJSScriptInstance(bodyString)
 	}
 }
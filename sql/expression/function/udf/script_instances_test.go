@@ -1,8 +1,13 @@
 package udf
 
 import (
-	"github.com/stretchr/testify/require"
+	"context"
+	"fmt"
+	"sync"
 	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
 )
 
 func TestScripting_Appropriate_Instance(t *testing.T) {
@@ -90,3 +95,321 @@ func TestScripting_V8_Expressions_With_Params(t *testing.T) {
 	res, _ := si.ScriptEval(map[string]interface{}{"x": 32, "y": 10})
 	assertions.True(42 == res.(float64))
 }
+
+func TestScripting_Concurrent_Eval(t *testing.T) {
+	for _, lang := range []string{"js", "expr"} {
+		t.Run(lang, func(t *testing.T) {
+			require := require.New(t)
+			si := GetScriptInstance(lang, "x * 2")
+
+			var wg sync.WaitGroup
+			errs := make(chan error, 8)
+			for i := 0; i < 8; i++ {
+				wg.Add(1)
+				go func(i int) {
+					defer wg.Done()
+					for j := 0; j < 100; j++ {
+						x := i*100 + j
+						res, err := si.ScriptEval(map[string]interface{}{"x": x})
+						if err != nil {
+							errs <- err
+							return
+						}
+						if fmt.Sprint(res) != fmt.Sprint(x*2) {
+							errs <- fmt.Errorf("expected %d, got %v", x*2, res)
+							return
+						}
+					}
+				}(i)
+			}
+			wg.Wait()
+			close(errs)
+
+			for err := range errs {
+				require.NoError(err)
+			}
+		})
+	}
+}
+
+func TestScripting_JS_Compile_Error(t *testing.T) {
+	require := require.New(t)
+	si := GetScriptInstance("js", "x +")
+	for i := 0; i < 2; i++ {
+		_, err := si.ScriptEval(map[string]interface{}{"x": 1})
+		require.Error(err)
+	}
+}
+
+func TestScripting_JS_Interrupt(t *testing.T) {
+	require := require.New(t)
+	si := GetScriptInstance("js", "while (true) {}")
+
+	_, err := si.ScriptEvalContext(context.Background(), EvalOptions{Timeout: 50 * time.Millisecond}, nil)
+	require.Equal(errTimeout, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		cancel()
+	}()
+	_, err = si.ScriptEvalContext(ctx, EvalOptions{}, nil)
+	require.Equal(context.Canceled, err)
+
+	_, err = si.ScriptEvalContext(ctx, EvalOptions{}, nil)
+	require.Equal(context.Canceled, err)
+
+	// Runtimes are still usable when scripts finish in time.
+	si = GetScriptInstance("js", "x + 1")
+	for i := 0; i < 10; i++ {
+		res, err := si.ScriptEvalContext(context.Background(), EvalOptions{Timeout: time.Second}, map[string]interface{}{"x": i})
+		require.NoError(err)
+		require.EqualValues(i+1, res)
+	}
+}
+
+func TestScripting_JS_InterruptWatcher(t *testing.T) {
+	require := require.New(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	watcher := func() *contextWatcher {
+		contextWatchers.Lock()
+		defer contextWatchers.Unlock()
+		return contextWatchers.byDone[ctx.Done()]
+	}
+
+	si := GetScriptInstance("js", "x + 1")
+	_, err := si.ScriptEvalContext(ctx, EvalOptions{}, map[string]interface{}{"x": 0})
+	require.NoError(err)
+	w := watcher()
+	require.NotNil(w)
+
+	// The context is watched once for all its evaluations.
+	for i := 1; i < 10; i++ {
+		_, err := si.ScriptEvalContext(ctx, EvalOptions{}, map[string]interface{}{"x": i})
+		require.NoError(err)
+		require.True(w == watcher())
+	}
+
+	// All the evaluations running with the context are interrupted.
+	si = GetScriptInstance("js", "while (true) {}")
+	var wg sync.WaitGroup
+	errs := make([]error, 3)
+	for i := range errs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			_, errs[i] = si.ScriptEvalContext(ctx, EvalOptions{}, nil)
+		}(i)
+	}
+
+	time.Sleep(50 * time.Millisecond)
+	cancel()
+	wg.Wait()
+	for _, err := range errs {
+		require.Equal(context.Canceled, err)
+	}
+
+	for i := 0; i < 100 && watcher() != nil; i++ {
+		time.Sleep(time.Millisecond)
+	}
+	require.Nil(watcher())
+}
+
+func TestScripting_JS_CleanRuntimes(t *testing.T) {
+	require := require.New(t)
+	si := GetScriptInstance("js", "var x = typeof y; y = 1; z = x; [typeof $_, x]")
+
+	for _, sandboxed := range []bool{false, true} {
+		opts := EvalOptions{Sandboxed: sandboxed}
+		res, err := si.ScriptEvalContext(context.Background(), opts, map[string]interface{}{"$_": 42})
+		require.NoError(err)
+		require.Equal([]string{"number", "undefined"}, res)
+
+		// The runtime is reused, but not the globals of the last evaluation.
+		for i := 0; i < 3; i++ {
+			res, err = si.ScriptEvalContext(context.Background(), opts, nil)
+			require.NoError(err)
+			require.Equal([]string{"undefined", "undefined"}, res)
+		}
+	}
+
+	// Scripts can't prevent the cleanup replacing the globals it uses.
+	si = GetScriptInstance("js", "Object.getOwnPropertyNames = function() { return [] }; secret = 1")
+	_, err := si.ScriptEvalContext(context.Background(), EvalOptions{}, nil)
+	require.NoError(err)
+
+	res, err := si.ScriptEvalContext(context.Background(), EvalOptions{}, nil)
+	require.NoError(err)
+	require.EqualValues(1, res)
+
+	rt, err := si.(*JSScriptInstance).runtime(false)
+	require.NoError(err)
+	v, err := rt.Get("secret")
+	require.NoError(err)
+	require.True(v.IsUndefined())
+}
+
+func TestScripting_JS_ModifiedBuiltins(t *testing.T) {
+	require := require.New(t)
+
+	scripts := []string{
+		`JSON = {stringify: function() { return "leak" }}`,
+		`var JSON = 1`,
+		`delete JSON`,
+		`JSON.stringify = function() { return "leak" }`,
+		`Array.prototype.join = function() { return "leak" }`,
+		`Array.prototype.leak = 1`,
+		`Object.prototype.leak = 1`,
+		`Math.max = Math.min`,
+		`String.prototype.toUpperCase = undefined`,
+		`Object.defineProperty(Number.prototype, "toFixed", {get: function() { return "leak" }})`,
+		`NaN = 1; Number.NaN = 1`,
+		`var define = Object.defineProperty; define(Array.prototype, "leak", {value: 1})`,
+	}
+
+	check := `[JSON.stringify([1]), [1, 2].join("-"), typeof JSON, "leak" in [],
+		"leak" in {}, Math.max(1, 2), "a".toUpperCase(), typeof (1).toFixed, isNaN(NaN)]`
+	expected := []interface{}{"[1]", "1-2", "object", false, false, float64(2), "A", "function", true}
+
+	for _, sandboxed := range []bool{false, true} {
+		opts := EvalOptions{Sandboxed: sandboxed}
+		for _, script := range scripts {
+			// Every evaluation sees the builtins as they were before the
+			// previous one modified them.
+			si := GetScriptInstance("js", "var before = "+check+"; "+script+"; before")
+			for i := 0; i < 3; i++ {
+				res, err := si.ScriptEvalContext(context.Background(), opts, nil)
+				require.NoError(err, script)
+				require.Equal(expected, res, script)
+			}
+		}
+	}
+
+	// Runtimes whose builtins are only used can be reused.
+	rt, err := GetScriptInstance("js", check).(*JSScriptInstance).runtime(false)
+	require.NoError(err)
+	_, err = rt.Run(check)
+	require.NoError(err)
+	require.True(rt.clean(checkAllBuiltins))
+}
+
+func TestBuiltinsCheckOf(t *testing.T) {
+	testCases := []struct {
+		script string
+		check  builtinsCheck
+	}{
+		{`x + 1`, skipBuiltins},
+		{`var s = 0; for (var i = 0; i < 10; i++) { s += i }; s`, skipBuiltins},
+		{`JSON.stringify($FILTERS) + $COLUMNS.join(",") + [1, 2][0]`, skipBuiltins},
+		{`var o = {a: 1}; o["b"]`, skipBuiltins},
+		{`x = 1; var y = 2; delete z; x++; for (k in {}) {}; function f() {}`, skipBuiltins},
+		{`JSON = 1`, checkBuiltins},
+		{`var Math = 2`, checkBuiltins},
+		{`delete Date`, checkBuiltins},
+		{`parseInt++`, checkBuiltins},
+		{`NaN++`, skipBuiltins},
+		{`for (var Array in {}) {}`, checkBuiltins},
+		{`function String() {}`, checkBuiltins},
+		{`var o = {}; o.a = 1`, checkBuiltins},
+		{`Array.prototype.leak = 1`, checkBuiltins},
+		{`this["JSON"] = 1`, checkBuiltins},
+		{`delete Array.prototype.join`, checkBuiltins},
+		{`Number.prototype.x++`, checkBuiltins},
+		{`for (Array.prototype.leak in {a: 1}) {}`, checkBuiltins},
+		{`var d = Object.defineProperty`, checkAllBuiltins},
+		{`[].push.call(Array.prototype, 1)`, checkBuiltins},
+		{`Object["define" + "Property"]`, checkAllBuiltins},
+		{`var o = {}; o[k]`, checkAllBuiltins},
+		{`eval("1")`, checkAllBuiltins},
+		{`with (Array.prototype) { join = 1 }`, checkAllBuiltins},
+		{`(function () { Object.prototype.x = 1 })()`, checkBuiltins},
+		{`syntax error (`, checkAllBuiltins},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.script, func(t *testing.T) {
+			require.Equal(t, tt.check, builtinsCheckOf(tt.script))
+		})
+	}
+}
+
+func TestScripting_JS_Sandboxed(t *testing.T) {
+	require := require.New(t)
+	si := GetScriptInstance("js", "[typeof eval, typeof Function, typeof console]")
+
+	res, err := si.ScriptEvalContext(context.Background(), EvalOptions{}, nil)
+	require.NoError(err)
+	require.Equal([]string{"function", "function", "object"}, res)
+
+	res, err = si.ScriptEvalContext(context.Background(), EvalOptions{Sandboxed: true}, nil)
+	require.NoError(err)
+	require.Equal([]string{"undefined", "undefined", "undefined"}, res)
+
+	// The Function constructor can't be reached through the constructors
+	// of other values either.
+	si = GetScriptInstance("js", `[
+		typeof [].constructor.constructor,
+		typeof (function() {}).constructor,
+		typeof Object.getPrototypeOf(function() {}).constructor
+	]`)
+
+	res, err = si.ScriptEvalContext(context.Background(), EvalOptions{Sandboxed: true}, nil)
+	require.NoError(err)
+	require.Equal([]string{"undefined", "undefined", "undefined"}, res)
+
+	si = GetScriptInstance("js", `Function.prototype.constructor = 1; [].constructor.constructor("return 1")()`)
+	_, err = si.ScriptEvalContext(context.Background(), EvalOptions{Sandboxed: true}, nil)
+	require.Error(err)
+
+	// Runtimes that are not sandboxed are not affected.
+	si = GetScriptInstance("js", `[].constructor.constructor("return 1")()`)
+	_, err = si.ScriptEvalContext(context.Background(), EvalOptions{Sandboxed: true}, nil)
+	require.Error(err)
+
+	res, err = si.ScriptEvalContext(context.Background(), EvalOptions{}, nil)
+	require.NoError(err)
+	require.EqualValues(1, res)
+}
+
+// BenchmarkScriptEvalContext evaluates a trivial script with a context that
+// can be cancelled, as the contexts of queries are, so it measures the cost
+// of making every evaluation interruptible.
+func BenchmarkScriptEvalContext(b *testing.B) {
+	si := GetScriptInstance("js", "x + 1")
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	env := map[string]interface{}{"x": 1}
+
+	for _, opts := range []EvalOptions{{}, {Timeout: time.Second}} {
+		b.Run(fmt.Sprintf("timeout=%s", opts.Timeout), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				if _, err := si.ScriptEvalContext(ctx, opts, env); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkScriptEvalBuiltins(b *testing.B) {
+	env := map[string]interface{}{"x": 1}
+
+	// Builtins are only checked after evaluating scripts that may modify
+	// them, and properties defined on them only after evaluating scripts
+	// that may define them.
+	for _, script := range []string{
+		"[x].concat(x)",
+		"var o = {}; o.x = x; o",
+		`Object.defineProperty({}, "x", {value: x})`,
+	} {
+		si := GetScriptInstance("js", script)
+		b.Run(script, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				if _, err := si.ScriptEvalContext(context.Background(), EvalOptions{}, env); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
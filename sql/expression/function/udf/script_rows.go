@@ -0,0 +1,225 @@
+package udf
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/src-d/go-mysql-server/sql"
+	errors "gopkg.in/src-d/go-errors.v1"
+)
+
+// ErrInvalidScriptRows is returned when the result of a script used as a
+// table can't be turned into rows.
+var ErrInvalidScriptRows = errors.NewKind("%s returned %#v, which is not a list of rows: %s")
+
+// ScriptElements returns the elements of the list returned by a script used
+// as a table, named as given in errors. JSON documents are decoded first.
+func ScriptElements(name string, value interface{}) ([]interface{}, error) {
+	switch v := value.(type) {
+	case nil:
+		return nil, nil
+	case []interface{}:
+		return v, nil
+	case string:
+		return decodeElements(name, value, []byte(v))
+	case []byte:
+		return decodeElements(name, value, v)
+	}
+
+	val := reflect.ValueOf(value)
+	if val.Kind() != reflect.Slice && val.Kind() != reflect.Array {
+		return nil, ErrInvalidScriptRows.New(name, value, "it's not a list")
+	}
+
+	elems := make([]interface{}, val.Len())
+	for i := range elems {
+		elems[i] = val.Index(i).Interface()
+	}
+	return elems, nil
+}
+
+func decodeElements(name string, value interface{}, doc []byte) ([]interface{}, error) {
+	var elems []interface{}
+	if err := json.Unmarshal(doc, &elems); err != nil {
+		return nil, ErrInvalidScriptRows.New(name, value, err)
+	}
+	return elems, nil
+}
+
+// ScriptRow converts an element of the list returned by a script used as a
+// table to a row of the given schema. The elements can be objects, whose
+// keys are the columns, lists of column values, or single values.
+func ScriptRow(name string, elem interface{}, schema sql.Schema) (sql.Row, error) {
+	row := make(sql.Row, len(schema))
+
+	val := reflect.ValueOf(elem)
+	switch elementKind(elem) {
+	case objectElement:
+		for _, key := range val.MapKeys() {
+			col := fmt.Sprint(key.Interface())
+			for i, c := range schema {
+				if strings.EqualFold(c.Name, col) {
+					row[i] = val.MapIndex(key).Interface()
+					break
+				}
+			}
+		}
+	case listElement:
+		for i := 0; i < val.Len() && i < len(row); i++ {
+			row[i] = val.Index(i).Interface()
+		}
+	case valueElement:
+		row[0] = elem
+	}
+
+	for i, v := range row {
+		if v == nil {
+			continue
+		}
+
+		v, err := schema[i].Type.Convert(v)
+		if err != nil {
+			return nil, ErrInvalidScriptRows.New(name, elem, err)
+		}
+		row[i] = v
+	}
+
+	return row, nil
+}
+
+// InferScriptSchema infers the columns of a table from the elements of the
+// list returned by a script. The columns of objects are their keys, sorted
+// by name, the columns of lists are named by their position, and single
+// values are in a column named value.
+func InferScriptSchema(name string, value interface{}, elems []interface{}) (sql.Schema, error) {
+	var (
+		names  []string
+		values = make(map[string][]interface{})
+		kind   = nullElement
+	)
+
+	add := func(name string, v interface{}) {
+		if _, ok := values[name]; !ok {
+			names = append(names, name)
+		}
+		values[name] = append(values[name], v)
+	}
+
+	for _, elem := range elems {
+		k := elementKind(elem)
+		if k == nullElement {
+			continue
+		}
+
+		if kind != nullElement && k != kind {
+			return nil, ErrInvalidScriptRows.New(name, value, "its elements are not all of the same kind")
+		}
+		kind = k
+
+		val := reflect.ValueOf(elem)
+		switch k {
+		case objectElement:
+			for _, key := range val.MapKeys() {
+				add(fmt.Sprint(key.Interface()), val.MapIndex(key).Interface())
+			}
+		case listElement:
+			for i := 0; i < val.Len(); i++ {
+				add(fmt.Sprintf("column_%d", i), val.Index(i).Interface())
+			}
+		default:
+			add("value", elem)
+		}
+	}
+
+	if kind == objectElement {
+		sort.Strings(names)
+	}
+
+	if len(names) == 0 {
+		names = []string{"value"}
+	}
+
+	schema := make(sql.Schema, len(names))
+	for i, name := range names {
+		schema[i] = &sql.Column{Name: name, Type: inferType(values[name])}
+	}
+
+	return schema, nil
+}
+
+type elemKind byte
+
+const (
+	nullElement elemKind = iota
+	objectElement
+	listElement
+	valueElement
+)
+
+// elementKind returns whether an element of the result of a script is an
+// object, a list or a single value.
+func elementKind(elem interface{}) elemKind {
+	if elem == nil {
+		return nullElement
+	}
+
+	if _, ok := elem.([]byte); ok {
+		return valueElement
+	}
+
+	switch reflect.ValueOf(elem).Kind() {
+	case reflect.Map:
+		return objectElement
+	case reflect.Slice, reflect.Array:
+		return listElement
+	default:
+		return valueElement
+	}
+}
+
+// inferType returns the narrowest type for all the given values of a
+// column. Values of different or nested types are JSON.
+func inferType(values []interface{}) sql.Type {
+	var integers, numbers, strs, bools, total int
+	for _, v := range values {
+		if v == nil {
+			continue
+		}
+		total++
+
+		switch val := reflect.ValueOf(v); val.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+			reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			integers++
+			numbers++
+		case reflect.Float32, reflect.Float64:
+			if f := val.Float(); f == math.Trunc(f) && !math.IsInf(f, 0) {
+				integers++
+			}
+			numbers++
+		case reflect.String:
+			strs++
+		case reflect.Bool:
+			bools++
+		}
+	}
+
+	switch total {
+	case 0:
+		return sql.Text
+	case integers:
+		return sql.Int64
+	case numbers:
+		return sql.Float64
+	case strs:
+		return sql.Text
+	case bools:
+		return sql.Boolean
+	default:
+		return sql.JSON
+	}
+}
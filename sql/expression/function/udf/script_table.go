@@ -0,0 +1,331 @@
+package udf
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/src-d/go-mysql-server/sql"
+	"github.com/src-d/go-mysql-server/sql/expression"
+)
+
+// ScriptTable is a table whose partitions and rows are produced by a
+// script, such as one created with
+// `CREATE TABLE t (a INT) ENGINE=SCRIPT SCRIPT='[{a: 1}, {a: 2}]'`. The
+// script returns the rows of the table in the same way as table functions,
+// as a list of objects, lists of column values or single values.
+//
+// The script is first run with a null $PARTITION. It can return an object
+// with a list of partitions in its partitions key, and then it's run again
+// with each of them in $PARTITION to return its rows. Any other result is
+// the rows of the only partition of the table.
+//
+// The table name is in $TABLE, the names of the columns that are read in
+// $COLUMNS and the filters the rows must satisfy, as SQL, in $FILTERS, so
+// scripts can avoid producing rows that are not needed. The filters are
+// applied to the rows anyway, so scripts don't have to.
+type ScriptTable struct {
+	name       string
+	schema     sql.Schema
+	script     ScriptInstance
+	filters    []sql.Expression
+	columns    []int
+	projection []string
+}
+
+var _ sql.FilteredTable = (*ScriptTable)(nil)
+var _ sql.ProjectedTable = (*ScriptTable)(nil)
+
+// NewScriptTable creates a new table with the given name and schema whose
+// rows are produced by the given script.
+func NewScriptTable(name string, schema sql.Schema, script ScriptInstance) *ScriptTable {
+	return &ScriptTable{name: name, schema: schema, script: script}
+}
+
+// Name implements the sql.Table interface.
+func (t *ScriptTable) Name() string {
+	return t.name
+}
+
+// Schema implements the sql.Table interface.
+func (t *ScriptTable) Schema() sql.Schema {
+	if len(t.columns) == 0 {
+		return t.schema
+	}
+
+	schema := make(sql.Schema, len(t.columns))
+	for i, idx := range t.columns {
+		schema[i] = t.schema[idx]
+	}
+	return schema
+}
+
+// Script returns the script producing the rows of the table.
+func (t *ScriptTable) Script() ScriptInstance {
+	return t.script
+}
+
+// Partitions implements the sql.Table interface.
+func (t *ScriptTable) Partitions(ctx *sql.Context) (sql.PartitionIter, error) {
+	value, err := t.eval(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	obj, ok := value.(map[string]interface{})
+	if !ok {
+		return &scriptPartitionIter{partitions: []*scriptPartition{
+			{key: []byte(t.name), rows: value, evaluated: true},
+		}}, nil
+	}
+
+	values, ok := obj["partitions"]
+	if !ok {
+		return nil, ErrInvalidScriptRows.New(t.description(), value, "it has no partitions")
+	}
+
+	elems, err := ScriptElements(t.description(), values)
+	if err != nil {
+		return nil, err
+	}
+
+	var partitions = make([]*scriptPartition, len(elems))
+	for i, elem := range elems {
+		key, err := json.Marshal(elem)
+		if err != nil {
+			return nil, ErrInvalidScriptRows.New(t.description(), value, err)
+		}
+
+		partitions[i] = &scriptPartition{key: key, value: elem}
+	}
+
+	return &scriptPartitionIter{partitions: partitions}, nil
+}
+
+// PartitionRows implements the sql.Table interface.
+func (t *ScriptTable) PartitionRows(ctx *sql.Context, partition sql.Partition) (sql.RowIter, error) {
+	p, ok := partition.(*scriptPartition)
+	if !ok {
+		return nil, fmt.Errorf("partition not found: %q", partition.Key())
+	}
+
+	value := p.rows
+	if !p.evaluated {
+		var err error
+		value, err = t.eval(ctx, p.value)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	elems, err := ScriptElements(t.description(), value)
+	if err != nil {
+		return nil, err
+	}
+
+	var rows []sql.Row
+	for _, elem := range elems {
+		row, err := ScriptRow(t.description(), elem, t.schema)
+		if err != nil {
+			return nil, err
+		}
+
+		ok, err := t.matches(ctx, row)
+		if err != nil {
+			return nil, err
+		}
+
+		if ok {
+			rows = append(rows, t.project(row))
+		}
+	}
+
+	return sql.RowsToRowIter(rows...), nil
+}
+
+// HandledFilters implements the sql.FilteredTable interface.
+func (t *ScriptTable) HandledFilters(filters []sql.Expression) []sql.Expression {
+	var handled []sql.Expression
+	for _, f := range filters {
+		var hasOtherFields bool
+		expression.Inspect(f, func(e sql.Expression) bool {
+			if e, ok := e.(*expression.GetField); ok {
+				if e.Table() != t.name || !t.schema.Contains(e.Name(), t.name) {
+					hasOtherFields = true
+					return false
+				}
+			}
+			return true
+		})
+
+		if !hasOtherFields {
+			handled = append(handled, f)
+		}
+	}
+
+	return handled
+}
+
+// WithFilters implements the sql.FilteredTable interface.
+func (t *ScriptTable) WithFilters(filters []sql.Expression) sql.Table {
+	if len(filters) == 0 {
+		return t
+	}
+
+	nt := *t
+	nt.filters = filters
+	return &nt
+}
+
+// WithProjection implements the sql.ProjectedTable interface.
+func (t *ScriptTable) WithProjection(colNames []string) sql.Table {
+	if len(colNames) == 0 {
+		return t
+	}
+
+	var columns []int
+	for _, name := range colNames {
+		if i := t.schema.IndexOf(name, t.name); i >= 0 {
+			columns = append(columns, i)
+		}
+	}
+
+	nt := *t
+	nt.columns = columns
+	nt.projection = colNames
+	return &nt
+}
+
+// Projection implements the sql.ProjectedTable interface.
+func (t *ScriptTable) Projection() []string {
+	return t.projection
+}
+
+// Filters implements the sql.FilteredTable interface.
+func (t *ScriptTable) Filters() []sql.Expression {
+	return t.filters
+}
+
+// String implements the sql.Table interface.
+func (t *ScriptTable) String() string {
+	p := sql.NewTreePrinter()
+
+	kind := ""
+	if len(t.columns) > 0 {
+		kind += "Projected "
+	}
+
+	if len(t.filters) > 0 {
+		kind += "Filtered "
+	}
+
+	if kind != "" {
+		kind = ": " + kind
+	}
+
+	_ = p.WriteNode("ScriptTable(%s, %s)%s", t.name, t.script.Dialect(), kind)
+	var schema = make([]string, len(t.Schema()))
+	for i, col := range t.Schema() {
+		schema[i] = fmt.Sprintf(
+			"Column(%s, %s, nullable=%v)",
+			col.Name,
+			col.Type.Type().String(),
+			col.Nullable,
+		)
+	}
+	_ = p.WriteChildren(schema...)
+	return p.String()
+}
+
+// eval runs the script to produce the rows of the given partition, or the
+// partitions if it's nil, with the limits of the session. The script is
+// always sandboxed.
+func (t *ScriptTable) eval(ctx *sql.Context, partition interface{}) (interface{}, error) {
+	limits, err := sessionLimits(ctx)
+	if err != nil {
+		return nil, err
+	}
+	// tables are read in the sessions of any user, so their scripts are
+	// never trusted and don't get the context in $CONTEXT
+	limits.Sandboxed = true
+
+	columns := make([]string, len(t.Schema()))
+	for i, col := range t.Schema() {
+		columns[i] = col.Name
+	}
+
+	filters := make([]string, len(t.filters))
+	for i, f := range t.filters {
+		filters[i] = f.String()
+	}
+
+	env := map[string]interface{}{
+		"$TABLE":     t.name,
+		"$PARTITION": partition,
+		"$COLUMNS":   columns,
+		"$FILTERS":   filters,
+	}
+
+	value, err := t.script.ScriptEvalContext(ctx, limits.EvalOptions, env)
+	if err == errTimeout {
+		return nil, ErrScriptTimeout.New(t.name, limits.Timeout)
+	}
+	return value, err
+}
+
+// matches returns whether the row satisfies all the filters of the table.
+func (t *ScriptTable) matches(ctx *sql.Context, row sql.Row) (bool, error) {
+	for _, f := range t.filters {
+		ok, err := sql.EvaluateCondition(ctx, f, row)
+		if err != nil || !ok {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+func (t *ScriptTable) project(row sql.Row) sql.Row {
+	if len(t.columns) == 0 {
+		return row
+	}
+
+	projected := make(sql.Row, len(t.columns))
+	for i, idx := range t.columns {
+		projected[i] = row[idx]
+	}
+	return projected
+}
+
+// description is the name of the table in errors.
+func (t *ScriptTable) description() string {
+	return "script table " + t.name
+}
+
+// scriptPartition is a partition of a script table, identified by the
+// JSON of the value the script returned for it. The only partition of
+// tables that are not partitioned has the rows already.
+type scriptPartition struct {
+	key       []byte
+	value     interface{}
+	rows      interface{}
+	evaluated bool
+}
+
+func (p *scriptPartition) Key() []byte { return p.key }
+
+type scriptPartitionIter struct {
+	partitions []*scriptPartition
+	pos        int
+}
+
+func (i *scriptPartitionIter) Next() (sql.Partition, error) {
+	if i.pos >= len(i.partitions) {
+		return nil, io.EOF
+	}
+
+	p := i.partitions[i.pos]
+	i.pos++
+	return p, nil
+}
+
+func (i *scriptPartitionIter) Close() error { return nil }
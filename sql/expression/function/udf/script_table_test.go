@@ -0,0 +1,111 @@
+package udf
+
+import (
+	"testing"
+
+	"github.com/src-d/go-mysql-server/sql"
+	"github.com/src-d/go-mysql-server/sql/expression"
+	"github.com/stretchr/testify/require"
+)
+
+var scriptTableSchema = sql.Schema{
+	{Name: "n", Type: sql.Int64, Source: "t", Nullable: true},
+	{Name: "s", Type: sql.Text, Source: "t", Nullable: true},
+}
+
+func scriptTableRows(t *testing.T, table sql.Table) []sql.Row {
+	t.Helper()
+	ctx := sql.NewEmptyContext()
+
+	partitions, err := table.Partitions(ctx)
+	require.NoError(t, err)
+
+	var rows []sql.Row
+	for {
+		p, err := partitions.Next()
+		if err != nil {
+			break
+		}
+
+		iter, err := table.PartitionRows(ctx, p)
+		require.NoError(t, err)
+
+		prows, err := sql.RowIterToRows(iter)
+		require.NoError(t, err)
+		rows = append(rows, prows...)
+	}
+
+	require.NoError(t, partitions.Close())
+	return rows
+}
+
+func TestScriptTable(t *testing.T) {
+	require := require.New(t)
+
+	table := NewScriptTable("t", scriptTableSchema, GetScriptInstance("js", `[{n: 1, s: $TABLE}, [2, "b"]]`))
+	require.Equal("t", table.Name())
+	require.Equal(scriptTableSchema, table.Schema())
+	require.Equal([]sql.Row{{int64(1), "t"}, {int64(2), "b"}}, scriptTableRows(t, table))
+
+	table = NewScriptTable("t", scriptTableSchema, GetScriptInstance("expr", `["1", "2"]`))
+	require.Equal([]sql.Row{{int64(1), nil}, {int64(2), nil}}, scriptTableRows(t, table))
+}
+
+func TestScriptTablePartitions(t *testing.T) {
+	require := require.New(t)
+
+	script := `$PARTITION == null ? {partitions: ["a", "b"]} : [{n: 1, s: $PARTITION}, {n: 2, s: $PARTITION}]`
+	table := NewScriptTable("t", scriptTableSchema, GetScriptInstance("js", script))
+
+	ctx := sql.NewEmptyContext()
+	partitions, err := table.Partitions(ctx)
+	require.NoError(err)
+	p, err := partitions.Next()
+	require.NoError(err)
+	require.Equal(`"a"`, string(p.Key()))
+
+	require.Equal([]sql.Row{
+		{int64(1), "a"}, {int64(2), "a"},
+		{int64(1), "b"}, {int64(2), "b"},
+	}, scriptTableRows(t, table))
+
+	table = NewScriptTable("t", scriptTableSchema, GetScriptInstance("js", `({parts: [1]})`))
+	_, err = table.Partitions(ctx)
+	require.True(ErrInvalidScriptRows.Is(err))
+
+	table = NewScriptTable("t", scriptTableSchema, GetScriptInstance("js", `"foo"`))
+	partitions, err = table.Partitions(ctx)
+	require.NoError(err)
+	p, err = partitions.Next()
+	require.NoError(err)
+	_, err = table.PartitionRows(ctx, p)
+	require.True(ErrInvalidScriptRows.Is(err))
+}
+
+func TestScriptTableFiltersAndProjection(t *testing.T) {
+	require := require.New(t)
+
+	script := `[[1, JSON.stringify($FILTERS)], [2, $COLUMNS.join(",")], [3, "c"]]`
+	var table sql.Table = NewScriptTable("t", scriptTableSchema, GetScriptInstance("js", script))
+
+	filter := expression.NewGreaterThan(
+		expression.NewGetFieldWithTable(0, sql.Int64, "t", "n", true),
+		expression.NewLiteral(int64(1), sql.Int64),
+	)
+	other := expression.NewEquals(
+		expression.NewGetFieldWithTable(0, sql.Int64, "u", "n", true),
+		expression.NewLiteral(int64(1), sql.Int64),
+	)
+
+	filtered := table.(sql.FilteredTable)
+	require.Equal([]sql.Expression{filter}, filtered.HandledFilters([]sql.Expression{filter, other}))
+
+	table = filtered.WithFilters([]sql.Expression{filter})
+	require.Equal([]sql.Expression{filter}, table.(sql.FilteredTable).Filters())
+	require.Equal([]sql.Row{{int64(2), "n,s"}, {int64(3), "c"}}, scriptTableRows(t, table))
+
+	table = table.(sql.ProjectedTable).WithProjection([]string{"s"})
+	require.Equal([]string{"s"}, table.(sql.ProjectedTable).Projection())
+	require.Equal(sql.Schema{scriptTableSchema[1]}, table.Schema())
+	require.Equal([]sql.Row{{"s"}, {"c"}}, scriptTableRows(t, table))
+}
@@ -1,14 +1,19 @@
 package udf
 
 import (
-	"errors"
 	"fmt"
 	"github.com/src-d/go-mysql-server/sql"
+	errors "gopkg.in/src-d/go-errors.v1"
 	"reflect"
 	"regexp"
 	"strings"
+	"sync/atomic"
 )
 
+// ErrNoCombiner is returned when the partial results of a generic aggregator
+// without a combiner need to be merged.
+var ErrNoCombiner = errors.NewKind("script UDF %s can't merge partial results without a combiner, as in <?AGG@ init # step # combiner ?>")
+
 type AggregatorTypeOfUDF int
 
 const (
@@ -30,16 +35,46 @@ type ScriptUDF struct {
 	Script  ScriptInstance
 	initial interface{}
 	UdfType TypeOfUDF
+	// Combiner is the script that combines two partial results of a generic
+	// aggregator, $_ and $PARTIAL, into one. Lists and sets don't need it.
+	Combiner ScriptInstance
+	// Params are the names given to the arguments in the script, if the UDF
+	// has a fixed number of arguments, as functions created with CREATE
+	// FUNCTION do.
+	Params []string
+	// ParamTypes are the types the arguments are converted to before
+	// running the script. Arguments without a type are not converted.
+	ParamTypes []sql.Type
+	// ReturnType is the type the results of the script are converted to.
+	// Without a type, results are JSON. The results of transposed
+	// aggregations and of list and set aggregators are lists, so it is the
+	// type of their elements.
+	ReturnType sql.Type
+	// Sandboxed is true if the script is untrusted, so it can't use the
+	// context of the query in $CONTEXT nor the globals that run code that
+	// is not in the script. Functions created with CREATE FUNCTION are
+	// always sandboxed, as they run in the sessions of any user.
+	Sandboxed bool
 }
 
 type Scriptable struct {
 	Meta *ScriptUDF
 	args []sql.Expression
+	// calls is the number of times the script has been evaluated in the
+	// query. Expressions are created for every query, so it is shared by
+	// all the copies of the expression.
+	calls *int64
 }
 
-var AggregatorRegex = regexp.MustCompile(`^<\?([LS][F_][T_])|(AG[GT])@.+`)
+var AggregatorRegex = regexp.MustCompile(`^<\?([LS][F_][T_]|AG[GT])@`)
+
+// aggregatorPrefixRegex matches anything that looks like an aggregator
+// prefix at the start of a macro, to report the unknown ones.
+var aggregatorPrefixRegex = regexp.MustCompile(`^([A-Z_]{3})@`)
 
-// within UDF this does parameter extraction
+// ParamRegex matches the parameters of a macro, such as @{mytable.x}. The
+// parameters are replaced by the macro lexer, which skips the strings and
+// comments of the script.
 var ParamRegex = regexp.MustCompile(`@{([^(@{)^}]+)}`)
 
 /**
@@ -82,7 +117,10 @@ func AggregatorType(macroStart string) (interface{}, TypeOfUDF) {
 		return make(map[interface{}]bool), typeOfUDF
 	case 'A':
 		typeOfUDF.AggregatorType = GenericAggregator
-		i := strings.Index(macroStart, "#")
+		i := indexScript(macroStart, '#')
+		if i < 0 {
+			return macroStart[5:], typeOfUDF
+		}
 		return macroStart[5 : i+1], typeOfUDF
 	default:
 		typeOfUDF.IsAggregator = false
@@ -92,41 +130,62 @@ func AggregatorType(macroStart string) (interface{}, TypeOfUDF) {
 	return nil, typeOfUDF
 }
 
-// Extract out macros, failed with regex. TODO Sandy to find out better alternatives
+// FindAllUDFStrings returns the macros in the query, each of them as the
+// whole macro and its body. Macros in SQL strings and comments are ignored.
 func FindAllUDFStrings(query string) ([][]string, error) {
-	var myRet = make([][]string, 0)
-	buf := query
-	start := strings.Index(buf, "<?")
-	end := start
-	for start >= 0 {
-		end = strings.Index(buf, "?>")
-		if end < start {
-			return nil, errors.New(fmt.Sprintf("Invalid Macro!!! <?%d : %d?> !!!", start, end))
-		}
-		values := make([]string, 2)
-		values[0] = buf[start : end+2]
-		values[1] = values[0][2 : len(values[0])-2]
-		myRet = append(myRet, values)
-		buf = buf[end+2:]
-		start = strings.Index(buf, "<?")
+	macros, err := findMacros(query)
+	if err != nil {
+		return nil, err
+	}
+
+	var myRet = make([][]string, len(macros))
+	for i, m := range macros {
+		myRet[i] = []string{query[m.start:m.end], m.body}
 	}
 	return myRet, nil
 }
 
+// MacroProcessor replaces the macros in the query with calls to the UDFs
+// returned, whose scripts are in the given language. Invalid macros are left
+// as they are.
 func MacroProcessor(query string, funcNumStart int, langDialect string) (string, []ScriptUDF) {
-	list, _ := FindAllUDFStrings(query)
-	N := len(list)
-	if N == 0 {
+	processed, udfs, err := processMacros(query, funcNumStart, func(body string) ScriptInstance {
+		return GetScriptInstance(langDialect, body)
+	})
+	if err != nil {
 		return query, nil
 	}
-	retString := query
+	return processed, udfs
+}
+
+func processMacros(query string, funcNumStart int, newScript func(body string) ScriptInstance) (string, []ScriptUDF, error) {
+	macros, err := findMacros(query)
+	if err != nil {
+		return "", nil, err
+	}
+	N := len(macros)
+	if N == 0 {
+		return query, nil, nil
+	}
+	var retString strings.Builder
+	written := 0
 	udfArray := make([]ScriptUDF, N)
 	for i := 0; i < N; i++ {
-		actual := list[i][0]
-		expr := list[i][1]
+		returnType, expr, err := macroReturnType(macros[i].body)
+		if err != nil {
+			return "", nil, err
+		}
 		udfName := fmt.Sprintf("_auto_%d_udf_", i+1+funcNumStart)
 		// check if initialAggregatorValue ?
-		initialAggregatorValue, udfType := AggregatorType(actual)
+		initialAggregatorValue, udfType := AggregatorType("<?" + expr)
+		if m := aggregatorPrefixRegex.FindStringSubmatch(expr); m != nil && !udfType.IsAggregator {
+			line, col := position(query, macros[i].start)
+			return "", nil, ErrUnknownAggregator.New(m[1], line, col)
+		}
+		if udfType.AggregatorType == GenericAggregator && indexScript(expr, '#') < 0 {
+			line, col := position(query, macros[i].start)
+			return "", nil, ErrInvalidMacro.New(line, col, "missing # after the initial value of the aggregator")
+		}
 		if initialAggregatorValue != nil {
 			prefixInx := 4
 			if udfType.AggregatorType == GenericAggregator {
@@ -136,30 +195,69 @@ func MacroProcessor(query string, funcNumStart int, langDialect string) (string,
 			udfName = "fold" + udfName
 		}
 
-		myParams := ParamRegex.FindAllStringSubmatch(expr, -1)
-		paramNameMap := make(map[string]bool)
-		for j := 0; j < len(myParams); j++ {
-			matchString := myParams[j][0]
-			paramString := myParams[j][1]
-			paramNameMap[paramString] = true
-			expr = strings.Replace(expr, matchString, paramString, 1)
-		}
-		paramNames := make([]string, len(paramNameMap))
-		k := 0
-		for n := range paramNameMap {
-			paramNames[k] = n
-			k++
+		// generic aggregators may have a combiner after the step
+		var combiner ScriptInstance
+		if udfType.AggregatorType == GenericAggregator {
+			if i := indexScript(expr, '#'); i >= 0 {
+				combiner = newScript(expr[i+1:])
+				expr = expr[:i]
+			}
 		}
+
+		expr, paramNames := replaceParams(expr)
 		udfCall := fmt.Sprintf("%s(%s)", udfName, strings.Join(paramNames, ","))
-		udfArray[i] = ScriptUDF{Id: udfName, Script: GetScriptInstance(langDialect, expr),
-			initial: initialAggregatorValue, UdfType: udfType}
-		retString = strings.Replace(retString, actual, udfCall, 1)
+		udfArray[i] = ScriptUDF{Id: udfName, Script: newScript(expr), Combiner: combiner,
+			initial: initialAggregatorValue, UdfType: udfType, ReturnType: returnType}
+		retString.WriteString(query[written:macros[i].start])
+		retString.WriteString(udfCall)
+		written = macros[i].end
+	}
+	retString.WriteString(query[written:])
+	return retString.String(), udfArray, nil
+}
+
+// ReturnTypeRegex matches the return type annotation at the start of a
+// macro, as in <?:INT64 ... ?>.
+var ReturnTypeRegex = regexp.MustCompile(`^:(\w+)\s+`)
+
+// macroReturnType returns the return type annotated in the given macro body,
+// if any, and the body without the annotation.
+func macroReturnType(expr string) (sql.Type, string, error) {
+	m := ReturnTypeRegex.FindStringSubmatch(expr)
+	if m == nil {
+		return nil, expr, nil
+	}
+
+	typ, err := ReturnType(m[1])
+	if err != nil {
+		return nil, "", err
+	}
+
+	return typ, expr[len(m[0]):], nil
+}
+
+// NewStoredUDF creates the UDF of a function created with CREATE FUNCTION.
+// Its arguments are available in the script with the names of the
+// parameters of the function, besides $ARGS.
+func NewStoredUDF(fn *sql.StoredFunction) ScriptUDF {
+	params := make([]string, len(fn.Params))
+	copy(params, fn.Params)
+	return ScriptUDF{
+		Id:         fn.Name,
+		Script:     GetScriptInstance(fn.Language, fn.Body),
+		UdfType:    TypeOfUDF{AggregatorType: NotAnAggregator},
+		Params:     params,
+		ParamTypes: fn.ParamTypes,
+		ReturnType: fn.ReturnType,
+		Sandboxed:  true,
 	}
-	return retString, udfArray
 }
 
 func (s *ScriptUDF) Fn(args ...sql.Expression) (sql.Expression, error) {
-	return &Scriptable{Meta: s, args: args}, nil
+	if s.Params != nil && len(args) != len(s.Params) {
+		return nil, sql.ErrInvalidArgumentNumber.New(strings.ToLower(s.Id), len(s.Params), len(args))
+	}
+	return &Scriptable{Meta: s, args: args, calls: new(int64)}, nil
 }
 
 func (s *ScriptUDF) AsFunction() sql.FunctionN {
@@ -182,6 +280,10 @@ func CanBeVariableName(s string) (bool, []string) {
 }
 
 func (a *Scriptable) EvalScript(ctx *sql.Context, row sql.Row, partial interface{}) (interface{}, error) {
+	limits, e := a.limits(ctx)
+	if e != nil {
+		return nil, e
+	}
 	myArgs, params, e := a.__createArgs(ctx, row)
 	if e != nil {
 		return nil, e
@@ -192,14 +294,42 @@ func (a *Scriptable) EvalScript(ctx *sql.Context, row sql.Row, partial interface
 	for k, v := range params {
 		env[k] = v
 	}
+	// the parameters of the function take precedence
+	for i, name := range a.Meta.Params {
+		env[name] = myArgs[i]
+	}
 	// rest of the world
 	env["$ROW"] = row
-	env["$CONTEXT"] = ctx
+	// untrusted scripts can't reach the engine through the context
+	if !limits.Sandboxed {
+		env["$CONTEXT"] = ctx
+	}
 	env["$ARGS"] = myArgs
 	if partial != nil {
 		env["$_"] = partial
 	}
-	value, err := a.Meta.Script.ScriptEval(env)
+	return a.run(ctx, limits, a.Meta.Script, env)
+}
+
+// limits returns the limits of the script in the session and counts a new
+// call, failing if there are too many.
+func (a *Scriptable) limits(ctx *sql.Context) (limits, error) {
+	limits, err := sessionLimits(ctx)
+	if err != nil {
+		return limits, err
+	}
+	limits.Sandboxed = a.Meta.Sandboxed
+	if calls := atomic.AddInt64(a.calls, 1); limits.maxCalls > 0 && calls > limits.maxCalls {
+		return limits, ErrScriptMaxCalls.New(strings.ToLower(a.Meta.Id), limits.maxCalls)
+	}
+	return limits, nil
+}
+
+func (a *Scriptable) run(ctx *sql.Context, limits limits, script ScriptInstance, env map[string]interface{}) (interface{}, error) {
+	value, err := script.ScriptEvalContext(ctx, limits.EvalOptions, env)
+	if err == errTimeout {
+		return nil, ErrScriptTimeout.New(strings.ToLower(a.Meta.Id), limits.Timeout)
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -214,10 +344,16 @@ func (a *Scriptable) __createArgs(ctx *sql.Context, row sql.Row) ([]interface{},
 		if e != nil {
 			return nil, nil, e
 		}
+		if i < len(a.Meta.ParamTypes) && a.Meta.ParamTypes[i] != nil && o != nil {
+			o, e = a.Meta.ParamTypes[i].Convert(o)
+			if e != nil {
+				return nil, nil, e
+			}
+		}
 		myArgs[i] = o
 		varName := a.args[i].String()
 		isVar, paths := CanBeVariableName(varName)
-		if isVar { // setup the named parameters
+		if isVar && len(paths) == 2 { // setup the named parameters
 			if val, ok := params[paths[0]]; ok {
 				//do something here
 				val[paths[1]] = o
@@ -247,10 +383,30 @@ func (a *Scriptable) Resolved() bool {
 }
 
 // Type implements AggregationExpression interface. (AggregationExpression[Expression]])
+// The results of list and set aggregators that are not transposed are JSON
+// lists, whatever the type of their elements.
 func (a *Scriptable) Type() sql.Type {
+	if a.Meta.ReturnType != nil && (a.Meta.UdfType.Transpose || !a.isList()) {
+		return a.Meta.ReturnType
+	}
 	return sql.JSON
 }
 
+// isList returns whether the results of the UDF are lists of elements.
+func (a *Scriptable) isList() bool {
+	switch a.Meta.UdfType.AggregatorType {
+	case ListAggregator, SetAggregator:
+		return true
+	default:
+		return a.Meta.UdfType.Transpose
+	}
+}
+
+// Transposed implements the sql.TransposedAggregation interface.
+func (a *Scriptable) Transposed() bool {
+	return a.Meta.UdfType.Transpose
+}
+
 // IsNullable implements AggregationExpression interface. (AggregationExpression[Expression]])
 func (a *Scriptable) IsNullable() bool {
 	return true
@@ -260,7 +416,11 @@ func (a *Scriptable) IsNullable() bool {
 func (a *Scriptable) Eval(ctx *sql.Context, buffer sql.Row) (interface{}, error) {
 	if a.Meta.initial == nil {
 		// this is where we are non aggregated
-		return a.EvalScript(ctx, buffer, nil)
+		value, err := a.EvalScript(ctx, buffer, nil)
+		if err != nil {
+			return nil, err
+		}
+		return a.convert(value)
 	}
 	// now aggregated ....
 	if a.Meta.UdfType.AggregatorType == SetAggregator {
@@ -271,28 +431,72 @@ func (a *Scriptable) Eval(ctx *sql.Context, buffer sql.Row) (interface{}, error)
 			retList[k] = v
 			k++
 		}
-		return retList, nil
+		return a.convert(retList)
 	} else {
-		return buffer[0], nil
+		return a.convert(buffer[0])
 	}
 }
 
+// convert converts a result of the script to the return type of the UDF.
+// The elements of lists are converted one by one, as the elements of
+// transposed results end up in different rows.
+func (a *Scriptable) convert(value interface{}) (interface{}, error) {
+	typ := a.Meta.ReturnType
+	if typ == nil || value == nil {
+		return value, nil
+	}
+
+	if a.isList() {
+		if val := reflect.ValueOf(value); val.Kind() == reflect.Slice {
+			converted := make([]interface{}, val.Len())
+			for i := range converted {
+				v, err := a.convert1(val.Index(i).Interface())
+				if err != nil {
+					return nil, err
+				}
+				converted[i] = v
+			}
+			return converted, nil
+		}
+	}
+
+	return a.convert1(value)
+}
+
+func (a *Scriptable) convert1(value interface{}) (interface{}, error) {
+	if value == nil {
+		return nil, nil
+	}
+
+	v, err := a.Meta.ReturnType.Convert(value)
+	if err != nil {
+		return nil, ErrInvalidReturnValue.New(strings.ToLower(a.Meta.Id), value, a.Meta.ReturnType, err)
+	}
+	return v, nil
+}
+
 // WithChildren implements the Expression interface.
 func (a *Scriptable) WithChildren(children ...sql.Expression) (sql.Expression, error) {
-	return &Scriptable{args: children, Meta: a.Meta}, nil
+	return &Scriptable{args: children, Meta: a.Meta, calls: a.calls}, nil
 }
 
 // NewBuffer implements AggregationExpression interface. (AggregationExpression)
 func (a *Scriptable) NewBuffer() sql.Row {
-	if a.Meta.UdfType.AggregatorType == GenericAggregator {
+	// every buffer needs its own initial value, as they are modified in place
+	switch a.Meta.UdfType.AggregatorType {
+	case ListAggregator:
+		return sql.NewRow(make([]interface{}, 0))
+	case SetAggregator:
+		return sql.NewRow(make(map[interface{}]bool))
+	case GenericAggregator:
 		initExpr := a.Meta.initial.(string)
 		initExpr = initExpr[1 : len(initExpr)-1]
 		value, err := a.Meta.Script.EvalFromString(initExpr)
 		if err != nil {
 			fmt.Printf("Invalid Expression for Aggregate query '%s' \n", initExpr)
-		} else {
-			a.Meta.initial = value
+			return sql.NewRow(nil)
 		}
+		return sql.NewRow(value)
 	}
 	return sql.NewRow(a.Meta.initial)
 }
@@ -350,6 +554,40 @@ func (a *Scriptable) Update(ctx *sql.Context, buffer, row sql.Row) error {
 }
 
 // Merge implements AggregationExpression interface. (AggregationExpression)
+// Lists are concatenated and sets are joined, but the partial results of
+// generic aggregators can only be merged with their combiner.
 func (a *Scriptable) Merge(ctx *sql.Context, buffer, partial sql.Row) error {
-	return a.accumulate(ctx, buffer, partial)
+	switch a.Meta.UdfType.AggregatorType {
+	case ListAggregator:
+		buffer[0] = append(buffer[0].([]interface{}), partial[0].([]interface{})...)
+	case SetAggregator:
+		dataMap := buffer[0].(map[interface{}]bool)
+		for v := range partial[0].(map[interface{}]bool) {
+			dataMap[v] = true
+		}
+	default:
+		if a.Meta.Combiner == nil {
+			return ErrNoCombiner.New(strings.ToLower(a.Meta.Id))
+		}
+
+		limits, err := a.limits(ctx)
+		if err != nil {
+			return err
+		}
+
+		env := map[string]interface{}{
+			"$_":       buffer[0],
+			"$PARTIAL": partial[0],
+		}
+		if !limits.Sandboxed {
+			env["$CONTEXT"] = ctx
+		}
+
+		value, err := a.run(ctx, limits, a.Meta.Combiner, env)
+		if err != nil {
+			return err
+		}
+		buffer[0] = value
+	}
+	return nil
 }
@@ -1,9 +1,12 @@
 package udf
 
 import (
+	"fmt"
 	"reflect"
 	"testing"
 
+	"github.com/src-d/go-mysql-server/sql"
+	"github.com/src-d/go-mysql-server/sql/expression"
 	"github.com/stretchr/testify/require"
 )
 
@@ -84,3 +87,210 @@ func TestMacroProcessor_Agg_Pivot_Generic(t *testing.T) {
 	assertions.True(udfs[0].UdfType.Transpose)
 
 }
+
+func TestNewStoredUDF(t *testing.T) {
+	assertions := require.New(t)
+	fn := NewStoredUDF(&sql.StoredFunction{
+		Name:       "Scale",
+		Params:     []string{"n", "factor"},
+		ParamTypes: []sql.Type{sql.Int64, nil},
+		Language:   "expr",
+		Body:       "n * factor + len($ARGS)",
+	})
+	assertions.Equal("scale", fn.AsFunction().Name)
+
+	e, err := fn.Fn(
+		expression.NewLiteral("3", sql.Text),
+		expression.NewLiteral(int64(2), sql.Int64),
+	)
+	assertions.NoError(err)
+
+	v, err := e.Eval(sql.NewEmptyContext(), nil)
+	assertions.NoError(err)
+	assertions.EqualValues(8, v)
+
+	_, err = fn.Fn(expression.NewLiteral(int64(2), sql.Int64))
+	assertions.True(sql.ErrInvalidArgumentNumber.Is(err))
+}
+
+func TestScriptable_Limits(t *testing.T) {
+	require := require.New(t)
+
+	eval := func(ctx *sql.Context, body string) error {
+		fn := ScriptUDF{Id: "_auto_1_udf_", Script: GetScriptInstance("js", body)}
+		e, err := fn.Fn()
+		require.NoError(err)
+
+		for i := 0; i < 3; i++ {
+			if _, err := e.Eval(ctx, nil); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	ctx := sql.NewEmptyContext()
+	require.NoError(eval(ctx, "1"))
+
+	ctx.Set(MaxCallsKey, sql.Int64, int64(2))
+	err := eval(ctx, "1")
+	require.True(ErrScriptMaxCalls.Is(err))
+	require.Contains(err.Error(), "_auto_1_udf_")
+
+	ctx = sql.NewEmptyContext()
+	ctx.Set(TimeoutKey, sql.Int8, int8(20))
+	err = eval(ctx, "while (true) {}")
+	require.True(ErrScriptTimeout.Is(err))
+	require.Contains(err.Error(), "_auto_1_udf_ was aborted after 20ms")
+
+	fn := ScriptUDF{Id: "_auto_1_udf_", Script: GetScriptInstance("js", "typeof $CONTEXT")}
+	e, err := fn.Fn()
+	require.NoError(err)
+
+	ctx = sql.NewEmptyContext()
+	v, err := e.Eval(ctx, nil)
+	require.NoError(err)
+	require.Equal("object", v)
+
+	// Sandboxing is up to whoever creates the script, not the session.
+	fn.Sandboxed = true
+	e, err = fn.Fn()
+	require.NoError(err)
+
+	v, err = e.Eval(ctx, nil)
+	require.NoError(err)
+	require.Equal("undefined", v)
+
+	stored := NewStoredUDF(&sql.StoredFunction{Name: "f", Language: "js", Body: "typeof $CONTEXT"})
+	require.True(stored.Sandboxed)
+	e, err = stored.Fn()
+	require.NoError(err)
+
+	v, err = e.Eval(ctx, nil)
+	require.NoError(err)
+	require.Equal("undefined", v)
+}
+
+func TestMacroProcessor_ReturnType(t *testing.T) {
+	require := require.New(t)
+
+	var c *ScriptCache
+	q, udfs, err := c.MacroProcessor("SELECT <?:INT64 @{mytable.x} * 2 ?> FROM mytable", 0, "js")
+	require.NoError(err)
+	require.Equal("SELECT _auto_1_udf_(mytable.x) FROM mytable", q)
+	require.Len(udfs, 1)
+	require.Equal(sql.Int64, udfs[0].ReturnType)
+	require.Equal("mytable.x * 2 ", udfs[0].Script.Body())
+
+	q, udfs, err = c.MacroProcessor("SELECT <?:double AGG@ 0 # $_ + @{mytable.x} ?> FROM mytable", 0, "js")
+	require.NoError(err)
+	require.Equal("SELECT fold_auto_1_udf_(mytable.x) FROM mytable", q)
+	require.Equal(sql.Float64, udfs[0].ReturnType)
+	require.Equal(GenericAggregator, udfs[0].UdfType.AggregatorType)
+
+	_, udfs, err = c.MacroProcessor("SELECT <? @{mytable.x} ?> FROM mytable", 0, "js")
+	require.NoError(err)
+	require.Nil(udfs[0].ReturnType)
+
+	s := "SELECT <?:FOO @{mytable.x} ?> FROM mytable"
+	_, _, err = c.MacroProcessor(s, 0, "js")
+	require.True(ErrUnknownReturnType.Is(err))
+
+	q, udfs = MacroProcessor(s, 0, "js")
+	require.Equal(s, q)
+	require.Nil(udfs)
+}
+
+func TestScriptable_ReturnType(t *testing.T) {
+	require := require.New(t)
+	ctx := sql.NewEmptyContext()
+
+	fn := ScriptUDF{Id: "_auto_1_udf_", Script: GetScriptInstance("js", "$ARGS[0] + '1'")}
+	e, err := fn.Fn(expression.NewLiteral("4", sql.Text))
+	require.NoError(err)
+	require.Equal(sql.JSON, e.Type())
+	v, err := e.Eval(ctx, nil)
+	require.NoError(err)
+	require.Equal("41", v)
+
+	fn.ReturnType = sql.Int64
+	require.Equal(sql.Int64, e.Type())
+	v, err = e.Eval(ctx, nil)
+	require.NoError(err)
+	require.Equal(int64(41), v)
+
+	e, err = fn.Fn(expression.NewLiteral("x", sql.Text))
+	require.NoError(err)
+	_, err = e.Eval(ctx, nil)
+	require.True(ErrInvalidReturnValue.Is(err))
+	require.Contains(err.Error(), "_auto_1_udf_")
+}
+
+func TestScriptable_Merge(t *testing.T) {
+	rows := make([]sql.Row, 100)
+	for i := range rows {
+		rows[i] = sql.NewRow(int64(i % 10))
+	}
+
+	// aggregate evaluates the macro over all the rows at once and splitting
+	// them into partial aggregations that are merged afterwards.
+	aggregate := func(t *testing.T, macro string) (interface{}, interface{}, error) {
+		require := require.New(t)
+		ctx := sql.NewEmptyContext()
+
+		_, udfs := MacroProcessor("SELECT "+macro+" FROM t", 0, "js")
+		require.Len(udfs, 1)
+		e, err := udfs[0].Fn(expression.NewGetFieldWithTable(0, sql.Int64, "t", "x", false))
+		require.NoError(err)
+		agg := e.(*Scriptable)
+
+		buffer := agg.NewBuffer()
+		for _, row := range rows {
+			require.NoError(agg.Update(ctx, buffer, row))
+		}
+		expected, err := agg.Eval(ctx, buffer)
+		require.NoError(err)
+
+		const partitions = 7
+		buffer = agg.NewBuffer()
+		for p := 0; p < partitions; p++ {
+			partial := agg.NewBuffer()
+			for i := p; i < len(rows); i += partitions {
+				require.NoError(agg.Update(ctx, partial, rows[i]))
+			}
+			if err := agg.Merge(ctx, buffer, partial); err != nil {
+				return nil, nil, err
+			}
+		}
+		merged, err := agg.Eval(ctx, buffer)
+		require.NoError(err)
+		return expected, merged, nil
+	}
+
+	t.Run("list", func(t *testing.T) {
+		expected, merged, err := aggregate(t, "<?L__@ @{t.x} ?>")
+		require.NoError(t, err)
+		require.Len(t, merged, 100)
+		require.ElementsMatch(t, expected, merged)
+	})
+
+	t.Run("set", func(t *testing.T) {
+		expected, merged, err := aggregate(t, "<?S__@ @{t.x} ?>")
+		require.NoError(t, err)
+		require.Len(t, merged, 10)
+		require.ElementsMatch(t, expected, merged)
+	})
+
+	t.Run("generic with combiner", func(t *testing.T) {
+		expected, merged, err := aggregate(t, "<?AGG@ [0, 0] # $_[0] += @{t.x}; $_[1]++; $_ # [$_[0] + $PARTIAL[0], $_[1] + $PARTIAL[1]] ?>")
+		require.NoError(t, err)
+		// numbers may be exported as integers or floats
+		require.Equal(t, "[450 100]", fmt.Sprint(expected))
+		require.Equal(t, "[450 100]", fmt.Sprint(merged))
+	})
+
+	t.Run("generic without combiner", func(t *testing.T) {
+		_, _, err := aggregate(t, "<?AGG@ 0 # $_ + @{t.x} ?>")
+		require.True(t, ErrNoCombiner.Is(err))
+	})
+}
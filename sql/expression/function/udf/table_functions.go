@@ -0,0 +1,41 @@
+package udf
+
+import (
+	"github.com/src-d/go-mysql-server/sql"
+	"github.com/src-d/go-mysql-server/sql/expression"
+	errors "gopkg.in/src-d/go-errors.v1"
+)
+
+// ErrInvalidScriptArgument is returned when the script of a script table
+// function is not a string literal.
+var ErrInvalidScriptArgument = errors.NewKind("the script of %s must be a string literal, but it is %s")
+
+// TableFunctions are the functions that run the script given as their
+// first argument, with the rest of the arguments in $ARGS. They are meant to
+// be used as tables, as in `SELECT * FROM js_table('[{a: 1}, {a: 2}]')`,
+// where every element of the list returned by the script is a row.
+var TableFunctions = []sql.Function{
+	sql.FunctionN{Name: "js_table", Fn: newScriptTableFunction("js_table", "js")},
+	sql.FunctionN{Name: "expr_table", Fn: newScriptTableFunction("expr_table", "expr")},
+}
+
+func newScriptTableFunction(name, lang string) func(...sql.Expression) (sql.Expression, error) {
+	return func(args ...sql.Expression) (sql.Expression, error) {
+		if len(args) == 0 {
+			return nil, sql.ErrInvalidArgumentNumber.New(name, "1 or more", 0)
+		}
+
+		lit, ok := args[0].(*expression.Literal)
+		if !ok {
+			return nil, ErrInvalidScriptArgument.New(name, args[0])
+		}
+
+		body, ok := lit.Value().(string)
+		if !ok {
+			return nil, ErrInvalidScriptArgument.New(name, args[0])
+		}
+
+		fn := &ScriptUDF{Id: name, Script: GetScriptInstance(lang, body)}
+		return fn.Fn(args[1:]...)
+	}
+}
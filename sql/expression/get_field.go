@@ -94,6 +94,32 @@ func (p *GetField) WithIndex(n int) sql.Expression {
 	return &p2
 }
 
+// OuterField is an expression to get a value of the row of the outer query of
+// a correlated subquery. The values of the outer row used by the subquery are
+// given to it in the context, so the index is the position of the value among
+// them.
+type OuterField struct {
+	GetField
+}
+
+// NewOuterField creates an OuterField expression.
+func NewOuterField(index int, fieldType sql.Type, table, fieldName string, nullable bool) *OuterField {
+	return &OuterField{*NewGetFieldWithTable(index, fieldType, table, fieldName, nullable)}
+}
+
+// Eval implements the Expression interface.
+func (p *OuterField) Eval(ctx *sql.Context, _ sql.Row) (interface{}, error) {
+	return p.GetField.Eval(ctx, ctx.OuterRow())
+}
+
+// WithChildren implements the Expression interface.
+func (p *OuterField) WithChildren(children ...sql.Expression) (sql.Expression, error) {
+	if len(children) != 0 {
+		return nil, sql.ErrInvalidChildrenNumber.New(p, len(children), 0)
+	}
+	return p, nil
+}
+
 // GetSessionField is an expression that returns the value of a session configuration.
 type GetSessionField struct {
 	name  string
@@ -2,6 +2,7 @@ package expression
 
 import (
 	"fmt"
+	"io"
 
 	"github.com/src-d/go-mysql-server/sql"
 	errors "gopkg.in/src-d/go-errors.v1"
@@ -9,19 +10,28 @@ import (
 
 var errExpectedSingleRow = errors.NewKind("the subquery returned more than 1 row")
 
-// Subquery that is executed as an expression.
+// Subquery that is executed as an expression. If the subquery is correlated,
+// the values of the outer row it uses are the result of the Outer
+// expressions, which are the children of the subquery, and it's executed
+// again for each row.
 type Subquery struct {
 	Query sql.Node
+	Outer []sql.Expression
 	value interface{}
 }
 
 // NewSubquery returns a new subquery node.
 func NewSubquery(node sql.Node) *Subquery {
-	return &Subquery{node, nil}
+	return &Subquery{node, nil, nil}
+}
+
+// Correlated returns whether the subquery uses values of the outer row.
+func (s *Subquery) Correlated() bool {
+	return len(s.Outer) > 0
 }
 
 // Eval implements the Expression interface.
-func (s *Subquery) Eval(ctx *sql.Context, _ sql.Row) (interface{}, error) {
+func (s *Subquery) Eval(ctx *sql.Context, row sql.Row) (interface{}, error) {
 	if s.value != nil {
 		if elems, ok := s.value.([]interface{}); ok {
 			if len(elems) > 1 {
@@ -32,18 +42,12 @@ func (s *Subquery) Eval(ctx *sql.Context, _ sql.Row) (interface{}, error) {
 		return s.value, nil
 	}
 
-	iter, err := s.Query.RowIter(ctx)
-	if err != nil {
-		return nil, err
-	}
-
-	rows, err := sql.RowIterToRows(iter)
+	rows, err := s.evalRows(ctx, row)
 	if err != nil {
 		return nil, err
 	}
 
 	if len(rows) == 0 {
-		s.value = nil
 		return nil, nil
 	}
 
@@ -51,38 +55,88 @@ func (s *Subquery) Eval(ctx *sql.Context, _ sql.Row) (interface{}, error) {
 		return nil, errExpectedSingleRow.New()
 	}
 
-	s.value = rows[0][0]
-	return s.value, nil
+	if !s.Correlated() {
+		s.value = rows[0][0]
+	}
+
+	return rows[0][0], nil
 }
 
 // EvalMultiple returns all rows returned by a subquery.
-func (s *Subquery) EvalMultiple(ctx *sql.Context) ([]interface{}, error) {
+func (s *Subquery) EvalMultiple(ctx *sql.Context, row sql.Row) ([]interface{}, error) {
 	if s.value != nil {
 		return s.value.([]interface{}), nil
 	}
 
+	rows, err := s.evalRows(ctx, row)
+	if err != nil {
+		return nil, err
+	}
+
+	var result = make([]interface{}, len(rows))
+	for i, row := range rows {
+		result[i] = row[0]
+	}
+
+	if !s.Correlated() {
+		s.value = result
+	}
+
+	return result, nil
+}
+
+// HasRows returns whether the subquery returns any row.
+func (s *Subquery) HasRows(ctx *sql.Context, row sql.Row) (bool, error) {
+	ctx, err := s.outerContext(ctx, row)
+	if err != nil {
+		return false, err
+	}
+
 	iter, err := s.Query.RowIter(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	_, err = iter.Next()
+	if err != nil && err != io.EOF {
+		_ = iter.Close()
+		return false, err
+	}
+
+	return err == nil, iter.Close()
+}
+
+func (s *Subquery) evalRows(ctx *sql.Context, row sql.Row) ([]sql.Row, error) {
+	ctx, err := s.outerContext(ctx, row)
 	if err != nil {
 		return nil, err
 	}
 
-	rows, err := sql.RowIterToRows(iter)
+	iter, err := s.Query.RowIter(ctx)
 	if err != nil {
 		return nil, err
 	}
 
-	if len(rows) == 0 {
-		s.value = []interface{}{}
-		return nil, nil
+	return sql.RowIterToRows(iter)
+}
+
+// outerContext returns the context the subquery is run with, which has the
+// values of the outer row it uses.
+func (s *Subquery) outerContext(ctx *sql.Context, row sql.Row) (*sql.Context, error) {
+	if !s.Correlated() {
+		return ctx, nil
 	}
 
-	var result = make([]interface{}, len(rows))
-	for i, row := range rows {
-		result[i] = row[0]
+	outer := make(sql.Row, len(s.Outer))
+	for i, e := range s.Outer {
+		v, err := e.Eval(ctx, row)
+		if err != nil {
+			return nil, err
+		}
+		outer[i] = v
 	}
-	s.value = result
 
-	return result, nil
+	return ctx.WithOuterRow(outer), nil
 }
 
 // IsNullable implements the Expression interface.
@@ -96,6 +150,11 @@ func (s *Subquery) String() string {
 
 // Resolved implements the Expression interface.
 func (s *Subquery) Resolved() bool {
+	for _, e := range s.Outer {
+		if !e.Resolved() {
+			return false
+		}
+	}
 	return s.Query.Resolved()
 }
 
@@ -106,20 +165,35 @@ func (s *Subquery) Type() sql.Type {
 
 // WithChildren implements the Expression interface.
 func (s *Subquery) WithChildren(children ...sql.Expression) (sql.Expression, error) {
-	if len(children) != 0 {
-		return nil, sql.ErrInvalidChildrenNumber.New(s, len(children), 0)
+	if len(children) != len(s.Outer) {
+		return nil, sql.ErrInvalidChildrenNumber.New(s, len(children), len(s.Outer))
 	}
-	return s, nil
+
+	if len(children) == 0 {
+		return s, nil
+	}
+
+	return s.WithOuter(children), nil
 }
 
 // Children implements the Expression interface.
 func (s *Subquery) Children() []sql.Expression {
-	return nil
+	return s.Outer
 }
 
 // WithQuery returns the subquery with the query node changed.
 func (s *Subquery) WithQuery(node sql.Node) *Subquery {
 	ns := *s
 	ns.Query = node
+	ns.value = nil
+	return &ns
+}
+
+// WithOuter returns the subquery with the expressions of the outer row it
+// uses changed.
+func (s *Subquery) WithOuter(outer []sql.Expression) *Subquery {
+	ns := *s
+	ns.Outer = outer
+	ns.value = nil
 	return &ns
 }
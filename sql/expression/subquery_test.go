@@ -63,7 +63,7 @@ func TestSubqueryMultipleRows(t *testing.T) {
 		plan.NewResolvedTable(table),
 	))
 
-	values, err := subquery.EvalMultiple(ctx)
+	values, err := subquery.EvalMultiple(ctx, nil)
 	require.NoError(err)
 	require.Equal(values, []interface{}{"one", "two", "three"})
 }
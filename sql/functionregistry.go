@@ -202,6 +202,11 @@ func (r FunctionRegistry) MustRegister(fn ...Function) {
 	}
 }
 
+// Unregister removes the function with the given name, if any.
+func (r FunctionRegistry) Unregister(name string) {
+	delete(r, name)
+}
+
 // Function returns a function with the given name.
 func (r FunctionRegistry) Function(name string) (Function, error) {
 	if len(r) == 0 {
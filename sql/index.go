@@ -4,6 +4,7 @@ import (
 	"io"
 	"strings"
 	"sync"
+	"sync/atomic"
 
 	"github.com/src-d/go-mysql-server/internal/similartext"
 
@@ -159,6 +160,10 @@ type indexKey struct {
 
 // IndexRegistry keeps track of all indexes in the engine.
 type IndexRegistry struct {
+	// generation is increased every time the indexes that can be used
+	// change. It's the first field so it's aligned for atomic operations.
+	generation uint64
+
 	// Root path where all the data of the indexes is stored on disk.
 	Root string
 
@@ -267,13 +272,21 @@ func (r *IndexRegistry) LoadIndexes(dbs Databases) error {
 		}
 	}
 
+	atomic.AddUint64(&r.generation, 1)
 	return nil
 }
 
 // MarkOutdated sets the index status as outdated. This method is not thread
 // safe and should not be used directly except for testing.
 func (r *IndexRegistry) MarkOutdated(idx Index) {
-	r.statuses[indexKey{idx.Database(), idx.ID()}] = IndexOutdated
+	r.setStatus(idx, IndexOutdated)
+}
+
+// IndexGeneration returns a number that changes every time an index is
+// added, becomes ready or is deleted, so the plans using the indexes known
+// at some point can tell when they are outdated.
+func (r *IndexRegistry) IndexGeneration() uint64 {
+	return atomic.LoadUint64(&r.generation)
 }
 
 func (r *IndexRegistry) retainIndex(db, id string) {
@@ -312,6 +325,7 @@ func (r *IndexRegistry) canUseIndex(idx Index) bool {
 // setStatus is not thread-safe, it should be guarded using mut.
 func (r *IndexRegistry) setStatus(idx Index, status IndexStatus) {
 	r.statuses[indexKey{idx.Database(), idx.ID()}] = status
+	atomic.AddUint64(&r.generation, 1)
 }
 
 // ReleaseIndex releases an index after it's been used.
@@ -586,6 +600,7 @@ func (r *IndexRegistry) DeleteIndex(db, id string, force bool) (<-chan struct{},
 		defer r.rcmut.Unlock()
 
 		delete(r.indexes, key)
+		atomic.AddUint64(&r.generation, 1)
 		var pos = -1
 		for i, k := range r.indexOrder {
 			if k == key {
@@ -609,6 +624,7 @@ func (r *IndexRegistry) DeleteIndex(db, id string, force bool) (<-chan struct{},
 		r.mut.Lock()
 		defer r.mut.Unlock()
 		delete(r.indexes, key)
+		atomic.AddUint64(&r.generation, 1)
 
 		done <- struct{}{}
 	}()
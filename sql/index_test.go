@@ -429,3 +429,34 @@ type checksumIndex struct {
 func (idx *checksumIndex) Checksum() (string, error) {
 	return idx.checksum, nil
 }
+
+func TestIndexGeneration(t *testing.T) {
+	require := require.New(t)
+	r := NewIndexRegistry()
+	idx := &dummyIdx{
+		id:       "foo",
+		expr:     []Expression{new(dummyExpr)},
+		database: "foo",
+		table:    "foo",
+	}
+
+	generation := r.IndexGeneration()
+
+	done, ready, err := r.AddIndex(idx)
+	require.NoError(err)
+	done <- struct{}{}
+	<-ready
+
+	require.True(r.IndexGeneration() > generation)
+	generation = r.IndexGeneration()
+
+	r.MarkOutdated(idx)
+	require.True(r.IndexGeneration() > generation)
+	generation = r.IndexGeneration()
+
+	deleted, err := r.DeleteIndex("foo", "foo", true)
+	require.NoError(err)
+	<-deleted
+
+	require.True(r.IndexGeneration() > generation)
+}
@@ -20,6 +20,8 @@ const (
 	ColumnsTableName = "columns"
 	// SchemataTableName is the name of the schemata table.
 	SchemataTableName = "schemata"
+	// ViewsTableName is the name of the views table.
+	ViewsTableName = "views"
 )
 
 type informationSchemaDatabase struct {
@@ -31,7 +33,7 @@ type informationSchemaTable struct {
 	name    string
 	schema  Schema
 	catalog *Catalog
-	rowIter func(*Catalog) RowIter
+	rowIter func(*Context, *Catalog) RowIter
 }
 
 type informationSchemaPartition struct {
@@ -154,7 +156,20 @@ var schemataSchema = Schema{
 	{Name: "sql_path", Type: Text, Default: nil, Nullable: true, Source: SchemataTableName},
 }
 
-func tablesRowIter(cat *Catalog) RowIter {
+var viewsSchema = Schema{
+	{Name: "table_catalog", Type: Text, Default: "", Nullable: false, Source: ViewsTableName},
+	{Name: "table_schema", Type: Text, Default: "", Nullable: false, Source: ViewsTableName},
+	{Name: "table_name", Type: Text, Default: "", Nullable: false, Source: ViewsTableName},
+	{Name: "view_definition", Type: Text, Default: "", Nullable: false, Source: ViewsTableName},
+	{Name: "check_option", Type: Text, Default: "", Nullable: false, Source: ViewsTableName},
+	{Name: "is_updatable", Type: Text, Default: "", Nullable: false, Source: ViewsTableName},
+	{Name: "definer", Type: Text, Default: "", Nullable: false, Source: ViewsTableName},
+	{Name: "security_type", Type: Text, Default: "", Nullable: false, Source: ViewsTableName},
+	{Name: "character_set_client", Type: Text, Default: "", Nullable: false, Source: ViewsTableName},
+	{Name: "collation_connection", Type: Text, Default: "", Nullable: false, Source: ViewsTableName},
+}
+
+func tablesRowIter(ctx *Context, cat *Catalog) RowIter {
 	var rows []Row
 	for _, db := range cat.AllDatabases() {
 		tableType := "BASE TABLE"
@@ -166,6 +181,11 @@ func tablesRowIter(cat *Catalog) RowIter {
 			rowFormat = "Fixed"
 		}
 		for _, t := range db.Tables() {
+			var tableRows interface{}
+			if stats := tableStatistics(ctx, t); stats != nil {
+				tableRows = stats.RowCount
+			}
+
 			rows = append(rows, Row{
 				"def",      //table_catalog
 				db.Name(),  // table_schema
@@ -174,7 +194,7 @@ func tablesRowIter(cat *Catalog) RowIter {
 				engine,     // engine
 				10,         //version (protocol, always 10)
 				rowFormat,  //row_format
-				nil,        //table_rows
+				tableRows,  //table_rows
 				nil,        //avg_row_length
 				nil,        //data_length
 				nil,        //max_data_length
@@ -190,12 +210,38 @@ func tablesRowIter(cat *Catalog) RowIter {
 				"",         //table_comment
 			})
 		}
+
+		for _, v := range cat.ViewsInDatabase(db.Name()) {
+			rows = append(rows, Row{
+				"def",     //table_catalog
+				db.Name(), // table_schema
+				v.Name(),  // table_name
+				"VIEW",    // table_type
+				nil,       // engine
+				nil,       //version
+				nil,       //row_format
+				nil,       //table_rows
+				nil,       //avg_row_length
+				nil,       //data_length
+				nil,       //max_data_length
+				nil,       //max_data_length
+				nil,       //data_free
+				nil,       //auto_increment
+				nil,       //create_time
+				nil,       //update_time
+				nil,       //check_time
+				nil,       //table_collation
+				nil,       //checksum
+				nil,       //create_options
+				"VIEW",    //table_comment
+			})
+		}
 	}
 
 	return RowsToRowIter(rows...)
 }
 
-func columnsRowIter(cat *Catalog) RowIter {
+func columnsRowIter(ctx *Context, cat *Catalog) RowIter {
 	var rows []Row
 	for _, db := range cat.AllDatabases() {
 		for _, t := range db.Tables() {
@@ -243,7 +289,93 @@ func columnsRowIter(cat *Catalog) RowIter {
 	return RowsToRowIter(rows...)
 }
 
-func schemataRowIter(c *Catalog) RowIter {
+func columnStatisticsRowIter(ctx *Context, cat *Catalog) RowIter {
+	var rows []Row
+	for _, db := range cat.AllDatabases() {
+		for _, t := range db.Tables() {
+			stats := tableStatistics(ctx, t)
+			if stats == nil {
+				continue
+			}
+
+			for _, c := range stats.Columns {
+				rows = append(rows, Row{
+					db.Name(),                   // schema_name
+					t.Name(),                    // table_name
+					c.Name,                      // column_name
+					histogramDocument(stats, c), // histogram
+				})
+			}
+		}
+	}
+
+	return RowsToRowIter(rows...)
+}
+
+// tableStatistics returns the statistics of the given table, or nil if it
+// has none or they can't be retrieved.
+func tableStatistics(ctx *Context, t Table) *TableStatistics {
+	st, ok := t.(StatisticsTable)
+	if !ok {
+		return nil
+	}
+
+	stats, err := st.Statistics(ctx)
+	if err != nil {
+		ctx.Warn(0, "unable to get the statistics of table %s: %s", t.Name(), err)
+		return nil
+	}
+
+	return stats
+}
+
+// histogramDocument returns the statistics of a column as the JSON document
+// of MySQL histograms, with the number of distinct values and the smallest
+// and greatest values in addition.
+func histogramDocument(stats *TableStatistics, c *ColumnStatistics) interface{} {
+	buckets := make([]interface{}, len(c.Histogram))
+	for i, b := range c.Histogram {
+		buckets[i] = []interface{}{
+			b.LowerBound,
+			b.UpperBound,
+			b.CumulativeFrequency,
+			b.DistinctCount,
+		}
+	}
+
+	return map[string]interface{}{
+		"buckets":                     buckets,
+		"data-type":                   histogramDataType(c.Type),
+		"null-values":                 c.NullFraction,
+		"collation-id":                8,
+		"last-updated":                stats.Updated.UTC().Format("2006-01-02 15:04:05.000000"),
+		"sampling-rate":               1.0,
+		"histogram-type":              "equi-height",
+		"number-of-buckets-specified": DefaultHistogramBuckets,
+		"distinct-values":             c.DistinctCount,
+		"min-value":                   c.Min,
+		"max-value":                   c.Max,
+	}
+}
+
+func histogramDataType(t Type) string {
+	switch {
+	case IsSigned(t):
+		return "int"
+	case IsUnsigned(t):
+		return "uint"
+	case IsDecimal(t):
+		return "double"
+	case t == Date:
+		return "date"
+	case IsTime(t):
+		return "datetime"
+	default:
+		return "string"
+	}
+}
+
+func schemataRowIter(ctx *Context, c *Catalog) RowIter {
 	dbs := c.AllDatabases()
 
 	var rows []Row
@@ -264,6 +396,28 @@ func schemataRowIter(c *Catalog) RowIter {
 	return RowsToRowIter(rows...)
 }
 
+func viewsRowIter(ctx *Context, cat *Catalog) RowIter {
+	var rows []Row
+	for _, db := range cat.AllDatabases() {
+		for _, v := range cat.ViewsInDatabase(db.Name()) {
+			rows = append(rows, Row{
+				"def",            // table_catalog
+				db.Name(),        // table_schema
+				v.Name(),         // table_name
+				v.TextDefinition, // view_definition
+				"NONE",           // check_option
+				"NO",             // is_updatable
+				"root@localhost", // definer
+				"DEFINER",        // security_type
+				"utf8mb4",        // character_set_client
+				"utf8_bin",       // collation_connection
+			})
+		}
+	}
+
+	return RowsToRowIter(rows...)
+}
+
 // NewInformationSchemaDatabase creates a new INFORMATION_SCHEMA Database.
 func NewInformationSchemaDatabase(cat *Catalog) Database {
 	return &informationSchemaDatabase{
@@ -278,6 +432,7 @@ func NewInformationSchemaDatabase(cat *Catalog) Database {
 				name:    ColumnStatisticsTableName,
 				schema:  columnStatisticsSchema,
 				catalog: cat,
+				rowIter: columnStatisticsRowIter,
 			},
 			TablesTableName: &informationSchemaTable{
 				name:    TablesTableName,
@@ -297,6 +452,12 @@ func NewInformationSchemaDatabase(cat *Catalog) Database {
 				catalog: cat,
 				rowIter: schemataRowIter,
 			},
+			ViewsTableName: &informationSchemaTable{
+				name:    ViewsTableName,
+				schema:  viewsSchema,
+				catalog: cat,
+				rowIter: viewsRowIter,
+			},
 		},
 	}
 }
@@ -333,7 +494,7 @@ func (t *informationSchemaTable) PartitionRows(ctx *Context, partition Partition
 		return RowsToRowIter(), nil
 	}
 
-	return t.rowIter(t.catalog), nil
+	return t.rowIter(ctx, t.catalog), nil
 }
 
 // PartitionCount implements the sql.PartitionCounter interface.
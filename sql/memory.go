@@ -107,6 +107,7 @@ type MemoryManager struct {
 	reporter Reporter
 	caches   map[uint64]Disposable
 	token    uint64
+	tempDir  string
 }
 
 // NewMemoryManager creates a new manager with the given memory reporter. If nil is given,
@@ -127,6 +128,26 @@ func (m *MemoryManager) HasAvailable() bool {
 	return HasAvailableMemory(m.reporter)
 }
 
+// SetTempDir sets the directory where rows are stored when they don't fit in
+// memory. If it's empty, the default directory for temporary files is used.
+func (m *MemoryManager) SetTempDir(dir string) {
+	m.mu.Lock()
+	m.tempDir = dir
+	m.mu.Unlock()
+}
+
+// TempDir returns the directory where rows are stored when they don't fit in
+// memory.
+func (m *MemoryManager) TempDir() string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if m.tempDir == "" {
+		return os.TempDir()
+	}
+	return m.tempDir
+}
+
 // DisposeFunc is a function to completely erase a cache and remove it from the manager.
 type DisposeFunc func()
 
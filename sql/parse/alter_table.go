@@ -0,0 +1,222 @@
+package parse
+
+import (
+	"bufio"
+	"regexp"
+	"strings"
+	"unicode"
+
+	"github.com/src-d/go-mysql-server/sql"
+	"github.com/src-d/go-mysql-server/sql/plan"
+	"vitess.io/vitess/go/vt/sqlparser"
+)
+
+var columnOrderRegex = regexp.MustCompile("(?is)^(.*?)\\s+(first|after\\s+(`[^`]+`|\\w+))\\s*$")
+
+func parseAlterTable(s string) (sql.Node, error) {
+	r := bufio.NewReader(strings.NewReader(s))
+
+	var db, table, action string
+	err := parseFuncs{
+		expect("alter"),
+		skipSpaces,
+		expect("table"),
+		skipSpaces,
+		readQualifiedIdent(&db, &table),
+		skipSpaces,
+		readIdent(&action),
+		skipSpaces,
+	}.exec(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var rest string
+	if err := readRemaining(&rest)(r); err != nil {
+		return nil, err
+	}
+
+	if hasTopLevelComma(rest) {
+		return nil, ErrUnsupportedFeature.New("multiple ALTER TABLE actions")
+	}
+
+	r = bufio.NewReader(strings.NewReader(rest))
+	database := sql.UnresolvedDatabase(db)
+
+	switch action {
+	case "add":
+		skipColumnKeyword(r)
+		column, order, err := readColumnDefinition(r)
+		if err != nil {
+			return nil, err
+		}
+
+		return plan.NewAddColumn(database, table, column, order), nil
+	case "drop":
+		skipColumnKeyword(r)
+		var column string
+		err := parseFuncs{
+			readQuotableIdent(&column),
+			skipSpaces,
+			checkEOF,
+		}.exec(r)
+		if err != nil {
+			return nil, err
+		}
+
+		return plan.NewDropColumn(database, table, column), nil
+	case "modify":
+		skipColumnKeyword(r)
+		column, order, err := readColumnDefinition(r)
+		if err != nil {
+			return nil, err
+		}
+
+		return plan.NewModifyColumn(database, table, column.Name, column, order), nil
+	case "change":
+		skipColumnKeyword(r)
+		var name string
+		err := parseFuncs{
+			readQuotableIdent(&name),
+			skipSpaces,
+		}.exec(r)
+		if err != nil {
+			return nil, err
+		}
+
+		column, order, err := readColumnDefinition(r)
+		if err != nil {
+			return nil, err
+		}
+
+		return plan.NewModifyColumn(database, table, name, column, order), nil
+	case "rename":
+		return parseAlterTableRename(r, database, table)
+	default:
+		return nil, ErrUnsupportedFeature.New("ALTER TABLE " + strings.ToUpper(action))
+	}
+}
+
+func parseAlterTableRename(r *bufio.Reader, db sql.Database, table string) (sql.Node, error) {
+	var word string
+	if err := readQuotableIdent(&word)(r); err != nil {
+		return nil, err
+	}
+
+	if word == "column" {
+		var oldName, newName string
+		err := parseFuncs{
+			skipSpaces,
+			readQuotableIdent(&oldName),
+			skipSpaces,
+			expect("to"),
+			skipSpaces,
+			readQuotableIdent(&newName),
+			skipSpaces,
+			checkEOF,
+		}.exec(r)
+		if err != nil {
+			return nil, err
+		}
+
+		return plan.NewRenameColumn(db, table, oldName, newName), nil
+	}
+
+	// The TO and AS keywords are optional, but they can also be the new name
+	// of the table.
+	if err := skipSpaces(r); err != nil {
+		return nil, err
+	}
+
+	if _, err := r.Peek(1); err != nil || (word != "to" && word != "as") {
+		unreadString(r, word)
+	}
+
+	var newDB, newName string
+	err := parseFuncs{
+		readQualifiedIdent(&newDB, &newName),
+		skipSpaces,
+		checkEOF,
+	}.exec(r)
+	if err != nil {
+		return nil, err
+	}
+
+	if newDB != "" && newDB != db.Name() {
+		return nil, ErrUnsupportedFeature.New("renaming tables between databases")
+	}
+
+	return plan.NewRenameTable(db, []string{table}, []string{newName}), nil
+}
+
+// skipColumnKeyword skips the optional COLUMN keyword that may precede a
+// column in ALTER TABLE actions.
+func skipColumnKeyword(r *bufio.Reader) {
+	bs, err := r.Peek(len("column "))
+	if err == nil && strings.ToLower(string(bs[:6])) == "column" && unicode.IsSpace(rune(bs[6])) {
+		_, _ = r.Discard(len("column "))
+		_ = skipSpaces(r)
+	}
+}
+
+// readColumnDefinition reads the rest of the input as a column definition,
+// optionally followed by its position in the table.
+func readColumnDefinition(r *bufio.Reader) (*sql.Column, *sql.ColumnOrder, error) {
+	var definition string
+	if err := readRemaining(&definition)(r); err != nil {
+		return nil, nil, err
+	}
+
+	var order *sql.ColumnOrder
+	if m := columnOrderRegex.FindStringSubmatch(definition); m != nil {
+		definition = m[1]
+		if strings.ToLower(m[2]) == "first" {
+			order = &sql.ColumnOrder{First: true}
+		} else {
+			order = &sql.ColumnOrder{AfterColumn: strings.Trim(m[3], "`")}
+		}
+	}
+
+	// The definition is parsed as part of a CREATE TABLE statement, which is
+	// the only place where vitess keeps column definitions.
+	stmt, err := sqlparser.ParseStrictDDL("create table t (" + definition + ")")
+	if err != nil {
+		return nil, nil, err
+	}
+
+	ddl, ok := stmt.(*sqlparser.DDL)
+	if !ok || ddl.TableSpec == nil || len(ddl.TableSpec.Columns) != 1 || len(ddl.TableSpec.Indexes) > 0 {
+		return nil, nil, ErrUnsupportedSyntax.New(definition)
+	}
+
+	column, err := getColumn(ddl.TableSpec.Columns[0], nil)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return column, order, nil
+}
+
+// hasTopLevelComma returns whether the given string contains a comma outside
+// of parenthesis and quotes.
+func hasTopLevelComma(s string) bool {
+	var depth int
+	var quote rune
+	for _, ru := range s {
+		switch {
+		case quote != 0:
+			if ru == quote {
+				quote = 0
+			}
+		case ru == '\'' || ru == '"' || ru == '`':
+			quote = ru
+		case ru == '(':
+			depth++
+		case ru == ')':
+			depth--
+		case ru == ',' && depth == 0:
+			return true
+		}
+	}
+	return false
+}
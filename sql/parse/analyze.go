@@ -0,0 +1,102 @@
+package parse
+
+import (
+	"bufio"
+	"io"
+	"strings"
+
+	"github.com/src-d/go-mysql-server/sql"
+	"github.com/src-d/go-mysql-server/sql/plan"
+)
+
+func parseAnalyzeTable(s string) (sql.Node, error) {
+	r := bufio.NewReader(strings.NewReader(s))
+
+	var word string
+	err := parseFuncs{
+		expect("analyze"),
+		skipSpaces,
+		readIdent(&word),
+		skipSpaces,
+	}.exec(r)
+	if err != nil {
+		return nil, err
+	}
+
+	// NO_WRITE_TO_BINLOG and LOCAL don't make any difference because there
+	// is no binary log.
+	if word == "no_write_to_binlog" || word == "local" {
+		err = parseFuncs{readIdent(&word), skipSpaces}.exec(r)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if word != "table" && word != "tables" {
+		return nil, errUnexpectedSyntax.New("table", word)
+	}
+
+	var tables []*plan.UnresolvedTable
+	err = parseFuncs{
+		readTableNames(&tables),
+		skipSpaces,
+		checkEOF,
+	}.exec(r)
+	if err != nil {
+		return nil, err
+	}
+
+	return plan.NewAnalyzeTable(tables...), nil
+}
+
+// readTableNames reads a list of table names separated by commas, which can
+// be qualified with their database.
+func readTableNames(tables *[]*plan.UnresolvedTable) parseFunc {
+	return func(rd *bufio.Reader) error {
+		for {
+			var db, name string
+			if err := readQuotableIdent(&name)(rd); err != nil {
+				return err
+			}
+
+			b, err := rd.Peek(1)
+			if err == nil && b[0] == '.' {
+				db, name = name, ""
+				err = parseFuncs{expectRune('.'), readQuotableIdent(&name)}.exec(rd)
+			}
+
+			if err != nil && err != io.EOF {
+				return err
+			}
+
+			if name == "" {
+				return errUnexpectedSyntax.New("table name", "")
+			}
+
+			*tables = append(*tables, plan.NewUnresolvedTable(name, db))
+
+			if err := skipSpaces(rd); err != nil {
+				return err
+			}
+
+			b, err = rd.Peek(1)
+			if err == io.EOF {
+				return nil
+			} else if err != nil {
+				return err
+			}
+
+			if b[0] != ',' {
+				return nil
+			}
+
+			if _, err := rd.Discard(1); err != nil {
+				return err
+			}
+
+			if err := skipSpaces(rd); err != nil {
+				return err
+			}
+		}
+	}
+}
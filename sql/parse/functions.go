@@ -0,0 +1,266 @@
+package parse
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"unicode"
+
+	"github.com/src-d/go-mysql-server/sql"
+	"github.com/src-d/go-mysql-server/sql/expression"
+	"github.com/src-d/go-mysql-server/sql/plan"
+	errors "gopkg.in/src-d/go-errors.v1"
+)
+
+var errInvalidFunctionBody = errors.NewKind("the body of a function must be a string, but got: %s")
+
+var (
+	functionParamRegex = regexp.MustCompile("(?s)^(`[^`]+`|\\w+)(?:\\s+(.+))?$")
+	functionTailRegex  = regexp.MustCompile(`(?is)^returns\s+(.+?)\s+language\s+(\w+)\s+as\s+(.+)$`)
+)
+
+// functionLanguages are the names of the supported script languages, by
+// the names they can be written with.
+var functionLanguages = map[string]string{
+	"js":         "js",
+	"javascript": "js",
+	"expr":       "expr",
+}
+
+func parseCreateFunction(ctx *sql.Context, s string) (sql.Node, error) {
+	r := bufio.NewReader(strings.NewReader(s))
+
+	var name, rest string
+	err := parseFuncs{
+		expect("create"),
+		skipSpaces,
+		expect("function"),
+		skipSpaces,
+		readQuotableIdent(&name),
+		skipSpaces,
+		expectRune('('),
+		readRemaining(&rest),
+	}.exec(r)
+	if err != nil {
+		return nil, err
+	}
+
+	end := closingParenthesis(rest)
+	if end < 0 {
+		return nil, errUnexpectedSyntax.New(")", rest)
+	}
+
+	fn := &sql.StoredFunction{
+		Name:           name,
+		Params:         []string{},
+		TextDefinition: s,
+	}
+
+	var params []string
+	if strings.TrimSpace(rest[:end]) != "" {
+		params = splitTopLevelCommas(rest[:end])
+	}
+
+	for _, param := range params {
+		m := functionParamRegex.FindStringSubmatch(strings.TrimSpace(param))
+		if m == nil {
+			return nil, ErrUnsupportedSyntax.New(param)
+		}
+
+		var typ sql.Type
+		if m[2] != "" {
+			typ, err = parseType(m[2])
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		fn.Params = append(fn.Params, strings.Trim(m[1], "`"))
+		fn.ParamTypes = append(fn.ParamTypes, typ)
+	}
+
+	m := functionTailRegex.FindStringSubmatch(strings.TrimSpace(rest[end+1:]))
+	if m == nil {
+		return nil, ErrUnsupportedSyntax.New(s)
+	}
+
+	fn.ReturnType, err = parseType(m[1])
+	if err != nil {
+		return nil, err
+	}
+
+	language, ok := functionLanguages[strings.ToLower(m[2])]
+	if !ok {
+		return nil, ErrUnsupportedFeature.New(fmt.Sprintf("functions in language %s", m[2]))
+	}
+	fn.Language = language
+
+	body, err := parseExpr(ctx, m[3])
+	if err != nil {
+		return nil, err
+	}
+
+	lit, ok := body.(*expression.Literal)
+	if !ok || lit.Type() != sql.Text {
+		return nil, errInvalidFunctionBody.New(m[3])
+	}
+	fn.Body = lit.Value().(string)
+
+	return plan.NewCreateFunction(fn), nil
+}
+
+func parseDropFunction(s string) (sql.Node, error) {
+	r := bufio.NewReader(strings.NewReader(s))
+
+	err := parseFuncs{
+		expect("drop"),
+		skipSpaces,
+		expect("function"),
+		skipSpaces,
+	}.exec(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var ifExists bool
+	bs, err := r.Peek(len("if "))
+	if err == nil && strings.ToLower(string(bs[:2])) == "if" && unicode.IsSpace(rune(bs[2])) {
+		ifExists = true
+		err = parseFuncs{
+			expect("if"),
+			skipSpaces,
+			expect("exists"),
+			skipSpaces,
+		}.exec(r)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var names []string
+	for {
+		var name string
+		err := parseFuncs{
+			readQuotableIdent(&name),
+			skipSpaces,
+		}.exec(r)
+		if err != nil {
+			return nil, err
+		}
+
+		names = append(names, name)
+
+		ru, _, err := r.ReadRune()
+		if err == io.EOF {
+			break
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		if ru != ',' {
+			return nil, errUnexpectedSyntax.New(",", string(ru))
+		}
+
+		if err := skipSpaces(r); err != nil {
+			return nil, err
+		}
+	}
+
+	return plan.NewDropFunction(ifExists, names...), nil
+}
+
+func parseShowFunctionStatus(s string) (sql.Node, error) {
+	var pattern string
+	r := bufio.NewReader(strings.NewReader(s))
+	err := parseFuncs{
+		expect("show"),
+		skipSpaces,
+		expect("function"),
+		skipSpaces,
+		expect("status"),
+		skipSpaces,
+		func(in *bufio.Reader) error {
+			if expect("like")(in) == nil {
+				if err := skipSpaces(in); err != nil {
+					return err
+				}
+
+				return readValue(&pattern)(in)
+			}
+			return nil
+		},
+		skipSpaces,
+		checkEOF,
+	}.exec(r)
+	if err != nil {
+		return nil, err
+	}
+
+	return plan.NewShowFunctionStatus(pattern), nil
+}
+
+// parseType parses the name of a SQL type, such as the ones in column
+// definitions.
+func parseType(s string) (sql.Type, error) {
+	column, _, err := readColumnDefinition(bufio.NewReader(strings.NewReader("x " + s)))
+	if err != nil {
+		return nil, err
+	}
+
+	return column.Type, nil
+}
+
+// closingParenthesis returns the position of the parenthesis that closes an
+// already open one in the given string, or -1 if there is none.
+func closingParenthesis(s string) int {
+	var depth int
+	var quote rune
+	for i, ru := range s {
+		switch {
+		case quote != 0:
+			if ru == quote {
+				quote = 0
+			}
+		case ru == '\'' || ru == '"' || ru == '`':
+			quote = ru
+		case ru == '(':
+			depth++
+		case ru == ')':
+			if depth == 0 {
+				return i
+			}
+			depth--
+		}
+	}
+	return -1
+}
+
+// splitTopLevelCommas splits the given string by the commas outside of
+// parenthesis and quotes.
+func splitTopLevelCommas(s string) []string {
+	var parts []string
+	var depth, start int
+	var quote rune
+	for i, ru := range s {
+		switch {
+		case quote != 0:
+			if ru == quote {
+				quote = 0
+			}
+		case ru == '\'' || ru == '"' || ru == '`':
+			quote = ru
+		case ru == '(':
+			depth++
+		case ru == ')':
+			depth--
+		case ru == ',' && depth == 0:
+			parts = append(parts, s[start:i])
+			start = i + 1
+		}
+	}
+	return append(parts, s[start:])
+}
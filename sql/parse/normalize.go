@@ -0,0 +1,218 @@
+package parse
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/src-d/go-mysql-server/sql"
+	"vitess.io/vitess/go/vt/sqlparser"
+)
+
+// NormalizedQuery is a SELECT query whose literals in conditions have been
+// replaced by placeholders, so all the queries that only differ in those
+// literals have the same normalized query.
+type NormalizedQuery struct {
+	// Query is the text of the normalized query, with the placeholders
+	// instead of the literals.
+	Query string
+	// Bindings are the literals of the query by the names of the
+	// placeholders that replaced them.
+	Bindings map[string]sql.Expression
+	stmt     sqlparser.Statement
+}
+
+// Parse returns the node of the normalized query, with placeholders instead
+// of the literals of the query.
+func (q *NormalizedQuery) Parse(ctx *sql.Context) (sql.Node, error) {
+	return convert(ctx, q.stmt, q.Query)
+}
+
+// rewrittenQueryRegexes match the queries that are not parsed by the SQL
+// parser as they are written, but are parsed by hand or rewritten first.
+var rewrittenQueryRegexes = []*regexp.Regexp{
+	describeTablesRegex,
+	createIndexRegex,
+	dropIndexRegex,
+	showIndexRegex,
+	showCreateRegex,
+	showVariablesRegex,
+	showWarningsRegex,
+	showCollationRegex,
+	describeRegex,
+	fullProcessListRegex,
+	unlockTablesRegex,
+	lockTablesRegex,
+	setRegex,
+	withRegex,
+	createViewRegex,
+	dropViewRegex,
+	savepointRegex,
+	rollbackToRegex,
+	releaseRegex,
+	alterTableRegex,
+	createFunctionRegex,
+	dropFunctionRegex,
+	showFunctionRegex,
+	analyzeTableRegex,
+	overRegex,
+	tableFunctionRegex,
+	pivotRegex,
+}
+
+// Normalize parses the given query replacing with placeholders the literals
+// of its WHERE and HAVING clauses and its join conditions, including the
+// ones of its subqueries. The placeholders are named after the ones already
+// in the query, if any. Only SELECT queries are normalized, and it returns
+// nil for the queries that are not, the ones that are rewritten before being
+// parsed, such as the queries with window functions or table functions, and
+// the ones whose plan depends on the session, which happens when the
+// sql_select_limit is not the default one. Queries that can't be normalized
+// are parsed as usual, so it also returns nil if the query can't be parsed.
+func Normalize(ctx *sql.Context, query string) *NormalizedQuery {
+	s := strings.TrimSpace(removeComments(query))
+	if strings.HasSuffix(s, ";") {
+		s = s[:len(s)-1]
+	}
+
+	lowerQuery := strings.ToLower(s)
+	for _, re := range rewrittenQueryRegexes {
+		if re.MatchString(lowerQuery) {
+			return nil
+		}
+	}
+
+	if ok, _ := sql.HasDefaultValue(ctx.Session, "sql_select_limit"); !ok {
+		return nil
+	}
+
+	stmt, err := sqlparser.Parse(s)
+	if err != nil {
+		return nil
+	}
+
+	sel, ok := stmt.(sqlparser.SelectStatement)
+	if !ok {
+		return nil
+	}
+
+	n := &normalizer{bindings: make(map[string]sql.Expression)}
+	_ = sqlparser.Walk(func(node sqlparser.SQLNode) (bool, error) {
+		if v, ok := node.(*sqlparser.SQLVal); ok && v.Type == sqlparser.ValArg && bindVarRegex.Match(v.Val) {
+			if pos, err := strconv.Atoi(string(v.Val[2:])); err == nil && pos > n.last {
+				n.last = pos
+			}
+		}
+		return true, nil
+	}, stmt)
+
+	if err := n.selectStatement(sel); err != nil {
+		return nil
+	}
+
+	return &NormalizedQuery{
+		Query:    sqlparser.String(stmt),
+		Bindings: n.bindings,
+		stmt:     stmt,
+	}
+}
+
+// normalizer replaces the literals of a query with placeholders.
+type normalizer struct {
+	// last is the position of the last placeholder of the query.
+	last     int
+	bindings map[string]sql.Expression
+}
+
+func (n *normalizer) selectStatement(stmt sqlparser.SelectStatement) error {
+	switch stmt := stmt.(type) {
+	case *sqlparser.Select:
+		if err := n.tableExprs(stmt.From); err != nil {
+			return err
+		}
+
+		if stmt.Where != nil {
+			if err := n.expr(stmt.Where.Expr); err != nil {
+				return err
+			}
+		}
+
+		if stmt.Having != nil {
+			return n.expr(stmt.Having.Expr)
+		}
+	case *sqlparser.Union:
+		if err := n.selectStatement(stmt.Left); err != nil {
+			return err
+		}
+		return n.selectStatement(stmt.Right)
+	case *sqlparser.ParenSelect:
+		return n.selectStatement(stmt.Select)
+	}
+
+	return nil
+}
+
+func (n *normalizer) tableExprs(exprs sqlparser.TableExprs) error {
+	for _, e := range exprs {
+		switch e := e.(type) {
+		case *sqlparser.AliasedTableExpr:
+			if sq, ok := e.Expr.(*sqlparser.Subquery); ok {
+				if err := n.selectStatement(sq.Select); err != nil {
+					return err
+				}
+			}
+		case *sqlparser.JoinTableExpr:
+			if err := n.tableExprs(sqlparser.TableExprs{e.LeftExpr, e.RightExpr}); err != nil {
+				return err
+			}
+
+			if e.Condition.On != nil {
+				if err := n.expr(e.Condition.On); err != nil {
+					return err
+				}
+			}
+		case *sqlparser.ParenTableExpr:
+			if err := n.tableExprs(e.Exprs); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func (n *normalizer) expr(e sqlparser.Expr) error {
+	return sqlparser.Walk(func(node sqlparser.SQLNode) (bool, error) {
+		switch node := node.(type) {
+		case *sqlparser.Subquery:
+			return false, n.selectStatement(node.Select)
+		case *sqlparser.SQLVal:
+			return false, n.val(node)
+		default:
+			return true, nil
+		}
+	}, e)
+}
+
+func (n *normalizer) val(v *sqlparser.SQLVal) error {
+	switch v.Type {
+	case sqlparser.StrVal, sqlparser.IntVal, sqlparser.FloatVal,
+		sqlparser.HexNum, sqlparser.HexVal, sqlparser.BitVal:
+	default:
+		return nil
+	}
+
+	lit, err := convertVal(v)
+	if err != nil {
+		return err
+	}
+
+	n.last++
+	name := fmt.Sprintf("v%d", n.last)
+	n.bindings[name] = lit
+
+	v.Type = sqlparser.ValArg
+	v.Val = []byte(":" + name)
+	return nil
+}
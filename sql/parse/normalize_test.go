@@ -0,0 +1,98 @@
+package parse
+
+import (
+	"testing"
+
+	"github.com/src-d/go-mysql-server/sql"
+	"github.com/src-d/go-mysql-server/sql/expression"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNormalize(t *testing.T) {
+	testCases := []struct {
+		query    string
+		expected string
+		bindings map[string]sql.Expression
+	}{
+		{
+			`SELECT a FROM t WHERE b = 1 AND c > 'foo'`,
+			`select a from t where b = :v1 and c > :v2`,
+			map[string]sql.Expression{
+				"v1": expression.NewLiteral(int8(1), sql.Int8),
+				"v2": expression.NewLiteral("foo", sql.Text),
+			},
+		},
+		{
+			`SELECT a, 1 FROM t GROUP BY a HAVING COUNT(*) > 2.5 LIMIT 10`,
+			`select a, 1 from t group by a having COUNT(*) > :v1 limit 10`,
+			map[string]sql.Expression{
+				"v1": expression.NewLiteral(2.5, sql.Float64),
+			},
+		},
+		{
+			`SELECT a FROM t INNER JOIN u ON t.a = u.b AND u.c = 3 WHERE t.d = :v1`,
+			`select a from t join u on t.a = u.b and u.c = :v2 where t.d = :v1`,
+			map[string]sql.Expression{
+				"v2": expression.NewLiteral(int8(3), sql.Int8),
+			},
+		},
+		{
+			`SELECT a FROM (SELECT a FROM t WHERE b = 1) s WHERE a IN (SELECT c FROM u WHERE d = 2)`,
+			`select a from (select a from t where b = :v1) as s where a in (select c from u where d = :v2)`,
+			map[string]sql.Expression{
+				"v1": expression.NewLiteral(int8(1), sql.Int8),
+				"v2": expression.NewLiteral(int8(2), sql.Int8),
+			},
+		},
+		{
+			`SELECT a FROM t WHERE b IS NULL AND c = TRUE`,
+			`select a from t where b is null and c = true`,
+			map[string]sql.Expression{},
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.query, func(t *testing.T) {
+			require := require.New(t)
+			q := Normalize(sql.NewEmptyContext(), tt.query)
+			require.NotNil(q)
+			require.Equal(tt.expected, q.Query)
+			require.Equal(tt.bindings, q.Bindings)
+		})
+	}
+}
+
+func TestNormalizeNotSelect(t *testing.T) {
+	queries := []string{
+		`INSERT INTO t VALUES (1)`,
+		`SHOW TABLES`,
+		`DESCRIBE TABLE t`,
+		`SELECT a, ROW_NUMBER() OVER (ORDER BY a) FROM t WHERE b = 1`,
+		`WITH s AS (SELECT 1) SELECT * FROM s`,
+		`SELECT * FROM`,
+	}
+
+	for _, query := range queries {
+		t.Run(query, func(t *testing.T) {
+			require.Nil(t, Normalize(sql.NewEmptyContext(), query))
+		})
+	}
+}
+
+func TestNormalizedQueryParse(t *testing.T) {
+	require := require.New(t)
+	ctx := sql.NewEmptyContext()
+
+	a := Normalize(ctx, `SELECT a FROM t WHERE b = 1`)
+	require.NotNil(a)
+	b := Normalize(ctx, `SELECT a FROM t WHERE b = 2`)
+	require.NotNil(b)
+	require.Equal(a.Query, b.Query)
+
+	node, err := a.Parse(ctx)
+	require.NoError(err)
+
+	expected, err := Parse(ctx, `SELECT a FROM t WHERE b = :v1`)
+	require.NoError(err)
+	require.Equal(expected, node)
+}
@@ -47,7 +47,18 @@ var (
 	unlockTablesRegex    = regexp.MustCompile(`^unlock\s+tables$`)
 	lockTablesRegex      = regexp.MustCompile(`^lock\s+tables\s`)
 	setRegex             = regexp.MustCompile(`^set\s+`)
-	createViewRegex      = regexp.MustCompile(`^create\s+view\s+`)
+	createViewRegex      = regexp.MustCompile(`^create\s+(or\s+replace\s+)?view\s+`)
+	dropViewRegex        = regexp.MustCompile(`^drop\s+view\s+`)
+	withRegex            = regexp.MustCompile(`^with\s+`)
+	savepointRegex       = regexp.MustCompile(`^savepoint\s+`)
+	rollbackToRegex      = regexp.MustCompile(`^rollback\s+(work\s+)?to\s+`)
+	releaseRegex         = regexp.MustCompile(`^release\s+savepoint\s+`)
+	alterTableRegex      = regexp.MustCompile(`^alter\s+table\s+`)
+	createFunctionRegex  = regexp.MustCompile(`^create\s+function\s+`)
+	dropFunctionRegex    = regexp.MustCompile(`^drop\s+function\s+`)
+	showFunctionRegex    = regexp.MustCompile(`^show\s+function\s+status`)
+	analyzeTableRegex    = regexp.MustCompile(`^analyze\s+((no_write_to_binlog|local)\s+)?tables?\s`)
+	bindVarRegex         = regexp.MustCompile(`^:v[0-9]+$`)
 )
 
 // These constants aren't exported from vitess for some reason. This could be removed if we changed this.
@@ -104,9 +115,52 @@ func Parse(ctx *sql.Context, query string) (sql.Node, error) {
 		return parseLockTables(ctx, s)
 	case setRegex.MatchString(lowerQuery):
 		s = fixSetQuery(s)
+	case withRegex.MatchString(lowerQuery):
+		return parseWith(ctx, s)
 	case createViewRegex.MatchString(lowerQuery):
-		// CREATE VIEW parses as a CREATE DDL statement with an empty table spec
-		return nil, ErrUnsupportedFeature.New("CREATE VIEW")
+		return parseCreateView(ctx, s)
+	case dropViewRegex.MatchString(lowerQuery):
+		return parseDropView(s)
+	case savepointRegex.MatchString(lowerQuery):
+		return parseSavepoint(s)
+	case rollbackToRegex.MatchString(lowerQuery):
+		return parseRollbackSavepoint(s)
+	case releaseRegex.MatchString(lowerQuery):
+		return parseReleaseSavepoint(s)
+	case alterTableRegex.MatchString(lowerQuery):
+		return parseAlterTable(s)
+	case createFunctionRegex.MatchString(lowerQuery):
+		return parseCreateFunction(ctx, s)
+	case dropFunctionRegex.MatchString(lowerQuery):
+		return parseDropFunction(s)
+	case showFunctionRegex.MatchString(lowerQuery):
+		return parseShowFunctionStatus(s)
+	case analyzeTableRegex.MatchString(lowerQuery):
+		return parseAnalyzeTable(s)
+	}
+
+	if overRegex.MatchString(lowerQuery) {
+		var err error
+		s, err = replaceWindows(s)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if tableFunctionRegex.MatchString(lowerQuery) {
+		var err error
+		s, err = replaceTableFunctions(s)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if pivotRegex.MatchString(lowerQuery) {
+		var err error
+		s, err = replacePivots(s)
+		if err != nil {
+			return nil, err
+		}
 	}
 
 	stmt, err := sqlparser.Parse(s)
@@ -155,6 +209,10 @@ func convert(ctx *sql.Context, stmt sqlparser.Statement, query string) (sql.Node
 		return convertShow(ctx, n, query)
 	case *sqlparser.Select:
 		return convertSelect(ctx, n)
+	case *sqlparser.Union:
+		return convertUnion(ctx, n)
+	case *sqlparser.ParenSelect:
+		return convert(ctx, n.Select, query)
 	case *sqlparser.Insert:
 		return convertInsert(ctx, n)
 	case *sqlparser.DDL:
@@ -163,11 +221,15 @@ func convert(ctx *sql.Context, stmt sqlparser.Statement, query string) (sql.Node
 		if err != nil {
 			return nil, err
 		}
-		return convertDDL(ddl.(*sqlparser.DDL))
+		return convertDDL(ddl.(*sqlparser.DDL), query)
 	case *sqlparser.Set:
 		return convertSet(ctx, n)
 	case *sqlparser.Use:
 		return convertUse(n)
+	case *sqlparser.Begin:
+		return plan.NewStartTransaction(), nil
+	case *sqlparser.Commit:
+		return plan.NewCommit(), nil
 	case *sqlparser.Rollback:
 		return plan.NewRollback(), nil
 	case *sqlparser.Delete:
@@ -313,6 +375,15 @@ func convertShow(ctx *sql.Context, s *sqlparser.Show, query string) (sql.Node, e
 }
 
 func convertSelect(ctx *sql.Context, s *sqlparser.Select) (sql.Node, error) {
+	node, err := selectToNode(ctx, s)
+	if err != nil {
+		return nil, err
+	}
+
+	return selectLimitToLimit(ctx, s.Limit, node), nil
+}
+
+func selectToNode(ctx *sql.Context, s *sqlparser.Select) (sql.Node, error) {
 	node, err := tableExprsToTable(ctx, s.From)
 	if err != nil {
 		return nil, err
@@ -341,40 +412,114 @@ func convertSelect(ctx *sql.Context, s *sqlparser.Select) (sql.Node, error) {
 		node = plan.NewDistinct(node)
 	}
 
-	if len(s.OrderBy) != 0 {
-		node, err = orderByToSort(ctx, s.OrderBy, node)
+	return orderByAndLimitToNode(ctx, s.OrderBy, s.Limit, node)
+}
+
+func convertUnion(ctx *sql.Context, u *sqlparser.Union) (sql.Node, error) {
+	node, err := unionToNode(ctx, u)
+	if err != nil {
+		return nil, err
+	}
+
+	return selectLimitToLimit(ctx, u.Limit, node), nil
+}
+
+// unionToNode converts an union to a node. The ORDER BY and LIMIT clauses
+// of the union are applied to the whole union and not to its last branch.
+func unionToNode(ctx *sql.Context, u *sqlparser.Union) (sql.Node, error) {
+	left, err := selectStatementToNode(ctx, u.Left)
+	if err != nil {
+		return nil, err
+	}
+
+	right, err := selectStatementToNode(ctx, u.Right)
+	if err != nil {
+		return nil, err
+	}
+
+	var node sql.Node
+	switch u.Type {
+	case sqlparser.UnionAllStr:
+		node = plan.NewUnion(left, right)
+	case sqlparser.UnionStr, sqlparser.UnionDistinctStr:
+		node = plan.NewDistinctUnion(left, right)
+	default:
+		return nil, ErrUnsupportedSyntax.New(u)
+	}
+
+	return orderByAndLimitToNode(ctx, u.OrderBy, u.Limit, node)
+}
+
+// selectStatementToNode converts a select statement that is part of another
+// statement, so the default select limit of the session is not applied to it.
+func selectStatementToNode(ctx *sql.Context, s sqlparser.SelectStatement) (sql.Node, error) {
+	switch s := s.(type) {
+	case *sqlparser.Select:
+		return selectToNode(ctx, s)
+	case *sqlparser.Union:
+		return unionToNode(ctx, s)
+	case *sqlparser.ParenSelect:
+		return selectStatementToNode(ctx, s.Select)
+	default:
+		return nil, ErrUnsupportedSyntax.New(s)
+	}
+}
+
+func orderByAndLimitToNode(
+	ctx *sql.Context,
+	ob sqlparser.OrderBy,
+	limit *sqlparser.Limit,
+	node sql.Node,
+) (sql.Node, error) {
+	var err error
+	if len(ob) != 0 {
+		node, err = orderByToSort(ctx, ob, node)
 		if err != nil {
 			return nil, err
 		}
 	}
 
 	// Limit must wrap offset, and not vice-versa, so that skipped rows don't count toward the returned row count.
-	if s.Limit != nil && s.Limit.Offset != nil {
-		node, err = offsetToOffset(ctx, s.Limit.Offset, node)
+	if limit != nil && limit.Offset != nil {
+		node, err = offsetToOffset(ctx, limit.Offset, node)
 		if err != nil {
 			return nil, err
 		}
 	}
 
-	if s.Limit != nil {
-		node, err = limitToLimit(ctx, s.Limit.Rowcount, node)
+	if limit != nil {
+		node, err = limitToLimit(ctx, limit.Rowcount, node)
 		if err != nil {
 			return nil, err
 		}
-	} else if ok, val := sql.HasDefaultValue(ctx.Session, "sql_select_limit"); !ok {
-		limit := val.(int64)
-		node = plan.NewLimit(int64(limit), node)
 	}
 
 	return node, nil
 }
 
-func convertDDL(c *sqlparser.DDL) (sql.Node, error) {
+// selectLimitToLimit wraps the node in a limit if the query has no explicit
+// limit and the session sql_select_limit is not the default one.
+func selectLimitToLimit(ctx *sql.Context, limit *sqlparser.Limit, node sql.Node) sql.Node {
+	if limit != nil {
+		return node
+	}
+
+	if ok, val := sql.HasDefaultValue(ctx.Session, "sql_select_limit"); !ok {
+		limit := val.(int64)
+		return plan.NewLimit(int64(limit), node)
+	}
+
+	return node
+}
+
+func convertDDL(c *sqlparser.DDL, query string) (sql.Node, error) {
 	switch c.Action {
 	case sqlparser.CreateStr:
-		return convertCreateTable(c)
+		return convertCreateTable(c, query)
 	case sqlparser.DropStr:
 		return convertDropTable(c)
+	case sqlparser.RenameStr:
+		return convertRenameTable(c)
 	default:
 		return nil, ErrUnsupportedSyntax.New(c)
 	}
@@ -388,12 +533,34 @@ func convertDropTable(c *sqlparser.DDL) (sql.Node, error) {
 	return plan.NewDropTable(sql.UnresolvedDatabase(""), c.IfExists, tableNames...), nil
 }
 
-func convertCreateTable(c *sqlparser.DDL) (sql.Node, error) {
+func convertRenameTable(c *sqlparser.DDL) (sql.Node, error) {
+	db := c.FromTables[0].Qualifier.String()
+	oldNames := make([]string, len(c.FromTables))
+	newNames := make([]string, len(c.ToTables))
+	for i := range c.FromTables {
+		from, to := c.FromTables[i], c.ToTables[i]
+		if from.Qualifier.String() != db || (!to.Qualifier.IsEmpty() && to.Qualifier.String() != db) {
+			return nil, ErrUnsupportedFeature.New("renaming tables between databases")
+		}
+
+		oldNames[i] = from.Name.String()
+		newNames[i] = to.Name.String()
+	}
+
+	return plan.NewRenameTable(sql.UnresolvedDatabase(db), oldNames, newNames), nil
+}
+
+func convertCreateTable(c *sqlparser.DDL, query string) (sql.Node, error) {
 	schema, err := tableSpecToSchema(c.TableSpec)
 	if err != nil {
 		return nil, err
 	}
 
+	options := tableOptions(query)
+	if strings.EqualFold(options["engine"], "script") {
+		return convertCreateScriptTable(c.Table.Name.String(), schema, options)
+	}
+
 	return plan.NewCreateTable(
 		sql.UnresolvedDatabase(""), c.Table.Name.String(), schema), nil
 }
@@ -544,16 +711,45 @@ func getColumn(cd *sqlparser.ColumnDefinition, indexes []*sqlparser.IndexDefinit
 		}
 	}
 
+	var def interface{}
+	if typ.Default != nil {
+		def, err = columnDefaultValue(typ.Default, internalTyp)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	return &sql.Column{
 		Nullable:   !bool(typ.NotNull),
 		Type:       internalTyp,
 		Name:       cd.Name.String(),
 		PrimaryKey: isPkey,
-		// TODO
-		Default: nil,
+		Default:    def,
 	}, nil
 }
 
+// columnDefaultValue returns the value of the given default expression of a
+// column with the given type. Only constant defaults are supported, the rest
+// of them are ignored.
+func columnDefaultValue(e sqlparser.Expr, typ sql.Type) (interface{}, error) {
+	ctx := sql.NewEmptyContext()
+	expr, err := exprToExpression(ctx, e)
+	if err != nil {
+		return nil, err
+	}
+
+	if !expr.Resolved() {
+		return nil, nil
+	}
+
+	v, err := expr.Eval(ctx, nil)
+	if err != nil || v == nil {
+		return nil, err
+	}
+
+	return typ.Convert(v)
+}
+
 func columnsToStrings(cols sqlparser.Columns) []string {
 	res := make([]string, len(cols))
 	for i, c := range cols {
@@ -568,7 +764,7 @@ func insertRowsToNode(ctx *sql.Context, ir sqlparser.InsertRows) (sql.Node, erro
 	case *sqlparser.Select:
 		return convertSelect(ctx, v)
 	case *sqlparser.Union:
-		return nil, ErrUnsupportedFeature.New("UNION")
+		return convertUnion(ctx, v)
 	case sqlparser.Values:
 		return valuesToValues(ctx, v)
 	default:
@@ -642,6 +838,18 @@ func tableExprToTable(
 
 			return node, nil
 		case *sqlparser.Subquery:
+			if f, ok := tableFunction(e); ok {
+				return tableFunctionToTable(ctx, t.As.String(), f)
+			}
+
+			if node, ok, err := pivotToTable(ctx, e); ok {
+				if err != nil {
+					return nil, err
+				}
+
+				return plan.NewSubqueryAlias(t.As.String(), node), nil
+			}
+
 			node, err := convert(ctx, e.Select, "")
 			if err != nil {
 				return nil, err
@@ -708,6 +916,15 @@ func whereToFilter(ctx *sql.Context, w *sqlparser.Where, child sql.Node) (*plan.
 }
 
 func orderByToSort(ctx *sql.Context, ob sqlparser.OrderBy, child sql.Node) (*plan.Sort, error) {
+	sortFields, err := orderByToSortFields(ctx, ob)
+	if err != nil {
+		return nil, err
+	}
+
+	return plan.NewSort(sortFields, child), nil
+}
+
+func orderByToSortFields(ctx *sql.Context, ob sqlparser.OrderBy) ([]plan.SortField, error) {
 	var sortFields []plan.SortField
 	for _, o := range ob {
 		e, err := exprToExpression(ctx, o.Expr)
@@ -729,7 +946,7 @@ func orderByToSort(ctx *sql.Context, ob sqlparser.OrderBy, child sql.Node) (*pla
 		sortFields = append(sortFields, sf)
 	}
 
-	return plan.NewSort(sortFields, child), nil
+	return sortFields, nil
 }
 
 func limitToLimit(
@@ -821,6 +1038,9 @@ func isAggregate(e sql.Expression) bool {
 			isAgg = isAgg || e.IsAggregate
 		case *aggregation.CountDistinct:
 			isAgg = true
+		case *plan.WindowExpression:
+			// Aggregations in windows are not computed by a GroupBy.
+			return false
 		}
 
 		return true
@@ -828,6 +1048,17 @@ func isAggregate(e sql.Expression) bool {
 	return isAgg
 }
 
+func hasWindow(e sql.Expression) bool {
+	var found bool
+	expression.Inspect(e, func(e sql.Expression) bool {
+		if _, ok := e.(*plan.WindowExpression); ok {
+			found = true
+		}
+		return !found
+	})
+	return found
+}
+
 func selectToProjectOrGroupBy(
 	ctx *sql.Context,
 	se sqlparser.SelectExprs,
@@ -850,6 +1081,12 @@ func selectToProjectOrGroupBy(
 	}
 
 	if isAgg {
+		for _, e := range selectExprs {
+			if hasWindow(e) {
+				return nil, ErrUnsupportedFeature.New("window functions in aggregated queries")
+			}
+		}
+
 		groupingExprs, err := groupByToExpressions(ctx, g)
 		if err != nil {
 			return nil, err
@@ -949,6 +1186,10 @@ func exprToExpression(ctx *sql.Context, e sqlparser.Expr) (sql.Expression, error
 		}
 		return expression.NewUnresolvedColumn(v.Name.String()), nil
 	case *sqlparser.FuncExpr:
+		if v.Name.Lowered() == windowFunctionName {
+			return windowToExpression(ctx, v)
+		}
+
 		exprs, err := selectExprsToExpressions(ctx, v.Exprs)
 		if err != nil {
 			return nil, err
@@ -1046,6 +1287,12 @@ func exprToExpression(ctx *sql.Context, e sqlparser.Expr) (sql.Expression, error
 			return nil, err
 		}
 		return expression.NewSubquery(node), nil
+	case *sqlparser.ExistsExpr:
+		node, err := convert(ctx, v.Subquery.Select, "")
+		if err != nil {
+			return nil, err
+		}
+		return expression.NewExists(expression.NewSubquery(node)), nil
 	case *sqlparser.CaseExpr:
 		return caseExprToExpression(ctx, v)
 	case *sqlparser.IntervalExpr:
@@ -1128,6 +1375,10 @@ func convertVal(v *sqlparser.SQLVal) (sql.Expression, error) {
 		}
 		return expression.NewLiteral(val, sql.Blob), nil
 	case sqlparser.ValArg:
+		// The parser names the ? placeholders :v1, :v2 and so on.
+		if bindVarRegex.Match(v.Val) {
+			return expression.NewBindVar(string(v.Val[1:])), nil
+		}
 		return expression.NewLiteral(string(v.Val), sql.Text), nil
 	case sqlparser.BitVal:
 		return expression.NewLiteral(v.Val[0] == '1', sql.Boolean), nil
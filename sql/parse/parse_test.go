@@ -6,6 +6,7 @@ import (
 
 	"github.com/src-d/go-mysql-server/sql/expression"
 	"github.com/src-d/go-mysql-server/sql/expression/function/aggregation"
+	"github.com/src-d/go-mysql-server/sql/expression/function/udf"
 	"github.com/src-d/go-mysql-server/sql/plan"
 	"gopkg.in/src-d/go-errors.v1"
 
@@ -96,6 +97,25 @@ var fixtures = map[string]sql.Node{
 			PrimaryKey: true,
 		}},
 	),
+	`CREATE TABLE t1(a INTEGER) ENGINE=SCRIPT LANGUAGE=expr, SCRIPT='[1, 2]'`: plan.NewCreateScriptTable(
+		sql.UnresolvedDatabase(""),
+		"t1",
+		sql.Schema{{
+			Name:     "a",
+			Type:     sql.Int32,
+			Nullable: true,
+		}},
+		udf.GetScriptInstance("expr", "[1, 2]"),
+	),
+	`CREATE TABLE t1(a INTEGER) ENGINE=InnoDB COMMENT='ENGINE=SCRIPT'`: plan.NewCreateTable(
+		sql.UnresolvedDatabase(""),
+		"t1",
+		sql.Schema{{
+			Name:     "a",
+			Type:     sql.Int32,
+			Nullable: true,
+		}},
+	),
 	`DROP TABLE foo;`: plan.NewDropTable(
 		sql.UnresolvedDatabase(""), false, "foo",
 	),
@@ -546,6 +566,22 @@ var fixtures = map[string]sql.Node{
 			plan.NewUnresolvedTable("foo", ""),
 		),
 	),
+	`SELECT * FROM foo WHERE a = ? AND b > ?`: plan.NewProject(
+		[]sql.Expression{expression.NewStar()},
+		plan.NewFilter(
+			expression.NewAnd(
+				expression.NewEquals(
+					expression.NewUnresolvedColumn("a"),
+					expression.NewBindVar("v1"),
+				),
+				expression.NewGreaterThan(
+					expression.NewUnresolvedColumn("b"),
+					expression.NewBindVar("v2"),
+				),
+			),
+			plan.NewUnresolvedTable("foo", ""),
+		),
+	),
 	`SELECT * FROM foo INNER JOIN bar ON a = b`: plan.NewProject(
 		[]sql.Expression{expression.NewStar()},
 		plan.NewInnerJoin(
@@ -637,6 +673,34 @@ var fixtures = map[string]sql.Node{
 			plan.NewUnresolvedTable("foo", ""),
 		),
 	),
+	`SELECT * FROM foo WHERE EXISTS (SELECT 1 FROM bar WHERE bar.a = foo.a)`: plan.NewProject(
+		[]sql.Expression{expression.NewStar()},
+		plan.NewFilter(
+			expression.NewExists(expression.NewSubquery(plan.NewProject(
+				[]sql.Expression{expression.NewLiteral(int8(1), sql.Int8)},
+				plan.NewFilter(
+					expression.NewEquals(
+						expression.NewUnresolvedQualifiedColumn("bar", "a"),
+						expression.NewUnresolvedQualifiedColumn("foo", "a"),
+					),
+					plan.NewUnresolvedTable("bar", ""),
+				),
+			))),
+			plan.NewUnresolvedTable("foo", ""),
+		),
+	),
+	`SELECT * FROM foo WHERE NOT EXISTS (SELECT * FROM bar)`: plan.NewProject(
+		[]sql.Expression{expression.NewStar()},
+		plan.NewFilter(
+			expression.NewNot(
+				expression.NewExists(expression.NewSubquery(plan.NewProject(
+					[]sql.Expression{expression.NewStar()},
+					plan.NewUnresolvedTable("bar", ""),
+				))),
+			),
+			plan.NewUnresolvedTable("foo", ""),
+		),
+	),
 	`SELECT a, b FROM t ORDER BY 2, 1`: plan.NewSort(
 		[]plan.SortField{
 			{
@@ -972,6 +1036,15 @@ var fixtures = map[string]sql.Node{
 	`SHOW VARIABLES LIKE 'gtid_mode'`:          plan.NewShowVariables(sql.NewEmptyContext().GetAll(), "gtid_mode"),
 	`SHOW SESSION VARIABLES LIKE 'autocommit'`: plan.NewShowVariables(sql.NewEmptyContext().GetAll(), "autocommit"),
 	`UNLOCK TABLES`:                            plan.NewUnlockTables(),
+	`ANALYZE TABLE foo`:                        plan.NewAnalyzeTable(plan.NewUnresolvedTable("foo", "")),
+	"ANALYZE LOCAL TABLE foo, `bar`.baz": plan.NewAnalyzeTable(
+		plan.NewUnresolvedTable("foo", ""),
+		plan.NewUnresolvedTable("baz", "bar"),
+	),
+	`analyze no_write_to_binlog tables foo ,bar`: plan.NewAnalyzeTable(
+		plan.NewUnresolvedTable("foo", ""),
+		plan.NewUnresolvedTable("bar", ""),
+	),
 	`LOCK TABLES foo READ`: plan.NewLockTables([]*plan.TableLock{
 		{Table: plan.NewUnresolvedTable("foo", "")},
 	}),
@@ -1098,6 +1171,52 @@ var fixtures = map[string]sql.Node{
 		plan.NewShowCollation(),
 	),
 	`ROLLBACK`:                               plan.NewRollback(),
+	`BEGIN`:                                  plan.NewStartTransaction(),
+	`START TRANSACTION`:                      plan.NewStartTransaction(),
+	`COMMIT`:                                 plan.NewCommit(),
+	`SAVEPOINT sp1`:                          plan.NewCreateSavepoint("sp1"),
+	`ROLLBACK TO SAVEPOINT sp1`:              plan.NewRollbackSavepoint("sp1"),
+	"ROLLBACK WORK TO `sp1`":                 plan.NewRollbackSavepoint("sp1"),
+	`ROLLBACK TO savepoint`:                  plan.NewRollbackSavepoint("savepoint"),
+	`RELEASE SAVEPOINT sp1`:                  plan.NewReleaseSavepoint("sp1"),
+	`ALTER TABLE foo ADD COLUMN b INT NOT NULL DEFAULT 5 AFTER a`: plan.NewAddColumn(
+		sql.UnresolvedDatabase(""),
+		"foo",
+		&sql.Column{Name: "b", Type: sql.Int32, Default: int32(5)},
+		&sql.ColumnOrder{AfterColumn: "a"},
+	),
+	"ALTER TABLE mydb.foo ADD `b` VARCHAR(20) DEFAULT 'x, y' FIRST": plan.NewAddColumn(
+		sql.UnresolvedDatabase("mydb"),
+		"foo",
+		&sql.Column{Name: "b", Type: sql.Text, Nullable: true, Default: "x, y"},
+		&sql.ColumnOrder{First: true},
+	),
+	`ALTER TABLE foo DROP COLUMN b`: plan.NewDropColumn(sql.UnresolvedDatabase(""), "foo", "b"),
+	`ALTER TABLE foo DROP b`:        plan.NewDropColumn(sql.UnresolvedDatabase(""), "foo", "b"),
+	`ALTER TABLE foo MODIFY b TEXT`: plan.NewModifyColumn(
+		sql.UnresolvedDatabase(""),
+		"foo",
+		"b",
+		&sql.Column{Name: "b", Type: sql.Text, Nullable: true},
+		nil,
+	),
+	`ALTER TABLE foo CHANGE COLUMN b c BIGINT NOT NULL FIRST`: plan.NewModifyColumn(
+		sql.UnresolvedDatabase(""),
+		"foo",
+		"b",
+		&sql.Column{Name: "c", Type: sql.Int64},
+		&sql.ColumnOrder{First: true},
+	),
+	`ALTER TABLE foo RENAME COLUMN b TO c`: plan.NewRenameColumn(sql.UnresolvedDatabase(""), "foo", "b", "c"),
+	`ALTER TABLE foo RENAME TO bar`:        plan.NewRenameTable(sql.UnresolvedDatabase(""), []string{"foo"}, []string{"bar"}),
+	`ALTER TABLE foo RENAME AS bar`:        plan.NewRenameTable(sql.UnresolvedDatabase(""), []string{"foo"}, []string{"bar"}),
+	`ALTER TABLE foo RENAME bar`:           plan.NewRenameTable(sql.UnresolvedDatabase(""), []string{"foo"}, []string{"bar"}),
+	`ALTER TABLE foo RENAME to`:            plan.NewRenameTable(sql.UnresolvedDatabase(""), []string{"foo"}, []string{"to"}),
+	`RENAME TABLE foo TO bar, baz TO qux`: plan.NewRenameTable(
+		sql.UnresolvedDatabase(""),
+		[]string{"foo", "baz"},
+		[]string{"bar", "qux"},
+	),
 	"SHOW CREATE TABLE `mytable`":            plan.NewShowCreateTable("", nil, "mytable"),
 	"SHOW CREATE TABLE `mydb`.`mytable`":     plan.NewShowCreateTable("mydb", nil, "mytable"),
 	"SHOW CREATE TABLE `my.table`":           plan.NewShowCreateTable("", nil, "my.table"),
@@ -1263,6 +1382,331 @@ var fixtures = map[string]sql.Node{
 		},
 		plan.NewUnresolvedTable("dual", ""),
 	),
+	`SELECT a FROM foo UNION ALL SELECT b FROM bar`: plan.NewUnion(
+		plan.NewProject(
+			[]sql.Expression{expression.NewUnresolvedColumn("a")},
+			plan.NewUnresolvedTable("foo", ""),
+		),
+		plan.NewProject(
+			[]sql.Expression{expression.NewUnresolvedColumn("b")},
+			plan.NewUnresolvedTable("bar", ""),
+		),
+	),
+	`SELECT a FROM foo UNION SELECT b FROM bar UNION DISTINCT SELECT c FROM baz`: plan.NewDistinctUnion(
+		plan.NewDistinctUnion(
+			plan.NewProject(
+				[]sql.Expression{expression.NewUnresolvedColumn("a")},
+				plan.NewUnresolvedTable("foo", ""),
+			),
+			plan.NewProject(
+				[]sql.Expression{expression.NewUnresolvedColumn("b")},
+				plan.NewUnresolvedTable("bar", ""),
+			),
+		),
+		plan.NewProject(
+			[]sql.Expression{expression.NewUnresolvedColumn("c")},
+			plan.NewUnresolvedTable("baz", ""),
+		),
+	),
+	`SELECT a FROM foo UNION ALL (SELECT b FROM bar LIMIT 1) ORDER BY a DESC LIMIT 5`: plan.NewLimit(5,
+		plan.NewSort(
+			[]plan.SortField{
+				{
+					Column:       expression.NewUnresolvedColumn("a"),
+					Order:        plan.Descending,
+					NullOrdering: plan.NullsFirst,
+				},
+			},
+			plan.NewUnion(
+				plan.NewProject(
+					[]sql.Expression{expression.NewUnresolvedColumn("a")},
+					plan.NewUnresolvedTable("foo", ""),
+				),
+				plan.NewLimit(1,
+					plan.NewProject(
+						[]sql.Expression{expression.NewUnresolvedColumn("b")},
+						plan.NewUnresolvedTable("bar", ""),
+					),
+				),
+			),
+		),
+	),
+	`INSERT INTO t1 SELECT a FROM foo UNION SELECT b FROM bar`: plan.NewInsertInto(
+		plan.NewUnresolvedTable("t1", ""),
+		plan.NewDistinctUnion(
+			plan.NewProject(
+				[]sql.Expression{expression.NewUnresolvedColumn("a")},
+				plan.NewUnresolvedTable("foo", ""),
+			),
+			plan.NewProject(
+				[]sql.Expression{expression.NewUnresolvedColumn("b")},
+				plan.NewUnresolvedTable("bar", ""),
+			),
+		),
+		false,
+		[]string{},
+	),
+	`WITH t AS (SELECT a FROM foo) SELECT a FROM t`: plan.NewWith(
+		plan.NewProject(
+			[]sql.Expression{expression.NewUnresolvedColumn("a")},
+			plan.NewUnresolvedTable("t", ""),
+		),
+		[]*plan.CommonTableExpression{
+			plan.NewCommonTableExpression("t", nil, plan.NewProject(
+				[]sql.Expression{expression.NewUnresolvedColumn("a")},
+				plan.NewUnresolvedTable("foo", ""),
+			)),
+		},
+		false,
+	),
+	`WITH RECURSIVE t (n) AS (SELECT 1 UNION ALL SELECT n + 1 FROM t WHERE n < 5), u AS (SELECT ')' AS x) SELECT n FROM t`: plan.NewWith(
+		plan.NewProject(
+			[]sql.Expression{expression.NewUnresolvedColumn("n")},
+			plan.NewUnresolvedTable("t", ""),
+		),
+		[]*plan.CommonTableExpression{
+			plan.NewCommonTableExpression("t", []string{"n"}, plan.NewUnion(
+				plan.NewProject(
+					[]sql.Expression{expression.NewLiteral(int8(1), sql.Int8)},
+					plan.NewUnresolvedTable("dual", ""),
+				),
+				plan.NewProject(
+					[]sql.Expression{expression.NewArithmetic(
+						expression.NewUnresolvedColumn("n"),
+						expression.NewLiteral(int8(1), sql.Int8),
+						"+",
+					)},
+					plan.NewFilter(
+						expression.NewLessThan(
+							expression.NewUnresolvedColumn("n"),
+							expression.NewLiteral(int8(5), sql.Int8),
+						),
+						plan.NewUnresolvedTable("t", ""),
+					),
+				),
+			)),
+			plan.NewCommonTableExpression("u", nil, plan.NewProject(
+				[]sql.Expression{expression.NewAlias(
+					expression.NewLiteral(")", sql.Text),
+					"x",
+				)},
+				plan.NewUnresolvedTable("dual", ""),
+			)),
+		},
+		true,
+	),
+	`SELECT a, ROW_NUMBER() OVER (PARTITION BY b ORDER BY c DESC) AS rn, SUM(a) OVER (ORDER BY c ROWS BETWEEN 1 PRECEDING AND CURRENT ROW) FROM foo`: plan.NewProject(
+		[]sql.Expression{
+			expression.NewUnresolvedColumn("a"),
+			expression.NewAlias(
+				plan.NewWindowExpression(
+					expression.NewUnresolvedFunction("row_number", false),
+					[]sql.Expression{expression.NewUnresolvedColumn("b")},
+					[]plan.SortField{{
+						Column: expression.NewUnresolvedColumn("c"),
+						Order:  plan.Descending,
+					}},
+					nil,
+				),
+				"rn",
+			),
+			plan.NewWindowExpression(
+				expression.NewUnresolvedFunction("sum", true, expression.NewUnresolvedColumn("a")),
+				nil,
+				[]plan.SortField{{
+					Column: expression.NewUnresolvedColumn("c"),
+					Order:  plan.Ascending,
+				}},
+				&plan.WindowFrame{
+					Unit: plan.RowsFrame,
+					Start: plan.WindowFrameBound{
+						Type:   plan.Preceding,
+						Offset: expression.NewLiteral(int8(1), sql.Int8),
+					},
+					End: plan.WindowFrameBound{Type: plan.CurrentRow},
+				},
+			),
+		},
+		plan.NewUnresolvedTable("foo", ""),
+	),
+	`CREATE VIEW view1 AS SELECT x FROM t1 WHERE x>0`: plan.NewCreateView(
+		sql.UnresolvedDatabase(""),
+		"view1",
+		nil,
+		plan.NewProject(
+			[]sql.Expression{expression.NewUnresolvedColumn("x")},
+			plan.NewFilter(
+				expression.NewGreaterThan(
+					expression.NewUnresolvedColumn("x"),
+					expression.NewLiteral(int8(0), sql.Int8),
+				),
+				plan.NewUnresolvedTable("t1", ""),
+			),
+		),
+		"SELECT x FROM t1 WHERE x>0",
+		false,
+	),
+	"CREATE OR REPLACE VIEW mydb.`myview` (a, b) AS SELECT 1, 2": plan.NewCreateView(
+		sql.UnresolvedDatabase("mydb"),
+		"myview",
+		[]string{"a", "b"},
+		plan.NewProject(
+			[]sql.Expression{
+				expression.NewLiteral(int8(1), sql.Int8),
+				expression.NewLiteral(int8(2), sql.Int8),
+			},
+			plan.NewUnresolvedTable("dual", ""),
+		),
+		"SELECT 1, 2",
+		true,
+	),
+	`DROP VIEW v1`:                         plan.NewDropView(sql.UnresolvedDatabase(""), false, "v1"),
+	`DROP VIEW IF EXISTS mydb.v1, mydb.v2`: plan.NewDropView(sql.UnresolvedDatabase("mydb"), true, "v1", "v2"),
+	`SHOW CREATE VIEW mydb.v1`:             plan.NewShowCreateView(sql.UnresolvedDatabase("mydb"), "v1"),
+	`CREATE FUNCTION discount(price, tier) RETURNS DOUBLE LANGUAGE js AS 'price * (tier == 1 ? 0.9 : 1)'`: plan.NewCreateFunction(&sql.StoredFunction{
+		Name:           "discount",
+		Params:         []string{"price", "tier"},
+		ParamTypes:     []sql.Type{nil, nil},
+		ReturnType:     sql.Float64,
+		Language:       "js",
+		Body:           "price * (tier == 1 ? 0.9 : 1)",
+		TextDefinition: `CREATE FUNCTION discount(price, tier) RETURNS DOUBLE LANGUAGE js AS 'price * (tier == 1 ? 0.9 : 1)'`,
+	}),
+	"create function `Greet` (`Name` VARCHAR(10), n INT) returns TEXT language EXPR as \"'hello, ' + Name\"": plan.NewCreateFunction(&sql.StoredFunction{
+		Name:           "greet",
+		Params:         []string{"Name", "n"},
+		ParamTypes:     []sql.Type{sql.Text, sql.Int32},
+		ReturnType:     sql.Text,
+		Language:       "expr",
+		Body:           "'hello, ' + Name",
+		TextDefinition: "create function `Greet` (`Name` VARCHAR(10), n INT) returns TEXT language EXPR as \"'hello, ' + Name\"",
+	}),
+	`CREATE FUNCTION answer() RETURNS INT LANGUAGE javascript AS '42'`: plan.NewCreateFunction(&sql.StoredFunction{
+		Name:           "answer",
+		Params:         []string{},
+		ReturnType:     sql.Int32,
+		Language:       "js",
+		Body:           "42",
+		TextDefinition: `CREATE FUNCTION answer() RETURNS INT LANGUAGE javascript AS '42'`,
+	}),
+	`DROP FUNCTION f`:                plan.NewDropFunction(false, "f"),
+	`DROP FUNCTION IF EXISTS f, g`:   plan.NewDropFunction(true, "f", "g"),
+	`SHOW FUNCTION STATUS`:           plan.NewShowFunctionStatus(""),
+	`SHOW FUNCTION STATUS LIKE 'f%'`: plan.NewShowFunctionStatus("f%"),
+	"SELECT `over`, 'a over (b)' FROM foo": plan.NewProject(
+		[]sql.Expression{
+			expression.NewUnresolvedColumn("over"),
+			expression.NewLiteral("a over (b)", sql.Text),
+		},
+		plan.NewUnresolvedTable("foo", ""),
+	),
+	`SELECT * FROM js_table('[1]', 2)`: plan.NewProject(
+		[]sql.Expression{expression.NewStar()},
+		plan.NewTableFunction(
+			"js_table",
+			expression.NewUnresolvedFunction("js_table", false,
+				expression.NewLiteral("[1]", sql.Text),
+				expression.NewLiteral(int8(2), sql.Int8),
+			),
+			nil,
+		),
+	),
+	`SELECT t.a FROM foo JOIN js_table('[]') t(a INT, b TEXT) ON foo.a = t.a`: plan.NewProject(
+		[]sql.Expression{expression.NewUnresolvedQualifiedColumn("t", "a")},
+		plan.NewInnerJoin(
+			plan.NewUnresolvedTable("foo", ""),
+			plan.NewTableFunction(
+				"t",
+				expression.NewUnresolvedFunction("js_table", false,
+					expression.NewLiteral("[]", sql.Text),
+				),
+				sql.Schema{
+					{Name: "a", Type: sql.Int32},
+					{Name: "b", Type: sql.Text},
+				},
+			),
+			expression.NewEquals(
+				expression.NewUnresolvedQualifiedColumn("foo", "a"),
+				expression.NewUnresolvedQualifiedColumn("t", "a"),
+			),
+		),
+	),
+	"SELECT 'from f(x)' FROM (SELECT b FROM f(1) AS `x y`) AS s": plan.NewProject(
+		[]sql.Expression{expression.NewLiteral("from f(x)", sql.Text)},
+		plan.NewSubqueryAlias("s", plan.NewProject(
+			[]sql.Expression{expression.NewUnresolvedColumn("b")},
+			plan.NewTableFunction(
+				"x y",
+				expression.NewUnresolvedFunction("f", false,
+					expression.NewLiteral(int8(1), sql.Int8),
+				),
+				nil,
+			),
+		)),
+	),
+	`SELECT * FROM sales s PIVOT (SUM(amount) AS total, COUNT(*) FOR month IN ('jan', 'feb' AS february)) AS p`: plan.NewProject(
+		[]sql.Expression{expression.NewStar()},
+		plan.NewSubqueryAlias("p", plan.NewPivot(
+			[]sql.Expression{
+				expression.NewAlias(
+					expression.NewUnresolvedFunction("sum", true, expression.NewUnresolvedColumn("amount")),
+					"total",
+				),
+				expression.NewUnresolvedFunction("count", true, expression.NewStar()),
+			},
+			expression.NewUnresolvedColumn("month"),
+			[]sql.Expression{
+				expression.NewLiteral("jan", sql.Text),
+				expression.NewAlias(expression.NewLiteral("feb", sql.Text), "february"),
+			},
+			plan.NewTableAlias("s", plan.NewUnresolvedTable("sales", "")),
+		)),
+	),
+	`SELECT pivot FROM foo, bar UNPIVOT INCLUDE NULLS (v FOR k IN (bar.a, b AS 'B')) WHERE k = 'a'`: plan.NewProject(
+		[]sql.Expression{expression.NewUnresolvedColumn("pivot")},
+		plan.NewFilter(
+			expression.NewEquals(
+				expression.NewUnresolvedColumn("k"),
+				expression.NewLiteral("a", sql.Text),
+			),
+			plan.NewCrossJoin(
+				plan.NewUnresolvedTable("foo", ""),
+				plan.NewSubqueryAlias("unpivot", plan.NewUnpivot(
+					"v", "k",
+					[]sql.Expression{
+						expression.NewUnresolvedQualifiedColumn("bar", "a"),
+						expression.NewUnresolvedQualifiedColumn("", "b"),
+					},
+					[]string{"a", "B"},
+					true,
+					plan.NewUnresolvedTable("bar", ""),
+				)),
+			),
+		),
+	),
+	"SELECT * FROM (SELECT * FROM foo) AS t PIVOT (MAX(a) FOR b IN (1, 2)) p UNPIVOT (v FOR c IN (p.`1`))": plan.NewProject(
+		[]sql.Expression{expression.NewStar()},
+		plan.NewSubqueryAlias("unpivot", plan.NewUnpivot(
+			"v", "c",
+			[]sql.Expression{expression.NewUnresolvedQualifiedColumn("p", "1")},
+			[]string{"1"},
+			false,
+			plan.NewSubqueryAlias("p", plan.NewPivot(
+				[]sql.Expression{
+					expression.NewUnresolvedFunction("max", true, expression.NewUnresolvedColumn("a")),
+				},
+				expression.NewUnresolvedColumn("b"),
+				[]sql.Expression{
+					expression.NewLiteral(int8(1), sql.Int8),
+					expression.NewLiteral(int8(2), sql.Int8),
+				},
+				plan.NewSubqueryAlias("t", plan.NewProject(
+					[]sql.Expression{expression.NewStar()},
+					plan.NewUnresolvedTable("foo", ""),
+				)),
+			)),
+		)),
+	),
 }
 
 func TestParse(t *testing.T) {
@@ -1296,7 +1740,42 @@ var fixturesErrors = map[string]*errors.Kind{
 	`SELECT INTERVAL 1 DAY + INTERVAL 1 DAY`:                  ErrUnsupportedSyntax,
 	`SELECT '2018-05-01' + (INTERVAL 1 DAY + INTERVAL 1 DAY)`: ErrUnsupportedSyntax,
 	`SELECT AVG(DISTINCT foo) FROM b`:                         ErrUnsupportedSyntax,
-	`CREATE VIEW view1 AS SELECT x FROM t1 WHERE x>0`:         ErrUnsupportedFeature,
+	`CREATE VIEW v AS INSERT INTO t VALUES (1)`:               ErrUnsupportedSyntax,
+	`DROP VIEW a, b.c`:                                        ErrUnsupportedFeature,
+	`WITH t (a, b AS (SELECT 1, 2) SELECT * FROM t`:           errUnexpectedSyntax,
+	`WITH t AS (SELECT 1 SELECT * FROM t`:                     errUnexpectedSyntax,
+	`SELECT ROW_NUMBER() OVER w FROM foo`:                     ErrUnsupportedFeature,
+	`SELECT SUM(a) OVER (ORDER BY SUM(b) OVER ()) FROM foo`:   ErrUnsupportedFeature,
+	`SELECT COUNT(DISTINCT a) OVER () FROM foo`:               ErrUnsupportedFeature,
+	`SELECT a, COUNT(*) OVER () FROM foo GROUP BY a`:          ErrUnsupportedFeature,
+	`SELECT SUM(a) OVER (ORDER BY b PARTITION BY c) FROM foo`: ErrUnsupportedSyntax,
+	`SELECT SUM(a) OVER (ROWS b PRECEDING) FROM foo`:          ErrUnsupportedSyntax,
+	`ROLLBACK TO SAVEPOINT sp1 sp2`:                           errUnexpectedSyntax,
+	`RELEASE SAVEPOINT sp1, sp2`:                              errUnexpectedSyntax,
+	`ALTER TABLE foo ADD a INT, ADD b INT`:                    ErrUnsupportedFeature,
+	`ALTER TABLE foo ENGINE = InnoDB`:                         ErrUnsupportedFeature,
+	`ALTER TABLE foo RENAME TO otherdb.bar`:                   ErrUnsupportedFeature,
+	`RENAME TABLE foo TO otherdb.bar`:                         ErrUnsupportedFeature,
+	`CREATE FUNCTION f(a) RETURNS INT LANGUAGE py AS 'a'`:     ErrUnsupportedFeature,
+	`CREATE FUNCTION f() RETURNS INT LANGUAGE js AS 1 + 2`:    errInvalidFunctionBody,
+	`CREATE FUNCTION f(a) LANGUAGE js AS 'a'`:                 ErrUnsupportedSyntax,
+	`CREATE FUNCTION f(a+1) RETURNS INT LANGUAGE js AS 'a'`:   ErrUnsupportedSyntax,
+	`DROP FUNCTION f g`:                                       errUnexpectedSyntax,
+	`ANALYZE TABLE foo bar`:                                   errUnexpectedSyntax,
+	`ANALYZE TABLE foo.`:                                      errUnexpectedSyntax,
+	`ANALYZE TABLE foo, , bar`:                                errUnexpectedSyntax,
+	`SELECT * FROM f(1`:                                       errUnexpectedSyntax,
+	`SELECT * FROM f(1) AS t(a`:                               errUnexpectedSyntax,
+	`CREATE TABLE t1(a INTEGER) ENGINE=SCRIPT`:                errMissingTableScript,
+	`CREATE TABLE t1(a INTEGER) ENGINE=SCRIPT LANGUAGE=lua SCRIPT='1'`: ErrUnsupportedFeature,
+	`SELECT * FROM foo PIVOT (SUM(a) FOR b IN (1)`:            errUnexpectedSyntax,
+	`SELECT * FROM foo PIVOT (SUM(a) IN (1))`:                 errUnexpectedSyntax,
+	`SELECT * FROM foo PIVOT (SUM(a) FOR b IN ())`:            errUnexpectedSyntax,
+	`SELECT * FROM foo PIVOT (SUM(a) FOR b IN (1)) p(x INT)`:  errUnexpectedSyntax,
+	`SELECT * FROM foo UNPIVOT INCLUDE (a FOR b IN (c))`:      errUnexpectedSyntax,
+	`SELECT PIVOT (SUM(a) FOR b IN (1))`:                      errUnexpectedSyntax,
+	`SELECT * FROM foo UNPIVOT (a + 1 FOR b IN (c))`:          ErrUnsupportedSyntax,
+	`SELECT * FROM foo UNPIVOT (a FOR b IN (c + 1))`:          ErrUnsupportedSyntax,
 }
 
 func TestParseErrors(t *testing.T) {
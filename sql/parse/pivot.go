@@ -0,0 +1,379 @@
+package parse
+
+import (
+	"bytes"
+	"regexp"
+	"strings"
+
+	"github.com/src-d/go-mysql-server/sql"
+	"github.com/src-d/go-mysql-server/sql/expression"
+	"github.com/src-d/go-mysql-server/sql/plan"
+	"vitess.io/vitess/go/vt/sqlparser"
+)
+
+// pivotName and unpivotName are the names of the functions PIVOT and
+// UNPIVOT clauses are rewritten to before parsing the query, because vitess
+// does not support them.
+const (
+	pivotName   = "__pivot__"
+	unpivotName = "__unpivot__"
+)
+
+var pivotRegex = regexp.MustCompile(`\b(un)?pivot\b`)
+
+// replacePivots rewrites all the PIVOT and UNPIVOT clauses in the query as
+// subqueries selecting from the pivoted table, so the query can be parsed.
+// A PIVOT, such as `sales PIVOT (SUM(amount) FOR month IN ('jan', 'feb'))
+// AS p`, is rewritten as
+// `(SELECT SUM(amount), __pivot__(month), 'jan', 'feb' FROM sales) AS p`,
+// and an UNPIVOT, such as `sales UNPIVOT (amount FOR month IN (jan, feb))
+// AS u`, is rewritten as
+// `(SELECT __unpivot__(amount, month, false), jan, feb FROM sales) AS u`.
+// PIVOT and UNPIVOT clauses without an alias are named pivot and unpivot.
+func replacePivots(query string) (string, error) {
+	for {
+		rewritten, ok, err := replacePivot(query)
+		if err != nil {
+			return "", err
+		}
+
+		if !ok {
+			return query, nil
+		}
+
+		query = rewritten
+	}
+}
+
+// replacePivot rewrites the first PIVOT or UNPIVOT clause in the query, if
+// any. The pivoted table is the one between the FROM, the JOIN or the comma
+// before the clause and the clause.
+func replacePivot(query string) (string, bool, error) {
+	// Position where the last table starts at each level of parentheses,
+	// or -1 if there is none.
+	starts := []int{-1}
+	for i := 0; i < len(query); {
+		switch c := query[i]; {
+		case c == '\'' || c == '"' || c == '`':
+			i = skipQuoted(query, i)
+			continue
+		case c == '(':
+			starts = append(starts, -1)
+		case c == ')':
+			if len(starts) > 1 {
+				starts = starts[:len(starts)-1]
+			}
+		case c == ',':
+			starts[len(starts)-1] = i + 1
+		case isIdentByte(c):
+			j := readIdentAt(query, i)
+			keyword := strings.ToLower(query[i:j])
+			if i > 0 && query[i-1] == '.' {
+				i = j
+				continue
+			}
+
+			switch {
+			case keyword == "from" || strings.HasSuffix(keyword, "join"):
+				starts[len(starts)-1] = j
+			case keyword == "pivot" || keyword == "unpivot":
+				start := starts[len(starts)-1]
+				rewritten, ok, err := rewritePivot(query, start, i, j, keyword == "unpivot")
+				if err != nil || ok {
+					return rewritten, ok, err
+				}
+			}
+
+			i = j
+			continue
+		}
+
+		i++
+	}
+
+	return query, false, nil
+}
+
+// rewritePivot rewrites the PIVOT or UNPIVOT clause whose keyword is
+// between the given positions, on the table starting at start. It returns
+// false if the keyword does not start a clause.
+func rewritePivot(query string, start, keywordStart, keywordEnd int, unpivot bool) (string, bool, error) {
+	open := skipSpacesAt(query, keywordEnd)
+	includeNulls := false
+	if unpivot {
+		modEnd := readIdentAt(query, open)
+		switch mod := strings.ToLower(query[open:modEnd]); mod {
+		case "include", "exclude":
+			nullsStart := skipSpacesAt(query, modEnd)
+			nullsEnd := readIdentAt(query, nullsStart)
+			if !strings.EqualFold(query[nullsStart:nullsEnd], "nulls") {
+				return "", false, errUnexpectedSyntax.New("NULLS", query[nullsStart:nullsEnd])
+			}
+
+			includeNulls = mod == "include"
+			open = skipSpacesAt(query, nullsEnd)
+		}
+	}
+
+	if open >= len(query) || query[open] != '(' {
+		return "", false, nil
+	}
+
+	keyword := strings.ToUpper(query[keywordStart:keywordEnd])
+	if start < 0 || strings.TrimSpace(query[start:keywordStart]) == "" {
+		return "", false, errUnexpectedSyntax.New("table", keyword)
+	}
+
+	closing := matchingParen(query, open)
+	if closing < 0 {
+		return "", false, errUnexpectedSyntax.New(")", "EOF")
+	}
+
+	head, column, values, err := splitPivotSpec(query[open+1 : closing])
+	if err != nil {
+		return "", false, err
+	}
+
+	alias, columns, end, err := tableFunctionAlias(query, closing+1)
+	if err != nil {
+		return "", false, err
+	}
+
+	if columns != "" {
+		return "", false, errUnexpectedSyntax.New("alias", columns)
+	}
+
+	if alias == "" {
+		alias = "`" + strings.ToLower(keyword) + "`"
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString(query[:start])
+	buf.WriteString(" (SELECT ")
+	if unpivot {
+		buf.WriteString(unpivotName)
+		buf.WriteRune('(')
+		buf.WriteString(head)
+		buf.WriteString(", ")
+		buf.WriteString(column)
+		if includeNulls {
+			buf.WriteString(", true")
+		} else {
+			buf.WriteString(", false")
+		}
+		buf.WriteString("), ")
+		buf.WriteString(values)
+	} else {
+		buf.WriteString(head)
+		buf.WriteString(", ")
+		buf.WriteString(pivotName)
+		buf.WriteRune('(')
+		buf.WriteString(column)
+		buf.WriteString("), ")
+		buf.WriteString(values)
+	}
+	buf.WriteString(" FROM ")
+	buf.WriteString(strings.TrimSpace(query[start:keywordStart]))
+	buf.WriteString(") AS ")
+	buf.WriteString(alias)
+	buf.WriteString(query[end:])
+
+	return buf.String(), true, nil
+}
+
+// splitPivotSpec splits the spec of a PIVOT or UNPIVOT clause, such as
+// `SUM(amount) FOR month IN ('jan', 'feb')`, in the part before FOR, the
+// part between FOR and IN, and the values inside IN.
+func splitPivotSpec(spec string) (head, column, values string, err error) {
+	forStart, forEnd := indexKeyword(spec, "for", 0)
+	if forStart < 0 {
+		return "", "", "", errUnexpectedSyntax.New("FOR", spec)
+	}
+
+	inStart, inEnd := indexKeyword(spec, "in", forEnd)
+	if inStart < 0 {
+		return "", "", "", errUnexpectedSyntax.New("IN", spec[forEnd:])
+	}
+
+	open := skipSpacesAt(spec, inEnd)
+	if open >= len(spec) || spec[open] != '(' {
+		return "", "", "", errUnexpectedSyntax.New("(", spec[open:])
+	}
+
+	closing := matchingParen(spec, open)
+	if closing < 0 {
+		return "", "", "", errUnexpectedSyntax.New(")", "EOF")
+	}
+
+	if rest := strings.TrimSpace(spec[closing+1:]); rest != "" {
+		return "", "", "", errUnexpectedSyntax.New(")", rest)
+	}
+
+	head = strings.TrimSpace(spec[:forStart])
+	column = strings.TrimSpace(spec[forEnd:inStart])
+	values = strings.TrimSpace(spec[open+1 : closing])
+	switch {
+	case head == "":
+		return "", "", "", errUnexpectedSyntax.New("expression", "FOR")
+	case column == "":
+		return "", "", "", errUnexpectedSyntax.New("column", "IN")
+	case values == "":
+		return "", "", "", errUnexpectedSyntax.New("value", ")")
+	}
+
+	return head, column, values, nil
+}
+
+// indexKeyword returns the position of the given keyword and the position
+// after it, outside of quotes and parentheses, starting at the given
+// position. The positions are -1 if it's not found.
+func indexKeyword(query, keyword string, from int) (int, int) {
+	var depth int
+	for i := from; i < len(query); {
+		switch c := query[i]; {
+		case c == '\'' || c == '"' || c == '`':
+			i = skipQuoted(query, i)
+			continue
+		case c == '(':
+			depth++
+		case c == ')':
+			depth--
+		case isIdentByte(c):
+			j := readIdentAt(query, i)
+			if depth == 0 && strings.EqualFold(query[i:j], keyword) {
+				return i, j
+			}
+			i = j
+			continue
+		}
+
+		i++
+	}
+
+	return -1, -1
+}
+
+// pivotToTable converts a subquery created by replacePivots to the PIVOT or
+// UNPIVOT it was rewritten from. It returns false if the subquery is not
+// one of them.
+func pivotToTable(ctx *sql.Context, subquery *sqlparser.Subquery) (sql.Node, bool, error) {
+	s, ok := subquery.Select.(*sqlparser.Select)
+	if !ok {
+		return nil, false, nil
+	}
+
+	for i, se := range s.SelectExprs {
+		e, ok := se.(*sqlparser.AliasedExpr)
+		if !ok {
+			continue
+		}
+
+		f, ok := e.Expr.(*sqlparser.FuncExpr)
+		if !ok {
+			continue
+		}
+
+		switch f.Name.Lowered() {
+		case pivotName:
+			node, err := convertPivot(ctx, s, i, f)
+			return node, true, err
+		case unpivotName:
+			node, err := convertUnpivot(ctx, s, i, f)
+			return node, true, err
+		}
+	}
+
+	return nil, false, nil
+}
+
+func convertPivot(ctx *sql.Context, s *sqlparser.Select, idx int, f *sqlparser.FuncExpr) (sql.Node, error) {
+	if len(f.Exprs) != 1 {
+		return nil, ErrUnsupportedSyntax.New(f)
+	}
+
+	col, ok := f.Exprs[0].(*sqlparser.AliasedExpr)
+	if !ok {
+		return nil, ErrUnsupportedSyntax.New(f)
+	}
+
+	column, err := exprToExpression(ctx, col.Expr)
+	if err != nil {
+		return nil, err
+	}
+
+	aggregates, err := selectExprsToExpressions(ctx, s.SelectExprs[:idx])
+	if err != nil {
+		return nil, err
+	}
+
+	values, err := selectExprsToExpressions(ctx, s.SelectExprs[idx+1:])
+	if err != nil {
+		return nil, err
+	}
+
+	child, err := tableExprsToTable(ctx, s.From)
+	if err != nil {
+		return nil, err
+	}
+
+	return plan.NewPivot(aggregates, column, values, child), nil
+}
+
+func convertUnpivot(ctx *sql.Context, s *sqlparser.Select, idx int, f *sqlparser.FuncExpr) (sql.Node, error) {
+	if len(f.Exprs) != 3 || idx != 0 {
+		return nil, ErrUnsupportedSyntax.New(f)
+	}
+
+	var names [2]string
+	for i := range names {
+		e, ok := f.Exprs[i].(*sqlparser.AliasedExpr)
+		if !ok {
+			return nil, ErrUnsupportedSyntax.New(f)
+		}
+
+		col, ok := e.Expr.(*sqlparser.ColName)
+		if !ok || !col.Qualifier.IsEmpty() {
+			return nil, ErrUnsupportedSyntax.New(e.Expr)
+		}
+		names[i] = col.Name.String()
+	}
+
+	var includeNulls bool
+	if e, ok := f.Exprs[2].(*sqlparser.AliasedExpr); ok {
+		includeNulls = e.Expr == sqlparser.BoolVal(true)
+	}
+
+	var (
+		columns []sql.Expression
+		labels  []string
+	)
+	for _, se := range s.SelectExprs[1:] {
+		e, ok := se.(*sqlparser.AliasedExpr)
+		if !ok {
+			return nil, ErrUnsupportedSyntax.New(se)
+		}
+
+		col, ok := e.Expr.(*sqlparser.ColName)
+		if !ok {
+			return nil, ErrUnsupportedSyntax.New(e.Expr)
+		}
+
+		columns = append(columns, expression.NewUnresolvedQualifiedColumn(
+			col.Qualifier.Name.String(),
+			col.Name.String(),
+		))
+
+		label := e.As.String()
+		if label == "" {
+			label = col.Name.String()
+		}
+		labels = append(labels, label)
+	}
+
+	child, err := tableExprsToTable(ctx, s.From)
+	if err != nil {
+		return nil, err
+	}
+
+	return plan.NewUnpivot(names[0], names[1], columns, labels, includeNulls, child), nil
+}
@@ -59,6 +59,18 @@ func parseShowCreate(s string) (sql.Node, error) {
 			db,
 			nil,
 			table), nil
+	case "view":
+		var db, name string
+		err = parseFuncs{
+			readQualifiedIdent(&db, &name),
+			skipSpaces,
+			checkEOF,
+		}.exec(r)
+		if err != nil {
+			return nil, err
+		}
+
+		return plan.NewShowCreateView(sql.UnresolvedDatabase(db), name), nil
 	case "database", "schema":
 		var ifNotExists bool
 		var next string
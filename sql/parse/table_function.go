@@ -0,0 +1,236 @@
+package parse
+
+import (
+	"bytes"
+	"regexp"
+	"strings"
+
+	"github.com/src-d/go-mysql-server/sql"
+	"github.com/src-d/go-mysql-server/sql/plan"
+	"vitess.io/vitess/go/vt/sqlparser"
+)
+
+// tableFunctionName is the name of the function table function calls are
+// rewritten to before parsing the query, because vitess does not support
+// function calls in the FROM clause.
+const tableFunctionName = "__table_function__"
+
+var tableFunctionRegex = regexp.MustCompile(`\b(from|join)\s+[\w$]+\s*\(`)
+
+// tableAliasStop are the keywords that can follow a table in the FROM
+// clause, so they are not the alias of a table function.
+var tableAliasStop = map[string]bool{
+	"where": true, "group": true, "having": true, "order": true,
+	"limit": true, "join": true, "inner": true, "left": true,
+	"right": true, "cross": true, "natural": true, "straight_join": true,
+	"on": true, "using": true, "union": true, "for": true, "lock": true,
+	"into": true, "procedure": true, "offset": true, "pivot": true,
+	"unpivot": true,
+}
+
+// replaceTableFunctions rewrites all the function calls used as tables in
+// the query, such as `FROM js_table('...') AS t(a INT)`, as subqueries
+// calling an internal function with the table function call and the column
+// definitions as a string, such as
+// `FROM (SELECT __table_function__(js_table('...'), 'a INT')) AS t`, so the
+// query can be parsed. Table functions without an alias are named after the
+// function.
+func replaceTableFunctions(query string) (string, error) {
+	var (
+		buf     bytes.Buffer
+		written int
+	)
+
+	for i := 0; i < len(query); {
+		switch c := query[i]; {
+		case c == '\'' || c == '"' || c == '`':
+			i = skipQuoted(query, i)
+			continue
+		case isIdentByte(c):
+			j := readIdentAt(query, i)
+			keyword := strings.ToLower(query[i:j])
+			if (keyword != "from" && keyword != "join") || (i > 0 && query[i-1] == '.') {
+				i = j
+				continue
+			}
+
+			nameStart := skipSpacesAt(query, j)
+			nameEnd := readIdentAt(query, nameStart)
+			open := skipSpacesAt(query, nameEnd)
+			if nameStart == nameEnd || open >= len(query) || query[open] != '(' {
+				i = j
+				continue
+			}
+
+			closing := matchingParen(query, open)
+			if closing < 0 {
+				return "", errUnexpectedSyntax.New(")", "EOF")
+			}
+
+			args, err := replaceTableFunctions(query[open+1 : closing])
+			if err != nil {
+				return "", err
+			}
+
+			name := query[nameStart:nameEnd]
+			alias, columns, end, err := tableFunctionAlias(query, closing+1)
+			if err != nil {
+				return "", err
+			}
+			if alias == "" {
+				alias = "`" + name + "`"
+			}
+
+			buf.WriteString(query[written:nameStart])
+			buf.WriteString("(SELECT ")
+			buf.WriteString(tableFunctionName)
+			buf.WriteRune('(')
+			buf.WriteString(name)
+			buf.WriteRune('(')
+			buf.WriteString(args)
+			buf.WriteRune(')')
+			if columns != "" {
+				buf.WriteString(", '")
+				buf.WriteString(windowSpecEscaper.Replace(columns))
+				buf.WriteRune('\'')
+			}
+			buf.WriteString(")) AS ")
+			buf.WriteString(alias)
+
+			written = end
+			i = written
+			continue
+		}
+
+		i++
+	}
+
+	buf.WriteString(query[written:])
+	return buf.String(), nil
+}
+
+// tableFunctionAlias reads the alias of the table function call ending at
+// the given position, if any, and the column definitions following it. It
+// returns the position after them.
+func tableFunctionAlias(query string, pos int) (alias, columns string, end int, err error) {
+	start := skipSpacesAt(query, pos)
+	aliasEnd := readIdentAt(query, start)
+	if strings.EqualFold(query[start:aliasEnd], "as") {
+		start = skipSpacesAt(query, aliasEnd)
+		aliasEnd = readIdentAt(query, start)
+	} else if tableAliasStop[strings.ToLower(query[start:aliasEnd])] {
+		return "", "", pos, nil
+	}
+
+	if start < len(query) && query[start] == '`' {
+		aliasEnd = skipQuoted(query, start)
+	}
+
+	if start == aliasEnd {
+		return "", "", pos, nil
+	}
+
+	alias = query[start:aliasEnd]
+	open := skipSpacesAt(query, aliasEnd)
+	if open >= len(query) || query[open] != '(' {
+		return alias, "", aliasEnd, nil
+	}
+
+	closing := matchingParen(query, open)
+	if closing < 0 {
+		return "", "", 0, errUnexpectedSyntax.New(")", "EOF")
+	}
+
+	return alias, query[open+1 : closing], closing + 1, nil
+}
+
+func readIdentAt(query string, i int) int {
+	for i < len(query) && isIdentByte(query[i]) {
+		i++
+	}
+	return i
+}
+
+func skipSpacesAt(query string, i int) int {
+	for i < len(query) && isSpace(query[i]) {
+		i++
+	}
+	return i
+}
+
+// tableFunction returns the call to the internal table function created by
+// replaceTableFunctions in the given subquery, if any.
+func tableFunction(subquery *sqlparser.Subquery) (*sqlparser.FuncExpr, bool) {
+	s, ok := subquery.Select.(*sqlparser.Select)
+	if !ok || len(s.SelectExprs) != 1 {
+		return nil, false
+	}
+
+	e, ok := s.SelectExprs[0].(*sqlparser.AliasedExpr)
+	if !ok {
+		return nil, false
+	}
+
+	f, ok := e.Expr.(*sqlparser.FuncExpr)
+	if !ok || f.Name.Lowered() != tableFunctionName {
+		return nil, false
+	}
+
+	return f, true
+}
+
+// tableFunctionToTable converts a call to the internal table function
+// created by replaceTableFunctions to a table with the given name.
+func tableFunctionToTable(ctx *sql.Context, name string, f *sqlparser.FuncExpr) (sql.Node, error) {
+	if len(f.Exprs) < 1 || len(f.Exprs) > 2 {
+		return nil, ErrUnsupportedSyntax.New(f)
+	}
+
+	call, ok := f.Exprs[0].(*sqlparser.AliasedExpr)
+	if !ok {
+		return nil, ErrUnsupportedSyntax.New(f)
+	}
+
+	fn, err := exprToExpression(ctx, call.Expr)
+	if err != nil {
+		return nil, err
+	}
+
+	var schema sql.Schema
+	if len(f.Exprs) == 2 {
+		columns, ok := f.Exprs[1].(*sqlparser.AliasedExpr)
+		if !ok {
+			return nil, ErrUnsupportedSyntax.New(f)
+		}
+
+		val, ok := columns.Expr.(*sqlparser.SQLVal)
+		if !ok || val.Type != sqlparser.StrVal {
+			return nil, ErrUnsupportedSyntax.New(f)
+		}
+
+		schema, err = parseColumnDefinitions(string(val.Val))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return plan.NewTableFunction(name, fn, schema), nil
+}
+
+// parseColumnDefinitions parses a list of column definitions, such as
+// `a INT, b TEXT`.
+func parseColumnDefinitions(definitions string) (sql.Schema, error) {
+	// The definitions are parsed as part of a CREATE TABLE statement, which
+	// is the only place where vitess keeps column definitions.
+	stmt, err := sqlparser.ParseStrictDDL("create table t (" + definitions + ")")
+	if err != nil {
+		return nil, err
+	}
+
+	ddl, ok := stmt.(*sqlparser.DDL)
+	if !ok || ddl.TableSpec == nil || len(ddl.TableSpec.Columns) == 0 || len(ddl.TableSpec.Indexes) > 0 {
+		return nil, ErrUnsupportedSyntax.New(definitions)
+	}
+
+	return tableSpecToSchema(ddl.TableSpec)
+}
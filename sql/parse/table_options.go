@@ -0,0 +1,74 @@
+package parse
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/src-d/go-mysql-server/sql"
+	"github.com/src-d/go-mysql-server/sql/expression/function/udf"
+	"github.com/src-d/go-mysql-server/sql/plan"
+	errors "gopkg.in/src-d/go-errors.v1"
+	"vitess.io/vitess/go/vt/sqlparser"
+)
+
+var errMissingTableScript = errors.NewKind("the SCRIPT of the table %s is missing")
+
+// tableOptions returns the options of a CREATE TABLE statement written as
+// name=value after the column definitions, such as ENGINE=InnoDB, by their
+// lowercase name. Quoted values are unquoted.
+func tableOptions(query string) map[string]string {
+	var (
+		options = make(map[string]string)
+		tkn     = sqlparser.NewStringTokenizer(query)
+		depth   int
+		defined bool
+		prev    string
+		key     string
+	)
+
+	for {
+		typ, val := tkn.Scan()
+		switch {
+		case typ == 0 || typ == sqlparser.LEX_ERROR:
+			return options
+		case typ == '(':
+			depth++
+			defined = true
+		case typ == ')':
+			depth--
+		case depth > 0 || !defined:
+		case typ == '=':
+			key = strings.ToLower(prev)
+		case key != "":
+			options[key] = string(val)
+			key = ""
+		}
+
+		prev = string(val)
+	}
+}
+
+// convertCreateScriptTable creates a table whose rows are produced by the
+// script in its SCRIPT option, in the language of its LANGUAGE option or
+// JavaScript, as in `CREATE TABLE t (a INT) ENGINE=SCRIPT SCRIPT='[1, 2]'`.
+func convertCreateScriptTable(name string, schema sql.Schema, options map[string]string) (sql.Node, error) {
+	script, ok := options["script"]
+	if !ok {
+		return nil, errMissingTableScript.New(name)
+	}
+
+	language := "js"
+	if lang, ok := options["language"]; ok {
+		language, ok = functionLanguages[strings.ToLower(lang)]
+		if !ok {
+			return nil, ErrUnsupportedFeature.New(fmt.Sprintf("tables in language %s", lang))
+		}
+	}
+
+	return plan.NewCreateScriptTable(
+		sql.UnresolvedDatabase(""),
+		name,
+		schema,
+		udf.GetScriptInstance(language, script),
+	), nil
+}
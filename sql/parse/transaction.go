@@ -0,0 +1,103 @@
+package parse
+
+import (
+	"bufio"
+	"strings"
+
+	"github.com/src-d/go-mysql-server/sql"
+	"github.com/src-d/go-mysql-server/sql/plan"
+)
+
+func parseSavepoint(s string) (sql.Node, error) {
+	r := bufio.NewReader(strings.NewReader(s))
+
+	var name string
+	err := parseFuncs{
+		expect("savepoint"),
+		skipSpaces,
+		readQuotableIdent(&name),
+		skipSpaces,
+		checkEOF,
+	}.exec(r)
+	if err != nil {
+		return nil, err
+	}
+
+	return plan.NewCreateSavepoint(name), nil
+}
+
+func parseRollbackSavepoint(s string) (sql.Node, error) {
+	r := bufio.NewReader(strings.NewReader(s))
+
+	var word string
+	err := parseFuncs{
+		expect("rollback"),
+		skipSpaces,
+		readIdent(&word),
+		skipSpaces,
+	}.exec(r)
+	if err != nil {
+		return nil, err
+	}
+
+	if word == "work" {
+		err = parseFuncs{
+			readIdent(&word),
+			skipSpaces,
+		}.exec(r)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if word != "to" {
+		return nil, errUnexpectedSyntax.New("to", word)
+	}
+
+	// The SAVEPOINT keyword is optional, but it can also be the name of the
+	// savepoint.
+	var name string
+	err = parseFuncs{
+		readQuotableIdent(&name),
+		skipSpaces,
+	}.exec(r)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := r.Peek(1); err == nil && name == "savepoint" {
+		if err := readQuotableIdent(&name)(r); err != nil {
+			return nil, err
+		}
+	}
+
+	err = parseFuncs{
+		skipSpaces,
+		checkEOF,
+	}.exec(r)
+	if err != nil {
+		return nil, err
+	}
+
+	return plan.NewRollbackSavepoint(name), nil
+}
+
+func parseReleaseSavepoint(s string) (sql.Node, error) {
+	r := bufio.NewReader(strings.NewReader(s))
+
+	var name string
+	err := parseFuncs{
+		expect("release"),
+		skipSpaces,
+		expect("savepoint"),
+		skipSpaces,
+		readQuotableIdent(&name),
+		skipSpaces,
+		checkEOF,
+	}.exec(r)
+	if err != nil {
+		return nil, err
+	}
+
+	return plan.NewReleaseSavepoint(name), nil
+}
@@ -0,0 +1,172 @@
+package parse
+
+import (
+	"bufio"
+	"io"
+	"regexp"
+	"strings"
+	"unicode"
+
+	"github.com/src-d/go-mysql-server/sql"
+	"github.com/src-d/go-mysql-server/sql/plan"
+)
+
+var viewDefinitionRegex = regexp.MustCompile(`^(select|with)\s|^\(`)
+
+func parseCreateView(ctx *sql.Context, s string) (sql.Node, error) {
+	r := bufio.NewReader(strings.NewReader(s))
+
+	err := parseFuncs{
+		expect("create"),
+		skipSpaces,
+	}.exec(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var isReplace bool
+	bs, err := r.Peek(len("or "))
+	if err == nil && strings.ToLower(string(bs[:2])) == "or" && unicode.IsSpace(rune(bs[2])) {
+		isReplace = true
+		err = parseFuncs{
+			expect("or"),
+			skipSpaces,
+			expect("replace"),
+			skipSpaces,
+		}.exec(r)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var db, name, definition string
+	var columns []string
+	err = parseFuncs{
+		expect("view"),
+		skipSpaces,
+		readQualifiedIdent(&db, &name),
+		skipSpaces,
+		readColumnNames(&columns),
+		skipSpaces,
+		expect("as"),
+		skipSpaces,
+		readRemaining(&definition),
+	}.exec(r)
+	if err != nil {
+		return nil, err
+	}
+
+	if !viewDefinitionRegex.MatchString(strings.ToLower(definition)) {
+		return nil, ErrUnsupportedSyntax.New(s)
+	}
+
+	node, err := Parse(ctx, definition)
+	if err != nil {
+		return nil, err
+	}
+
+	return plan.NewCreateView(
+		sql.UnresolvedDatabase(db),
+		name,
+		columns,
+		node,
+		definition,
+		isReplace,
+	), nil
+}
+
+func parseDropView(s string) (sql.Node, error) {
+	r := bufio.NewReader(strings.NewReader(s))
+
+	err := parseFuncs{
+		expect("drop"),
+		skipSpaces,
+		expect("view"),
+		skipSpaces,
+	}.exec(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var ifExists bool
+	bs, err := r.Peek(len("if "))
+	if err == nil && strings.ToLower(string(bs[:2])) == "if" && unicode.IsSpace(rune(bs[2])) {
+		ifExists = true
+		err = parseFuncs{
+			expect("if"),
+			skipSpaces,
+			expect("exists"),
+			skipSpaces,
+		}.exec(r)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var database string
+	var names []string
+	for {
+		var db, name string
+		err := parseFuncs{
+			readQualifiedIdent(&db, &name),
+			skipSpaces,
+		}.exec(r)
+		if err != nil {
+			return nil, err
+		}
+
+		// All the views must be in the same database, because they are
+		// dropped from the database of the node.
+		if len(names) > 0 && db != database {
+			return nil, ErrUnsupportedFeature.New("dropping views from several databases")
+		}
+
+		database = db
+		names = append(names, name)
+
+		ru, _, err := r.ReadRune()
+		if err == io.EOF {
+			break
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		if ru != ',' {
+			return nil, errUnexpectedSyntax.New(",", string(ru))
+		}
+
+		if err := skipSpaces(r); err != nil {
+			return nil, err
+		}
+	}
+
+	return plan.NewDropView(sql.UnresolvedDatabase(database), ifExists, names...), nil
+}
+
+// readQualifiedIdent reads an identifier that may be qualified with the name
+// of a database, such as `db.name`.
+func readQualifiedIdent(db, name *string) parseFunc {
+	return func(r *bufio.Reader) error {
+		if err := readQuotableIdent(name)(r); err != nil {
+			return err
+		}
+
+		ru, _, err := r.ReadRune()
+		if err == io.EOF {
+			return nil
+		}
+
+		if err != nil {
+			return err
+		}
+
+		if ru != '.' {
+			return r.UnreadRune()
+		}
+
+		*db = *name
+		return readQuotableIdent(name)(r)
+	}
+}
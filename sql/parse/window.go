@@ -0,0 +1,439 @@
+package parse
+
+import (
+	"bytes"
+	"regexp"
+	"strings"
+
+	"github.com/src-d/go-mysql-server/sql"
+	"github.com/src-d/go-mysql-server/sql/expression"
+	"github.com/src-d/go-mysql-server/sql/plan"
+	"vitess.io/vitess/go/vt/sqlparser"
+)
+
+// windowFunctionName is the name of the function window function calls are
+// rewritten to before parsing the query, because the OVER clause is not
+// supported by vitess.
+const windowFunctionName = "__window__"
+
+var overRegex = regexp.MustCompile(`\bover\b`)
+
+var windowSpecEscaper = strings.NewReplacer(`\`, `\\`, `'`, `\'`)
+
+// replaceWindows rewrites all the window function calls in the query, such as
+// `ROW_NUMBER() OVER (ORDER BY a)`, as calls to an internal function with the
+// window function call and the window specification as a string, such as
+// `__window__(ROW_NUMBER(), 'ORDER BY a')`, so the query can be parsed.
+func replaceWindows(query string) (string, error) {
+	var (
+		buf     bytes.Buffer
+		opening []int
+		matches = make(map[int]int)
+		written int
+	)
+
+	for i := 0; i < len(query); {
+		switch c := query[i]; {
+		case c == '\'' || c == '"' || c == '`':
+			i = skipQuoted(query, i)
+			continue
+		case c == '(':
+			opening = append(opening, i)
+		case c == ')':
+			if len(opening) > 0 {
+				matches[i] = opening[len(opening)-1]
+				opening = opening[:len(opening)-1]
+			}
+		case isIdentByte(c):
+			j := i
+			for j < len(query) && isIdentByte(query[j]) {
+				j++
+			}
+
+			if !strings.EqualFold(query[i:j], "over") || (i > 0 && query[i-1] == '.') {
+				i = j
+				continue
+			}
+
+			callStart, callEnd, ok := windowFunctionCall(query, i, matches)
+			if !ok {
+				i = j
+				continue
+			}
+
+			if callStart < written {
+				return "", ErrUnsupportedFeature.New("nested window functions")
+			}
+
+			specStart := j
+			for specStart < len(query) && isSpace(query[specStart]) {
+				specStart++
+			}
+
+			if specStart >= len(query) || query[specStart] != '(' {
+				return "", ErrUnsupportedFeature.New("named windows")
+			}
+
+			specEnd := matchingParen(query, specStart)
+			if specEnd < 0 {
+				return "", errUnexpectedSyntax.New(")", "EOF")
+			}
+
+			spec := query[specStart+1 : specEnd]
+			if replaced, err := replaceWindows(spec); err != nil || replaced != spec {
+				return "", ErrUnsupportedFeature.New("nested window functions")
+			}
+
+			buf.WriteString(query[written:callStart])
+			buf.WriteString(windowFunctionName)
+			buf.WriteRune('(')
+			buf.WriteString(query[callStart:callEnd])
+			buf.WriteString(", '")
+			buf.WriteString(windowSpecEscaper.Replace(spec))
+			buf.WriteString("')")
+
+			written = specEnd + 1
+			i = written
+			continue
+		}
+
+		i++
+	}
+
+	buf.WriteString(query[written:])
+	return buf.String(), nil
+}
+
+// windowFunctionCall returns the start and the end of the function call
+// before the OVER keyword at the given position, if any.
+func windowFunctionCall(query string, over int, matches map[int]int) (start, end int, ok bool) {
+	end = over
+	for end > 0 && isSpace(query[end-1]) {
+		end--
+	}
+
+	if end == 0 || query[end-1] != ')' {
+		return 0, 0, false
+	}
+
+	start, ok = matches[end-1]
+	if !ok {
+		return 0, 0, false
+	}
+
+	nameEnd := start
+	for start > 0 && isIdentByte(query[start-1]) {
+		start--
+	}
+
+	return start, end, start < nameEnd
+}
+
+// matchingParen returns the position of the parenthesis closing the one at
+// the given position, or -1 if there is none.
+func matchingParen(query string, open int) int {
+	var depth int
+	for i := open; i < len(query); {
+		switch query[i] {
+		case '\'', '"', '`':
+			i = skipQuoted(query, i)
+			continue
+		case '(':
+			depth++
+		case ')':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+		i++
+	}
+	return -1
+}
+
+// skipQuoted returns the position after the quoted string or identifier
+// starting at the given position.
+func skipQuoted(query string, i int) int {
+	quote := query[i]
+	for i++; i < len(query); i++ {
+		switch {
+		case query[i] == '\\' && quote != '`':
+			i++
+		case query[i] == quote:
+			if i+1 < len(query) && query[i+1] == quote {
+				i++
+				continue
+			}
+			return i + 1
+		}
+	}
+	return len(query)
+}
+
+func isIdentByte(c byte) bool {
+	return c == '_' || c == '$' ||
+		(c >= 'a' && c <= 'z') ||
+		(c >= 'A' && c <= 'Z') ||
+		(c >= '0' && c <= '9')
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r'
+}
+
+// windowToExpression converts a call to the internal window function created
+// by replaceWindows to a window expression.
+func windowToExpression(ctx *sql.Context, f *sqlparser.FuncExpr) (sql.Expression, error) {
+	if len(f.Exprs) != 2 {
+		return nil, ErrUnsupportedSyntax.New(f)
+	}
+
+	call, ok := f.Exprs[0].(*sqlparser.AliasedExpr)
+	if !ok {
+		return nil, ErrUnsupportedSyntax.New(f)
+	}
+
+	if fn, ok := call.Expr.(*sqlparser.FuncExpr); ok && fn.Distinct {
+		return nil, ErrUnsupportedFeature.New("DISTINCT in window functions")
+	}
+
+	fn, err := exprToExpression(ctx, call.Expr)
+	if err != nil {
+		return nil, err
+	}
+
+	spec, ok := f.Exprs[1].(*sqlparser.AliasedExpr)
+	if !ok {
+		return nil, ErrUnsupportedSyntax.New(f)
+	}
+
+	val, ok := spec.Expr.(*sqlparser.SQLVal)
+	if !ok || val.Type != sqlparser.StrVal {
+		return nil, ErrUnsupportedSyntax.New(f)
+	}
+
+	partitionBy, orderBy, frame, err := parseWindowSpec(ctx, string(val.Val))
+	if err != nil {
+		return nil, err
+	}
+
+	return plan.NewWindowExpression(fn, partitionBy, orderBy, frame), nil
+}
+
+type windowClause struct {
+	kind       string
+	start, end int
+}
+
+// parseWindowSpec parses the specification of a window, which is the part
+// between parenthesis after the OVER keyword.
+func parseWindowSpec(
+	ctx *sql.Context,
+	spec string,
+) ([]sql.Expression, []plan.SortField, *plan.WindowFrame, error) {
+	clauses, err := windowClauses(spec)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	var (
+		partitionBy []sql.Expression
+		orderBy     []plan.SortField
+		frame       *plan.WindowFrame
+	)
+
+	for i, c := range clauses {
+		end := len(spec)
+		if i+1 < len(clauses) {
+			end = clauses[i+1].start
+		}
+		text := spec[c.end:end]
+
+		switch c.kind {
+		case "partition":
+			partitionBy, err = parseExprList(ctx, text)
+		case "order":
+			orderBy, err = parseOrderByList(ctx, text)
+		case "frame":
+			frame, err = parseWindowFrame(ctx, text)
+		}
+
+		if err != nil {
+			return nil, nil, nil, err
+		}
+	}
+
+	return partitionBy, orderBy, frame, nil
+}
+
+// windowClauses returns the PARTITION BY, ORDER BY and frame clauses of the
+// window specification, making sure they appear at most once and in the
+// right order.
+func windowClauses(spec string) ([]windowClause, error) {
+	var words []windowClause
+	var depth int
+	for i := 0; i < len(spec); {
+		switch c := spec[i]; {
+		case c == '\'' || c == '"' || c == '`':
+			i = skipQuoted(spec, i)
+			continue
+		case c == '(':
+			depth++
+		case c == ')':
+			depth--
+		case isIdentByte(c):
+			j := i
+			for j < len(spec) && isIdentByte(spec[j]) {
+				j++
+			}
+
+			if depth == 0 {
+				words = append(words, windowClause{strings.ToLower(spec[i:j]), i, j})
+			}
+			i = j
+			continue
+		}
+		i++
+	}
+
+	var clauses []windowClause
+	for i := 0; i < len(words); i++ {
+		w := words[i]
+		switch w.kind {
+		case "partition", "order":
+			if i+1 < len(words) && words[i+1].kind == "by" {
+				clauses = append(clauses, windowClause{w.kind, w.start, words[i+1].end})
+				i++
+			}
+		case "rows", "range":
+			clauses = append(clauses, windowClause{"frame", w.start, w.start})
+			// Everything after this is part of the frame.
+			i = len(words)
+		}
+	}
+
+	var kinds = map[string]int{"partition": 0, "order": 1, "frame": 2}
+	for i, c := range clauses {
+		if i > 0 && kinds[clauses[i-1].kind] >= kinds[c.kind] {
+			return nil, ErrUnsupportedSyntax.New(spec)
+		}
+	}
+
+	var first = len(spec)
+	if len(clauses) > 0 {
+		first = clauses[0].start
+	}
+
+	if strings.TrimSpace(spec[:first]) != "" {
+		return nil, ErrUnsupportedFeature.New("named windows")
+	}
+
+	return clauses, nil
+}
+
+func parseExprList(ctx *sql.Context, str string) ([]sql.Expression, error) {
+	stmt, err := sqlparser.Parse("SELECT " + str)
+	if err != nil {
+		return nil, err
+	}
+
+	s, ok := stmt.(*sqlparser.Select)
+	if !ok {
+		return nil, ErrUnsupportedSyntax.New(str)
+	}
+
+	return selectExprsToExpressions(ctx, s.SelectExprs)
+}
+
+func parseOrderByList(ctx *sql.Context, str string) ([]plan.SortField, error) {
+	stmt, err := sqlparser.Parse("SELECT 1 FROM dual ORDER BY " + str)
+	if err != nil {
+		return nil, err
+	}
+
+	s, ok := stmt.(*sqlparser.Select)
+	if !ok {
+		return nil, ErrUnsupportedSyntax.New(str)
+	}
+
+	return orderByToSortFields(ctx, s.OrderBy)
+}
+
+// parseWindowFrame parses a frame clause, which can be either
+// `{ROWS | RANGE} start` or `{ROWS | RANGE} BETWEEN start AND end`.
+func parseWindowFrame(ctx *sql.Context, str string) (*plan.WindowFrame, error) {
+	fields := strings.Fields(strings.ToLower(str))
+
+	var unit plan.WindowFrameUnit
+	switch fields[0] {
+	case "rows":
+		unit = plan.RowsFrame
+	case "range":
+		unit = plan.RangeFrame
+	}
+
+	var startFields, endFields = fields[1:], []string{"current", "row"}
+	if len(fields) > 1 && fields[1] == "between" {
+		var and = -1
+		for i, f := range fields {
+			if f == "and" {
+				and = i
+				break
+			}
+		}
+
+		if and < 0 {
+			return nil, ErrUnsupportedSyntax.New(str)
+		}
+
+		startFields, endFields = fields[2:and], fields[and+1:]
+	}
+
+	start, err := parseWindowFrameBound(ctx, startFields, str)
+	if err != nil {
+		return nil, err
+	}
+
+	end, err := parseWindowFrameBound(ctx, endFields, str)
+	if err != nil {
+		return nil, err
+	}
+
+	return plan.NewWindowFrame(unit, start, end)
+}
+
+func parseWindowFrameBound(ctx *sql.Context, fields []string, str string) (plan.WindowFrameBound, error) {
+	if len(fields) != 2 {
+		return plan.WindowFrameBound{}, ErrUnsupportedSyntax.New(str)
+	}
+
+	switch fields[0] + " " + fields[1] {
+	case "unbounded preceding":
+		return plan.WindowFrameBound{Type: plan.UnboundedPreceding}, nil
+	case "unbounded following":
+		return plan.WindowFrameBound{Type: plan.UnboundedFollowing}, nil
+	case "current row":
+		return plan.WindowFrameBound{Type: plan.CurrentRow}, nil
+	}
+
+	var typ plan.WindowFrameBoundType
+	switch fields[1] {
+	case "preceding":
+		typ = plan.Preceding
+	case "following":
+		typ = plan.Following
+	default:
+		return plan.WindowFrameBound{}, ErrUnsupportedSyntax.New(str)
+	}
+
+	offset, err := parseExpr(ctx, fields[0])
+	if err != nil {
+		return plan.WindowFrameBound{}, err
+	}
+
+	if l, ok := offset.(*expression.Literal); !ok || !sql.IsNumber(l.Type()) {
+		return plan.WindowFrameBound{}, ErrUnsupportedSyntax.New(str)
+	}
+
+	return plan.WindowFrameBound{Type: typ, Offset: offset}, nil
+}
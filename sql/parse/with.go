@@ -0,0 +1,184 @@
+package parse
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"strings"
+	"unicode"
+
+	"github.com/src-d/go-mysql-server/sql"
+	"github.com/src-d/go-mysql-server/sql/plan"
+)
+
+func parseWith(ctx *sql.Context, s string) (sql.Node, error) {
+	r := bufio.NewReader(strings.NewReader(s))
+
+	err := parseFuncs{
+		expect("with"),
+		skipSpaces,
+	}.exec(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var recursive bool
+	bs, err := r.Peek(len("recursive "))
+	if err == nil && strings.ToLower(string(bs[:len(bs)-1])) == "recursive" &&
+		unicode.IsSpace(rune(bs[len(bs)-1])) {
+		recursive = true
+		err = parseFuncs{
+			expect("recursive"),
+			skipSpaces,
+		}.exec(r)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var ctes []*plan.CommonTableExpression
+	for {
+		var name, definition string
+		var columns []string
+		err := parseFuncs{
+			readQuotableIdent(&name),
+			skipSpaces,
+			readColumnNames(&columns),
+			skipSpaces,
+			expect("as"),
+			skipSpaces,
+			readParenthesized(&definition),
+			skipSpaces,
+		}.exec(r)
+		if err != nil {
+			return nil, err
+		}
+
+		node, err := Parse(ctx, definition)
+		if err != nil {
+			return nil, err
+		}
+
+		ctes = append(ctes, plan.NewCommonTableExpression(name, columns, node))
+
+		ru, _, err := r.ReadRune()
+		if err != nil {
+			return nil, err
+		}
+
+		if ru != ',' {
+			if err := r.UnreadRune(); err != nil {
+				return nil, err
+			}
+			break
+		}
+
+		if err := skipSpaces(r); err != nil {
+			return nil, err
+		}
+	}
+
+	var query string
+	if err := readRemaining(&query)(r); err != nil {
+		return nil, err
+	}
+
+	node, err := Parse(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	return plan.NewWith(node, ctes, recursive), nil
+}
+
+// readColumnNames reads an optional list of column names between
+// parenthesis.
+func readColumnNames(columns *[]string) parseFunc {
+	return func(r *bufio.Reader) error {
+		bs, err := r.Peek(1)
+		if err != nil || bs[0] != '(' {
+			return err
+		}
+
+		if err := expectRune('(')(r); err != nil {
+			return err
+		}
+
+		for {
+			var column string
+			err := parseFuncs{
+				skipSpaces,
+				readQuotableIdent(&column),
+				skipSpaces,
+			}.exec(r)
+			if err != nil {
+				return err
+			}
+
+			*columns = append(*columns, column)
+
+			ru, _, err := r.ReadRune()
+			if err != nil {
+				return err
+			}
+
+			switch ru {
+			case ',':
+				continue
+			case ')':
+				return nil
+			default:
+				return errUnexpectedSyntax.New(")", string(ru))
+			}
+		}
+	}
+}
+
+// readParenthesized reads everything between a pair of balanced parenthesis.
+// Parenthesis inside quoted strings or identifiers are ignored.
+func readParenthesized(val *string) parseFunc {
+	return func(r *bufio.Reader) error {
+		if err := expectRune('(')(r); err != nil {
+			return err
+		}
+
+		var buf bytes.Buffer
+		var quote rune
+		var depth = 1
+		for {
+			ru, _, err := r.ReadRune()
+			if err == io.EOF {
+				return errUnexpectedSyntax.New(")", "EOF")
+			}
+
+			if err != nil {
+				return err
+			}
+
+			switch {
+			case quote != 0:
+				if ru == '\\' && quote != '`' {
+					buf.WriteRune(ru)
+					ru, _, err = r.ReadRune()
+					if err != nil {
+						return err
+					}
+				} else if ru == quote {
+					quote = 0
+				}
+			case ru == '\'' || ru == '"' || ru == '`':
+				quote = ru
+			case ru == '(':
+				depth++
+			case ru == ')':
+				depth--
+				if depth == 0 {
+					*val = buf.String()
+					return nil
+				}
+			}
+
+			buf.WriteRune(ru)
+		}
+	}
+}
@@ -0,0 +1,400 @@
+package plan
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/src-d/go-mysql-server/sql"
+	"gopkg.in/src-d/go-errors.v1"
+)
+
+// ErrAlterTableNotSupported is thrown when the table doesn't support changing
+// its schema.
+var ErrAlterTableNotSupported = errors.NewKind("table %s cannot be altered")
+
+// ErrRenameTableNotSupported is thrown when the database doesn't support
+// renaming tables.
+var ErrRenameTableNotSupported = errors.NewKind("tables cannot be renamed on database %s")
+
+// RenameTable is a node describing renaming one or more tables.
+type RenameTable struct {
+	db       sql.Database
+	oldNames []string
+	newNames []string
+}
+
+// NewRenameTable creates a new RenameTable node. Each table in oldNames is
+// renamed to the name in the same position of newNames.
+func NewRenameTable(db sql.Database, oldNames, newNames []string) *RenameTable {
+	return &RenameTable{
+		db:       db,
+		oldNames: oldNames,
+		newNames: newNames,
+	}
+}
+
+var _ sql.Databaser = (*RenameTable)(nil)
+
+// Database implements the sql.Databaser interface.
+func (r *RenameTable) Database() sql.Database {
+	return r.db
+}
+
+// WithDatabase implements the sql.Databaser interface.
+func (r *RenameTable) WithDatabase(db sql.Database) (sql.Node, error) {
+	nr := *r
+	nr.db = db
+	return &nr, nil
+}
+
+// Resolved implements the Resolvable interface.
+func (r *RenameTable) Resolved() bool {
+	_, ok := r.db.(sql.UnresolvedDatabase)
+	return !ok
+}
+
+// RowIter implements the Node interface.
+func (r *RenameTable) RowIter(ctx *sql.Context) (sql.RowIter, error) {
+	renamer, ok := r.db.(sql.TableRenamer)
+	if !ok {
+		return nil, ErrRenameTableNotSupported.New(r.db.Name())
+	}
+
+	// Tables are renamed in order, so tables can be swapped using a
+	// temporary name.
+	for i, oldName := range r.oldNames {
+		if err := renamer.RenameTable(ctx, oldName, r.newNames[i]); err != nil {
+			return nil, err
+		}
+	}
+
+	return sql.RowsToRowIter(), nil
+}
+
+// Schema implements the Node interface.
+func (r *RenameTable) Schema() sql.Schema { return nil }
+
+// Children implements the Node interface.
+func (r *RenameTable) Children() []sql.Node { return nil }
+
+// WithChildren implements the Node interface.
+func (r *RenameTable) WithChildren(children ...sql.Node) (sql.Node, error) {
+	if len(children) != 0 {
+		return nil, sql.ErrInvalidChildrenNumber.New(r, len(children), 0)
+	}
+	return r, nil
+}
+
+func (r *RenameTable) String() string {
+	var renames = make([]string, len(r.oldNames))
+	for i, oldName := range r.oldNames {
+		renames[i] = fmt.Sprintf("%s to %s", oldName, r.newNames[i])
+	}
+	return fmt.Sprintf("Rename table %s", strings.Join(renames, ", "))
+}
+
+// AddColumn is a node describing adding a column to a table.
+type AddColumn struct {
+	db     sql.Database
+	table  string
+	column *sql.Column
+	order  *sql.ColumnOrder
+}
+
+// NewAddColumn creates a new AddColumn node.
+func NewAddColumn(db sql.Database, table string, column *sql.Column, order *sql.ColumnOrder) *AddColumn {
+	column.Source = table
+	return &AddColumn{
+		db:     db,
+		table:  table,
+		column: column,
+		order:  order,
+	}
+}
+
+var _ sql.Databaser = (*AddColumn)(nil)
+
+// Database implements the sql.Databaser interface.
+func (a *AddColumn) Database() sql.Database {
+	return a.db
+}
+
+// WithDatabase implements the sql.Databaser interface.
+func (a *AddColumn) WithDatabase(db sql.Database) (sql.Node, error) {
+	na := *a
+	na.db = db
+	return &na, nil
+}
+
+// Resolved implements the Resolvable interface.
+func (a *AddColumn) Resolved() bool {
+	_, ok := a.db.(sql.UnresolvedDatabase)
+	return !ok
+}
+
+// RowIter implements the Node interface.
+func (a *AddColumn) RowIter(ctx *sql.Context) (sql.RowIter, error) {
+	alterable, err := getAlterableTable(a.db, a.table)
+	if err != nil {
+		return nil, err
+	}
+
+	return sql.RowsToRowIter(), alterable.AddColumn(ctx, a.column, a.order)
+}
+
+// Schema implements the Node interface.
+func (a *AddColumn) Schema() sql.Schema { return nil }
+
+// Children implements the Node interface.
+func (a *AddColumn) Children() []sql.Node { return nil }
+
+// WithChildren implements the Node interface.
+func (a *AddColumn) WithChildren(children ...sql.Node) (sql.Node, error) {
+	if len(children) != 0 {
+		return nil, sql.ErrInvalidChildrenNumber.New(a, len(children), 0)
+	}
+	return a, nil
+}
+
+func (a *AddColumn) String() string {
+	return fmt.Sprintf("Add column %s to %s%s", a.column.Name, a.table, columnOrderString(a.order))
+}
+
+// DropColumn is a node describing removing a column from a table.
+type DropColumn struct {
+	db     sql.Database
+	table  string
+	column string
+}
+
+// NewDropColumn creates a new DropColumn node.
+func NewDropColumn(db sql.Database, table, column string) *DropColumn {
+	return &DropColumn{
+		db:     db,
+		table:  table,
+		column: column,
+	}
+}
+
+var _ sql.Databaser = (*DropColumn)(nil)
+
+// Database implements the sql.Databaser interface.
+func (d *DropColumn) Database() sql.Database {
+	return d.db
+}
+
+// WithDatabase implements the sql.Databaser interface.
+func (d *DropColumn) WithDatabase(db sql.Database) (sql.Node, error) {
+	nd := *d
+	nd.db = db
+	return &nd, nil
+}
+
+// Resolved implements the Resolvable interface.
+func (d *DropColumn) Resolved() bool {
+	_, ok := d.db.(sql.UnresolvedDatabase)
+	return !ok
+}
+
+// RowIter implements the Node interface.
+func (d *DropColumn) RowIter(ctx *sql.Context) (sql.RowIter, error) {
+	alterable, err := getAlterableTable(d.db, d.table)
+	if err != nil {
+		return nil, err
+	}
+
+	return sql.RowsToRowIter(), alterable.DropColumn(ctx, d.column)
+}
+
+// Schema implements the Node interface.
+func (d *DropColumn) Schema() sql.Schema { return nil }
+
+// Children implements the Node interface.
+func (d *DropColumn) Children() []sql.Node { return nil }
+
+// WithChildren implements the Node interface.
+func (d *DropColumn) WithChildren(children ...sql.Node) (sql.Node, error) {
+	if len(children) != 0 {
+		return nil, sql.ErrInvalidChildrenNumber.New(d, len(children), 0)
+	}
+	return d, nil
+}
+
+func (d *DropColumn) String() string {
+	return fmt.Sprintf("Drop column %s from %s", d.column, d.table)
+}
+
+// ModifyColumn is a node describing changing the definition of a column of a
+// table, including its name.
+type ModifyColumn struct {
+	db     sql.Database
+	table  string
+	name   string
+	column *sql.Column
+	order  *sql.ColumnOrder
+}
+
+// NewModifyColumn creates a new ModifyColumn node that replaces the column
+// with the given name with the given column.
+func NewModifyColumn(
+	db sql.Database,
+	table string,
+	name string,
+	column *sql.Column,
+	order *sql.ColumnOrder,
+) *ModifyColumn {
+	column.Source = table
+	return &ModifyColumn{
+		db:     db,
+		table:  table,
+		name:   name,
+		column: column,
+		order:  order,
+	}
+}
+
+var _ sql.Databaser = (*ModifyColumn)(nil)
+
+// Database implements the sql.Databaser interface.
+func (m *ModifyColumn) Database() sql.Database {
+	return m.db
+}
+
+// WithDatabase implements the sql.Databaser interface.
+func (m *ModifyColumn) WithDatabase(db sql.Database) (sql.Node, error) {
+	nm := *m
+	nm.db = db
+	return &nm, nil
+}
+
+// Resolved implements the Resolvable interface.
+func (m *ModifyColumn) Resolved() bool {
+	_, ok := m.db.(sql.UnresolvedDatabase)
+	return !ok
+}
+
+// RowIter implements the Node interface.
+func (m *ModifyColumn) RowIter(ctx *sql.Context) (sql.RowIter, error) {
+	alterable, err := getAlterableTable(m.db, m.table)
+	if err != nil {
+		return nil, err
+	}
+
+	return sql.RowsToRowIter(), alterable.ModifyColumn(ctx, m.name, m.column, m.order)
+}
+
+// Schema implements the Node interface.
+func (m *ModifyColumn) Schema() sql.Schema { return nil }
+
+// Children implements the Node interface.
+func (m *ModifyColumn) Children() []sql.Node { return nil }
+
+// WithChildren implements the Node interface.
+func (m *ModifyColumn) WithChildren(children ...sql.Node) (sql.Node, error) {
+	if len(children) != 0 {
+		return nil, sql.ErrInvalidChildrenNumber.New(m, len(children), 0)
+	}
+	return m, nil
+}
+
+func (m *ModifyColumn) String() string {
+	return fmt.Sprintf(
+		"Modify column %s of %s to %s %s%s",
+		m.name,
+		m.table,
+		m.column.Name,
+		m.column.Type,
+		columnOrderString(m.order),
+	)
+}
+
+// RenameColumn is a node describing changing the name of a column of a table.
+type RenameColumn struct {
+	db      sql.Database
+	table   string
+	oldName string
+	newName string
+}
+
+// NewRenameColumn creates a new RenameColumn node.
+func NewRenameColumn(db sql.Database, table, oldName, newName string) *RenameColumn {
+	return &RenameColumn{
+		db:      db,
+		table:   table,
+		oldName: oldName,
+		newName: newName,
+	}
+}
+
+var _ sql.Databaser = (*RenameColumn)(nil)
+
+// Database implements the sql.Databaser interface.
+func (r *RenameColumn) Database() sql.Database {
+	return r.db
+}
+
+// WithDatabase implements the sql.Databaser interface.
+func (r *RenameColumn) WithDatabase(db sql.Database) (sql.Node, error) {
+	nr := *r
+	nr.db = db
+	return &nr, nil
+}
+
+// Resolved implements the Resolvable interface.
+func (r *RenameColumn) Resolved() bool {
+	_, ok := r.db.(sql.UnresolvedDatabase)
+	return !ok
+}
+
+// RowIter implements the Node interface.
+func (r *RenameColumn) RowIter(ctx *sql.Context) (sql.RowIter, error) {
+	alterable, err := getAlterableTable(r.db, r.table)
+	if err != nil {
+		return nil, err
+	}
+
+	return sql.RowsToRowIter(), alterable.RenameColumn(ctx, r.oldName, r.newName)
+}
+
+// Schema implements the Node interface.
+func (r *RenameColumn) Schema() sql.Schema { return nil }
+
+// Children implements the Node interface.
+func (r *RenameColumn) Children() []sql.Node { return nil }
+
+// WithChildren implements the Node interface.
+func (r *RenameColumn) WithChildren(children ...sql.Node) (sql.Node, error) {
+	if len(children) != 0 {
+		return nil, sql.ErrInvalidChildrenNumber.New(r, len(children), 0)
+	}
+	return r, nil
+}
+
+func (r *RenameColumn) String() string {
+	return fmt.Sprintf("Rename column %s of %s to %s", r.oldName, r.table, r.newName)
+}
+
+func getAlterableTable(db sql.Database, name string) (sql.TableAlterer, error) {
+	table, ok := db.Tables()[name]
+	if !ok {
+		return nil, sql.ErrTableNotFound.New(name)
+	}
+
+	alterable, ok := table.(sql.TableAlterer)
+	if !ok {
+		return nil, ErrAlterTableNotSupported.New(name)
+	}
+
+	return alterable, nil
+}
+
+func columnOrderString(order *sql.ColumnOrder) string {
+	switch {
+	case order == nil:
+		return ""
+	case order.First:
+		return " first"
+	default:
+		return fmt.Sprintf(" after %s", order.AfterColumn)
+	}
+}
@@ -0,0 +1,141 @@
+package plan
+
+import (
+	"strings"
+
+	"github.com/src-d/go-mysql-server/sql"
+)
+
+// AnalyzeTable computes the statistics of tables, which are used to estimate
+// the cost of the queries that read them. Like in MySQL, it returns a row
+// with the result for each table.
+type AnalyzeTable struct {
+	// CurrentDatabase is the database of the tables whose name is not
+	// qualified with one.
+	CurrentDatabase string
+	Tables          []sql.Node
+
+	databases []string
+	names     []string
+}
+
+var analyzeTableSchema = sql.Schema{
+	{Name: "Table", Type: sql.Text},
+	{Name: "Op", Type: sql.Text},
+	{Name: "Msg_type", Type: sql.Text},
+	{Name: "Msg_text", Type: sql.Text},
+}
+
+// NewAnalyzeTable creates a new AnalyzeTable node for the given tables.
+func NewAnalyzeTable(tables ...*UnresolvedTable) *AnalyzeTable {
+	var a = &AnalyzeTable{
+		Tables:    make([]sql.Node, len(tables)),
+		databases: make([]string, len(tables)),
+		names:     make([]string, len(tables)),
+	}
+
+	for i, t := range tables {
+		a.Tables[i] = t
+		a.databases[i] = t.Database
+		a.names[i] = t.Name()
+	}
+
+	return a
+}
+
+// Children implements the sql.Node interface.
+func (a *AnalyzeTable) Children() []sql.Node {
+	return a.Tables
+}
+
+// Resolved implements the sql.Node interface.
+func (a *AnalyzeTable) Resolved() bool {
+	for _, t := range a.Tables {
+		if !t.Resolved() {
+			return false
+		}
+	}
+	return true
+}
+
+// Schema implements the sql.Node interface.
+func (a *AnalyzeTable) Schema() sql.Schema {
+	return analyzeTableSchema
+}
+
+// RowIter implements the sql.Node interface.
+func (a *AnalyzeTable) RowIter(ctx *sql.Context) (sql.RowIter, error) {
+	span, ctx := ctx.Span("plan.AnalyzeTable")
+	defer span.Finish()
+
+	var rows = make([]sql.Row, len(a.Tables))
+	for i, t := range a.Tables {
+		msgType, msgText := "status", "OK"
+
+		table, ok := getAnalyzableTable(t)
+		if !ok {
+			msgType, msgText = "note", "The storage engine for the table doesn't support analyze"
+		} else if err := table.Analyze(ctx); err != nil {
+			msgType, msgText = "Error", err.Error()
+		}
+
+		rows[i] = sql.Row{a.tableName(i), "analyze", msgType, msgText}
+	}
+
+	return sql.RowsToRowIter(rows...), nil
+}
+
+// tableName returns the name of the i-th table qualified with its database,
+// if it's known.
+func (a *AnalyzeTable) tableName(i int) string {
+	db := a.databases[i]
+	if db == "" {
+		db = a.CurrentDatabase
+	}
+
+	if db == "" {
+		return a.names[i]
+	}
+	return db + "." + a.names[i]
+}
+
+func (a *AnalyzeTable) String() string {
+	var names = make([]string, len(a.Tables))
+	for i := range names {
+		names[i] = a.tableName(i)
+	}
+
+	p := sql.NewTreePrinter()
+	_ = p.WriteNode("AnalyzeTable(%s)", strings.Join(names, ", "))
+	return p.String()
+}
+
+// WithChildren implements the Node interface.
+func (a *AnalyzeTable) WithChildren(children ...sql.Node) (sql.Node, error) {
+	if len(children) != len(a.Tables) {
+		return nil, sql.ErrInvalidChildrenNumber.New(a, len(children), len(a.Tables))
+	}
+
+	na := *a
+	na.Tables = children
+	return &na, nil
+}
+
+func getAnalyzableTable(node sql.Node) (sql.AnalyzableTable, bool) {
+	t, ok := node.(*ResolvedTable)
+	if !ok {
+		return nil, false
+	}
+
+	table := t.Table
+	for {
+		switch tt := table.(type) {
+		case sql.AnalyzableTable:
+			return tt, true
+		case sql.TableWrapper:
+			table = tt.Underlying()
+		default:
+			return nil, false
+		}
+	}
+}
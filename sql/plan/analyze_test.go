@@ -0,0 +1,48 @@
+package plan
+
+import (
+	"testing"
+
+	"github.com/src-d/go-mysql-server/memory"
+	"github.com/src-d/go-mysql-server/sql"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAnalyzeTable(t *testing.T) {
+	require := require.New(t)
+	ctx := sql.NewEmptyContext()
+
+	table := memory.NewTable("foo", sql.Schema{
+		{Name: "i", Type: sql.Int64, Source: "foo"},
+	})
+	require.NoError(table.Insert(ctx, sql.NewRow(int64(1))))
+	require.NoError(table.Insert(ctx, sql.NewRow(int64(2))))
+
+	info := sql.NewInformationSchemaDatabase(sql.NewCatalog())
+
+	node := NewAnalyzeTable(
+		NewUnresolvedTable("foo", ""),
+		NewUnresolvedTable("files", "information_schema"),
+	)
+	node.CurrentDatabase = "db"
+	require.False(node.Resolved())
+
+	resolved, err := node.WithChildren(
+		NewResolvedTable(table),
+		NewResolvedTable(info.Tables()["files"]),
+	)
+	require.NoError(err)
+	require.True(resolved.Resolved())
+	require.Equal("AnalyzeTable(db.foo, information_schema.files)\n", resolved.String())
+
+	rows, err := sql.NodeToRows(ctx, resolved)
+	require.NoError(err)
+	require.Equal([]sql.Row{
+		{"db.foo", "analyze", "status", "OK"},
+		{"information_schema.files", "analyze", "note", "The storage engine for the table doesn't support analyze"},
+	}, rows)
+
+	stats, err := table.Statistics(ctx)
+	require.NoError(err)
+	require.Equal(uint64(2), stats.RowCount)
+}
@@ -0,0 +1,168 @@
+package plan
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/src-d/go-mysql-server/sql"
+	"github.com/src-d/go-mysql-server/sql/expression"
+)
+
+// ApplyBindings replaces the placeholders of the given node, including the
+// ones in subqueries, with the expression bound to their name. Placeholders
+// without a binding are left untouched.
+func ApplyBindings(node sql.Node, bindings map[string]sql.Expression) (sql.Node, error) {
+	children := node.Children()
+	if len(children) > 0 {
+		newChildren := make([]sql.Node, len(children))
+		for i, c := range children {
+			c, err := ApplyBindings(c, bindings)
+			if err != nil {
+				return nil, err
+			}
+			newChildren[i] = c
+		}
+
+		var err error
+		node, err = node.WithChildren(newChildren...)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return TransformExpressions(node, func(e sql.Expression) (sql.Expression, error) {
+		switch e := e.(type) {
+		case *expression.BindVar:
+			if value, ok := bindings[e.Name]; ok {
+				return value, nil
+			}
+			return e, nil
+		case *expression.Subquery:
+			query, err := ApplyBindings(e.Query, bindings)
+			if err != nil {
+				return nil, err
+			}
+			return e.WithQuery(query), nil
+		default:
+			return e, nil
+		}
+	})
+}
+
+// BindVars returns the names of all the placeholders of the given node in
+// the order they appear in the query.
+func BindVars(node sql.Node) []string {
+	var seen = make(map[string]bool)
+	var names []string
+	inspectExpressionsWithSubqueries(node, func(e sql.Expression) {
+		if bv, ok := e.(*expression.BindVar); ok && !seen[bv.Name] {
+			seen[bv.Name] = true
+			names = append(names, bv.Name)
+		}
+	})
+
+	// Placeholders are named v1, v2, ... in the order they are written.
+	sort.SliceStable(names, func(i, j int) bool {
+		return bindVarPosition(names[i]) < bindVarPosition(names[j])
+	})
+	return names
+}
+
+func bindVarPosition(name string) int {
+	n, err := strconv.Atoi(strings.TrimPrefix(name, "v"))
+	if err != nil {
+		return -1
+	}
+	return n
+}
+
+// BindVarTypes returns the types of the placeholders of the given analyzed
+// node, which are inferred from the expressions they are compared or
+// operated with and the columns they are inserted into. Placeholders whose
+// type can't be inferred are not included.
+func BindVarTypes(node sql.Node) map[string]sql.Type {
+	var types = make(map[string]sql.Type)
+	setType := func(e sql.Expression, typ sql.Type) {
+		if bv, ok := e.(*expression.BindVar); ok {
+			if _, ok := types[bv.Name]; !ok {
+				types[bv.Name] = typ
+			}
+		}
+	}
+
+	infer := func(e, other sql.Expression) {
+		if _, ok := other.(*expression.BindVar); !ok && other != nil {
+			setType(e, other.Type())
+		}
+	}
+
+	inspectExpressionsWithSubqueries(node, func(e sql.Expression) {
+		switch e := e.(type) {
+		case expression.Comparer:
+			infer(e.Left(), e.Right())
+			infer(e.Right(), e.Left())
+			if tuple, ok := e.Right().(expression.Tuple); ok {
+				for _, el := range tuple {
+					infer(el, e.Left())
+				}
+			}
+		case *expression.Arithmetic:
+			infer(e.Left, e.Right)
+			infer(e.Right, e.Left)
+		case *expression.Between:
+			infer(e.Val, e.Lower)
+			infer(e.Val, e.Upper)
+			infer(e.Lower, e.Val)
+			infer(e.Upper, e.Val)
+		}
+	})
+
+	Inspect(node, func(node sql.Node) bool {
+		insert, ok := node.(*InsertInto)
+		if !ok {
+			return true
+		}
+
+		values, ok := insert.Right.(*Values)
+		if !ok {
+			return true
+		}
+
+		schema := insert.Left.Schema()
+		columns := insert.Columns
+		if len(columns) == 0 {
+			columns = make([]string, len(schema))
+			for i, col := range schema {
+				columns[i] = col.Name
+			}
+		}
+
+		for _, tuple := range values.ExpressionTuples {
+			for i, e := range tuple {
+				if i >= len(columns) {
+					break
+				}
+
+				for _, col := range schema {
+					if strings.EqualFold(col.Name, columns[i]) {
+						setType(e, col.Type)
+					}
+				}
+			}
+		}
+		return true
+	})
+
+	return types
+}
+
+func inspectExpressionsWithSubqueries(node sql.Node, f func(sql.Expression)) {
+	InspectExpressions(node, func(e sql.Expression) bool {
+		if sq, ok := e.(*expression.Subquery); ok {
+			inspectExpressionsWithSubqueries(sq.Query, f)
+		}
+		f(e)
+		return true
+	})
+}
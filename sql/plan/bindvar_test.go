@@ -0,0 +1,88 @@
+package plan
+
+import (
+	"testing"
+
+	"github.com/src-d/go-mysql-server/memory"
+	"github.com/src-d/go-mysql-server/sql"
+	"github.com/src-d/go-mysql-server/sql/expression"
+	"github.com/stretchr/testify/require"
+)
+
+func TestApplyBindings(t *testing.T) {
+	require := require.New(t)
+
+	table := memory.NewTable("t", sql.Schema{
+		{Name: "a", Type: sql.Int64, Source: "t"},
+		{Name: "b", Type: sql.Text, Source: "t"},
+	})
+
+	node := NewFilter(
+		expression.NewAnd(
+			expression.NewEquals(
+				expression.NewGetFieldWithTable(0, sql.Int64, "t", "a", false),
+				expression.NewBindVar("v2"),
+			),
+			expression.NewIn(
+				expression.NewGetFieldWithTable(1, sql.Text, "t", "b", false),
+				expression.NewSubquery(NewProject(
+					[]sql.Expression{expression.NewBindVar("v1")},
+					NewResolvedTable(table),
+				)),
+			),
+		),
+		NewResolvedTable(table),
+	)
+
+	require.Equal([]string{"v1", "v2"}, BindVars(node))
+	require.Equal(map[string]sql.Type{"v2": sql.Int64}, BindVarTypes(node))
+
+	result, err := ApplyBindings(node, map[string]sql.Expression{
+		"v1": expression.NewLiteral("foo", sql.Text),
+		"v2": expression.NewLiteral(int64(1), sql.Int64),
+	})
+	require.NoError(err)
+	require.Empty(BindVars(result))
+
+	expected := NewFilter(
+		expression.NewAnd(
+			expression.NewEquals(
+				expression.NewGetFieldWithTable(0, sql.Int64, "t", "a", false),
+				expression.NewLiteral(int64(1), sql.Int64),
+			),
+			expression.NewIn(
+				expression.NewGetFieldWithTable(1, sql.Text, "t", "b", false),
+				expression.NewSubquery(NewProject(
+					[]sql.Expression{expression.NewLiteral("foo", sql.Text)},
+					NewResolvedTable(table),
+				)),
+			),
+		),
+		NewResolvedTable(table),
+	)
+	require.Equal(expected, result)
+}
+
+func TestBindVarTypesInsert(t *testing.T) {
+	require := require.New(t)
+
+	table := memory.NewTable("t", sql.Schema{
+		{Name: "a", Type: sql.Int64, Source: "t"},
+		{Name: "b", Type: sql.Text, Source: "t"},
+	})
+
+	node := NewInsertInto(
+		NewResolvedTable(table),
+		NewValues([][]sql.Expression{{
+			expression.NewBindVar("v1"),
+			expression.NewBindVar("v2"),
+		}}),
+		false,
+		[]string{"b", "a"},
+	)
+
+	require.Equal(map[string]sql.Type{
+		"v1": sql.Text,
+		"v2": sql.Int64,
+	}, BindVarTypes(node))
+}
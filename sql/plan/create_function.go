@@ -0,0 +1,143 @@
+package plan
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/src-d/go-mysql-server/sql"
+	"github.com/src-d/go-mysql-server/sql/expression/function/udf"
+)
+
+// CreateFunction is a node that creates a function whose body is a script
+// and stores it in the catalog, as in CREATE FUNCTION.
+type CreateFunction struct {
+	Function *sql.StoredFunction
+	Catalog  *sql.Catalog
+}
+
+// NewCreateFunction creates a new CreateFunction node.
+func NewCreateFunction(fn *sql.StoredFunction) *CreateFunction {
+	return &CreateFunction{Function: fn}
+}
+
+// Resolved implements the Resolvable interface.
+func (c *CreateFunction) Resolved() bool { return true }
+
+// Schema implements the Node interface.
+func (c *CreateFunction) Schema() sql.Schema { return nil }
+
+// Children implements the Node interface.
+func (c *CreateFunction) Children() []sql.Node { return nil }
+
+// RowIter implements the Node interface.
+func (c *CreateFunction) RowIter(ctx *sql.Context) (sql.RowIter, error) {
+	name := strings.ToLower(c.Function.Name)
+	if _, err := c.Catalog.StoredFunction(name); err == nil {
+		return nil, sql.ErrStoredFunctionAlreadyExists.New(name)
+	}
+
+	// Functions can't have the name of built-in functions or functions
+	// registered by other means.
+	if _, err := c.Catalog.Function(name); err == nil {
+		return nil, sql.ErrFunctionAlreadyRegistered.New(name)
+	}
+
+	fn := *c.Function
+	fn.Name = name
+	if fn.Created.IsZero() {
+		fn.Created = time.Now()
+	}
+	if err := c.Catalog.RegisterStoredFunction(&fn); err != nil {
+		return nil, err
+	}
+
+	scriptUDF := udf.NewStoredUDF(&fn)
+	if err := c.Catalog.Register(scriptUDF.AsFunction()); err != nil {
+		_ = c.Catalog.DeleteStoredFunction(name)
+		return nil, err
+	}
+
+	return sql.RowsToRowIter(), nil
+}
+
+// WithChildren implements the Node interface.
+func (c *CreateFunction) WithChildren(children ...sql.Node) (sql.Node, error) {
+	if len(children) != 0 {
+		return nil, sql.ErrInvalidChildrenNumber.New(c, len(children), 0)
+	}
+	return c, nil
+}
+
+func (c *CreateFunction) String() string {
+	return fmt.Sprintf(
+		"CreateFunction(%s(%s), %s)",
+		c.Function.Name,
+		strings.Join(c.Function.Params, ", "),
+		c.Function.Language,
+	)
+}
+
+// DropFunction is a node that removes functions created with CREATE
+// FUNCTION from the catalog.
+type DropFunction struct {
+	names    []string
+	ifExists bool
+	Catalog  *sql.Catalog
+}
+
+// NewDropFunction creates a new DropFunction node.
+func NewDropFunction(ifExists bool, names ...string) *DropFunction {
+	return &DropFunction{names: names, ifExists: ifExists}
+}
+
+// Resolved implements the Resolvable interface.
+func (d *DropFunction) Resolved() bool { return true }
+
+// Schema implements the Node interface.
+func (d *DropFunction) Schema() sql.Schema { return nil }
+
+// Children implements the Node interface.
+func (d *DropFunction) Children() []sql.Node { return nil }
+
+// RowIter implements the Node interface.
+func (d *DropFunction) RowIter(ctx *sql.Context) (sql.RowIter, error) {
+	// Check all the functions exist before dropping any of them.
+	if !d.ifExists {
+		for _, name := range d.names {
+			if _, err := d.Catalog.StoredFunction(name); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	for _, name := range d.names {
+		err := d.Catalog.DeleteStoredFunction(name)
+		if err != nil {
+			if sql.ErrStoredFunctionNotFound.Is(err) {
+				continue
+			}
+			return nil, err
+		}
+
+		d.Catalog.Unregister(strings.ToLower(name))
+	}
+
+	return sql.RowsToRowIter(), nil
+}
+
+// WithChildren implements the Node interface.
+func (d *DropFunction) WithChildren(children ...sql.Node) (sql.Node, error) {
+	if len(children) != 0 {
+		return nil, sql.ErrInvalidChildrenNumber.New(d, len(children), 0)
+	}
+	return d, nil
+}
+
+func (d *DropFunction) String() string {
+	ifExists := ""
+	if d.ifExists {
+		ifExists = "if exists "
+	}
+	return fmt.Sprintf("DropFunction(%s%s)", ifExists, strings.Join(d.names, ", "))
+}
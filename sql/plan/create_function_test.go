@@ -0,0 +1,136 @@
+package plan
+
+import (
+	"testing"
+	"time"
+
+	"github.com/src-d/go-mysql-server/sql"
+	"github.com/src-d/go-mysql-server/sql/expression"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCreateFunction(t *testing.T) {
+	require := require.New(t)
+
+	catalog := sql.NewCatalog()
+	catalog.MustRegister(sql.Function0{Name: "builtin", Fn: nil})
+	ctx := sql.NewEmptyContext()
+
+	create := func(name string) error {
+		cf := NewCreateFunction(&sql.StoredFunction{
+			Name:       name,
+			Params:     []string{"price", "tier"},
+			ParamTypes: []sql.Type{sql.Float64, nil},
+			ReturnType: sql.Float64,
+			Language:   "js",
+			Body:       "tier == 1 ? price * 0.9 : price",
+		})
+		cf.Catalog = catalog
+		_, err := cf.RowIter(ctx)
+		return err
+	}
+
+	require.NoError(create("Discount"))
+
+	stored, err := catalog.StoredFunction("discount")
+	require.NoError(err)
+	require.Equal("discount", stored.Name)
+	require.False(stored.Created.IsZero())
+
+	fn, err := catalog.Function("discount")
+	require.NoError(err)
+
+	e, err := fn.Call(
+		expression.NewLiteral("10", sql.Text),
+		expression.NewLiteral(int64(1), sql.Int64),
+	)
+	require.NoError(err)
+
+	v, err := e.Eval(ctx, nil)
+	require.NoError(err)
+	require.Equal(float64(9), v)
+
+	_, err = fn.Call(expression.NewLiteral(int64(1), sql.Int64))
+	require.True(sql.ErrInvalidArgumentNumber.Is(err))
+
+	err = create("discount")
+	require.True(sql.ErrStoredFunctionAlreadyExists.Is(err))
+
+	err = create("builtin")
+	require.True(sql.ErrFunctionAlreadyRegistered.Is(err))
+	_, err = catalog.StoredFunction("builtin")
+	require.True(sql.ErrStoredFunctionNotFound.Is(err))
+}
+
+func TestDropFunction(t *testing.T) {
+	require := require.New(t)
+
+	catalog := sql.NewCatalog()
+	catalog.MustRegister(sql.Function0{Name: "builtin", Fn: nil})
+	ctx := sql.NewEmptyContext()
+
+	for _, name := range []string{"f", "g"} {
+		cf := NewCreateFunction(&sql.StoredFunction{Name: name, Language: "js", Body: "1"})
+		cf.Catalog = catalog
+		_, err := cf.RowIter(ctx)
+		require.NoError(err)
+	}
+
+	drop := func(ifExists bool, names ...string) error {
+		df := NewDropFunction(ifExists, names...)
+		df.Catalog = catalog
+		_, err := df.RowIter(ctx)
+		return err
+	}
+
+	err := drop(false, "f", "builtin")
+	require.True(sql.ErrStoredFunctionNotFound.Is(err))
+	_, err = catalog.Function("f")
+	require.NoError(err)
+
+	require.NoError(drop(true, "F", "builtin"))
+	_, err = catalog.StoredFunction("f")
+	require.True(sql.ErrStoredFunctionNotFound.Is(err))
+	_, err = catalog.Function("f")
+	require.True(sql.ErrFunctionNotFound.Is(err))
+
+	// Only functions created with CREATE FUNCTION can be dropped.
+	_, err = catalog.Function("builtin")
+	require.NoError(err)
+
+	require.NoError(drop(false, "g"))
+	require.Len(catalog.StoredFunctions(), 0)
+}
+
+func TestShowFunctionStatus(t *testing.T) {
+	require := require.New(t)
+
+	catalog := sql.NewCatalog()
+	created := time.Date(2019, time.March, 1, 10, 0, 0, 0, time.UTC)
+	for _, name := range []string{"foo", "bar", "baz"} {
+		require.NoError(catalog.RegisterStoredFunction(&sql.StoredFunction{
+			Name:    name,
+			Created: created,
+		}))
+	}
+
+	show := func(pattern string) []sql.Row {
+		s := NewShowFunctionStatus(pattern)
+		s.Catalog = catalog
+		iter, err := s.RowIter(sql.NewEmptyContext())
+		require.NoError(err)
+		rows, err := sql.RowIterToRows(iter)
+		require.NoError(err)
+		return rows
+	}
+
+	row := func(name string) sql.Row {
+		return sql.NewRow(
+			"", name, "FUNCTION", "", created, created, "DEFINER", "",
+			defaultCharacterSet, defaultCollation, defaultCollation,
+		)
+	}
+
+	require.Equal([]sql.Row{row("bar"), row("baz"), row("foo")}, show(""))
+	require.Equal([]sql.Row{row("bar"), row("baz")}, show("ba%"))
+}
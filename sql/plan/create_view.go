@@ -0,0 +1,203 @@
+package plan
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/src-d/go-mysql-server/sql"
+)
+
+// CreateView is a node that stores a query in the catalog as a view. Its
+// child is the definition of the view as a subquery, which is only resolved
+// to make sure the view is valid. The catalog keeps the definition as it was
+// parsed, so the view is resolved again every time it is used.
+type CreateView struct {
+	UnaryNode
+	db      sql.Database
+	name    string
+	Columns []string
+	// Definition is the query of the view, as it is returned by the parser.
+	Definition sql.Node
+	// TextDefinition is the query of the view as it was written.
+	TextDefinition string
+	// IsReplace is true if an existing view with the same name must be
+	// replaced, as in CREATE OR REPLACE VIEW.
+	IsReplace bool
+	Catalog   *sql.Catalog
+}
+
+// NewCreateView creates a new CreateView node.
+func NewCreateView(
+	db sql.Database,
+	name string,
+	columns []string,
+	definition sql.Node,
+	textDefinition string,
+	isReplace bool,
+) *CreateView {
+	return &CreateView{
+		UnaryNode:      UnaryNode{Child: NewSubqueryAlias(name, definition).WithColumns(columns)},
+		db:             db,
+		name:           name,
+		Columns:        columns,
+		Definition:     definition,
+		TextDefinition: textDefinition,
+		IsReplace:      isReplace,
+	}
+}
+
+var _ sql.Databaser = (*CreateView)(nil)
+
+// Name returns the name of the view.
+func (c *CreateView) Name() string { return c.name }
+
+// Database implements the sql.Databaser interface.
+func (c *CreateView) Database() sql.Database {
+	return c.db
+}
+
+// WithDatabase implements the sql.Databaser interface.
+func (c *CreateView) WithDatabase(db sql.Database) (sql.Node, error) {
+	nc := *c
+	nc.db = db
+	return &nc, nil
+}
+
+// WithDefinition returns a copy of the node with the given definition.
+func (c *CreateView) WithDefinition(definition sql.Node) *CreateView {
+	nc := *c
+	nc.Definition = definition
+	nc.Child = NewSubqueryAlias(c.name, definition).WithColumns(c.Columns)
+	return &nc
+}
+
+// Resolved implements the Resolvable interface.
+func (c *CreateView) Resolved() bool {
+	_, ok := c.db.(sql.UnresolvedDatabase)
+	return !ok && c.Child.Resolved()
+}
+
+// Schema implements the Node interface.
+func (c *CreateView) Schema() sql.Schema { return nil }
+
+// RowIter implements the Node interface.
+func (c *CreateView) RowIter(ctx *sql.Context) (sql.RowIter, error) {
+	if _, ok := c.db.Tables()[c.name]; ok {
+		return nil, sql.ErrTableAlreadyExists.New(c.name)
+	}
+
+	view := sql.NewView(c.name, c.Columns, c.Definition, c.TextDefinition)
+	if c.IsReplace {
+		c.Catalog.ReplaceView(c.db.Name(), view)
+		return sql.RowsToRowIter(), nil
+	}
+
+	return sql.RowsToRowIter(), c.Catalog.RegisterView(c.db.Name(), view)
+}
+
+// WithChildren implements the Node interface.
+func (c *CreateView) WithChildren(children ...sql.Node) (sql.Node, error) {
+	if len(children) != 1 {
+		return nil, sql.ErrInvalidChildrenNumber.New(c, len(children), 1)
+	}
+
+	nc := *c
+	nc.Child = children[0]
+	return &nc, nil
+}
+
+func (c *CreateView) String() string {
+	name := c.name
+	if len(c.Columns) > 0 {
+		name = fmt.Sprintf("%s(%s)", name, strings.Join(c.Columns, ", "))
+	}
+
+	pr := sql.NewTreePrinter()
+	if c.IsReplace {
+		_ = pr.WriteNode("CreateView(%s, replace)", name)
+	} else {
+		_ = pr.WriteNode("CreateView(%s)", name)
+	}
+	_ = pr.WriteChildren(c.Child.String())
+	return pr.String()
+}
+
+// DropView is a node that removes one or more views from the catalog.
+type DropView struct {
+	db       sql.Database
+	names    []string
+	ifExists bool
+	Catalog  *sql.Catalog
+}
+
+// NewDropView creates a new DropView node.
+func NewDropView(db sql.Database, ifExists bool, names ...string) *DropView {
+	return &DropView{
+		db:       db,
+		names:    names,
+		ifExists: ifExists,
+	}
+}
+
+var _ sql.Databaser = (*DropView)(nil)
+
+// Database implements the sql.Databaser interface.
+func (d *DropView) Database() sql.Database {
+	return d.db
+}
+
+// WithDatabase implements the sql.Databaser interface.
+func (d *DropView) WithDatabase(db sql.Database) (sql.Node, error) {
+	nc := *d
+	nc.db = db
+	return &nc, nil
+}
+
+// Resolved implements the Resolvable interface.
+func (d *DropView) Resolved() bool {
+	_, ok := d.db.(sql.UnresolvedDatabase)
+	return !ok
+}
+
+// RowIter implements the Node interface.
+func (d *DropView) RowIter(ctx *sql.Context) (sql.RowIter, error) {
+	// Check all the views exist before dropping any of them.
+	if !d.ifExists {
+		for _, name := range d.names {
+			if _, err := d.Catalog.View(d.db.Name(), name); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	for _, name := range d.names {
+		err := d.Catalog.DeleteView(d.db.Name(), name)
+		if err != nil && !sql.ErrViewNotFound.Is(err) {
+			return nil, err
+		}
+	}
+
+	return sql.RowsToRowIter(), nil
+}
+
+// Schema implements the Node interface.
+func (d *DropView) Schema() sql.Schema { return nil }
+
+// Children implements the Node interface.
+func (d *DropView) Children() []sql.Node { return nil }
+
+// WithChildren implements the Node interface.
+func (d *DropView) WithChildren(children ...sql.Node) (sql.Node, error) {
+	if len(children) != 0 {
+		return nil, sql.ErrInvalidChildrenNumber.New(d, len(children), 0)
+	}
+	return d, nil
+}
+
+func (d *DropView) String() string {
+	ifExists := ""
+	if d.ifExists {
+		ifExists = "if exists "
+	}
+	return fmt.Sprintf("Drop view %s%s", ifExists, d.names)
+}
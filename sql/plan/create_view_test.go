@@ -0,0 +1,84 @@
+package plan
+
+import (
+	"testing"
+
+	"github.com/src-d/go-mysql-server/memory"
+	"github.com/src-d/go-mysql-server/sql"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCreateView(t *testing.T) {
+	require := require.New(t)
+
+	db := memory.NewDatabase("db")
+	db.AddTable("t", memory.NewTable("t", nil))
+	catalog := sql.NewCatalog()
+	catalog.AddDatabase(db)
+
+	definition := NewUnresolvedTable("t", "db")
+	ctx := sql.NewEmptyContext()
+
+	create := func(name string, isReplace bool) error {
+		cv := NewCreateView(db, name, []string{"a"}, definition, "SELECT * FROM t", isReplace)
+		cv.Catalog = catalog
+		_, err := cv.RowIter(ctx)
+		return err
+	}
+
+	require.NoError(create("v", false))
+
+	view, err := catalog.View("db", "v")
+	require.NoError(err)
+	require.Equal(sql.NewView("v", []string{"a"}, definition, "SELECT * FROM t"), view)
+
+	err = create("v", false)
+	require.True(sql.ErrViewAlreadyExists.Is(err))
+	require.NoError(create("v", true))
+
+	err = create("t", false)
+	require.True(sql.ErrTableAlreadyExists.Is(err))
+
+	sc := NewShowCreateView(db, "v")
+	sc.Catalog = catalog
+	iter, err := sc.RowIter(ctx)
+	require.NoError(err)
+	rows, err := sql.RowIterToRows(iter)
+	require.NoError(err)
+	require.Equal([]sql.Row{{
+		"v",
+		"CREATE VIEW `v` (`a`) AS SELECT * FROM t",
+		defaultCharacterSet,
+		defaultCollation,
+	}}, rows)
+}
+
+func TestDropView(t *testing.T) {
+	require := require.New(t)
+
+	db := memory.NewDatabase("db")
+	catalog := sql.NewCatalog()
+	catalog.AddDatabase(db)
+	require.NoError(catalog.RegisterView("db", sql.NewView("v1", nil, nil, "")))
+	require.NoError(catalog.RegisterView("db", sql.NewView("v2", nil, nil, "")))
+
+	ctx := sql.NewEmptyContext()
+	drop := func(ifExists bool, names ...string) error {
+		dv := NewDropView(db, ifExists, names...)
+		dv.Catalog = catalog
+		_, err := dv.RowIter(ctx)
+		return err
+	}
+
+	err := drop(false, "v1", "v3")
+	require.True(sql.ErrViewNotFound.Is(err))
+	_, err = catalog.View("db", "v1")
+	require.NoError(err)
+
+	require.NoError(drop(true, "v1", "v3"))
+	_, err = catalog.View("db", "v1")
+	require.True(sql.ErrViewNotFound.Is(err))
+
+	require.NoError(drop(false, "v2"))
+	require.Len(catalog.ViewsInDatabase("db"), 0)
+}
@@ -3,6 +3,7 @@ package plan
 import (
 	"fmt"
 	"github.com/src-d/go-mysql-server/sql"
+	"github.com/src-d/go-mysql-server/sql/expression/function/udf"
 	"gopkg.in/src-d/go-errors.v1"
 )
 
@@ -15,6 +16,7 @@ type CreateTable struct {
 	db     sql.Database
 	name   string
 	schema sql.Schema
+	script udf.ScriptInstance
 }
 
 // NewCreateTable creates a new CreateTable node
@@ -30,6 +32,14 @@ func NewCreateTable(db sql.Database, name string, schema sql.Schema) *CreateTabl
 	}
 }
 
+// NewCreateScriptTable creates a new CreateTable node for a table whose rows
+// are produced by the given script, as in `CREATE TABLE ... ENGINE=SCRIPT`.
+func NewCreateScriptTable(db sql.Database, name string, schema sql.Schema, script udf.ScriptInstance) *CreateTable {
+	c := NewCreateTable(db, name, schema)
+	c.script = script
+	return c
+}
+
 var _ sql.Databaser = (*CreateTable)(nil)
 
 // Database implements the sql.Databaser interface.
@@ -52,6 +62,10 @@ func (c *CreateTable) Resolved() bool {
 
 // RowIter implements the Node interface.
 func (c *CreateTable) RowIter(s *sql.Context) (sql.RowIter, error) {
+	if c.script != nil {
+		return c.createScriptTable()
+	}
+
 	creatable, ok := c.db.(sql.TableCreator)
 	if ok {
 		return sql.RowsToRowIter(), creatable.CreateTable(s, c.name, c.schema)
@@ -60,6 +74,20 @@ func (c *CreateTable) RowIter(s *sql.Context) (sql.RowIter, error) {
 	return nil, ErrCreateTableNotSupported.New(c.db.Name())
 }
 
+func (c *CreateTable) createScriptTable() (sql.RowIter, error) {
+	adder, ok := c.db.(sql.TableAdder)
+	if !ok {
+		return nil, ErrCreateTableNotSupported.New(c.db.Name())
+	}
+
+	if _, ok := c.db.Tables()[c.name]; ok {
+		return nil, sql.ErrTableAlreadyExists.New(c.name)
+	}
+
+	adder.AddTable(c.name, udf.NewScriptTable(c.name, c.schema, c.script))
+	return sql.RowsToRowIter(), nil
+}
+
 // Schema implements the Node interface.
 func (c *CreateTable) Schema() sql.Schema { return nil }
 
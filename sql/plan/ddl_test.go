@@ -6,6 +6,7 @@ import (
 
 	"github.com/src-d/go-mysql-server/memory"
 	"github.com/src-d/go-mysql-server/sql"
+	"github.com/src-d/go-mysql-server/sql/expression/function/udf"
 	"github.com/stretchr/testify/require"
 )
 
@@ -36,6 +37,28 @@ func TestCreateTable(t *testing.T) {
 	}
 }
 
+func TestCreateScriptTable(t *testing.T) {
+	require := require.New(t)
+
+	db := memory.NewDatabase("test")
+	s := sql.Schema{{Name: "c1", Type: sql.Int64}}
+
+	c := NewCreateScriptTable(db, "testTable", s, udf.GetScriptInstance("js", "[1, 2]"))
+	rows, err := c.RowIter(sql.NewEmptyContext())
+	require.NoError(err)
+	r, err := rows.Next()
+	require.Equal(io.EOF, err)
+	require.Nil(r)
+
+	table, ok := db.Tables()["testTable"]
+	require.True(ok)
+	require.IsType(&udf.ScriptTable{}, table)
+	require.Equal("testTable", table.Schema()[0].Source)
+
+	_, err = c.RowIter(sql.NewEmptyContext())
+	require.True(sql.ErrTableAlreadyExists.Is(err))
+}
+
 func TestDropTable(t *testing.T) {
 	require := require.New(t)
 
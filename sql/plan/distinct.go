@@ -53,19 +53,25 @@ func (d Distinct) String() string {
 }
 
 // distinctIter keeps track of the hashes of all rows that have been emitted.
-// It does not emit any rows whose hashes have been seen already.
-// TODO: come up with a way to use less memory than keeping all hashes in memory.
-// Even though they are just 64-bit integers, this could be a problem in large
-// result sets.
+// It does not emit any rows whose hashes have been seen already. When there is
+// no memory for more hashes, the rows that have not been seen are stored on
+// disk in partitions, which are processed after all other rows.
 type distinctIter struct {
+	ctx       *sql.Context
 	childIter sql.RowIter
 	seen      sql.KeyValueCache
 	dispose   sql.DisposeFunc
+	childDone bool
+
+	level      int
+	partitions *spillPartitions
+	partition  *distinctIter
 }
 
 func newDistinctIter(ctx *sql.Context, child sql.RowIter) *distinctIter {
 	cache, dispose := ctx.Memory.NewHistoryCache()
 	return &distinctIter{
+		ctx:       ctx,
 		childIter: child,
 		seen:      cache,
 		dispose:   dispose,
@@ -73,11 +79,17 @@ func newDistinctIter(ctx *sql.Context, child sql.RowIter) *distinctIter {
 }
 
 func (di *distinctIter) Next() (sql.Row, error) {
+	if di.childDone {
+		return di.nextPartitionRow()
+	}
+
 	for {
 		row, err := di.childIter.Next()
 		if err != nil {
 			if err == io.EOF {
 				di.Dispose()
+				di.childDone = true
+				return di.nextPartitionRow()
 			}
 			return nil, err
 		}
@@ -87,22 +99,85 @@ func (di *distinctIter) Next() (sql.Row, error) {
 			continue
 		}
 
+		if di.partitions != nil {
+			if err := di.partitions.add(hash, row); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
 		if err := di.seen.Put(hash, struct{}{}); err != nil {
-			return nil, err
+			if !sql.ErrNoMemoryAvailable.Is(err) {
+				return nil, err
+			}
+
+			// The rows in the partitions can't be equal to any of the rows
+			// already returned, because no more hashes are added from now on.
+			di.partitions, err = newSpillPartitions(di.ctx, di.level)
+			if err != nil {
+				return nil, err
+			}
+
+			if err := di.partitions.add(hash, row); err != nil {
+				return nil, err
+			}
+			continue
 		}
 
 		return row, nil
 	}
 }
 
+func (di *distinctIter) nextPartitionRow() (sql.Row, error) {
+	if di.partitions == nil {
+		return nil, io.EOF
+	}
+
+	for {
+		if di.partition != nil {
+			row, err := di.partition.Next()
+			if err != io.EOF {
+				return row, err
+			}
+
+			if err := di.partition.Close(); err != nil {
+				return nil, err
+			}
+			di.partition = nil
+		}
+
+		iter, err := di.partitions.next()
+		if err != nil {
+			return nil, err
+		}
+
+		if iter == nil {
+			return nil, io.EOF
+		}
+
+		di.partition = newDistinctIter(di.ctx, iter)
+		di.partition.level = di.level + 1
+	}
+}
+
 func (di *distinctIter) Close() error {
 	di.Dispose()
+
+	if di.partition != nil {
+		_ = di.partition.Close()
+	}
+
+	if di.partitions != nil {
+		di.partitions.dispose()
+	}
+
 	return di.childIter.Close()
 }
 
 func (di *distinctIter) Dispose() {
 	if di.dispose != nil {
 		di.dispose()
+		di.dispose = nil
 	}
 }
 
@@ -2,6 +2,7 @@ package plan
 
 import (
 	"io"
+	"os"
 	"testing"
 
 	"github.com/src-d/go-mysql-server/memory"
@@ -169,3 +170,24 @@ func BenchmarkOrderedDistinct(b *testing.B) {
 		require.Equal(100, rows)
 	}
 }
+
+func TestDistinctSpill(t *testing.T) {
+	require := require.New(t)
+
+	table := spillTable(t, "t", 500)
+	d := NewDistinct(NewProject([]sql.Expression{
+		expression.NewGetField(0, sql.Int64, "i", true),
+	}, NewResolvedTable(table)))
+
+	expected, err := sql.NodeToRows(sql.NewEmptyContext(), d)
+	require.NoError(err)
+	require.Len(expected, 38)
+
+	ctx, dir := newSpillContext(t, 5)
+	defer os.RemoveAll(dir)
+
+	rows, err := sql.NodeToRows(ctx, d)
+	require.NoError(err)
+	require.ElementsMatch(expected, rows)
+	requireNoSpools(t, dir)
+}
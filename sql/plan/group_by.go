@@ -207,7 +207,11 @@ func (i *groupByIter) Next() (sql.Row, error) {
 			i.done = true
 			return row, err
 		}
-		i.eRows.rows = expandRow(row, i.aggregate)
+		i.eRows.rows, err = expandRow(i.ctx, row, i.aggregate)
+		if err != nil {
+			i.done = true
+			return nil, err
+		}
 		if len(i.eRows.rows) == 0 {
 			i.done = true
 			return nil, io.EOF
@@ -222,33 +226,103 @@ func (i *groupByIter) Next() (sql.Row, error) {
 	return row, nil;
 }
 
-func expandRow(row sql.Row, aggregate []sql.Expression) []sql.Row {
-	transposeColInd := -1
+// expandRow returns the rows of an aggregated row, which are more than one
+// if it has transposed aggregations. The results of several transposed
+// aggregations are combined element by element or by their cross product,
+// depending on the transpose mode of the session. Results that are not
+// lists are the same in all the rows.
+func expandRow(ctx *sql.Context, row sql.Row, aggregate []sql.Expression) ([]sql.Row, error) {
+	var transposed []int
 	for i, agg := range aggregate {
-		switch agg.(type) {
-		case *udf.Scriptable:
-			if agg.(*udf.Scriptable).Meta.UdfType.Transpose {
-				transposeColInd = i
-				break
+		if a, ok := agg.(*expression.Alias); ok {
+			agg = a.Child
+		}
+
+		if t, ok := agg.(sql.TransposedAggregation); ok && t.Transposed() {
+			transposed = append(transposed, i)
+		}
+	}
+
+	if len(transposed) == 0 {
+		return []sql.Row{row}, nil
+	}
+
+	mode, err := udf.TransposeMode(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var lists int
+	values := make([][]interface{}, len(transposed))
+	for i, idx := range transposed {
+		switch val := reflect.ValueOf(row[idx]); val.Kind() {
+		case reflect.Slice, reflect.Array:
+			lists++
+			values[i] = make([]interface{}, val.Len())
+			for j := range values[i] {
+				values[i][j] = val.Index(j).Interface()
 			}
 		}
 	}
-	var rows []sql.Row
-	if transposeColInd == -1 {
-		rows = []sql.Row { row }
-	} else {
-		col := row[transposeColInd]
-		switch val := reflect.ValueOf(col); val.Type().Kind() {
-		case reflect.Slice:
-			for i := 0; i < val.Len(); i++ {
-				v := val.Index(i)
-				r := sql.Row.Copy(row)
-				r[transposeColInd] = v.Interface()
-				rows = append(rows, r)
+
+	if lists == 0 {
+		return []sql.Row{row}, nil
+	}
+
+	if mode == udf.TransposeCross {
+		return crossRows(row, transposed, values), nil
+	}
+	return zipRows(row, transposed, values), nil
+}
+
+// zipRows returns a row for each position of the given lists, with the
+// element at that position of each of them. Lists that are shorter than the
+// longest one are null at the positions they don't have. Results that are
+// not lists, whose values are nil, are left as they are.
+func zipRows(row sql.Row, transposed []int, values [][]interface{}) []sql.Row {
+	var n int
+	for _, v := range values {
+		if len(v) > n {
+			n = len(v)
+		}
+	}
+
+	rows := make([]sql.Row, n)
+	for i := range rows {
+		r := row.Copy()
+		for j, idx := range transposed {
+			switch {
+			case values[j] == nil:
+			case i < len(values[j]):
+				r[idx] = values[j][i]
+			default:
+				r[idx] = nil
 			}
-		default:
-			rows = []sql.Row{ row }
 		}
+		rows[i] = r
+	}
+	return rows
+}
+
+// crossRows returns a row for each combination of one element of each of
+// the given lists. Results that are not lists, whose values are nil, are
+// left as they are.
+func crossRows(row sql.Row, transposed []int, values [][]interface{}) []sql.Row {
+	rows := []sql.Row{row}
+	for j, idx := range transposed {
+		if values[j] == nil {
+			continue
+		}
+
+		next := make([]sql.Row, 0, len(rows)*len(values[j]))
+		for _, r := range rows {
+			for _, v := range values[j] {
+				nr := r.Copy()
+				nr[idx] = v
+				next = append(next, nr)
+			}
+		}
+		rows = next
 	}
 	return rows
 }
@@ -267,6 +341,15 @@ type groupByGroupingIter struct {
 	child       sql.RowIter
 	ctx         *sql.Context
 	dispose     sql.DisposeFunc
+	// expanded are the rows of the last group that have not been returned
+	// yet, when it has transposed aggregations.
+	expanded []sql.Row
+
+	// The rows of the groups that don't fit in memory are stored in
+	// partitions, which are aggregated after the groups in memory.
+	level      int
+	partitions *spillPartitions
+	partition  *groupByGroupingIter
 }
 
 func newGroupByGroupingIter(
@@ -290,16 +373,67 @@ func (i *groupByGroupingIter) Next() (sql.Row, error) {
 		}
 	}
 
-	if i.pos >= len(i.keys) {
+	for len(i.expanded) == 0 {
+		if i.pos >= len(i.keys) {
+			return i.nextPartitionRow()
+		}
+
+		buffers, err := i.aggregation.Get(i.keys[i.pos])
+		if err != nil {
+			return nil, err
+		}
+		i.pos++
+
+		row, err := evalBuffers(i.ctx, buffers.([]sql.Row), i.aggregate)
+		if err != nil {
+			return nil, err
+		}
+
+		i.expanded, err = expandRow(i.ctx, row, i.aggregate)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	row := i.expanded[0]
+	i.expanded = i.expanded[1:]
+	return row, nil
+}
+
+func (i *groupByGroupingIter) nextPartitionRow() (sql.Row, error) {
+	if i.partitions == nil {
 		return nil, io.EOF
 	}
 
-	buffers, err := i.aggregation.Get(i.keys[i.pos])
-	if err != nil {
-		return nil, err
+	// The groups in memory are no longer needed, and their memory is needed
+	// to aggregate the partitions.
+	i.disposeAggregation()
+
+	for {
+		if i.partition != nil {
+			row, err := i.partition.Next()
+			if err != io.EOF {
+				return row, err
+			}
+
+			if err := i.partition.Close(); err != nil {
+				return nil, err
+			}
+			i.partition = nil
+		}
+
+		iter, err := i.partitions.next()
+		if err != nil {
+			return nil, err
+		}
+
+		if iter == nil {
+			return nil, io.EOF
+		}
+
+		i.partition = newGroupByGroupingIter(i.ctx, i.aggregate, i.grouping, iter)
+		i.partition.level = i.level + 1
 	}
-	i.pos++
-	return evalBuffers(i.ctx, buffers.([]sql.Row), i.aggregate)
 }
 
 func (i *groupByGroupingIter) compute() error {
@@ -318,13 +452,37 @@ func (i *groupByGroupingIter) compute() error {
 		}
 
 		if _, err := i.aggregation.Get(key); err != nil {
+			if i.partitions != nil {
+				if err := i.partitions.add(key, row); err != nil {
+					return err
+				}
+				continue
+			}
+
 			var buf = make([]sql.Row, len(i.aggregate))
 			for j, a := range i.aggregate {
 				buf[j] = fillBuffer(a)
 			}
 
 			if err := i.aggregation.Put(key, buf); err != nil {
-				return err
+				if !sql.ErrNoMemoryAvailable.Is(err) {
+					return err
+				}
+
+				// Rows are partitioned even if no group fits in memory yet,
+				// because memory may be available when the partitions are
+				// processed. The number of levels is limited, so this ends
+				// up failing if memory is never available.
+
+				i.partitions, err = newSpillPartitions(i.ctx, i.level)
+				if err != nil {
+					return err
+				}
+
+				if err := i.partitions.add(key, row); err != nil {
+					return err
+				}
+				continue
 			}
 
 			i.keys = append(i.keys, key)
@@ -344,8 +502,25 @@ func (i *groupByGroupingIter) compute() error {
 	return nil
 }
 
+func (i *groupByGroupingIter) disposeAggregation() {
+	if i.dispose != nil {
+		i.dispose()
+		i.dispose = nil
+	}
+}
+
 func (i *groupByGroupingIter) Close() error {
+	i.disposeAggregation()
 	i.aggregation = nil
+
+	if i.partition != nil {
+		_ = i.partition.Close()
+	}
+
+	if i.partitions != nil {
+		i.partitions.dispose()
+	}
+
 	return i.child.Close()
 }
 
@@ -1,12 +1,15 @@
 package plan
 
 import (
+	"fmt"
+	"os"
 	"testing"
 
 	"github.com/src-d/go-mysql-server/memory"
 	"github.com/src-d/go-mysql-server/sql"
 	"github.com/src-d/go-mysql-server/sql/expression"
 	"github.com/src-d/go-mysql-server/sql/expression/function/aggregation"
+	"github.com/src-d/go-mysql-server/sql/expression/function/udf"
 	"github.com/stretchr/testify/require"
 )
 
@@ -222,3 +225,186 @@ func benchmarkTable(t testing.TB) sql.Table {
 
 	return table
 }
+
+func TestGroupBySpill(t *testing.T) {
+	require := require.New(t)
+
+	table := spillTable(t, "t", 500)
+	gb := NewGroupBy(
+		[]sql.Expression{
+			expression.NewGetField(0, sql.Int64, "i", true),
+			aggregation.NewCount(expression.NewGetField(1, sql.Text, "s", false)),
+			aggregation.NewMax(expression.NewGetField(1, sql.Text, "s", false)),
+		},
+		[]sql.Expression{
+			expression.NewGetField(0, sql.Int64, "i", true),
+		},
+		NewResolvedTable(table),
+	)
+
+	expected, err := sql.NodeToRows(sql.NewEmptyContext(), gb)
+	require.NoError(err)
+	require.Len(expected, 38)
+
+	ctx, dir := newSpillContext(t, 5)
+	defer os.RemoveAll(dir)
+
+	rows, err := sql.NodeToRows(ctx, gb)
+	require.NoError(err)
+	require.ElementsMatch(expected, rows)
+	requireNoSpools(t, dir)
+}
+
+func TestGroupByTransposed(t *testing.T) {
+	childSchema := sql.Schema{
+		{Name: "g", Type: sql.Int64},
+		{Name: "a", Type: sql.Int64},
+		{Name: "b", Type: sql.Text},
+	}
+	child := memory.NewTable("test", childSchema)
+	for _, r := range []sql.Row{
+		sql.NewRow(int64(1), int64(1), "x"),
+		sql.NewRow(int64(1), int64(2), "y"),
+		sql.NewRow(int64(2), int64(3), "z"),
+	} {
+		require.NoError(t, child.Insert(sql.NewEmptyContext(), r))
+	}
+
+	aggregate := []sql.Expression{
+		newTransposedList(expression.NewGetField(1, sql.Int64, "a", false)),
+		expression.NewAlias(newTransposedList(expression.NewGetField(2, sql.Text, "b", false)), "b"),
+		aggregation.NewCount(expression.NewStar()),
+	}
+
+	testCases := []struct {
+		name     string
+		mode     string
+		grouping []sql.Expression
+		expected []sql.Row
+	}{
+		{
+			"zip",
+			"",
+			nil,
+			[]sql.Row{{int64(1), "x", int64(3)}, {int64(2), "y", int64(3)}, {int64(3), "z", int64(3)}},
+		},
+		{
+			"cross",
+			udf.TransposeCross,
+			nil,
+			[]sql.Row{
+				{int64(1), "x", int64(3)}, {int64(1), "y", int64(3)}, {int64(1), "z", int64(3)},
+				{int64(2), "x", int64(3)}, {int64(2), "y", int64(3)}, {int64(2), "z", int64(3)},
+				{int64(3), "x", int64(3)}, {int64(3), "y", int64(3)}, {int64(3), "z", int64(3)},
+			},
+		},
+		{
+			"zip with grouping",
+			udf.TransposeZip,
+			[]sql.Expression{expression.NewGetField(0, sql.Int64, "g", false)},
+			[]sql.Row{{int64(1), "x", int64(2)}, {int64(2), "y", int64(2)}, {int64(3), "z", int64(1)}},
+		},
+		{
+			"cross with grouping",
+			udf.TransposeCross,
+			[]sql.Expression{expression.NewGetField(0, sql.Int64, "g", false)},
+			[]sql.Row{
+				{int64(1), "x", int64(2)}, {int64(1), "y", int64(2)},
+				{int64(2), "x", int64(2)}, {int64(2), "y", int64(2)},
+				{int64(3), "z", int64(1)},
+			},
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			require := require.New(t)
+			ctx := sql.NewEmptyContext()
+			if tt.mode != "" {
+				ctx.Set(udf.TransposeKey, sql.Text, tt.mode)
+			}
+
+			node := NewSort(
+				[]SortField{
+					{Column: expression.NewGetField(0, sql.Int64, "a", false)},
+					{Column: expression.NewGetField(1, sql.Text, "b", false)},
+				},
+				NewGroupBy(aggregate, tt.grouping, NewResolvedTable(child)),
+			)
+
+			rows, err := sql.NodeToRows(ctx, node)
+			require.NoError(err)
+			require.Equal(tt.expected, rows)
+		})
+	}
+
+	ctx := sql.NewEmptyContext()
+	ctx.Set(udf.TransposeKey, sql.Text, "foo")
+	_, err := sql.NodeToRows(ctx, NewGroupBy(aggregate, nil, NewResolvedTable(child)))
+	require.True(t, udf.ErrInvalidTransposeMode.Is(err))
+}
+
+func TestExpandRow(t *testing.T) {
+	require := require.New(t)
+	ctx := sql.NewEmptyContext()
+
+	aggregate := []sql.Expression{
+		newTransposedList(expression.NewGetField(0, sql.Int64, "a", false)),
+		newTransposedList(expression.NewGetField(1, sql.Int64, "b", false)),
+		newTransposedList(expression.NewGetField(2, sql.Int64, "c", false)),
+	}
+
+	rows, err := expandRow(ctx, sql.NewRow([]interface{}{1, 2}, []interface{}{3}, 4), aggregate)
+	require.NoError(err)
+	require.Equal([]sql.Row{{1, 3, 4}, {2, nil, 4}}, rows)
+
+	rows, err = expandRow(ctx, sql.NewRow([]interface{}{}, []interface{}{3}, 4), aggregate)
+	require.NoError(err)
+	require.Equal([]sql.Row{{nil, 3, 4}}, rows)
+
+	rows, err = expandRow(ctx, sql.NewRow(1, 2, 3), aggregate)
+	require.NoError(err)
+	require.Equal([]sql.Row{{1, 2, 3}}, rows)
+
+	ctx.Set(udf.TransposeKey, sql.Text, udf.TransposeCross)
+	rows, err = expandRow(ctx, sql.NewRow([]interface{}{}, []interface{}{3}, 4), aggregate)
+	require.NoError(err)
+	require.Len(rows, 0)
+}
+
+// transposedList is a transposed aggregation that collects the values of
+// its child in a list.
+type transposedList struct {
+	expression.UnaryExpression
+}
+
+func newTransposedList(child sql.Expression) *transposedList {
+	return &transposedList{expression.UnaryExpression{Child: child}}
+}
+
+func (l *transposedList) Transposed() bool   { return true }
+func (l *transposedList) Type() sql.Type     { return sql.Array(l.Child.Type()) }
+func (l *transposedList) String() string     { return fmt.Sprintf("LIST(%s)", l.Child) }
+func (l *transposedList) NewBuffer() sql.Row { return sql.NewRow([]interface{}{}) }
+
+func (l *transposedList) WithChildren(children ...sql.Expression) (sql.Expression, error) {
+	return newTransposedList(children[0]), nil
+}
+
+func (l *transposedList) Update(ctx *sql.Context, buffer, row sql.Row) error {
+	v, err := l.Child.Eval(ctx, row)
+	if err != nil {
+		return err
+	}
+	buffer[0] = append(buffer[0].([]interface{}), v)
+	return nil
+}
+
+func (l *transposedList) Merge(ctx *sql.Context, buffer, partial sql.Row) error {
+	buffer[0] = append(buffer[0].([]interface{}), partial[0].([]interface{})...)
+	return nil
+}
+
+func (l *transposedList) Eval(ctx *sql.Context, buffer sql.Row) (interface{}, error) {
+	return buffer[0], nil
+}
@@ -0,0 +1,905 @@
+package plan
+
+import (
+	"io"
+	"time"
+
+	opentracing "github.com/opentracing/opentracing-go"
+	"github.com/src-d/go-mysql-server/sql"
+	"github.com/src-d/go-mysql-server/sql/expression"
+)
+
+// HashJoin is a join whose condition contains equalities between expressions
+// of the left and the right side. Instead of iterating one side once for each
+// row of the other, it builds a hash table with the rows of the smaller side
+// and looks up the rows of the other side in it.
+type HashJoin struct {
+	BinaryNode
+	Type  JoinType
+	Cond  sql.Expression
+	Build BuildSide
+
+	leftKeys  []sql.Expression
+	rightKeys []sql.Expression
+	keyTypes  []sql.Type
+}
+
+// NewHashJoin creates a new hash join node of the given type. The keys of the
+// hash table are the equalities between both sides found in the condition. If
+// there are none, the join is computed as a regular join, so CanHashJoin
+// should be checked before. Semi and anti joins always build the hash table
+// with the right side.
+func NewHashJoin(typ JoinType, left, right sql.Node, cond sql.Expression) *HashJoin {
+	leftKeys, rightKeys, keyTypes := hashJoinKeys(len(left.Schema()), cond)
+	var build BuildSide
+	if typ.returnsLeftOnly() {
+		build = BuildRight
+	}
+
+	return &HashJoin{
+		BinaryNode: BinaryNode{
+			Left:  left,
+			Right: right,
+		},
+		Type:      typ,
+		Cond:      cond,
+		Build:     build,
+		leftKeys:  leftKeys,
+		rightKeys: rightKeys,
+		keyTypes:  keyTypes,
+	}
+}
+
+// BuildSide is the side of a hash join whose rows are used to build the hash
+// table.
+type BuildSide byte
+
+const (
+	// BuildSmaller builds the hash table with the side that turns out to be
+	// the smaller one when the rows of both are read alternately.
+	BuildSmaller BuildSide = iota
+	// BuildLeft builds the hash table with the rows of the left side.
+	BuildLeft
+	// BuildRight builds the hash table with the rows of the right side.
+	BuildRight
+)
+
+func (s BuildSide) String() string {
+	switch s {
+	case BuildLeft:
+		return "left"
+	case BuildRight:
+		return "right"
+	default:
+		return "smaller"
+	}
+}
+
+// WithBuildSide returns a copy of the join that builds the hash table with
+// the given side, such as the one that is estimated to be smaller, instead of
+// finding out which one it is while reading them. The build side of semi and
+// anti joins can't be changed.
+func (j *HashJoin) WithBuildSide(side BuildSide) *HashJoin {
+	if j.Type.returnsLeftOnly() {
+		return j
+	}
+
+	nj := *j
+	nj.Build = side
+	return &nj
+}
+
+// CanHashJoin reports whether the join between the given nodes with the given
+// condition can be computed with a hash join.
+func CanHashJoin(left sql.Node, cond sql.Expression) bool {
+	keys, _, _ := hashJoinKeys(len(left.Schema()), cond)
+	return len(keys) > 0
+}
+
+// Schema implements the Node interface.
+func (j *HashJoin) Schema() sql.Schema {
+	switch j.Type {
+	case JoinTypeLeft:
+		return append(j.Left.Schema(), makeNullable(j.Right.Schema())...)
+	case JoinTypeRight:
+		return append(makeNullable(j.Left.Schema()), j.Right.Schema()...)
+	case JoinTypeSemi, JoinTypeAnti, JoinTypeNullAwareAnti:
+		return j.Left.Schema()
+	default:
+		return append(j.Left.Schema(), j.Right.Schema()...)
+	}
+}
+
+// Resolved implements the Resolvable interface.
+func (j *HashJoin) Resolved() bool {
+	return j.Left.Resolved() && j.Right.Resolved() && j.Cond.Resolved()
+}
+
+// RowIter implements the Node interface.
+func (j *HashJoin) RowIter(ctx *sql.Context) (sql.RowIter, error) {
+	if len(j.leftKeys) == 0 {
+		return joinRowIter(ctx, j.Type, j.Left, j.Right, j.Cond)
+	}
+
+	span, ctx := ctx.Span("plan.HashJoin", opentracing.Tags{
+		"type": j.Type.String(),
+	})
+
+	l, err := j.Left.RowIter(ctx)
+	if err != nil {
+		span.Finish()
+		return nil, err
+	}
+
+	r, err := j.Right.RowIter(ctx)
+	if err != nil {
+		span.Finish()
+		_ = l.Close()
+		return nil, err
+	}
+
+	return sql.NewSpanIter(span, &hashJoinIter{
+		ctx:       ctx,
+		join:      j,
+		leftIter:  l,
+		rightIter: r,
+		leftSize:  len(j.Left.Schema()),
+		rightSize: len(j.Right.Schema()),
+	}), nil
+}
+
+// WithChildren implements the Node interface.
+func (j *HashJoin) WithChildren(children ...sql.Node) (sql.Node, error) {
+	if len(children) != 2 {
+		return nil, sql.ErrInvalidChildrenNumber.New(j, len(children), 2)
+	}
+
+	return NewHashJoin(j.Type, children[0], children[1], j.Cond).WithBuildSide(j.Build), nil
+}
+
+// WithExpressions implements the Expressioner interface.
+func (j *HashJoin) WithExpressions(exprs ...sql.Expression) (sql.Node, error) {
+	if len(exprs) != 1 {
+		return nil, sql.ErrInvalidChildrenNumber.New(j, len(exprs), 1)
+	}
+
+	return NewHashJoin(j.Type, j.Left, j.Right, exprs[0]).WithBuildSide(j.Build), nil
+}
+
+// Expressions implements the Expressioner interface.
+func (j *HashJoin) Expressions() []sql.Expression {
+	return []sql.Expression{j.Cond}
+}
+
+func (j *HashJoin) String() string {
+	pr := sql.NewTreePrinter()
+	if j.Build == BuildSmaller {
+		_ = pr.WriteNode("HashJoin(%s, %s)", j.Type, j.Cond)
+	} else {
+		_ = pr.WriteNode("HashJoin(%s, %s, build %s)", j.Type, j.Cond, j.Build)
+	}
+	_ = pr.WriteChildren(j.Left.String(), j.Right.String())
+	return pr.String()
+}
+
+// hashJoinKeys returns the pairs of expressions of the left and right side
+// that need to be equal for the condition to be true, along with the type
+// their values are converted to before being hashed. Expressions of the right
+// side are indexed using only the schema of the right side.
+func hashJoinKeys(
+	leftSize int,
+	cond sql.Expression,
+) (leftKeys, rightKeys []sql.Expression, keyTypes []sql.Type) {
+	for _, e := range splitConjunction(cond) {
+		eq, ok := e.(*expression.Equals)
+		if !ok {
+			continue
+		}
+
+		left, right := eq.Left(), eq.Right()
+		switch {
+		case exprSide(left, leftSize) == JoinTypeLeft &&
+			exprSide(right, leftSize) == JoinTypeRight:
+		case exprSide(left, leftSize) == JoinTypeRight &&
+			exprSide(right, leftSize) == JoinTypeLeft:
+			left, right = right, left
+		default:
+			continue
+		}
+
+		typ, ok := hashKeyType(left.Type(), right.Type())
+		if !ok {
+			continue
+		}
+
+		right, err := expression.TransformUp(right, func(e sql.Expression) (sql.Expression, error) {
+			if gf, ok := e.(*expression.GetField); ok {
+				return gf.WithIndex(gf.Index() - leftSize), nil
+			}
+			return e, nil
+		})
+		if err != nil {
+			continue
+		}
+
+		leftKeys = append(leftKeys, left)
+		rightKeys = append(rightKeys, right)
+		keyTypes = append(keyTypes, typ)
+	}
+
+	return leftKeys, rightKeys, keyTypes
+}
+
+func splitConjunction(e sql.Expression) []sql.Expression {
+	and, ok := e.(*expression.And)
+	if !ok {
+		return []sql.Expression{e}
+	}
+
+	return append(
+		splitConjunction(and.Left),
+		splitConjunction(and.Right)...,
+	)
+}
+
+// exprSide returns the side of the join whose columns are used in the given
+// expression, or JoinTypeInner if it uses both sides, none of them or
+// something that can't be used as a hash join key.
+func exprSide(e sql.Expression, leftSize int) JoinType {
+	var usesLeft, usesRight, invalid bool
+	expression.Inspect(e, func(e sql.Expression) bool {
+		switch e := e.(type) {
+		case *expression.GetField:
+			if e.Index() < leftSize {
+				usesLeft = true
+			} else {
+				usesRight = true
+			}
+		case *expression.Subquery, *expression.BindVar:
+			invalid = true
+		}
+		return !invalid
+	})
+
+	switch {
+	case invalid || usesLeft == usesRight:
+		return JoinTypeInner
+	case usesLeft:
+		return JoinTypeLeft
+	default:
+		return JoinTypeRight
+	}
+}
+
+// hashKeyType returns the type both values of an equality between the given
+// types need to be converted to so that equal values have the same hash. The
+// conversions are the same ones made by the comparison.
+func hashKeyType(left, right sql.Type) (sql.Type, bool) {
+	switch {
+	case sql.IsSigned(left) && sql.IsSigned(right):
+		return sql.Int64, true
+	case sql.IsUnsigned(left) && sql.IsUnsigned(right):
+		return sql.Uint64, true
+	case isStringType(left) && isStringType(right):
+		return sql.Text, true
+	case left == right && (sql.IsTime(left) || left == sql.Blob):
+		return left, true
+	default:
+		return nil, false
+	}
+}
+
+func isStringType(t sql.Type) bool {
+	return t == sql.Text || sql.IsVarChar(t) || sql.IsChar(t)
+}
+
+// hashJoinIter computes a hash join. Rows are read alternately from both
+// sides until one of them is exhausted. That one is the smaller side, so the
+// hash table is built with its rows, and the rows of the other side, both the
+// ones already read and the remaining ones, are looked up in it.
+type hashJoinIter struct {
+	ctx       *sql.Context
+	join      *HashJoin
+	leftIter  sql.RowIter
+	rightIter sql.RowIter
+	leftSize  int
+	rightSize int
+
+	// spilled computes the join with the rows stored on disk when the hash
+	// table doesn't fit in memory.
+	spilled    sql.RowIter
+	level      int
+	partitions []*spillPartitions
+	spools     []*sql.RowSpool
+
+	built      bool
+	buildLeft  bool
+	buildRows  sql.RowsCache
+	probeRows  sql.RowsCache
+	probePos   int
+	probe      sql.RowIter
+	table      map[uint64][]int
+	nullKeys   []int
+	matched    []bool
+	unmatched  int
+	dispose    []sql.DisposeFunc
+	probeRow   sql.Row
+	candidates []int
+	foundMatch bool
+}
+
+func (i *hashJoinIter) Next() (sql.Row, error) {
+	if !i.built {
+		if err := i.buildTable(); err != nil {
+			return nil, err
+		}
+	}
+
+	if i.spilled != nil {
+		return i.spilled.Next()
+	}
+
+	for {
+		if i.probeRow == nil {
+			row, err := i.nextProbeRow()
+			if err == io.EOF {
+				return i.nextUnmatched()
+			}
+
+			if err != nil {
+				return nil, err
+			}
+
+			key, ok, err := i.key(i.probeKeys(), row)
+			if err != nil {
+				return nil, err
+			}
+
+			i.probeRow = row
+			i.foundMatch = false
+			i.candidates = i.probeCandidates(key, ok)
+		}
+
+		if len(i.candidates) == 0 {
+			row := i.probeRow
+			i.probeRow = nil
+			if !i.foundMatch && i.preserves(!i.buildLeft) {
+				return i.buildRow(row, nil), nil
+			}
+
+			if !i.foundMatch && i.join.Type.isAnti() {
+				return row, nil
+			}
+			continue
+		}
+
+		idx := i.candidates[0]
+		i.candidates = i.candidates[1:]
+
+		// The whole condition is evaluated, not only the non-equi predicates,
+		// because rows with different keys may have the same hash.
+		row := i.buildRow(i.probeRow, i.buildRows.Get()[idx])
+		ok, err := evaluateJoinCondition(i.ctx, i.join.Type, i.join.Cond, row)
+		if err != nil {
+			return nil, err
+		}
+
+		if !ok {
+			continue
+		}
+
+		i.foundMatch = true
+		if i.matched != nil {
+			i.matched[idx] = true
+		}
+
+		if !i.join.Type.returnsLeftOnly() {
+			return row, nil
+		}
+
+		// The rest of the candidates don't matter once a row of a semi or
+		// anti join has a match.
+		i.candidates = nil
+		if i.join.Type == JoinTypeSemi {
+			return i.probeRow, nil
+		}
+	}
+}
+
+// probeCandidates returns the build rows that may match a probe row with the
+// given key.
+func (i *hashJoinIter) probeCandidates(key uint64, ok bool) []int {
+	if i.join.Type != JoinTypeNullAwareAnti {
+		if !ok {
+			return nil
+		}
+		return i.table[key]
+	}
+
+	// Rows with null keys may match any row in a null-aware anti join.
+	if !ok {
+		all := make([]int, len(i.buildRows.Get()))
+		for idx := range all {
+			all[idx] = idx
+		}
+		return all
+	}
+
+	if len(i.nullKeys) == 0 {
+		return i.table[key]
+	}
+
+	candidates := make([]int, 0, len(i.table[key])+len(i.nullKeys))
+	candidates = append(candidates, i.table[key]...)
+	return append(candidates, i.nullKeys...)
+}
+
+func (i *hashJoinIter) buildTable() error {
+	i.built = true
+
+	leftRows, disposeLeft := i.ctx.Memory.NewRowsCache()
+	rightRows, disposeRight := i.ctx.Memory.NewRowsCache()
+	i.dispose = []sql.DisposeFunc{disposeLeft, disposeRight}
+
+	var row sql.Row
+	var err error
+	switch i.join.Build {
+	case BuildLeft:
+		i.buildLeft = true
+		row, err = addRemainingRows(i.leftIter, leftRows)
+	case BuildRight:
+		row, err = addRemainingRows(i.rightIter, rightRows)
+	default:
+		// The right side is read first, so it's the build side when both
+		// have the same size and the rows are returned in the order of the
+		// left side, like in a regular join.
+		for {
+			var done bool
+			row, done, err = addNextRow(i.rightIter, rightRows)
+			if err != nil || done {
+				break
+			}
+
+			row, done, err = addNextRow(i.leftIter, leftRows)
+			if err != nil || done {
+				i.buildLeft = true
+				break
+			}
+		}
+	}
+
+	if sql.ErrNoMemoryAvailable.Is(err) {
+		// The row that didn't fit is from the side being read.
+		left, right := leftRows.Get(), rightRows.Get()
+		if i.buildLeft {
+			left = append(left, row)
+		} else {
+			right = append(right, row)
+		}
+
+		return i.spill(left, right)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	var buildKeys []sql.Expression
+	if i.buildLeft {
+		buildKeys = i.join.leftKeys
+		i.buildRows, i.probeRows, i.probe = leftRows, rightRows, i.rightIter
+		err = i.leftIter.Close()
+		i.leftIter = nil
+	} else {
+		buildKeys = i.join.rightKeys
+		i.buildRows, i.probeRows, i.probe = rightRows, leftRows, i.leftIter
+		err = i.rightIter.Close()
+		i.rightIter = nil
+	}
+
+	if err != nil {
+		return err
+	}
+
+	rows := i.buildRows.Get()
+	i.table = make(map[uint64][]int)
+	for idx, row := range rows {
+		key, ok, err := i.key(buildKeys, row)
+		if err != nil {
+			return err
+		}
+
+		// Rows with null keys never match, but they are still kept in the
+		// cache in case they need to be returned as unmatched rows, or
+		// they may match in a null-aware anti join.
+		if ok {
+			i.table[key] = append(i.table[key], idx)
+		} else if i.join.Type == JoinTypeNullAwareAnti {
+			i.nullKeys = append(i.nullKeys, idx)
+		}
+	}
+
+	if i.preserves(i.buildLeft) {
+		i.matched = make([]bool, len(rows))
+	}
+
+	return nil
+}
+
+// spill stores the rows of both sides on disk, partitioned by the hash of
+// their keys, so each pair of partitions can be joined separately. If they
+// can't be partitioned any more, they are joined with a nested loop.
+func (i *hashJoinIter) spill(leftRows, rightRows []sql.Row) error {
+	i.disposeCaches()
+
+	// Rows with null keys may match rows of any partition in null-aware
+	// anti joins, so they can't be partitioned.
+	if i.join.Type == JoinTypeNullAwareAnti {
+		return i.spillNestedLoop(leftRows, rightRows)
+	}
+
+	left, err := newSpillPartitions(i.ctx, i.level)
+	if sql.ErrNoMemoryAvailable.Is(err) {
+		return i.spillNestedLoop(leftRows, rightRows)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	right, err := newSpillPartitions(i.ctx, i.level)
+	if err != nil {
+		return err
+	}
+
+	i.partitions = []*spillPartitions{left, right}
+	if err := i.addToPartitions(left, i.join.leftKeys, leftRows, i.leftIter); err != nil {
+		return err
+	}
+
+	if err := i.addToPartitions(right, i.join.rightKeys, rightRows, i.rightIter); err != nil {
+		return err
+	}
+
+	if err := i.closeIters(); err != nil {
+		return err
+	}
+
+	i.spilled = &partitionedJoinIter{
+		ctx:       i.ctx,
+		join:      i.join,
+		level:     i.level + 1,
+		left:      left,
+		right:     right,
+		leftSize:  i.leftSize,
+		rightSize: i.rightSize,
+	}
+	return nil
+}
+
+func (i *hashJoinIter) addToPartitions(
+	p *spillPartitions,
+	keys []sql.Expression,
+	rows []sql.Row,
+	iter sql.RowIter,
+) error {
+	for {
+		var row sql.Row
+		if len(rows) > 0 {
+			row, rows = rows[0], rows[1:]
+		} else {
+			var err error
+			row, err = iter.Next()
+			if err == io.EOF {
+				return nil
+			}
+
+			if err != nil {
+				return err
+			}
+		}
+
+		// Rows with null keys don't match any other, so it doesn't matter
+		// in which partition they are.
+		key, _, err := i.key(keys, row)
+		if err != nil {
+			return err
+		}
+
+		if err := p.add(key, row); err != nil {
+			return err
+		}
+	}
+}
+
+// spillNestedLoop stores the rows of both sides on disk and joins them with
+// a nested loop.
+func (i *hashJoinIter) spillNestedLoop(leftRows, rightRows []sql.Row) error {
+	left, err := i.spoolSide(leftRows, i.leftIter)
+	if err != nil {
+		return err
+	}
+
+	right, err := i.spoolSide(rightRows, i.rightIter)
+	if err != nil {
+		return err
+	}
+
+	if err := i.closeIters(); err != nil {
+		return err
+	}
+
+	// The primary side of the join is the one whose rows are returned even
+	// if they have no match.
+	primary, secondary := left, right
+	if i.join.Type == JoinTypeRight {
+		primary, secondary = right, left
+	}
+
+	iter, err := primary.RowIter()
+	if err != nil {
+		return err
+	}
+
+	i.spilled = &joinIter{
+		typ:               i.join.Type,
+		primary:           iter,
+		secondaryProvider: spoolIterProvider{secondary},
+		ctx:               i.ctx,
+		cond:              i.join.Cond,
+		rowSize:           i.leftSize + i.rightSize,
+		mode:              multipassMode,
+	}
+	return nil
+}
+
+func (i *hashJoinIter) spoolSide(rows []sql.Row, iter sql.RowIter) (*sql.RowSpool, error) {
+	spool, err := i.ctx.Memory.NewRowSpool()
+	if err != nil {
+		return nil, err
+	}
+	i.spools = append(i.spools, spool)
+
+	for _, row := range rows {
+		if err := spool.Add(row); err != nil {
+			return nil, err
+		}
+	}
+
+	return spool, addAllRows(iter, spool)
+}
+
+func addNextRow(iter sql.RowIter, cache sql.RowsCache) (row sql.Row, done bool, err error) {
+	row, err = iter.Next()
+	if err == io.EOF {
+		return nil, true, nil
+	}
+
+	if err != nil {
+		return nil, false, err
+	}
+
+	return row, false, cache.Add(row)
+}
+
+// addRemainingRows adds all the remaining rows of the iterator to the cache. If
+// there is an error, the row being added is returned along with it.
+func addRemainingRows(iter sql.RowIter, cache sql.RowsCache) (sql.Row, error) {
+	for {
+		row, done, err := addNextRow(iter, cache)
+		if err != nil || done {
+			return row, err
+		}
+	}
+}
+
+// partitionedJoinIter joins each pair of partitions of the rows of both sides
+// of a hash join.
+type partitionedJoinIter struct {
+	ctx       *sql.Context
+	join      *HashJoin
+	level     int
+	left      *spillPartitions
+	right     *spillPartitions
+	leftSize  int
+	rightSize int
+	idx       int
+	current   sql.RowIter
+}
+
+func (i *partitionedJoinIter) Next() (sql.Row, error) {
+	for {
+		if i.current != nil {
+			row, err := i.current.Next()
+			if err != io.EOF {
+				return row, err
+			}
+
+			if err := i.current.Close(); err != nil {
+				return nil, err
+			}
+			i.current = nil
+		}
+
+		if i.idx >= numPartitions {
+			return nil, io.EOF
+		}
+
+		left, err := i.left.partition(i.idx)
+		if err != nil {
+			return nil, err
+		}
+
+		right, err := i.right.partition(i.idx)
+		if err != nil {
+			if left != nil {
+				_ = left.Close()
+			}
+			return nil, err
+		}
+		i.idx++
+
+		if left == nil && right == nil {
+			continue
+		}
+
+		if left == nil {
+			left = sql.RowsToRowIter()
+		}
+
+		if right == nil {
+			right = sql.RowsToRowIter()
+		}
+
+		i.current = &hashJoinIter{
+			ctx:       i.ctx,
+			join:      i.join,
+			leftIter:  left,
+			rightIter: right,
+			leftSize:  i.leftSize,
+			rightSize: i.rightSize,
+			level:     i.level,
+		}
+	}
+}
+
+func (i *partitionedJoinIter) Close() error {
+	var err error
+	if i.current != nil {
+		err = i.current.Close()
+	}
+
+	i.left.dispose()
+	i.right.dispose()
+	return err
+}
+
+func (i *hashJoinIter) nextProbeRow() (sql.Row, error) {
+	// Inner and semi joins with nothing to match against don't need to read
+	// the rest of the rows.
+	if len(i.table) == 0 && len(i.nullKeys) == 0 &&
+		!i.preserves(!i.buildLeft) && !i.join.Type.isAnti() {
+		return nil, io.EOF
+	}
+
+	if rows := i.probeRows.Get(); i.probePos < len(rows) {
+		i.probePos++
+		return rows[i.probePos-1], nil
+	}
+
+	return i.probe.Next()
+}
+
+// nextUnmatched returns the next row of the build side without any match when
+// the build side is preserved by the join.
+func (i *hashJoinIter) nextUnmatched() (sql.Row, error) {
+	for i.unmatched < len(i.matched) {
+		idx := i.unmatched
+		i.unmatched++
+		if !i.matched[idx] {
+			return i.buildRow(nil, i.buildRows.Get()[idx]), nil
+		}
+	}
+
+	return nil, io.EOF
+}
+
+func (i *hashJoinIter) probeKeys() []sql.Expression {
+	if i.buildLeft {
+		return i.join.rightKeys
+	}
+	return i.join.leftKeys
+}
+
+// preserves reports whether the rows of the given side are returned even if
+// they have no match.
+func (i *hashJoinIter) preserves(left bool) bool {
+	if left {
+		return i.join.Type == JoinTypeLeft
+	}
+	return i.join.Type == JoinTypeRight
+}
+
+// key returns the hash of the values of the given keys for the given row, or
+// false if any of them is null, because then the row can't match any other.
+func (i *hashJoinIter) key(keys []sql.Expression, row sql.Row) (uint64, bool, error) {
+	values := make([]interface{}, len(keys))
+	for idx, k := range keys {
+		v, err := k.Eval(i.ctx, row)
+		if err != nil {
+			return 0, false, err
+		}
+
+		if v == nil {
+			return 0, false, nil
+		}
+
+		v, err = i.join.keyTypes[idx].Convert(v)
+		if err != nil {
+			return 0, false, err
+		}
+
+		// Equal times may be in different locations.
+		if t, ok := v.(time.Time); ok {
+			v = t.UnixNano()
+		}
+
+		values[idx] = v
+	}
+
+	return sql.CacheKey(values), true, nil
+}
+
+// buildRow builds the resulting row from the rows of the probe and build
+// sides. Any of them may be nil, in which case its columns will be null.
+func (i *hashJoinIter) buildRow(probe, build sql.Row) sql.Row {
+	left, right := probe, build
+	if i.buildLeft {
+		left, right = build, probe
+	}
+
+	row := make(sql.Row, i.leftSize+i.rightSize)
+	copy(row, left)
+	copy(row[i.leftSize:], right)
+	return row
+}
+
+func (i *hashJoinIter) disposeCaches() {
+	for _, dispose := range i.dispose {
+		dispose()
+	}
+	i.dispose = nil
+}
+
+func (i *hashJoinIter) closeIters() error {
+	var err error
+	for _, iter := range []sql.RowIter{i.leftIter, i.rightIter} {
+		if iter == nil {
+			continue
+		}
+
+		if e := iter.Close(); e != nil && err == nil {
+			err = e
+		}
+	}
+
+	i.leftIter, i.rightIter = nil, nil
+	return err
+}
+
+func (i *hashJoinIter) Close() error {
+	i.disposeCaches()
+	i.table = nil
+	i.matched = nil
+
+	if i.spilled != nil {
+		_ = i.spilled.Close()
+	}
+
+	for _, p := range i.partitions {
+		p.dispose()
+	}
+	disposeSpools(i.spools)
+
+	return i.closeIters()
+}
@@ -0,0 +1,320 @@
+package plan
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/src-d/go-mysql-server/memory"
+	"github.com/src-d/go-mysql-server/sql"
+	"github.com/src-d/go-mysql-server/sql/expression"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHashJoin(t *testing.T) {
+	ltable := memory.NewTable("l", sql.Schema{
+		{Name: "a", Type: sql.Int64, Source: "l", Nullable: true},
+		{Name: "b", Type: sql.Text, Source: "l"},
+	})
+	lrows := []sql.Row{
+		sql.NewRow(int64(1), "a"),
+		sql.NewRow(int64(2), "b"),
+		sql.NewRow(int64(2), "y"),
+		sql.NewRow(int64(3), "c"),
+		sql.NewRow(nil, "d"),
+	}
+
+	rtable := memory.NewTable("r", sql.Schema{
+		{Name: "c", Type: sql.Int32, Source: "r", Nullable: true},
+		{Name: "d", Type: sql.Text, Source: "r"},
+	})
+	rrows := []sql.Row{
+		sql.NewRow(int32(2), "x"),
+		sql.NewRow(int32(3), "y"),
+		sql.NewRow(int32(3), "z"),
+		sql.NewRow(int32(4), "w"),
+		sql.NewRow(nil, "v"),
+	}
+
+	for _, row := range lrows {
+		require.NoError(t, ltable.Insert(sql.NewEmptyContext(), row))
+	}
+
+	// The right side has more rows, so the hash table is built with the
+	// left side.
+	for _, row := range append(rrows, sql.NewRow(int32(5), "u")) {
+		require.NoError(t, rtable.Insert(sql.NewEmptyContext(), row))
+	}
+
+	equals := expression.NewEquals(
+		expression.NewGetFieldWithTable(0, sql.Int64, "l", "a", true),
+		expression.NewGetFieldWithTable(2, sql.Int32, "r", "c", true),
+	)
+
+	conds := map[string]sql.Expression{
+		"equality": equals,
+		"residual": expression.NewAnd(
+			equals,
+			expression.NewLessThan(
+				expression.NewGetFieldWithTable(1, sql.Text, "l", "b", false),
+				expression.NewGetFieldWithTable(3, sql.Text, "r", "d", false),
+			),
+		),
+	}
+
+	types := []JoinType{JoinTypeInner, JoinTypeLeft, JoinTypeRight, JoinTypeSemi, JoinTypeAnti}
+	sides := map[string][2]sql.Node{
+		"build left":  {NewResolvedTable(ltable), NewResolvedTable(rtable)},
+		"build right": {NewResolvedTable(rtable), NewResolvedTable(ltable)},
+	}
+
+	for sideName, side := range sides {
+		left, right := side[0], side[1]
+		for condName, cond := range conds {
+			if sideName == "build right" {
+				// The tables are swapped, so the fields of the condition are
+				// too.
+				cond = swapJoinSides(t, cond, 2)
+			}
+
+			for _, typ := range types {
+				t.Run(sideName+"/"+condName+"/"+typ.String(), func(t *testing.T) {
+					require := require.New(t)
+					require.True(CanHashJoin(left, cond))
+
+					expected := collectRows(t, newJoin(typ, left, right, cond))
+					require.NotEmpty(expected)
+
+					rows := collectRows(t, NewHashJoin(typ, left, right, cond))
+					require.ElementsMatch(expected, rows)
+
+					for _, build := range []BuildSide{BuildLeft, BuildRight} {
+						join := NewHashJoin(typ, left, right, cond).WithBuildSide(build)
+						require.ElementsMatch(expected, collectRows(t, join), "build %s", build)
+					}
+
+					// Without available memory, all rows are spilled to disk.
+					ctx := sql.NewContext(context.TODO(), sql.WithMemoryManager(
+						sql.NewMemoryManager(mockReporter{2, 1}),
+					))
+					iter, err := NewHashJoin(typ, left, right, cond).RowIter(ctx)
+					require.NoError(err)
+					rows, err = sql.RowIterToRows(iter)
+					require.NoError(err)
+					require.ElementsMatch(expected, rows)
+				})
+			}
+		}
+	}
+}
+
+func TestHashJoinSpill(t *testing.T) {
+	left := NewResolvedTable(spillTable(t, "l", 300))
+	right := NewResolvedTable(spillTable(t, "r", 200))
+	cond := expression.NewEquals(
+		expression.NewGetFieldWithTable(0, sql.Int64, "l", "i", true),
+		expression.NewGetFieldWithTable(2, sql.Int64, "r", "i", true),
+	)
+
+	types := []JoinType{
+		JoinTypeInner,
+		JoinTypeLeft,
+		JoinTypeRight,
+		JoinTypeSemi,
+		JoinTypeAnti,
+		JoinTypeNullAwareAnti,
+	}
+
+	for _, typ := range types {
+		t.Run(typ.String(), func(t *testing.T) {
+			require := require.New(t)
+
+			expected := collectRows(t, newJoin(typ, left, right, cond))
+
+			ctx, dir := newSpillContext(t, 7)
+			defer os.RemoveAll(dir)
+
+			rows, err := sql.NodeToRows(ctx, NewHashJoin(typ, left, right, cond))
+			require.NoError(err)
+			require.ElementsMatch(expected, rows)
+			requireNoSpools(t, dir)
+
+			for _, build := range []BuildSide{BuildLeft, BuildRight} {
+				join := NewHashJoin(typ, left, right, cond).WithBuildSide(build)
+				rows, err = sql.NodeToRows(ctx, join)
+				require.NoError(err)
+				require.ElementsMatch(expected, rows, "build %s", build)
+				requireNoSpools(t, dir)
+			}
+
+			// Once the rows can't be partitioned anymore, they are joined
+			// reading them from disk.
+			l, err := left.RowIter(ctx)
+			require.NoError(err)
+			r, err := right.RowIter(ctx)
+			require.NoError(err)
+
+			rows, err = sql.RowIterToRows(&hashJoinIter{
+				ctx:       ctx,
+				join:      NewHashJoin(typ, left, right, cond),
+				leftIter:  l,
+				rightIter: r,
+				leftSize:  2,
+				rightSize: 2,
+				level:     maxPartitionLevel,
+			})
+			require.NoError(err)
+			require.ElementsMatch(expected, rows)
+			requireNoSpools(t, dir)
+		})
+	}
+}
+
+func TestHashJoinEmpty(t *testing.T) {
+	require := require.New(t)
+
+	ltable := memory.NewTable("left", lSchema)
+	rtable := memory.NewTable("right", rSchema)
+	insertData(t, rtable)
+
+	cond := expression.NewEquals(
+		expression.NewGetField(0, sql.Text, "lcol1", false),
+		expression.NewGetField(4, sql.Text, "rcol1", false),
+	)
+
+	rows := collectRows(t, NewHashJoin(JoinTypeInner, NewResolvedTable(ltable), NewResolvedTable(rtable), cond))
+	require.Len(rows, 0)
+
+	rows = collectRows(t, NewHashJoin(JoinTypeRight, NewResolvedTable(ltable), NewResolvedTable(rtable), cond))
+	require.Equal([]sql.Row{
+		{nil, nil, nil, nil, "col1_1", "col2_1", int32(1), int64(2)},
+		{nil, nil, nil, nil, "col1_2", "col2_2", int32(3), int64(4)},
+	}, rows)
+}
+
+func TestCanHashJoin(t *testing.T) {
+	left := NewResolvedTable(memory.NewTable("left", lSchema))
+
+	testCases := []struct {
+		name string
+		cond sql.Expression
+		ok   bool
+	}{
+		{
+			"equality",
+			expression.NewEquals(
+				expression.NewGetField(2, sql.Int32, "lcol3", false),
+				expression.NewGetField(7, sql.Int64, "rcol4", false),
+			),
+			true,
+		},
+		{
+			"reversed equality",
+			expression.NewEquals(
+				expression.NewGetField(4, sql.Text, "rcol1", false),
+				expression.NewGetField(0, sql.Text, "lcol1", false),
+			),
+			true,
+		},
+		{
+			"equality with other predicates",
+			expression.NewAnd(
+				expression.NewGreaterThan(
+					expression.NewGetField(2, sql.Int32, "lcol3", false),
+					expression.NewGetField(6, sql.Int32, "rcol3", false),
+				),
+				expression.NewEquals(
+					expression.NewGetField(1, sql.Text, "lcol2", false),
+					expression.NewGetField(5, sql.Text, "rcol2", false),
+				),
+			),
+			true,
+		},
+		{
+			"no equality",
+			expression.NewGreaterThan(
+				expression.NewGetField(2, sql.Int32, "lcol3", false),
+				expression.NewGetField(6, sql.Int32, "rcol3", false),
+			),
+			false,
+		},
+		{
+			"equality on the same side",
+			expression.NewEquals(
+				expression.NewGetField(0, sql.Text, "lcol1", false),
+				expression.NewGetField(1, sql.Text, "lcol2", false),
+			),
+			false,
+		},
+		{
+			"equality using both sides",
+			expression.NewEquals(
+				expression.NewPlus(
+					expression.NewGetField(2, sql.Int32, "lcol3", false),
+					expression.NewGetField(6, sql.Int32, "rcol3", false),
+				),
+				expression.NewLiteral(int32(1), sql.Int32),
+			),
+			false,
+		},
+		{
+			"equality with conversion",
+			expression.NewEquals(
+				expression.NewGetField(0, sql.Text, "lcol1", false),
+				expression.NewGetField(6, sql.Int32, "rcol3", false),
+			),
+			false,
+		},
+		{
+			"disjunction",
+			expression.NewOr(
+				expression.NewEquals(
+					expression.NewGetField(0, sql.Text, "lcol1", false),
+					expression.NewGetField(4, sql.Text, "rcol1", false),
+				),
+				expression.NewEquals(
+					expression.NewGetField(1, sql.Text, "lcol2", false),
+					expression.NewGetField(5, sql.Text, "rcol2", false),
+				),
+			),
+			false,
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.ok, CanHashJoin(left, tt.cond))
+		})
+	}
+}
+
+func newJoin(typ JoinType, left, right sql.Node, cond sql.Expression) sql.Node {
+	switch typ {
+	case JoinTypeLeft:
+		return NewLeftJoin(left, right, cond)
+	case JoinTypeRight:
+		return NewRightJoin(left, right, cond)
+	case JoinTypeSemi:
+		return NewSemiJoin(left, right, cond)
+	case JoinTypeAnti, JoinTypeNullAwareAnti:
+		return NewAntiJoin(left, right, cond, typ == JoinTypeNullAwareAnti)
+	default:
+		return NewInnerJoin(left, right, cond)
+	}
+}
+
+// swapJoinSides changes the indexes of the fields in the given expression so
+// that it can be used with the sides of the join swapped, given the number of
+// columns in each side.
+func swapJoinSides(t *testing.T, e sql.Expression, size int) sql.Expression {
+	t.Helper()
+
+	e, err := expression.TransformUp(e, func(e sql.Expression) (sql.Expression, error) {
+		if gf, ok := e.(*expression.GetField); ok {
+			return gf.WithIndex((gf.Index() + size) % (2 * size)), nil
+		}
+		return e, nil
+	})
+	require.NoError(t, err)
+	return e
+}
@@ -8,6 +8,7 @@ import (
 
 	opentracing "github.com/opentracing/opentracing-go"
 	"github.com/src-d/go-mysql-server/sql"
+	"github.com/src-d/go-mysql-server/sql/expression"
 )
 
 const (
@@ -51,7 +52,7 @@ func (j *InnerJoin) Resolved() bool {
 
 // RowIter implements the Node interface.
 func (j *InnerJoin) RowIter(ctx *sql.Context) (sql.RowIter, error) {
-	return joinRowIter(ctx, innerJoin, j.Left, j.Right, j.Cond)
+	return joinRowIter(ctx, JoinTypeInner, j.Left, j.Right, j.Cond)
 }
 
 // WithChildren implements the Node interface.
@@ -113,7 +114,7 @@ func (j *LeftJoin) Resolved() bool {
 
 // RowIter implements the Node interface.
 func (j *LeftJoin) RowIter(ctx *sql.Context) (sql.RowIter, error) {
-	return joinRowIter(ctx, leftJoin, j.Left, j.Right, j.Cond)
+	return joinRowIter(ctx, JoinTypeLeft, j.Left, j.Right, j.Cond)
 }
 
 // WithChildren implements the Node interface.
@@ -175,7 +176,7 @@ func (j *RightJoin) Resolved() bool {
 
 // RowIter implements the Node interface.
 func (j *RightJoin) RowIter(ctx *sql.Context) (sql.RowIter, error) {
-	return joinRowIter(ctx, rightJoin, j.Left, j.Right, j.Cond)
+	return joinRowIter(ctx, JoinTypeRight, j.Left, j.Right, j.Cond)
 }
 
 // WithChildren implements the Node interface.
@@ -208,30 +209,87 @@ func (j *RightJoin) Expressions() []sql.Expression {
 	return []sql.Expression{j.Cond}
 }
 
-type joinType byte
+// JoinType is the type of a join, which defines what happens with the rows
+// that have no match on the other side.
+type JoinType byte
 
 const (
-	innerJoin joinType = iota
-	leftJoin
-	rightJoin
+	// JoinTypeInner only returns the rows that match on both sides.
+	JoinTypeInner JoinType = iota
+	// JoinTypeLeft returns all the rows on the left side, with nulls on the
+	// right side when they don't match.
+	JoinTypeLeft
+	// JoinTypeRight returns all the rows on the right side, with nulls on the
+	// left side when they don't match.
+	JoinTypeRight
+	// JoinTypeSemi returns the rows on the left side that match any row on
+	// the right side, only with the columns of the left side.
+	JoinTypeSemi
+	// JoinTypeAnti returns the rows on the left side that don't match any row
+	// on the right side, only with the columns of the left side.
+	JoinTypeAnti
+	// JoinTypeNullAwareAnti is an anti join in which a condition whose
+	// result is unknown counts as a match.
+	JoinTypeNullAwareAnti
 )
 
-func (t joinType) String() string {
+func (t JoinType) String() string {
 	switch t {
-	case innerJoin:
+	case JoinTypeInner:
 		return "InnerJoin"
-	case leftJoin:
+	case JoinTypeLeft:
 		return "LeftJoin"
-	case rightJoin:
+	case JoinTypeRight:
 		return "RightJoin"
+	case JoinTypeSemi:
+		return "SemiJoin"
+	case JoinTypeAnti:
+		return "AntiJoin"
+	case JoinTypeNullAwareAnti:
+		return "NullAwareAntiJoin"
 	default:
 		return "INVALID"
 	}
 }
 
+// returnsLeftOnly reports whether the join only returns the rows of the left
+// side, which happens with semi and anti joins.
+func (t JoinType) returnsLeftOnly() bool {
+	return t == JoinTypeSemi || t.isAnti()
+}
+
+func (t JoinType) isAnti() bool {
+	return t == JoinTypeAnti || t == JoinTypeNullAwareAnti
+}
+
+// evaluateJoinCondition reports whether the given joined row matches the
+// condition of a join of the given type. In null-aware anti joins, rows for
+// which the condition is unknown are also considered a match.
+func evaluateJoinCondition(
+	ctx *sql.Context,
+	typ JoinType,
+	cond sql.Expression,
+	row sql.Row,
+) (bool, error) {
+	if typ != JoinTypeNullAwareAnti {
+		return sql.EvaluateCondition(ctx, cond, row)
+	}
+
+	v, err := cond.Eval(ctx, row)
+	if err != nil {
+		return false, err
+	}
+
+	if v == nil {
+		return true, nil
+	}
+
+	return sql.EvaluateCondition(ctx, expression.NewLiteral(v, cond.Type()), row)
+}
+
 func joinRowIter(
 	ctx *sql.Context,
-	typ joinType,
+	typ JoinType,
 	left, right sql.Node,
 	cond sql.Expression,
 ) (sql.RowIter, error) {
@@ -264,8 +322,12 @@ func joinRowIter(
 		mode = memoryMode
 	}
 
+	// The size of the rows is needed beforehand, because the first row may
+	// not have a match.
+	rowSize := len(left.Schema()) + len(right.Schema())
+
 	cache, dispose := ctx.Memory.NewRowsCache()
-	if typ == rightJoin {
+	if typ == JoinTypeRight {
 		r, err := right.RowIter(ctx)
 		if err != nil {
 			span.Finish()
@@ -277,6 +339,7 @@ func joinRowIter(
 			secondaryProvider: left,
 			ctx:               ctx,
 			cond:              cond,
+			rowSize:           rowSize,
 			mode:              mode,
 			secondaryRows:     cache,
 			dispose:           dispose,
@@ -295,6 +358,7 @@ func joinRowIter(
 		secondaryProvider: right,
 		ctx:               ctx,
 		cond:              cond,
+		rowSize:           rowSize,
 		mode:              mode,
 		secondaryRows:     cache,
 		dispose:           dispose,
@@ -322,7 +386,7 @@ const (
 
 // joinIter is a generic iterator for all join types.
 type joinIter struct {
-	typ               joinType
+	typ               JoinType
 	primary           sql.RowIter
 	secondaryProvider rowIterProvider
 	secondary         sql.RowIter
@@ -338,6 +402,10 @@ type joinIter struct {
 	secondaryRows sql.RowsCache
 	pos           int
 	dispose       sql.DisposeFunc
+
+	// spool stores the secondary rows on disk when they have to be in memory
+	// but don't fit.
+	spool *sql.RowSpool
 }
 
 func (i *joinIter) Dispose() {
@@ -373,6 +441,9 @@ func (i *joinIter) loadSecondaryInMemory() error {
 	for {
 		row, err := iter.Next()
 		if err == io.EOF {
+			if err := iter.Close(); err != nil {
+				return err
+			}
 			break
 		}
 		if err != nil {
@@ -380,7 +451,11 @@ func (i *joinIter) loadSecondaryInMemory() error {
 		}
 
 		if err := i.secondaryRows.Add(row); err != nil {
-			return err
+			if !sql.ErrNoMemoryAvailable.Is(err) {
+				return err
+			}
+
+			return i.spillSecondary(append(i.secondaryRows.Get(), row), iter)
 		}
 	}
 
@@ -391,14 +466,49 @@ func (i *joinIter) loadSecondaryInMemory() error {
 	return nil
 }
 
+// spillSecondary stores on disk the secondary rows read so far and the rest
+// of them, which are read from there from now on.
+func (i *joinIter) spillSecondary(rows []sql.Row, iter sql.RowIter) error {
+	i.Dispose()
+	i.secondaryRows = nil
+
+	spool, err := i.ctx.Memory.NewRowSpool()
+	if err != nil {
+		return err
+	}
+	i.spool = spool
+
+	for _, row := range rows {
+		if err := spool.Add(row); err != nil {
+			return err
+		}
+	}
+
+	if err := addAllRows(iter, spool); err != nil {
+		return err
+	}
+
+	if err := iter.Close(); err != nil {
+		return err
+	}
+
+	i.secondaryProvider = spoolIterProvider{spool}
+	i.mode = multipassMode
+	return nil
+}
+
 func (i *joinIter) loadSecondary() (row sql.Row, err error) {
-	if i.mode == memoryMode {
-		if len(i.secondaryRows.Get()) == 0 {
-			if err = i.loadSecondaryInMemory(); err != nil {
-				return nil, err
+	if i.mode == memoryMode && len(i.secondaryRows.Get()) == 0 {
+		if err = i.loadSecondaryInMemory(); err != nil {
+			// There are no secondary rows, so this primary row is done.
+			if err == io.EOF {
+				i.primaryRow = nil
 			}
+			return nil, err
 		}
+	}
 
+	if i.mode == memoryMode {
 		if i.pos >= len(i.secondaryRows.Get()) {
 			i.primaryRow = nil
 			i.pos = 0
@@ -423,6 +533,10 @@ func (i *joinIter) loadSecondary() (row sql.Row, err error) {
 	rightRow, err := i.secondary.Next()
 	if err != nil {
 		if err == io.EOF {
+			if err := i.secondary.Close(); err != nil {
+				return nil, err
+			}
+
 			i.secondary = nil
 			i.primaryRow = nil
 
@@ -469,26 +583,47 @@ func (i *joinIter) Next() (sql.Row, error) {
 		secondary, err := i.loadSecondary()
 		if err != nil {
 			if err == io.EOF {
-				if !i.foundMatch && (i.typ == leftJoin || i.typ == rightJoin) {
+				if !i.foundMatch && (i.typ == JoinTypeLeft || i.typ == JoinTypeRight) {
 					return i.buildRow(primary, nil), nil
 				}
+
+				if !i.foundMatch && i.typ.isAnti() {
+					return primary, nil
+				}
 				continue
 			}
 			return nil, err
 		}
 
+		// Once a row of a semi or anti join has a match there is nothing
+		// else to do with it, but the secondary rows still need to be
+		// consumed.
+		if i.foundMatch && i.typ.returnsLeftOnly() {
+			continue
+		}
+
 		row := i.buildRow(primary, secondary)
-		v, err := i.cond.Eval(i.ctx, row)
+		matches, err := evaluateJoinCondition(i.ctx, i.typ, i.cond, row)
 		if err != nil {
 			return nil, err
 		}
 
-		if v == false {
+		if !matches {
 			continue
 		}
 
 		i.foundMatch = true
-		return row, nil
+		if !i.typ.returnsLeftOnly() {
+			return row, nil
+		}
+
+		if i.mode == memoryMode {
+			i.pos = len(i.secondaryRows.Get())
+		}
+
+		if i.typ == JoinTypeSemi {
+			return primary, nil
+		}
 	}
 }
 
@@ -504,7 +639,7 @@ func (i *joinIter) buildRow(primary, secondary sql.Row) sql.Row {
 	}
 
 	switch i.typ {
-	case rightJoin:
+	case JoinTypeRight:
 		copy(row, secondary)
 		copy(row[i.rowSize-len(primary):], primary)
 	default:
@@ -517,7 +652,9 @@ func (i *joinIter) buildRow(primary, secondary sql.Row) sql.Row {
 
 func (i *joinIter) Close() (err error) {
 	i.Dispose()
-	i.secondary = nil
+	if i.spool != nil {
+		i.spool.Dispose()
+	}
 
 	if i.primary != nil {
 		if err = i.primary.Close(); err != nil {
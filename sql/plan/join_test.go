@@ -3,6 +3,7 @@ package plan
 import (
 	"context"
 	"fmt"
+	"os"
 	"testing"
 
 	"github.com/src-d/go-mysql-server/memory"
@@ -68,6 +69,35 @@ func TestMultiPassInnerJoin(t *testing.T) {
 	testInnerJoin(t, ctx)
 }
 
+func TestInMemoryJoinSpill(t *testing.T) {
+	left := NewResolvedTable(spillTable(t, "l", 100))
+	right := NewResolvedTable(spillTable(t, "r", 200))
+	cond := expression.NewEquals(
+		expression.NewGetFieldWithTable(0, sql.Int64, "l", "i", true),
+		expression.NewGetFieldWithTable(2, sql.Int64, "r", "i", true),
+	)
+
+	for _, typ := range []JoinType{JoinTypeInner, JoinTypeLeft, JoinTypeRight} {
+		t.Run(typ.String(), func(t *testing.T) {
+			require := require.New(t)
+
+			j := newJoin(typ, left, right, cond)
+			expected := collectRows(t, j)
+
+			// The secondary rows don't fit in memory, so they are read from
+			// disk instead.
+			ctx, dir := newSpillContext(t, 50)
+			defer os.RemoveAll(dir)
+			ctx.Set(inMemoryJoinSessionVar, sql.Text, "true")
+
+			rows, err := sql.NodeToRows(ctx, j)
+			require.NoError(err)
+			require.ElementsMatch(expected, rows)
+			requireNoSpools(t, dir)
+		})
+	}
+}
+
 func testInnerJoin(t *testing.T, ctx *sql.Context) {
 	t.Helper()
 
@@ -0,0 +1,173 @@
+package plan
+
+import (
+	"fmt"
+	"strings"
+
+	opentracing "github.com/opentracing/opentracing-go"
+	"github.com/src-d/go-mysql-server/sql"
+)
+
+// PartialGroupBy groups the rows of its child like GroupBy, but instead of
+// the results of the aggregations it returns their buffers, which are
+// partial results that can be merged with the ones of other rows. Its rows
+// have the values of the grouping expressions followed by the elements of
+// the buffers, like the ones of tables with aggregations pushed down.
+type PartialGroupBy struct {
+	UnaryNode
+	Grouping     []sql.Expression
+	Aggregations []sql.Aggregation
+}
+
+// NewPartialGroupBy creates a new PartialGroupBy node.
+func NewPartialGroupBy(
+	grouping []sql.Expression,
+	aggregations []sql.Aggregation,
+	child sql.Node,
+) *PartialGroupBy {
+	return &PartialGroupBy{
+		UnaryNode:    UnaryNode{Child: child},
+		Grouping:     grouping,
+		Aggregations: aggregations,
+	}
+}
+
+// Resolved implements the Resolvable interface.
+func (p *PartialGroupBy) Resolved() bool {
+	if !p.Child.Resolved() || !expressionsResolved(p.Grouping...) {
+		return false
+	}
+
+	for _, a := range p.Aggregations {
+		if !a.Resolved() {
+			return false
+		}
+	}
+	return true
+}
+
+// Schema implements the Node interface.
+func (p *PartialGroupBy) Schema() sql.Schema {
+	return sql.PartialAggregationSchema("", p.Grouping, p.Aggregations)
+}
+
+// RowIter implements the Node interface.
+func (p *PartialGroupBy) RowIter(ctx *sql.Context) (sql.RowIter, error) {
+	span, ctx := ctx.Span("plan.PartialGroupBy", opentracing.Tags{
+		"groupings":    len(p.Grouping),
+		"aggregations": len(p.Aggregations),
+	})
+
+	i, err := p.Child.RowIter(ctx)
+	if err != nil {
+		span.Finish()
+		return nil, err
+	}
+
+	// The values of the grouping expressions are returned along with the
+	// buffers, which are flattened afterwards.
+	var aggregate = make([]sql.Expression, 0, len(p.Grouping)+len(p.Aggregations))
+	aggregate = append(aggregate, p.Grouping...)
+	for _, a := range p.Aggregations {
+		aggregate = append(aggregate, partialAggregation{a})
+	}
+
+	var iter sql.RowIter
+	if len(p.Grouping) == 0 {
+		iter = newGroupByIter(ctx, aggregate, i)
+	} else {
+		iter = newGroupByGroupingIter(ctx, aggregate, p.Grouping, i)
+	}
+
+	return sql.NewSpanIter(span, &partialGroupByIter{iter, len(p.Grouping)}), nil
+}
+
+// WithChildren implements the Node interface.
+func (p *PartialGroupBy) WithChildren(children ...sql.Node) (sql.Node, error) {
+	if len(children) != 1 {
+		return nil, sql.ErrInvalidChildrenNumber.New(p, len(children), 1)
+	}
+
+	return NewPartialGroupBy(p.Grouping, p.Aggregations, children[0]), nil
+}
+
+// WithExpressions implements the Expressioner interface.
+func (p *PartialGroupBy) WithExpressions(exprs ...sql.Expression) (sql.Node, error) {
+	expected := len(p.Grouping) + len(p.Aggregations)
+	if len(exprs) != expected {
+		return nil, sql.ErrInvalidChildrenNumber.New(p, len(exprs), expected)
+	}
+
+	var aggregations = make([]sql.Aggregation, len(p.Aggregations))
+	for i, e := range exprs[len(p.Grouping):] {
+		a, ok := e.(sql.Aggregation)
+		if !ok {
+			return nil, ErrGroupBy.New(e)
+		}
+		aggregations[i] = a
+	}
+
+	return NewPartialGroupBy(exprs[:len(p.Grouping)], aggregations, p.Child), nil
+}
+
+// Expressions implements the Expressioner interface.
+func (p *PartialGroupBy) Expressions() []sql.Expression {
+	var exprs []sql.Expression
+	exprs = append(exprs, p.Grouping...)
+	for _, a := range p.Aggregations {
+		exprs = append(exprs, a)
+	}
+	return exprs
+}
+
+func (p *PartialGroupBy) String() string {
+	pr := sql.NewTreePrinter()
+	_ = pr.WriteNode("PartialGroupBy")
+
+	var aggregations = make([]string, len(p.Aggregations))
+	for i, a := range p.Aggregations {
+		aggregations[i] = a.String()
+	}
+
+	var grouping = make([]string, len(p.Grouping))
+	for i, g := range p.Grouping {
+		grouping[i] = g.String()
+	}
+
+	_ = pr.WriteChildren(
+		fmt.Sprintf("Aggregations(%s)", strings.Join(aggregations, ", ")),
+		fmt.Sprintf("Grouping(%s)", strings.Join(grouping, ", ")),
+		p.Child.String(),
+	)
+	return pr.String()
+}
+
+// partialAggregation is an aggregation whose result is its buffer.
+type partialAggregation struct {
+	sql.Aggregation
+}
+
+func (a partialAggregation) Eval(ctx *sql.Context, buffer sql.Row) (interface{}, error) {
+	return buffer, nil
+}
+
+// partialGroupByIter flattens the buffers in the rows of an iterator of
+// groups whose results are partial aggregations.
+type partialGroupByIter struct {
+	sql.RowIter
+	grouping int
+}
+
+func (i *partialGroupByIter) Next() (sql.Row, error) {
+	row, err := i.RowIter.Next()
+	if err != nil {
+		return nil, err
+	}
+
+	var result = make(sql.Row, i.grouping, len(row))
+	copy(result, row[:i.grouping])
+	for _, buffer := range row[i.grouping:] {
+		result = append(result, buffer.(sql.Row)...)
+	}
+	return result, nil
+}
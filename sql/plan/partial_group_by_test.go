@@ -0,0 +1,80 @@
+package plan
+
+import (
+	"testing"
+
+	"github.com/src-d/go-mysql-server/memory"
+	"github.com/src-d/go-mysql-server/sql"
+	"github.com/src-d/go-mysql-server/sql/expression"
+	"github.com/src-d/go-mysql-server/sql/expression/function/aggregation"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPartialGroupBy(t *testing.T) {
+	require := require.New(t)
+	ctx := sql.NewEmptyContext()
+
+	child := memory.NewTable("test", sql.Schema{
+		{Name: "col1", Type: sql.Text, Source: "test"},
+		{Name: "col2", Type: sql.Int64, Source: "test", Nullable: true},
+	})
+
+	for _, r := range []sql.Row{
+		sql.NewRow("a", int64(1)),
+		sql.NewRow("b", int64(2)),
+		sql.NewRow("a", int64(3)),
+		sql.NewRow("b", nil),
+	} {
+		require.NoError(child.Insert(ctx, r))
+	}
+
+	col1 := expression.NewGetFieldWithTable(0, sql.Text, "test", "col1", false)
+	col2 := expression.NewGetFieldWithTable(1, sql.Int64, "test", "col2", true)
+	count := aggregation.NewCount(col2)
+	avg := aggregation.NewAvg(col2)
+
+	p := NewPartialGroupBy(
+		[]sql.Expression{col1},
+		[]sql.Aggregation{count, avg},
+		NewResolvedTable(child),
+	)
+	require.True(p.Resolved())
+	require.Equal(sql.Schema{
+		{Name: "col1", Type: sql.Text},
+		{Name: "COUNT(test.col2)", Type: sql.Int64, Nullable: true},
+		{Name: "AVG(test.col2)[0]", Type: sql.Float64, Nullable: true},
+		{Name: "AVG(test.col2)[1]", Type: sql.Float64, Nullable: true},
+		{Name: "AVG(test.col2)[2]", Type: sql.Float64, Nullable: true},
+	}, p.Schema())
+
+	rows, err := sql.NodeToRows(ctx, p)
+	require.NoError(err)
+	require.ElementsMatch([]sql.Row{
+		{"a", int64(2), float64(4), int64(2), false},
+		{"b", int64(1), float64(2), int64(1), true},
+	}, rows)
+
+	// The partial results are merged by a group by of their rows.
+	merged := NewGroupBy(
+		[]sql.Expression{
+			expression.NewGetFieldWithTable(0, sql.Text, "", "col1", false),
+			expression.NewAlias(aggregation.NewMerge(count, 1), "c"),
+		},
+		[]sql.Expression{expression.NewGetFieldWithTable(0, sql.Text, "", "col1", false)},
+		NewPartialGroupBy(
+			[]sql.Expression{col1},
+			[]sql.Aggregation{count},
+			NewResolvedTable(child),
+		),
+	)
+
+	rows, err = sql.NodeToRows(ctx, merged)
+	require.NoError(err)
+	require.ElementsMatch([]sql.Row{{"a", int64(2)}, {"b", int64(1)}}, rows)
+
+	// Without grouping, there is a partial result even if there are no rows.
+	p = NewPartialGroupBy(nil, []sql.Aggregation{count}, NewResolvedTable(memory.NewTable("empty", nil)))
+	rows, err = sql.NodeToRows(ctx, p)
+	require.NoError(err)
+	require.Equal([]sql.Row{{int64(0)}}, rows)
+}
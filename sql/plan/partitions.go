@@ -0,0 +1,125 @@
+package plan
+
+import (
+	"io"
+
+	"github.com/src-d/go-mysql-server/sql"
+)
+
+const (
+	partitionBits = 4
+	numPartitions = 1 << partitionBits
+	// maxPartitionLevel is the number of times rows can be partitioned
+	// before running out of bits of their hash.
+	maxPartitionLevel = 64 / partitionBits
+)
+
+// spillPartitions stores on disk the rows that don't fit in memory,
+// partitioned by the hash of their key, so each partition can be processed
+// later in memory. Partitions that still don't fit in memory are partitioned
+// again at the next level, which uses different bits of the hash.
+type spillPartitions struct {
+	ctx    *sql.Context
+	level  int
+	spools []*sql.RowSpool
+	pos    int
+}
+
+func newSpillPartitions(ctx *sql.Context, level int) (*spillPartitions, error) {
+	if level >= maxPartitionLevel {
+		return nil, sql.ErrNoMemoryAvailable.New()
+	}
+
+	return &spillPartitions{
+		ctx:    ctx,
+		level:  level,
+		spools: make([]*sql.RowSpool, numPartitions),
+	}, nil
+}
+
+// add a row with the given hash to its partition.
+func (p *spillPartitions) add(hash uint64, row sql.Row) error {
+	idx := (hash >> uint(p.level*partitionBits)) % numPartitions
+	if p.spools[idx] == nil {
+		spool, err := p.ctx.Memory.NewRowSpool()
+		if err != nil {
+			return err
+		}
+		p.spools[idx] = spool
+	}
+
+	return p.spools[idx].Add(row)
+}
+
+// partition returns an iterator over the rows of the partition with the given
+// index, or nil if it's empty. The partition is removed when the iterator is
+// closed.
+func (p *spillPartitions) partition(idx int) (sql.RowIter, error) {
+	spool := p.spools[idx]
+	if spool == nil {
+		return nil, nil
+	}
+
+	iter, err := spool.RowIter()
+	if err != nil {
+		return nil, err
+	}
+
+	return &spillPartitionIter{iter, spool}, nil
+}
+
+// next returns an iterator over the rows of the next partition that is not
+// empty, or nil if there are no more partitions.
+func (p *spillPartitions) next() (sql.RowIter, error) {
+	for p.pos < len(p.spools) {
+		p.pos++
+		iter, err := p.partition(p.pos - 1)
+		if iter != nil || err != nil {
+			return iter, err
+		}
+	}
+
+	return nil, nil
+}
+
+func (p *spillPartitions) dispose() {
+	disposeSpools(p.spools)
+}
+
+type spillPartitionIter struct {
+	sql.RowIter
+	spool *sql.RowSpool
+}
+
+func (i *spillPartitionIter) Close() error {
+	defer i.spool.Dispose()
+	return i.RowIter.Close()
+}
+
+// spoolIterProvider provides iterators over the rows of a spool, so they can
+// be iterated as many times as needed.
+type spoolIterProvider struct {
+	spool *sql.RowSpool
+}
+
+func (p spoolIterProvider) RowIter(*sql.Context) (sql.RowIter, error) {
+	return p.spool.RowIter()
+}
+
+// addAllRows adds all the rows of the given iterator to the spool.
+func addAllRows(iter sql.RowIter, spool *sql.RowSpool) error {
+	for {
+		row, err := iter.Next()
+		if err == io.EOF {
+			return nil
+		}
+
+		if err != nil {
+			return err
+		}
+
+		if err := spool.Add(row); err != nil {
+			return err
+		}
+	}
+}
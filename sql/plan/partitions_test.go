@@ -0,0 +1,117 @@
+package plan
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/src-d/go-mysql-server/memory"
+	"github.com/src-d/go-mysql-server/sql"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSpillPartitions(t *testing.T) {
+	require := require.New(t)
+
+	ctx, dir := newSpillContext(t, 0)
+	defer os.RemoveAll(dir)
+
+	p, err := newSpillPartitions(ctx, 1)
+	require.NoError(err)
+
+	require.NoError(p.add(0x01, sql.NewRow(int64(1))))
+	require.NoError(p.add(0x10, sql.NewRow(int64(2))))
+	require.NoError(p.add(0x31, sql.NewRow(int64(3))))
+	require.NoError(p.add(0x12, sql.NewRow(int64(4))))
+
+	var partitions [][]sql.Row
+	for {
+		iter, err := p.next()
+		require.NoError(err)
+		if iter == nil {
+			break
+		}
+
+		rows, err := sql.RowIterToRows(iter)
+		require.NoError(err)
+		partitions = append(partitions, rows)
+	}
+
+	require.Equal([][]sql.Row{
+		{{int64(1)}},
+		{{int64(2)}, {int64(4)}},
+		{{int64(3)}},
+	}, partitions)
+
+	p.dispose()
+	requireNoSpools(t, dir)
+
+	_, err = newSpillPartitions(ctx, maxPartitionLevel)
+	require.True(sql.ErrNoMemoryAvailable.Is(err))
+}
+
+// spillReporter reports that there is no memory available in two
+// consecutive checks out of every given number of checks, which is enough to
+// make caches fail to store a row every once in a while.
+type spillReporter struct {
+	every  int
+	checks int
+}
+
+func (r *spillReporter) UsedMemory() uint64 {
+	r.checks++
+	if r.every > 0 && r.checks%r.every >= r.every-2 {
+		return 2
+	}
+	return 0
+}
+
+func (r *spillReporter) MaxMemory() uint64 { return 1 }
+
+// newSpillContext returns a context whose memory runs out once every given
+// number of checks, along with the directory where rows are spilled.
+func newSpillContext(t *testing.T, every int) (*sql.Context, string) {
+	t.Helper()
+
+	dir, err := ioutil.TempDir("", "spill")
+	require.NoError(t, err)
+
+	m := sql.NewMemoryManager(&spillReporter{every: every})
+	m.SetTempDir(dir)
+
+	return sql.NewContext(context.TODO(), sql.WithMemoryManager(m)), dir
+}
+
+// requireNoSpools checks that all the spools have been removed.
+func requireNoSpools(t *testing.T, dir string) {
+	t.Helper()
+
+	files, err := ioutil.ReadDir(dir)
+	require.NoError(t, err)
+	require.Len(t, files, 0)
+}
+
+// spillTable returns a table with the given number of rows with an integer
+// column with few distinct values and a unique text column.
+func spillTable(t *testing.T, name string, rows int) *memory.Table {
+	t.Helper()
+
+	table := memory.NewTable(name, sql.Schema{
+		{Name: "i", Type: sql.Int64, Source: name, Nullable: true},
+		{Name: "s", Type: sql.Text, Source: name},
+	})
+
+	for i := 0; i < rows; i++ {
+		var v interface{} = int64((i * 7) % 37)
+		if i%50 == 0 {
+			v = nil
+		}
+
+		row := sql.NewRow(v, fmt.Sprintf("%s_%d", name, i))
+		require.NoError(t, table.Insert(sql.NewEmptyContext(), row))
+	}
+
+	return table
+}
@@ -0,0 +1,142 @@
+package plan
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/src-d/go-mysql-server/sql"
+	errors "gopkg.in/src-d/go-errors.v1"
+)
+
+// ErrUnresolvedPivot is returned when a PIVOT or UNPIVOT is executed before
+// being resolved by the analyzer.
+var ErrUnresolvedPivot = errors.NewKind("unresolved %s")
+
+// Pivot turns the values of a column of its child into columns, such as
+// `sales PIVOT (SUM(amount) FOR month IN ('jan', 'feb'))`. The rows are
+// grouped by the columns of the child that are not used in the aggregations
+// or the pivoted column, and there is a column for each of the values and
+// aggregations with the aggregation of the rows with that value. The
+// analyzer resolves it into a GroupBy once its child is resolved.
+type Pivot struct {
+	UnaryNode
+	Aggregates []sql.Expression
+	Column     sql.Expression
+	Values     []sql.Expression
+}
+
+// NewPivot creates a new Pivot node.
+func NewPivot(aggregates []sql.Expression, column sql.Expression, values []sql.Expression, child sql.Node) *Pivot {
+	return &Pivot{UnaryNode{child}, aggregates, column, values}
+}
+
+// Resolved implements the Resolvable interface.
+func (*Pivot) Resolved() bool { return false }
+
+// Schema implements the Node interface.
+func (*Pivot) Schema() sql.Schema { return nil }
+
+// RowIter implements the Node interface.
+func (*Pivot) RowIter(ctx *sql.Context) (sql.RowIter, error) {
+	return nil, ErrUnresolvedPivot.New("PIVOT")
+}
+
+// WithChildren implements the Node interface.
+func (p *Pivot) WithChildren(children ...sql.Node) (sql.Node, error) {
+	if len(children) != 1 {
+		return nil, sql.ErrInvalidChildrenNumber.New(p, len(children), 1)
+	}
+
+	return NewPivot(p.Aggregates, p.Column, p.Values, children[0]), nil
+}
+
+func (p *Pivot) String() string {
+	pr := sql.NewTreePrinter()
+	_ = pr.WriteNode(
+		"Pivot(%s FOR %s IN (%s))",
+		exprsString(p.Aggregates),
+		p.Column,
+		exprsString(p.Values),
+	)
+	_ = pr.WriteChildren(p.Child.String())
+	return pr.String()
+}
+
+// Unpivot turns columns of its child into rows, such as
+// `sales UNPIVOT (amount FOR month IN (jan, feb))`. There is a row for each
+// row of the child and each of the columns, with the rest of the columns of
+// the child, the label of the column in the name column and its value in
+// the value column. Rows whose value is null are skipped unless nulls are
+// included. The analyzer resolves it into a Generate once its child is
+// resolved.
+type Unpivot struct {
+	UnaryNode
+	ValueColumn  string
+	NameColumn   string
+	Columns      []sql.Expression
+	Labels       []string
+	IncludeNulls bool
+}
+
+// NewUnpivot creates a new Unpivot node. There must be a label for each of
+// the columns.
+func NewUnpivot(
+	valueColumn, nameColumn string,
+	columns []sql.Expression,
+	labels []string,
+	includeNulls bool,
+	child sql.Node,
+) *Unpivot {
+	return &Unpivot{UnaryNode{child}, valueColumn, nameColumn, columns, labels, includeNulls}
+}
+
+// Resolved implements the Resolvable interface.
+func (*Unpivot) Resolved() bool { return false }
+
+// Schema implements the Node interface.
+func (*Unpivot) Schema() sql.Schema { return nil }
+
+// RowIter implements the Node interface.
+func (*Unpivot) RowIter(ctx *sql.Context) (sql.RowIter, error) {
+	return nil, ErrUnresolvedPivot.New("UNPIVOT")
+}
+
+// WithChildren implements the Node interface.
+func (u *Unpivot) WithChildren(children ...sql.Node) (sql.Node, error) {
+	if len(children) != 1 {
+		return nil, sql.ErrInvalidChildrenNumber.New(u, len(children), 1)
+	}
+
+	return NewUnpivot(u.ValueColumn, u.NameColumn, u.Columns, u.Labels, u.IncludeNulls, children[0]), nil
+}
+
+func (u *Unpivot) String() string {
+	var columns = make([]string, len(u.Columns))
+	for i, col := range u.Columns {
+		columns[i] = fmt.Sprintf("%s AS %q", col, u.Labels[i])
+	}
+
+	nulls := "EXCLUDE"
+	if u.IncludeNulls {
+		nulls = "INCLUDE"
+	}
+
+	pr := sql.NewTreePrinter()
+	_ = pr.WriteNode(
+		"Unpivot %s NULLS(%s FOR %s IN (%s))",
+		nulls,
+		u.ValueColumn,
+		u.NameColumn,
+		strings.Join(columns, ", "),
+	)
+	_ = pr.WriteChildren(u.Child.String())
+	return pr.String()
+}
+
+func exprsString(exprs []sql.Expression) string {
+	var strs = make([]string, len(exprs))
+	for i, e := range exprs {
+		strs[i] = e.String()
+	}
+	return strings.Join(strs, ", ")
+}
@@ -0,0 +1,359 @@
+package plan
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/src-d/go-mysql-server/sql"
+	errors "gopkg.in/src-d/go-errors.v1"
+)
+
+// CteMaxRecursionDepthKey is the session variable with the maximum number of
+// iterations a recursive common table expression can perform.
+const CteMaxRecursionDepthKey = "cte_max_recursion_depth"
+
+// ErrCteMaxRecursionDepth is returned when a recursive common table
+// expression performs more iterations than allowed.
+var ErrCteMaxRecursionDepth = errors.NewKind(
+	"recursive query aborted after %d iterations, try increasing @@" +
+		CteMaxRecursionDepthKey + " to a larger value",
+)
+
+// RecursiveCte is a node that evaluates a recursive common table expression.
+// The rows of the left child (the anchor) are returned first and then the
+// right child (the recursive part) is evaluated over and over again, reading
+// the rows returned in the previous iteration from the recursive table, until
+// it does not return any new row.
+type RecursiveCte struct {
+	BinaryNode
+	name string
+	// Columns are the optional names given to the columns of the expression.
+	Columns []string
+	// Distinct is true if rows that have already been returned must not be
+	// returned again, as in UNION DISTINCT.
+	Distinct bool
+	table    *RecursiveTable
+}
+
+// NewRecursiveCte creates a new RecursiveCte node with the given anchor and
+// recursive part.
+func NewRecursiveCte(
+	name string,
+	columns []string,
+	distinct bool,
+	anchor, recursive sql.Node,
+) *RecursiveCte {
+	return &RecursiveCte{
+		BinaryNode: BinaryNode{Left: anchor, Right: recursive},
+		name:       name,
+		Columns:    columns,
+		Distinct:   distinct,
+	}
+}
+
+// Name implements the sql.Nameable interface.
+func (r *RecursiveCte) Name() string { return r.name }
+
+// Table returns the table the recursive part reads the rows of the previous
+// iteration from.
+func (r *RecursiveCte) Table() *RecursiveTable { return r.table }
+
+// WithTable returns a copy of the node with the given recursive table.
+func (r *RecursiveCte) WithTable(table *RecursiveTable) *RecursiveCte {
+	nr := *r
+	nr.table = table
+	return &nr
+}
+
+// Resolved implements the Resolvable interface.
+func (r *RecursiveCte) Resolved() bool {
+	return r.table != nil && r.BinaryNode.Resolved()
+}
+
+// Opaque implements the OpaqueNode interface.
+func (r *RecursiveCte) Opaque() bool {
+	return true
+}
+
+// Schema implements the Node interface.
+func (r *RecursiveCte) Schema() sql.Schema {
+	if r.table != nil {
+		return r.table.Schema()
+	}
+	return r.Left.Schema()
+}
+
+// RowIter implements the Node interface.
+func (r *RecursiveCte) RowIter(ctx *sql.Context) (sql.RowIter, error) {
+	if r.table == nil {
+		return nil, ErrUnresolvedTable.New()
+	}
+
+	span, ctx := ctx.Span("plan.RecursiveCte")
+
+	maxDepth, err := cteMaxRecursionDepth(ctx)
+	if err != nil {
+		span.Finish()
+		return nil, err
+	}
+
+	// The rows of the previous iteration are only for this execution, so
+	// they are in the context the recursive part is evaluated with instead
+	// of the table, which is shared by all the executions of the node.
+	previous := new(recursiveRows)
+	ctx = ctx.WithContext(context.WithValue(ctx, recursiveRowsKey{r.table}, previous))
+
+	iter := &recursiveCteIter{
+		ctx:      ctx,
+		cte:      r,
+		maxDepth: maxDepth,
+		previous: previous,
+	}
+
+	if r.Distinct {
+		iter.seen, iter.dispose = ctx.Memory.NewHistoryCache()
+	}
+
+	return sql.NewSpanIter(span, iter), nil
+}
+
+// WithChildren implements the Node interface.
+func (r *RecursiveCte) WithChildren(children ...sql.Node) (sql.Node, error) {
+	if len(children) != 2 {
+		return nil, sql.ErrInvalidChildrenNumber.New(r, len(children), 2)
+	}
+
+	nr := *r
+	nr.Left = children[0]
+	nr.Right = children[1]
+	return &nr, nil
+}
+
+func (r *RecursiveCte) String() string {
+	name := r.name
+	if len(r.Columns) > 0 {
+		name = fmt.Sprintf("%s(%s)", name, strings.Join(r.Columns, ", "))
+	}
+
+	pr := sql.NewTreePrinter()
+	if r.Distinct {
+		_ = pr.WriteNode("RecursiveCte(%s, distinct)", name)
+	} else {
+		_ = pr.WriteNode("RecursiveCte(%s)", name)
+	}
+	_ = pr.WriteChildren(r.Left.String(), r.Right.String())
+	return pr.String()
+}
+
+func cteMaxRecursionDepth(ctx *sql.Context) (int64, error) {
+	_, v := ctx.Session.Get(CteMaxRecursionDepthKey)
+	if v == nil {
+		return sql.DefaultSessionConfig()[CteMaxRecursionDepthKey].Value.(int64), nil
+	}
+
+	depth, err := sql.Int64.Convert(v)
+	if err != nil {
+		return 0, err
+	}
+
+	return depth.(int64), nil
+}
+
+type recursiveCteIter struct {
+	ctx      *sql.Context
+	cte      *RecursiveCte
+	maxDepth int64
+	depth    int64
+	started  bool
+	rows     []sql.Row
+	pos      int
+	previous *recursiveRows
+	seen     sql.KeyValueCache
+	dispose  sql.DisposeFunc
+}
+
+func (i *recursiveCteIter) Next() (sql.Row, error) {
+	for {
+		if i.pos < len(i.rows) {
+			row := i.rows[i.pos]
+			i.pos++
+			return row, nil
+		}
+
+		var node = i.cte.Right
+		if !i.started {
+			i.started = true
+			node = i.cte.Left
+		} else if len(i.rows) == 0 {
+			i.Dispose()
+			return nil, io.EOF
+		} else {
+			i.depth++
+			i.previous.rows = i.rows
+		}
+
+		rows, err := i.evalIteration(node)
+		if err != nil {
+			return nil, err
+		}
+
+		// The recursion only goes deeper than allowed if the last iteration
+		// returned new rows.
+		if i.depth > i.maxDepth && len(rows) > 0 {
+			return nil, ErrCteMaxRecursionDepth.New(i.maxDepth)
+		}
+
+		i.rows = rows
+		i.pos = 0
+	}
+}
+
+// evalIteration returns the new rows returned by the given node, converted
+// to the types of the schema of the expression.
+func (i *recursiveCteIter) evalIteration(node sql.Node) ([]sql.Row, error) {
+	iter, err := node.RowIter(i.ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	schema := i.cte.table.Schema()
+	var rows []sql.Row
+	for {
+		row, err := iter.Next()
+		if err == io.EOF {
+			break
+		}
+
+		if err != nil {
+			_ = iter.Close()
+			return nil, err
+		}
+
+		if len(row) != len(schema) {
+			_ = iter.Close()
+			return nil, sql.ErrUnexpectedRowLength.New(len(schema), len(row))
+		}
+
+		converted := make(sql.Row, len(row))
+		for j, v := range row {
+			converted[j], err = schema[j].Type.Convert(v)
+			if err != nil {
+				_ = iter.Close()
+				return nil, err
+			}
+		}
+
+		if i.seen != nil {
+			hash := sql.CacheKey(converted)
+			if _, err := i.seen.Get(hash); err == nil {
+				continue
+			}
+
+			if err := i.seen.Put(hash, struct{}{}); err != nil {
+				_ = iter.Close()
+				return nil, err
+			}
+		}
+
+		rows = append(rows, converted)
+	}
+
+	return rows, iter.Close()
+}
+
+func (i *recursiveCteIter) Close() error {
+	i.Dispose()
+	i.rows = nil
+	i.previous.rows = nil
+	return nil
+}
+
+func (i *recursiveCteIter) Dispose() {
+	if i.dispose != nil {
+		i.dispose()
+		i.dispose = nil
+	}
+}
+
+// recursiveRows are the rows returned by the previous iteration of an
+// execution of a recursive common table expression.
+type recursiveRows struct {
+	rows []sql.Row
+}
+
+// recursiveRowsKey is the key of the rows of the previous iteration of a
+// recursive table in the context of an execution.
+type recursiveRowsKey struct {
+	table *RecursiveTable
+}
+
+// RecursiveTable is the table a recursive common table expression reads
+// the rows returned by its previous iteration from. The rows are not in the
+// table, but in the context of each execution of the expression.
+type RecursiveTable struct {
+	name   string
+	schema sql.Schema
+}
+
+var _ sql.Table = (*RecursiveTable)(nil)
+
+// NewRecursiveTable creates a new recursive table with the given name and
+// schema.
+func NewRecursiveTable(name string, schema sql.Schema) *RecursiveTable {
+	var s = make(sql.Schema, len(schema))
+	for i, col := range schema {
+		c := *col
+		c.Source = name
+		s[i] = &c
+	}
+
+	return &RecursiveTable{name: name, schema: s}
+}
+
+// Name implements the sql.Table interface.
+func (t *RecursiveTable) Name() string { return t.name }
+
+// Schema implements the sql.Table interface.
+func (t *RecursiveTable) Schema() sql.Schema { return t.schema }
+
+// Partitions implements the sql.Table interface.
+func (t *RecursiveTable) Partitions(*sql.Context) (sql.PartitionIter, error) {
+	return &recursiveTablePartitionIter{key: []byte(t.name)}, nil
+}
+
+// PartitionRows implements the sql.Table interface.
+func (t *RecursiveTable) PartitionRows(ctx *sql.Context, _ sql.Partition) (sql.RowIter, error) {
+	previous, ok := ctx.Value(recursiveRowsKey{t}).(*recursiveRows)
+	if !ok {
+		return sql.RowsToRowIter(), nil
+	}
+	return sql.RowsToRowIter(previous.rows...), nil
+}
+
+func (t *RecursiveTable) String() string {
+	return fmt.Sprintf("RecursiveTable(%s)", t.name)
+}
+
+type recursiveTablePartition []byte
+
+func (p recursiveTablePartition) Key() []byte { return p }
+
+type recursiveTablePartitionIter struct {
+	key  []byte
+	done bool
+}
+
+func (i *recursiveTablePartitionIter) Next() (sql.Partition, error) {
+	if i.done {
+		return nil, io.EOF
+	}
+
+	i.done = true
+	return recursiveTablePartition(i.key), nil
+}
+
+func (i *recursiveTablePartitionIter) Close() error {
+	return nil
+}
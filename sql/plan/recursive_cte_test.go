@@ -0,0 +1,147 @@
+package plan
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/src-d/go-mysql-server/memory"
+	"github.com/src-d/go-mysql-server/sql"
+	"github.com/src-d/go-mysql-server/sql/expression"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRecursiveCte(t *testing.T) {
+	require := require.New(t)
+
+	rows, err := sql.RowIterToRows(
+		mustRowIter(t, countingCte(t, 5), sql.NewEmptyContext()),
+	)
+	require.NoError(err)
+	require.Equal([]sql.Row{{int64(1)}, {int64(2)}, {int64(3)}, {int64(4)}, {int64(5)}}, rows)
+}
+
+func TestRecursiveCteDistinct(t *testing.T) {
+	require := require.New(t)
+
+	// WITH RECURSIVE t (n) AS (SELECT 1 UNION SELECT n FROM t) only returns
+	// the row of the anchor, because the recursive part never returns a new
+	// one.
+	table := NewRecursiveTable("t", sql.Schema{{Name: "n", Type: sql.Int64}})
+	cte := NewRecursiveCte(
+		"t",
+		[]string{"n"},
+		true,
+		NewProject(
+			[]sql.Expression{expression.NewLiteral(int64(1), sql.Int64)},
+			NewResolvedTable(recursiveCteDual(t)),
+		),
+		NewResolvedTable(table),
+	).WithTable(table)
+
+	rows, err := sql.RowIterToRows(mustRowIter(t, cte, sql.NewEmptyContext()))
+	require.NoError(err)
+	require.Equal([]sql.Row{{int64(1)}}, rows)
+}
+
+func TestRecursiveCteConcurrentExecutions(t *testing.T) {
+	// Executions of the same node don't share the rows of their iterations.
+	cte := countingCte(t, 20)
+
+	var wg sync.WaitGroup
+	var errs = make(chan error, 8)
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 20; j++ {
+				iter, err := cte.RowIter(sql.NewEmptyContext())
+				if err == nil {
+					var rows []sql.Row
+					rows, err = sql.RowIterToRows(iter)
+					if err == nil && len(rows) != 20 {
+						err = fmt.Errorf("expected 20 rows, got %d", len(rows))
+					}
+				}
+
+				if err != nil {
+					errs <- err
+					return
+				}
+			}
+		}()
+	}
+
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		require.NoError(t, err)
+	}
+}
+
+func TestRecursiveCteMaxDepth(t *testing.T) {
+	require := require.New(t)
+
+	ctx := sql.NewEmptyContext()
+	ctx.Set(CteMaxRecursionDepthKey, sql.Int64, int64(3))
+
+	// Counting to 4 takes exactly 3 iterations.
+	rows, err := sql.RowIterToRows(mustRowIter(t, countingCte(t, 4), ctx))
+	require.NoError(err)
+	require.Len(rows, 4)
+
+	_, err = sql.RowIterToRows(mustRowIter(t, countingCte(t, 5), ctx))
+	require.Error(err)
+	require.True(ErrCteMaxRecursionDepth.Is(err))
+}
+
+func TestRecursiveCteUnresolved(t *testing.T) {
+	require := require.New(t)
+
+	dual := NewResolvedTable(recursiveCteDual(t))
+	cte := NewRecursiveCte("t", nil, false, dual, dual)
+	require.False(cte.Resolved())
+
+	_, err := cte.RowIter(sql.NewEmptyContext())
+	require.Error(err)
+}
+
+// countingCte returns the node of the following expression:
+// WITH RECURSIVE t (n) AS (SELECT 1 UNION ALL SELECT n + 1 FROM t WHERE n < limit)
+func countingCte(t *testing.T, limit int64) *RecursiveCte {
+	table := NewRecursiveTable("t", sql.Schema{
+		{Name: "n", Type: sql.Int64},
+	})
+
+	anchor := NewProject(
+		[]sql.Expression{expression.NewLiteral(int64(1), sql.Int64)},
+		NewResolvedTable(recursiveCteDual(t)),
+	)
+
+	n := expression.NewGetFieldWithTable(0, sql.Int64, "t", "n", false)
+	recursive := NewProject(
+		[]sql.Expression{
+			expression.NewArithmetic(n, expression.NewLiteral(int64(1), sql.Int64), "+"),
+		},
+		NewFilter(
+			expression.NewLessThan(n, expression.NewLiteral(limit, sql.Int64)),
+			NewResolvedTable(table),
+		),
+	)
+
+	return NewRecursiveCte("t", []string{"n"}, false, anchor, recursive).WithTable(table)
+}
+
+func mustRowIter(t *testing.T, n sql.Node, ctx *sql.Context) sql.RowIter {
+	t.Helper()
+	iter, err := n.RowIter(ctx)
+	require.NoError(t, err)
+	return iter
+}
+
+func recursiveCteDual(t *testing.T) *memory.Table {
+	t.Helper()
+	table := memory.NewTable("dual", sql.Schema{{Name: "dummy", Type: sql.Int64, Source: "dual"}})
+	require.NoError(t, table.Insert(sql.NewEmptyContext(), sql.NewRow(int64(0))))
+	return table
+}
@@ -0,0 +1,145 @@
+package plan
+
+import (
+	"github.com/src-d/go-mysql-server/sql"
+)
+
+// SemiJoin returns the rows of the left side that match at least one row of
+// the right side, each of them only once and without the columns of the
+// right side. It's the result of rewriting IN and EXISTS subqueries.
+type SemiJoin struct {
+	BinaryNode
+	Cond sql.Expression
+}
+
+// NewSemiJoin creates a new semi join node from two nodes.
+func NewSemiJoin(left, right sql.Node, cond sql.Expression) *SemiJoin {
+	return &SemiJoin{
+		BinaryNode: BinaryNode{
+			Left:  left,
+			Right: right,
+		},
+		Cond: cond,
+	}
+}
+
+// Schema implements the Node interface.
+func (j *SemiJoin) Schema() sql.Schema {
+	return j.Left.Schema()
+}
+
+// Resolved implements the Resolvable interface.
+func (j *SemiJoin) Resolved() bool {
+	return j.Left.Resolved() && j.Right.Resolved() && j.Cond.Resolved()
+}
+
+// RowIter implements the Node interface.
+func (j *SemiJoin) RowIter(ctx *sql.Context) (sql.RowIter, error) {
+	return joinRowIter(ctx, JoinTypeSemi, j.Left, j.Right, j.Cond)
+}
+
+// WithChildren implements the Node interface.
+func (j *SemiJoin) WithChildren(children ...sql.Node) (sql.Node, error) {
+	if len(children) != 2 {
+		return nil, sql.ErrInvalidChildrenNumber.New(j, len(children), 2)
+	}
+
+	return NewSemiJoin(children[0], children[1], j.Cond), nil
+}
+
+// WithExpressions implements the Expressioner interface.
+func (j *SemiJoin) WithExpressions(exprs ...sql.Expression) (sql.Node, error) {
+	if len(exprs) != 1 {
+		return nil, sql.ErrInvalidChildrenNumber.New(j, len(exprs), 1)
+	}
+
+	return NewSemiJoin(j.Left, j.Right, exprs[0]), nil
+}
+
+func (j *SemiJoin) String() string {
+	pr := sql.NewTreePrinter()
+	_ = pr.WriteNode("SemiJoin(%s)", j.Cond)
+	_ = pr.WriteChildren(j.Left.String(), j.Right.String())
+	return pr.String()
+}
+
+// Expressions implements the Expressioner interface.
+func (j *SemiJoin) Expressions() []sql.Expression {
+	return []sql.Expression{j.Cond}
+}
+
+// AntiJoin returns the rows of the left side that don't match any row of the
+// right side, without the columns of the right side. It's the result of
+// rewriting NOT IN and NOT EXISTS subqueries. A null-aware anti join also
+// discards the rows for which the condition is unknown, because they could
+// match, which is what NOT IN does with null values.
+type AntiJoin struct {
+	BinaryNode
+	Cond      sql.Expression
+	NullAware bool
+}
+
+// NewAntiJoin creates a new anti join node from two nodes.
+func NewAntiJoin(left, right sql.Node, cond sql.Expression, nullAware bool) *AntiJoin {
+	return &AntiJoin{
+		BinaryNode: BinaryNode{
+			Left:  left,
+			Right: right,
+		},
+		Cond:      cond,
+		NullAware: nullAware,
+	}
+}
+
+// Type returns the type of the join.
+func (j *AntiJoin) Type() JoinType {
+	if j.NullAware {
+		return JoinTypeNullAwareAnti
+	}
+	return JoinTypeAnti
+}
+
+// Schema implements the Node interface.
+func (j *AntiJoin) Schema() sql.Schema {
+	return j.Left.Schema()
+}
+
+// Resolved implements the Resolvable interface.
+func (j *AntiJoin) Resolved() bool {
+	return j.Left.Resolved() && j.Right.Resolved() && j.Cond.Resolved()
+}
+
+// RowIter implements the Node interface.
+func (j *AntiJoin) RowIter(ctx *sql.Context) (sql.RowIter, error) {
+	return joinRowIter(ctx, j.Type(), j.Left, j.Right, j.Cond)
+}
+
+// WithChildren implements the Node interface.
+func (j *AntiJoin) WithChildren(children ...sql.Node) (sql.Node, error) {
+	if len(children) != 2 {
+		return nil, sql.ErrInvalidChildrenNumber.New(j, len(children), 2)
+	}
+
+	return NewAntiJoin(children[0], children[1], j.Cond, j.NullAware), nil
+}
+
+// WithExpressions implements the Expressioner interface.
+func (j *AntiJoin) WithExpressions(exprs ...sql.Expression) (sql.Node, error) {
+	if len(exprs) != 1 {
+		return nil, sql.ErrInvalidChildrenNumber.New(j, len(exprs), 1)
+	}
+
+	return NewAntiJoin(j.Left, j.Right, exprs[0], j.NullAware), nil
+}
+
+func (j *AntiJoin) String() string {
+	pr := sql.NewTreePrinter()
+	_ = pr.WriteNode("%s(%s)", j.Type(), j.Cond)
+	_ = pr.WriteChildren(j.Left.String(), j.Right.String())
+	return pr.String()
+}
+
+// Expressions implements the Expressioner interface.
+func (j *AntiJoin) Expressions() []sql.Expression {
+	return []sql.Expression{j.Cond}
+}
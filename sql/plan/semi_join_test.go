@@ -0,0 +1,177 @@
+package plan
+
+import (
+	"testing"
+
+	"github.com/src-d/go-mysql-server/memory"
+	"github.com/src-d/go-mysql-server/sql"
+	"github.com/src-d/go-mysql-server/sql/expression"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSemiAndAntiJoins(t *testing.T) {
+	left := NewResolvedTable(joinTestTable(t, "l",
+		sql.NewRow(int64(1), "a"),
+		sql.NewRow(int64(2), "b"),
+		sql.NewRow(int64(2), "c"),
+		sql.NewRow(int64(3), "d"),
+		sql.NewRow(nil, "e"),
+	))
+	right := NewResolvedTable(joinTestTable(t, "r",
+		sql.NewRow(int64(2), "x"),
+		sql.NewRow(int64(2), "y"),
+		sql.NewRow(int64(3), "z"),
+		sql.NewRow(int64(4), "w"),
+	))
+	rightWithNulls := NewResolvedTable(joinTestTable(t, "r",
+		sql.NewRow(int64(2), "x"),
+		sql.NewRow(nil, "y"),
+	))
+	empty := NewResolvedTable(joinTestTable(t, "r"))
+
+	cond := expression.NewEquals(
+		expression.NewGetFieldWithTable(0, sql.Int64, "l", "i", true),
+		expression.NewGetFieldWithTable(2, sql.Int64, "r", "i", true),
+	)
+
+	testCases := []struct {
+		name     string
+		typ      JoinType
+		right    sql.Node
+		expected []sql.Row
+	}{
+		{
+			"semi join",
+			JoinTypeSemi,
+			right,
+			[]sql.Row{
+				{int64(2), "b"},
+				{int64(2), "c"},
+				{int64(3), "d"},
+			},
+		},
+		{
+			"semi join with empty right side",
+			JoinTypeSemi,
+			empty,
+			nil,
+		},
+		{
+			"anti join",
+			JoinTypeAnti,
+			right,
+			[]sql.Row{
+				{int64(1), "a"},
+				{nil, "e"},
+			},
+		},
+		{
+			"anti join with nulls on the right side",
+			JoinTypeAnti,
+			rightWithNulls,
+			[]sql.Row{
+				{int64(1), "a"},
+				{int64(3), "d"},
+				{nil, "e"},
+			},
+		},
+		{
+			"anti join with empty right side",
+			JoinTypeAnti,
+			empty,
+			[]sql.Row{
+				{int64(1), "a"},
+				{int64(2), "b"},
+				{int64(2), "c"},
+				{int64(3), "d"},
+				{nil, "e"},
+			},
+		},
+		{
+			"null-aware anti join",
+			JoinTypeNullAwareAnti,
+			right,
+			[]sql.Row{
+				{int64(1), "a"},
+			},
+		},
+		{
+			"null-aware anti join with nulls on the right side",
+			JoinTypeNullAwareAnti,
+			rightWithNulls,
+			nil,
+		},
+		{
+			"null-aware anti join with empty right side",
+			JoinTypeNullAwareAnti,
+			empty,
+			[]sql.Row{
+				{int64(1), "a"},
+				{int64(2), "b"},
+				{int64(2), "c"},
+				{int64(3), "d"},
+				{nil, "e"},
+			},
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			require := require.New(t)
+
+			join := newJoin(tt.typ, left, tt.right, cond)
+			require.Equal(left.Schema(), join.Schema())
+			require.ElementsMatch(tt.expected, collectRows(t, join))
+
+			hashJoin := NewHashJoin(tt.typ, left, tt.right, cond)
+			require.Equal(BuildRight, hashJoin.Build)
+			require.Equal(left.Schema(), hashJoin.Schema())
+			require.ElementsMatch(tt.expected, collectRows(t, hashJoin))
+
+			ctx := sql.NewEmptyContext()
+			ctx.Set(inMemoryJoinSessionVar, sql.Text, "true")
+			rows, err := sql.NodeToRows(ctx, join)
+			require.NoError(err)
+			require.ElementsMatch(tt.expected, rows)
+		})
+	}
+}
+
+func TestSemiAndAntiJoinString(t *testing.T) {
+	require := require.New(t)
+
+	left := NewResolvedTable(memory.NewTable("l", nil))
+	right := NewResolvedTable(memory.NewTable("r", nil))
+	cond := expression.NewEquals(
+		expression.NewGetFieldWithTable(0, sql.Int64, "l", "i", true),
+		expression.NewGetFieldWithTable(1, sql.Int64, "r", "i", true),
+	)
+
+	require.Equal(
+		"SemiJoin(l.i = r.i)\n ├─ Table(l)\n └─ Table(r)\n",
+		NewSemiJoin(left, right, cond).String(),
+	)
+	require.Equal(
+		"AntiJoin(l.i = r.i)\n ├─ Table(l)\n └─ Table(r)\n",
+		NewAntiJoin(left, right, cond, false).String(),
+	)
+	require.Equal(
+		"NullAwareAntiJoin(l.i = r.i)\n ├─ Table(l)\n └─ Table(r)\n",
+		NewAntiJoin(left, right, cond, true).String(),
+	)
+}
+
+func joinTestTable(t *testing.T, name string, rows ...sql.Row) *memory.Table {
+	t.Helper()
+
+	table := memory.NewTable(name, sql.Schema{
+		{Name: "i", Type: sql.Int64, Source: name, Nullable: true},
+		{Name: "s", Type: sql.Text, Source: name},
+	})
+
+	for _, row := range rows {
+		require.NoError(t, table.Insert(sql.NewEmptyContext(), row))
+	}
+
+	return table
+}
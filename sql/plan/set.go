@@ -113,6 +113,15 @@ func (s *Set) RowIter(ctx *sql.Context) (sql.RowIter, error) {
 		}
 
 		ctx.Set(name, typ, value)
+
+		// Enabling autocommit commits the transaction in progress.
+		if name == "autocommit" && sql.IsAutocommit(ctx.Session) {
+			if tx := ctx.Transaction(); tx != nil && !tx.Implicit {
+				if err := tx.Commit(ctx); err != nil {
+					return nil, err
+				}
+			}
+		}
 	}
 
 	return sql.RowsToRowIter(), nil
@@ -0,0 +1,96 @@
+package plan
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"github.com/src-d/go-mysql-server/sql"
+)
+
+// ShowCreateView returns the SQL for creating a view.
+type ShowCreateView struct {
+	db      sql.Database
+	Name    string
+	Catalog *sql.Catalog
+}
+
+var showCreateViewSchema = sql.Schema{
+	{Name: "View", Type: sql.Text},
+	{Name: "Create View", Type: sql.Text},
+	{Name: "character_set_client", Type: sql.Text},
+	{Name: "collation_connection", Type: sql.Text},
+}
+
+// NewShowCreateView creates a new ShowCreateView node.
+func NewShowCreateView(db sql.Database, name string) *ShowCreateView {
+	return &ShowCreateView{db: db, Name: name}
+}
+
+var _ sql.Databaser = (*ShowCreateView)(nil)
+
+// Database implements the sql.Databaser interface.
+func (s *ShowCreateView) Database() sql.Database {
+	return s.db
+}
+
+// WithDatabase implements the sql.Databaser interface.
+func (s *ShowCreateView) WithDatabase(db sql.Database) (sql.Node, error) {
+	nc := *s
+	nc.db = db
+	return &nc, nil
+}
+
+// RowIter implements the sql.Node interface.
+func (s *ShowCreateView) RowIter(ctx *sql.Context) (sql.RowIter, error) {
+	view, err := s.Catalog.View(s.db.Name(), s.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString("CREATE VIEW `")
+	buf.WriteString(view.Name())
+	buf.WriteRune('`')
+	if len(view.Columns) > 0 {
+		buf.WriteString(" (`")
+		buf.WriteString(strings.Join(view.Columns, "`, `"))
+		buf.WriteString("`)")
+	}
+	buf.WriteString(" AS ")
+	buf.WriteString(view.TextDefinition)
+
+	return sql.RowsToRowIter(sql.NewRow(
+		view.Name(),
+		buf.String(),
+		defaultCharacterSet,
+		defaultCollation,
+	)), nil
+}
+
+// Schema implements the sql.Node interface.
+func (s *ShowCreateView) Schema() sql.Schema {
+	return showCreateViewSchema
+}
+
+func (s *ShowCreateView) String() string {
+	return fmt.Sprintf("SHOW CREATE VIEW %s", s.Name)
+}
+
+// Children implements the sql.Node interface.
+func (s *ShowCreateView) Children() []sql.Node { return nil }
+
+// Resolved implements the sql.Node interface.
+func (s *ShowCreateView) Resolved() bool {
+	_, ok := s.db.(sql.UnresolvedDatabase)
+	return !ok
+}
+
+// WithChildren implements the Node interface.
+func (s *ShowCreateView) WithChildren(children ...sql.Node) (sql.Node, error) {
+	if len(children) != 0 {
+		return nil, sql.ErrInvalidChildrenNumber.New(s, len(children), 0)
+	}
+
+	return s, nil
+}
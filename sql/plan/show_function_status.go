@@ -0,0 +1,102 @@
+package plan
+
+import (
+	"fmt"
+
+	"github.com/src-d/go-mysql-server/sql"
+	"github.com/src-d/go-mysql-server/sql/expression"
+)
+
+// ShowFunctionStatus shows the functions created with CREATE FUNCTION.
+type ShowFunctionStatus struct {
+	pattern string
+	Catalog *sql.Catalog
+}
+
+// NewShowFunctionStatus creates a new ShowFunctionStatus node. If pattern is
+// not empty, only the functions whose name is like the pattern are shown.
+func NewShowFunctionStatus(pattern string) *ShowFunctionStatus {
+	return &ShowFunctionStatus{pattern: pattern}
+}
+
+var showFunctionStatusSchema = sql.Schema{
+	{Name: "Db", Type: sql.Text},
+	{Name: "Name", Type: sql.Text},
+	{Name: "Type", Type: sql.Text},
+	{Name: "Definer", Type: sql.Text},
+	{Name: "Modified", Type: sql.Timestamp},
+	{Name: "Created", Type: sql.Timestamp},
+	{Name: "Security_type", Type: sql.Text},
+	{Name: "Comment", Type: sql.Text},
+	{Name: "character_set_client", Type: sql.Text},
+	{Name: "collation_connection", Type: sql.Text},
+	{Name: "Database Collation", Type: sql.Text},
+}
+
+// Resolved implements the sql.Node interface.
+func (s *ShowFunctionStatus) Resolved() bool { return true }
+
+// Schema implements the sql.Node interface.
+func (s *ShowFunctionStatus) Schema() sql.Schema { return showFunctionStatusSchema }
+
+// Children implements the sql.Node interface.
+func (s *ShowFunctionStatus) Children() []sql.Node { return nil }
+
+// RowIter implements the sql.Node interface.
+func (s *ShowFunctionStatus) RowIter(ctx *sql.Context) (sql.RowIter, error) {
+	var like sql.Expression
+	if s.pattern != "" {
+		like = expression.NewLike(
+			expression.NewGetField(0, sql.Text, "", false),
+			expression.NewLiteral(s.pattern, sql.Text),
+		)
+	}
+
+	var rows []sql.Row
+	for _, fn := range s.Catalog.StoredFunctions() {
+		if like != nil {
+			b, err := like.Eval(ctx, sql.NewRow(fn.Name))
+			if err != nil {
+				return nil, err
+			}
+			if !b.(bool) {
+				continue
+			}
+		}
+
+		// Functions don't belong to any database, and they can't be
+		// modified.
+		rows = append(rows, sql.NewRow(
+			"",                  // Db
+			fn.Name,             // Name
+			"FUNCTION",          // Type
+			"",                  // Definer
+			fn.Created,          // Modified
+			fn.Created,          // Created
+			"DEFINER",           // Security_type
+			"",                  // Comment
+			defaultCharacterSet, // character_set_client
+			defaultCollation,    // collation_connection
+			defaultCollation,    // Database Collation
+		))
+	}
+
+	return sql.RowsToRowIter(rows...), nil
+}
+
+// WithChildren implements the Node interface.
+func (s *ShowFunctionStatus) WithChildren(children ...sql.Node) (sql.Node, error) {
+	if len(children) != 0 {
+		return nil, sql.ErrInvalidChildrenNumber.New(s, len(children), 0)
+	}
+
+	return s, nil
+}
+
+func (s *ShowFunctionStatus) String() string {
+	var like string
+	if s.pattern != "" {
+		like = fmt.Sprintf(" LIKE '%s'", s.pattern)
+	}
+	return fmt.Sprintf("SHOW FUNCTION STATUS%s", like)
+}
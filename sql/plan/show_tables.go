@@ -10,6 +10,8 @@ import (
 type ShowTables struct {
 	db   sql.Database
 	Full bool
+	// Catalog is used to list the views of the database, if it's set.
+	Catalog *sql.Catalog
 }
 
 var showTablesSchema = sql.Schema{
@@ -65,22 +67,29 @@ func (p *ShowTables) Schema() sql.Schema {
 
 // RowIter implements the Node interface.
 func (p *ShowTables) RowIter(ctx *sql.Context) (sql.RowIter, error) {
-	tableNames := []string{}
+	var rows []sql.Row
 	for key := range p.db.Tables() {
-		tableNames = append(tableNames, key)
-	}
-
-	sort.Strings(tableNames)
-
-	var rows = make([]sql.Row, len(tableNames))
-	for i, n := range tableNames {
-		row := sql.Row{n}
+		row := sql.Row{key}
 		if p.Full {
 			row = append(row, "BASE TABLE")
 		}
-		rows[i] = row
+		rows = append(rows, row)
 	}
 
+	if p.Catalog != nil {
+		for _, view := range p.Catalog.ViewsInDatabase(p.db.Name()) {
+			row := sql.Row{view.Name()}
+			if p.Full {
+				row = append(row, "VIEW")
+			}
+			rows = append(rows, row)
+		}
+	}
+
+	sort.Slice(rows, func(i, j int) bool {
+		return rows[i][0].(string) < rows[j][0].(string)
+	})
+
 	return sql.RowsToRowIter(rows...), nil
 }
 
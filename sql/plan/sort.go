@@ -1,6 +1,7 @@
 package plan
 
 import (
+	"container/heap"
 	"fmt"
 	"io"
 	"sort"
@@ -144,6 +145,8 @@ type sortIter struct {
 	childIter  sql.RowIter
 	sortedRows []sql.Row
 	idx        int
+	runs       []*sql.RowSpool
+	merged     sql.RowIter
 }
 
 func newSortIter(ctx *sql.Context, s *Sort, child sql.RowIter) *sortIter {
@@ -164,6 +167,10 @@ func (i *sortIter) Next() (sql.Row, error) {
 		i.idx = 0
 	}
 
+	if i.merged != nil {
+		return i.merged.Next()
+	}
+
 	if i.idx >= len(i.sortedRows) {
 		return nil, io.EOF
 	}
@@ -174,12 +181,16 @@ func (i *sortIter) Next() (sql.Row, error) {
 
 func (i *sortIter) Close() error {
 	i.sortedRows = nil
+	if i.merged != nil {
+		_ = i.merged.Close()
+	}
+	disposeSpools(i.runs)
 	return i.childIter.Close()
 }
 
 func (i *sortIter) computeSortedRows() error {
 	cache, dispose := i.ctx.Memory.NewRowsCache()
-	defer dispose()
+	defer func() { dispose() }()
 
 	for {
 		row, err := i.childIter.Next()
@@ -191,24 +202,260 @@ func (i *sortIter) computeSortedRows() error {
 		}
 
 		if err := cache.Add(row); err != nil {
-			return err
+			if !sql.ErrNoMemoryAvailable.Is(err) {
+				return err
+			}
+
+			// When rows don't fit in memory they are sorted and stored on
+			// disk in runs, which are merged once all rows have been read.
+			if err := i.addRun(append(cache.Get(), row)); err != nil {
+				return err
+			}
+
+			dispose()
+			cache, dispose = i.ctx.Memory.NewRowsCache()
 		}
 	}
 
 	rows := cache.Get()
-	sorter := &sorter{
+	if err := i.sort(rows); err != nil {
+		return err
+	}
+
+	if len(i.runs) == 0 {
+		i.sortedRows = rows
+		return nil
+	}
+
+	// There may be too many runs to read all of them at the same time, so
+	// they are merged in groups until there are few enough.
+	for len(i.runs) > maxMergedRuns {
+		var runs []*sql.RowSpool
+		for len(i.runs) > 0 {
+			n := maxMergedRuns
+			if n > len(i.runs) {
+				n = len(i.runs)
+			}
+
+			run, err := i.mergeRuns(i.runs[:n])
+			if err != nil {
+				// The runs are kept so they're disposed when the iterator
+				// is closed.
+				i.runs = append(i.runs, runs...)
+				return err
+			}
+
+			disposeSpools(i.runs[:n])
+			i.runs = i.runs[n:]
+			runs = append(runs, run)
+		}
+		i.runs = runs
+	}
+
+	iters, err := spoolIters(i.runs)
+	if err != nil {
+		return err
+	}
+
+	// The rows still in memory are the last ones, so they go after the
+	// runs to keep the sort stable.
+	i.merged = newMergeIter(i.sorter(), append(iters, sql.RowsToRowIter(rows...)))
+	return nil
+}
+
+// maxMergedRuns is the maximum number of runs merged at the same time when
+// sorting rows that don't fit in memory.
+const maxMergedRuns = 64
+
+func (i *sortIter) sorter() *sorter {
+	return &sorter{
 		sortFields: i.s.SortFields,
-		rows:       rows,
-		lastError:  nil,
+		ctx:        i.ctx,
 	}
+}
+
+func (i *sortIter) sort(rows []sql.Row) error {
+	sorter := i.sorter()
+	sorter.rows = rows
 	sort.Stable(sorter)
-	if sorter.lastError != nil {
-		return sorter.lastError
+	return sorter.lastError
+}
+
+func (i *sortIter) addRun(rows []sql.Row) error {
+	if err := i.sort(rows); err != nil {
+		return err
+	}
+
+	run, err := i.ctx.Memory.NewRowSpool()
+	if err != nil {
+		return err
 	}
-	i.sortedRows = rows
+	i.runs = append(i.runs, run)
+
+	for _, row := range rows {
+		if err := run.Add(row); err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
+func (i *sortIter) mergeRuns(runs []*sql.RowSpool) (*sql.RowSpool, error) {
+	iters, err := spoolIters(runs)
+	if err != nil {
+		return nil, err
+	}
+
+	iter := newMergeIter(i.sorter(), iters)
+	defer iter.Close()
+
+	run, err := i.ctx.Memory.NewRowSpool()
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		row, err := iter.Next()
+		if err == io.EOF {
+			return run, nil
+		}
+
+		if err == nil {
+			err = run.Add(row)
+		}
+
+		if err != nil {
+			run.Dispose()
+			return nil, err
+		}
+	}
+}
+
+func spoolIters(spools []*sql.RowSpool) ([]sql.RowIter, error) {
+	var iters = make([]sql.RowIter, len(spools))
+	for i, s := range spools {
+		iter, err := s.RowIter()
+		if err != nil {
+			for _, iter := range iters[:i] {
+				_ = iter.Close()
+			}
+			return nil, err
+		}
+		iters[i] = iter
+	}
+	return iters, nil
+}
+
+func disposeSpools(spools []*sql.RowSpool) {
+	for _, s := range spools {
+		if s != nil {
+			s.Dispose()
+		}
+	}
+}
+
+// mergeIter merges iterators of rows that are already sorted. Rows that are
+// equal are returned in the order of the iterators they come from.
+type mergeIter struct {
+	sorter *sorter
+	iters  []sql.RowIter
+	heap   *mergeHeap
+}
+
+func newMergeIter(sorter *sorter, iters []sql.RowIter) *mergeIter {
+	return &mergeIter{
+		sorter: sorter,
+		iters:  iters,
+	}
+}
+
+func (i *mergeIter) Next() (sql.Row, error) {
+	if i.heap == nil {
+		i.heap = &mergeHeap{sorter: i.sorter}
+		for idx := range i.iters {
+			if err := i.push(idx); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if i.heap.Len() == 0 {
+		return nil, io.EOF
+	}
+
+	item := heap.Pop(i.heap).(mergeItem)
+	if i.sorter.lastError != nil {
+		return nil, i.sorter.lastError
+	}
+
+	if err := i.push(item.iter); err != nil {
+		return nil, err
+	}
+
+	return item.row, nil
+}
+
+// push adds the next row of the iterator with the given index to the heap.
+func (i *mergeIter) push(idx int) error {
+	row, err := i.iters[idx].Next()
+	if err == io.EOF {
+		return nil
+	}
+
+	if err != nil {
+		return err
+	}
+
+	heap.Push(i.heap, mergeItem{row, idx})
+	return i.sorter.lastError
+}
+
+func (i *mergeIter) Close() error {
+	var err error
+	for _, iter := range i.iters {
+		if e := iter.Close(); e != nil && err == nil {
+			err = e
+		}
+	}
+	return err
+}
+
+type mergeItem struct {
+	row  sql.Row
+	iter int
+}
+
+type mergeHeap struct {
+	sorter *sorter
+	items  []mergeItem
+}
+
+func (h *mergeHeap) Len() int { return len(h.items) }
+
+func (h *mergeHeap) Less(i, j int) bool {
+	a, b := h.items[i], h.items[j]
+	if h.sorter.less(a.row, b.row) {
+		return true
+	}
+
+	if h.sorter.less(b.row, a.row) {
+		return false
+	}
+
+	return a.iter < b.iter
+}
+
+func (h *mergeHeap) Swap(i, j int) { h.items[i], h.items[j] = h.items[j], h.items[i] }
+
+func (h *mergeHeap) Push(x interface{}) { h.items = append(h.items, x.(mergeItem)) }
+
+func (h *mergeHeap) Pop() interface{} {
+	item := h.items[len(h.items)-1]
+	h.items = h.items[:len(h.items)-1]
+	return item
+}
+
 type sorter struct {
 	sortFields []SortField
 	rows       []sql.Row
@@ -225,12 +472,14 @@ func (s *sorter) Swap(i, j int) {
 }
 
 func (s *sorter) Less(i, j int) bool {
+	return s.less(s.rows[i], s.rows[j])
+}
+
+func (s *sorter) less(a, b sql.Row) bool {
 	if s.lastError != nil {
 		return false
 	}
 
-	a := s.rows[i]
-	b := s.rows[j]
 	for _, sf := range s.sortFields {
 		typ := sf.Column.Type()
 		av, err := sf.Column.Eval(s.ctx, a)
@@ -245,6 +494,10 @@ func (s *sorter) Less(i, j int) bool {
 			return false
 		}
 
+		if av == nil && bv == nil {
+			continue
+		}
+
 		if av == nil {
 			return sf.NullOrdering == NullsFirst
 		}
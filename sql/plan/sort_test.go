@@ -1,6 +1,7 @@
 package plan
 
 import (
+	"os"
 	"testing"
 
 	"github.com/src-d/go-mysql-server/memory"
@@ -131,3 +132,24 @@ func TestSortDescending(t *testing.T) {
 	require.NoError(err)
 	require.Equal(expected, actual)
 }
+
+func TestSortSpill(t *testing.T) {
+	require := require.New(t)
+
+	// Enough rows to need more runs than can be merged at once.
+	table := spillTable(t, "t", 1000)
+	s := NewSort([]SortField{
+		{Column: expression.NewGetField(0, sql.Int64, "i", true), Order: Descending, NullOrdering: NullsFirst},
+	}, NewResolvedTable(table))
+
+	expected, err := sql.NodeToRows(sql.NewEmptyContext(), s)
+	require.NoError(err)
+
+	ctx, dir := newSpillContext(t, 5)
+	defer os.RemoveAll(dir)
+
+	rows, err := sql.NodeToRows(ctx, s)
+	require.NoError(err)
+	require.Equal(expected, rows)
+	requireNoSpools(t, dir)
+}
@@ -9,11 +9,22 @@ type SubqueryAlias struct {
 	UnaryNode
 	name   string
 	schema sql.Schema
+	// Columns are the names given to the columns of the subquery, if any.
+	// They are applied by the analyzer when the subquery is resolved.
+	Columns []string
 }
 
 // NewSubqueryAlias creates a new SubqueryAlias node.
 func NewSubqueryAlias(name string, node sql.Node) *SubqueryAlias {
-	return &SubqueryAlias{UnaryNode{Child: node}, name, nil}
+	return &SubqueryAlias{UnaryNode{Child: node}, name, nil, nil}
+}
+
+// WithColumns returns a copy of the node with the given column names.
+func (n *SubqueryAlias) WithColumns(columns []string) *SubqueryAlias {
+	nn := *n
+	nn.Columns = columns
+	nn.schema = nil
+	return &nn
 }
 
 // Name implements the Table interface.
@@ -0,0 +1,159 @@
+package plan
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/src-d/go-mysql-server/sql"
+	"github.com/src-d/go-mysql-server/sql/expression"
+	"github.com/src-d/go-mysql-server/sql/expression/function/udf"
+)
+
+// ErrInvalidTableFunctionResult is returned when the result of a function
+// used as a table can't be turned into rows.
+var ErrInvalidTableFunctionResult = udf.ErrInvalidScriptRows
+
+// TableFunction is a table whose rows are the elements of the list returned
+// by a function, such as `SELECT * FROM js_table('[{a: 1}, {a: 2}]')`. The
+// elements can be objects, whose keys are the columns, lists of column
+// values, or single values.
+//
+// The schema can be declared, as in `js_table('...') AS t(a INT)`. If it's
+// not, the function is evaluated while the query is analyzed to infer the
+// schema from its result, and the result is kept to be returned as rows.
+type TableFunction struct {
+	name     string
+	Function sql.Expression
+	schema   sql.Schema
+}
+
+// NewTableFunction creates a new table with the given name whose rows are
+// the result of the given function. The schema may be nil, in which case it
+// will be inferred from the result once the function is resolved.
+func NewTableFunction(name string, fn sql.Expression, schema sql.Schema) *TableFunction {
+	var columns sql.Schema
+	for _, col := range schema {
+		c := *col
+		c.Source = name
+		c.Nullable = true
+		columns = append(columns, &c)
+	}
+
+	return &TableFunction{name: name, Function: fn, schema: columns}
+}
+
+// Name implements the Nameable interface.
+func (t *TableFunction) Name() string {
+	return t.name
+}
+
+// Resolved implements the Resolvable interface.
+func (t *TableFunction) Resolved() bool {
+	return t.Function.Resolved() && t.schema != nil
+}
+
+// Schema implements the Node interface.
+func (t *TableFunction) Schema() sql.Schema {
+	return t.schema
+}
+
+// Children implements the Node interface.
+func (*TableFunction) Children() []sql.Node { return nil }
+
+// Expressions implements the Expressioner interface.
+func (t *TableFunction) Expressions() []sql.Expression {
+	return []sql.Expression{t.Function}
+}
+
+// WithExpressions implements the Expressioner interface.
+func (t *TableFunction) WithExpressions(exprs ...sql.Expression) (sql.Node, error) {
+	if len(exprs) != 1 {
+		return nil, sql.ErrInvalidChildrenNumber.New(t, len(exprs), 1)
+	}
+
+	return &TableFunction{t.name, exprs[0], t.schema}, nil
+}
+
+// WithChildren implements the Node interface.
+func (t *TableFunction) WithChildren(children ...sql.Node) (sql.Node, error) {
+	if len(children) != 0 {
+		return nil, sql.ErrInvalidChildrenNumber.New(t, len(children), 0)
+	}
+
+	return t, nil
+}
+
+// RowIter implements the Node interface.
+func (t *TableFunction) RowIter(ctx *sql.Context) (sql.RowIter, error) {
+	span, ctx := ctx.Span("plan.TableFunction")
+
+	value, err := t.Function.Eval(ctx, nil)
+	if err != nil {
+		span.Finish()
+		return nil, err
+	}
+
+	elems, err := udf.ScriptElements(t.description(), value)
+	if err != nil {
+		span.Finish()
+		return nil, err
+	}
+
+	rows := make([]sql.Row, len(elems))
+	for i, elem := range elems {
+		rows[i], err = udf.ScriptRow(t.description(), elem, t.schema)
+		if err != nil {
+			span.Finish()
+			return nil, err
+		}
+	}
+
+	return sql.NewSpanIter(span, sql.RowsToRowIter(rows...)), nil
+}
+
+// WithInferredSchema evaluates the function and returns the table with the
+// schema inferred from its result, which is kept to be returned as rows
+// instead of evaluating the function again. Tables with a schema are
+// returned as they are.
+func (t *TableFunction) WithInferredSchema(ctx *sql.Context) (*TableFunction, error) {
+	if t.schema != nil {
+		return t, nil
+	}
+
+	value, err := t.Function.Eval(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	elems, err := udf.ScriptElements(t.description(), value)
+	if err != nil {
+		return nil, err
+	}
+
+	schema, err := udf.InferScriptSchema(t.description(), value, elems)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewTableFunction(t.name, expression.NewLiteral(value, t.Function.Type()), schema), nil
+}
+
+func (t *TableFunction) String() string {
+	var columns = make([]string, len(t.schema))
+	for i, col := range t.schema {
+		columns[i] = fmt.Sprintf("%s %s", col.Name, col.Type)
+	}
+
+	pr := sql.NewTreePrinter()
+	_ = pr.WriteNode("TableFunction(%s)", t.name)
+	_ = pr.WriteChildren(
+		fmt.Sprintf("Function(%s)", t.Function),
+		fmt.Sprintf("Columns(%s)", strings.Join(columns, ", ")),
+	)
+	return pr.String()
+}
+
+// description is the name of the table in errors.
+func (t *TableFunction) description() string {
+	return "table function " + t.name
+}
@@ -0,0 +1,126 @@
+package plan
+
+import (
+	"testing"
+
+	"github.com/src-d/go-mysql-server/sql"
+	"github.com/src-d/go-mysql-server/sql/expression"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTableFunction(t *testing.T) {
+	testCases := []struct {
+		name   string
+		value  interface{}
+		schema sql.Schema
+		rows   []sql.Row
+	}{
+		{
+			"objects",
+			[]interface{}{
+				map[string]interface{}{"b": "x", "a": float64(1)},
+				map[string]interface{}{"a": float64(2), "c": true},
+				nil,
+			},
+			sql.Schema{
+				{Name: "a", Type: sql.Int64, Source: "t", Nullable: true},
+				{Name: "b", Type: sql.Text, Source: "t", Nullable: true},
+				{Name: "c", Type: sql.Boolean, Source: "t", Nullable: true},
+			},
+			[]sql.Row{{int64(1), "x", nil}, {int64(2), nil, true}, {nil, nil, nil}},
+		},
+		{
+			"lists",
+			[][]interface{}{{1, 1.5, "a"}, {2, 3, []interface{}{1}}},
+			sql.Schema{
+				{Name: "column_0", Type: sql.Int64, Source: "t", Nullable: true},
+				{Name: "column_1", Type: sql.Float64, Source: "t", Nullable: true},
+				{Name: "column_2", Type: sql.JSON, Source: "t", Nullable: true},
+			},
+			[]sql.Row{
+				{int64(1), float64(1.5), []byte(`"a"`)},
+				{int64(2), float64(3), []byte(`[1]`)},
+			},
+		},
+		{
+			"values",
+			[]string{"a", "b"},
+			sql.Schema{{Name: "value", Type: sql.Text, Source: "t", Nullable: true}},
+			[]sql.Row{{"a"}, {"b"}},
+		},
+		{
+			"json document",
+			`[{"a": 1}]`,
+			sql.Schema{{Name: "a", Type: sql.Int64, Source: "t", Nullable: true}},
+			[]sql.Row{{int64(1)}},
+		},
+		{
+			"empty",
+			nil,
+			sql.Schema{{Name: "value", Type: sql.Text, Source: "t", Nullable: true}},
+			nil,
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			require := require.New(t)
+			ctx := sql.NewEmptyContext()
+
+			fn := expression.NewLiteral(tt.value, sql.JSON)
+			table, err := NewTableFunction("t", fn, nil).WithInferredSchema(ctx)
+			require.NoError(err)
+			require.True(table.Resolved())
+			require.Equal(tt.schema, table.Schema())
+
+			rows, err := sql.NodeToRows(ctx, table)
+			require.NoError(err)
+			require.Equal(tt.rows, rows)
+		})
+	}
+}
+
+func TestTableFunctionDeclaredSchema(t *testing.T) {
+	require := require.New(t)
+	ctx := sql.NewEmptyContext()
+
+	value := []interface{}{
+		map[string]interface{}{"A": "1", "b": 2},
+		[]interface{}{3},
+	}
+	table := NewTableFunction("t", expression.NewLiteral(value, sql.JSON), sql.Schema{
+		{Name: "a", Type: sql.Int32},
+		{Name: "b", Type: sql.Text},
+	})
+	require.True(table.Resolved())
+
+	inferred, err := table.WithInferredSchema(ctx)
+	require.NoError(err)
+	require.Equal(table, inferred)
+
+	rows, err := sql.NodeToRows(ctx, table)
+	require.NoError(err)
+	require.Equal([]sql.Row{{int32(1), "2"}, {int32(3), nil}}, rows)
+
+	value = []interface{}{map[string]interface{}{"a": "foo"}}
+	table = NewTableFunction("t", expression.NewLiteral(value, sql.JSON), sql.Schema{
+		{Name: "a", Type: sql.Int64},
+	})
+	_, err = sql.NodeToRows(ctx, table)
+	require.True(ErrInvalidTableFunctionResult.Is(err))
+}
+
+func TestTableFunctionInvalidResult(t *testing.T) {
+	require := require.New(t)
+	ctx := sql.NewEmptyContext()
+
+	for _, value := range []interface{}{
+		int64(1),
+		"{",
+		[]interface{}{map[string]interface{}{"a": 1}, []interface{}{1}},
+	} {
+		table := NewTableFunction("t", expression.NewLiteral(value, sql.JSON), nil)
+		_, err := table.WithInferredSchema(ctx)
+		require.True(ErrInvalidTableFunctionResult.Is(err), "%v", value)
+	}
+}
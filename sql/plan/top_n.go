@@ -0,0 +1,231 @@
+package plan
+
+import (
+	"container/heap"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	opentracing "github.com/opentracing/opentracing-go"
+	"github.com/src-d/go-mysql-server/sql"
+)
+
+// TopN returns the first rows of its child sorted by some fields, skipping
+// some of them, which is the same as a Sort followed by an Offset and a
+// Limit, but only the rows that may be returned are kept in memory.
+type TopN struct {
+	UnaryNode
+	SortFields []SortField
+	Limit      int64
+	Offset     int64
+}
+
+var _ sql.Expressioner = (*TopN)(nil)
+
+// NewTopN creates a new TopN node that returns up to limit rows after
+// skipping the first offset ones.
+func NewTopN(sortFields []SortField, limit, offset int64, child sql.Node) *TopN {
+	return &TopN{
+		UnaryNode:  UnaryNode{child},
+		SortFields: sortFields,
+		Limit:      limit,
+		Offset:     offset,
+	}
+}
+
+// Resolved implements the Resolvable interface.
+func (t *TopN) Resolved() bool {
+	for _, f := range t.SortFields {
+		if !f.Column.Resolved() {
+			return false
+		}
+	}
+	return t.Child.Resolved()
+}
+
+// RowIter implements the Node interface.
+func (t *TopN) RowIter(ctx *sql.Context) (sql.RowIter, error) {
+	span, ctx := ctx.Span("plan.TopN", opentracing.Tags{
+		"limit":  t.Limit,
+		"offset": t.Offset,
+	})
+
+	i, err := t.Child.RowIter(ctx)
+	if err != nil {
+		span.Finish()
+		return nil, err
+	}
+
+	return sql.NewSpanIter(span, &topNIter{ctx: ctx, topN: t, childIter: i}), nil
+}
+
+func (t *TopN) String() string {
+	pr := sql.NewTreePrinter()
+	var fields = make([]string, len(t.SortFields))
+	for i, f := range t.SortFields {
+		fields[i] = fmt.Sprintf("%s %s", f.Column, f.Order)
+	}
+
+	limit := fmt.Sprintf("limit=%d", t.Limit)
+	if t.Offset > 0 {
+		limit += fmt.Sprintf(", offset=%d", t.Offset)
+	}
+
+	_ = pr.WriteNode("TopN(%s; %s)", strings.Join(fields, ", "), limit)
+	_ = pr.WriteChildren(t.Child.String())
+	return pr.String()
+}
+
+// Expressions implements the Expressioner interface.
+func (t *TopN) Expressions() []sql.Expression {
+	var exprs = make([]sql.Expression, len(t.SortFields))
+	for i, f := range t.SortFields {
+		exprs[i] = f.Column
+	}
+	return exprs
+}
+
+// WithChildren implements the Node interface.
+func (t *TopN) WithChildren(children ...sql.Node) (sql.Node, error) {
+	if len(children) != 1 {
+		return nil, sql.ErrInvalidChildrenNumber.New(t, len(children), 1)
+	}
+
+	return NewTopN(t.SortFields, t.Limit, t.Offset, children[0]), nil
+}
+
+// WithExpressions implements the Expressioner interface.
+func (t *TopN) WithExpressions(exprs ...sql.Expression) (sql.Node, error) {
+	if len(exprs) != len(t.SortFields) {
+		return nil, sql.ErrInvalidChildrenNumber.New(t, len(exprs), len(t.SortFields))
+	}
+
+	var fields = make([]SortField, len(t.SortFields))
+	for i, expr := range exprs {
+		fields[i] = SortField{
+			Column:       expr,
+			NullOrdering: t.SortFields[i].NullOrdering,
+			Order:        t.SortFields[i].Order,
+		}
+	}
+
+	return NewTopN(fields, t.Limit, t.Offset, t.Child), nil
+}
+
+type topNIter struct {
+	ctx       *sql.Context
+	topN      *TopN
+	childIter sql.RowIter
+	rows      []sql.Row
+	idx       int
+	computed  bool
+}
+
+func (i *topNIter) Next() (sql.Row, error) {
+	if !i.computed {
+		i.computed = true
+		if err := i.computeRows(); err != nil {
+			return nil, err
+		}
+	}
+
+	if i.idx >= len(i.rows) {
+		return nil, io.EOF
+	}
+
+	row := i.rows[i.idx]
+	i.idx++
+	return row, nil
+}
+
+// computeRows keeps the first rows in a heap whose top is the last of them,
+// which is replaced when a row that goes before is found.
+func (i *topNIter) computeRows() error {
+	n := i.topN.Limit + i.topN.Offset
+	h := &topNHeap{sorter: &sorter{sortFields: i.topN.SortFields, ctx: i.ctx}}
+
+	for pos := 0; ; pos++ {
+		row, err := i.childIter.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+
+		if n <= 0 {
+			continue
+		}
+
+		item := topNItem{row, pos}
+		if int64(h.Len()) < n {
+			heap.Push(h, item)
+		} else if h.before(item, h.items[0]) {
+			h.items[0] = item
+			heap.Fix(h, 0)
+		}
+
+		if h.sorter.lastError != nil {
+			return h.sorter.lastError
+		}
+	}
+
+	sort.Slice(h.items, func(a, b int) bool {
+		return h.before(h.items[a], h.items[b])
+	})
+	if h.sorter.lastError != nil {
+		return h.sorter.lastError
+	}
+
+	for idx, item := range h.items {
+		if int64(idx) >= i.topN.Offset {
+			i.rows = append(i.rows, item.row)
+		}
+	}
+	return nil
+}
+
+func (i *topNIter) Close() error {
+	i.rows = nil
+	return i.childIter.Close()
+}
+
+type topNItem struct {
+	row sql.Row
+	pos int
+}
+
+// topNHeap is a heap of rows whose top is the one that goes last. Rows that
+// are equal go in the order they were read, so that the result is the same
+// as with a stable sort.
+type topNHeap struct {
+	sorter *sorter
+	items  []topNItem
+}
+
+func (h *topNHeap) before(a, b topNItem) bool {
+	if h.sorter.less(a.row, b.row) {
+		return true
+	}
+
+	if h.sorter.less(b.row, a.row) {
+		return false
+	}
+
+	return a.pos < b.pos
+}
+
+func (h *topNHeap) Len() int { return len(h.items) }
+
+func (h *topNHeap) Less(i, j int) bool { return h.before(h.items[j], h.items[i]) }
+
+func (h *topNHeap) Swap(i, j int) { h.items[i], h.items[j] = h.items[j], h.items[i] }
+
+func (h *topNHeap) Push(x interface{}) { h.items = append(h.items, x.(topNItem)) }
+
+func (h *topNHeap) Pop() interface{} {
+	item := h.items[len(h.items)-1]
+	h.items = h.items[:len(h.items)-1]
+	return item
+}
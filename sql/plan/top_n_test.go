@@ -0,0 +1,76 @@
+package plan
+
+import (
+	"fmt"
+	"math/rand"
+	"testing"
+
+	"github.com/src-d/go-mysql-server/memory"
+	"github.com/src-d/go-mysql-server/sql"
+	"github.com/src-d/go-mysql-server/sql/expression"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTopN(t *testing.T) {
+	ctx := sql.NewEmptyContext()
+	schema := sql.Schema{
+		{Name: "a", Type: sql.Int64, Source: "t", Nullable: true},
+		{Name: "b", Type: sql.Int64, Source: "t"},
+	}
+	table := memory.NewPartitionedTable("t", schema, 3)
+
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < 100; i++ {
+		var a interface{} = int64(r.Intn(10))
+		if r.Intn(10) == 0 {
+			a = nil
+		}
+		require.NoError(t, table.Insert(ctx, sql.NewRow(a, int64(i))))
+	}
+
+	a := expression.NewGetFieldWithTable(0, sql.Int64, "t", "a", true)
+	fieldSets := map[string][]SortField{
+		"asc":           {{Column: a, Order: Ascending, NullOrdering: NullsFirst}},
+		"desc":          {{Column: a, Order: Descending, NullOrdering: NullsLast}},
+		"nulls last":    {{Column: a, Order: Ascending, NullOrdering: NullsLast}},
+		"no sort field": nil,
+	}
+
+	child := NewResolvedTable(table)
+	for name, fields := range fieldSets {
+		for _, tt := range [][2]int64{{10, 0}, {10, 5}, {1, 0}, {0, 3}, {95, 10}, {200, 0}} {
+			limit, offset := tt[0], tt[1]
+			t.Run(fmt.Sprintf("%s/limit=%d/offset=%d", name, limit, offset), func(t *testing.T) {
+				require := require.New(t)
+
+				// The rows of the table are always read in the same order,
+				// so equal rows must be in the same order too.
+				expected, err := sql.NodeToRows(ctx, NewLimit(limit, NewOffset(offset, NewSort(fields, child))))
+				require.NoError(err)
+
+				rows, err := sql.NodeToRows(ctx, NewTopN(fields, limit, offset, child))
+				require.NoError(err)
+				require.Equal(expected, rows)
+			})
+		}
+	}
+}
+
+func TestTopNString(t *testing.T) {
+	require := require.New(t)
+
+	child := NewResolvedTable(memory.NewTable("foo", nil))
+	fields := []SortField{
+		{Column: expression.NewGetFieldWithTable(0, sql.Int64, "foo", "a", true), Order: Descending},
+		{Column: expression.NewGetFieldWithTable(1, sql.Int64, "foo", "b", true), Order: Ascending},
+	}
+
+	require.Equal(
+		"TopN(foo.a DESC, foo.b ASC; limit=10)\n └─ Table(foo)\n",
+		NewTopN(fields, 10, 0, child).String(),
+	)
+	require.Equal(
+		"TopN(foo.a DESC, foo.b ASC; limit=10, offset=5)\n └─ Table(foo)\n",
+		NewTopN(fields, 10, 5, child).String(),
+	)
+}
@@ -1,6 +1,91 @@
 package plan
 
-import "github.com/src-d/go-mysql-server/sql"
+import (
+	"fmt"
+
+	"github.com/src-d/go-mysql-server/sql"
+)
+
+// StartTransaction starts a new transaction in the session. Any transaction
+// in progress is committed first.
+type StartTransaction struct {
+	Catalog *sql.Catalog
+}
+
+// NewStartTransaction creates a new StartTransaction node.
+func NewStartTransaction() *StartTransaction { return new(StartTransaction) }
+
+// RowIter implements the sql.Node interface.
+func (s *StartTransaction) RowIter(ctx *sql.Context) (sql.RowIter, error) {
+	if tx := ctx.Transaction(); tx != nil {
+		if err := tx.Commit(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	if _, err := sql.BeginTransaction(ctx, s.Catalog.AllDatabases(), false); err != nil {
+		return nil, err
+	}
+
+	return sql.RowsToRowIter(), nil
+}
+
+func (*StartTransaction) String() string { return "START TRANSACTION" }
+
+// WithChildren implements the Node interface.
+func (s *StartTransaction) WithChildren(children ...sql.Node) (sql.Node, error) {
+	if len(children) != 0 {
+		return nil, sql.ErrInvalidChildrenNumber.New(s, len(children), 0)
+	}
+
+	return s, nil
+}
+
+// Resolved implements the sql.Node interface.
+func (*StartTransaction) Resolved() bool { return true }
+
+// Children implements the sql.Node interface.
+func (*StartTransaction) Children() []sql.Node { return nil }
+
+// Schema implements the sql.Node interface.
+func (*StartTransaction) Schema() sql.Schema { return nil }
+
+// Commit makes the changes performed in a transaction permanent.
+type Commit struct{}
+
+// NewCommit creates a new Commit node.
+func NewCommit() *Commit { return new(Commit) }
+
+// RowIter implements the sql.Node interface.
+func (*Commit) RowIter(ctx *sql.Context) (sql.RowIter, error) {
+	if tx := ctx.Transaction(); tx != nil {
+		if err := tx.Commit(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	return sql.RowsToRowIter(), nil
+}
+
+func (*Commit) String() string { return "COMMIT" }
+
+// WithChildren implements the Node interface.
+func (c *Commit) WithChildren(children ...sql.Node) (sql.Node, error) {
+	if len(children) != 0 {
+		return nil, sql.ErrInvalidChildrenNumber.New(c, len(children), 0)
+	}
+
+	return c, nil
+}
+
+// Resolved implements the sql.Node interface.
+func (*Commit) Resolved() bool { return true }
+
+// Children implements the sql.Node interface.
+func (*Commit) Children() []sql.Node { return nil }
+
+// Schema implements the sql.Node interface.
+func (*Commit) Schema() sql.Schema { return nil }
 
 // Rollback undoes the changes performed in a transaction.
 type Rollback struct{}
@@ -9,7 +94,13 @@ type Rollback struct{}
 func NewRollback() *Rollback { return new(Rollback) }
 
 // RowIter implements the sql.Node interface.
-func (*Rollback) RowIter(*sql.Context) (sql.RowIter, error) {
+func (*Rollback) RowIter(ctx *sql.Context) (sql.RowIter, error) {
+	if tx := ctx.Transaction(); tx != nil {
+		if err := tx.Rollback(ctx); err != nil {
+			return nil, err
+		}
+	}
+
 	return sql.RowsToRowIter(), nil
 }
 
@@ -32,3 +123,138 @@ func (*Rollback) Children() []sql.Node { return nil }
 
 // Schema implements the sql.Node interface.
 func (*Rollback) Schema() sql.Schema { return nil }
+
+// CreateSavepoint creates a savepoint in the transaction in progress.
+type CreateSavepoint struct {
+	Name string
+}
+
+// NewCreateSavepoint creates a new CreateSavepoint node.
+func NewCreateSavepoint(name string) *CreateSavepoint {
+	return &CreateSavepoint{Name: name}
+}
+
+// RowIter implements the sql.Node interface.
+func (c *CreateSavepoint) RowIter(ctx *sql.Context) (sql.RowIter, error) {
+	// Savepoints outside of a transaction are ignored, like MySQL does.
+	if tx := ctx.Transaction(); tx != nil {
+		if err := tx.CreateSavepoint(ctx, c.Name); err != nil {
+			return nil, err
+		}
+	}
+
+	return sql.RowsToRowIter(), nil
+}
+
+func (c *CreateSavepoint) String() string { return fmt.Sprintf("SAVEPOINT %s", c.Name) }
+
+// WithChildren implements the Node interface.
+func (c *CreateSavepoint) WithChildren(children ...sql.Node) (sql.Node, error) {
+	if len(children) != 0 {
+		return nil, sql.ErrInvalidChildrenNumber.New(c, len(children), 0)
+	}
+
+	return c, nil
+}
+
+// Resolved implements the sql.Node interface.
+func (*CreateSavepoint) Resolved() bool { return true }
+
+// Children implements the sql.Node interface.
+func (*CreateSavepoint) Children() []sql.Node { return nil }
+
+// Schema implements the sql.Node interface.
+func (*CreateSavepoint) Schema() sql.Schema { return nil }
+
+// RollbackSavepoint undoes the changes performed in the transaction in
+// progress since a savepoint was created.
+type RollbackSavepoint struct {
+	Name string
+}
+
+// NewRollbackSavepoint creates a new RollbackSavepoint node.
+func NewRollbackSavepoint(name string) *RollbackSavepoint {
+	return &RollbackSavepoint{Name: name}
+}
+
+// RowIter implements the sql.Node interface.
+func (r *RollbackSavepoint) RowIter(ctx *sql.Context) (sql.RowIter, error) {
+	tx := ctx.Transaction()
+	if tx == nil {
+		return nil, sql.ErrSavepointNotFound.New(r.Name)
+	}
+
+	if err := tx.RollbackToSavepoint(ctx, r.Name); err != nil {
+		return nil, err
+	}
+
+	return sql.RowsToRowIter(), nil
+}
+
+func (r *RollbackSavepoint) String() string {
+	return fmt.Sprintf("ROLLBACK TO SAVEPOINT %s", r.Name)
+}
+
+// WithChildren implements the Node interface.
+func (r *RollbackSavepoint) WithChildren(children ...sql.Node) (sql.Node, error) {
+	if len(children) != 0 {
+		return nil, sql.ErrInvalidChildrenNumber.New(r, len(children), 0)
+	}
+
+	return r, nil
+}
+
+// Resolved implements the sql.Node interface.
+func (*RollbackSavepoint) Resolved() bool { return true }
+
+// Children implements the sql.Node interface.
+func (*RollbackSavepoint) Children() []sql.Node { return nil }
+
+// Schema implements the sql.Node interface.
+func (*RollbackSavepoint) Schema() sql.Schema { return nil }
+
+// ReleaseSavepoint removes a savepoint from the transaction in progress.
+type ReleaseSavepoint struct {
+	Name string
+}
+
+// NewReleaseSavepoint creates a new ReleaseSavepoint node.
+func NewReleaseSavepoint(name string) *ReleaseSavepoint {
+	return &ReleaseSavepoint{Name: name}
+}
+
+// RowIter implements the sql.Node interface.
+func (r *ReleaseSavepoint) RowIter(ctx *sql.Context) (sql.RowIter, error) {
+	tx := ctx.Transaction()
+	if tx == nil {
+		return nil, sql.ErrSavepointNotFound.New(r.Name)
+	}
+
+	if err := tx.ReleaseSavepoint(ctx, r.Name); err != nil {
+		return nil, err
+	}
+
+	return sql.RowsToRowIter(), nil
+}
+
+func (r *ReleaseSavepoint) String() string {
+	return fmt.Sprintf("RELEASE SAVEPOINT %s", r.Name)
+}
+
+// WithChildren implements the Node interface.
+func (r *ReleaseSavepoint) WithChildren(children ...sql.Node) (sql.Node, error) {
+	if len(children) != 0 {
+		return nil, sql.ErrInvalidChildrenNumber.New(r, len(children), 0)
+	}
+
+	return r, nil
+}
+
+// Resolved implements the sql.Node interface.
+func (*ReleaseSavepoint) Resolved() bool { return true }
+
+// Children implements the sql.Node interface.
+func (*ReleaseSavepoint) Children() []sql.Node { return nil }
+
+// Schema implements the sql.Node interface.
+func (*ReleaseSavepoint) Schema() sql.Schema { return nil }
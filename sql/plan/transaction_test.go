@@ -0,0 +1,52 @@
+package plan
+
+import (
+	"testing"
+
+	"github.com/src-d/go-mysql-server/memory"
+	"github.com/src-d/go-mysql-server/sql"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTransactionNodes(t *testing.T) {
+	require := require.New(t)
+
+	catalog := sql.NewCatalog()
+	catalog.AddDatabase(memory.NewDatabase("db"))
+	ctx := sql.NewEmptyContext()
+
+	run := func(n sql.Node) error {
+		_, err := sql.NodeToRows(ctx, n)
+		return err
+	}
+
+	// Savepoints are ignored outside transactions, but they can't be used.
+	require.NoError(run(NewCreateSavepoint("a")))
+	require.True(sql.ErrSavepointNotFound.Is(run(NewRollbackSavepoint("a"))))
+	require.True(sql.ErrSavepointNotFound.Is(run(NewReleaseSavepoint("a"))))
+	require.NoError(run(NewCommit()))
+	require.NoError(run(NewRollback()))
+
+	begin := NewStartTransaction()
+	begin.Catalog = catalog
+	require.NoError(run(begin))
+	tx := ctx.Transaction()
+	require.NotNil(tx)
+	require.False(tx.Implicit)
+
+	require.NoError(run(NewCreateSavepoint("a")))
+	require.NoError(run(NewCreateSavepoint("b")))
+	require.Equal([]string{"a", "b"}, tx.Savepoints())
+	require.NoError(run(NewRollbackSavepoint("a")))
+	require.Equal([]string{"a"}, tx.Savepoints())
+	require.NoError(run(NewReleaseSavepoint("a")))
+	require.Len(tx.Savepoints(), 0)
+
+	// Starting a transaction commits the one in progress.
+	require.NoError(run(begin))
+	require.NotNil(ctx.Transaction())
+	require.True(tx != ctx.Transaction())
+
+	require.NoError(run(NewCommit()))
+	require.Nil(ctx.Transaction())
+}
@@ -0,0 +1,141 @@
+package plan
+
+import (
+	"io"
+
+	"github.com/src-d/go-mysql-server/sql"
+)
+
+// Union is a node that returns the rows of its left child followed by the
+// rows of its right child. If Distinct is true, duplicated rows are only
+// returned once.
+type Union struct {
+	BinaryNode
+	Distinct bool
+}
+
+// NewUnion creates a new Union node that returns all the rows of both
+// children, as UNION ALL does.
+func NewUnion(left, right sql.Node) *Union {
+	return &Union{
+		BinaryNode: BinaryNode{Left: left, Right: right},
+	}
+}
+
+// NewDistinctUnion creates a new Union node that only returns distinct rows,
+// as UNION and UNION DISTINCT do.
+func NewDistinctUnion(left, right sql.Node) *Union {
+	return &Union{
+		BinaryNode: BinaryNode{Left: left, Right: right},
+		Distinct:   true,
+	}
+}
+
+// Schema implements the Node interface. The schema of the union is the one
+// of its left child, but a column is nullable if it's nullable in any of the
+// children.
+func (u *Union) Schema() sql.Schema {
+	left := u.Left.Schema()
+	right := u.Right.Schema()
+
+	schema := make(sql.Schema, len(left))
+	for i, col := range left {
+		c := *col
+		if i < len(right) && right[i].Nullable {
+			c.Nullable = true
+		}
+		schema[i] = &c
+	}
+
+	return schema
+}
+
+// RowIter implements the Node interface.
+func (u *Union) RowIter(ctx *sql.Context) (sql.RowIter, error) {
+	var name = "plan.Union"
+	if u.Distinct {
+		name = "plan.DistinctUnion"
+	}
+	span, ctx := ctx.Span(name)
+
+	var iter sql.RowIter = &unionIter{ctx: ctx, branches: u.Children()}
+	if u.Distinct {
+		iter = newDistinctIter(ctx, iter)
+	}
+
+	return sql.NewSpanIter(span, iter), nil
+}
+
+// WithChildren implements the Node interface.
+func (u *Union) WithChildren(children ...sql.Node) (sql.Node, error) {
+	if len(children) != 2 {
+		return nil, sql.ErrInvalidChildrenNumber.New(u, len(children), 2)
+	}
+
+	nu := *u
+	nu.Left = children[0]
+	nu.Right = children[1]
+	return &nu, nil
+}
+
+func (u *Union) String() string {
+	pr := sql.NewTreePrinter()
+	if u.Distinct {
+		_ = pr.WriteNode("Union(distinct)")
+	} else {
+		_ = pr.WriteNode("Union")
+	}
+	_ = pr.WriteChildren(u.Left.String(), u.Right.String())
+	return pr.String()
+}
+
+// unionIter iterates the branches of a union one after another. Branches
+// are only opened once the previous one has been exhausted.
+type unionIter struct {
+	ctx      *sql.Context
+	branches []sql.Node
+	current  sql.RowIter
+}
+
+func (i *unionIter) Next() (sql.Row, error) {
+	for {
+		if i.current == nil {
+			if len(i.branches) == 0 {
+				return nil, io.EOF
+			}
+
+			iter, err := i.branches[0].RowIter(i.ctx)
+			if err != nil {
+				return nil, err
+			}
+
+			i.branches = i.branches[1:]
+			i.current = iter
+		}
+
+		row, err := i.current.Next()
+		if err == io.EOF {
+			if err := i.current.Close(); err != nil {
+				return nil, err
+			}
+			i.current = nil
+			continue
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		return row, nil
+	}
+}
+
+func (i *unionIter) Close() error {
+	i.branches = nil
+	if i.current != nil {
+		err := i.current.Close()
+		i.current = nil
+		return err
+	}
+	return nil
+}
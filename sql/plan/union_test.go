@@ -0,0 +1,101 @@
+package plan
+
+import (
+	"testing"
+
+	"github.com/src-d/go-mysql-server/memory"
+	"github.com/src-d/go-mysql-server/sql"
+	"github.com/src-d/go-mysql-server/sql/expression"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUnion(t *testing.T) {
+	ctx := sql.NewEmptyContext()
+	left, right := unionTestTables(t)
+
+	testCases := []struct {
+		name     string
+		node     sql.Node
+		expected []sql.Row
+	}{
+		{
+			"union all",
+			NewUnion(NewResolvedTable(left), NewResolvedTable(right)),
+			[]sql.Row{
+				{"john"}, {"jane"}, {"john"},
+				{"martha"}, {"jane"},
+			},
+		},
+		{
+			"union distinct",
+			NewDistinctUnion(NewResolvedTable(left), NewResolvedTable(right)),
+			[]sql.Row{{"john"}, {"jane"}, {"martha"}},
+		},
+		{
+			"nested union",
+			NewUnion(
+				NewDistinctUnion(NewResolvedTable(left), NewResolvedTable(right)),
+				NewFilter(
+					expression.NewEquals(
+						expression.NewGetField(0, sql.Text, "name", true),
+						expression.NewLiteral("martha", sql.Text),
+					),
+					NewResolvedTable(right),
+				),
+			),
+			[]sql.Row{{"john"}, {"jane"}, {"martha"}, {"martha"}},
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			require := require.New(t)
+
+			iter, err := tt.node.RowIter(ctx)
+			require.NoError(err)
+
+			rows, err := sql.RowIterToRows(iter)
+			require.NoError(err)
+			require.Equal(tt.expected, rows)
+		})
+	}
+}
+
+func TestUnionSchema(t *testing.T) {
+	require := require.New(t)
+	left, right := unionTestTables(t)
+
+	schema := NewUnion(NewResolvedTable(left), NewResolvedTable(right)).Schema()
+	require.Equal(sql.Schema{
+		{Name: "name", Type: sql.Text, Source: "left", Nullable: true},
+	}, schema)
+}
+
+func unionTestTables(t *testing.T) (*memory.Table, *memory.Table) {
+	t.Helper()
+	ctx := sql.NewEmptyContext()
+
+	left := memory.NewTable("left", sql.Schema{
+		{Name: "name", Type: sql.Text, Source: "left"},
+	})
+	right := memory.NewTable("right", sql.Schema{
+		{Name: "name", Type: sql.Text, Source: "right", Nullable: true},
+	})
+
+	for _, r := range []sql.Row{
+		sql.NewRow("john"),
+		sql.NewRow("jane"),
+		sql.NewRow("john"),
+	} {
+		require.NoError(t, left.Insert(ctx, r))
+	}
+
+	for _, r := range []sql.Row{
+		sql.NewRow("martha"),
+		sql.NewRow("jane"),
+	} {
+		require.NoError(t, right.Insert(ctx, r))
+	}
+
+	return left, right
+}
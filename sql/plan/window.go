@@ -0,0 +1,953 @@
+package plan
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	opentracing "github.com/opentracing/opentracing-go"
+	"github.com/src-d/go-mysql-server/sql"
+	"github.com/src-d/go-mysql-server/sql/expression"
+	errors "gopkg.in/src-d/go-errors.v1"
+)
+
+var (
+	// ErrWindowExpressionEval is returned when a window expression is
+	// evaluated outside of a Window node.
+	ErrWindowExpressionEval = errors.NewKind("window expression %s can only be evaluated in a Window node")
+
+	// ErrInvalidWindowFrame is returned when the frame of a window is not
+	// valid.
+	ErrInvalidWindowFrame = errors.NewKind("invalid window frame: %s")
+
+	// ErrNotWindowFunction is returned when the function of a window
+	// expression is neither a window function nor an aggregation.
+	ErrNotWindowFunction = errors.NewKind("%s is not a window function or an aggregation")
+)
+
+// WindowFrameUnit is the unit of the bounds of a window frame.
+type WindowFrameUnit byte
+
+const (
+	// RowsFrame has bounds that are an offset of rows from the current row.
+	RowsFrame WindowFrameUnit = iota
+	// RangeFrame has bounds that are an offset of the value of the ORDER BY
+	// expression of the current row.
+	RangeFrame
+)
+
+func (u WindowFrameUnit) String() string {
+	switch u {
+	case RowsFrame:
+		return "ROWS"
+	case RangeFrame:
+		return "RANGE"
+	default:
+		return "invalid WindowFrameUnit"
+	}
+}
+
+// WindowFrameBoundType is the kind of bound of a window frame.
+type WindowFrameBoundType byte
+
+const (
+	// UnboundedPreceding is the first row of the partition.
+	UnboundedPreceding WindowFrameBoundType = iota
+	// Preceding is the given offset before the current row.
+	Preceding
+	// CurrentRow is the current row. In RANGE frames, it includes the peers
+	// of the current row.
+	CurrentRow
+	// Following is the given offset after the current row.
+	Following
+	// UnboundedFollowing is the last row of the partition.
+	UnboundedFollowing
+)
+
+// WindowFrameBound is the start or the end of a window frame.
+type WindowFrameBound struct {
+	Type WindowFrameBoundType
+	// Offset of the bound, only used in Preceding and Following bounds.
+	Offset sql.Expression
+}
+
+func (b WindowFrameBound) String() string {
+	switch b.Type {
+	case UnboundedPreceding:
+		return "UNBOUNDED PRECEDING"
+	case Preceding:
+		return fmt.Sprintf("%s PRECEDING", b.Offset)
+	case CurrentRow:
+		return "CURRENT ROW"
+	case Following:
+		return fmt.Sprintf("%s FOLLOWING", b.Offset)
+	case UnboundedFollowing:
+		return "UNBOUNDED FOLLOWING"
+	default:
+		return "invalid WindowFrameBound"
+	}
+}
+
+// WindowFrame is the set of rows of the partition a function is evaluated
+// over for each row.
+type WindowFrame struct {
+	Unit  WindowFrameUnit
+	Start WindowFrameBound
+	End   WindowFrameBound
+}
+
+// NewWindowFrame creates a new window frame, making sure its bounds are
+// valid.
+func NewWindowFrame(unit WindowFrameUnit, start, end WindowFrameBound) (*WindowFrame, error) {
+	if start.Type == UnboundedFollowing {
+		return nil, ErrInvalidWindowFrame.New("frame start cannot be UNBOUNDED FOLLOWING")
+	}
+
+	if end.Type == UnboundedPreceding {
+		return nil, ErrInvalidWindowFrame.New("frame end cannot be UNBOUNDED PRECEDING")
+	}
+
+	if start.Type > end.Type {
+		return nil, ErrInvalidWindowFrame.New(
+			fmt.Sprintf("frame starting from %s cannot end with %s", start, end),
+		)
+	}
+
+	return &WindowFrame{Unit: unit, Start: start, End: end}, nil
+}
+
+func (f *WindowFrame) String() string {
+	return fmt.Sprintf("%s BETWEEN %s AND %s", f.Unit, f.Start, f.End)
+}
+
+// WindowExpression is a function evaluated over a window of rows, defined by
+// an OVER clause. The function can be either a sql.WindowFunction or a
+// sql.Aggregation, which is computed over the frame of each row.
+type WindowExpression struct {
+	Function    sql.Expression
+	PartitionBy []sql.Expression
+	OrderBy     []SortField
+	// Frame of the window, if any.
+	Frame *WindowFrame
+}
+
+// NewWindowExpression creates a new window expression.
+func NewWindowExpression(
+	fn sql.Expression,
+	partitionBy []sql.Expression,
+	orderBy []SortField,
+	frame *WindowFrame,
+) *WindowExpression {
+	return &WindowExpression{
+		Function:    fn,
+		PartitionBy: partitionBy,
+		OrderBy:     orderBy,
+		Frame:       frame,
+	}
+}
+
+// Resolved implements the Expression interface.
+func (w *WindowExpression) Resolved() bool {
+	return expressionsResolved(w.Children()...)
+}
+
+// IsNullable implements the Expression interface.
+func (w *WindowExpression) IsNullable() bool {
+	return w.Function.IsNullable()
+}
+
+// Type implements the Expression interface.
+func (w *WindowExpression) Type() sql.Type {
+	return w.Function.Type()
+}
+
+// Children implements the Expression interface.
+func (w *WindowExpression) Children() []sql.Expression {
+	var children = make([]sql.Expression, 0, 1+len(w.PartitionBy)+len(w.OrderBy))
+	children = append(children, w.Function)
+	children = append(children, w.PartitionBy...)
+	for _, f := range w.OrderBy {
+		children = append(children, f.Column)
+	}
+	return children
+}
+
+// WithChildren implements the Expression interface.
+func (w *WindowExpression) WithChildren(children ...sql.Expression) (sql.Expression, error) {
+	expected := 1 + len(w.PartitionBy) + len(w.OrderBy)
+	if len(children) != expected {
+		return nil, sql.ErrInvalidChildrenNumber.New(w, len(children), expected)
+	}
+
+	var partitionBy = make([]sql.Expression, len(w.PartitionBy))
+	copy(partitionBy, children[1:])
+
+	var orderBy = make([]SortField, len(w.OrderBy))
+	for i, f := range w.OrderBy {
+		orderBy[i] = SortField{
+			Column:       children[1+len(partitionBy)+i],
+			Order:        f.Order,
+			NullOrdering: f.NullOrdering,
+		}
+	}
+
+	return NewWindowExpression(children[0], partitionBy, orderBy, w.Frame), nil
+}
+
+// Eval implements the Expression interface.
+func (w *WindowExpression) Eval(ctx *sql.Context, row sql.Row) (interface{}, error) {
+	return nil, ErrWindowExpressionEval.New(w)
+}
+
+func (w *WindowExpression) String() string {
+	var clauses []string
+	if len(w.PartitionBy) > 0 {
+		var exprs = make([]string, len(w.PartitionBy))
+		for i, e := range w.PartitionBy {
+			exprs[i] = e.String()
+		}
+		clauses = append(clauses, "PARTITION BY "+strings.Join(exprs, ", "))
+	}
+
+	if len(w.OrderBy) > 0 {
+		var fields = make([]string, len(w.OrderBy))
+		for i, f := range w.OrderBy {
+			fields[i] = fmt.Sprintf("%s %s", f.Column, f.Order)
+		}
+		clauses = append(clauses, "ORDER BY "+strings.Join(fields, ", "))
+	}
+
+	if w.Frame != nil {
+		clauses = append(clauses, w.Frame.String())
+	}
+
+	return fmt.Sprintf("%s OVER (%s)", w.Function, strings.Join(clauses, " "))
+}
+
+// frame returns the frame of the window, using the default one if none was
+// given. The default frame goes from the start of the partition to the
+// current row and its peers if there is an ORDER BY clause, or it's the
+// whole partition otherwise.
+func (w *WindowExpression) frame() *WindowFrame {
+	if w.Frame != nil {
+		return w.Frame
+	}
+
+	if len(w.OrderBy) > 0 {
+		return &WindowFrame{
+			Unit:  RangeFrame,
+			Start: WindowFrameBound{Type: UnboundedPreceding},
+			End:   WindowFrameBound{Type: CurrentRow},
+		}
+	}
+
+	return &WindowFrame{
+		Unit:  RowsFrame,
+		Start: WindowFrameBound{Type: UnboundedPreceding},
+		End:   WindowFrameBound{Type: UnboundedFollowing},
+	}
+}
+
+// Window is a projection that can contain window expressions. To compute
+// them, all the rows of the child are read, and then sorted and partitioned
+// as each one of the windows requires.
+type Window struct {
+	UnaryNode
+	SelectExprs []sql.Expression
+}
+
+var _ sql.Expressioner = (*Window)(nil)
+
+// NewWindow creates a new Window node.
+func NewWindow(selectExprs []sql.Expression, child sql.Node) *Window {
+	return &Window{
+		UnaryNode:   UnaryNode{Child: child},
+		SelectExprs: selectExprs,
+	}
+}
+
+// Resolved implements the Resolvable interface.
+func (w *Window) Resolved() bool {
+	return w.UnaryNode.Child.Resolved() &&
+		expressionsResolved(w.SelectExprs...)
+}
+
+// Schema implements the Node interface.
+func (w *Window) Schema() sql.Schema {
+	var s = make(sql.Schema, len(w.SelectExprs))
+	for i, e := range w.SelectExprs {
+		var name string
+		if n, ok := e.(sql.Nameable); ok {
+			name = n.Name()
+		} else {
+			name = e.String()
+		}
+
+		var table string
+		if t, ok := e.(sql.Tableable); ok {
+			table = t.Table()
+		}
+
+		s[i] = &sql.Column{
+			Name:     name,
+			Type:     e.Type(),
+			Nullable: e.IsNullable(),
+			Source:   table,
+		}
+	}
+	return s
+}
+
+// RowIter implements the Node interface.
+func (w *Window) RowIter(ctx *sql.Context) (sql.RowIter, error) {
+	span, ctx := ctx.Span("plan.Window", opentracing.Tag{
+		Key:   "select_exprs",
+		Value: len(w.SelectExprs),
+	})
+
+	i, err := w.Child.RowIter(ctx)
+	if err != nil {
+		span.Finish()
+		return nil, err
+	}
+
+	return sql.NewSpanIter(span, &windowIter{ctx: ctx, window: w, childIter: i}), nil
+}
+
+// Expressions implements the Expressioner interface.
+func (w *Window) Expressions() []sql.Expression {
+	return w.SelectExprs
+}
+
+// WithChildren implements the Node interface.
+func (w *Window) WithChildren(children ...sql.Node) (sql.Node, error) {
+	if len(children) != 1 {
+		return nil, sql.ErrInvalidChildrenNumber.New(w, len(children), 1)
+	}
+
+	return NewWindow(w.SelectExprs, children[0]), nil
+}
+
+// WithExpressions implements the Expressioner interface.
+func (w *Window) WithExpressions(exprs ...sql.Expression) (sql.Node, error) {
+	if len(exprs) != len(w.SelectExprs) {
+		return nil, sql.ErrInvalidChildrenNumber.New(w, len(exprs), len(w.SelectExprs))
+	}
+
+	return NewWindow(exprs, w.Child), nil
+}
+
+func (w *Window) String() string {
+	pr := sql.NewTreePrinter()
+	var exprs = make([]string, len(w.SelectExprs))
+	for i, expr := range w.SelectExprs {
+		exprs[i] = expr.String()
+	}
+	_ = pr.WriteNode("Window(%s)", strings.Join(exprs, ", "))
+	_ = pr.WriteChildren(w.Child.String())
+	return pr.String()
+}
+
+type windowIter struct {
+	ctx       *sql.Context
+	window    *Window
+	childIter sql.RowIter
+	rows      []sql.Row
+	pos       int
+	computed  bool
+}
+
+func (i *windowIter) Next() (sql.Row, error) {
+	if !i.computed {
+		i.computed = true
+		if err := i.computeRows(); err != nil {
+			return nil, err
+		}
+	}
+
+	if i.pos >= len(i.rows) {
+		return nil, io.EOF
+	}
+
+	row := i.rows[i.pos]
+	i.pos++
+	return row, nil
+}
+
+func (i *windowIter) Close() error {
+	i.rows = nil
+	return i.childIter.Close()
+}
+
+// computeRows reads all the rows of the child, computes the value of every
+// window expression for each one of them and then evaluates the select
+// expressions, which read the value of the windows from extra columns
+// appended to the child rows.
+func (i *windowIter) computeRows() error {
+	var rows []sql.Row
+	for {
+		row, err := i.childIter.Next()
+		if err == io.EOF {
+			break
+		}
+
+		if err != nil {
+			return err
+		}
+
+		rows = append(rows, row)
+	}
+
+	var windows []*WindowExpression
+	offset := len(i.window.Child.Schema())
+	projections := make([]sql.Expression, len(i.window.SelectExprs))
+	for j, e := range i.window.SelectExprs {
+		var err error
+		projections[j], err = replaceWindowExpressions(e, offset, &windows)
+		if err != nil {
+			return err
+		}
+	}
+
+	var values = make([][]interface{}, len(windows))
+	for j, w := range windows {
+		var err error
+		values[j], err = evalWindow(i.ctx, w, rows)
+		if err != nil {
+			return err
+		}
+	}
+
+	i.rows = make([]sql.Row, len(rows))
+	for j, row := range rows {
+		extended := make(sql.Row, len(row), len(row)+len(windows))
+		copy(extended, row)
+		for k := range windows {
+			extended = append(extended, values[k][j])
+		}
+
+		var err error
+		i.rows[j], err = filterRow(i.ctx, projections, extended)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// replaceWindowExpressions replaces all the window expressions in the given
+// expression with fields after the given offset, appending them to windows.
+func replaceWindowExpressions(
+	e sql.Expression,
+	offset int,
+	windows *[]*WindowExpression,
+) (sql.Expression, error) {
+	if w, ok := e.(*WindowExpression); ok {
+		*windows = append(*windows, w)
+		return expression.NewGetField(
+			offset+len(*windows)-1,
+			w.Type(),
+			w.String(),
+			w.IsNullable(),
+		), nil
+	}
+
+	children := e.Children()
+	if len(children) == 0 {
+		return e, nil
+	}
+
+	var newChildren = make([]sql.Expression, len(children))
+	for i, c := range children {
+		var err error
+		newChildren[i], err = replaceWindowExpressions(c, offset, windows)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return e.WithChildren(newChildren...)
+}
+
+// evalWindow returns the values of the window expression for all the given
+// rows, in the same order.
+func evalWindow(ctx *sql.Context, w *WindowExpression, rows []sql.Row) ([]interface{}, error) {
+	partitionKeys := make([][]interface{}, len(rows))
+	orderKeys := make([][]interface{}, len(rows))
+	for i, row := range rows {
+		var err error
+		partitionKeys[i], err = evalAll(ctx, w.PartitionBy, row)
+		if err != nil {
+			return nil, err
+		}
+
+		var orderBy = make([]sql.Expression, len(w.OrderBy))
+		for j, f := range w.OrderBy {
+			orderBy[j] = f.Column
+		}
+
+		orderKeys[i], err = evalAll(ctx, orderBy, row)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var order = make([]int, len(rows))
+	for i := range order {
+		order[i] = i
+	}
+
+	var sortErr error
+	sort.SliceStable(order, func(a, b int) bool {
+		x, y := order[a], order[b]
+		cmp, err := comparePartitionKeys(w, partitionKeys[x], partitionKeys[y])
+		if err == nil && cmp == 0 {
+			cmp, err = compareOrderKeys(w, orderKeys[x], orderKeys[y])
+		}
+
+		if err != nil {
+			sortErr = err
+		}
+		return cmp < 0
+	})
+	if sortErr != nil {
+		return nil, ErrUnableSort.Wrap(sortErr)
+	}
+
+	var values = make([]interface{}, len(rows))
+	for start := 0; start < len(order); {
+		end := start + 1
+		for end < len(order) {
+			cmp, err := comparePartitionKeys(w, partitionKeys[order[start]], partitionKeys[order[end]])
+			if err != nil {
+				return nil, err
+			}
+
+			if cmp != 0 {
+				break
+			}
+			end++
+		}
+
+		indexes := order[start:end]
+		partition := &sql.WindowPartition{Rows: make([]sql.Row, len(indexes))}
+		keys := make([][]interface{}, len(indexes))
+		for i, idx := range indexes {
+			partition.Rows[i] = rows[idx]
+			keys[i] = orderKeys[idx]
+		}
+
+		if err := computeFrames(w, partition, keys); err != nil {
+			return nil, err
+		}
+
+		result, err := evalPartition(ctx, w, partition)
+		if err != nil {
+			return nil, err
+		}
+
+		for i, idx := range indexes {
+			values[idx] = result[i]
+		}
+
+		start = end
+	}
+
+	return values, nil
+}
+
+func evalAll(ctx *sql.Context, exprs []sql.Expression, row sql.Row) ([]interface{}, error) {
+	var values = make([]interface{}, len(exprs))
+	for i, e := range exprs {
+		var err error
+		values[i], err = e.Eval(ctx, row)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return values, nil
+}
+
+func comparePartitionKeys(w *WindowExpression, a, b []interface{}) (int, error) {
+	for i, e := range w.PartitionBy {
+		cmp, err := compareNullable(e.Type(), a[i], b[i])
+		if err != nil || cmp != 0 {
+			return cmp, err
+		}
+	}
+	return 0, nil
+}
+
+func compareOrderKeys(w *WindowExpression, a, b []interface{}) (int, error) {
+	for i, f := range w.OrderBy {
+		var cmp int
+		switch {
+		case a[i] == nil && b[i] == nil:
+			continue
+		case a[i] == nil:
+			cmp = -1
+			if f.NullOrdering == NullsLast {
+				cmp = 1
+			}
+			return cmp, nil
+		case b[i] == nil:
+			cmp = 1
+			if f.NullOrdering == NullsLast {
+				cmp = -1
+			}
+			return cmp, nil
+		}
+
+		cmp, err := f.Column.Type().Compare(a[i], b[i])
+		if err != nil {
+			return 0, err
+		}
+
+		if f.Order == Descending {
+			cmp = -cmp
+		}
+
+		if cmp != 0 {
+			return cmp, nil
+		}
+	}
+	return 0, nil
+}
+
+func compareNullable(typ sql.Type, a, b interface{}) (int, error) {
+	switch {
+	case a == nil && b == nil:
+		return 0, nil
+	case a == nil:
+		return -1, nil
+	case b == nil:
+		return 1, nil
+	default:
+		return typ.Compare(a, b)
+	}
+}
+
+// computeFrames fills the peers and the frame of each row of the partition,
+// whose rows have the given values for the ORDER BY expressions.
+func computeFrames(w *WindowExpression, p *sql.WindowPartition, keys [][]interface{}) error {
+	n := len(p.Rows)
+	p.Peers = make([]bool, n)
+	p.FrameStart = make([]int, n)
+	p.FrameEnd = make([]int, n)
+
+	// Rows without ORDER BY are all peers.
+	peerStart := make([]int, n)
+	for i := 1; i < n; i++ {
+		cmp, err := compareOrderKeys(w, keys[i-1], keys[i])
+		if err != nil {
+			return err
+		}
+
+		p.Peers[i] = cmp == 0
+		if p.Peers[i] {
+			peerStart[i] = peerStart[i-1]
+		} else {
+			peerStart[i] = i
+		}
+	}
+
+	peerEnd := make([]int, n)
+	for i := n - 1; i >= 0; i-- {
+		if i == n-1 || !p.Peers[i+1] {
+			peerEnd[i] = i + 1
+		} else {
+			peerEnd[i] = peerEnd[i+1]
+		}
+	}
+
+	frame := w.frame()
+	var bounds frameBounds
+	switch frame.Unit {
+	case RowsFrame:
+		bounds = rowsFrameBounds{n}
+	case RangeFrame:
+		rb := rangeFrameBounds{n: n, peerStart: peerStart, peerEnd: peerEnd}
+		if hasOffset(frame.Start) || hasOffset(frame.End) {
+			if err := rb.withValues(w, keys); err != nil {
+				return err
+			}
+		}
+		bounds = rb
+	default:
+		return ErrInvalidWindowFrame.New(frame.Unit)
+	}
+
+	for i := 0; i < n; i++ {
+		start, err := bounds.start(i, frame.Start)
+		if err != nil {
+			return err
+		}
+
+		end, err := bounds.end(i, frame.End)
+		if err != nil {
+			return err
+		}
+
+		if start > n {
+			start = n
+		}
+
+		if end < start {
+			end = start
+		}
+
+		p.FrameStart[i] = start
+		p.FrameEnd[i] = end
+	}
+
+	return nil
+}
+
+func hasOffset(b WindowFrameBound) bool {
+	return b.Type == Preceding || b.Type == Following
+}
+
+type frameBounds interface {
+	// start returns the index of the first row of the frame of the given row.
+	start(i int, b WindowFrameBound) (int, error)
+	// end returns the index after the last row of the frame of the given row.
+	end(i int, b WindowFrameBound) (int, error)
+}
+
+type rowsFrameBounds struct {
+	n int
+}
+
+func (r rowsFrameBounds) start(i int, b WindowFrameBound) (int, error) {
+	switch b.Type {
+	case UnboundedPreceding:
+		return 0, nil
+	case CurrentRow:
+		return i, nil
+	default:
+		offset, err := rowsOffset(b)
+		if err != nil {
+			return 0, err
+		}
+
+		if b.Type == Preceding {
+			offset = -offset
+		}
+
+		return clamp(i+offset, 0, r.n), nil
+	}
+}
+
+func (r rowsFrameBounds) end(i int, b WindowFrameBound) (int, error) {
+	switch b.Type {
+	case UnboundedFollowing:
+		return r.n, nil
+	case CurrentRow:
+		return i + 1, nil
+	default:
+		offset, err := rowsOffset(b)
+		if err != nil {
+			return 0, err
+		}
+
+		if b.Type == Preceding {
+			offset = -offset
+		}
+
+		return clamp(i+offset+1, 0, r.n), nil
+	}
+}
+
+func rowsOffset(b WindowFrameBound) (int, error) {
+	v, err := b.Offset.Eval(nil, nil)
+	if err != nil {
+		return 0, err
+	}
+
+	offset, err := sql.Int64.Convert(v)
+	if err != nil || v == nil || offset.(int64) < 0 {
+		return 0, ErrInvalidWindowFrame.New(fmt.Sprintf("invalid ROWS offset %v", v))
+	}
+
+	return int(offset.(int64)), nil
+}
+
+type rangeFrameBounds struct {
+	n         int
+	peerStart []int
+	peerEnd   []int
+	// values of the ORDER BY expression, only needed when there are offsets.
+	values []interface{}
+	// direction is -1 if the ORDER BY expression is descending, 1 otherwise.
+	direction float64
+	// rows with non null values are in the interval [nonNullStart, nonNullEnd).
+	nonNullStart, nonNullEnd int
+}
+
+func (r *rangeFrameBounds) withValues(w *WindowExpression, keys [][]interface{}) error {
+	if len(w.OrderBy) != 1 || !sql.IsNumber(w.OrderBy[0].Column.Type()) {
+		return ErrInvalidWindowFrame.New(
+			"RANGE with offsets requires exactly one numeric ORDER BY expression",
+		)
+	}
+
+	r.direction = 1
+	if w.OrderBy[0].Order == Descending {
+		r.direction = -1
+	}
+
+	r.values = make([]interface{}, len(keys))
+	r.nonNullStart, r.nonNullEnd = len(keys), 0
+	for i, k := range keys {
+		if k[0] == nil {
+			continue
+		}
+
+		v, err := sql.Float64.Convert(k[0])
+		if err != nil {
+			return err
+		}
+		r.values[i] = v
+
+		if i < r.nonNullStart {
+			r.nonNullStart = i
+		}
+		r.nonNullEnd = i + 1
+	}
+
+	return nil
+}
+
+func (r rangeFrameBounds) start(i int, b WindowFrameBound) (int, error) {
+	switch b.Type {
+	case UnboundedPreceding:
+		return 0, nil
+	case CurrentRow:
+		return r.peerStart[i], nil
+	default:
+		if r.values[i] == nil {
+			return r.peerStart[i], nil
+		}
+
+		offset, err := rangeOffset(b)
+		if err != nil {
+			return 0, err
+		}
+
+		// First row whose distance to the current one is at least the offset.
+		return r.search(i, func(d float64) bool { return d >= offset }), nil
+	}
+}
+
+func (r rangeFrameBounds) end(i int, b WindowFrameBound) (int, error) {
+	switch b.Type {
+	case UnboundedFollowing:
+		return r.n, nil
+	case CurrentRow:
+		return r.peerEnd[i], nil
+	default:
+		if r.values[i] == nil {
+			return r.peerEnd[i], nil
+		}
+
+		offset, err := rangeOffset(b)
+		if err != nil {
+			return 0, err
+		}
+
+		// First row whose distance to the current one exceeds the offset.
+		return r.search(i, func(d float64) bool { return d > offset }), nil
+	}
+}
+
+// search returns the index of the first row with a non null value whose
+// distance to the value of the given row, in the direction of the order,
+// satisfies f. If no row satisfies it, the index after the last row with a
+// non null value is returned.
+func (r rangeFrameBounds) search(i int, f func(float64) bool) int {
+	current := r.values[i].(float64)
+	return r.nonNullStart + sort.Search(r.nonNullEnd-r.nonNullStart, func(j int) bool {
+		v := r.values[r.nonNullStart+j].(float64)
+		return f((v - current) * r.direction)
+	})
+}
+
+// rangeOffset returns the offset of the bound, which is negative for
+// preceding bounds.
+func rangeOffset(b WindowFrameBound) (float64, error) {
+	v, err := b.Offset.Eval(nil, nil)
+	if err != nil {
+		return 0, err
+	}
+
+	offset, err := sql.Float64.Convert(v)
+	if err != nil || v == nil || offset.(float64) < 0 {
+		return 0, ErrInvalidWindowFrame.New(fmt.Sprintf("invalid RANGE offset %v", v))
+	}
+
+	if b.Type == Preceding {
+		return -offset.(float64), nil
+	}
+	return offset.(float64), nil
+}
+
+func clamp(v, min, max int) int {
+	if v < min {
+		return min
+	}
+	if v > max {
+		return max
+	}
+	return v
+}
+
+// evalPartition returns the values of the function of the window expression
+// for each row of the partition.
+func evalPartition(ctx *sql.Context, w *WindowExpression, p *sql.WindowPartition) ([]interface{}, error) {
+	var values = make([]interface{}, len(p.Rows))
+	switch fn := w.Function.(type) {
+	case sql.WindowFunction:
+		buffer := fn.NewBuffer()
+		for i := range p.Rows {
+			if err := fn.UpdateWindow(ctx, buffer, p, i); err != nil {
+				return nil, err
+			}
+
+			v, err := fn.Eval(ctx, buffer)
+			if err != nil {
+				return nil, err
+			}
+			values[i] = v
+		}
+	case sql.Aggregation:
+		// When frames start at the beginning of the partition they only
+		// grow, so the same buffer can be used for all the rows.
+		running := w.frame().Start.Type == UnboundedPreceding
+		buffer := fn.NewBuffer()
+		var updated int
+		for i := range p.Rows {
+			if !running {
+				buffer = fn.NewBuffer()
+				updated = p.FrameStart[i]
+			}
+
+			for ; updated < p.FrameEnd[i]; updated++ {
+				if err := fn.Update(ctx, buffer, p.Rows[updated]); err != nil {
+					return nil, err
+				}
+			}
+
+			v, err := fn.Eval(ctx, buffer)
+			if err != nil {
+				return nil, err
+			}
+			values[i] = v
+		}
+	default:
+		return nil, ErrNotWindowFunction.New(w.Function)
+	}
+
+	return values, nil
+}
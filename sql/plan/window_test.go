@@ -0,0 +1,210 @@
+package plan
+
+import (
+	"testing"
+
+	"github.com/src-d/go-mysql-server/memory"
+	"github.com/src-d/go-mysql-server/sql"
+	"github.com/src-d/go-mysql-server/sql/expression"
+	"github.com/src-d/go-mysql-server/sql/expression/function/aggregation"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWindow(t *testing.T) {
+	require := require.New(t)
+
+	child := windowTestTable(t)
+	g := expression.NewGetField(0, sql.Text, "g", false)
+	n := expression.NewGetField(1, sql.Int64, "n", true)
+	orderByN := []SortField{{Column: n, Order: Ascending, NullOrdering: NullsFirst}}
+
+	w := NewWindow(
+		[]sql.Expression{
+			g,
+			n,
+			NewWindowExpression(aggregation.RowNumber{}, []sql.Expression{g}, orderByN, nil),
+			NewWindowExpression(aggregation.NewSum(n), []sql.Expression{g}, orderByN, nil),
+			NewWindowExpression(aggregation.NewCount(expression.NewStar()), nil, nil, nil),
+		},
+		NewResolvedTable(child),
+	)
+
+	require.Equal(sql.Schema{
+		{Name: "g", Type: sql.Text},
+		{Name: "n", Type: sql.Int64, Nullable: true},
+		{Name: "ROW_NUMBER() OVER (PARTITION BY g ORDER BY n ASC)", Type: sql.Int64},
+		{Name: "SUM(n) OVER (PARTITION BY g ORDER BY n ASC)", Type: sql.Float64, Nullable: true},
+		{Name: "COUNT(*) OVER ()", Type: sql.Int64},
+	}, w.Schema())
+
+	rows, err := sql.RowIterToRows(mustRowIter(t, w, sql.NewEmptyContext()))
+	require.NoError(err)
+
+	// Rows are returned in the same order they are read from the child. Peers
+	// are part of the default frame when there is an ORDER BY clause, so
+	// both rows with n = 2 have the same running sum.
+	require.Equal([]sql.Row{
+		{"a", int64(2), int64(3), float64(5), int64(6)},
+		{"b", int64(1), int64(1), float64(1), int64(6)},
+		{"a", nil, int64(1), nil, int64(6)},
+		{"a", int64(2), int64(4), float64(5), int64(6)},
+		{"a", int64(1), int64(2), float64(1), int64(6)},
+		{"b", int64(3), int64(2), float64(4), int64(6)},
+	}, rows)
+}
+
+func TestWindowFrames(t *testing.T) {
+	child := windowTestTable(t)
+	n := expression.NewGetField(1, sql.Int64, "n", true)
+	orderByN := []SortField{{Column: n, Order: Ascending, NullOrdering: NullsFirst}}
+	offset := expression.NewLiteral(int64(1), sql.Int64)
+
+	testCases := []struct {
+		name       string
+		unit       WindowFrameUnit
+		start, end WindowFrameBound
+		expected   []interface{}
+	}{
+		{
+			"rows between 1 preceding and current row",
+			RowsFrame,
+			WindowFrameBound{Type: Preceding, Offset: offset},
+			WindowFrameBound{Type: CurrentRow},
+			// Sorted by n, the rows are nil, 1, 1, 2, 2, 3.
+			[]interface{}{int64(2), int64(1), int64(0), int64(2), int64(2), int64(2)},
+		},
+		{
+			"rows between current row and unbounded following",
+			RowsFrame,
+			WindowFrameBound{Type: CurrentRow},
+			WindowFrameBound{Type: UnboundedFollowing},
+			[]interface{}{int64(3), int64(5), int64(5), int64(2), int64(4), int64(1)},
+		},
+		{
+			"range between 1 preceding and current row",
+			RangeFrame,
+			WindowFrameBound{Type: Preceding, Offset: offset},
+			WindowFrameBound{Type: CurrentRow},
+			[]interface{}{int64(4), int64(2), int64(0), int64(4), int64(2), int64(3)},
+		},
+		{
+			"range between current row and 1 following",
+			RangeFrame,
+			WindowFrameBound{Type: CurrentRow},
+			WindowFrameBound{Type: Following, Offset: offset},
+			[]interface{}{int64(3), int64(4), int64(0), int64(3), int64(4), int64(1)},
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			require := require.New(t)
+
+			frame, err := NewWindowFrame(tt.unit, tt.start, tt.end)
+			require.NoError(err)
+
+			w := NewWindow(
+				[]sql.Expression{
+					NewWindowExpression(aggregation.NewCount(n), nil, orderByN, frame),
+				},
+				NewResolvedTable(child),
+			)
+
+			rows, err := sql.RowIterToRows(mustRowIter(t, w, sql.NewEmptyContext()))
+			require.NoError(err)
+
+			var result []interface{}
+			for _, row := range rows {
+				result = append(result, row[0])
+			}
+			require.Equal(tt.expected, result)
+		})
+	}
+}
+
+func TestNewWindowFrame(t *testing.T) {
+	offset := expression.NewLiteral(int64(1), sql.Int64)
+
+	testCases := []struct {
+		name       string
+		start, end WindowFrameBound
+		ok         bool
+	}{
+		{
+			"unbounded",
+			WindowFrameBound{Type: UnboundedPreceding},
+			WindowFrameBound{Type: UnboundedFollowing},
+			true,
+		},
+		{
+			"preceding offsets",
+			WindowFrameBound{Type: Preceding, Offset: offset},
+			WindowFrameBound{Type: Preceding, Offset: offset},
+			true,
+		},
+		{
+			"start after end",
+			WindowFrameBound{Type: Following, Offset: offset},
+			WindowFrameBound{Type: CurrentRow},
+			false,
+		},
+		{
+			"start unbounded following",
+			WindowFrameBound{Type: UnboundedFollowing},
+			WindowFrameBound{Type: UnboundedFollowing},
+			false,
+		},
+		{
+			"end unbounded preceding",
+			WindowFrameBound{Type: UnboundedPreceding},
+			WindowFrameBound{Type: UnboundedPreceding},
+			false,
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			require := require.New(t)
+			_, err := NewWindowFrame(RowsFrame, tt.start, tt.end)
+			if tt.ok {
+				require.NoError(err)
+			} else {
+				require.Error(err)
+				require.True(ErrInvalidWindowFrame.Is(err))
+			}
+		})
+	}
+}
+
+func TestWindowExpressionEval(t *testing.T) {
+	require := require.New(t)
+
+	e := NewWindowExpression(aggregation.RowNumber{}, nil, nil, nil)
+	_, err := e.Eval(sql.NewEmptyContext(), nil)
+	require.Error(err)
+	require.True(ErrWindowExpressionEval.Is(err))
+}
+
+func windowTestTable(t *testing.T) *memory.Table {
+	t.Helper()
+
+	table := memory.NewTable("t", sql.Schema{
+		{Name: "g", Type: sql.Text, Source: "t"},
+		{Name: "n", Type: sql.Int64, Source: "t", Nullable: true},
+	})
+
+	rows := []sql.Row{
+		{"a", int64(2)},
+		{"b", int64(1)},
+		{"a", nil},
+		{"a", int64(2)},
+		{"a", int64(1)},
+		{"b", int64(3)},
+	}
+
+	for _, row := range rows {
+		require.NoError(t, table.Insert(sql.NewEmptyContext(), row))
+	}
+
+	return table
+}
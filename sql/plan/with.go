@@ -0,0 +1,98 @@
+package plan
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/src-d/go-mysql-server/sql"
+	errors "gopkg.in/src-d/go-errors.v1"
+)
+
+// ErrUnresolvedWith is thrown when a With node is executed without its common
+// table expressions being resolved first.
+var ErrUnresolvedWith = errors.NewKind("unresolved WITH clause")
+
+// CommonTableExpression is a named subquery defined in a WITH clause.
+type CommonTableExpression struct {
+	// Name of the expression, which can be used as a table name.
+	Name string
+	// Columns are the optional names given to the columns of the definition.
+	Columns []string
+	// Definition is the query of the expression.
+	Definition sql.Node
+}
+
+// NewCommonTableExpression creates a new common table expression.
+func NewCommonTableExpression(name string, columns []string, definition sql.Node) *CommonTableExpression {
+	return &CommonTableExpression{
+		Name:       name,
+		Columns:    columns,
+		Definition: definition,
+	}
+}
+
+func (c *CommonTableExpression) String() string {
+	name := c.Name
+	if len(c.Columns) > 0 {
+		name = fmt.Sprintf("%s(%s)", name, strings.Join(c.Columns, ", "))
+	}
+
+	pr := sql.NewTreePrinter()
+	_ = pr.WriteNode("%s", name)
+	_ = pr.WriteChildren(c.Definition.String())
+	return pr.String()
+}
+
+// With is a node that defines common table expressions which can be used as
+// tables by its child. With nodes are never executed, the analyzer replaces
+// them with their child once all the references to the expressions have
+// been resolved.
+type With struct {
+	UnaryNode
+	CTEs      []*CommonTableExpression
+	Recursive bool
+}
+
+// NewWith creates a new With node.
+func NewWith(child sql.Node, ctes []*CommonTableExpression, recursive bool) *With {
+	return &With{
+		UnaryNode: UnaryNode{Child: child},
+		CTEs:      ctes,
+		Recursive: recursive,
+	}
+}
+
+// Resolved implements the Resolvable interface.
+func (*With) Resolved() bool {
+	return false
+}
+
+// RowIter implements the Node interface.
+func (*With) RowIter(ctx *sql.Context) (sql.RowIter, error) {
+	return nil, ErrUnresolvedWith.New()
+}
+
+// WithChildren implements the Node interface.
+func (w *With) WithChildren(children ...sql.Node) (sql.Node, error) {
+	if len(children) != 1 {
+		return nil, sql.ErrInvalidChildrenNumber.New(w, len(children), 1)
+	}
+
+	return NewWith(children[0], w.CTEs, w.Recursive), nil
+}
+
+func (w *With) String() string {
+	var ctes = make([]string, len(w.CTEs))
+	for i, cte := range w.CTEs {
+		ctes[i] = cte.String()
+	}
+
+	pr := sql.NewTreePrinter()
+	if w.Recursive {
+		_ = pr.WriteNode("With(recursive)")
+	} else {
+		_ = pr.WriteNode("With")
+	}
+	_ = pr.WriteChildren(append(ctes, w.Child.String())...)
+	return pr.String()
+}
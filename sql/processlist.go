@@ -100,6 +100,9 @@ func (p *Process) Seconds() uint64 {
 type ProcessList struct {
 	mu    sync.RWMutex
 	procs map[uint64]*Process
+
+	lastPid          uint64
+	lastConnectionID uint32
 }
 
 // NewProcessList creates a new process list.
@@ -109,6 +112,27 @@ func NewProcessList() *ProcessList {
 	}
 }
 
+// NextPid returns a new pid for a process. Pids are never reused, so all the
+// sessions of the engine must take the pids of their processes from its
+// process list.
+func (pl *ProcessList) NextPid() uint64 {
+	pl.mu.Lock()
+	defer pl.mu.Unlock()
+	pl.lastPid++
+	return pl.lastPid
+}
+
+// NextConnectionID returns a new ID for a connection, which identifies its
+// session and its processes. IDs are never reused, so all the connections
+// to the engine, either through the server or the driver, must take their
+// IDs from its process list.
+func (pl *ProcessList) NextConnectionID() uint32 {
+	pl.mu.Lock()
+	defer pl.mu.Unlock()
+	pl.lastConnectionID++
+	return pl.lastConnectionID
+}
+
 // ErrPidAlreadyUsed is returned when the pid is already registered.
 var ErrPidAlreadyUsed = errors.NewKind("pid %d is already in use")
 
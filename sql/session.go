@@ -49,6 +49,11 @@ type Session interface {
 	ClearWarnings()
 	// WarningCount returns a number of session warnings
 	WarningCount() uint16
+	// Transaction returns the transaction in progress in the session, if any.
+	Transaction() *Transaction
+	// SetTransaction sets the transaction in progress in the session. A nil
+	// transaction means there is no transaction in progress.
+	SetTransaction(tx *Transaction)
 }
 
 // BaseSession is the basic session type.
@@ -60,6 +65,7 @@ type BaseSession struct {
 	config   map[string]TypedValue
 	warnings []*Warning
 	warncnt  uint16
+	tx       *Transaction
 }
 
 // Address returns the server address.
@@ -147,6 +153,20 @@ func (s *BaseSession) WarningCount() uint16 {
 	return uint16(len(s.warnings))
 }
 
+// Transaction implements the Session interface.
+func (s *BaseSession) Transaction() *Transaction {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.tx
+}
+
+// SetTransaction implements the Session interface.
+func (s *BaseSession) SetTransaction(tx *Transaction) {
+	s.mu.Lock()
+	s.tx = tx
+	s.mu.Unlock()
+}
+
 type (
 	// TypedValue is a value along with its type.
 	TypedValue struct {
@@ -177,6 +197,8 @@ func DefaultSessionConfig() map[string]TypedValue {
 		"transaction_isolation":    TypedValue{Text, "READ UNCOMMITTED"},
 		"version":                  TypedValue{Text, ""},
 		"version_comment":          TypedValue{Text, ""},
+		"cte_max_recursion_depth":  TypedValue{Int64, int64(1000)},
+		"autocommit":               TypedValue{Int64, int64(1)},
 	}
 }
 
@@ -211,11 +233,13 @@ func NewBaseSession() Session {
 type Context struct {
 	context.Context
 	Session
-	Memory   *MemoryManager
-	pid      uint64
-	query    string
-	tracer   opentracing.Tracer
-	rootSpan opentracing.Span
+	Memory    *MemoryManager
+	pid       uint64
+	query     string
+	tracer    opentracing.Tracer
+	rootSpan  opentracing.Span
+	functions FunctionRegistry
+	outerRow  Row
 }
 
 // ContextOption is a function to configure the context.
@@ -263,6 +287,15 @@ func WithRootSpan(s opentracing.Span) ContextOption {
 	}
 }
 
+// WithFunctions adds the given functions to the context. They are only
+// available to the queries run with the context, and take precedence over
+// the functions in the catalog.
+func WithFunctions(r FunctionRegistry) ContextOption {
+	return func(ctx *Context) {
+		ctx.functions = r
+	}
+}
+
 // NewContext creates a new query context. Options can be passed to configure
 // the context. If some aspect of the context is not configure, the default
 // value will be used.
@@ -272,7 +305,7 @@ func NewContext(
 	ctx context.Context,
 	opts ...ContextOption,
 ) *Context {
-	c := &Context{ctx, NewBaseSession(), nil, 0, "", opentracing.NoopTracer{}, nil}
+	c := &Context{ctx, NewBaseSession(), nil, 0, "", opentracing.NoopTracer{}, nil, nil, nil}
 	for _, opt := range opts {
 		opt(c)
 	}
@@ -306,12 +339,36 @@ func (c *Context) Span(
 	span := c.tracer.StartSpan(opName, opts...)
 	ctx := opentracing.ContextWithSpan(c.Context, span)
 
-	return span, &Context{ctx, c.Session, c.Memory, c.Pid(), c.Query(), c.tracer, c.rootSpan}
+	return span, &Context{ctx, c.Session, c.Memory, c.Pid(), c.Query(), c.tracer, c.rootSpan, c.functions, c.outerRow}
 }
 
 // WithContext returns a new context with the given underlying context.
 func (c *Context) WithContext(ctx context.Context) *Context {
-	return &Context{ctx, c.Session, c.Memory, c.Pid(), c.Query(), c.tracer, c.rootSpan}
+	return &Context{ctx, c.Session, c.Memory, c.Pid(), c.Query(), c.tracer, c.rootSpan, c.functions, c.outerRow}
+}
+
+// WithFunctions returns a new context with the given functions, which take
+// precedence over the functions in the catalog.
+func (c *Context) WithFunctions(r FunctionRegistry) *Context {
+	return &Context{c.Context, c.Session, c.Memory, c.Pid(), c.Query(), c.tracer, c.rootSpan, r, c.outerRow}
+}
+
+// Functions returns the functions only available to the queries run with
+// this context, if any.
+func (c *Context) Functions() FunctionRegistry {
+	return c.functions
+}
+
+// WithOuterRow returns a new context with the given row of an outer query,
+// whose values are used by the subquery run with the context.
+func (c *Context) WithOuterRow(row Row) *Context {
+	return &Context{c.Context, c.Session, c.Memory, c.Pid(), c.Query(), c.tracer, c.rootSpan, c.functions, row}
+}
+
+// OuterRow returns the row of the outer query of the subquery run with this
+// context, if any.
+func (c *Context) OuterRow() Row {
+	return c.outerRow
 }
 
 // RootSpan returns the root span, if any.
@@ -0,0 +1,110 @@
+package sql
+
+import (
+	"bufio"
+	"encoding/gob"
+	"io"
+	"io/ioutil"
+	"os"
+	"time"
+
+	errors "gopkg.in/src-d/go-errors.v1"
+)
+
+// ErrSpool is returned when rows can't be written to or read from disk.
+var ErrSpool = errors.NewKind("unable to spill rows to disk: %s")
+
+func init() {
+	// Values in rows are stored as interfaces, so all the types that are
+	// not basic types need to be registered.
+	gob.Register(time.Time{})
+	gob.Register([]interface{}{})
+	gob.Register(map[string]interface{}{})
+}
+
+// RowSpool is a sequence of rows stored in a temporary file, used by the
+// nodes that would need to keep more rows in memory than available. Rows can
+// be read any number of times, even while more rows are written.
+type RowSpool struct {
+	file *os.File
+	w    *bufio.Writer
+	enc  *gob.Encoder
+	len  int
+}
+
+// NewRowSpool creates an empty row spool in the temporary directory of the
+// memory manager. It must be disposed when it's no longer needed to remove
+// its file.
+func (m *MemoryManager) NewRowSpool() (*RowSpool, error) {
+	f, err := ioutil.TempFile(m.TempDir(), "go-mysql-server-spool-")
+	if err != nil {
+		return nil, ErrSpool.Wrap(err, err)
+	}
+
+	w := bufio.NewWriter(f)
+	return &RowSpool{file: f, w: w, enc: gob.NewEncoder(w)}, nil
+}
+
+// Add a row at the end of the spool.
+func (s *RowSpool) Add(row Row) error {
+	if err := s.enc.Encode(row); err != nil {
+		return ErrSpool.Wrap(err, err)
+	}
+
+	s.len++
+	return nil
+}
+
+// Len returns the number of rows in the spool.
+func (s *RowSpool) Len() int { return s.len }
+
+// RowIter returns an iterator over all the rows added to the spool so far.
+func (s *RowSpool) RowIter() (RowIter, error) {
+	if err := s.w.Flush(); err != nil {
+		return nil, ErrSpool.Wrap(err, err)
+	}
+
+	f, err := os.Open(s.file.Name())
+	if err != nil {
+		return nil, ErrSpool.Wrap(err, err)
+	}
+
+	return &spoolIter{file: f, dec: gob.NewDecoder(bufio.NewReader(f)), left: s.len}, nil
+}
+
+// Dispose removes the file of the spool.
+func (s *RowSpool) Dispose() {
+	if s.file == nil {
+		return
+	}
+
+	_ = s.file.Close()
+	_ = os.Remove(s.file.Name())
+	s.file = nil
+}
+
+type spoolIter struct {
+	file *os.File
+	dec  *gob.Decoder
+	left int
+}
+
+func (i *spoolIter) Next() (Row, error) {
+	// The file may contain rows added after the iterator was created, which
+	// are not returned.
+	if i.left == 0 {
+		return nil, io.EOF
+	}
+
+	var row Row
+	if err := i.dec.Decode(&row); err != nil {
+		return nil, ErrSpool.Wrap(err, err)
+	}
+
+	i.left--
+	return row, nil
+}
+
+func (i *spoolIter) Close() error {
+	return i.file.Close()
+}
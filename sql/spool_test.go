@@ -0,0 +1,61 @@
+package sql
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRowSpool(t *testing.T) {
+	require := require.New(t)
+
+	dir, err := ioutil.TempDir("", "spool")
+	require.NoError(err)
+	defer os.RemoveAll(dir)
+
+	m := NewMemoryManager(nil)
+	m.SetTempDir(dir)
+	require.Equal(dir, m.TempDir())
+
+	s, err := m.NewRowSpool()
+	require.NoError(err)
+
+	rows := []Row{
+		NewRow(int64(1), "foo", nil, true),
+		NewRow(int32(2), []byte("bar"), float64(1.5), time.Date(2019, 1, 2, 3, 4, 5, 0, time.UTC)),
+		NewRow(uint8(3), []interface{}{"a", int64(1)}, map[string]interface{}{"a": "b"}, nil),
+	}
+
+	for _, row := range rows[:2] {
+		require.NoError(s.Add(row))
+	}
+
+	iter, err := s.RowIter()
+	require.NoError(err)
+
+	require.NoError(s.Add(rows[2]))
+	require.Equal(3, s.Len())
+
+	result, err := RowIterToRows(iter)
+	require.NoError(err)
+	require.Equal(rows[:2], result)
+
+	iter, err = s.RowIter()
+	require.NoError(err)
+	result, err = RowIterToRows(iter)
+	require.NoError(err)
+	require.Equal(rows, result)
+
+	files, err := ioutil.ReadDir(dir)
+	require.NoError(err)
+	require.Len(files, 1)
+
+	s.Dispose()
+
+	files, err = ioutil.ReadDir(dir)
+	require.NoError(err)
+	require.Len(files, 0)
+}
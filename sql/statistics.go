@@ -0,0 +1,232 @@
+package sql
+
+import (
+	"io"
+	"sort"
+	"strings"
+	"time"
+)
+
+// DefaultHistogramBuckets is the maximum number of buckets of the histograms
+// computed when a table is analyzed, which is the same as the default of
+// MySQL.
+const DefaultHistogramBuckets = 100
+
+// StatisticsTable is a table that has statistics about its rows, which are
+// used to estimate the cost of the queries that read it.
+type StatisticsTable interface {
+	Table
+	// Statistics returns the statistics of the table, or nil if they have
+	// not been computed yet.
+	Statistics(*Context) (*TableStatistics, error)
+}
+
+// AnalyzableTable is a table whose statistics can be computed on demand,
+// which is what ANALYZE TABLE does.
+type AnalyzableTable interface {
+	StatisticsTable
+	// Analyze computes the statistics of the table with its current rows.
+	Analyze(*Context) error
+}
+
+// TableStatistics are the statistics of the rows of a table.
+type TableStatistics struct {
+	// RowCount is the number of rows of the table.
+	RowCount uint64
+	// Columns are the statistics of each column of the table.
+	Columns []*ColumnStatistics
+	// Updated is the time when the statistics were computed.
+	Updated time.Time
+}
+
+// Column returns the statistics of the column with the given name, or nil
+// if there are none.
+func (s *TableStatistics) Column(name string) *ColumnStatistics {
+	if s == nil {
+		return nil
+	}
+
+	for _, c := range s.Columns {
+		if strings.EqualFold(c.Name, name) {
+			return c
+		}
+	}
+	return nil
+}
+
+// ColumnStatistics are the statistics of the values of a column.
+type ColumnStatistics struct {
+	// Name of the column.
+	Name string
+	// Type of the values of the column.
+	Type Type
+	// DistinctCount is the number of distinct values that are not null.
+	DistinctCount uint64
+	// NullFraction is the fraction of the rows whose value is null.
+	NullFraction float64
+	// Min and Max are the smallest and the greatest values that are not
+	// null, which are nil if all the values are null.
+	Min, Max interface{}
+	// Histogram has the distribution of the values that are not null, in
+	// buckets of about the same number of rows sorted by value.
+	Histogram []HistogramBucket
+}
+
+// HistogramBucket is a range of values of a column histogram. Values are
+// never split between buckets, so buckets with frequent values are bigger.
+type HistogramBucket struct {
+	// LowerBound and UpperBound are the smallest and the greatest values in
+	// the bucket.
+	LowerBound, UpperBound interface{}
+	// CumulativeFrequency is the fraction of the rows of the table whose
+	// value is in this bucket or one of the previous ones.
+	CumulativeFrequency float64
+	// DistinctCount is the number of distinct values in the bucket.
+	DistinctCount uint64
+}
+
+// ComputeStatistics reads all the rows of the given table to compute their
+// statistics, with histograms of up to the given number of buckets.
+func ComputeStatistics(ctx *Context, table Table, buckets int) (*TableStatistics, error) {
+	schema := table.Schema()
+	values := make([][]interface{}, len(schema))
+	nulls := make([]uint64, len(schema))
+
+	var count uint64
+	err := forEachRow(ctx, table, func(row Row) {
+		count++
+		for i, v := range row {
+			if v == nil {
+				nulls[i]++
+			} else {
+				values[i] = append(values[i], v)
+			}
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	stats := &TableStatistics{
+		RowCount: count,
+		Columns:  make([]*ColumnStatistics, len(schema)),
+		Updated:  time.Now(),
+	}
+
+	for i, col := range schema {
+		stats.Columns[i], err = columnStatistics(col, values[i], nulls[i], count, buckets)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return stats, nil
+}
+
+func forEachRow(ctx *Context, table Table, fn func(Row)) error {
+	partitions, err := table.Partitions(ctx)
+	if err != nil {
+		return err
+	}
+
+	for {
+		p, err := partitions.Next()
+		if err == io.EOF {
+			break
+		}
+
+		if err != nil {
+			_ = partitions.Close()
+			return err
+		}
+
+		iter, err := table.PartitionRows(ctx, p)
+		if err != nil {
+			_ = partitions.Close()
+			return err
+		}
+
+		rows, err := RowIterToRows(iter)
+		if err != nil {
+			_ = partitions.Close()
+			return err
+		}
+
+		for _, row := range rows {
+			fn(row)
+		}
+	}
+
+	return partitions.Close()
+}
+
+func columnStatistics(
+	col *Column,
+	values []interface{},
+	nulls, count uint64,
+	buckets int,
+) (*ColumnStatistics, error) {
+	stats := &ColumnStatistics{Name: col.Name, Type: col.Type}
+	if count > 0 {
+		stats.NullFraction = float64(nulls) / float64(count)
+	}
+
+	if len(values) == 0 {
+		return stats, nil
+	}
+
+	var err error
+	sort.SliceStable(values, func(i, j int) bool {
+		cmp, e := col.Type.Compare(values[i], values[j])
+		if e != nil {
+			err = e
+		}
+		return cmp < 0
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if buckets < 1 {
+		buckets = 1
+	}
+
+	// Buckets are closed once they have at least the rows they would have
+	// if all had the same size, at the end of a run of equal values.
+	size := (len(values) + buckets - 1) / buckets
+	var bucket *HistogramBucket
+	var inBucket int
+	for i, v := range values {
+		distinct := i == 0
+		if !distinct {
+			cmp, err := col.Type.Compare(values[i-1], v)
+			if err != nil {
+				return nil, err
+			}
+			distinct = cmp != 0
+		}
+
+		if distinct {
+			stats.DistinctCount++
+			if bucket != nil && inBucket >= size {
+				stats.Histogram = append(stats.Histogram, *bucket)
+				bucket = nil
+			}
+
+			if bucket == nil {
+				bucket = &HistogramBucket{LowerBound: v}
+				inBucket = 0
+			}
+			bucket.DistinctCount++
+		}
+
+		inBucket++
+		bucket.UpperBound = v
+		bucket.CumulativeFrequency = float64(i+1) / float64(count)
+	}
+	stats.Histogram = append(stats.Histogram, *bucket)
+
+	stats.Min = values[0]
+	stats.Max = values[len(values)-1]
+	return stats, nil
+}
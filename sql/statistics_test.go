@@ -0,0 +1,60 @@
+package sql_test
+
+import (
+	"testing"
+
+	"github.com/src-d/go-mysql-server/memory"
+	"github.com/src-d/go-mysql-server/sql"
+	"github.com/stretchr/testify/require"
+)
+
+func TestComputeStatistics(t *testing.T) {
+	require := require.New(t)
+	ctx := sql.NewEmptyContext()
+
+	table := memory.NewPartitionedTable("t", sql.Schema{
+		{Name: "i", Type: sql.Int64, Source: "t", Nullable: true},
+		{Name: "s", Type: sql.Text, Source: "t", Nullable: true},
+	}, 2)
+
+	for _, row := range []sql.Row{
+		{int64(3), nil},
+		{int64(1), nil},
+		{int64(2), nil},
+		{int64(1), nil},
+		{nil, nil},
+		{int64(1), nil},
+		{int64(5), nil},
+		{int64(4), nil},
+	} {
+		require.NoError(table.Insert(ctx, row))
+	}
+
+	stats, err := sql.ComputeStatistics(ctx, table, 3)
+	require.NoError(err)
+	require.Equal(uint64(8), stats.RowCount)
+	require.Len(stats.Columns, 2)
+
+	i := stats.Column("I")
+	require.Equal("i", i.Name)
+	require.Equal(sql.Int64, i.Type)
+	require.Equal(uint64(5), i.DistinctCount)
+	require.Equal(0.125, i.NullFraction)
+	require.Equal(int64(1), i.Min)
+	require.Equal(int64(5), i.Max)
+
+	// The repeated value fills the first bucket on its own.
+	require.Equal([]sql.HistogramBucket{
+		{LowerBound: int64(1), UpperBound: int64(1), CumulativeFrequency: 0.375, DistinctCount: 1},
+		{LowerBound: int64(2), UpperBound: int64(4), CumulativeFrequency: 0.75, DistinctCount: 3},
+		{LowerBound: int64(5), UpperBound: int64(5), CumulativeFrequency: 0.875, DistinctCount: 1},
+	}, i.Histogram)
+
+	s := stats.Column("s")
+	require.Equal(uint64(0), s.DistinctCount)
+	require.Equal(1.0, s.NullFraction)
+	require.Nil(s.Min)
+	require.Nil(s.Histogram)
+
+	require.Nil(stats.Column("foo"))
+}
@@ -0,0 +1,146 @@
+package sql
+
+import (
+	"encoding/json"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"gopkg.in/src-d/go-errors.v1"
+)
+
+var (
+	// ErrStoredFunctionAlreadyExists is returned when a function is created
+	// with the name of an existing one.
+	ErrStoredFunctionAlreadyExists = errors.NewKind("function %s already exists")
+
+	// ErrStoredFunctionNotFound is returned when a function created with
+	// CREATE FUNCTION does not exist.
+	ErrStoredFunctionNotFound = errors.NewKind("function %s does not exist")
+)
+
+// StoredFunction is a function defined with CREATE FUNCTION, whose body is a
+// script that receives the arguments by the names of its parameters.
+type StoredFunction struct {
+	Name string
+	// Params are the names of the parameters of the function.
+	Params []string
+	// ParamTypes are the declared types of the parameters, which are nil for
+	// the parameters without a type.
+	ParamTypes []Type
+	// ReturnType is the declared type of the result of the function.
+	ReturnType Type
+	// Language is the language of the script, such as js or expr.
+	Language string
+	// Body is the script of the function.
+	Body string
+	// TextDefinition is the CREATE FUNCTION statement as it was written.
+	TextDefinition string
+	// Created is the time the function was created.
+	Created time.Time
+}
+
+// StoredFunctionRegistry keeps the functions created with CREATE FUNCTION.
+// The functions can be saved and loaded again using their definitions.
+type StoredFunctionRegistry struct {
+	mut       sync.RWMutex
+	functions map[string]*StoredFunction
+}
+
+// NewStoredFunctionRegistry returns a new empty StoredFunctionRegistry.
+func NewStoredFunctionRegistry() *StoredFunctionRegistry {
+	return &StoredFunctionRegistry{functions: make(map[string]*StoredFunction)}
+}
+
+// RegisterStoredFunction adds the given function. It fails if there is
+// already a function with the same name.
+func (r *StoredFunctionRegistry) RegisterStoredFunction(fn *StoredFunction) error {
+	r.mut.Lock()
+	defer r.mut.Unlock()
+
+	name := strings.ToLower(fn.Name)
+	if _, ok := r.functions[name]; ok {
+		return ErrStoredFunctionAlreadyExists.New(fn.Name)
+	}
+
+	r.functions[name] = fn
+	return nil
+}
+
+// DeleteStoredFunction removes the function with the given name.
+func (r *StoredFunctionRegistry) DeleteStoredFunction(name string) error {
+	r.mut.Lock()
+	defer r.mut.Unlock()
+
+	key := strings.ToLower(name)
+	if _, ok := r.functions[key]; !ok {
+		return ErrStoredFunctionNotFound.New(name)
+	}
+
+	delete(r.functions, key)
+	return nil
+}
+
+// StoredFunction returns the function with the given name.
+func (r *StoredFunctionRegistry) StoredFunction(name string) (*StoredFunction, error) {
+	r.mut.RLock()
+	defer r.mut.RUnlock()
+
+	fn, ok := r.functions[strings.ToLower(name)]
+	if !ok {
+		return nil, ErrStoredFunctionNotFound.New(name)
+	}
+
+	return fn, nil
+}
+
+// StoredFunctions returns all the functions, sorted by name.
+func (r *StoredFunctionRegistry) StoredFunctions() []*StoredFunction {
+	r.mut.RLock()
+	defer r.mut.RUnlock()
+
+	var functions = make([]*StoredFunction, 0, len(r.functions))
+	for _, fn := range r.functions {
+		functions = append(functions, fn)
+	}
+
+	sort.Slice(functions, func(i, j int) bool {
+		return functions[i].Name < functions[j].Name
+	})
+
+	return functions
+}
+
+// SavedFunction is a function as it's written by
+// StoredFunctionRegistry.SaveStoredFunctions.
+type SavedFunction struct {
+	Definition string    `json:"definition"`
+	Created    time.Time `json:"created"`
+}
+
+// SaveStoredFunctions writes the definitions of all the functions to the
+// given writer, so they can be read with ReadSavedFunctions and created
+// again.
+func (r *StoredFunctionRegistry) SaveStoredFunctions(w io.Writer) error {
+	var saved []SavedFunction
+	for _, fn := range r.StoredFunctions() {
+		saved = append(saved, SavedFunction{fn.TextDefinition, fn.Created})
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(saved)
+}
+
+// ReadSavedFunctions reads the functions written with
+// StoredFunctionRegistry.SaveStoredFunctions.
+func ReadSavedFunctions(r io.Reader) ([]SavedFunction, error) {
+	var saved []SavedFunction
+	if err := json.NewDecoder(r).Decode(&saved); err != nil {
+		return nil, err
+	}
+
+	return saved, nil
+}
@@ -0,0 +1,67 @@
+package sql_test
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/src-d/go-mysql-server/sql"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStoredFunctionRegistry(t *testing.T) {
+	require := require.New(t)
+
+	r := sql.NewStoredFunctionRegistry()
+	foo := &sql.StoredFunction{Name: "foo", TextDefinition: "CREATE FUNCTION foo() ..."}
+	bar := &sql.StoredFunction{Name: "bar", TextDefinition: "CREATE FUNCTION bar() ..."}
+
+	require.NoError(r.RegisterStoredFunction(foo))
+	require.NoError(r.RegisterStoredFunction(bar))
+
+	err := r.RegisterStoredFunction(&sql.StoredFunction{Name: "FOO"})
+	require.Error(err)
+	require.True(sql.ErrStoredFunctionAlreadyExists.Is(err))
+
+	fn, err := r.StoredFunction("Foo")
+	require.NoError(err)
+	require.Equal(foo, fn)
+
+	require.Equal([]*sql.StoredFunction{bar, foo}, r.StoredFunctions())
+
+	require.NoError(r.DeleteStoredFunction("FOO"))
+	_, err = r.StoredFunction("foo")
+	require.True(sql.ErrStoredFunctionNotFound.Is(err))
+
+	err = r.DeleteStoredFunction("foo")
+	require.True(sql.ErrStoredFunctionNotFound.Is(err))
+
+	require.Equal([]*sql.StoredFunction{bar}, r.StoredFunctions())
+}
+
+func TestSaveStoredFunctions(t *testing.T) {
+	require := require.New(t)
+
+	created := time.Date(2019, time.March, 1, 10, 0, 0, 0, time.UTC)
+	r := sql.NewStoredFunctionRegistry()
+	require.NoError(r.RegisterStoredFunction(&sql.StoredFunction{
+		Name:           "foo",
+		TextDefinition: "CREATE FUNCTION foo() RETURNS INT LANGUAGE js AS '1'",
+		Created:        created,
+	}))
+	require.NoError(r.RegisterStoredFunction(&sql.StoredFunction{
+		Name:           "bar",
+		TextDefinition: "CREATE FUNCTION bar() RETURNS INT LANGUAGE js AS '2'",
+		Created:        created,
+	}))
+
+	var buf bytes.Buffer
+	require.NoError(r.SaveStoredFunctions(&buf))
+
+	saved, err := sql.ReadSavedFunctions(&buf)
+	require.NoError(err)
+	require.Equal([]sql.SavedFunction{
+		{"CREATE FUNCTION bar() RETURNS INT LANGUAGE js AS '2'", created},
+		{"CREATE FUNCTION foo() RETURNS INT LANGUAGE js AS '1'", created},
+	}, saved)
+}
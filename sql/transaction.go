@@ -0,0 +1,212 @@
+package sql
+
+import (
+	"strings"
+
+	"gopkg.in/src-d/go-errors.v1"
+)
+
+var (
+	// ErrSavepointNotFound is returned when a savepoint does not exist in
+	// the current transaction.
+	ErrSavepointNotFound = errors.NewKind("SAVEPOINT %s does not exist")
+)
+
+// TransactionalDatabase is a database whose changes can be grouped in
+// transactions. All transactions are keyed by the transaction in progress in
+// the session of the context, so the changes made in a transaction are only
+// visible to its session until they are committed.
+type TransactionalDatabase interface {
+	Database
+	// BeginTransaction starts a transaction for the session.
+	BeginTransaction(ctx *Context) error
+	// CommitTransaction makes the changes of the transaction of the session
+	// visible to all sessions and finishes the transaction.
+	CommitTransaction(ctx *Context) error
+	// RollbackTransaction discards the changes of the transaction of the
+	// session and finishes the transaction.
+	RollbackTransaction(ctx *Context) error
+	// CreateSavepoint saves the state of the transaction of the session with
+	// the given name, replacing any savepoint with the same name.
+	CreateSavepoint(ctx *Context, name string) error
+	// RollbackToSavepoint discards the changes made in the transaction of the
+	// session after the savepoint with the given name was created.
+	RollbackToSavepoint(ctx *Context, name string) error
+	// ReleaseSavepoint removes the savepoint with the given name from the
+	// transaction of the session.
+	ReleaseSavepoint(ctx *Context, name string) error
+}
+
+// Transaction is a transaction in progress in a session, which spans all the
+// transactional databases of the catalog at the moment it started.
+type Transaction struct {
+	// Implicit is true if the transaction was started just to run a single
+	// statement because autocommit is enabled, so it must be finished as soon
+	// as the statement is done.
+	Implicit   bool
+	databases  []TransactionalDatabase
+	savepoints []string
+}
+
+// BeginTransaction starts a transaction in the session of the context in all
+// the given databases that are transactional and sets it as the transaction
+// in progress of the session.
+func BeginTransaction(ctx *Context, dbs Databases, implicit bool) (*Transaction, error) {
+	tx := &Transaction{Implicit: implicit}
+	ctx.SetTransaction(tx)
+	for _, db := range dbs {
+		tdb, ok := db.(TransactionalDatabase)
+		if !ok {
+			continue
+		}
+
+		if err := tdb.BeginTransaction(ctx); err != nil {
+			_ = tx.Rollback(ctx)
+			return nil, err
+		}
+
+		tx.databases = append(tx.databases, tdb)
+	}
+
+	return tx, nil
+}
+
+// Commit commits the transaction in all its databases. The transaction is
+// finished even if it fails to commit in any of them.
+func (t *Transaction) Commit(ctx *Context) error {
+	// The databases find the transaction in the session.
+	ctx.SetTransaction(t)
+	defer ctx.SetTransaction(nil)
+
+	var firstErr error
+	for _, db := range t.databases {
+		if err := db.CommitTransaction(ctx); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// Rollback rolls back the transaction in all its databases.
+func (t *Transaction) Rollback(ctx *Context) error {
+	// The databases find the transaction in the session.
+	ctx.SetTransaction(t)
+	defer ctx.SetTransaction(nil)
+
+	var firstErr error
+	for _, db := range t.databases {
+		if err := db.RollbackTransaction(ctx); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// CreateSavepoint creates a savepoint with the given name in all the
+// databases of the transaction. An existing savepoint with the same name is
+// replaced.
+func (t *Transaction) CreateSavepoint(ctx *Context, name string) error {
+	for _, db := range t.databases {
+		if err := db.CreateSavepoint(ctx, name); err != nil {
+			return err
+		}
+	}
+
+	if i := t.savepointIndex(name); i >= 0 {
+		t.savepoints = append(t.savepoints[:i], t.savepoints[i+1:]...)
+	}
+	t.savepoints = append(t.savepoints, name)
+	return nil
+}
+
+// RollbackToSavepoint rolls back the transaction to the savepoint with the
+// given name. All the savepoints created after it are removed.
+func (t *Transaction) RollbackToSavepoint(ctx *Context, name string) error {
+	i := t.savepointIndex(name)
+	if i < 0 {
+		return ErrSavepointNotFound.New(name)
+	}
+
+	for _, db := range t.databases {
+		if err := db.RollbackToSavepoint(ctx, name); err != nil {
+			return err
+		}
+	}
+
+	for _, sp := range t.savepoints[i+1:] {
+		if err := t.releaseSavepoint(ctx, sp); err != nil {
+			return err
+		}
+	}
+
+	t.savepoints = t.savepoints[:i+1]
+	return nil
+}
+
+// ReleaseSavepoint removes the savepoint with the given name and all the
+// savepoints created after it.
+func (t *Transaction) ReleaseSavepoint(ctx *Context, name string) error {
+	i := t.savepointIndex(name)
+	if i < 0 {
+		return ErrSavepointNotFound.New(name)
+	}
+
+	for _, sp := range t.savepoints[i:] {
+		if err := t.releaseSavepoint(ctx, sp); err != nil {
+			return err
+		}
+	}
+
+	t.savepoints = t.savepoints[:i]
+	return nil
+}
+
+// Savepoints returns the names of the savepoints of the transaction, in the
+// order they were created.
+func (t *Transaction) Savepoints() []string {
+	return append([]string(nil), t.savepoints...)
+}
+
+func (t *Transaction) releaseSavepoint(ctx *Context, name string) error {
+	for _, db := range t.databases {
+		if err := db.ReleaseSavepoint(ctx, name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (t *Transaction) savepointIndex(name string) int {
+	for i, sp := range t.savepoints {
+		if strings.EqualFold(sp, name) {
+			return i
+		}
+	}
+	return -1
+}
+
+// IsAutocommit returns whether the autocommit variable is enabled in the
+// given session.
+func IsAutocommit(s Session) bool {
+	_, v := s.Get("autocommit")
+	switch v := v.(type) {
+	case nil:
+		return true
+	case bool:
+		return v
+	case string:
+		switch strings.ToLower(v) {
+		case "on", "true", "1":
+			return true
+		default:
+			return false
+		}
+	}
+
+	n, err := Int64.Convert(v)
+	if err != nil {
+		return true
+	}
+
+	return n != int64(0)
+}
@@ -0,0 +1,133 @@
+package sql
+
+import (
+	"sort"
+	"strings"
+	"sync"
+
+	"gopkg.in/src-d/go-errors.v1"
+)
+
+var (
+	// ErrViewAlreadyExists is returned when a view is registered with the
+	// name of an existing one.
+	ErrViewAlreadyExists = errors.NewKind("view with name %s already exists in database %s")
+
+	// ErrViewNotFound is returned when a view is not registered.
+	ErrViewNotFound = errors.NewKind("view not found: %s")
+)
+
+// View is a query stored in the catalog with a name, which can be used as a
+// table in other queries.
+type View struct {
+	name string
+	// Columns are the names given to the columns of the view, if any.
+	Columns []string
+	// Definition is the query of the view, as it is returned by the parser.
+	Definition Node
+	// TextDefinition is the query of the view as it was written.
+	TextDefinition string
+}
+
+// NewView creates a new view with the given name, column names and query.
+func NewView(name string, columns []string, definition Node, textDefinition string) *View {
+	return &View{
+		name:           name,
+		Columns:        columns,
+		Definition:     definition,
+		TextDefinition: textDefinition,
+	}
+}
+
+// Name returns the name of the view.
+func (v *View) Name() string { return v.name }
+
+type viewKey struct {
+	db, name string
+}
+
+func newViewKey(db, name string) viewKey {
+	return viewKey{strings.ToLower(db), strings.ToLower(name)}
+}
+
+// ViewRegistry keeps the views of all the databases.
+type ViewRegistry struct {
+	mut   sync.RWMutex
+	views map[viewKey]*View
+}
+
+// NewViewRegistry returns a new empty ViewRegistry.
+func NewViewRegistry() *ViewRegistry {
+	return &ViewRegistry{views: make(map[viewKey]*View)}
+}
+
+// RegisterView adds the given view to the given database. It fails if
+// there is already a view with the same name in the database.
+func (r *ViewRegistry) RegisterView(db string, view *View) error {
+	r.mut.Lock()
+	defer r.mut.Unlock()
+
+	key := newViewKey(db, view.Name())
+	if _, ok := r.views[key]; ok {
+		return ErrViewAlreadyExists.New(view.Name(), db)
+	}
+
+	r.views[key] = view
+	return nil
+}
+
+// ReplaceView adds the given view to the given database, replacing the
+// existing view with the same name, if any.
+func (r *ViewRegistry) ReplaceView(db string, view *View) {
+	r.mut.Lock()
+	r.views[newViewKey(db, view.Name())] = view
+	r.mut.Unlock()
+}
+
+// DeleteView removes the view with the given name from the given database.
+func (r *ViewRegistry) DeleteView(db, name string) error {
+	r.mut.Lock()
+	defer r.mut.Unlock()
+
+	key := newViewKey(db, name)
+	if _, ok := r.views[key]; !ok {
+		return ErrViewNotFound.New(name)
+	}
+
+	delete(r.views, key)
+	return nil
+}
+
+// View returns the view with the given name in the given database.
+func (r *ViewRegistry) View(db, name string) (*View, error) {
+	r.mut.RLock()
+	defer r.mut.RUnlock()
+
+	view, ok := r.views[newViewKey(db, name)]
+	if !ok {
+		return nil, ErrViewNotFound.New(name)
+	}
+
+	return view, nil
+}
+
+// ViewsInDatabase returns all the views of the given database, sorted by
+// name.
+func (r *ViewRegistry) ViewsInDatabase(db string) []*View {
+	r.mut.RLock()
+	defer r.mut.RUnlock()
+
+	db = strings.ToLower(db)
+	var views []*View
+	for key, view := range r.views {
+		if key.db == db {
+			views = append(views, view)
+		}
+	}
+
+	sort.Slice(views, func(i, j int) bool {
+		return views[i].Name() < views[j].Name()
+	})
+
+	return views
+}
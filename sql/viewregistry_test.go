@@ -0,0 +1,48 @@
+package sql_test
+
+import (
+	"testing"
+
+	"github.com/src-d/go-mysql-server/sql"
+	"github.com/stretchr/testify/require"
+)
+
+func TestViewRegistry(t *testing.T) {
+	require := require.New(t)
+
+	r := sql.NewViewRegistry()
+	foo := sql.NewView("foo", nil, nil, "SELECT 1")
+	bar := sql.NewView("bar", []string{"a"}, nil, "SELECT 2")
+
+	require.NoError(r.RegisterView("db", foo))
+	require.NoError(r.RegisterView("db", bar))
+	require.NoError(r.RegisterView("db2", foo))
+
+	err := r.RegisterView("DB", sql.NewView("FOO", nil, nil, "SELECT 3"))
+	require.Error(err)
+	require.True(sql.ErrViewAlreadyExists.Is(err))
+
+	view, err := r.View("Db", "Foo")
+	require.NoError(err)
+	require.Equal(foo, view)
+
+	require.Equal([]*sql.View{bar, foo}, r.ViewsInDatabase("db"))
+	require.Equal([]*sql.View{foo}, r.ViewsInDatabase("db2"))
+	require.Len(r.ViewsInDatabase("db3"), 0)
+
+	replacement := sql.NewView("foo", nil, nil, "SELECT 3")
+	r.ReplaceView("db", replacement)
+	view, err = r.View("db", "foo")
+	require.NoError(err)
+	require.Equal(replacement, view)
+
+	require.NoError(r.DeleteView("db", "FOO"))
+	_, err = r.View("db", "foo")
+	require.True(sql.ErrViewNotFound.Is(err))
+
+	err = r.DeleteView("db", "foo")
+	require.True(sql.ErrViewNotFound.Is(err))
+
+	_, err = r.View("db2", "foo")
+	require.NoError(err)
+}